@@ -7,7 +7,7 @@ for membership testing with configurable false positive rates.
 Features:
 - Idiomatic Go implementation using channels and goroutines
 - Multiple hash functions for better distribution
-- Concurrent-safe operations using sync primitives
+- Concurrent-safe, lock-free bit operations using atomic word updates
 - Memory-efficient bit manipulation
 - Comprehensive statistics and monitoring
 - Builder pattern for flexible configuration
@@ -19,10 +19,8 @@ import (
 	"crypto/sha1"
 	"encoding/binary"
 	"fmt"
-	"hash/fnv"
 	"math"
 	"math/rand"
-	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -82,12 +80,24 @@ var (
 		return hash
 	}
 
-	// FNV-1a hash function
+	// FNV-1a hash function, with the seed hashed after the data. Written out
+	// rather than using hash/fnv so it doesn't allocate.
 	fnvHash HashFunction = func(data []byte, seed uint32) uint32 {
-		h := fnv.New32a()
-		h.Write(data)
-		h.Write([]byte{byte(seed), byte(seed >> 8), byte(seed >> 16), byte(seed >> 24)})
-		return h.Sum32()
+		const (
+			offset32 = 2166136261
+			prime32  = 16777619
+		)
+
+		hash := uint32(offset32)
+		for _, b := range data {
+			hash ^= uint32(b)
+			hash *= prime32
+		}
+		for shift := uint(0); shift < 32; shift += 8 {
+			hash ^= (seed >> shift) & 0xff
+			hash *= prime32
+		}
+		return hash
 	}
 
 	// DJB2 hash function
@@ -120,16 +130,22 @@ var (
 	}
 )
 
-// BitArray represents a thread-safe bit array
+// defaultHashFunctions returns the hash functions a filter cycles through
+func defaultHashFunctions() []HashFunction {
+	return []HashFunction{murmurHash3, fnvHash, djb2Hash, sdbmHash, sha1Hash}
+}
+
+// BitArray represents a thread-safe bit array. Bits are read and written
+// with atomic word operations, so concurrent Add and Contains calls never
+// block each other.
 type BitArray struct {
 	bits []uint64
 	size uint32
-	mu   sync.RWMutex
 }
 
 // NewBitArray creates a new bit array
 func NewBitArray(size uint32) *BitArray {
-	numWords := (size + 63) / 64
+	numWords := (uint64(size) + 63) / 64 // widened so sizes near MaxUint32 don't wrap
 	return &BitArray{
 		bits: make([]uint64, numWords),
 		size: size,
@@ -142,12 +158,9 @@ func (ba *BitArray) SetBit(index uint32) {
 		return
 	}
 
-	ba.mu.Lock()
-	defer ba.mu.Unlock()
-
 	wordIndex := index / 64
 	bitIndex := index % 64
-	ba.bits[wordIndex] |= 1 << bitIndex
+	ba.updateWord(wordIndex, func(word uint64) uint64 { return word | 1<<bitIndex })
 }
 
 // GetBit gets the value of a bit at the given index
@@ -156,31 +169,46 @@ func (ba *BitArray) GetBit(index uint32) bool {
 		return false
 	}
 
-	ba.mu.RLock()
-	defer ba.mu.RUnlock()
-
 	wordIndex := index / 64
 	bitIndex := index % 64
-	return (ba.bits[wordIndex] & (1 << bitIndex)) != 0
+	return (atomic.LoadUint64(&ba.bits[wordIndex]) & (1 << bitIndex)) != 0
+}
+
+// updateWord atomically replaces a word with op(word), retrying if another
+// goroutine changes it in between. Returns without writing if the word is
+// already up to date, so setting a bit that is already set costs one load.
+func (ba *BitArray) updateWord(wordIndex uint32, op func(word uint64) uint64) {
+	addr := &ba.bits[wordIndex]
+	for {
+		old := atomic.LoadUint64(addr)
+		updated := op(old)
+		if updated == old || atomic.CompareAndSwapUint64(addr, old, updated) {
+			return
+		}
+	}
+}
+
+// Snapshot returns a copy of the words. Each word is read atomically, but
+// bits set while the copy is taken may or may not be included.
+func (ba *BitArray) Snapshot() []uint64 {
+	words := make([]uint64, len(ba.bits))
+	for i := range ba.bits {
+		words[i] = atomic.LoadUint64(&ba.bits[i])
+	}
+	return words
 }
 
 // Clear clears all bits
 func (ba *BitArray) Clear() {
-	ba.mu.Lock()
-	defer ba.mu.Unlock()
-
 	for i := range ba.bits {
-		ba.bits[i] = 0
+		atomic.StoreUint64(&ba.bits[i], 0)
 	}
 }
 
-// CountSetBits counts the number of set bits
+// CountSetBits counts the number of set bits in a snapshot of the array
 func (ba *BitArray) CountSetBits() uint32 {
-	ba.mu.RLock()
-	defer ba.mu.RUnlock()
-
 	count := uint32(0)
-	for _, word := range ba.bits {
+	for _, word := range ba.Snapshot() {
 		count += uint32(popcount(word))
 	}
 	return count
@@ -210,6 +238,10 @@ type BloomFilterStats struct {
 	FalsePositiveRate  float64 `json:"falsePositiveRate"`
 	MemoryUsage        uint32  `json:"memoryUsage"`
 	FillRatio          float64 `json:"fillRatio"`
+
+	// EstimatedCardinality is the number of distinct elements implied by
+	// the set bits; unlike NumElements, duplicates don't inflate it
+	EstimatedCardinality uint32 `json:"estimatedCardinality"`
 }
 
 // UpdateFillRatio updates the fill ratio based on set bits
@@ -249,28 +281,26 @@ type BloomFilter struct {
 	falsePositiveRate float64
 	numElements       uint32
 	hashFunctions     []HashFunction
-	hashSeeds         []uint32
+	hashSeed          uint32
+	customHashes      bool // hashFunctions were chosen with the builder
 }
 
-// NewBloomFilter creates a new Bloom filter with optimal parameters
+// NewBloomFilter creates a new Bloom filter with optimal parameters. It
+// returns an error wrapping ErrFilterTooLarge if they need a bit array
+// larger than DefaultMaxBitArraySize; use the builder to raise the limit.
 func NewBloomFilter(expectedElements uint32, falsePositiveRate float64) (*BloomFilter, error) {
-	if expectedElements == 0 {
-		return nil, fmt.Errorf("expected elements must be positive")
-	}
-	if falsePositiveRate <= 0.0 || falsePositiveRate >= 1.0 {
-		return nil, fmt.Errorf("false positive rate must be between 0 and 1")
-	}
+	return newBloomFilter(expectedElements, falsePositiveRate, DefaultMaxBitArraySize)
+}
 
+// newBloomFilter creates a Bloom filter whose bit array may hold at most
+// maxBitArraySize bits
+func newBloomFilter(expectedElements uint32, falsePositiveRate float64, maxBitArraySize uint64) (*BloomFilter, error) {
 	// Calculate optimal parameters
-	bitArraySize := calculateBitArraySize(expectedElements, falsePositiveRate)
-	numHashFunctions := calculateNumHashFunctions(bitArraySize, expectedElements)
-
-	// Initialize hash functions and seeds
-	hashFunctions := []HashFunction{murmurHash3, fnvHash, djb2Hash, sdbmHash, sha1Hash}
-	hashSeeds := make([]uint32, numHashFunctions)
-	for i := uint32(0); i < numHashFunctions; i++ {
-		hashSeeds[i] = i
+	bitArraySize, err := sizeBitArray(expectedElements, falsePositiveRate, maxBitArraySize)
+	if err != nil {
+		return nil, err
 	}
+	numHashFunctions := calculateNumHashFunctions(bitArraySize, expectedElements)
 
 	return &BloomFilter{
 		bitArray:          NewBitArray(bitArraySize),
@@ -279,16 +309,17 @@ func NewBloomFilter(expectedElements uint32, falsePositiveRate float64) (*BloomF
 		expectedElements:  expectedElements,
 		falsePositiveRate: falsePositiveRate,
 		numElements:       0,
-		hashFunctions:     hashFunctions,
-		hashSeeds:         hashSeeds,
+		hashFunctions:     defaultHashFunctions(),
 	}, nil
 }
 
-// calculateBitArraySize calculates the optimal bit array size
-func calculateBitArraySize(expectedElements uint32, falsePositiveRate float64) uint32 {
+// calculateBitArraySize calculates the optimal bit array size. The result
+// is a float64 so a size too large for any integer type can still be
+// compared against a limit before it is converted.
+func calculateBitArraySize(expectedElements uint32, falsePositiveRate float64) float64 {
 	// m = -(n * ln(p)) / (ln(2)^2)
 	size := -(float64(expectedElements) * math.Log(falsePositiveRate)) / (math.Log(2) * math.Log(2))
-	return uint32(math.Max(1, math.Ceil(size)))
+	return math.Max(1, math.Ceil(size))
 }
 
 // calculateNumHashFunctions calculates the optimal number of hash functions
@@ -298,15 +329,35 @@ func calculateNumHashFunctions(bitArraySize, expectedElements uint32) uint32 {
 	return uint32(math.Max(1, math.Round(k)))
 }
 
-// getHashValues gets hash values for an element
-func (bf *BloomFilter) getHashValues(element string) []uint32 {
-	data := []byte(element)
-	hashes := make([]uint32, bf.numHashFunctions)
+// baseHashes computes the two hashes all of an element's bit indices are
+// derived from: by default MurmurHash3 and FNV-1a with the filter's seed.
+// A filter with a single hash function applies it again, seeded with
+// the first hash, to get the second.
+func baseHashes(data []byte, hashFunctions []HashFunction, seed uint32) (h1, h2 uint32) {
+	h1 = hashFunctions[0](data, seed)
+	if len(hashFunctions) == 1 {
+		h2 = hashFunctions[0](data, h1)
+	} else {
+		h2 = hashFunctions[1](data, seed)
+	}
+	return h1, h2 | 1 // an odd step never collapses every index onto h1
+}
 
-	for i := uint32(0); i < bf.numHashFunctions; i++ {
-		hashFunc := bf.hashFunctions[i%uint32(len(bf.hashFunctions))]
-		hashValue := hashFunc(data, bf.hashSeeds[i])
-		hashes[i] = hashValue % bf.bitArraySize
+// doubleHashIndex returns the i-th index of the Kirsch-Mitzenmacher double
+// hashing scheme, (h1 + i*h2) mod size. Deriving k indices from two hashes
+// gives the same asymptotic false positive rate as k independent hashes while
+// costing two hash evaluations instead of k.
+func doubleHashIndex(h1, h2, i, size uint32) uint32 {
+	return uint32((uint64(h1) + uint64(i)*uint64(h2)) % uint64(size))
+}
+
+// hashIndices maps data to count indices in [0, size) using double hashing
+func hashIndices(data []byte, hashFunctions []HashFunction, seed, count, size uint32) []uint32 {
+	h1, h2 := baseHashes(data, hashFunctions, seed)
+	hashes := make([]uint32, count)
+
+	for i := range hashes {
+		hashes[i] = doubleHashIndex(h1, h2, uint32(i), size)
 	}
 
 	return hashes
@@ -314,10 +365,15 @@ func (bf *BloomFilter) getHashValues(element string) []uint32 {
 
 // Add adds an element to the Bloom filter
 func (bf *BloomFilter) Add(element string) {
-	hashValues := bf.getHashValues(element)
+	bf.AddBytes([]byte(element))
+}
 
-	for _, hashValue := range hashValues {
-		bf.bitArray.SetBit(hashValue)
+// AddBytes adds an element given as raw bytes. Add and AddBytes hash the
+// same content to the same bits, so either can be used to query.
+func (bf *BloomFilter) AddBytes(data []byte) {
+	h1, h2 := baseHashes(data, bf.hashFunctions, bf.hashSeed)
+	for i := uint32(0); i < bf.numHashFunctions; i++ {
+		bf.bitArray.SetBit(doubleHashIndex(h1, h2, i, bf.bitArraySize))
 	}
 
 	atomic.AddUint32(&bf.numElements, 1)
@@ -325,10 +381,14 @@ func (bf *BloomFilter) Add(element string) {
 
 // Contains tests if an element might be in the set
 func (bf *BloomFilter) Contains(element string) bool {
-	hashValues := bf.getHashValues(element)
+	return bf.ContainsBytes([]byte(element))
+}
 
-	for _, hashValue := range hashValues {
-		if !bf.bitArray.GetBit(hashValue) {
+// ContainsBytes tests if an element given as raw bytes might be in the set
+func (bf *BloomFilter) ContainsBytes(data []byte) bool {
+	h1, h2 := baseHashes(data, bf.hashFunctions, bf.hashSeed)
+	for i := uint32(0); i < bf.numHashFunctions; i++ {
+		if !bf.bitArray.GetBit(doubleHashIndex(h1, h2, i, bf.bitArraySize)) {
 			return false
 		}
 	}
@@ -353,7 +413,9 @@ func (bf *BloomFilter) GetStats() *BloomFilterStats {
 		MemoryUsage:       bf.bitArray.GetMemoryUsage(),
 	}
 
-	stats.UpdateFillRatio(bf.bitArray.CountSetBits())
+	setBits := bf.bitArray.CountSetBits()
+	stats.UpdateFillRatio(setBits)
+	stats.UpdateEstimatedCardinality(setBits)
 	return stats
 }
 
@@ -381,12 +443,19 @@ func (bf *BloomFilter) GetExpectedElements() uint32  { return bf.expectedElement
 type BloomFilterBuilder struct {
 	expectedElements  *uint32
 	falsePositiveRate float64
+	maxBitArraySize   uint64
+	hashFunctions     []HashFunction // nil for the default set
+	hashSeed          uint32
+	hashCount         uint32         // 0 for the optimal k
+	window            time.Duration  // BuildDecaying only
+	windowSlices      int            // BuildDecaying only; 0 for DefaultWindowSlices
 }
 
 // NewBloomFilterBuilder creates a new builder
 func NewBloomFilterBuilder() *BloomFilterBuilder {
 	return &BloomFilterBuilder{
 		falsePositiveRate: 0.01,
+		maxBitArraySize:   DefaultMaxBitArraySize,
 	}
 }
 
@@ -402,17 +471,68 @@ func (b *BloomFilterBuilder) WithFalsePositiveRate(rate float64) *BloomFilterBui
 	return b
 }
 
+// WithMaxBitArraySize sets the largest bit array, in bits, that Build and
+// BuildCounting may allocate. It defaults to DefaultMaxBitArraySize and can
+// be raised to at most math.MaxUint32.
+func (b *BloomFilterBuilder) WithMaxBitArraySize(bits uint64) *BloomFilterBuilder {
+	b.maxBitArraySize = bits
+	return b
+}
+
+// WithHashFunctions sets the hash functions the filter's two base hashes
+// come from: the first and second function, or one function applied twice.
+// All k bit indices are derived from those two by double hashing, so one or
+// two functions are accepted. The default is MurmurHash3 and FNV-1a.
+func (b *BloomFilterBuilder) WithHashFunctions(funcs ...HashFunction) *BloomFilterBuilder {
+	b.hashFunctions = funcs
+	if b.hashFunctions == nil {
+		b.hashFunctions = []HashFunction{} // distinguish "none" from the default
+	}
+	return b
+}
+
+// WithSeed sets the seed of the two base hashes every bit index is derived
+// from, so filters with different seeds set different bits. The default is 0.
+func (b *BloomFilterBuilder) WithSeed(seed uint32) *BloomFilterBuilder {
+	b.hashSeed = seed
+	return b
+}
+
+// WithHashCount overrides the number of bit indices per element, k, instead
+// of the optimal count for the expected elements and false positive rate.
+// Fewer indices are faster but raise the false positive rate.
+func (b *BloomFilterBuilder) WithHashCount(k uint32) *BloomFilterBuilder {
+	b.hashCount = k
+	return b
+}
+
 // Build creates the Bloom filter
 func (b *BloomFilterBuilder) Build() (*BloomFilter, error) {
 	if b.expectedElements == nil {
 		return nil, fmt.Errorf("expected elements must be specified")
 	}
-	return NewBloomFilter(*b.expectedElements, b.falsePositiveRate)
+	bf, err := newBloomFilter(*b.expectedElements, b.falsePositiveRate, b.maxBitArraySize)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.applyHashOptions(bf); err != nil {
+		return nil, err
+	}
+	return bf, nil
+}
+
+// BuildCounting creates a counting Bloom filter, which supports Remove
+func (b *BloomFilterBuilder) BuildCounting() (*CountingBloomFilter, error) {
+	bf, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return newCountingFromFilter(bf), nil
 }
 
 // demo demonstrates the Bloom filter functionality
 func demo() {
-	fmt.Println("=== Bloom Filter Demo ===\n")
+	fmt.Print("=== Bloom Filter Demo ===\n\n")
 
 	// Create Bloom filter for 10,000 elements with 1% false positive rate
 	bf, err := NewBloomFilter(10000, 0.01)
@@ -509,4 +629,16 @@ func demo() {
 func main() {
 	rand.Seed(time.Now().UnixNano())
 	demo()
+	demoCountingBloomFilter()
+	demoSerialization()
+	demoSetOperations()
+	demoScalableBloomFilter()
+	demoHashable()
+	demoConcurrentBitArray()
+	demoCountMinSketch()
+	demoBulkOperations()
+	demoCardinality()
+	demoSizeLimits()
+	demoHashSelection()
+	demoDecayingBloomFilter()
 }
\ No newline at end of file
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// TestDoubleHashingAccuracy checks that double hashing keeps the observed
+// false positive rate within sampling noise of the theoretical rate
+func TestDoubleHashingAccuracy(t *testing.T) {
+	configs := []struct {
+		elements uint32
+		rate     float64
+	}{{1000, 0.01}, {10000, 0.01}, {10000, 0.001}, {50000, 0.05}}
+
+	for _, config := range configs {
+		t.Run(fmt.Sprintf("n=%d,p=%g", config.elements, config.rate), func(t *testing.T) {
+			bf, err := NewBloomFilter(config.elements, config.rate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := uint32(0); i < config.elements; i++ {
+				bf.Add(fmt.Sprintf("member-%d", i))
+			}
+
+			const probes = 200000
+			falsePositives := 0
+			for i := 0; i < probes; i++ {
+				if bf.Contains(fmt.Sprintf("probe-%d", i)) {
+					falsePositives++
+				}
+			}
+
+			// Allow four standard deviations of sampling noise around the theory
+			expected := bf.GetFalsePositiveRate()
+			observed := float64(falsePositives) / probes
+			tolerance := 4 * math.Sqrt(expected*(1-expected)/probes)
+			if math.Abs(observed-expected) > tolerance {
+				t.Errorf("observed false positive rate = %.5f, want %.5f ± %.5f (k=%d)",
+					observed, expected, tolerance, bf.GetNumHashFunctions())
+			}
+		})
+	}
+}
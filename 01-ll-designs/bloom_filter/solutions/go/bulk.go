@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+)
+
+// AddAll adds every element, computing all bit positions before touching
+// the bit array. Large batches are collected into a private mask per word
+// and merged with one atomic update per word instead of one per bit, which
+// is what makes bulk-loading a filter from a large dataset fast.
+func (bf *BloomFilter) AddAll(elements []string) {
+	if len(elements) == 0 {
+		return
+	}
+
+	var buf []byte
+	numWords := len(bf.bitArray.bits)
+	numBits := len(elements) * int(bf.numHashFunctions)
+
+	// Small batches would spend more time scanning a mask of every word
+	// than they save, so they set each bit directly
+	if numBits < numWords/8 {
+		for _, element := range elements {
+			buf = append(buf[:0], element...)
+			h1, h2 := baseHashes(buf, bf.hashFunctions, bf.hashSeed)
+			for i := uint32(0); i < bf.numHashFunctions; i++ {
+				bf.bitArray.SetBit(doubleHashIndex(h1, h2, i, bf.bitArraySize))
+			}
+		}
+	} else {
+		masks := make([]uint64, numWords)
+		for _, element := range elements {
+			buf = append(buf[:0], element...)
+			h1, h2 := baseHashes(buf, bf.hashFunctions, bf.hashSeed)
+			for i := uint32(0); i < bf.numHashFunctions; i++ {
+				index := doubleHashIndex(h1, h2, i, bf.bitArraySize)
+				masks[index/64] |= 1 << (index % 64)
+			}
+		}
+		bf.bitArray.orWords(masks)
+	}
+
+	atomic.AddUint32(&bf.numElements, uint32(len(elements)))
+}
+
+// ContainsAll tests each element and returns one result per element, in
+// order. Hashing runs in a tight loop over a reused buffer, without the
+// per-element allocation of Contains.
+func (bf *BloomFilter) ContainsAll(elements []string) []bool {
+	results := make([]bool, len(elements))
+
+	var buf []byte
+	for n, element := range elements {
+		buf = append(buf[:0], element...)
+		results[n] = bf.ContainsBytes(buf)
+	}
+	return results
+}
+
+// orWords ORs masks into the array's words, one atomic update for each
+// non-zero mask
+func (ba *BitArray) orWords(masks []uint64) {
+	for wordIndex, mask := range masks {
+		if mask != 0 {
+			ba.updateWord(uint32(wordIndex), func(word uint64) uint64 { return word | mask })
+		}
+	}
+}
+
+// demoBulkOperations checks that AddAll sets the same bits as a loop of Add,
+// and shows ContainsAll. BenchmarkAddLoop and BenchmarkAddAll compare their
+// speed.
+func demoBulkOperations() {
+	fmt.Println("\n=== Bulk Add and Contains Demo ===")
+
+	const count = 1000000
+	elements := make([]string, count)
+	for i := range elements {
+		elements[i] = "user-" + strconv.Itoa(i)
+	}
+
+	bf, _ := NewBloomFilter(count, 0.01)
+	for _, element := range elements {
+		bf.Add(element)
+	}
+	bulk, _ := NewBloomFilter(count, 0.01)
+	bulk.AddAll(elements)
+	fmt.Printf("Loaded %d elements with Add and with AddAll\n", count)
+
+	same := true
+	bulkWords := bulk.bitArray.Snapshot()
+	for i, word := range bf.bitArray.Snapshot() {
+		same = same && word == bulkWords[i]
+	}
+	fmt.Printf("Same bits set: %t, Size() = %d\n", same, bulk.Size())
+
+	probes := []string{"user-1", "user-999999", "user-1000000", "admin"}
+	fmt.Printf("ContainsAll(%v) = %v\n", probes, bulk.ContainsAll(probes))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+const bulkBenchmarkCount = 1000000
+
+// bulkElements returns count distinct elements
+func bulkElements(count int) []string {
+	elements := make([]string, count)
+	for i := range elements {
+		elements[i] = "user-" + strconv.Itoa(i)
+	}
+	return elements
+}
+
+func TestAddAllMatchesAdd(t *testing.T) {
+	elements := bulkElements(10000)
+
+	looped, _ := NewBloomFilter(uint32(len(elements)), 0.01)
+	for _, element := range elements {
+		looped.Add(element)
+	}
+	bulk, _ := NewBloomFilter(uint32(len(elements)), 0.01)
+	bulk.AddAll(elements)
+
+	bulkWords := bulk.bitArray.Snapshot()
+	for i, word := range looped.bitArray.Snapshot() {
+		if word != bulkWords[i] {
+			t.Fatalf("word %d: AddAll set %#x, Add set %#x", i, bulkWords[i], word)
+		}
+	}
+	for i, present := range bulk.ContainsAll(elements) {
+		if !present {
+			t.Fatalf("ContainsAll missed %s", elements[i])
+		}
+	}
+}
+
+// BenchmarkAddLoop loads 1M elements with one Add each
+func BenchmarkAddLoop(b *testing.B) {
+	elements := bulkElements(bulkBenchmarkCount)
+	bf, _ := NewBloomFilter(bulkBenchmarkCount, 0.01)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		bf.Clear()
+		for _, element := range elements {
+			bf.Add(element)
+		}
+	}
+}
+
+// BenchmarkAddAll loads the same 1M elements with one AddAll
+func BenchmarkAddAll(b *testing.B) {
+	elements := bulkElements(bulkBenchmarkCount)
+	bf, _ := NewBloomFilter(bulkBenchmarkCount, 0.01)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		bf.Clear()
+		bf.AddAll(elements)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// estimateCardinality estimates how many distinct elements set setBits of
+// size bits with k hashes each, using n ≈ -(m/k)·ln(1 - X/m) (Swamidass and
+// Baldi). A full array gives no upper bound, so it is treated as one bit
+// short of full and the result is a lower bound.
+func estimateCardinality(setBits, size, k uint32) uint32 {
+	if size == 0 || k == 0 {
+		return 0
+	}
+
+	x := float64(setBits)
+	m := float64(size)
+	if x >= m {
+		x = m - 1
+	}
+	return uint32(math.Round(-(m / float64(k)) * math.Log(1-x/m)))
+}
+
+// EstimateCardinality estimates the number of distinct elements in the
+// filter from how many bits are set. Unlike Size, it does not count an
+// element again when it is added twice.
+func (bf *BloomFilter) EstimateCardinality() uint32 {
+	return estimateCardinality(bf.bitArray.CountSetBits(), bf.bitArraySize, bf.numHashFunctions)
+}
+
+// UpdateEstimatedCardinality updates the distinct element estimate based on
+// set bits
+func (s *BloomFilterStats) UpdateEstimatedCardinality(setBits uint32) {
+	s.EstimatedCardinality = estimateCardinality(setBits, s.BitArraySize, s.NumHashFunctions)
+}
+
+// demoCardinality shows that duplicates inflate Size but not the estimate
+func demoCardinality() {
+	fmt.Println("\n=== Cardinality Estimation Demo ===")
+
+	for _, distinct := range []int{100, 1000, 10000} {
+		bf, _ := NewBloomFilter(10000, 0.01)
+
+		// Every element is added three times
+		for round := 0; round < 3; round++ {
+			for i := 0; i < distinct; i++ {
+				bf.Add(fmt.Sprintf("visitor-%d", i))
+			}
+		}
+
+		estimate := bf.EstimateCardinality()
+		errorPct := (float64(estimate) - float64(distinct)) / float64(distinct) * 100
+		fmt.Printf("distinct=%-5d Size()=%-5d EstimateCardinality()=%-5d (%+.1f%%)\n",
+			distinct, bf.Size(), estimate, errorPct)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestEstimateCardinality(t *testing.T) {
+	for _, distinct := range []int{100, 1000, 5000, 10000} {
+		t.Run(fmt.Sprint(distinct), func(t *testing.T) {
+			bf, err := NewBloomFilter(10000, 0.01)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Duplicates inflate Size but must not move the estimate
+			for round := 0; round < 3; round++ {
+				for i := 0; i < distinct; i++ {
+					bf.Add(fmt.Sprintf("visitor-%d", i))
+				}
+			}
+
+			if got, want := bf.Size(), uint32(3*distinct); got != want {
+				t.Errorf("Size() = %d, want %d", got, want)
+			}
+			estimate := bf.EstimateCardinality()
+			if relErr := math.Abs(float64(estimate)-float64(distinct)) / float64(distinct); relErr > 0.05 {
+				t.Errorf("EstimateCardinality() = %d, want %d ± 5%%", estimate, distinct)
+			}
+			if got := bf.GetStats().EstimatedCardinality; got != estimate {
+				t.Errorf("stats EstimatedCardinality = %d, want %d", got, estimate)
+			}
+		})
+	}
+}
+
+func TestEstimateCardinalityEdgeCases(t *testing.T) {
+	tests := []struct {
+		name             string
+		setBits, size, k uint32
+		want             uint32
+	}{
+		{"empty", 0, 1000, 7, 0},
+		{"zero size", 10, 0, 7, 0},
+		{"zero hashes", 10, 1000, 0, 0},
+		// A full array is treated as one bit short of full
+		{"full", 1000, 1000, 7, 987},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := estimateCardinality(tt.setBits, tt.size, tt.k); got != tt.want {
+				t.Errorf("estimateCardinality(%d, %d, %d) = %d, want %d", tt.setBits, tt.size, tt.k, got, tt.want)
+			}
+		})
+	}
+}
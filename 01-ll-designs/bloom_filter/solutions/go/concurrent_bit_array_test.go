@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestConcurrentAddContains runs writers and readers against one filter at
+// once. Run it with -race to have the race detector check the atomic bit
+// array; afterwards every added element must be found.
+func TestConcurrentAddContains(t *testing.T) {
+	const (
+		writers   = 8
+		readers   = 8
+		perWriter = 5000
+	)
+
+	bf, _ := NewBloomFilter(writers*perWriter, 0.01)
+	var wg sync.WaitGroup
+	var writing sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		writing.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			defer writing.Done()
+			for i := 0; i < perWriter; i++ {
+				bf.Add(fmt.Sprintf("writer-%d-item-%d", w, i))
+			}
+		}(w)
+	}
+
+	// Readers probe elements the writers are adding until they finish; any
+	// answer is fine while the element may not be added yet
+	done := make(chan struct{})
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				bf.Contains(fmt.Sprintf("writer-%d-item-%d", r%writers, i%perWriter))
+			}
+		}(r)
+	}
+	writing.Wait()
+	close(done)
+	wg.Wait()
+
+	if got := bf.Size(); got != writers*perWriter {
+		t.Errorf("Size() = %d, want %d", got, writers*perWriter)
+	}
+	for w := 0; w < writers; w++ {
+		for i := 0; i < perWriter; i++ {
+			if element := fmt.Sprintf("writer-%d-item-%d", w, i); !bf.Contains(element) {
+				t.Fatalf("%s was added concurrently but is missing", element)
+			}
+		}
+	}
+}
+
+// lockedBitArray is the RWMutex-guarded bit array BitArray used before it
+// switched to atomic word operations, kept as a benchmark baseline
+type lockedBitArray struct {
+	bits []uint64
+	size uint32
+	mu   sync.RWMutex
+}
+
+func newLockedBitArray(size uint32) *lockedBitArray {
+	return &lockedBitArray{bits: make([]uint64, (uint64(size)+63)/64), size: size}
+}
+
+func (ba *lockedBitArray) SetBit(index uint32) {
+	if index >= ba.size {
+		return
+	}
+	ba.mu.Lock()
+	defer ba.mu.Unlock()
+	ba.bits[index/64] |= 1 << (index % 64)
+}
+
+func (ba *lockedBitArray) GetBit(index uint32) bool {
+	if index >= ba.size {
+		return false
+	}
+	ba.mu.RLock()
+	defer ba.mu.RUnlock()
+	return ba.bits[index/64]&(1<<(index%64)) != 0
+}
+
+const benchmarkBitArraySize = 1 << 20
+
+// benchmarkBitArray runs a parallel mix of one write to every three reads,
+// roughly the shape of a filter that is queried more than it is filled
+func benchmarkBitArray(b *testing.B, setBit func(uint32), getBit func(uint32) bool) {
+	var seed uint32
+	b.RunParallel(func(pb *testing.PB) {
+		index := atomic.AddUint32(&seed, 7919)
+		for i := 0; pb.Next(); i++ {
+			index = index*1664525 + 1013904223
+			if i%4 == 0 {
+				setBit(index % benchmarkBitArraySize)
+			} else {
+				getBit(index % benchmarkBitArraySize)
+			}
+		}
+	})
+}
+
+func BenchmarkBitArrayAtomic(b *testing.B) {
+	bits := NewBitArray(benchmarkBitArraySize)
+	benchmarkBitArray(b, bits.SetBit, bits.GetBit)
+}
+
+func BenchmarkBitArrayRWMutex(b *testing.B) {
+	bits := newLockedBitArray(benchmarkBitArraySize)
+	benchmarkBitArray(b, bits.SetBit, bits.GetBit)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// CountMinSketch estimates how often each element has been added
+// (Cormode and Muthukrishnan, 2005). It is a depth x width grid of counters:
+// Add increments one counter per row, and Estimate returns the smallest of
+// the element's counters. Collisions only ever add to a counter, so
+// estimates never undercount. Sized by NewCountMinSketch, an estimate
+// exceeds the true count by at most epsilon * TotalCount() with probability
+// at least 1 - delta.
+//
+// Like BitArray, counters are updated with atomic operations, so concurrent
+// Add and Estimate calls never block each other.
+type CountMinSketch struct {
+	counters      []uint64
+	width         uint32
+	depth         uint32
+	epsilon       float64
+	delta         float64
+	totalCount    uint64
+	hashFunctions []HashFunction
+}
+
+// NewCountMinSketch creates a sketch whose estimates are within
+// epsilon * TotalCount() of the true count with probability 1 - delta
+func NewCountMinSketch(epsilon, delta float64) (*CountMinSketch, error) {
+	if epsilon <= 0.0 || epsilon >= 1.0 {
+		return nil, fmt.Errorf("epsilon must be between 0 and 1")
+	}
+	if delta <= 0.0 || delta >= 1.0 {
+		return nil, fmt.Errorf("delta must be between 0 and 1")
+	}
+
+	width := calculateSketchWidth(epsilon)
+	depth := calculateSketchDepth(delta)
+
+	return &CountMinSketch{
+		counters:      make([]uint64, uint64(width)*uint64(depth)),
+		width:         width,
+		depth:         depth,
+		epsilon:       epsilon,
+		delta:         delta,
+		hashFunctions: defaultHashFunctions(),
+	}, nil
+}
+
+// calculateSketchWidth calculates the counters per row
+func calculateSketchWidth(epsilon float64) uint32 {
+	// w = ceil(e / epsilon)
+	return uint32(math.Ceil(math.E / epsilon))
+}
+
+// calculateSketchDepth calculates the number of rows
+func calculateSketchDepth(delta float64) uint32 {
+	// d = ceil(ln(1 / delta))
+	return uint32(math.Max(1, math.Ceil(math.Log(1/delta))))
+}
+
+// Add records count more occurrences of an element
+func (cms *CountMinSketch) Add(element string, count uint64) {
+	if count == 0 {
+		return
+	}
+
+	for row, column := range hashIndices([]byte(element), cms.hashFunctions, 0, cms.depth, cms.width) {
+		atomic.AddUint64(&cms.counters[uint32(row)*cms.width+column], count)
+	}
+	atomic.AddUint64(&cms.totalCount, count)
+}
+
+// Estimate returns the estimated number of occurrences of an element. It is
+// never lower than the true count.
+func (cms *CountMinSketch) Estimate(element string) uint64 {
+	estimate := uint64(math.MaxUint64)
+	for row, column := range hashIndices([]byte(element), cms.hashFunctions, 0, cms.depth, cms.width) {
+		if count := atomic.LoadUint64(&cms.counters[uint32(row)*cms.width+column]); count < estimate {
+			estimate = count
+		}
+	}
+	return estimate
+}
+
+// TotalCount returns the sum of all counts added
+func (cms *CountMinSketch) TotalCount() uint64 {
+	return atomic.LoadUint64(&cms.totalCount)
+}
+
+// ErrorBound returns the most an estimate is expected to overcount by,
+// epsilon * TotalCount(), which holds with probability 1 - delta
+func (cms *CountMinSketch) ErrorBound() float64 {
+	return cms.epsilon * float64(cms.TotalCount())
+}
+
+// Clear resets every counter
+func (cms *CountMinSketch) Clear() {
+	for i := range cms.counters {
+		atomic.StoreUint64(&cms.counters[i], 0)
+	}
+	atomic.StoreUint64(&cms.totalCount, 0)
+}
+
+// GetMemoryUsage returns memory usage in bytes
+func (cms *CountMinSketch) GetMemoryUsage() uint64 {
+	return uint64(len(cms.counters)) * 8
+}
+
+// Getters for sketch properties
+func (cms *CountMinSketch) GetWidth() uint32 { return cms.width }
+func (cms *CountMinSketch) GetDepth() uint32 { return cms.depth }
+
+// demoCountMinSketch demonstrates estimating word frequencies in a text
+func demoCountMinSketch() {
+	fmt.Println("\n=== Count-Min Sketch Demo ===")
+
+	cms, err := NewCountMinSketch(0.01, 0.01)
+	if err != nil {
+		fmt.Printf("Error creating Count-Min Sketch: %v\n", err)
+		return
+	}
+	fmt.Printf("Sketch: %d rows x %d counters (%d bytes)\n", cms.GetDepth(), cms.GetWidth(), cms.GetMemoryUsage())
+
+	text := `a bloom filter answers whether an element might be in a set while a
+		count min sketch answers how often an element has been seen both trade a
+		small and bounded error for a fixed amount of memory and both use a few
+		hash functions per element the sketch keeps a grid of counters instead of
+		a row of bits and takes the smallest counter as the answer`
+
+	exact := make(map[string]uint64)
+	for repeat := 0; repeat < 50; repeat++ {
+		for _, word := range strings.Fields(text) {
+			cms.Add(word, 1)
+			exact[word]++
+		}
+	}
+	for i := 0; i < 20000; i++ {
+		cms.Add(fmt.Sprintf("rare-%d", i), 1)
+	}
+
+	words := make([]string, 0, len(exact))
+	for word := range exact {
+		words = append(words, word)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if exact[words[i]] != exact[words[j]] {
+			return exact[words[i]] > exact[words[j]]
+		}
+		return words[i] < words[j]
+	})
+
+	fmt.Printf("Total count %d, error bound %.0f (with probability %.2f)\n",
+		cms.TotalCount(), cms.ErrorBound(), 1-cms.delta)
+	for _, word := range words[:8] {
+		fmt.Printf("%-10s exact: %-4d estimate: %d\n", word, exact[word], cms.Estimate(word))
+	}
+
+	withinBound := 0
+	for _, word := range words {
+		if float64(cms.Estimate(word)-exact[word]) <= cms.ErrorBound() {
+			withinBound++
+		}
+	}
+	fmt.Printf("Estimates within the error bound: %d/%d\n", withinBound, len(words))
+	fmt.Printf("Estimate for an unseen word: %d\n", cms.Estimate("hyperloglog"))
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	counterBits     = 4
+	countersPerWord = 64 / counterBits
+	counterMax      = 1<<counterBits - 1
+)
+
+// CountingBloomFilter is a Bloom filter that supports removal. Each position
+// holds a 4-bit counter instead of a single bit; Add increments the element's
+// counters and Remove decrements them. It uses four times the memory of a
+// BloomFilter with the same parameters.
+//
+// Removal brings back the risk of false negatives:
+//   - Removing an element that was never added, but tests positive because of
+//     a false positive, or removing an element more times than it was added,
+//     decrements counters that belong to other elements and can make them
+//     disappear. Remove rejects elements that are definitely absent, but it
+//     cannot detect false positives.
+//   - A counter that reaches 15 saturates and is never changed again, since
+//     its true count is unknown. Saturation cannot cause false negatives, but
+//     elements covering only saturated counters can never be removed.
+//
+// Only remove elements you know were added, once per Add.
+type CountingBloomFilter struct {
+	counters          []uint64
+	size              uint32
+	numHashFunctions  uint32
+	expectedElements  uint32
+	falsePositiveRate float64
+	numElements       uint32
+	saturated         uint32
+	hashFunctions     []HashFunction
+	hashSeed          uint32
+	mu                sync.RWMutex
+}
+
+// NewCountingBloomFilter creates a counting Bloom filter with optimal
+// parameters. It uses the same sizing as NewBloomFilter at four times the memory.
+func NewCountingBloomFilter(expectedElements uint32, falsePositiveRate float64) (*CountingBloomFilter, error) {
+	return newCountingBloomFilter(expectedElements, falsePositiveRate, DefaultMaxBitArraySize)
+}
+
+// newCountingBloomFilter creates a counting Bloom filter with at most
+// maxCounters counters
+func newCountingBloomFilter(expectedElements uint32, falsePositiveRate float64, maxCounters uint64) (*CountingBloomFilter, error) {
+	bf, err := newBloomFilter(expectedElements, falsePositiveRate, maxCounters)
+	if err != nil {
+		return nil, err
+	}
+	return newCountingFromFilter(bf), nil
+}
+
+// newCountingFromFilter creates an empty counting filter with one counter per
+// bit of bf, hashing the same way
+func newCountingFromFilter(bf *BloomFilter) *CountingBloomFilter {
+	return &CountingBloomFilter{
+		counters:          make([]uint64, (uint64(bf.bitArraySize)+countersPerWord-1)/countersPerWord),
+		size:              bf.bitArraySize,
+		numHashFunctions:  bf.numHashFunctions,
+		expectedElements:  bf.expectedElements,
+		falsePositiveRate: bf.falsePositiveRate,
+		hashFunctions:     bf.hashFunctions,
+		hashSeed:          bf.hashSeed,
+	}
+}
+
+// getCounterIndices returns the distinct counter positions for an element, so
+// an element whose hashes collide with each other counts once per position
+func (cbf *CountingBloomFilter) getCounterIndices(element string) []uint32 {
+	indices := hashIndices([]byte(element), cbf.hashFunctions, cbf.hashSeed, cbf.numHashFunctions, cbf.size)
+
+	distinct := indices[:0]
+	for _, index := range indices {
+		seen := false
+		for _, d := range distinct {
+			if d == index {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			distinct = append(distinct, index)
+		}
+	}
+	return distinct
+}
+
+// counter returns the counter at index (must be called with lock held)
+func (cbf *CountingBloomFilter) counter(index uint32) uint64 {
+	shift := (index % countersPerWord) * counterBits
+	return (cbf.counters[index/countersPerWord] >> shift) & counterMax
+}
+
+// setCounter stores value in the counter at index (must be called with lock held)
+func (cbf *CountingBloomFilter) setCounter(index uint32, value uint64) {
+	shift := (index % countersPerWord) * counterBits
+	word := &cbf.counters[index/countersPerWord]
+	*word = (*word &^ (counterMax << shift)) | (value << shift)
+}
+
+// Add adds an element to the filter
+func (cbf *CountingBloomFilter) Add(element string) {
+	indices := cbf.getCounterIndices(element)
+
+	cbf.mu.Lock()
+	defer cbf.mu.Unlock()
+
+	for _, index := range indices {
+		value := cbf.counter(index)
+		if value == counterMax {
+			continue // saturated
+		}
+		cbf.setCounter(index, value+1)
+		if value+1 == counterMax {
+			cbf.saturated++
+		}
+	}
+	cbf.numElements++
+}
+
+// Remove removes an element previously added. It returns an error, and leaves
+// the filter unchanged, if the element is definitely not in the filter.
+func (cbf *CountingBloomFilter) Remove(element string) error {
+	indices := cbf.getCounterIndices(element)
+
+	cbf.mu.Lock()
+	defer cbf.mu.Unlock()
+
+	for _, index := range indices {
+		if cbf.counter(index) == 0 {
+			return fmt.Errorf("element %q is not in the filter", element)
+		}
+	}
+
+	for _, index := range indices {
+		if value := cbf.counter(index); value < counterMax {
+			cbf.setCounter(index, value-1)
+		}
+	}
+	if cbf.numElements > 0 {
+		cbf.numElements--
+	}
+	return nil
+}
+
+// Contains tests if an element might be in the set
+func (cbf *CountingBloomFilter) Contains(element string) bool {
+	indices := cbf.getCounterIndices(element)
+
+	cbf.mu.RLock()
+	defer cbf.mu.RUnlock()
+
+	for _, index := range indices {
+		if cbf.counter(index) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Clear clears all elements from the filter
+func (cbf *CountingBloomFilter) Clear() {
+	cbf.mu.Lock()
+	defer cbf.mu.Unlock()
+
+	for i := range cbf.counters {
+		cbf.counters[i] = 0
+	}
+	cbf.numElements = 0
+	cbf.saturated = 0
+}
+
+// Size returns the number of elements currently in the filter
+func (cbf *CountingBloomFilter) Size() uint32 {
+	cbf.mu.RLock()
+	defer cbf.mu.RUnlock()
+	return cbf.numElements
+}
+
+// SaturatedCounters returns how many counters have hit the 4-bit maximum and
+// are stuck. A non-zero value means Remove can no longer fully clear those
+// positions.
+func (cbf *CountingBloomFilter) SaturatedCounters() uint32 {
+	cbf.mu.RLock()
+	defer cbf.mu.RUnlock()
+	return cbf.saturated
+}
+
+// GetStats returns current statistics. FillRatio is the fraction of non-zero
+// counters, and EstimatedCardinality is derived from the same count.
+func (cbf *CountingBloomFilter) GetStats() *BloomFilterStats {
+	cbf.mu.RLock()
+	defer cbf.mu.RUnlock()
+
+	stats := &BloomFilterStats{
+		BitArraySize:      cbf.size,
+		NumHashFunctions:  cbf.numHashFunctions,
+		NumElements:       cbf.numElements,
+		ExpectedElements:  cbf.expectedElements,
+		FalsePositiveRate: cbf.falsePositiveRate,
+		MemoryUsage:       cbf.GetMemoryUsage(),
+	}
+
+	nonZero := uint32(0)
+	for index := uint32(0); index < cbf.size; index++ {
+		if cbf.counter(index) != 0 {
+			nonZero++
+		}
+	}
+	stats.UpdateFillRatio(nonZero)
+	stats.UpdateEstimatedCardinality(nonZero)
+	return stats
+}
+
+// GetMemoryUsage returns memory usage in bytes
+func (cbf *CountingBloomFilter) GetMemoryUsage() uint32 {
+	return uint32(len(cbf.counters) * 8)
+}
+
+// demoCountingBloomFilter demonstrates removal, the false-negative risk of
+// unbalanced removes, and counter saturation
+func demoCountingBloomFilter() {
+	fmt.Println("\n=== Counting Bloom Filter Demo ===")
+
+	cbf, err := NewBloomFilterBuilder().
+		WithExpectedElements(1000).
+		WithFalsePositiveRate(0.01).
+		BuildCounting()
+	if err != nil {
+		fmt.Printf("Error creating counting Bloom filter: %v\n", err)
+		return
+	}
+	fmt.Printf("Created counting filter: %s\n", cbf.GetStats())
+
+	sessions := []string{"session-a", "session-b", "session-c"}
+	for _, session := range sessions {
+		cbf.Add(session)
+	}
+	fmt.Printf("'session-b' in filter: %t\n", cbf.Contains("session-b"))
+
+	if err := cbf.Remove("session-b"); err != nil {
+		fmt.Printf("Remove failed: %v\n", err)
+	}
+	fmt.Printf("After Remove, 'session-b' in filter: %t\n", cbf.Contains("session-b"))
+	fmt.Printf("'session-a' still in filter: %t\n", cbf.Contains("session-a"))
+
+	if err := cbf.Remove("never-added"); err != nil {
+		fmt.Printf("Removing an absent element is rejected: %v\n", err)
+	}
+
+	// Removing a false positive clears counters owned by real members
+	small, _ := NewCountingBloomFilter(50, 0.1)
+	members := make([]string, 50)
+	for i := range members {
+		members[i] = fmt.Sprintf("member-%d", i)
+		small.Add(members[i])
+	}
+	for i := 0; i < 10000; i++ {
+		impostor := fmt.Sprintf("impostor-%d", i)
+		if !small.Contains(impostor) {
+			continue
+		}
+		small.Remove(impostor)
+		lost := 0
+		for _, member := range members {
+			if !small.Contains(member) {
+				lost++
+			}
+		}
+		fmt.Printf("Removing false positive %q caused %d false negatives\n", impostor, lost)
+		break
+	}
+
+	// Counters saturate instead of overflowing
+	small.Clear()
+	for i := 0; i < 20; i++ {
+		small.Add("hot-key")
+	}
+	fmt.Printf("Saturated counters after 20 adds of one key: %d\n", small.SaturatedCounters())
+	for i := 0; i < 20; i++ {
+		small.Remove("hot-key")
+	}
+	fmt.Printf("'hot-key' after 20 removes (saturated counters never drop): %t\n", small.Contains("hot-key"))
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// newTestCountingFilter builds a counting filter or fails the test
+func newTestCountingFilter(t *testing.T, expectedElements uint32, falsePositiveRate float64) *CountingBloomFilter {
+	t.Helper()
+	cbf, err := NewCountingBloomFilter(expectedElements, falsePositiveRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cbf
+}
+
+func TestCountingRemove(t *testing.T) {
+	cbf := newTestCountingFilter(t, 1000, 0.01)
+	members := make([]string, 100)
+	for i := range members {
+		members[i] = fmt.Sprintf("member-%d", i)
+		cbf.Add(members[i])
+	}
+
+	for _, member := range members[:50] {
+		if err := cbf.Remove(member); err != nil {
+			t.Fatalf("Remove(%q) = %v", member, err)
+		}
+	}
+	if got := cbf.Size(); got != 50 {
+		t.Errorf("Size() = %d, want 50", got)
+	}
+	for _, member := range members[50:] {
+		if !cbf.Contains(member) {
+			t.Errorf("remaining member %q reported absent", member)
+		}
+	}
+
+	// A few removed members may survive as false positives, never most of them
+	stillPresent := 0
+	for _, member := range members[:50] {
+		if cbf.Contains(member) {
+			stillPresent++
+		}
+	}
+	if stillPresent > 5 {
+		t.Errorf("%d of 50 removed members still present", stillPresent)
+	}
+}
+
+func TestCountingRemoveAddedTwice(t *testing.T) {
+	cbf := newTestCountingFilter(t, 1000, 0.01)
+	cbf.Add("twice")
+	cbf.Add("twice")
+
+	if err := cbf.Remove("twice"); err != nil {
+		t.Fatal(err)
+	}
+	if !cbf.Contains("twice") {
+		t.Error("element added twice and removed once reported absent")
+	}
+	if err := cbf.Remove("twice"); err != nil {
+		t.Fatal(err)
+	}
+	if cbf.Contains("twice") {
+		t.Error("element removed as many times as added still present")
+	}
+}
+
+func TestCountingRemoveAbsentRejected(t *testing.T) {
+	cbf := newTestCountingFilter(t, 1000, 0.01)
+	cbf.Add("present")
+	before := append([]uint64(nil), cbf.counters...)
+
+	if err := cbf.Remove("never-added"); err == nil {
+		t.Fatal("Remove of an absent element succeeded")
+	}
+	for i, word := range cbf.counters {
+		if word != before[i] {
+			t.Fatalf("counter word %d changed from %#x to %#x", i, before[i], word)
+		}
+	}
+	if got := cbf.Size(); got != 1 {
+		t.Errorf("Size() = %d, want 1", got)
+	}
+	if !cbf.Contains("present") {
+		t.Error("rejected Remove cleared another element")
+	}
+}
+
+func TestCountingSaturatedCountersNeverDrop(t *testing.T) {
+	cbf := newTestCountingFilter(t, 1000, 0.01)
+	const adds = counterMax + 5
+	for i := 0; i < adds; i++ {
+		cbf.Add("hot-key")
+	}
+
+	indices := cbf.getCounterIndices("hot-key")
+	if got := cbf.SaturatedCounters(); got != uint32(len(indices)) {
+		t.Errorf("SaturatedCounters() = %d, want %d", got, len(indices))
+	}
+
+	for i := 0; i < adds; i++ {
+		if err := cbf.Remove("hot-key"); err != nil {
+			t.Fatalf("Remove #%d = %v", i+1, err)
+		}
+	}
+	for _, index := range indices {
+		if got := cbf.counter(index); got != counterMax {
+			t.Errorf("counter %d = %d after removes, want %d", index, got, counterMax)
+		}
+	}
+	if !cbf.Contains("hot-key") {
+		t.Error("element on saturated counters reported absent")
+	}
+}
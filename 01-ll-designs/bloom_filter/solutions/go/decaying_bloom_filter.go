@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultWindowSlices is how many sub-filters a decaying filter divides its
+// window into unless the builder sets a count
+const DefaultWindowSlices = 4
+
+// DecayingBloomFilter answers "was this seen in the last window?" for a
+// stream that never ends. It keeps a ring of BloomFilter slices, each
+// covering window/slices of time. Add writes to the current slice, Contains
+// checks every slice, and each time a slice's time is up the oldest slice is
+// cleared and becomes the current one. An element is therefore remembered
+// for at least window*(slices-1)/slices and forgotten within window of being
+// added; more slices narrow that gap at the cost of memory.
+//
+// Each slice is sized for expectedElements/slices at falsePositiveRate/slices,
+// so the false positive rate across the ring stays near falsePositiveRate as
+// long as arrivals are roughly steady over the window.
+type DecayingBloomFilter struct {
+	slices        []*BloomFilter
+	current       int
+	sliceDuration time.Duration
+	nextRotation  time.Time
+	now           func() time.Time
+	mu            sync.RWMutex
+}
+
+// DecayingBloomFilterStats summarizes a decaying filter and its slices,
+// oldest first
+type DecayingBloomFilterStats struct {
+	Window                     time.Duration       `json:"window"`
+	NumSlices                  int                 `json:"numSlices"`
+	NumElements                uint32              `json:"numElements"`
+	MemoryUsage                uint32              `json:"memoryUsage"`
+	EstimatedFalsePositiveRate float64             `json:"estimatedFalsePositiveRate"`
+	Slices                     []*BloomFilterStats `json:"slices"`
+}
+
+// String returns a string representation of the stats
+func (s *DecayingBloomFilterStats) String() string {
+	return fmt.Sprintf("DecayingBloomFilterStats{window=%v, slices=%d, elements=%d, "+
+		"estimatedFalsePositiveRate=%.4f, memory=%d bytes}",
+		s.Window, s.NumSlices, s.NumElements, s.EstimatedFalsePositiveRate, s.MemoryUsage)
+}
+
+// WithWindow sets how long BuildDecaying's filter remembers an element
+func (b *BloomFilterBuilder) WithWindow(window time.Duration) *BloomFilterBuilder {
+	b.window = window
+	return b
+}
+
+// WithWindowSlices sets how many sub-filters BuildDecaying divides the
+// window into. It defaults to DefaultWindowSlices.
+func (b *BloomFilterBuilder) WithWindowSlices(slices int) *BloomFilterBuilder {
+	b.windowSlices = slices
+	return b
+}
+
+// BuildDecaying creates a decaying Bloom filter. The expected elements are
+// those added over one window; WithWindow is required.
+func (b *BloomFilterBuilder) BuildDecaying() (*DecayingBloomFilter, error) {
+	if b.expectedElements == nil {
+		return nil, fmt.Errorf("expected elements must be specified")
+	}
+	if b.window <= 0 {
+		return nil, fmt.Errorf("window must be positive")
+	}
+	slices := b.windowSlices
+	if slices == 0 {
+		slices = DefaultWindowSlices
+	}
+	if slices < 1 || time.Duration(slices) > b.window {
+		return nil, fmt.Errorf("window slices must be between 1 and the window in nanoseconds")
+	}
+	// Checked here because dividing could bring an invalid rate into range
+	if !(b.falsePositiveRate > 0.0 && b.falsePositiveRate < 1.0) {
+		return nil, fmt.Errorf("false positive rate must be between 0 and 1")
+	}
+
+	sliceBuilder := *b
+	perSlice := (*b.expectedElements + uint32(slices) - 1) / uint32(slices)
+	sliceBuilder.expectedElements = &perSlice
+	sliceBuilder.falsePositiveRate = b.falsePositiveRate / float64(slices)
+
+	dbf := &DecayingBloomFilter{
+		slices:        make([]*BloomFilter, slices),
+		sliceDuration: b.window / time.Duration(slices),
+		now:           time.Now,
+	}
+	for i := range dbf.slices {
+		slice, err := sliceBuilder.Build()
+		if err != nil {
+			return nil, err
+		}
+		dbf.slices[i] = slice
+	}
+	dbf.nextRotation = dbf.now().Add(dbf.sliceDuration)
+	return dbf, nil
+}
+
+// Add adds an element to the current slice
+func (dbf *DecayingBloomFilter) Add(element string) {
+	dbf.AddBytes([]byte(element))
+}
+
+// AddBytes adds an element given as raw bytes to the current slice
+func (dbf *DecayingBloomFilter) AddBytes(data []byte) {
+	dbf.advance()
+
+	dbf.mu.RLock()
+	defer dbf.mu.RUnlock()
+	dbf.slices[dbf.current].AddBytes(data)
+}
+
+// Contains tests if an element might have been added within the window
+func (dbf *DecayingBloomFilter) Contains(element string) bool {
+	return dbf.ContainsBytes([]byte(element))
+}
+
+// ContainsBytes tests if an element given as raw bytes might have been added
+// within the window, checking slices newest first
+func (dbf *DecayingBloomFilter) ContainsBytes(data []byte) bool {
+	dbf.advance()
+
+	dbf.mu.RLock()
+	defer dbf.mu.RUnlock()
+	for i := 0; i < len(dbf.slices); i++ {
+		if dbf.slices[dbf.sliceAt(i)].ContainsBytes(data) {
+			return true
+		}
+	}
+	return false
+}
+
+// advance clears the slices whose time has run out. Rotation happens lazily
+// on the next Add or Contains, so an idle filter needs no goroutine.
+func (dbf *DecayingBloomFilter) advance() {
+	now := dbf.now()
+
+	dbf.mu.RLock()
+	due := !now.Before(dbf.nextRotation)
+	dbf.mu.RUnlock()
+	if !due {
+		return
+	}
+
+	dbf.mu.Lock()
+	defer dbf.mu.Unlock()
+
+	// After a long idle spell every slice has expired; clearing each once is enough
+	for rotations := 0; !now.Before(dbf.nextRotation); rotations++ {
+		if rotations < len(dbf.slices) {
+			dbf.current = (dbf.current + 1) % len(dbf.slices)
+			dbf.slices[dbf.current].Clear()
+		}
+		dbf.nextRotation = dbf.nextRotation.Add(dbf.sliceDuration)
+	}
+}
+
+// sliceAt returns the index of the slice i steps older than the current one
+// (must be called with lock held)
+func (dbf *DecayingBloomFilter) sliceAt(i int) int {
+	return (dbf.current - i + len(dbf.slices)) % len(dbf.slices)
+}
+
+// Size returns the number of elements added to the slices still in the window
+func (dbf *DecayingBloomFilter) Size() uint32 {
+	dbf.advance()
+
+	dbf.mu.RLock()
+	defer dbf.mu.RUnlock()
+
+	total := uint32(0)
+	for _, slice := range dbf.slices {
+		total += slice.Size()
+	}
+	return total
+}
+
+// GetWindow returns how long an element is remembered for at most
+func (dbf *DecayingBloomFilter) GetWindow() time.Duration {
+	return dbf.sliceDuration * time.Duration(len(dbf.slices))
+}
+
+// GetNumSlices returns the number of sub-filters in the ring
+func (dbf *DecayingBloomFilter) GetNumSlices() int {
+	return len(dbf.slices)
+}
+
+// GetMemoryUsage returns memory usage in bytes across all slices
+func (dbf *DecayingBloomFilter) GetMemoryUsage() uint32 {
+	total := uint32(0)
+	for _, slice := range dbf.slices {
+		total += slice.GetMemoryUsage()
+	}
+	return total
+}
+
+// GetStats returns aggregate statistics. EstimatedFalsePositiveRate is the
+// probability that a lookup matches at least one slice given each slice's
+// current element count.
+func (dbf *DecayingBloomFilter) GetStats() *DecayingBloomFilterStats {
+	dbf.advance()
+
+	dbf.mu.RLock()
+	defer dbf.mu.RUnlock()
+
+	stats := &DecayingBloomFilterStats{
+		Window:    dbf.GetWindow(),
+		NumSlices: len(dbf.slices),
+	}
+	missAll := 1.0
+	for i := len(dbf.slices) - 1; i >= 0; i-- {
+		sliceStats := dbf.slices[dbf.sliceAt(i)].GetStats()
+		stats.Slices = append(stats.Slices, sliceStats)
+		stats.NumElements += sliceStats.NumElements
+		stats.MemoryUsage += sliceStats.MemoryUsage
+		missAll *= 1 - sliceStats.GetActualFalsePositiveRate()
+	}
+	stats.EstimatedFalsePositiveRate = 1 - missAll
+	return stats
+}
+
+// demoDecayingBloomFilter demonstrates "seen in the last hour" deduplication,
+// with a simulated clock so the hour passes instantly
+func demoDecayingBloomFilter() {
+	fmt.Println("\n=== Decaying Bloom Filter Demo ===")
+
+	dbf, err := NewBloomFilterBuilder().
+		WithExpectedElements(10000).
+		WithFalsePositiveRate(0.01).
+		WithWindow(time.Hour).
+		WithWindowSlices(6).
+		BuildDecaying()
+	if err != nil {
+		fmt.Printf("Error creating decaying Bloom filter: %v\n", err)
+		return
+	}
+	start := time.Now()
+	clock := start
+	dbf.now = func() time.Time { return clock }
+
+	dbf.Add("order-1001")
+	fmt.Printf("Window %v in %d slices; added order-1001\n", dbf.GetWindow(), dbf.GetNumSlices())
+
+	for _, elapsed := range []time.Duration{10 * time.Minute, 45 * time.Minute, 55 * time.Minute, 61 * time.Minute} {
+		clock = start.Add(elapsed)
+		fmt.Printf("After %v: order-1001 seen: %t\n", elapsed, dbf.Contains("order-1001"))
+	}
+
+	// A full window of traffic, then the false positive rate against fresh probes
+	for i := 0; i < 10000; i++ {
+		clock = start.Add(time.Hour + time.Duration(i)*time.Hour/10000)
+		dbf.Add(fmt.Sprintf("order-%d", i))
+	}
+	falsePositives, testCount := 0, 10000
+	for i := 0; i < testCount; i++ {
+		if dbf.Contains(fmt.Sprintf("probe-%d", i)) {
+			falsePositives++
+		}
+	}
+	fmt.Println(dbf.GetStats())
+	fmt.Printf("Observed false positive rate over a full window: %.4f\n", float64(falsePositives)/float64(testCount))
+}
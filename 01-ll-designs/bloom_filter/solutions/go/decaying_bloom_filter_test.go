@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newTestDecayingFilter builds a one-hour, six-slice decaying filter whose
+// clock reads *clock, starting from start
+func newTestDecayingFilter(t *testing.T, start time.Time, clock *time.Time) *DecayingBloomFilter {
+	t.Helper()
+	dbf, err := NewBloomFilterBuilder().
+		WithExpectedElements(10000).
+		WithFalsePositiveRate(0.01).
+		WithWindow(time.Hour).
+		WithWindowSlices(6).
+		BuildDecaying()
+	if err != nil {
+		t.Fatal(err)
+	}
+	*clock = start
+	dbf.now = func() time.Time { return *clock }
+	dbf.nextRotation = start.Add(dbf.sliceDuration)
+	return dbf
+}
+
+func TestDecayingElementsAgeOut(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	var clock time.Time
+	dbf := newTestDecayingFilter(t, start, &clock)
+
+	// Added at the start of a slice, an element lasts the whole window;
+	// added at its end, one slice less. Both leave when the slice is reused.
+	dbf.Add("order-1")
+	clock = start.Add(10*time.Minute - time.Nanosecond)
+	dbf.Add("order-2")
+
+	tests := []struct {
+		elapsed      time.Duration
+		want1, want2 bool
+	}{
+		{10*time.Minute - time.Nanosecond, true, true},
+		{30 * time.Minute, true, true},
+		{60*time.Minute - time.Nanosecond, true, true},
+		{60 * time.Minute, false, false},
+	}
+	for _, tt := range tests {
+		clock = start.Add(tt.elapsed)
+		if got := dbf.Contains("order-1"); got != tt.want1 {
+			t.Errorf("after %v: Contains(order-1) = %t, want %t", tt.elapsed, got, tt.want1)
+		}
+		if got := dbf.Contains("order-2"); got != tt.want2 {
+			t.Errorf("after %v: Contains(order-2) = %t, want %t", tt.elapsed, got, tt.want2)
+		}
+	}
+	if got := dbf.Size(); got != 0 {
+		t.Errorf("Size() after both aged out = %d, want 0", got)
+	}
+}
+
+func TestDecayingIdleSpellClearsEverySlice(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	var clock time.Time
+	dbf := newTestDecayingFilter(t, start, &clock)
+
+	for i := 0; i < 6; i++ {
+		clock = start.Add(time.Duration(i) * 10 * time.Minute)
+		dbf.Add(fmt.Sprintf("order-%d", i))
+	}
+	if got := dbf.Size(); got != 6 {
+		t.Fatalf("Size() = %d, want 6", got)
+	}
+
+	clock = start.Add(24 * time.Hour)
+	if got := dbf.Size(); got != 0 {
+		t.Errorf("Size() after a day idle = %d, want 0", got)
+	}
+	for i := 0; i < 6; i++ {
+		if dbf.Contains(fmt.Sprintf("order-%d", i)) {
+			t.Errorf("order-%d still present after a day idle", i)
+		}
+	}
+
+	// The ring keeps rotating on schedule from the new time
+	dbf.Add("fresh")
+	clock = clock.Add(50 * time.Minute)
+	if !dbf.Contains("fresh") {
+		t.Error("element added after the idle spell aged out early")
+	}
+}
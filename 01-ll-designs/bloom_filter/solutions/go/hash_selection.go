@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"reflect"
+)
+
+// ErrCustomHashFunctions is returned by MarshalBinary for a filter built
+// with WithHashFunctions. The binary format pins the default functions.
+var ErrCustomHashFunctions = errors.New("cannot serialize a filter with custom hash functions")
+
+// applyHashOptions replaces the filter's hash functions, index count, and
+// seed with those chosen on the builder, after checking they fit together
+func (b *BloomFilterBuilder) applyHashOptions(bf *BloomFilter) error {
+	if b.hashFunctions != nil {
+		switch {
+		case len(b.hashFunctions) == 0:
+			return fmt.Errorf("at least one hash function must be provided")
+		case len(b.hashFunctions) > 2:
+			return fmt.Errorf("at most two hash functions can be used, got %d: "+
+				"every index is derived from two base hashes", len(b.hashFunctions))
+		}
+		for i, f := range b.hashFunctions {
+			if f == nil {
+				return fmt.Errorf("hash function %d is nil", i)
+			}
+		}
+		bf.hashFunctions = append([]HashFunction(nil), b.hashFunctions...)
+		bf.customHashes = true
+	}
+
+	if b.hashCount > 0 {
+		bf.numHashFunctions = b.hashCount
+	}
+	bf.hashSeed = b.hashSeed
+	return nil
+}
+
+// sameHashFunctions reports whether two filters hash with the same
+// functions. Functions can't be compared directly, so their code pointers
+// are; two closures over different state would compare equal.
+func sameHashFunctions(a, b []HashFunction) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if reflect.ValueOf(a[i]).Pointer() != reflect.ValueOf(b[i]).Pointer() {
+			return false
+		}
+	}
+	return true
+}
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// crc32Hash is an example of a custom hash function: CRC-32C of the data
+// followed by the seed
+var crc32Hash HashFunction = func(data []byte, seed uint32) uint32 {
+	var seedBytes [4]byte
+	binary.LittleEndian.PutUint32(seedBytes[:], seed)
+	return crc32.Update(crc32.Checksum(data, castagnoliTable), castagnoliTable, seedBytes[:])
+}
+
+// demoHashSelection builds filters with different hash functions and index
+// counts and compares their false positive rates
+func demoHashSelection() {
+	fmt.Println("\n=== Hash Function Selection ===")
+
+	const elements = 10000
+	configs := []struct {
+		name    string
+		builder *BloomFilterBuilder
+	}{
+		{"default (murmur3 + fnv)", NewBloomFilterBuilder()},
+		{"sha1 only", NewBloomFilterBuilder().WithHashFunctions(sha1Hash)},
+		{"crc32c + djb2 (custom)", NewBloomFilterBuilder().WithHashFunctions(crc32Hash, djb2Hash)},
+		{"default, k=3", NewBloomFilterBuilder().WithHashCount(3)},
+		{"default, seed 42", NewBloomFilterBuilder().WithSeed(42)},
+	}
+
+	var filters []*BloomFilter
+	for _, config := range configs {
+		bf, err := config.builder.WithExpectedElements(elements).Build()
+		if err != nil {
+			fmt.Printf("%-24s ERROR: %v\n", config.name, err)
+			continue
+		}
+		for i := 0; i < elements; i++ {
+			bf.Add(fmt.Sprintf("member-%d", i))
+		}
+
+		probes, falsePositives := 100000, 0
+		for i := 0; i < probes; i++ {
+			if bf.Contains(fmt.Sprintf("probe-%d", i)) {
+				falsePositives++
+			}
+		}
+		fmt.Printf("%-24s k=%d, expected %.4f, observed %.4f\n", config.name,
+			bf.GetNumHashFunctions(), bf.GetFalsePositiveRate(), float64(falsePositives)/float64(probes))
+		filters = append(filters, bf)
+	}
+
+	invalid := []struct {
+		name    string
+		builder *BloomFilterBuilder
+	}{
+		{"No functions", NewBloomFilterBuilder().WithHashFunctions()},
+		{"Three functions", NewBloomFilterBuilder().WithHashFunctions(murmurHash3, fnvHash, djb2Hash)},
+	}
+	for _, c := range invalid {
+		_, err := c.builder.WithExpectedElements(elements).Build()
+		fmt.Printf("%-24s -> %v\n", c.name, err)
+	}
+	if len(filters) >= 3 {
+		_, err := filters[2].MarshalBinary()
+		fmt.Printf("Serializing the custom filter -> %v\n", err)
+		fmt.Printf("Union of default and custom filters -> %v\n", filters[0].Union(filters[2]))
+	}
+}
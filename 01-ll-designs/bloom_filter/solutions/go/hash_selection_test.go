@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestWithSeedChangesBits(t *testing.T) {
+	build := func(builder *BloomFilterBuilder) *BloomFilter {
+		t.Helper()
+		bf, err := builder.WithExpectedElements(1000).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 1000; i++ {
+			bf.Add("member-" + strconv.Itoa(i))
+		}
+		return bf
+	}
+
+	unseeded := build(NewBloomFilterBuilder())
+	zero := build(NewBloomFilterBuilder().WithSeed(0))
+	seeded := build(NewBloomFilterBuilder().WithSeed(42))
+
+	if err := unseeded.Union(zero); err != nil {
+		t.Errorf("seed 0 filter is incompatible with the default: %v", err)
+	}
+	if err := unseeded.Union(seeded); !errors.Is(err, ErrIncompatibleFilters) {
+		t.Errorf("Union across seeds: %v, want ErrIncompatibleFilters", err)
+	}
+
+	// Same elements, different bits
+	differ := 0
+	seededWords := seeded.bitArray.Snapshot()
+	for i, word := range zero.bitArray.Snapshot() {
+		if word != seededWords[i] {
+			differ++
+		}
+	}
+	if differ == 0 {
+		t.Error("seed 42 set the same bits as seed 0")
+	}
+	for i := 0; i < 1000; i++ {
+		if !seeded.Contains("member-" + strconv.Itoa(i)) {
+			t.Fatalf("seeded filter lost member-%d", i)
+		}
+	}
+
+	// The seed survives serialization
+	data, err := seeded.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadBloomFilter(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Contains("member-7") || loaded.Union(seeded) != nil {
+		t.Error("loaded filter doesn't hash like the one it was saved from")
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+)
+
+// AddHashable adds any value that can encode itself to bytes, such as
+// time.Time or a caller's own key type. Values are identified by their
+// MarshalBinary output, so equal encodings are the same element.
+func (bf *BloomFilter) AddHashable(value encoding.BinaryMarshaler) error {
+	data, err := value.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("marshal element: %w", err)
+	}
+	bf.AddBytes(data)
+	return nil
+}
+
+// ContainsHashable tests if a value added with AddHashable might be in the set
+func (bf *BloomFilter) ContainsHashable(value encoding.BinaryMarshaler) (bool, error) {
+	data, err := value.MarshalBinary()
+	if err != nil {
+		return false, fmt.Errorf("marshal element: %w", err)
+	}
+	return bf.ContainsBytes(data), nil
+}
+
+// userID is an example key type for AddHashable
+type userID uint64
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (id userID) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(id))
+	return data, nil
+}
+
+// demoHashable demonstrates the byte and BinaryMarshaler APIs. The string
+// and byte paths are compared by BenchmarkContainsString and
+// BenchmarkContainsBytes.
+func demoHashable() {
+	fmt.Println("\n=== Bytes and Hashable Values Demo ===")
+
+	bf, _ := NewBloomFilter(10000, 0.01)
+	bf.AddBytes([]byte{0xde, 0xad, 0xbe, 0xef})
+	bf.AddHashable(userID(42))
+
+	fmt.Printf("0xdeadbeef in filter: %t\n", bf.ContainsBytes([]byte{0xde, 0xad, 0xbe, 0xef}))
+	found, _ := bf.ContainsHashable(userID(42))
+	fmt.Printf("userID(42) in filter: %t\n", found)
+	found, _ = bf.ContainsHashable(userID(43))
+	fmt.Printf("userID(43) in filter: %t\n", found)
+}
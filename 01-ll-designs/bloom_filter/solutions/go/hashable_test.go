@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestHashableRoundTrip(t *testing.T) {
+	bf, _ := NewBloomFilter(10000, 0.01)
+	if err := bf.AddHashable(userID(42)); err != nil {
+		t.Fatalf("AddHashable: %v", err)
+	}
+
+	found, err := bf.ContainsHashable(userID(42))
+	if err != nil || !found {
+		t.Errorf("ContainsHashable(42) = %t, %v; want true, nil", found, err)
+	}
+	// The same encoding through the byte API is the same element
+	encoded, _ := userID(42).MarshalBinary()
+	if !bf.ContainsBytes(encoded) {
+		t.Error("ContainsBytes of userID(42)'s encoding = false, want true")
+	}
+}
+
+// benchmarkKeys returns 1024 string keys and the same keys as bytes
+func benchmarkKeys() ([]string, [][]byte) {
+	keys := make([]string, 1024)
+	keyBytes := make([][]byte, len(keys))
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+		keyBytes[i] = []byte(keys[i])
+	}
+	return keys, keyBytes
+}
+
+func BenchmarkContainsString(b *testing.B) {
+	bf, _ := NewBloomFilter(10000, 0.01)
+	keys, _ := benchmarkKeys()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bf.Contains(keys[i%len(keys)])
+	}
+}
+
+func BenchmarkContainsBytes(b *testing.B) {
+	bf, _ := NewBloomFilter(10000, 0.01)
+	_, keyBytes := benchmarkKeys()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bf.ContainsBytes(keyBytes[i%len(keyBytes)])
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+const (
+	// DefaultGrowthFactor is how much larger each new slice is than the last
+	DefaultGrowthFactor = 2
+	// DefaultTighteningRatio scales the false positive rate of each new slice
+	DefaultTighteningRatio = 0.8
+)
+
+// ScalableBloomFilter grows as elements are added instead of degrading once
+// an initial capacity is exceeded (Almeida et al., "Scalable Bloom Filters",
+// 2007). It chains BloomFilter slices: when the newest slice reaches its
+// expected element count, a slice DefaultGrowthFactor times larger with a
+// false positive rate DefaultTighteningRatio times the last is added. The rates
+// form a geometric series, so the overall false positive rate stays below
+// the requested bound however many slices are added.
+type ScalableBloomFilter struct {
+	slices            []*BloomFilter
+	falsePositiveRate float64
+	growthFactor      uint32
+	tighteningRatio   float64
+	mu                sync.RWMutex
+}
+
+// ScalableBloomFilterStats summarizes a scalable filter and its slices
+type ScalableBloomFilterStats struct {
+	NumSlices                  int                 `json:"numSlices"`
+	NumElements                uint32              `json:"numElements"`
+	Capacity                   uint32              `json:"capacity"`
+	MemoryUsage                uint32              `json:"memoryUsage"`
+	FalsePositiveBound         float64             `json:"falsePositiveBound"`
+	EstimatedFalsePositiveRate float64             `json:"estimatedFalsePositiveRate"`
+	Slices                     []*BloomFilterStats `json:"slices"`
+}
+
+// String returns a string representation of the stats
+func (s *ScalableBloomFilterStats) String() string {
+	return fmt.Sprintf("ScalableBloomFilterStats{slices=%d, elements=%d/%d, "+
+		"falsePositiveBound=%.4f, estimatedFalsePositiveRate=%.4f, memory=%d bytes}",
+		s.NumSlices, s.NumElements, s.Capacity, s.FalsePositiveBound, s.EstimatedFalsePositiveRate, s.MemoryUsage)
+}
+
+// NewScalableBloomFilter creates a scalable filter whose first slice holds
+// initialCapacity elements and whose overall false positive rate stays below
+// falsePositiveRate
+func NewScalableBloomFilter(initialCapacity uint32, falsePositiveRate float64) (*ScalableBloomFilter, error) {
+	sbf := &ScalableBloomFilter{
+		falsePositiveRate: falsePositiveRate,
+		growthFactor:      DefaultGrowthFactor,
+		tighteningRatio:   DefaultTighteningRatio,
+	}
+
+	// P = P0 / (1 - r), so the first slice gets P0 = P * (1 - r)
+	first, err := NewBloomFilter(initialCapacity, falsePositiveRate*(1-sbf.tighteningRatio))
+	if err != nil {
+		return nil, err
+	}
+	sbf.slices = []*BloomFilter{first}
+	return sbf, nil
+}
+
+// Add adds an element to the newest slice, adding a slice first if it is
+// full. Elements that already test positive are skipped so duplicates don't
+// use up capacity.
+func (sbf *ScalableBloomFilter) Add(element string) error {
+	sbf.mu.Lock()
+	defer sbf.mu.Unlock()
+
+	if sbf.contains(element) {
+		return nil
+	}
+
+	newest := sbf.slices[len(sbf.slices)-1]
+	if newest.Size() >= newest.GetExpectedElements() {
+		capacity := uint64(newest.GetExpectedElements()) * uint64(sbf.growthFactor)
+		if capacity > math.MaxUint32 {
+			return fmt.Errorf("scalable bloom filter cannot grow past %d elements per slice", uint32(math.MaxUint32))
+		}
+		next, err := NewBloomFilter(uint32(capacity), newest.falsePositiveRate*sbf.tighteningRatio)
+		if err != nil {
+			return err
+		}
+		sbf.slices = append(sbf.slices, next)
+		newest = next
+	}
+
+	newest.Add(element)
+	return nil
+}
+
+// Contains tests if an element might be in any slice
+func (sbf *ScalableBloomFilter) Contains(element string) bool {
+	sbf.mu.RLock()
+	defer sbf.mu.RUnlock()
+	return sbf.contains(element)
+}
+
+// contains checks slices newest first (must be called with lock held)
+func (sbf *ScalableBloomFilter) contains(element string) bool {
+	for i := len(sbf.slices) - 1; i >= 0; i-- {
+		if sbf.slices[i].Contains(element) {
+			return true
+		}
+	}
+	return false
+}
+
+// Size returns the number of elements added across all slices
+func (sbf *ScalableBloomFilter) Size() uint32 {
+	sbf.mu.RLock()
+	defer sbf.mu.RUnlock()
+
+	total := uint32(0)
+	for _, slice := range sbf.slices {
+		total += slice.Size()
+	}
+	return total
+}
+
+// GetStats returns aggregate statistics. FalsePositiveBound is the sum of the
+// slices' target rates, which never exceeds the rate the filter was created
+// with. EstimatedFalsePositiveRate is the probability that a lookup matches
+// at least one slice given each slice's current element count.
+func (sbf *ScalableBloomFilter) GetStats() *ScalableBloomFilterStats {
+	sbf.mu.RLock()
+	defer sbf.mu.RUnlock()
+
+	stats := &ScalableBloomFilterStats{NumSlices: len(sbf.slices)}
+	missAll := 1.0
+	for _, slice := range sbf.slices {
+		sliceStats := slice.GetStats()
+		stats.Slices = append(stats.Slices, sliceStats)
+		stats.NumElements += sliceStats.NumElements
+		stats.Capacity += sliceStats.ExpectedElements
+		stats.MemoryUsage += sliceStats.MemoryUsage
+		stats.FalsePositiveBound += sliceStats.FalsePositiveRate
+		missAll *= 1 - sliceStats.GetActualFalsePositiveRate()
+	}
+	stats.EstimatedFalsePositiveRate = 1 - missAll
+	return stats
+}
+
+// GetMemoryUsage returns memory usage in bytes across all slices
+func (sbf *ScalableBloomFilter) GetMemoryUsage() uint32 {
+	sbf.mu.RLock()
+	defer sbf.mu.RUnlock()
+
+	total := uint32(0)
+	for _, slice := range sbf.slices {
+		total += slice.GetMemoryUsage()
+	}
+	return total
+}
+
+// demoScalableBloomFilter demonstrates a filter growing far past its initial capacity
+func demoScalableBloomFilter() {
+	fmt.Println("\n=== Scalable Bloom Filter Demo ===")
+
+	sbf, err := NewScalableBloomFilter(1000, 0.01)
+	if err != nil {
+		fmt.Printf("Error creating scalable Bloom filter: %v\n", err)
+		return
+	}
+	fixed, _ := NewBloomFilter(1000, 0.01)
+
+	for i := 0; i < 20000; i++ {
+		element := fmt.Sprintf("item-%d", i)
+		sbf.Add(element)
+		fixed.Add(element)
+	}
+	fmt.Println(sbf.GetStats())
+
+	falsePositives, fixedFalsePositives := 0, 0
+	testCount := 10000
+	for i := 0; i < testCount; i++ {
+		probe := fmt.Sprintf("probe-%d", i)
+		if sbf.Contains(probe) {
+			falsePositives++
+		}
+		if fixed.Contains(probe) {
+			fixedFalsePositives++
+		}
+	}
+	fmt.Printf("Observed false positive rate after 20x capacity: scalable %.4f, fixed %.4f\n",
+		float64(falsePositives)/float64(testCount), float64(fixedFalsePositives)/float64(testCount))
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// Binary format (all integers little-endian):
+//
+//	magic             [4]byte "BLMF"
+//	version           uint8
+//	bitArraySize      uint32
+//	numHashFunctions  uint32
+//	expectedElements  uint32
+//	falsePositiveRate float64
+//	numElements       uint32
+//	hashSeed          uint32
+//	bits              [(bitArraySize+63)/64]uint64
+//
+// Hash functions cannot be serialized; the version number pins the set and
+// order used by defaultHashFunctions and how bit indices are derived from
+// them. Version 1 used one hash function per index and version 2 stored a
+// seed per index although only the first keyed the hashes; version 3 stores
+// that one seed. Neither older version can be loaded.
+const (
+	serializationMagic   = "BLMF"
+	serializationVersion = 3
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*BloomFilter)(nil)
+	_ encoding.BinaryUnmarshaler = (*BloomFilter)(nil)
+)
+
+// ErrInvalidFormat is returned when serialized data is not a Bloom filter
+var ErrInvalidFormat = errors.New("invalid Bloom filter data")
+
+// serializedHeader is the fixed-size part of the binary format
+type serializedHeader struct {
+	Magic             [4]byte
+	Version           uint8
+	BitArraySize      uint32
+	NumHashFunctions  uint32
+	ExpectedElements  uint32
+	FalsePositiveRate float64
+	NumElements       uint32
+	HashSeed          uint32
+}
+
+// MarshalBinary encodes the filter in a compact, versioned binary format.
+// Filters built with custom hash functions can't be encoded, since they
+// would be decoded with the default ones.
+func (bf *BloomFilter) MarshalBinary() ([]byte, error) {
+	if bf.customHashes {
+		return nil, ErrCustomHashFunctions
+	}
+	header := serializedHeader{
+		Version:           serializationVersion,
+		BitArraySize:      bf.bitArraySize,
+		NumHashFunctions:  bf.numHashFunctions,
+		ExpectedElements:  bf.expectedElements,
+		FalsePositiveRate: bf.falsePositiveRate,
+		NumElements:       atomic.LoadUint32(&bf.numElements),
+		HashSeed:          bf.hashSeed,
+	}
+	copy(header.Magic[:], serializationMagic)
+
+	words := bf.bitArray.Snapshot()
+
+	var buf bytes.Buffer
+	buf.Grow(binary.Size(header) + 8*len(words))
+	for _, part := range []interface{}{header, words} {
+		if err := binary.Write(&buf, binary.LittleEndian, part); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the filter's contents with data produced by MarshalBinary
+func (bf *BloomFilter) UnmarshalBinary(data []byte) error {
+	reader := bytes.NewReader(data)
+
+	var header serializedHeader
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil ||
+		string(header.Magic[:]) != serializationMagic {
+		return ErrInvalidFormat
+	}
+	if header.Version != serializationVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidFormat, header.Version)
+	}
+
+	numWords := (uint64(header.BitArraySize) + 63) / 64
+	if header.BitArraySize == 0 || header.NumHashFunctions == 0 ||
+		uint64(reader.Len()) != 8*numWords {
+		return fmt.Errorf("%w: truncated or inconsistent sizes", ErrInvalidFormat)
+	}
+
+	bitArray := NewBitArray(header.BitArraySize)
+	if err := binary.Read(reader, binary.LittleEndian, bitArray.bits); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+
+	*bf = BloomFilter{
+		bitArray:          bitArray,
+		bitArraySize:      header.BitArraySize,
+		numHashFunctions:  header.NumHashFunctions,
+		expectedElements:  header.ExpectedElements,
+		falsePositiveRate: header.FalsePositiveRate,
+		numElements:       header.NumElements,
+		hashFunctions:     defaultHashFunctions(),
+		hashSeed:          header.HashSeed,
+	}
+	return nil
+}
+
+// LoadBloomFilter decodes a filter produced by MarshalBinary
+func LoadBloomFilter(data []byte) (*BloomFilter, error) {
+	bf := &BloomFilter{}
+	if err := bf.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return bf, nil
+}
+
+// demoSerialization demonstrates sharing a filter by serializing it
+func demoSerialization() {
+	fmt.Println("\n=== Serialization Demo ===")
+
+	bf, _ := NewBloomFilter(10000, 0.01)
+	for i := 0; i < 5000; i++ {
+		bf.Add(fmt.Sprintf("user-%d", i))
+	}
+
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		fmt.Printf("Error serializing filter: %v\n", err)
+		return
+	}
+	fmt.Printf("Serialized %d elements into %d bytes (bit array: %d bytes)\n",
+		bf.Size(), len(data), bf.GetMemoryUsage())
+
+	loaded, err := LoadBloomFilter(data)
+	if err != nil {
+		fmt.Printf("Error loading filter: %v\n", err)
+		return
+	}
+
+	// Every answer, including false positives, must match the original
+	mismatches := 0
+	for i := 0; i < 20000; i++ {
+		element := fmt.Sprintf("user-%d", i)
+		if bf.Contains(element) != loaded.Contains(element) {
+			mismatches++
+		}
+	}
+	fmt.Printf("Loaded filter: %s\n", loaded.GetStats())
+	fmt.Printf("Contains mismatches across 20000 probes: %d\n", mismatches)
+
+	if _, err := LoadBloomFilter([]byte("not a filter")); err != nil {
+		fmt.Printf("Loading garbage fails: %v\n", err)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSerializationRoundTrip(t *testing.T) {
+	bf, err := NewBloomFilterBuilder().WithExpectedElements(10000).WithSeed(7).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5000; i++ {
+		bf.Add(fmt.Sprintf("user-%d", i))
+	}
+
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadBloomFilter(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := *loaded.GetStats(), *bf.GetStats(); got != want {
+		t.Errorf("loaded stats = %v, want %v", &got, &want)
+	}
+	// Every answer, false positives included, must match the original
+	for i := 0; i < 20000; i++ {
+		element := fmt.Sprintf("user-%d", i)
+		if got, want := loaded.Contains(element), bf.Contains(element); got != want {
+			t.Fatalf("loaded.Contains(%q) = %t, want %t", element, got, want)
+		}
+	}
+	if err := bf.Union(loaded); err != nil {
+		t.Errorf("Union with loaded filter = %v, want nil", err)
+	}
+}
+
+func TestUnmarshalRejectsOldVersions(t *testing.T) {
+	bf, _ := NewBloomFilter(1000, 0.01)
+	bf.Add("element")
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, version := range []uint8{1, 2, serializationVersion + 1} {
+		old := append([]byte(nil), data...)
+		old[len(serializationMagic)] = version
+		if _, err := LoadBloomFilter(old); !errors.Is(err, ErrInvalidFormat) {
+			t.Errorf("version %d: err = %v, want ErrInvalidFormat", version, err)
+		}
+	}
+}
+
+func TestUnmarshalRejectsMalformedData(t *testing.T) {
+	bf, _ := NewBloomFilter(1000, 0.01)
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	badMagic := append([]byte(nil), data...)
+	badMagic[0] = 'X'
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"garbage", []byte("not a filter")},
+		{"bad magic", badMagic},
+		{"truncated", data[:len(data)-1]},
+		{"trailing bytes", append(append([]byte(nil), data...), 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadBloomFilter(tt.data); !errors.Is(err, ErrInvalidFormat) {
+				t.Errorf("err = %v, want ErrInvalidFormat", err)
+			}
+		})
+	}
+}
+
+func TestMarshalRejectsCustomHashes(t *testing.T) {
+	bf, err := NewBloomFilterBuilder().WithExpectedElements(1000).WithHashFunctions(crc32Hash, djb2Hash).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bf.MarshalBinary(); !errors.Is(err, ErrCustomHashFunctions) {
+		t.Errorf("err = %v, want ErrCustomHashFunctions", err)
+	}
+}
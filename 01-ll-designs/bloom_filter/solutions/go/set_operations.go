@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+)
+
+// ErrIncompatibleFilters is returned when combining filters that were not
+// built with the same size, hash function count, and seeds
+var ErrIncompatibleFilters = errors.New("bloom filters are not compatible")
+
+// Or sets every bit that is set in other. Words are combined one at a time
+// with atomic operations, so concurrent Adds to either array are never lost.
+func (ba *BitArray) Or(other *BitArray) error {
+	return ba.combine(other, func(a, b uint64) uint64 { return a | b })
+}
+
+// And clears every bit that is not set in other, one word at a time like Or
+func (ba *BitArray) And(other *BitArray) error {
+	return ba.combine(other, func(a, b uint64) uint64 { return a & b })
+}
+
+// combine applies op word by word against a snapshot of other
+func (ba *BitArray) combine(other *BitArray, op func(a, b uint64) uint64) error {
+	if ba.size != other.size {
+		return fmt.Errorf("bit array sizes differ: %d vs %d", ba.size, other.size)
+	}
+	if ba == other {
+		return nil // x|x and x&x are both x
+	}
+
+	for i, operand := range other.Snapshot() {
+		ba.updateWord(uint32(i), func(word uint64) uint64 { return op(word, operand) })
+	}
+	return nil
+}
+
+// compatible checks that other hashes elements to the same positions
+func (bf *BloomFilter) compatible(other *BloomFilter) error {
+	if other == nil {
+		return fmt.Errorf("%w: other filter is nil", ErrIncompatibleFilters)
+	}
+	if bf.bitArraySize != other.bitArraySize || bf.numHashFunctions != other.numHashFunctions {
+		return fmt.Errorf("%w: size %d/%d hash functions vs %d/%d", ErrIncompatibleFilters,
+			bf.bitArraySize, bf.numHashFunctions, other.bitArraySize, other.numHashFunctions)
+	}
+	if bf.hashSeed != other.hashSeed {
+		return fmt.Errorf("%w: hash seed %d vs %d", ErrIncompatibleFilters, bf.hashSeed, other.hashSeed)
+	}
+	if !sameHashFunctions(bf.hashFunctions, other.hashFunctions) {
+		return fmt.Errorf("%w: hash functions differ", ErrIncompatibleFilters)
+	}
+	return nil
+}
+
+// Union adds every element of other to the filter by ORing the bit arrays.
+// The result is exactly the filter that adding both sets would have built.
+// The element count becomes the sum of both counts, an upper bound since
+// elements in both filters are counted twice.
+func (bf *BloomFilter) Union(other *BloomFilter) error {
+	if err := bf.compatible(other); err != nil {
+		return err
+	}
+	if err := bf.bitArray.Or(other.bitArray); err != nil {
+		return err
+	}
+
+	sum := uint64(atomic.LoadUint32(&bf.numElements)) + uint64(atomic.LoadUint32(&other.numElements))
+	atomic.StoreUint32(&bf.numElements, uint32(math.Min(float64(sum), math.MaxUint32)))
+	return nil
+}
+
+// Intersect keeps only bits set in both filters by ANDing the bit arrays.
+// Every element in both sets still tests positive, but the result
+// over-estimates the intersection: a bit can survive because different
+// elements set it in each filter, so the false positive rate is higher than
+// a filter built from the true intersection. The element count becomes the
+// smaller of the two counts, an upper bound on the intersection size.
+func (bf *BloomFilter) Intersect(other *BloomFilter) error {
+	if err := bf.compatible(other); err != nil {
+		return err
+	}
+	if err := bf.bitArray.And(other.bitArray); err != nil {
+		return err
+	}
+
+	if count := atomic.LoadUint32(&other.numElements); count < atomic.LoadUint32(&bf.numElements) {
+		atomic.StoreUint32(&bf.numElements, count)
+	}
+	return nil
+}
+
+// demoSetOperations demonstrates reconciling two replicas' filters
+func demoSetOperations() {
+	fmt.Println("\n=== Union / Intersect Demo ===")
+
+	replicaA, _ := NewBloomFilter(1000, 0.01)
+	replicaB, _ := NewBloomFilter(1000, 0.01)
+	for _, key := range []string{"order-1", "order-2", "order-3"} {
+		replicaA.Add(key)
+	}
+	for _, key := range []string{"order-3", "order-4"} {
+		replicaB.Add(key)
+	}
+
+	union, _ := NewBloomFilter(1000, 0.01)
+	union.Union(replicaA)
+	union.Union(replicaB)
+
+	intersection, _ := NewBloomFilter(1000, 0.01)
+	intersection.Union(replicaA)
+	intersection.Intersect(replicaB)
+
+	for _, key := range []string{"order-1", "order-3", "order-4", "order-9"} {
+		fmt.Printf("%-8s union: %-5t intersection: %t\n", key, union.Contains(key), intersection.Contains(key))
+	}
+	fmt.Printf("Union size estimate: %d, intersection size estimate: %d\n", union.Size(), intersection.Size())
+
+	other, _ := NewBloomFilter(50, 0.01)
+	if err := union.Union(other); err != nil {
+		fmt.Printf("Union with a differently sized filter fails: %v\n", err)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// DefaultMaxBitArraySize is the largest bit array, 2^30 bits or 128 MiB,
+// that NewBloomFilter and NewCountingBloomFilter will allocate. The builder's
+// WithMaxBitArraySize raises or lowers it.
+const DefaultMaxBitArraySize uint64 = 1 << 30
+
+// ErrFilterTooLarge is returned when the requested elements and false
+// positive rate need a bit array larger than the allowed maximum
+var ErrFilterTooLarge = errors.New("bloom filter too large")
+
+// sizeBitArray validates the parameters and returns the optimal bit array
+// size. The size is computed and checked before it is narrowed to uint32,
+// so a request that needs more than maxBitArraySize bits, or more than bit
+// indices can address, fails instead of wrapping to a small filter.
+func sizeBitArray(expectedElements uint32, falsePositiveRate float64, maxBitArraySize uint64) (uint32, error) {
+	if expectedElements == 0 {
+		return 0, fmt.Errorf("expected elements must be positive")
+	}
+	// Written so NaN fails too
+	if !(falsePositiveRate > 0.0 && falsePositiveRate < 1.0) {
+		return 0, fmt.Errorf("false positive rate must be between 0 and 1")
+	}
+	if maxBitArraySize == 0 || maxBitArraySize > math.MaxUint32 {
+		return 0, fmt.Errorf("max bit array size must be between 1 and %d", uint64(math.MaxUint32))
+	}
+
+	size := calculateBitArraySize(expectedElements, falsePositiveRate)
+	if size > float64(maxBitArraySize) {
+		return 0, fmt.Errorf("%w: %d elements at false positive rate %g need %.0f bits (%s), limit is %d bits (%s)",
+			ErrFilterTooLarge, expectedElements, falsePositiveRate,
+			size, formatBytes(size/8), maxBitArraySize, formatBytes(float64(maxBitArraySize)/8))
+	}
+	return uint32(size), nil
+}
+
+// formatBytes renders a byte count in the largest binary unit below it
+func formatBytes(bytes float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	unit := 0
+	for bytes >= 1024 && unit < len(units)-1 {
+		bytes /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s", bytes, units[unit])
+}
+
+// demoSizeLimits shows oversized and invalid parameters failing cleanly
+// rather than allocating gigabytes or wrapping to a tiny, corrupt filter
+func demoSizeLimits() {
+	fmt.Println("\n=== Size Limits Demo ===")
+
+	cases := []struct {
+		name              string
+		expectedElements  uint32
+		falsePositiveRate float64
+	}{
+		{"1e8 elements at 1e-9", 100000000, 1e-9},
+		{"max elements at 1e-3", math.MaxUint32, 1e-3},
+		{"2e8 elements at 1%", 200000000, 0.01},
+		{"NaN false positive rate", 1000, math.NaN()},
+		{"zero elements", 0, 0.01},
+	}
+	for _, c := range cases {
+		bits := calculateBitArraySize(c.expectedElements, c.falsePositiveRate)
+		_, err := NewBloomFilter(c.expectedElements, c.falsePositiveRate)
+		fmt.Printf("%-24s -> %v\n", c.name, err)
+		if errors.Is(err, ErrFilterTooLarge) && bits > math.MaxUint32 {
+			fmt.Printf("%-24s    (a uint32 size would have wrapped to %d bits)\n", "", uint32(uint64(bits)))
+		}
+	}
+
+	// The builder can allow more than the default, up to what uint32
+	// indices address
+	_, err := NewBloomFilterBuilder().
+		WithExpectedElements(100000000).
+		WithFalsePositiveRate(1e-9).
+		WithMaxBitArraySize(math.MaxUint32).
+		Build()
+	fmt.Printf("1e8 at 1e-9, limit raised to 2^32-1 bits -> %v\n", err)
+
+	_, err = NewBloomFilterBuilder().
+		WithExpectedElements(1000).
+		WithMaxBitArraySize(1 << 40).
+		Build()
+	fmt.Printf("Limit above 2^32-1 bits -> %v\n", err)
+
+	bf, err := NewBloomFilterBuilder().
+		WithExpectedElements(10000).
+		WithMaxBitArraySize(95851).
+		Build()
+	if err == nil {
+		fmt.Printf("10000 at 1%% with a limit of exactly the 95851 bits it needs -> ok, %d bits\n", bf.GetBitArraySize())
+	}
+	_, err = NewBloomFilterBuilder().
+		WithExpectedElements(10000).
+		WithMaxBitArraySize(95850).
+		Build()
+	fmt.Printf("One bit less -> %v\n", err)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestNewBloomFilterLimits(t *testing.T) {
+	tests := []struct {
+		name              string
+		expectedElements  uint32
+		falsePositiveRate float64
+		wantTooLarge      bool
+		wantErr           bool
+	}{
+		{"1e8 elements at 1e-9", 100000000, 1e-9, true, true},
+		{"max elements at 1e-3", math.MaxUint32, 1e-3, true, true},
+		{"2e8 elements at 1%", 200000000, 0.01, true, true},
+		{"NaN false positive rate", 1000, math.NaN(), false, true},
+		{"zero false positive rate", 1000, 0, false, true},
+		{"false positive rate of 1", 1000, 1, false, true},
+		{"negative false positive rate", 1000, -0.5, false, true},
+		{"zero elements", 0, 0.01, false, true},
+		{"valid", 10000, 0.01, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bf, err := NewBloomFilter(tt.expectedElements, tt.falsePositiveRate)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %t", err, tt.wantErr)
+			}
+			if got := errors.Is(err, ErrFilterTooLarge); got != tt.wantTooLarge {
+				t.Errorf("errors.Is(%v, ErrFilterTooLarge) = %t, want %t", err, got, tt.wantTooLarge)
+			}
+			if err != nil && bf != nil {
+				t.Error("got a filter along with the error")
+			}
+
+			// The counting filter sizes its counters the same way
+			_, err = NewCountingBloomFilter(tt.expectedElements, tt.falsePositiveRate)
+			if got := errors.Is(err, ErrFilterTooLarge); (err != nil) != tt.wantErr || got != tt.wantTooLarge {
+				t.Errorf("NewCountingBloomFilter err = %v", err)
+			}
+		})
+	}
+}
+
+func TestBuilderMaxBitArraySize(t *testing.T) {
+	tests := []struct {
+		name         string
+		elements     uint32
+		rate         float64
+		limit        uint64
+		wantTooLarge bool
+		wantErr      bool
+	}{
+		{"needs more than 2^32-1 bits", 100000000, 1e-9, math.MaxUint32, true, true},
+		{"exactly the bits needed", 10000, 0.01, 95851, false, false},
+		{"one bit short", 10000, 0.01, 95850, true, true},
+		{"above 2^32-1 bits", 1000, 0.01, 1 << 40, false, true},
+		{"zero", 1000, 0.01, 0, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bf, err := NewBloomFilterBuilder().
+				WithExpectedElements(tt.elements).
+				WithFalsePositiveRate(tt.rate).
+				WithMaxBitArraySize(tt.limit).
+				Build()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %t", err, tt.wantErr)
+			}
+			if got := errors.Is(err, ErrFilterTooLarge); got != tt.wantTooLarge {
+				t.Errorf("errors.Is(%v, ErrFilterTooLarge) = %t, want %t", err, got, tt.wantTooLarge)
+			}
+			if err == nil && uint64(bf.GetBitArraySize()) > tt.limit {
+				t.Errorf("bit array size = %d, want at most %d", bf.GetBitArraySize(), tt.limit)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// tenNodeRing returns a ring of server0 to server9 with 150 vnodes each
+func tenNodeRing() *ConsistentHash {
+	ch := NewConsistentHash(150)
+	for node := 0; node < 10; node++ {
+		ch.AddNode(fmt.Sprintf("server%d", node))
+	}
+	return ch
+}
+
+func TestGetNodesForKeysMatchesGetNode(t *testing.T) {
+	ch := tenNodeRing()
+	keys := append(routingKeys(10000), "key1", "key2") // duplicates appear once
+
+	batched := ch.GetNodesForKeys(keys)
+	if len(batched) != 10000 {
+		t.Fatalf("GetNodesForKeys returned %d routes, want 10000", len(batched))
+	}
+	for _, key := range keys {
+		if node, _ := ch.GetNode(key); batched[key] != node {
+			t.Fatalf("%s: batch routed to %s, GetNode to %s", key, batched[key], node)
+		}
+	}
+
+	if empty := NewConsistentHash(150).GetNodesForKeys(keys); len(empty) != 0 {
+		t.Errorf("empty ring routed %d keys, want none", len(empty))
+	}
+}
+
+// BenchmarkRouteLoop routes 1M keys with one GetNode call each, collecting
+// the same map GetNodesForKeys returns
+func BenchmarkRouteLoop(b *testing.B) {
+	ch := tenNodeRing()
+	keys := routingKeys(1000000)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		routes := make(map[string]string, len(keys))
+		for _, key := range keys {
+			if node, err := ch.GetNode(key); err == nil {
+				routes[key] = node
+			}
+		}
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(keys)), "ns/key")
+}
+
+// BenchmarkRouteBatch routes the same 1M keys with one GetNodesForKeys call
+func BenchmarkRouteBatch(b *testing.B) {
+	ch := tenNodeRing()
+	keys := routingKeys(1000000)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		ch.GetNodesForKeys(keys)
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(keys)), "ns/key")
+}
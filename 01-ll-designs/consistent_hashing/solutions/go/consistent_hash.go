@@ -1,12 +1,10 @@
 package main
 
 import (
-	"crypto/md5"
-	"encoding/binary"
-	"errors"
 	"fmt"
 	"sort"
 	"sync"
+	"sync/atomic"
 )
 
 // hashRingEntry represents a single entry in the hash ring
@@ -15,46 +13,79 @@ type hashRingEntry struct {
 	nodeID string
 }
 
-// ConsistentHash represents a consistent hashing ring with virtual nodes support
+// less orders entries by hash, breaking ties by node ID so the ring order,
+// and which node wins a hash collision, never depends on insertion order
+func (e hashRingEntry) less(other hashRingEntry) bool {
+	if e.hash != other.hash {
+		return e.hash < other.hash
+	}
+	return e.nodeID < other.nodeID
+}
+
+// ConsistentHash represents a consistent hashing ring with virtual nodes support.
+// Writers never modify a ring slice in place: each change builds a new one
+// and publishes it as an immutable RingSnapshot, which lookups read without
+// taking the mutex.
 type ConsistentHash struct {
 	virtualNodes int
-	ring         []hashRingEntry // sorted by hash value
-	nodes        map[string]bool // active nodes
+	ring         []hashRingEntry // sorted by hash value, then node ID
+	nodes        map[string]int  // active nodes and their weights
+	hashFunc     HashFunc
 	mutex        sync.RWMutex    // read-write mutex for thread safety
+	current      atomic.Pointer[RingSnapshot] // published after every change
 }
 
-// NewConsistentHash creates a new consistent hash ring
+// NewConsistentHash creates a new consistent hash ring using FNV1aHash
 func NewConsistentHash(virtualNodes int) *ConsistentHash {
-	return &ConsistentHash{
+	return NewConsistentHashWithHash(virtualNodes, FNV1aHash)
+}
+
+// NewConsistentHashWithHash creates a new consistent hash ring that places
+// keys and virtual nodes with hashFunc, or FNV1aHash if it is nil
+func NewConsistentHashWithHash(virtualNodes int, hashFunc HashFunc) *ConsistentHash {
+	if hashFunc == nil {
+		hashFunc = FNV1aHash
+	}
+	ch := &ConsistentHash{
 		virtualNodes: virtualNodes,
 		ring:         make([]hashRingEntry, 0),
-		nodes:        make(map[string]bool),
+		nodes:        make(map[string]int),
+		hashFunc:     hashFunc,
 	}
+	ch.publish()
+	return ch
 }
 
-// hash generates a hash value for a key using MD5
+// hash generates a hash value for a key using the ring's hash function
 func (ch *ConsistentHash) hash(key string) uint64 {
-	hasher := md5.New()
-	hasher.Write([]byte(key))
-	digest := hasher.Sum(nil)
-	
-	// Convert first 8 bytes of MD5 hash to uint64
-	return binary.BigEndian.Uint64(digest[:8])
+	return ch.hashFunc(key)
 }
 
-// AddNode adds a node to the hash ring
+// AddNode adds a node with weight 1 to the hash ring
 func (ch *ConsistentHash) AddNode(nodeID string) {
+	ch.AddWeightedNode(nodeID, 1)
+}
+
+// AddWeightedNode adds a node that owns weight times as many virtual nodes,
+// and so roughly weight times as many keys, as a node added with AddNode.
+// Weights below 1 are treated as 1.
+func (ch *ConsistentHash) AddWeightedNode(nodeID string, weight int) {
+	if weight < 1 {
+		weight = 1
+	}
+	
 	ch.mutex.Lock()
 	defer ch.mutex.Unlock()
 	
-	if ch.nodes[nodeID] {
+	if _, exists := ch.nodes[nodeID]; exists {
 		return // Node already exists
 	}
 	
-	ch.nodes[nodeID] = true
+	ch.nodes[nodeID] = weight
 	
-	// Add virtual nodes to the ring
-	for i := 0; i < ch.virtualNodes; i++ {
+	// Create the node's virtual nodes
+	added := make([]hashRingEntry, 0, weight*ch.virtualNodes)
+	for i := 0; i < weight*ch.virtualNodes; i++ {
 		virtualKey := fmt.Sprintf("%s:%d", nodeID, i)
 		hashValue := ch.hash(virtualKey)
 		
@@ -63,13 +94,40 @@ func (ch *ConsistentHash) AddNode(nodeID string) {
 			nodeID: nodeID,
 		}
 		
-		ch.ring = append(ch.ring, entry)
+		added = append(added, entry)
 	}
 	
-	// Keep ring sorted by hash value
-	sort.Slice(ch.ring, func(i, j int) bool {
-		return ch.ring[i].hash < ch.ring[j].hash
+	// Sort just the new entries and merge them in, keeping the ring sorted
+	// in O(N) instead of re-sorting all of it
+	sort.Slice(added, func(i, j int) bool {
+		return added[i].less(added[j])
 	})
+	ch.ring = mergeRing(copyRing(ch.ring, len(added)), added)
+	ch.publish()
+}
+
+// copyRing returns a copy of ring with room for extra more entries, so a
+// change never touches a ring that has already been published
+func copyRing(ring []hashRingEntry, extra int) []hashRingEntry {
+	return append(make([]hashRingEntry, 0, len(ring)+extra), ring...)
+}
+
+// mergeRing merges the sorted entries of added into the sorted ring in
+// place, filling from the back so no entry is overwritten before it moves.
+// The ring must not be published; pass a copy from copyRing.
+func mergeRing(ring, added []hashRingEntry) []hashRingEntry {
+	i, j := len(ring)-1, len(added)-1
+	ring = append(ring, added...)
+	for k := len(ring) - 1; j >= 0; k-- {
+		if i >= 0 && added[j].less(ring[i]) {
+			ring[k] = ring[i]
+			i--
+		} else {
+			ring[k] = added[j]
+			j--
+		}
+	}
+	return ring
 }
 
 // RemoveNode removes a node from the hash ring
@@ -77,13 +135,13 @@ func (ch *ConsistentHash) RemoveNode(nodeID string) {
 	ch.mutex.Lock()
 	defer ch.mutex.Unlock()
 	
-	if !ch.nodes[nodeID] {
+	if _, exists := ch.nodes[nodeID]; !exists {
 		return // Node doesn't exist
 	}
 	
 	delete(ch.nodes, nodeID)
 	
-	// Remove virtual nodes from the ring
+	// Remove all of the node's virtual nodes, however many its weight placed
 	newRing := make([]hashRingEntry, 0, len(ch.ring))
 	for _, entry := range ch.ring {
 		if entry.nodeID != nodeID {
@@ -91,34 +149,50 @@ func (ch *ConsistentHash) RemoveNode(nodeID string) {
 		}
 	}
 	ch.ring = newRing
+	ch.publish()
 }
 
-// GetNode returns the node responsible for a given key
+// GetNode returns the node responsible for a given key. It reads the
+// current snapshot and never waits on a writer.
 func (ch *ConsistentHash) GetNode(key string) (string, error) {
-	ch.mutex.RLock()
-	defer ch.mutex.RUnlock()
-	
-	if len(ch.ring) == 0 {
-		return "", errors.New("no nodes available")
-	}
-	
-	hashValue := ch.hash(key)
-	
+	return ch.Snapshot().GetNode(key)
+}
+
+// GetNodesForKeys returns the node responsible for each key, routing them
+// all against one snapshot of the ring. It is empty if the ring has no nodes.
+func (ch *ConsistentHash) GetNodesForKeys(keys []string) map[string]string {
+	return ch.Snapshot().GetNodesForKeys(keys)
+}
+
+// route calls visit with each key and its node, all against one snapshot
+// of the ring. It calls nothing if the ring is empty.
+func (ch *ConsistentHash) route(keys []string, visit func(key, nodeID string)) {
+	ch.Snapshot().route(keys, visit)
+}
+
+// search returns the index of the first ring entry clockwise from hashValue.
+// Must be called with the mutex held on a non-empty ring.
+func (ch *ConsistentHash) search(hashValue uint64) int {
+	return searchRing(ch.ring, hashValue)
+}
+
+// searchRing returns the index of the first entry of a non-empty sorted
+// ring clockwise from hashValue
+func searchRing(ring []hashRingEntry, hashValue uint64) int {
 	// Find the first node clockwise from the key's hash using binary search
-	idx := sort.Search(len(ch.ring), func(i int) bool {
-		return ch.ring[i].hash >= hashValue
+	idx := sort.Search(len(ring), func(i int) bool {
+		return ring[i].hash >= hashValue
 	})
 	
 	// Wrap around to the beginning if we're past the end
-	if idx == len(ch.ring) {
+	if idx == len(ring) {
 		idx = 0
 	}
-	
-	return ch.ring[idx].nodeID, nil
+	return idx
 }
 
-// GetNodes returns all active nodes in the system
-func (ch *ConsistentHash) GetNodes() []string {
+// GetAllNodes returns all active nodes in the system
+func (ch *ConsistentHash) GetAllNodes() []string {
 	ch.mutex.RLock()
 	defer ch.mutex.RUnlock()
 	
@@ -131,17 +205,13 @@ func (ch *ConsistentHash) GetNodes() []string {
 	return nodes
 }
 
-// GetLoadDistribution analyzes load distribution for a set of keys
+// GetLoadDistribution analyzes load distribution for a set of keys,
+// counting a key once for each time it appears
 func (ch *ConsistentHash) GetLoadDistribution(keys []string) map[string]int {
 	distribution := make(map[string]int)
-	
-	for _, key := range keys {
-		node, err := ch.GetNode(key)
-		if err == nil {
-			distribution[node]++
-		}
-	}
-	
+	ch.route(keys, func(key, nodeID string) {
+		distribution[nodeID]++
+	})
 	return distribution
 }
 
@@ -154,7 +224,9 @@ func (ch *ConsistentHash) GetRingInfo() map[string]interface{} {
 		"totalNodes":           len(ch.nodes),
 		"totalVirtualNodes":    len(ch.ring),
 		"virtualNodesPerNode":  ch.virtualNodes,
-		"nodes":               ch.GetNodes(),
+		"nodes":               ch.GetAllNodes(),
+		"nodeWeights":         ch.nodeWeights(),
+		"nodeShares":          ch.nodeShares(),
 	}
 }
 
@@ -171,7 +243,7 @@ func (ch *ConsistentHash) PrintRing() {
 
 // demonstrateConsistentHashing shows the functionality of consistent hashing
 func demonstrateConsistentHashing() {
-	fmt.Println("=== Consistent Hashing Demo ===\n")
+	fmt.Print("=== Consistent Hashing Demo ===\n\n")
 	
 	// Create hash ring
 	ch := NewConsistentHash(3)
@@ -248,4 +320,22 @@ func demonstrateConsistentHashing() {
 
 func main() {
 	demonstrateConsistentHashing()
+	fmt.Println()
+	demonstrateWeightedNodes()
+	fmt.Println()
+	demonstrateReplicas()
+	fmt.Println()
+	demonstrateHashFunctions()
+	fmt.Println()
+	demonstrateKeyMovement()
+	fmt.Println()
+	demonstrateRendezvousHashing()
+	fmt.Println()
+	demonstrateDistributionStats()
+	fmt.Println()
+	demonstrateDrain()
+	fmt.Println()
+	demonstrateBatchRouting()
+	fmt.Println()
+	demonstrateSnapshot()
 }
\ No newline at end of file
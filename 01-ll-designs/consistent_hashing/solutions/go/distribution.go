@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// ringSize is the size of the hash space, 2^64
+const ringSize = float64(1<<63) * 2
+
+// DistributionStats describes how evenly points are spaced around the ring.
+// Gaps are measured clockwise between adjacent points, including the gap
+// that wraps from the last point back to the first, in units of hash space.
+type DistributionStats struct {
+	Points    int     // total virtual nodes on the ring
+	MinGap    uint64  // smallest gap
+	MaxGap    uint64  // largest gap
+	MeanGap   float64 // 2^64 / Points
+	StdDevGap float64 // population standard deviation of the gaps
+	// GapCV is StdDevGap / MeanGap. For randomly placed points it stays
+	// near 1 however many virtual nodes there are, because individual gaps
+	// are exponentially distributed.
+	GapCV float64
+	// OwnershipCV is the coefficient of variation of the share of hash space
+	// each node owns, divided by its weight. It predicts load balance and
+	// falls as virtualNodes grows, since each node sums more gaps.
+	OwnershipCV float64
+}
+
+// GetDistributionStats scans the ring and reports the spread of gaps between
+// adjacent points and of the hash space each node owns, for tuning
+// virtualNodes. It returns zero stats for an empty ring.
+func (ch *ConsistentHash) GetDistributionStats() DistributionStats {
+	ch.mutex.RLock()
+	defer ch.mutex.RUnlock()
+
+	stats := DistributionStats{Points: len(ch.ring)}
+	if len(ch.ring) == 0 {
+		return stats
+	}
+
+	stats.MeanGap = ringSize / float64(len(ch.ring))
+	if len(ch.ring) == 1 {
+		// A single point's gap is the whole ring, one more than fits in a uint64
+		stats.MinGap, stats.MaxGap = math.MaxUint64, math.MaxUint64
+		return stats
+	}
+
+	stats.MinGap = math.MaxUint64
+	owned := make(map[string]float64, len(ch.nodes))
+	var sumSquares float64
+	for i := range ch.ring {
+		// Unsigned subtraction wraps, which handles the last-to-first gap.
+		// Keys in the gap belong to the point that ends it.
+		next := ch.ring[(i+1)%len(ch.ring)]
+		gap := next.hash - ch.ring[i].hash
+		owned[next.nodeID] += float64(gap)
+		if gap < stats.MinGap {
+			stats.MinGap = gap
+		}
+		if gap > stats.MaxGap {
+			stats.MaxGap = gap
+		}
+		deviation := float64(gap) - stats.MeanGap
+		sumSquares += deviation * deviation
+	}
+
+	stats.StdDevGap = math.Sqrt(sumSquares / float64(len(ch.ring)))
+	stats.GapCV = stats.StdDevGap / stats.MeanGap
+	stats.OwnershipCV = ch.ownershipCV(owned)
+	return stats
+}
+
+// ownershipCV returns the coefficient of variation of each node's owned
+// hash space per unit of weight. Must be called with the mutex held.
+func (ch *ConsistentHash) ownershipCV(owned map[string]float64) float64 {
+	totalWeight := 0
+	for _, weight := range ch.nodes {
+		totalWeight += weight
+	}
+	mean := ringSize / float64(totalWeight)
+
+	// Drained nodes own nothing by design, so they are left out
+	var sumSquares float64
+	for nodeID, weight := range ch.nodes {
+		if weight == 0 {
+			continue
+		}
+		deviation := owned[nodeID]/float64(weight) - mean
+		sumSquares += deviation * deviation
+	}
+	return math.Sqrt(sumSquares/float64(ch.activeNodeCount())) / mean
+}
+
+// demonstrateDistributionStats shows ownership evening out as the number of
+// virtual nodes grows, while the spread of individual gaps does not
+func demonstrateDistributionStats() {
+	fmt.Println("=== Ring Distribution Demo ===")
+
+	keys := make([]string, 100000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+	}
+
+	fmt.Println("10 nodes, 100000 keys:")
+	for _, virtualNodes := range []int{1, 10, 50, 150, 500} {
+		ch := NewConsistentHash(virtualNodes)
+		for i := 0; i < 10; i++ {
+			ch.AddNode(fmt.Sprintf("server%d", i))
+		}
+
+		minKeys, maxKeys := len(keys), 0
+		for _, count := range ch.GetLoadDistribution(keys) {
+			if count < minKeys {
+				minKeys = count
+			}
+			if count > maxKeys {
+				maxKeys = count
+			}
+		}
+
+		stats := ch.GetDistributionStats()
+		fmt.Printf("  %3d vnodes: gap CV %.2f, ownership CV %.3f, %d-%d keys per node\n",
+			virtualNodes, stats.GapCV, stats.OwnershipCV, minKeys, maxKeys)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// keyCV returns the coefficient of variation of the keys each node receives
+func keyCV(distribution map[string]int, nodes int) float64 {
+	total := 0
+	for _, count := range distribution {
+		total += count
+	}
+	mean := float64(total) / float64(nodes)
+
+	var sumSquares float64
+	for _, count := range distribution {
+		deviation := float64(count) - mean
+		sumSquares += deviation * deviation
+	}
+	// Nodes that received no keys are missing from the map
+	sumSquares += float64(nodes-len(distribution)) * mean * mean
+	return math.Sqrt(sumSquares/float64(nodes)) / mean
+}
+
+func TestMoreVirtualNodesLowerCV(t *testing.T) {
+	keys := routingKeys(100000)
+	prevOwnership, prevKeys := math.Inf(1), math.Inf(1)
+
+	for _, virtualNodes := range []int{1, 10, 50, 150, 500} {
+		ch := NewConsistentHash(virtualNodes)
+		for node := 0; node < 10; node++ {
+			ch.AddNode(fmt.Sprintf("server%d", node))
+		}
+		stats := ch.GetDistributionStats()
+		keysCV := keyCV(ch.GetLoadDistribution(keys), 10)
+
+		if stats.Points != 10*virtualNodes {
+			t.Errorf("%d vnodes: Points = %d, want %d", virtualNodes, stats.Points, 10*virtualNodes)
+		}
+		if stats.OwnershipCV >= prevOwnership {
+			t.Errorf("%d vnodes: OwnershipCV = %.3f, want below %.3f", virtualNodes, stats.OwnershipCV, prevOwnership)
+		}
+		if keysCV >= prevKeys {
+			t.Errorf("%d vnodes: key CV = %.3f, want below %.3f", virtualNodes, keysCV, prevKeys)
+		}
+		// Individual gaps stay exponentially spread however many points there are
+		if virtualNodes >= 10 && math.Abs(stats.GapCV-1) > 0.2 {
+			t.Errorf("%d vnodes: GapCV = %.2f, want about 1", virtualNodes, stats.GapCV)
+		}
+		prevOwnership, prevKeys = stats.OwnershipCV, keysCV
+	}
+	if prevOwnership > 0.1 {
+		t.Errorf("500 vnodes: OwnershipCV = %.3f, want below 0.1", prevOwnership)
+	}
+}
+
+func TestDistributionStatsSmallRings(t *testing.T) {
+	if stats := NewConsistentHash(10).GetDistributionStats(); stats != (DistributionStats{}) {
+		t.Errorf("empty ring stats = %+v, want zero", stats)
+	}
+
+	single := NewConsistentHash(1)
+	single.AddNode("server0")
+	stats := single.GetDistributionStats()
+	if stats.Points != 1 || stats.MinGap != math.MaxUint64 || stats.MaxGap != math.MaxUint64 {
+		t.Errorf("single point stats = %+v, want one point with a full-ring gap", stats)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SetNodeWeight changes the weight of a node already on the ring, adding or
+// removing only the virtual nodes for the difference. Virtual node i of a
+// node is always placed at the hash of "nodeID:i", so lowering the weight
+// removes the highest-numbered points and raising it restores them, and keys
+// only move between this node and its neighbours.
+//
+// A weight of 0 drains the node: it stays a member, but owns no points and
+// receives no keys, so traffic can be tapered to zero before RemoveNode.
+// It returns an error if the node is not on the ring or weight is negative.
+func (ch *ConsistentHash) SetNodeWeight(nodeID string, weight int) error {
+	if weight < 0 {
+		return fmt.Errorf("weight must not be negative, got %d", weight)
+	}
+
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+
+	current, exists := ch.nodes[nodeID]
+	if !exists {
+		return fmt.Errorf("node %s not found", nodeID)
+	}
+	ch.nodes[nodeID] = weight
+
+	from, to := current*ch.virtualNodes, weight*ch.virtualNodes
+	switch {
+	case to > from:
+		added := make([]hashRingEntry, 0, to-from)
+		for i := from; i < to; i++ {
+			added = append(added, hashRingEntry{
+				hash:   ch.hash(fmt.Sprintf("%s:%d", nodeID, i)),
+				nodeID: nodeID,
+			})
+		}
+		sort.Slice(added, func(i, j int) bool {
+			return added[i].less(added[j])
+		})
+		ch.ring = mergeRing(copyRing(ch.ring, len(added)), added)
+
+	case to < from:
+		// Count the hashes to drop, so that if two of the node's virtual
+		// nodes share a hash only the removed one goes
+		removed := make(map[uint64]int, from-to)
+		for i := to; i < from; i++ {
+			removed[ch.hash(fmt.Sprintf("%s:%d", nodeID, i))]++
+		}
+		kept := make([]hashRingEntry, 0, len(ch.ring)-(from-to))
+		for _, entry := range ch.ring {
+			if entry.nodeID == nodeID && removed[entry.hash] > 0 {
+				removed[entry.hash]--
+				continue
+			}
+			kept = append(kept, entry)
+		}
+		ch.ring = kept
+	}
+	ch.publish()
+	return nil
+}
+
+// DrainNode sets a node's weight to 0 so it receives no keys but stays on
+// the ring, ready to be removed or restored with SetNodeWeight
+func (ch *ConsistentHash) DrainNode(nodeID string) error {
+	return ch.SetNodeWeight(nodeID, 0)
+}
+
+// activeNodeCount returns how many nodes have a weight above 0.
+// Must be called with the mutex held.
+func (ch *ConsistentHash) activeNodeCount() int {
+	count := 0
+	for _, weight := range ch.nodes {
+		if weight > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// nodeShares returns the fraction of keys each node is expected to receive,
+// its weight over the total weight. Must be called with the mutex held.
+func (ch *ConsistentHash) nodeShares() map[string]float64 {
+	totalWeight := 0
+	for _, weight := range ch.nodes {
+		totalWeight += weight
+	}
+
+	shares := make(map[string]float64, len(ch.nodes))
+	for nodeID, weight := range ch.nodes {
+		if totalWeight > 0 {
+			shares[nodeID] = float64(weight) / float64(totalWeight)
+		}
+	}
+	return shares
+}
+
+// demonstrateDrain tapers one node's weight to zero and shows its share of
+// keys falling in proportion, with only its own keys moving
+func demonstrateDrain() {
+	fmt.Println("=== Node Drain Demo ===")
+
+	ch := NewConsistentHash(100)
+	for _, node := range []string{"server1", "server2", "server3"} {
+		ch.AddWeightedNode(node, 4)
+	}
+
+	keys := make([]string, 100000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+	}
+
+	owners := make(map[string]string, len(keys))
+	for _, key := range keys {
+		owners[key], _ = ch.GetNode(key)
+	}
+
+	for weight := 4; weight >= 0; weight-- {
+		if err := ch.SetNodeWeight("server3", weight); err != nil {
+			fmt.Printf("  ERROR: %v\n", err)
+			return
+		}
+
+		// Every moved key should come off server3
+		moved, strays := 0, 0
+		for _, key := range keys {
+			node, _ := ch.GetNode(key)
+			if node != owners[key] {
+				moved++
+				if owners[key] != "server3" {
+					strays++
+				}
+				owners[key] = node
+			}
+		}
+
+		expected := float64(weight) / float64(8+weight)
+		actual := float64(ch.GetLoadDistribution(keys)["server3"]) / float64(len(keys))
+		fmt.Printf("  server3 weight %d: %5.1f%% of keys (expected %4.1f%%), %5d moved, %d not from server3\n",
+			weight, actual*100, expected*100, moved, strays)
+	}
+
+	fmt.Printf("Ring info after draining: %v\n", ch.GetRingInfo())
+	ch.RemoveNode("server3")
+	fmt.Printf("After removing server3: %v\n", ch.GetAllNodes())
+
+	if err := ch.SetNodeWeight("server9", 1); err != nil {
+		fmt.Printf("Resizing an unknown node: %v\n", err)
+	}
+}
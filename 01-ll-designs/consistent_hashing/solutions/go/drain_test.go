@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestDrainShiftsLoadInProportion(t *testing.T) {
+	// Enough points per weight unit that one unit's share is close to its
+	// expected value
+	ch := NewConsistentHash(500)
+	for _, node := range []string{"server1", "server2", "server3"} {
+		ch.AddWeightedNode(node, 4)
+	}
+	keys := routingKeys(100000)
+	original := ch.GetNodesForKeys(keys)
+	owners := ch.GetNodesForKeys(keys)
+
+	for weight := 3; weight >= 0; weight-- {
+		if err := ch.SetNodeWeight("server3", weight); err != nil {
+			t.Fatal(err)
+		}
+
+		// Keys only ever leave server3
+		for key, node := range ch.GetNodesForKeys(keys) {
+			if node != owners[key] && owners[key] != "server3" {
+				t.Fatalf("weight %d: %s moved from %s to %s", weight, key, owners[key], node)
+			}
+			owners[key] = node
+		}
+
+		want := float64(weight) / float64(8+weight)
+		got := float64(ch.GetLoadDistribution(keys)["server3"]) / float64(len(keys))
+		if math.Abs(got-want) > 0.03 {
+			t.Errorf("weight %d: server3 has %.1f%% of keys, want %.1f%% ± 3", weight, got*100, want*100)
+		}
+	}
+
+	// Restoring the weight puts back the same points, so every key returns
+	if err := ch.SetNodeWeight("server3", 4); err != nil {
+		t.Fatal(err)
+	}
+	for key, node := range ch.GetNodesForKeys(keys) {
+		if node != original[key] {
+			t.Fatalf("after restoring: %s on %s, originally %s", key, node, original[key])
+		}
+	}
+}
+
+func TestDrainedNodeStaysAMember(t *testing.T) {
+	ch := NewConsistentHash(100)
+	for node := 1; node <= 3; node++ {
+		ch.AddNode(fmt.Sprintf("server%d", node))
+	}
+	if err := ch.DrainNode("server3"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(ch.GetAllNodes()); got != 3 {
+		t.Errorf("GetAllNodes() has %d nodes after draining, want 3", got)
+	}
+	if got := len(ch.ring); got != 200 {
+		t.Errorf("ring has %d points after draining, want 200", got)
+	}
+	if count := ch.GetLoadDistribution(routingKeys(10000))["server3"]; count != 0 {
+		t.Errorf("drained server3 received %d keys", count)
+	}
+}
+
+func TestSetNodeWeightErrors(t *testing.T) {
+	ch := NewConsistentHash(10)
+	ch.AddNode("server1")
+
+	if err := ch.SetNodeWeight("server9", 1); err == nil {
+		t.Error("SetNodeWeight on an unknown node succeeded")
+	}
+	if err := ch.SetNodeWeight("server1", -1); err == nil {
+		t.Error("SetNodeWeight with a negative weight succeeded")
+	}
+	if got := len(ch.ring); got != 10 {
+		t.Errorf("ring has %d points after rejected changes, want 10", got)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+)
+
+// HashFunc maps a key or virtual node name to a position on the ring
+type HashFunc func(key string) uint64
+
+// FNV1aHash is the default: the 64-bit FNV-1a hash followed by the murmur3
+// finalizer. Plain FNV-1a barely changes the high bits between similar keys
+// such as "server1:0" and "server1:1", which clumps virtual nodes together
+// on the ring; the finalizer spreads them out. Written out rather than using
+// hash/fnv so it doesn't allocate.
+func FNV1aHash(key string) uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	hash := uint64(offset64)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= prime64
+	}
+
+	// murmur3 fmix64
+	hash ^= hash >> 33
+	hash *= 0xff51afd7ed558ccd
+	hash ^= hash >> 33
+	hash *= 0xc4ceb93e53caefcd
+	hash ^= hash >> 33
+	return hash
+}
+
+// MD5Hash uses the first 8 bytes of the key's MD5 digest. It was the only
+// hash before HashFunc was pluggable; use it to keep existing key placement.
+func MD5Hash(key string) uint64 {
+	digest := md5.Sum([]byte(key))
+	return binary.BigEndian.Uint64(digest[:8])
+}
+
+// hashFunctions lists the built-in hash functions by name
+var hashFunctions = []struct {
+	name string
+	hash HashFunc
+}{{"FNV-1a", FNV1aHash}, {"MD5", MD5Hash}}
+
+// demonstrateHashFunctions compares how evenly the default FNV-1a hash and
+// MD5 spread keys. BenchmarkHash and BenchmarkBuildRing compare their speed.
+func demonstrateHashFunctions() {
+	fmt.Println("=== Hash Function Demo ===")
+
+	keys := make([]string, 100000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+	}
+
+	for _, candidate := range hashFunctions {
+		ch := NewConsistentHashWithHash(150, candidate.hash)
+		for node := 0; node < 10; node++ {
+			ch.AddNode(fmt.Sprintf("server%d", node))
+		}
+		minKeys, maxKeys := len(keys), 0
+		for _, count := range ch.GetLoadDistribution(keys) {
+			if count < minKeys {
+				minKeys = count
+			}
+			if count > maxKeys {
+				maxKeys = count
+			}
+		}
+
+		fmt.Printf("%s: 100000 keys on 10 nodes, %d-%d keys per node\n", candidate.name, minKeys, maxKeys)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHashFunctionsBalanceKeys(t *testing.T) {
+	keys := make([]string, 100000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+	}
+
+	for _, candidate := range hashFunctions {
+		ch := NewConsistentHashWithHash(150, candidate.hash)
+		for node := 0; node < 10; node++ {
+			ch.AddNode(fmt.Sprintf("server%d", node))
+		}
+		// 150 vnodes keep every node within 20% of its fair share
+		for node, count := range ch.GetLoadDistribution(keys) {
+			if count < 8000 || count > 12000 {
+				t.Errorf("%s: %s has %d of %d keys, want 8000-12000", candidate.name, node, count, len(keys))
+			}
+		}
+	}
+}
+
+func BenchmarkHash(b *testing.B) {
+	keys := make([]string, 100000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+	}
+
+	for _, candidate := range hashFunctions {
+		b.Run(candidate.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				candidate.hash(keys[i%len(keys)])
+			}
+		})
+	}
+}
+
+// BenchmarkBuildRing builds a ring of 1000 nodes with 150 vnodes each
+func BenchmarkBuildRing(b *testing.B) {
+	nodes := make([]string, 1000)
+	for i := range nodes {
+		nodes[i] = fmt.Sprintf("server%d", i)
+	}
+
+	for _, candidate := range hashFunctions {
+		b.Run(candidate.name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				ring := NewConsistentHashWithHash(150, candidate.hash)
+				for _, node := range nodes {
+					ring.AddNode(node)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import "fmt"
+
+// SimulateRemoval reports which keys would move, and to which node, if
+// nodeID were removed, without changing the ring. Only the removed node's
+// keys move; each goes to the next node clockwise. The result is empty if
+// nodeID is not on the ring, and maps keys to "" if it is the only node.
+func (ch *ConsistentHash) SimulateRemoval(nodeID string, keys []string) map[string]string {
+	ch.mutex.RLock()
+	defer ch.mutex.RUnlock()
+
+	moved := make(map[string]string)
+	if _, exists := ch.nodes[nodeID]; !exists {
+		return moved
+	}
+
+	for _, key := range keys {
+		start := ch.search(ch.hash(key))
+		if ch.ring[start].nodeID != nodeID {
+			continue
+		}
+		moved[key] = ""
+		for i := 1; i < len(ch.ring); i++ {
+			if next := ch.ring[(start+i)%len(ch.ring)].nodeID; next != nodeID {
+				moved[key] = next
+				break
+			}
+		}
+	}
+	return moved
+}
+
+// SimulateAddition reports which keys would move to nodeID if it were added
+// with the given weight, without changing the ring. The result is empty if
+// nodeID is already on the ring.
+func (ch *ConsistentHash) SimulateAddition(nodeID string, weight int, keys []string) map[string]string {
+	if weight < 1 {
+		weight = 1
+	}
+
+	// Build a private copy of the ring with the node added
+	ch.mutex.RLock()
+	_, exists := ch.nodes[nodeID]
+	preview := &ConsistentHash{
+		virtualNodes: ch.virtualNodes,
+		ring:         append([]hashRingEntry(nil), ch.ring...),
+		nodes:        ch.nodeWeights(),
+		hashFunc:     ch.hashFunc,
+	}
+	ch.mutex.RUnlock()
+
+	moved := make(map[string]string)
+	if exists {
+		return moved
+	}
+	preview.AddWeightedNode(nodeID, weight)
+
+	for _, key := range keys {
+		if node, _ := preview.GetNode(key); node == nodeID {
+			moved[key] = nodeID
+		}
+	}
+	return moved
+}
+
+// demonstrateKeyMovement shows that changing one node moves only about
+// 1/n of the keys
+func demonstrateKeyMovement() {
+	fmt.Println("=== Key Movement Demo ===")
+
+	ch := NewConsistentHash(150)
+	for i := 1; i <= 5; i++ {
+		ch.AddNode(fmt.Sprintf("server%d", i))
+	}
+
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+	}
+
+	removed := ch.SimulateRemoval("server3", keys)
+	destinations := make(map[string]int)
+	for _, node := range removed {
+		destinations[node]++
+	}
+	fmt.Printf("Removing server3 would move %d of %d keys (%.1f%%): %v\n",
+		len(removed), len(keys), float64(len(removed))*100.0/float64(len(keys)), destinations)
+
+	added := ch.SimulateAddition("server6", 1, keys)
+	fmt.Printf("Adding server6 would move %d of %d keys (%.1f%%)\n",
+		len(added), len(keys), float64(len(added))*100.0/float64(len(keys)))
+	fmt.Printf("Ring unchanged: %v\n", ch.GetAllNodes())
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// RendezvousHash assigns each key to the node with the highest hash of the
+// (key, node) pair, also known as highest random weight (HRW) hashing. It
+// needs no ring or virtual nodes, at the cost of O(n) lookups.
+type RendezvousHash struct {
+	nodes    []string // sorted
+	hashFunc HashFunc
+	mutex    sync.RWMutex
+}
+
+// NewRendezvousHash creates a rendezvous hash using hashFunc, or FNV1aHash
+// if it is nil
+func NewRendezvousHash(hashFunc HashFunc) *RendezvousHash {
+	if hashFunc == nil {
+		hashFunc = FNV1aHash
+	}
+	return &RendezvousHash{
+		nodes:    make([]string, 0),
+		hashFunc: hashFunc,
+	}
+}
+
+// score hashes a key and node together. The NUL separator keeps pairs like
+// ("ab", "c") and ("a", "bc") apart.
+func (rh *RendezvousHash) score(key, nodeID string) uint64 {
+	return rh.hashFunc(key + "\x00" + nodeID)
+}
+
+// AddNode adds a node
+func (rh *RendezvousHash) AddNode(nodeID string) {
+	rh.mutex.Lock()
+	defer rh.mutex.Unlock()
+
+	idx := sort.SearchStrings(rh.nodes, nodeID)
+	if idx < len(rh.nodes) && rh.nodes[idx] == nodeID {
+		return // Node already exists
+	}
+	rh.nodes = append(rh.nodes, "")
+	copy(rh.nodes[idx+1:], rh.nodes[idx:])
+	rh.nodes[idx] = nodeID
+}
+
+// RemoveNode removes a node
+func (rh *RendezvousHash) RemoveNode(nodeID string) {
+	rh.mutex.Lock()
+	defer rh.mutex.Unlock()
+
+	idx := sort.SearchStrings(rh.nodes, nodeID)
+	if idx < len(rh.nodes) && rh.nodes[idx] == nodeID {
+		rh.nodes = append(rh.nodes[:idx], rh.nodes[idx+1:]...)
+	}
+}
+
+// GetNode returns the node with the highest score for a key. Ties go to the
+// first node in sorted order.
+func (rh *RendezvousHash) GetNode(key string) (string, error) {
+	rh.mutex.RLock()
+	defer rh.mutex.RUnlock()
+
+	if len(rh.nodes) == 0 {
+		return "", errors.New("no nodes available")
+	}
+
+	best, bestScore := rh.nodes[0], rh.score(key, rh.nodes[0])
+	for _, nodeID := range rh.nodes[1:] {
+		if score := rh.score(key, nodeID); score > bestScore {
+			best, bestScore = nodeID, score
+		}
+	}
+	return best, nil
+}
+
+// GetNodes returns the n nodes with the highest scores for a key, best
+// first, for replication. It returns an error if n is not positive or there
+// are fewer than n nodes.
+func (rh *RendezvousHash) GetNodes(key string, n int) ([]string, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("replica count must be positive, got %d", n)
+	}
+
+	rh.mutex.RLock()
+	defer rh.mutex.RUnlock()
+
+	if len(rh.nodes) < n {
+		return nil, fmt.Errorf("need %d nodes but only %d available", n, len(rh.nodes))
+	}
+
+	type scoredNode struct {
+		nodeID string
+		score  uint64
+	}
+	scored := make([]scoredNode, len(rh.nodes))
+	for i, nodeID := range rh.nodes {
+		scored[i] = scoredNode{nodeID: nodeID, score: rh.score(key, nodeID)}
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+
+	nodes := make([]string, n)
+	for i := range nodes {
+		nodes[i] = scored[i].nodeID
+	}
+	return nodes, nil
+}
+
+// GetAllNodes returns all nodes, sorted
+func (rh *RendezvousHash) GetAllNodes() []string {
+	rh.mutex.RLock()
+	defer rh.mutex.RUnlock()
+	return append([]string(nil), rh.nodes...)
+}
+
+// demonstrateRendezvousHashing compares key movement of rendezvous hashing
+// against the ring. BenchmarkGetNodeScaling compares their lookup cost.
+func demonstrateRendezvousHashing() {
+	fmt.Println("=== Rendezvous Hashing Demo ===")
+
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+	}
+
+	ring := NewConsistentHash(150)
+	rendezvous := NewRendezvousHash(nil)
+	for i := 1; i <= 5; i++ {
+		ring.AddNode(fmt.Sprintf("server%d", i))
+		rendezvous.AddNode(fmt.Sprintf("server%d", i))
+	}
+
+	ringBefore := make(map[string]string, len(keys))
+	rendezvousBefore := make(map[string]string, len(keys))
+	for _, key := range keys {
+		ringBefore[key], _ = ring.GetNode(key)
+		rendezvousBefore[key], _ = rendezvous.GetNode(key)
+	}
+
+	ring.RemoveNode("server3")
+	rendezvous.RemoveNode("server3")
+	ringMoved, rendezvousMoved := 0, 0
+	for _, key := range keys {
+		if node, _ := ring.GetNode(key); node != ringBefore[key] {
+			ringMoved++
+		}
+		if node, _ := rendezvous.GetNode(key); node != rendezvousBefore[key] {
+			rendezvousMoved++
+		}
+	}
+	fmt.Printf("Removing 1 of 5 nodes moves %d keys on the ring (150 vnodes each) and %d with rendezvous (no vnodes)\n",
+		ringMoved, rendezvousMoved)
+
+	replicas, _ := rendezvous.GetNodes("user:1", 3)
+	fmt.Printf("Rendezvous replicas for user:1: %v\n", replicas)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRendezvousRemovalMovesOnlyRemovedKeys(t *testing.T) {
+	rh := NewRendezvousHash(nil)
+	for i := 1; i <= 5; i++ {
+		rh.AddNode(fmt.Sprintf("server%d", i))
+	}
+
+	keys := make([]string, 10000)
+	before := make(map[string]string, len(keys))
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+		before[keys[i]], _ = rh.GetNode(keys[i])
+	}
+
+	rh.RemoveNode("server3")
+	moved := 0
+	for _, key := range keys {
+		after, _ := rh.GetNode(key)
+		if after == before[key] {
+			continue
+		}
+		moved++
+		if before[key] != "server3" {
+			t.Fatalf("%s moved from %s to %s, but only server3 was removed", key, before[key], after)
+		}
+	}
+	// server3 held about a fifth of the keys
+	if moved < 1500 || moved > 2500 {
+		t.Errorf("%d of %d keys moved, want about 2000", moved, len(keys))
+	}
+}
+
+func TestRendezvousGetNodesAreDistinct(t *testing.T) {
+	rh := NewRendezvousHash(nil)
+	for i := 1; i <= 5; i++ {
+		rh.AddNode(fmt.Sprintf("server%d", i))
+	}
+
+	nodes, err := rh.GetNodes("user:1", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := rh.GetNode("user:1")
+	if len(nodes) != 3 || nodes[0] != first {
+		t.Fatalf("GetNodes = %v, want 3 nodes starting with GetNode's %s", nodes, first)
+	}
+	if nodes[0] == nodes[1] || nodes[1] == nodes[2] || nodes[0] == nodes[2] {
+		t.Errorf("GetNodes returned repeated nodes: %v", nodes)
+	}
+	if _, err := rh.GetNodes("user:1", 6); err == nil {
+		t.Error("GetNodes(6) on 5 nodes succeeded")
+	}
+}
+
+// BenchmarkGetNodeScaling compares ring and rendezvous lookups as the
+// cluster grows: O(log N) against O(n)
+func BenchmarkGetNodeScaling(b *testing.B) {
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+	}
+
+	for _, count := range []int{10, 100, 1000} {
+		ring := NewConsistentHash(150)
+		rendezvous := NewRendezvousHash(nil)
+		for i := 0; i < count; i++ {
+			ring.AddNode(fmt.Sprintf("server%d", i))
+			rendezvous.AddNode(fmt.Sprintf("server%d", i))
+		}
+
+		b.Run(fmt.Sprintf("ring/%d", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ring.GetNode(keys[i%len(keys)])
+			}
+		})
+		b.Run(fmt.Sprintf("rendezvous/%d", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				rendezvous.GetNode(keys[i%len(keys)])
+			}
+		})
+	}
+}
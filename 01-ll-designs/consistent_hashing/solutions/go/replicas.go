@@ -0,0 +1,57 @@
+package main
+
+import "fmt"
+
+// GetNodes returns n distinct physical nodes for a key, for replication.
+// It walks the ring clockwise from the key's hash, wrapping around, and
+// skips virtual nodes of physical nodes already chosen. The first node is
+// the one GetNode returns, and the result is the same for the same ring.
+// It returns an error if n is not positive or the ring has fewer than n
+// nodes.
+func (ch *ConsistentHash) GetNodes(key string, n int) ([]string, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("replica count must be positive, got %d", n)
+	}
+
+	ch.mutex.RLock()
+	defer ch.mutex.RUnlock()
+
+	if available := ch.activeNodeCount(); available < n {
+		return nil, fmt.Errorf("need %d nodes but only %d available", n, available)
+	}
+
+	nodes := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	start := ch.search(ch.hash(key))
+	for i := 0; i < len(ch.ring) && len(nodes) < n; i++ {
+		nodeID := ch.ring[(start+i)%len(ch.ring)].nodeID
+		if !seen[nodeID] {
+			seen[nodeID] = true
+			nodes = append(nodes, nodeID)
+		}
+	}
+	return nodes, nil
+}
+
+// demonstrateReplicas shows choosing replica nodes for quorum replication
+func demonstrateReplicas() {
+	fmt.Println("=== Replica Placement Demo ===")
+
+	ch := NewConsistentHash(50)
+	for _, node := range []string{"server1", "server2", "server3", "server4", "server5"} {
+		ch.AddNode(node)
+	}
+
+	for _, key := range []string{"user:1", "user:2", "user:3"} {
+		replicas, err := ch.GetNodes(key, 3)
+		if err != nil {
+			fmt.Printf("  %s -> ERROR: %v\n", key, err)
+			continue
+		}
+		fmt.Printf("  %s -> %v\n", key, replicas)
+	}
+
+	if _, err := ch.GetNodes("user:1", 6); err != nil {
+		fmt.Printf("Asking for 6 replicas: %v\n", err)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// fiveNodeRing returns a ring of server1 to server5 with 50 vnodes each
+func fiveNodeRing() *ConsistentHash {
+	ch := NewConsistentHash(50)
+	for node := 1; node <= 5; node++ {
+		ch.AddNode(fmt.Sprintf("server%d", node))
+	}
+	return ch
+}
+
+func TestGetNodesDistinctAndDeterministic(t *testing.T) {
+	ch, rebuilt := fiveNodeRing(), fiveNodeRing()
+
+	for _, key := range routingKeys(1000) {
+		replicas, err := ch.GetNodes(key, 3)
+		if err != nil {
+			t.Fatalf("GetNodes(%s, 3) = %v", key, err)
+		}
+		if len(replicas) != 3 {
+			t.Fatalf("GetNodes(%s, 3) = %v, want 3 nodes", key, replicas)
+		}
+		seen := make(map[string]bool)
+		for _, node := range replicas {
+			if seen[node] {
+				t.Fatalf("GetNodes(%s, 3) = %v repeats %s", key, replicas, node)
+			}
+			seen[node] = true
+		}
+		if primary, _ := ch.GetNode(key); replicas[0] != primary {
+			t.Errorf("GetNodes(%s, 3)[0] = %s, want GetNode's %s", key, replicas[0], primary)
+		}
+
+		again, _ := ch.GetNodes(key, 3)
+		other, _ := rebuilt.GetNodes(key, 3)
+		if fmt.Sprint(again) != fmt.Sprint(replicas) || fmt.Sprint(other) != fmt.Sprint(replicas) {
+			t.Fatalf("GetNodes(%s, 3) = %v, then %v, on a rebuilt ring %v", key, replicas, again, other)
+		}
+	}
+}
+
+func TestGetNodesAllNodes(t *testing.T) {
+	ch := fiveNodeRing()
+	replicas, err := ch.GetNodes("user:1", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, node := range replicas {
+		seen[node] = true
+	}
+	if len(seen) != 5 {
+		t.Errorf("GetNodes(user:1, 5) = %v, want all five nodes", replicas)
+	}
+}
+
+func TestGetNodesErrors(t *testing.T) {
+	ch := fiveNodeRing()
+	ch.DrainNode("server5") // a drained node can't hold a replica
+
+	for _, n := range []int{0, -1, 5, 6} {
+		if replicas, err := ch.GetNodes("user:1", n); err == nil {
+			t.Errorf("GetNodes(user:1, %d) = %v, want an error", n, replicas)
+		}
+	}
+	if _, err := NewConsistentHash(50).GetNodes("user:1", 1); err == nil {
+		t.Error("GetNodes on an empty ring succeeded")
+	}
+	if _, err := ch.GetNodes("user:1", 4); err != nil {
+		t.Errorf("GetNodes(user:1, 4) with four active nodes = %v", err)
+	}
+}
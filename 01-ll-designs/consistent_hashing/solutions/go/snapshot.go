@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// RingSnapshot is an immutable view of a ConsistentHash at one moment. It
+// never changes after it is published, so a caller can route a batch of
+// related keys against a stable topology while nodes are added, removed or
+// resized on the live ring, and any number of goroutines can read it
+// without locking.
+type RingSnapshot struct {
+	ring     []hashRingEntry // sorted by hash value, then node ID; never modified
+	nodes    map[string]int  // node weights; never modified
+	hashFunc HashFunc
+}
+
+// Snapshot returns the ring as it is now. Later changes to the live ring
+// publish a new snapshot and leave this one untouched.
+func (ch *ConsistentHash) Snapshot() *RingSnapshot {
+	return ch.current.Load()
+}
+
+// publish stores a snapshot of the current ring for lock-free readers.
+// Must be called with the write lock held, after every change.
+func (ch *ConsistentHash) publish() {
+	ch.current.Store(&RingSnapshot{
+		ring:     ch.ring,
+		nodes:    ch.nodeWeights(),
+		hashFunc: ch.hashFunc,
+	})
+}
+
+// GetNode returns the node responsible for a given key in this snapshot
+func (s *RingSnapshot) GetNode(key string) (string, error) {
+	if len(s.ring) == 0 {
+		return "", errors.New("no nodes available")
+	}
+	return s.ring[searchRing(s.ring, s.hashFunc(key))].nodeID, nil
+}
+
+// GetNodesForKeys returns the node responsible for each key in this
+// snapshot. It is empty if the snapshot has no nodes.
+func (s *RingSnapshot) GetNodesForKeys(keys []string) map[string]string {
+	nodes := make(map[string]string, len(keys))
+	s.route(keys, func(key, nodeID string) {
+		nodes[key] = nodeID
+	})
+	return nodes
+}
+
+// GetAllNodes returns the nodes in this snapshot, sorted
+func (s *RingSnapshot) GetAllNodes() []string {
+	nodes := make([]string, 0, len(s.nodes))
+	for nodeID := range s.nodes {
+		nodes = append(nodes, nodeID)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// route calls visit with each key and its node. It calls nothing if the
+// snapshot is empty.
+func (s *RingSnapshot) route(keys []string, visit func(key, nodeID string)) {
+	if len(s.ring) == 0 {
+		return
+	}
+	for _, key := range keys {
+		visit(key, s.ring[searchRing(s.ring, s.hashFunc(key))].nodeID)
+	}
+}
+
+// demonstrateSnapshot routes related keys against a snapshot while the live
+// ring changes
+func demonstrateSnapshot() {
+	fmt.Println("=== Ring Snapshots ===")
+
+	ch := NewConsistentHash(150)
+	for node := 0; node < 3; node++ {
+		ch.AddNode(fmt.Sprintf("server%d", node))
+	}
+
+	cart := []string{"cart:42", "cart:42:items", "cart:42:totals", "cart:42:coupons"}
+	snapshot := ch.Snapshot()
+	before := snapshot.GetNodesForKeys(cart)
+
+	ch.AddNode("server3")
+	ch.RemoveNode("server1")
+
+	fmt.Printf("Snapshot nodes: %v, live nodes: %v\n", snapshot.GetAllNodes(), ch.GetAllNodes())
+	for _, key := range cart {
+		live, _ := ch.GetNode(key)
+		again, _ := snapshot.GetNode(key)
+		fmt.Printf("  %-16s snapshot: %s (still %s), live: %s\n", key, before[key], again, live)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// getNodeLocked looks a key up on the live ring under the read lock, the
+// way GetNode did before snapshots. It is kept to compare the two designs.
+func (ch *ConsistentHash) getNodeLocked(key string) (string, error) {
+	ch.mutex.RLock()
+	defer ch.mutex.RUnlock()
+
+	if len(ch.ring) == 0 {
+		return "", errors.New("no nodes available")
+	}
+	return ch.ring[ch.search(ch.hash(key))].nodeID, nil
+}
+
+func TestSnapshotIgnoresLaterChanges(t *testing.T) {
+	ch := NewConsistentHash(150)
+	for node := 0; node < 3; node++ {
+		ch.AddNode(fmt.Sprintf("server%d", node))
+	}
+	keys := routingKeys(1000)
+	snapshot := ch.Snapshot()
+	before := snapshot.GetNodesForKeys(keys)
+
+	ch.AddNode("server3")
+	ch.RemoveNode("server1")
+	ch.SetNodeWeight("server0", 3)
+
+	if got := fmt.Sprint(snapshot.GetAllNodes()); got != "[server0 server1 server2]" {
+		t.Errorf("snapshot nodes %s, want the nodes at snapshot time", got)
+	}
+	changed := 0
+	for _, key := range keys {
+		if node, _ := snapshot.GetNode(key); node != before[key] {
+			t.Fatalf("%s: snapshot routed to %s, then to %s", key, before[key], node)
+		}
+		if live, _ := ch.GetNode(key); live != before[key] {
+			changed++
+		}
+	}
+	if changed == 0 {
+		t.Error("no key moved on the live ring")
+	}
+}
+
+func TestConcurrentLookupsDuringChanges(t *testing.T) {
+	ch := NewConsistentHash(50)
+	for node := 0; node < 5; node++ {
+		ch.AddNode(fmt.Sprintf("server%d", node))
+	}
+	keys := routingKeys(100)
+
+	var wg sync.WaitGroup
+	var failures atomic.Int64
+	stop := make(chan struct{})
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				for _, key := range keys {
+					// server0 keeps changing weight but never leaves
+					if _, err := ch.GetNode(key); err != nil {
+						failures.Add(1)
+					}
+				}
+				ch.GetNodesForKeys(keys)
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		ch.SetNodeWeight("server0", 1+i%3)
+		ch.AddNode("server5")
+		ch.RemoveNode("server5")
+	}
+	close(stop)
+	wg.Wait()
+
+	if n := failures.Load(); n != 0 {
+		t.Errorf("%d lookups failed while the ring changed", n)
+	}
+}
+
+// BenchmarkLookupParallel compares lookups under the read lock with
+// lock-free snapshot lookups, from GOMAXPROCS readers, with and without a
+// goroutine resizing a node in a tight loop
+func BenchmarkLookupParallel(b *testing.B) {
+	ch := tenNodeRing()
+	keys := routingKeys(10000)
+	lookups := []struct {
+		name   string
+		lookup func(key string) (string, error)
+	}{
+		{"rwmutex", ch.getNodeLocked},
+		{"snapshot", ch.GetNode},
+	}
+
+	for _, resize := range []bool{false, true} {
+		for _, l := range lookups {
+			name := l.name
+			if resize {
+				name += "/writer"
+			}
+			b.Run(name, func(b *testing.B) {
+				var wg sync.WaitGroup
+				stop := make(chan struct{})
+				if resize {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for weight := 1; ; weight = 3 - weight {
+							select {
+							case <-stop:
+								return
+							default:
+							}
+							ch.SetNodeWeight("server0", weight)
+						}
+					}()
+				}
+
+				b.RunParallel(func(pb *testing.PB) {
+					for i := 0; pb.Next(); i++ {
+						l.lookup(keys[i%len(keys)])
+					}
+				})
+				b.StopTimer()
+				close(stop)
+				wg.Wait()
+			})
+		}
+	}
+}
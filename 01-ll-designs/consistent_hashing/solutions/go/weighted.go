@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// nodeWeights returns a copy of each active node's weight.
+// Must be called with the mutex held.
+func (ch *ConsistentHash) nodeWeights() map[string]int {
+	weights := make(map[string]int, len(ch.nodes))
+	for nodeID, weight := range ch.nodes {
+		weights[nodeID] = weight
+	}
+	return weights
+}
+
+// demonstrateWeightedNodes shows a large server taking a proportionally
+// larger share of keys
+func demonstrateWeightedNodes() {
+	fmt.Println("=== Weighted Nodes Demo ===")
+
+	ch := NewConsistentHash(200)
+	ch.AddWeightedNode("large-server", 3)
+	ch.AddNode("small-server")
+	fmt.Printf("Ring info: %v\n", ch.GetRingInfo())
+
+	keys := make([]string, 100000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+	}
+
+	distribution := ch.GetLoadDistribution(keys)
+	nodes := make([]string, 0, len(distribution))
+	for node := range distribution {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	fmt.Printf("Distribution of %d keys with weights 3:1:\n", len(keys))
+	for _, node := range nodes {
+		count := distribution[node]
+		fmt.Printf("  %s: %d keys (%.1f%%)\n", node, count, float64(count)*100.0/float64(len(keys)))
+	}
+	fmt.Printf("Ratio: %.2f:1\n", float64(distribution["large-server"])/float64(distribution["small-server"]))
+
+	ch.RemoveNode("large-server")
+	fmt.Printf("After removing large-server: %v\n", ch.GetRingInfo())
+}
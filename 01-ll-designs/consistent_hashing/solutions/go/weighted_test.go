@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestWeightedNodeShare(t *testing.T) {
+	ch := NewConsistentHash(200)
+	ch.AddWeightedNode("large-server", 3)
+	ch.AddNode("small-server")
+
+	distribution := ch.GetLoadDistribution(routingKeys(100000))
+	ratio := float64(distribution["large-server"]) / float64(distribution["small-server"])
+	if ratio < 2.7 || ratio > 3.3 {
+		t.Errorf("large:small = %d:%d (%.2f:1), want about 3:1",
+			distribution["large-server"], distribution["small-server"], ratio)
+	}
+}
+
+func TestRemoveWeightedNodeRemovesAllPoints(t *testing.T) {
+	const virtualNodes = 50
+	ch := NewConsistentHash(virtualNodes)
+	ch.AddWeightedNode("large-server", 3)
+	ch.AddNode("small-server")
+	if got, want := len(ch.ring), 4*virtualNodes; got != want {
+		t.Fatalf("ring has %d points, want %d", got, want)
+	}
+
+	ch.RemoveNode("large-server")
+	if got, want := len(ch.ring), virtualNodes; got != want {
+		t.Errorf("ring has %d points after removing the weight 3 node, want %d", got, want)
+	}
+	for _, entry := range ch.ring {
+		if entry.nodeID != "small-server" {
+			t.Fatalf("point %d still belongs to %s", entry.hash, entry.nodeID)
+		}
+	}
+	if got := ch.Snapshot().GetAllNodes(); len(got) != 1 || got[0] != "small-server" {
+		t.Errorf("snapshot nodes = %v, want [small-server]", got)
+	}
+}
+
+func TestAddWeightedNodeClampsWeight(t *testing.T) {
+	ch := NewConsistentHash(10)
+	ch.AddWeightedNode("server", 0)
+	if got := len(ch.ring); got != 10 {
+		t.Errorf("weight 0 placed %d points, want 10 (weight 1)", got)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConcurrentLRU makes an LRUCache safe for concurrent use by holding a
+// mutex around every call. Get reorders the list, so reads take the same
+// lock as writes. Eviction callbacks run with the lock held and must not
+// call back into the ConcurrentLRU.
+type ConcurrentLRU[K comparable, V any] struct {
+	mu    sync.Mutex
+	cache *LRUCache[K, V]
+	loads map[K]*load[V] // loaders running for GetOrLoad, by key
+}
+
+// load is a GetOrLoad call in flight that other callers for the same key
+// wait on
+type load[V any] struct {
+	done  chan struct{} // closed once value and err are set
+	value V
+	err   error
+}
+
+// NewConcurrentLRU wraps cache, which must not be used directly afterwards
+func NewConcurrentLRU[K comparable, V any](cache *LRUCache[K, V]) *ConcurrentLRU[K, V] {
+	return &ConcurrentLRU[K, V]{
+		cache: cache,
+		loads: make(map[K]*load[V]),
+	}
+}
+
+// Get retrieves value by key and marks as recently used
+func (c *ConcurrentLRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Get(key)
+}
+
+// Put inserts or updates key-value pair
+func (c *ConcurrentLRU[K, V]) Put(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.Put(key, value)
+}
+
+// PutWithTTL inserts or updates key-value pair that expires after ttl
+func (c *ConcurrentLRU[K, V]) PutWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.PutWithTTL(key, value, ttl)
+}
+
+// Delete removes key from the cache and reports whether it was present
+func (c *ConcurrentLRU[K, V]) Delete(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Delete(key)
+}
+
+// Size returns current number of items in cache
+func (c *ConcurrentLRU[K, V]) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Size()
+}
+
+// Stats returns the cache's usage counters
+func (c *ConcurrentLRU[K, V]) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Stats()
+}
+
+// GetOrLoad returns the cached value for key, or on a miss calls loader and
+// caches what it returns. Concurrent misses for the same key share one
+// loader call: the first caller runs it without holding the lock, and the
+// rest wait for its result instead of each loading the value again. A
+// loader error is returned to every waiter and nothing is cached, so the
+// next call tries again. If loader panics, waiters get an error and the
+// panic continues in the caller that ran it.
+func (c *ConcurrentLRU[K, V]) GetOrLoad(key K, loader func() (V, error)) (V, error) {
+	c.mu.Lock()
+	if value, found := c.cache.Get(key); found {
+		c.mu.Unlock()
+		return value, nil
+	}
+	if inFlight, exists := c.loads[key]; exists {
+		c.mu.Unlock()
+		<-inFlight.done
+		return inFlight.value, inFlight.err
+	}
+	call := &load[V]{done: make(chan struct{})}
+	c.loads[key] = call
+	c.mu.Unlock()
+
+	finished := false
+	defer func() {
+		if !finished {
+			call.err = fmt.Errorf("loader for %v panicked", key)
+		}
+		c.mu.Lock()
+		if call.err == nil {
+			c.cache.Put(key, call.value)
+		}
+		delete(c.loads, key)
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.value, call.err = loader()
+	finished = true
+	return call.value, call.err
+}
+
+// demoGetOrLoad demonstrates a burst of concurrent misses on a hot key
+// triggering a single slow load
+func demoGetOrLoad() {
+	fmt.Println("Testing GetOrLoad Request Coalescing")
+	fmt.Println("========================================")
+
+	cache := NewConcurrentLRU(NewLRUCache[string, string](100))
+
+	var loads int64
+	loader := func() (string, error) {
+		atomic.AddInt64(&loads, 1)
+		time.Sleep(50 * time.Millisecond) // an expensive database query
+		return "profile of user 42", nil
+	}
+
+	const callers = 100
+	var wg sync.WaitGroup
+	results := make([]string, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = cache.GetOrLoad("user:42", loader)
+		}(i)
+	}
+	wg.Wait()
+
+	fmt.Printf("%d concurrent GetOrLoad calls: loader ran %d time(s), result %q\n",
+		callers, atomic.LoadInt64(&loads), results[callers-1])
+
+	cache.GetOrLoad("user:42", loader)
+	fmt.Printf("Next call is a hit: loader ran %d time(s), hits %d\n",
+		atomic.LoadInt64(&loads), cache.Stats().Hits)
+}
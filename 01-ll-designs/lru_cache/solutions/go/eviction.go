@@ -0,0 +1,83 @@
+package main
+
+import "fmt"
+
+// Option configures optional behavior of an LRU cache at construction time
+type Option[K comparable, V any] func(*LRUCache[K, V])
+
+// WithOnEvict registers a callback for entries removed by the cache itself,
+// either to make room for a new key or because their TTL passed. It runs
+// after the cache has finished updating, once per removed entry, so it may
+// safely call back into the cache. Overwriting a key does not trigger it.
+func WithOnEvict[K comparable, V any](onEvict func(key K, value V)) Option[K, V] {
+	return func(lru *LRUCache[K, V]) {
+		lru.onEvict = onEvict
+	}
+}
+
+// CacheStats is a snapshot of cache usage counters
+type CacheStats struct {
+	Hits        uint64 // Get calls that found a live entry
+	Misses      uint64 // Get calls for a missing or expired key
+	Evictions   uint64 // entries removed to make room for new keys
+	Expirations uint64 // entries removed because their TTL passed
+	Size        int
+	Capacity    int
+	TotalCost   int64 // sum of entry costs
+	MaxCost     int64 // cost budget, zero if unlimited
+}
+
+// HitRatio returns the fraction of Get calls that were hits, or 0 before
+// the first Get
+func (s CacheStats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// Stats returns the cache's usage counters
+func (lru *LRUCache[K, V]) Stats() CacheStats {
+	return CacheStats{
+		Hits:        lru.hits,
+		Misses:      lru.misses,
+		Evictions:   lru.evictions,
+		Expirations: lru.expirations,
+		Size:        len(lru.cache),
+		Capacity:    lru.capacity,
+		TotalCost:   lru.totalCost,
+		MaxCost:     lru.maxCost,
+	}
+}
+
+// notifyEvict passes a removed entry to the eviction callback, if any
+func (lru *LRUCache[K, V]) notifyEvict(node *Node[K, V]) {
+	if lru.onEvict != nil {
+		lru.onEvict(node.key, node.value)
+	}
+}
+
+// demoEviction demonstrates eviction callbacks and usage statistics
+func demoEviction() {
+	fmt.Println("Testing Eviction Callbacks and Stats")
+	fmt.Println("========================================")
+
+	cache := NewLRUCache[string, int](2, WithOnEvict(func(key string, value int) {
+		fmt.Printf("Evicted %s = %d\n", key, value)
+	}))
+
+	cache.Put("a", 1)
+	cache.Put("b", 2)
+	cache.Get("a")
+	cache.Put("c", 3)  // Evicts b
+	cache.Get("b")     // Miss
+	cache.Put("a", 10) // Overwrite, no callback
+	cache.Put("d", 4)  // Evicts c
+	cache.Get("a")
+
+	stats := cache.Stats()
+	fmt.Printf("Hits: %d, Misses: %d, Evictions: %d, Size: %d/%d\n",
+		stats.Hits, stats.Misses, stats.Evictions, stats.Size, stats.Capacity)
+	fmt.Printf("Hit ratio: %.2f\n", stats.HitRatio())
+}
@@ -0,0 +1,62 @@
+package main
+
+import "fmt"
+
+// Keys returns the keys of live entries from most to least recently used.
+// It does not change recency, and expired entries are skipped.
+func (lru *LRUCache[K, V]) Keys() []K {
+	keys := make([]K, 0, len(lru.cache))
+	lru.ForEach(func(key K, value V) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// ForEach calls fn for each live entry from most to least recently used,
+// stopping early if fn returns false. It walks the list directly instead of
+// going through Get, so iterating does not change recency or stats, and
+// expired entries are skipped without being removed.
+//
+// fn must not modify the cache. The cache is not thread-safe; a concurrent
+// wrapper should hold its lock for the whole iteration.
+func (lru *LRUCache[K, V]) ForEach(fn func(key K, value V) bool) {
+	for node := lru.head.next; node != lru.tail; node = node.next {
+		if lru.expired(node) {
+			continue
+		}
+		if !fn(node.key, node.value) {
+			return
+		}
+	}
+}
+
+// demoIteration demonstrates walking entries in recency order
+func demoIteration() {
+	fmt.Println("Testing Iteration")
+	fmt.Println("========================================")
+
+	cache := NewLRUCache[string, int](4)
+	cache.Put("a", 1)
+	cache.Put("b", 2)
+	cache.Put("c", 3)
+	cache.Put("d", 4)
+	cache.Get("b")
+	fmt.Printf("Keys() = %v\n", cache.Keys()) // Most recent first
+
+	cache.Delete("d") // Head
+	cache.Delete("a") // Tail
+	fmt.Printf("After Delete(d), Delete(a): Keys() = %v\n", cache.Keys())
+
+	cache.Put("e", 5)
+	fmt.Print("ForEach until value < 3:")
+	cache.ForEach(func(key string, value int) bool {
+		if value < 3 {
+			return false
+		}
+		fmt.Printf(" %s=%d", key, value)
+		return true
+	})
+	fmt.Println()
+	fmt.Printf("Keys() unchanged by iteration = %v\n", cache.Keys())
+}
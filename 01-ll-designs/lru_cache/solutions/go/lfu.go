@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// EvictionPolicy chooses which entry a full cache evicts
+type EvictionPolicy int
+
+const (
+	// PolicyLRU evicts the least recently used entry
+	PolicyLRU EvictionPolicy = iota
+	// PolicyLFU evicts the least frequently used entry, breaking ties by
+	// recency, so a one-time scan cannot push out keys that are read often
+	PolicyLFU
+)
+
+// lfuAgingFactor sets how often PolicyLFU halves every access count: once
+// per lfuAgingFactor accesses per cached entry. Halving lets keys that were
+// hot long ago decay until newer keys can displace them.
+const lfuAgingFactor = 10
+
+// WithEvictionPolicy selects the eviction policy. The default is PolicyLRU.
+func WithEvictionPolicy[K comparable, V any](policy EvictionPolicy) Option[K, V] {
+	return func(lru *LRUCache[K, V]) {
+		lru.policy = policy
+		if policy == PolicyLFU {
+			lru.buckets = make(map[int]*freqBucket[K, V])
+		}
+	}
+}
+
+// freqBucket lists the entries with one access count, most recently used
+// first, linked through their fprev and fnext pointers
+type freqBucket[K comparable, V any] struct {
+	head *Node[K, V]
+	tail *Node[K, V]
+}
+
+// newFreqBucket creates an empty bucket with dummy head and tail
+func newFreqBucket[K comparable, V any]() *freqBucket[K, V] {
+	bucket := &freqBucket[K, V]{
+		head: &Node[K, V]{},
+		tail: &Node[K, V]{},
+	}
+	bucket.head.fnext = bucket.tail
+	bucket.tail.fprev = bucket.head
+	return bucket
+}
+
+// pushFront adds node as the bucket's most recently used entry
+func (b *freqBucket[K, V]) pushFront(node *Node[K, V]) {
+	node.fprev = b.head
+	node.fnext = b.head.fnext
+	b.head.fnext.fprev = node
+	b.head.fnext = node
+}
+
+// victim returns the bucket's least recently used entry other than protect,
+// or nil if there is none
+func (b *freqBucket[K, V]) victim(protect *Node[K, V]) *Node[K, V] {
+	node := b.tail.fprev
+	if node == protect {
+		node = node.fprev
+	}
+	if node == b.head {
+		return nil
+	}
+	return node
+}
+
+// empty reports whether the bucket has no entries
+func (b *freqBucket[K, V]) empty() bool {
+	return b.head.fnext == b.tail
+}
+
+// addToBucket adds node to the bucket for its access count
+func (lru *LRUCache[K, V]) addToBucket(node *Node[K, V]) {
+	bucket, exists := lru.buckets[node.freq]
+	if !exists {
+		bucket = newFreqBucket[K, V]()
+		lru.buckets[node.freq] = bucket
+	}
+	bucket.pushFront(node)
+}
+
+// untrack removes node from its bucket, dropping the bucket once empty
+func (lru *LRUCache[K, V]) untrack(node *Node[K, V]) {
+	node.fprev.fnext = node.fnext
+	node.fnext.fprev = node.fprev
+	if lru.buckets[node.freq].empty() {
+		delete(lru.buckets, node.freq)
+	}
+}
+
+// track counts the first access to a newly inserted node
+func (lru *LRUCache[K, V]) track(node *Node[K, V]) {
+	node.freq = 1
+	lru.addToBucket(node)
+	lru.minFreq = 1
+	lru.recordAccess()
+}
+
+// touch counts another access to node, moving it up one bucket
+func (lru *LRUCache[K, V]) touch(node *Node[K, V]) {
+	lru.untrack(node)
+	if _, exists := lru.buckets[node.freq]; !exists && lru.minFreq == node.freq {
+		lru.minFreq = node.freq + 1
+	}
+	node.freq++
+	lru.addToBucket(node)
+	lru.recordAccess()
+}
+
+// lfuVictim returns the least recently used entry among those with the
+// lowest access count, other than protect. The cache must hold at least
+// one entry besides protect.
+func (lru *LRUCache[K, V]) lfuVictim(protect *Node[K, V]) *Node[K, V] {
+	if bucket, exists := lru.buckets[lru.minFreq]; exists {
+		if node := bucket.victim(protect); node != nil {
+			return node
+		}
+	}
+
+	// minFreq is stale after a removal, or its bucket holds only protect,
+	// so scan the buckets for the lowest one
+	var victim *Node[K, V]
+	victimFreq, minFreq := 0, 0
+	for freq, bucket := range lru.buckets {
+		if minFreq == 0 || freq < minFreq {
+			minFreq = freq
+		}
+		if victim != nil && freq >= victimFreq {
+			continue
+		}
+		if node := bucket.victim(protect); node != nil {
+			victim, victimFreq = node, freq
+		}
+	}
+	lru.minFreq = minFreq
+	return victim
+}
+
+// recordAccess counts an access and ages the counts once enough have
+// accumulated, keeping the cost of aging O(1) amortized per access
+func (lru *LRUCache[K, V]) recordAccess() {
+	lru.accesses++
+	if lru.accesses < lfuAgingFactor*len(lru.cache) {
+		return
+	}
+	lru.accesses = 0
+
+	// Halve every count and rebuild the buckets. Walking from the least
+	// recently used entry keeps each bucket in recency order.
+	lru.buckets = make(map[int]*freqBucket[K, V])
+	lru.minFreq = 0
+	for node := lru.tail.prev; node != lru.head; node = node.prev {
+		node.freq = (node.freq + 1) / 2
+		lru.addToBucket(node)
+		if lru.minFreq == 0 || node.freq < lru.minFreq {
+			lru.minFreq = node.freq
+		}
+	}
+}
+
+// Parameters of the Zipfian workload zipfHitRatio runs
+const (
+	zipfCapacity = 100
+	zipfKeySpace = 10000
+	zipfRequests = 200000
+)
+
+// zipfHitRatio reads keys drawn from a Zipf(1.1) distribution through a
+// cache with the given policy, caching each miss, and returns the hit
+// ratio. With scans, every 1000th request starts a sweep of 200 keys that
+// are never read again. The seed is fixed, so the result is repeatable.
+func zipfHitRatio(policy EvictionPolicy, scans bool) float64 {
+	cache := NewLRUCache[int, int](zipfCapacity, WithEvictionPolicy[int, int](policy))
+	zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.1, 1, zipfKeySpace-1)
+	scanKey := zipfKeySpace
+
+	for i := 0; i < zipfRequests; i++ {
+		if scans && i%1000 == 0 {
+			for j := 0; j < 200; j++ {
+				cache.Put(scanKey, scanKey)
+				scanKey++
+			}
+		}
+		key := int(zipf.Uint64())
+		if _, found := cache.Get(key); !found {
+			cache.Put(key, key)
+		}
+	}
+	return cache.Stats().HitRatio()
+}
+
+// demoEvictionPolicies compares LRU and LFU hit ratios on a Zipfian
+// workload, with and without one-time scans mixed in
+func demoEvictionPolicies() {
+	fmt.Println("Testing LRU vs LFU Eviction")
+	fmt.Println("========================================")
+
+	fmt.Printf("Zipf(1.1) over %d keys, capacity %d, %d reads\n", zipfKeySpace, zipfCapacity, zipfRequests)
+	fmt.Printf("%-16s %8s %8s\n", "Workload", "LRU", "LFU")
+	fmt.Printf("%-16s %8.3f %8.3f\n", "Zipf", zipfHitRatio(PolicyLRU, false), zipfHitRatio(PolicyLFU, false))
+	fmt.Printf("%-16s %8.3f %8.3f\n", "Zipf + scans", zipfHitRatio(PolicyLRU, true), zipfHitRatio(PolicyLFU, true))
+}
@@ -2,7 +2,7 @@
 LRU Cache Implementation in Go
 
 A Least Recently Used (LRU) cache implementation using a doubly-linked list
-and map for O(1) get and put operations.
+and map for O(1) get and put operations, generic over key and value types.
 
 Time Complexity:
 - Get(): O(1)
@@ -13,19 +13,29 @@ Space Complexity: O(capacity)
 
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // Node represents a doubly-linked list node for LRU cache
-type Node struct {
-	key   int
-	value int
-	prev  *Node
-	next  *Node
+type Node[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time // zero if the entry never expires
+	cost      int64     // weight counted against maxCost
+	prev      *Node[K, V]
+	next      *Node[K, V]
+	
+	// Access count and frequency bucket links, used by PolicyLFU
+	freq  int
+	fprev *Node[K, V]
+	fnext *Node[K, V]
 }
 
 // NewNode creates a new node with given key and value
-func NewNode(key, value int) *Node {
-	return &Node{
+func NewNode[K comparable, V any](key K, value V) *Node[K, V] {
+	return &Node[K, V]{
 		key:   key,
 		value: value,
 	}
@@ -36,37 +46,59 @@ func NewNode(key, value int) *Node {
 // Uses a combination of:
 // - Map for O(1) key lookup
 // - Doubly-linked list for O(1) insertion/deletion
-type LRUCache struct {
+type LRUCache[K comparable, V any] struct {
 	capacity int
-	cache    map[int]*Node
-	head     *Node
-	tail     *Node
+	cache    map[K]*Node[K, V]
+	head     *Node[K, V]
+	tail     *Node[K, V]
+	now      func() time.Time // clock for TTL expiry, replaceable with SetClock
+	onEvict  func(key K, value V)
+	
+	// Frequency buckets, set by WithEvictionPolicy(PolicyLFU)
+	policy   EvictionPolicy
+	buckets  map[int]*freqBucket[K, V]
+	minFreq  int // lowest bucket, may be stale after a removal
+	accesses int // since the last aging pass
+	
+	// Cost budget, set by NewWeightedLRUCache; zero means unlimited
+	maxCost   int64
+	totalCost int64
+	
+	// Counters reported by Stats
+	hits        uint64
+	misses      uint64
+	evictions   uint64
+	expirations uint64
 }
 
-// NewLRUCache initializes LRU cache with given capacity
-func NewLRUCache(capacity int) *LRUCache {
-	cache := &LRUCache{
+// NewLRUCache initializes LRU cache with given capacity and options
+func NewLRUCache[K comparable, V any](capacity int, opts ...Option[K, V]) *LRUCache[K, V] {
+	cache := &LRUCache[K, V]{
 		capacity: capacity,
-		cache:    make(map[int]*Node),
-		head:     &Node{}, // dummy head
-		tail:     &Node{}, // dummy tail
+		cache:    make(map[K]*Node[K, V]),
+		head:     &Node[K, V]{}, // dummy head
+		tail:     &Node[K, V]{}, // dummy tail
+		now:      time.Now,
 	}
 	
 	// Connect dummy nodes
 	cache.head.next = cache.tail
 	cache.tail.prev = cache.head
 	
+	for _, opt := range opts {
+		opt(cache)
+	}
 	return cache
 }
 
 // removeNode removes node from doubly-linked list
-func (lru *LRUCache) removeNode(node *Node) {
+func (lru *LRUCache[K, V]) removeNode(node *Node[K, V]) {
 	node.prev.next = node.next
 	node.next.prev = node.prev
 }
 
 // addToHead adds node right after head (most recently used position)
-func (lru *LRUCache) addToHead(node *Node) {
+func (lru *LRUCache[K, V]) addToHead(node *Node[K, V]) {
 	node.prev = lru.head
 	node.next = lru.head.next
 	lru.head.next.prev = node
@@ -74,54 +106,141 @@ func (lru *LRUCache) addToHead(node *Node) {
 }
 
 // moveToHead moves existing node to head (mark as recently used)
-func (lru *LRUCache) moveToHead(node *Node) {
+func (lru *LRUCache[K, V]) moveToHead(node *Node[K, V]) {
 	lru.removeNode(node)
 	lru.addToHead(node)
 }
 
-// removeTail removes and returns the last node (least recently used)
-func (lru *LRUCache) removeTail() *Node {
-	lastNode := lru.tail.prev
-	lru.removeNode(lastNode)
-	return lastNode
+// removeEntry unlinks node and deletes it from the map
+func (lru *LRUCache[K, V]) removeEntry(node *Node[K, V]) {
+	lru.removeNode(node)
+	delete(lru.cache, node.key)
+	lru.totalCost -= node.cost
+	if lru.policy == PolicyLFU {
+		lru.untrack(node)
+	}
+}
+
+// evict removes and returns an entry to make room, chosen by the eviction
+// policy: the least recently used one, or for PolicyLFU the least
+// frequently used one. protect, if not nil, is never chosen.
+func (lru *LRUCache[K, V]) evict(protect *Node[K, V]) *Node[K, V] {
+	node := lru.tail.prev // least recently used
+	if lru.policy == PolicyLFU {
+		node = lru.lfuVictim(protect)
+	}
+	lru.removeEntry(node)
+	lru.evictions++
+	return node
 }
 
 // Get retrieves value by key and marks as recently used
 //
-// Returns value if key exists, -1 otherwise
-func (lru *LRUCache) Get(key int) int {
+// Returns the value and true if key exists and has not expired, otherwise
+// the zero value and false
+func (lru *LRUCache[K, V]) Get(key K) (V, bool) {
 	if node, exists := lru.cache[key]; exists {
+		if lru.expired(node) {
+			lru.removeEntry(node)
+			lru.expirations++
+			lru.misses++
+			lru.notifyEvict(node)
+			var zero V
+			return zero, false
+		}
+		
 		// Move to head (mark as recently used)
 		lru.moveToHead(node)
-		return node.value
+		if lru.policy == PolicyLFU {
+			lru.touch(node)
+		}
+		lru.hits++
+		return node.value, true
 	}
-	return -1
+	lru.misses++
+	var zero V
+	return zero, false
+}
+
+// Put inserts or updates key-value pair. The entry never expires, even if
+// it replaces one stored with a TTL, and has a cost of zero.
+func (lru *LRUCache[K, V]) Put(key K, value V) {
+	lru.put(key, value, time.Time{}, 0)
 }
 
-// Put inserts or updates key-value pair
-func (lru *LRUCache) Put(key, value int) {
+// put inserts or updates key-value pair with the given expiry time and cost,
+// then evicts entries until the cache is within its capacity and cost budget
+func (lru *LRUCache[K, V]) put(key K, value V, expiresAt time.Time, cost int64) {
+	var evicted []*Node[K, V]
 	if node, exists := lru.cache[key]; exists {
 		// Update existing key
 		node.value = value
+		node.expiresAt = expiresAt
+		lru.totalCost += cost - node.cost
+		node.cost = cost
 		lru.moveToHead(node)
+		if lru.policy == PolicyLFU {
+			lru.touch(node)
+		}
 	} else {
 		// Insert new key
 		newNode := NewNode(key, value)
+		newNode.expiresAt = expiresAt
+		newNode.cost = cost
 		
 		if len(lru.cache) >= lru.capacity {
-			// Remove least recently used item
-			tailNode := lru.removeTail()
-			delete(lru.cache, tailNode.key)
+			// Make room for the new key
+			evicted = append(evicted, lru.evict(nil))
 		}
 		
 		// Add new node
 		lru.cache[key] = newNode
 		lru.addToHead(newNode)
+		lru.totalCost += cost
+		if lru.policy == PolicyLFU {
+			lru.track(newNode)
+		}
+	}
+	
+	// The entry just stored is within budget, so it is never evicted
+	for lru.maxCost > 0 && lru.totalCost > lru.maxCost {
+		evicted = append(evicted, lru.evict(lru.cache[key]))
+	}
+	
+	// Notify once the cache is consistent again
+	for _, node := range evicted {
+		lru.notifyEvict(node)
+	}
+}
+
+// Delete removes key from the cache and reports whether it was present.
+// The eviction callback is not called for deleted entries.
+func (lru *LRUCache[K, V]) Delete(key K) bool {
+	node, exists := lru.cache[key]
+	if !exists {
+		return false
 	}
+	lru.removeEntry(node)
+	return true
 }
 
-// Size returns current number of items in cache
-func (lru *LRUCache) Size() int {
+// Clear removes every entry, leaving the cache empty with its capacity,
+// options, and stats unchanged. The eviction callback is not called.
+func (lru *LRUCache[K, V]) Clear() {
+	// Drop the old map and list so their nodes can be garbage collected
+	lru.cache = make(map[K]*Node[K, V])
+	lru.head.next = lru.tail
+	lru.tail.prev = lru.head
+	lru.totalCost = 0
+	if lru.policy == PolicyLFU {
+		lru.buckets = make(map[int]*freqBucket[K, V])
+		lru.accesses = 0
+	}
+}
+
+// Size returns current number of items in cache, including expired
+// entries that have not been removed yet
+func (lru *LRUCache[K, V]) Size() int {
 	return len(lru.cache)
 }
 
@@ -130,7 +249,8 @@ func main() {
 	fmt.Println("Testing LRU Cache Implementation")
 	fmt.Println("========================================")
 	
-	cache := NewLRUCache(2)
+	// The original int/int cache is just one instantiation of the generic type
+	cache := NewLRUCache[int, int](2)
 	
 	fmt.Println("Creating cache with capacity 2")
 	
@@ -140,26 +260,92 @@ func main() {
 	cache.Put(2, 2)
 	fmt.Println("Put(2, 2)")
 	
-	result := cache.Get(1)
-	fmt.Printf("Get(1) = %d\n", result) // Should return 1
+	value, found := cache.Get(1)
+	fmt.Printf("Get(1) = %d, %t\n", value, found) // Should return 1, true
 	
 	cache.Put(3, 3) // Evicts key 2
 	fmt.Println("Put(3, 3) - evicts key 2")
 	
-	result = cache.Get(2)
-	fmt.Printf("Get(2) = %d\n", result) // Should return -1 (not found)
+	value, found = cache.Get(2)
+	fmt.Printf("Get(2) = %d, %t\n", value, found) // Should return 0, false (not found)
 	
 	cache.Put(4, 4) // Evicts key 1
 	fmt.Println("Put(4, 4) - evicts key 1")
 	
-	result = cache.Get(1)
-	fmt.Printf("Get(1) = %d\n", result) // Should return -1 (not found)
+	value, found = cache.Get(1)
+	fmt.Printf("Get(1) = %d, %t\n", value, found) // Should return 0, false (not found)
 	
-	result = cache.Get(3)
-	fmt.Printf("Get(3) = %d\n", result) // Should return 3
+	value, found = cache.Get(3)
+	fmt.Printf("Get(3) = %d, %t\n", value, found) // Should return 3, true
 	
-	result = cache.Get(4)
-	fmt.Printf("Get(4) = %d\n", result) // Should return 4
+	cache.Put(4, -1) // -1 is an ordinary value, not a not-found sentinel
+	value, found = cache.Get(4)
+	fmt.Printf("Put(4, -1), Get(4) = %d, %t\n", value, found) // Should return -1, true
 	
 	fmt.Printf("\nFinal cache size: %d\n", cache.Size())
-}
\ No newline at end of file
+	
+	fmt.Println()
+	demoGenericTypes()
+	fmt.Println()
+	demoTTL()
+	fmt.Println()
+	demoEviction()
+	fmt.Println()
+	demoDeleteAndClear()
+	fmt.Println()
+	demoIteration()
+	fmt.Println()
+	demoWeighted()
+	fmt.Println()
+	demoEvictionPolicies()
+	fmt.Println()
+	demoGetOrLoad()
+}
+
+// demoGenericTypes demonstrates a cache with string keys and struct values
+func demoGenericTypes() {
+	fmt.Println("Testing Generic Key and Value Types")
+	fmt.Println("========================================")
+	
+	type user struct {
+		Name  string
+		Email string
+	}
+	
+	users := NewLRUCache[string, user](2)
+	users.Put("u1", user{Name: "Ada", Email: "ada@example.com"})
+	users.Put("u2", user{Name: "Linus", Email: "linus@example.com"})
+	users.Get("u1")
+	users.Put("u3", user{Name: "Grace", Email: "grace@example.com"}) // Evicts u2
+	
+	for _, id := range []string{"u1", "u2", "u3"} {
+		if u, found := users.Get(id); found {
+			fmt.Printf("Get(%q) = %s <%s>\n", id, u.Name, u.Email)
+		} else {
+			fmt.Printf("Get(%q) = not found\n", id)
+		}
+	}
+}
+
+// demoDeleteAndClear demonstrates removing single keys and emptying the cache
+func demoDeleteAndClear() {
+	fmt.Println("Testing Delete and Clear")
+	fmt.Println("========================================")
+	
+	cache := NewLRUCache[int, int](3)
+	cache.Put(1, 1)
+	cache.Put(2, 2)
+	cache.Put(3, 3)
+	fmt.Printf("Put 1, 2, 3: size %d\n", cache.Size())
+	
+	fmt.Printf("Delete(2) = %t, size %d\n", cache.Delete(2), cache.Size()) // Middle node
+	fmt.Printf("Delete(2) = %t, size %d\n", cache.Delete(2), cache.Size()) // Already gone
+	
+	cache.Put(4, 4) // Fills the freed slot without evicting
+	_, found := cache.Get(1)
+	fmt.Printf("Put(4, 4), Get(1) found = %t\n", found)
+	
+	cache.Clear()
+	_, found = cache.Get(3)
+	fmt.Printf("Clear(): size %d, Get(3) found = %t\n", cache.Size(), found)
+}
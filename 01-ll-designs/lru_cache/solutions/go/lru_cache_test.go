@@ -0,0 +1,263 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// checkList fails unless the cache's list holds exactly want, most recent
+// first, linked the same way in both directions
+func checkList(t *testing.T, cache *LRUCache[int, int], want ...int) {
+	t.Helper()
+
+	var forward, backward []int
+	for node := cache.head.next; node != cache.tail; node = node.next {
+		forward = append(forward, node.key)
+	}
+	for node := cache.tail.prev; node != cache.head; node = node.prev {
+		backward = append([]int{node.key}, backward...)
+	}
+	if fmt.Sprint(forward) != fmt.Sprint(want) || fmt.Sprint(backward) != fmt.Sprint(want) {
+		t.Fatalf("list forward %v, backward %v, want %v", forward, backward, want)
+	}
+	if cache.Size() != len(want) {
+		t.Fatalf("Size() = %d, want %d", cache.Size(), len(want))
+	}
+}
+
+func TestTTLExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := NewLRUCache[int, int](3)
+	cache.SetClock(func() time.Time { return now })
+
+	cache.PutWithTTL(1, 10, time.Minute)
+	cache.PutWithTTL(2, 20, time.Hour)
+	cache.Put(3, 30)
+
+	now = now.Add(30 * time.Second)
+	if value, found := cache.Get(1); !found || value != 10 {
+		t.Errorf("after 30s: Get(1) = %d, %t; want 10, true", value, found)
+	}
+
+	now = now.Add(time.Minute)
+	if value, found := cache.Get(1); found {
+		t.Errorf("after 1m30s: Get(1) = %d, true; want expired", value)
+	}
+	if value, found := cache.Get(2); !found || value != 20 {
+		t.Errorf("after 1m30s: Get(2) = %d, %t; want 20, true", value, found)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if removed := cache.PurgeExpired(); removed != 1 {
+		t.Errorf("after 2h: PurgeExpired() = %d, want 1", removed)
+	}
+	if value, found := cache.Get(3); !found || value != 30 {
+		t.Errorf("after 2h: Get(3) = %d, %t; want 30, true (no TTL)", value, found)
+	}
+
+	if size := cache.Size(); size != 1 {
+		t.Errorf("Size() = %d, want 1", size)
+	}
+	if stats := cache.Stats(); stats.Expirations != 2 || stats.Evictions != 0 {
+		t.Errorf("stats %+v, want 2 expirations and no evictions", stats)
+	}
+}
+
+func TestTTLExpiryAtDeadline(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := NewLRUCache[int, int](2)
+	cache.SetClock(func() time.Time { return now })
+
+	cache.PutWithTTL(1, 10, time.Minute)
+	cache.PutWithTTL(2, 20, 0) // no expiry, like Put
+
+	now = now.Add(time.Minute)
+	if _, found := cache.Get(1); found {
+		t.Error("Get(1) found an entry exactly at its expiry")
+	}
+	now = now.Add(24 * time.Hour)
+	if _, found := cache.Get(2); !found {
+		t.Error("Get(2) lost an entry stored with a zero TTL")
+	}
+}
+
+func TestEvictionCallbackOncePerEviction(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	calls := make(map[int]int)
+	cache := NewLRUCache[int, int](2, WithOnEvict(func(key, value int) {
+		calls[key]++
+	}))
+	cache.SetClock(func() time.Time { return now })
+
+	cache.Put(1, 1)
+	cache.Put(2, 2)
+	cache.Put(3, 3)                     // evicts 1 for room
+	cache.Put(3, 30)                    // overwrite, no callback
+	cache.PutWithTTL(4, 4, time.Minute) // evicts 2 for room
+	now = now.Add(time.Hour)
+	cache.Get(4)         // expired on read
+	cache.PurgeExpired() // nothing left to expire
+	cache.PutWithTTL(5, 5, time.Minute)
+	now = now.Add(time.Hour)
+	cache.PurgeExpired() // expires 5
+	cache.Delete(3)      // deleted, no callback
+	cache.Put(6, 6)
+	cache.Clear() // cleared, no callback
+
+	want := map[int]int{1: 1, 2: 1, 4: 1, 5: 1}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("callbacks per key %v, want %v", calls, want)
+	}
+	stats := cache.Stats()
+	if total := stats.Evictions + stats.Expirations; total != 4 {
+		t.Errorf("stats count %d evictions and %d expirations, want 4 in total", stats.Evictions, stats.Expirations)
+	}
+}
+
+func TestEvictionCallbackMayUseCache(t *testing.T) {
+	var cache *LRUCache[int, int]
+	var sizes []int
+	cache = NewLRUCache[int, int](2, WithOnEvict(func(key, value int) {
+		// The cache is consistent again by the time the callback runs
+		sizes = append(sizes, cache.Size())
+		cache.Get(key)
+	}))
+
+	cache.Put(1, 1)
+	cache.Put(2, 2)
+	cache.Put(3, 3)
+	if fmt.Sprint(sizes) != "[2]" {
+		t.Errorf("callback saw sizes %v, want [2]", sizes)
+	}
+	checkList(t, cache, 3, 2)
+}
+
+func TestDeleteHeadTailAndMiddle(t *testing.T) {
+	tests := []struct {
+		name   string
+		delete int
+		want   []int
+	}{
+		{"head", 4, []int{3, 2, 1}},
+		{"tail", 1, []int{4, 3, 2}},
+		{"middle", 3, []int{4, 2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewLRUCache[int, int](4)
+			for key := 1; key <= 4; key++ {
+				cache.Put(key, key)
+			}
+			checkList(t, cache, 4, 3, 2, 1)
+
+			if !cache.Delete(tt.delete) {
+				t.Fatalf("Delete(%d) = false, want true", tt.delete)
+			}
+			checkList(t, cache, tt.want...)
+			if cache.Delete(tt.delete) {
+				t.Errorf("second Delete(%d) = true, want false", tt.delete)
+			}
+
+			// The freed slot takes a new key without evicting
+			cache.Put(5, 5)
+			checkList(t, cache, append([]int{5}, tt.want...)...)
+		})
+	}
+}
+
+func TestDeleteOnlyEntry(t *testing.T) {
+	cache := NewLRUCache[int, int](1)
+	cache.Put(1, 1)
+	if !cache.Delete(1) {
+		t.Fatal("Delete(1) = false, want true")
+	}
+	checkList(t, cache)
+	cache.Put(2, 2)
+	checkList(t, cache, 2)
+}
+
+func TestGetOrLoadRunsLoaderOnce(t *testing.T) {
+	cache := NewConcurrentLRU(NewLRUCache[string, string](100))
+
+	var loads int64
+	release := make(chan struct{})
+	loader := func() (string, error) {
+		atomic.AddInt64(&loads, 1)
+		<-release // hold the load open until every caller is waiting
+		return "profile of user 42", nil
+	}
+
+	const callers = 100
+	var started, finished sync.WaitGroup
+	results := make([]string, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		started.Add(1)
+		finished.Add(1)
+		go func(i int) {
+			defer finished.Done()
+			started.Done()
+			results[i], errs[i] = cache.GetOrLoad("user:42", loader)
+		}(i)
+	}
+	started.Wait()
+	time.Sleep(20 * time.Millisecond) // let the callers reach GetOrLoad
+	close(release)
+	finished.Wait()
+
+	if got := atomic.LoadInt64(&loads); got != 1 {
+		t.Errorf("loader ran %d times for %d concurrent callers, want 1", got, callers)
+	}
+	for i := range results {
+		if errs[i] != nil || results[i] != "profile of user 42" {
+			t.Fatalf("caller %d got %q, %v", i, results[i], errs[i])
+		}
+	}
+
+	// The loaded value is cached, so the next call doesn't load
+	hits := cache.Stats().Hits
+	if _, err := cache.GetOrLoad("user:42", loader); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt64(&loads); got != 1 {
+		t.Errorf("loader ran again on a cached key: %d loads", got)
+	}
+	if got := cache.Stats().Hits; got != hits+1 {
+		t.Errorf("hits went from %d to %d, want one more", hits, got)
+	}
+}
+
+func TestGetOrLoadDoesNotCacheErrors(t *testing.T) {
+	cache := NewConcurrentLRU(NewLRUCache[string, string](100))
+	failure := errors.New("database unavailable")
+
+	if _, err := cache.GetOrLoad("user:42", func() (string, error) { return "", failure }); err != failure {
+		t.Fatalf("GetOrLoad error %v, want %v", err, failure)
+	}
+	value, err := cache.GetOrLoad("user:42", func() (string, error) { return "profile", nil })
+	if err != nil || value != "profile" {
+		t.Errorf("GetOrLoad after a failed load = %q, %v; want the loader to run again", value, err)
+	}
+}
+
+func TestLFUBeatsLRUOnZipf(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs 800k cache reads")
+	}
+
+	for _, scans := range []bool{false, true} {
+		lru := zipfHitRatio(PolicyLRU, scans)
+		lfu := zipfHitRatio(PolicyLFU, scans)
+		if lfu <= lru {
+			t.Errorf("scans %t: LFU hit ratio %.3f, want above LRU's %.3f", scans, lfu, lru)
+		}
+		// A cache of 1% of the keys still serves most Zipf(1.1) reads
+		if lru < 0.4 || lfu < 0.5 {
+			t.Errorf("scans %t: hit ratios LRU %.3f, LFU %.3f are implausibly low", scans, lru, lfu)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// PutWithTTL inserts or updates key-value pair that expires after ttl.
+// A ttl of zero or less stores the entry without expiry, like Put.
+//
+// Expiry is lazy: an expired entry still holds its slot until Get finds it,
+// PurgeExpired sweeps it, or it reaches the tail and is evicted as usual.
+// This avoids a background goroutine, which an unsynchronized cache could
+// not share safely anyway.
+func (lru *LRUCache[K, V]) PutWithTTL(key K, value V, ttl time.Duration) {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = lru.now().Add(ttl)
+	}
+	lru.put(key, value, expiresAt, 0)
+}
+
+// PurgeExpired removes every expired entry and returns how many were removed.
+// Runs in O(n).
+func (lru *LRUCache[K, V]) PurgeExpired() int {
+	var removed []*Node[K, V]
+	for node := lru.head.next; node != lru.tail; {
+		next := node.next
+		if lru.expired(node) {
+			lru.removeEntry(node)
+			removed = append(removed, node)
+		}
+		node = next
+	}
+	lru.expirations += uint64(len(removed))
+
+	// Notify after the sweep so callbacks never see a half-walked list
+	for _, node := range removed {
+		lru.notifyEvict(node)
+	}
+	return len(removed)
+}
+
+// SetClock replaces the function the cache reads the current time from,
+// so tests can control expiry deterministically
+func (lru *LRUCache[K, V]) SetClock(now func() time.Time) {
+	lru.now = now
+}
+
+// expired reports whether node has a TTL that has passed
+func (lru *LRUCache[K, V]) expired(node *Node[K, V]) bool {
+	return !node.expiresAt.IsZero() && !lru.now().Before(node.expiresAt)
+}
+
+// demoTTL demonstrates per-entry expiry using a manually advanced clock
+func demoTTL() {
+	fmt.Println("Testing TTL Expiry")
+	fmt.Println("========================================")
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := NewLRUCache[int, int](3)
+	cache.SetClock(func() time.Time { return now })
+
+	cache.PutWithTTL(1, 10, time.Minute)
+	cache.PutWithTTL(2, 20, time.Hour)
+	cache.Put(3, 30)
+	fmt.Println("PutWithTTL(1, 10, 1m), PutWithTTL(2, 20, 1h), Put(3, 30)")
+
+	now = now.Add(30 * time.Second)
+	value, found := cache.Get(1)
+	fmt.Printf("After 30s: Get(1) = %d, %t\n", value, found) // Should return 10, true
+
+	now = now.Add(time.Minute)
+	value, found = cache.Get(1)
+	fmt.Printf("After 1m30s: Get(1) = %d, %t\n", value, found) // Should return 0, false (expired)
+	value, found = cache.Get(2)
+	fmt.Printf("After 1m30s: Get(2) = %d, %t\n", value, found) // Should return 20, true
+
+	now = now.Add(2 * time.Hour)
+	fmt.Printf("After 2h: PurgeExpired() = %d\n", cache.PurgeExpired()) // Removes key 2
+	value, found = cache.Get(3)
+	fmt.Printf("After 2h: Get(3) = %d, %t\n", value, found) // Should return 30, true (no TTL)
+
+	fmt.Printf("\nFinal cache size: %d\n", cache.Size())
+}
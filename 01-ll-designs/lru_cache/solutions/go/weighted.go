@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrCostExceedsBudget is returned when a single entry costs more than the
+// cache's entire budget
+var ErrCostExceedsBudget = errors.New("entry cost exceeds cache budget")
+
+// NewWeightedLRUCache initializes an LRU cache bounded by the total cost of
+// its entries, such as their size in bytes, rather than by their count
+func NewWeightedLRUCache[K comparable, V any](maxCost int64, opts ...Option[K, V]) *LRUCache[K, V] {
+	cache := NewLRUCache[K, V](math.MaxInt, opts...)
+	cache.maxCost = maxCost
+	return cache
+}
+
+// PutWithCost inserts or updates key-value pair with the given cost, then
+// evicts entries until the total cost is within budget.
+// One insert may evict several entries.
+//
+// An entry costing more than the whole budget is rejected with
+// ErrCostExceedsBudget rather than flushing the cache to make room it could
+// never fit in. The cache, including any existing value for key, is left
+// unchanged. Negative costs are treated as zero.
+func (lru *LRUCache[K, V]) PutWithCost(key K, value V, cost int64) error {
+	if cost < 0 {
+		cost = 0
+	}
+	if lru.maxCost > 0 && cost > lru.maxCost {
+		return ErrCostExceedsBudget
+	}
+	lru.put(key, value, time.Time{}, cost)
+	return nil
+}
+
+// Cost returns the total cost of entries in cache, including expired
+// entries that have not been removed yet
+func (lru *LRUCache[K, V]) Cost() int64 {
+	return lru.totalCost
+}
+
+// demoWeighted demonstrates a cache bounded by total byte size
+func demoWeighted() {
+	fmt.Println("Testing Weighted Cache")
+	fmt.Println("========================================")
+
+	cache := NewWeightedLRUCache[string, []byte](1024, WithOnEvict(func(key string, value []byte) {
+		fmt.Printf("Evicted %s (%d bytes)\n", key, len(value))
+	}))
+
+	for _, file := range []struct {
+		name string
+		size int
+	}{{"logo.png", 300}, {"app.js", 400}, {"style.css", 200}} {
+		data := make([]byte, file.size)
+		cache.PutWithCost(file.name, data, int64(len(data)))
+	}
+	fmt.Printf("Cached %v: %d/1024 bytes\n", cache.Keys(), cache.Cost())
+
+	video := make([]byte, 700)
+	cache.PutWithCost("intro.mp4", video, int64(len(video))) // Evicts two entries
+	fmt.Printf("Cached %v: %d/1024 bytes\n", cache.Keys(), cache.Cost())
+
+	huge := make([]byte, 2048)
+	if err := cache.PutWithCost("backup.zip", huge, int64(len(huge))); err != nil {
+		fmt.Printf("PutWithCost(backup.zip, 2048 bytes): %v\n", err)
+	}
+	fmt.Printf("Cached %v: %d/1024 bytes\n", cache.Keys(), cache.Cost())
+}
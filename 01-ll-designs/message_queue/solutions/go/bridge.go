@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultBridgeBatchSize is how many messages a bridge sends in one
+	// request unless configured otherwise
+	DefaultBridgeBatchSize = 100
+	// DefaultBridgeBatchDelay is how long a bridge waits for a batch to fill
+	DefaultBridgeBatchDelay = 20 * time.Millisecond
+	// DefaultBridgeTimeout bounds each request to the broker
+	DefaultBridgeTimeout = 10 * time.Second
+)
+
+// BridgeConfig configures a BridgeConsumer. Zero fields take the defaults.
+type BridgeConfig struct {
+	BrokerURL    string        // base URL of the simple-message-broker, e.g. http://localhost:8080
+	MaxBatchSize int           // send once this many messages for a topic are waiting
+	MaxDelay     time.Duration // send once the oldest waiting message is this old
+	Retry        RetryPolicy   // retries of a failed request; DefaultRetryPolicy if MaxAttempts is 0
+	Client       *http.Client  // http.Client with DefaultBridgeTimeout if nil
+}
+
+// BridgeStats counts a bridge's requests to the broker
+type BridgeStats struct {
+	Batches  int64 `json:"batches"`  // requests the broker accepted
+	Messages int64 `json:"messages"` // messages in those requests
+	Retries  int64 `json:"retries"`  // requests sent again after a retryable failure
+	Failed   int64 `json:"failed"`   // batches given up on
+}
+
+// BridgeConsumer is a MessageHandler that forwards messages to the
+// networked simple-message-broker, so a local topic can tee into it.
+// Subscribe a Consumer with it as the handler. Messages are grouped by topic
+// and sent with POST /publish/batch/{topic}, each as its JSON form (id,
+// topic, payload, timestamp, headers), so broker consumers can deduplicate
+// by the local ID.
+//
+// HandleMessage returns once its message's batch is sent or has failed, so
+// a slow or full broker holds up the consumer's workers, and the topic's
+// overflow policy takes over from there. A batch can only be as large as the
+// number of messages handled at once, so give the consumer at least
+// MaxBatchSize workers. Requests that fail with a network error, 429 or 5xx
+// are retried per the Retry policy, waiting at least as long as the broker's
+// Retry-After. Other failures, and the last retryable one, are returned to
+// every message in the batch for the consumer's own retry policy. The broker
+// stops a batch at its first rejected message, so a batch sent again may
+// publish some messages twice.
+type BridgeConsumer struct {
+	config  BridgeConfig
+	mu      sync.Mutex
+	pending map[string]*bridgeBatch // by topic
+
+	batches  int64 // atomic
+	messages int64 // atomic
+	retries  int64 // atomic
+	failed   int64 // atomic
+}
+
+// bridgeBatch is a group of messages for one topic waiting to be sent
+type bridgeBatch struct {
+	topic    string
+	messages []*Message
+	timer    *time.Timer
+	done     chan struct{} // closed once err is set
+	err      error
+}
+
+// bridgeError is a failed request to the broker
+type bridgeError struct {
+	status     int // 0 for a network error
+	code       string
+	message    string
+	retryAfter time.Duration
+}
+
+func (e *bridgeError) Error() string {
+	if e.status == 0 {
+		return fmt.Sprintf("broker unreachable: %s", e.message)
+	}
+	if e.code != "" {
+		return fmt.Sprintf("broker returned %d %s: %s", e.status, e.code, e.message)
+	}
+	return fmt.Sprintf("broker returned %d: %s", e.status, e.message)
+}
+
+// retryable reports whether sending the same request again may succeed
+func (e *bridgeError) retryable() bool {
+	return e.status == 0 || e.status == http.StatusTooManyRequests || e.status >= 500
+}
+
+// NewBridgeConsumer creates a bridge to the broker at config.BrokerURL
+func NewBridgeConsumer(config BridgeConfig) *BridgeConsumer {
+	config.BrokerURL = strings.TrimRight(config.BrokerURL, "/")
+	if config.MaxBatchSize < 1 {
+		config.MaxBatchSize = DefaultBridgeBatchSize
+	}
+	if config.MaxDelay <= 0 {
+		config.MaxDelay = DefaultBridgeBatchDelay
+	}
+	if config.Retry.MaxAttempts < 1 {
+		config.Retry = DefaultRetryPolicy()
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: DefaultBridgeTimeout}
+	}
+
+	return &BridgeConsumer{
+		config:  config,
+		pending: make(map[string]*bridgeBatch),
+	}
+}
+
+// HandleMessage implements MessageHandler. It adds the message to its
+// topic's batch and waits for the batch to be sent.
+func (b *BridgeConsumer) HandleMessage(message *Message) error {
+	b.mu.Lock()
+	batch, exists := b.pending[message.Topic]
+	if !exists {
+		batch = &bridgeBatch{topic: message.Topic, done: make(chan struct{})}
+		b.pending[message.Topic] = batch
+		batch.timer = time.AfterFunc(b.config.MaxDelay, func() { b.flush(batch) })
+	}
+	batch.messages = append(batch.messages, message)
+	full := len(batch.messages) >= b.config.MaxBatchSize
+	b.mu.Unlock()
+
+	if full {
+		b.flush(batch)
+	}
+	<-batch.done
+	return batch.err
+}
+
+// GetStats returns the bridge's request counters
+func (b *BridgeConsumer) GetStats() BridgeStats {
+	return BridgeStats{
+		Batches:  atomic.LoadInt64(&b.batches),
+		Messages: atomic.LoadInt64(&b.messages),
+		Retries:  atomic.LoadInt64(&b.retries),
+		Failed:   atomic.LoadInt64(&b.failed),
+	}
+}
+
+// flush sends batch unless its timer and a full batch both tried and the
+// other got there first
+func (b *BridgeConsumer) flush(batch *bridgeBatch) {
+	b.mu.Lock()
+	if b.pending[batch.topic] != batch {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, batch.topic)
+	batch.timer.Stop()
+	b.mu.Unlock()
+
+	batch.err = b.send(batch)
+	close(batch.done)
+}
+
+// send posts the batch, retrying retryable failures with backoff
+func (b *BridgeConsumer) send(batch *bridgeBatch) error {
+	body, err := json.Marshal(batch.messages)
+	if err != nil {
+		atomic.AddInt64(&b.failed, 1)
+		return err
+	}
+	endpoint := b.config.BrokerURL + "/publish/batch/" + url.PathEscape(batch.topic)
+	policy := b.config.Retry
+
+	for attempt := 1; ; attempt++ {
+		err := b.post(endpoint, body)
+		if err == nil {
+			atomic.AddInt64(&b.batches, 1)
+			atomic.AddInt64(&b.messages, int64(len(batch.messages)))
+			return nil
+		}
+		if !err.retryable() || attempt >= policy.MaxAttempts {
+			atomic.AddInt64(&b.failed, 1)
+			log.Printf("Bridge giving up on %d messages for topic %s after %d attempts: %v",
+				len(batch.messages), batch.topic, attempt, err)
+			return err
+		}
+
+		wait := policy.Backoff(attempt)
+		if err.retryAfter > wait {
+			wait = err.retryAfter
+		}
+		log.Printf("Bridge retrying %d messages for topic %s in %v: %v", len(batch.messages), batch.topic, wait, err)
+		time.Sleep(wait)
+		atomic.AddInt64(&b.retries, 1)
+	}
+}
+
+// post sends one request and turns any failure into a bridgeError
+func (b *BridgeConsumer) post(endpoint string, body []byte) *bridgeError {
+	resp, err := b.config.Client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return &bridgeError{message: err.Error()}
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 300 {
+		return nil
+	}
+
+	// The broker answers errors with {"code", "message"}; anything else,
+	// such as a proxy's error page, is kept as the message
+	failure := &bridgeError{status: resp.StatusCode}
+	var envelope struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(data, &envelope) == nil && envelope.Code != "" {
+		failure.code, failure.message = envelope.Code, envelope.Message
+	} else {
+		failure.message = strings.TrimSpace(string(data))
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		failure.retryAfter = time.Duration(seconds) * time.Second
+	}
+	return failure
+}
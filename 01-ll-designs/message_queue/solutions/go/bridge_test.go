@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubBroker stands in for the simple-message-broker. It answers each
+// request with the next of its responses, repeating the last, and records
+// what every request carried.
+type stubBroker struct {
+	*httptest.Server
+	mu        sync.Mutex
+	responses []stubResponse
+	requests  []stubRequest
+}
+
+// stubResponse is one answer of a stubBroker; status 0 accepts the batch
+type stubResponse struct {
+	status     int
+	body       string
+	retryAfter string
+}
+
+// stubRequest is one request a stubBroker received
+type stubRequest struct {
+	path     string
+	messages []Message
+	at       time.Time
+}
+
+func newStubBroker(t *testing.T, responses ...stubResponse) *stubBroker {
+	stub := &stubBroker{responses: responses}
+	stub.Server = httptest.NewServer(http.HandlerFunc(stub.serve))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+func (s *stubBroker) serve(w http.ResponseWriter, r *http.Request) {
+	var messages []Message
+	json.NewDecoder(r.Body).Decode(&messages)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, stubRequest{path: r.URL.Path, messages: messages, at: time.Now()})
+	response := stubResponse{}
+	if len(s.responses) > 0 {
+		response = s.responses[0]
+		if len(s.responses) > 1 {
+			s.responses = s.responses[1:]
+		}
+	}
+	s.mu.Unlock()
+
+	if response.status == 0 {
+		fmt.Fprintf(w, `{"count": %d}`, len(messages))
+		return
+	}
+	if response.retryAfter != "" {
+		w.Header().Set("Retry-After", response.retryAfter)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.status)
+	fmt.Fprint(w, response.body)
+}
+
+// received returns the requests the stub has seen so far
+func (s *stubBroker) received() []stubRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]stubRequest(nil), s.requests...)
+}
+
+// quickRetry retries fast so tests don't wait on the default backoff
+var quickRetry = RetryPolicy{MaxAttempts: 3, InitialBackoff: 10 * time.Millisecond, Multiplier: 2}
+
+func TestBridgeBatchesBySizeAndDelay(t *testing.T) {
+	broker := newStubBroker(t)
+	bridge := NewBridgeConsumer(BridgeConfig{
+		BrokerURL:    broker.URL,
+		MaxBatchSize: 10,
+		MaxDelay:     50 * time.Millisecond,
+		Retry:        quickRetry,
+	})
+
+	mq := NewMessageQueue()
+	defer mq.Close()
+	forwarder := NewConsumer("broker-bridge", bridge)
+	forwarder.SetWorkerPool(10, 100)
+	mq.Subscribe(forwarder, "orders")
+	for i := 1; i <= 25; i++ {
+		mq.Publish("orders", fmt.Sprintf("Order #%d", i), nil)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for bridge.GetStats().Messages < 25 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Two batches fill up; the last 5 messages go once MaxDelay passes
+	var sizes []int
+	for _, request := range broker.received() {
+		if request.path != "/publish/batch/orders" {
+			t.Errorf("request to %s, want /publish/batch/orders", request.path)
+		}
+		sizes = append(sizes, len(request.messages))
+	}
+	sort.Ints(sizes)
+	if fmt.Sprint(sizes) != "[5 10 10]" {
+		t.Errorf("batch sizes %v, want [5 10 10]", sizes)
+	}
+	if stats := bridge.GetStats(); stats != (BridgeStats{Batches: 3, Messages: 25}) {
+		t.Errorf("stats %+v, want 3 batches of 25 messages and no retries or failures", stats)
+	}
+}
+
+func TestBridgeWaitsForRetryAfter(t *testing.T) {
+	broker := newStubBroker(t,
+		stubResponse{status: http.StatusServiceUnavailable, retryAfter: "1",
+			body: `{"code": "queue_full", "message": "topic orders queue is full"}`},
+		stubResponse{},
+	)
+	bridge := NewBridgeConsumer(BridgeConfig{BrokerURL: broker.URL, MaxBatchSize: 1, Retry: quickRetry})
+
+	if err := bridge.HandleMessage(NewMessage("orders", "Order #1", nil)); err != nil {
+		t.Fatalf("HandleMessage: %v", err)
+	}
+
+	requests := broker.received()
+	if len(requests) != 2 {
+		t.Fatalf("%d requests, want 2", len(requests))
+	}
+	// The policy alone would wait 10ms; Retry-After asks for a second
+	if wait := requests[1].at.Sub(requests[0].at); wait < time.Second {
+		t.Errorf("retried after %v, want at least the 1s Retry-After", wait)
+	}
+	if stats := bridge.GetStats(); stats != (BridgeStats{Batches: 1, Messages: 1, Retries: 1}) {
+		t.Errorf("stats %+v, want 1 batch, 1 message, 1 retry", stats)
+	}
+}
+
+func TestBridgeGivesUpAfterMaxAttempts(t *testing.T) {
+	broker := newStubBroker(t, stubResponse{status: http.StatusBadGateway, body: "upstream down"})
+	bridge := NewBridgeConsumer(BridgeConfig{BrokerURL: broker.URL, MaxBatchSize: 1, Retry: quickRetry})
+
+	err := bridge.HandleMessage(NewMessage("orders", "Order #1", nil))
+	var failure *bridgeError
+	if !errors.As(err, &failure) || failure.status != http.StatusBadGateway || failure.message != "upstream down" {
+		t.Fatalf("HandleMessage error %v, want the broker's 502", err)
+	}
+	if got := len(broker.received()); got != quickRetry.MaxAttempts {
+		t.Errorf("%d requests, want %d", got, quickRetry.MaxAttempts)
+	}
+	want := BridgeStats{Retries: int64(quickRetry.MaxAttempts - 1), Failed: 1}
+	if stats := bridge.GetStats(); stats != want {
+		t.Errorf("stats %+v, want %+v", stats, want)
+	}
+}
+
+func TestBridgeDoesNotRetryClientErrors(t *testing.T) {
+	broker := newStubBroker(t, stubResponse{status: http.StatusRequestEntityTooLarge,
+		body: `{"code": "message_too_large", "message": "message data is over the limit"}`})
+	bridge := NewBridgeConsumer(BridgeConfig{BrokerURL: broker.URL, MaxBatchSize: 1, Retry: quickRetry})
+
+	err := bridge.HandleMessage(NewMessage("orders", "Order #1", nil))
+	var failure *bridgeError
+	if !errors.As(err, &failure) || failure.code != "message_too_large" {
+		t.Fatalf("HandleMessage error %v, want message_too_large", err)
+	}
+	if got := len(broker.received()); got != 1 {
+		t.Errorf("%d requests, want 1", got)
+	}
+	if stats := bridge.GetStats(); stats != (BridgeStats{Failed: 1}) {
+		t.Errorf("stats %+v, want 1 failure and no retries", stats)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultDrainTimeout bounds how long MessageQueue.Close waits for topics to drain
+const DefaultDrainTimeout = 5 * time.Second
+
+// stopAccepting rejects new messages and closes the buffer so the dispatcher
+// exits once it is empty. Safe to call more than once.
+func (t *Topic) stopAccepting() {
+	t.closeOnce.Do(func() {
+		close(t.closing) // wake publishers blocked by the Block policy
+		t.sendMu.Lock()
+		t.closed = true
+		close(t.messages)
+		t.sendMu.Unlock()
+	})
+}
+
+// trackInFlight wraps a delivery callback so Drain can wait for the handler
+func (t *Topic) trackInFlight(done func(err error)) func(err error) {
+	t.inFlight.Add(1)
+	return func(err error) {
+		if done != nil {
+			done(err)
+		}
+		t.inFlight.Done()
+	}
+}
+
+// Drain closes the topic gracefully: it stops accepting messages, delivers
+// everything already buffered, waits for handlers (including retries) to
+// finish, and then releases the topic. If ctx ends first, remaining
+// handlers are abandoned as by Close and ctx's error is returned.
+func (t *Topic) Drain(ctx context.Context) error {
+	t.stopAccepting()
+	defer t.cancel()
+
+	select {
+	case <-t.dispatched:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	handled := make(chan struct{})
+	go func() {
+		t.inFlight.Wait()
+		close(handled)
+	}()
+
+	select {
+	case <-handled:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Drain drains every topic concurrently and returns the first error
+func (mq *MessageQueue) Drain(ctx context.Context) error {
+	mq.mu.RLock()
+	topics := make([]*Topic, 0, len(mq.topics))
+	for _, topic := range mq.topics {
+		topics = append(topics, topic)
+	}
+	mq.mu.RUnlock()
+
+	errs := make(chan error, len(topics))
+	for _, topic := range topics {
+		go func(topic *Topic) {
+			errs <- topic.Drain(ctx)
+		}(topic)
+	}
+
+	var firstErr error
+	for range topics {
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// demoDrain demonstrates closing a queue without losing buffered messages
+func demoDrain() {
+	fmt.Println("=== Drain Demo ===")
+
+	mq := NewMessageQueue()
+	mq.CreateTopicWithConfig("emails", TopicConfig{MaxSize: 10, Synchronous: true})
+
+	var sent int64
+	mailer := NewConsumer("mailer", MessageHandlerFunc(func(message *Message) error {
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt64(&sent, 1)
+		return nil
+	}))
+	mq.Subscribe(mailer, "emails")
+
+	// Publish from goroutines so messages pile up behind the slow mailer
+	for i := 1; i <= 5; i++ {
+		go mq.Publish("emails", fmt.Sprintf("welcome email %d", i), nil)
+	}
+	time.Sleep(10 * time.Millisecond)
+	fmt.Printf("Buffered before close: %d, sent: %d\n",
+		mq.GetTopicStats("emails").QueueSize, atomic.LoadInt64(&sent))
+
+	start := time.Now()
+	mq.Close()
+	fmt.Printf("Closed after %v, sent: %d\n", time.Since(start).Round(10*time.Millisecond), atomic.LoadInt64(&sent))
+
+	if _, err := mq.Publish("emails", "too late", nil); err != nil {
+		fmt.Printf("Publishing after close fails: %v\n", err)
+	}
+}
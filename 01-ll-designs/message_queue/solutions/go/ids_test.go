@@ -0,0 +1,95 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestRandomIDsUnique generates a million IDs from concurrent goroutines,
+// as publishers would, and fails on the first duplicate
+func TestRandomIDsUnique(t *testing.T) {
+	const (
+		goroutines = 8
+		perRoutine = 125000
+	)
+
+	generator := RandomIDs{}
+	results := make([][]string, goroutines)
+	var wg sync.WaitGroup
+	for g := range results {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			ids := make([]string, perRoutine)
+			for i := range ids {
+				ids[i] = generator.NewID()
+			}
+			results[g] = ids
+		}(g)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool, goroutines*perRoutine)
+	for _, ids := range results {
+		for _, id := range ids {
+			if seen[id] {
+				t.Fatalf("duplicate ID %s after %d IDs", id, len(seen))
+			}
+			if !isUUID(id) {
+				t.Fatalf("ID %s is not a UUID", id)
+			}
+			seen[id] = true
+		}
+	}
+}
+
+// TestSequentialIDsUnique checks the counter stays unique when shared by
+// concurrent publishers
+func TestSequentialIDsUnique(t *testing.T) {
+	const (
+		goroutines = 8
+		perRoutine = 10000
+	)
+
+	generator := NewSequentialIDs("order-")
+	var mu sync.Mutex
+	seen := make(map[string]bool, goroutines*perRoutine)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perRoutine; i++ {
+				id := generator.NewID()
+				mu.Lock()
+				if seen[id] {
+					t.Errorf("duplicate ID %s", id)
+				}
+				seen[id] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(seen) != goroutines*perRoutine {
+		t.Errorf("got %d unique IDs, want %d", len(seen), goroutines*perRoutine)
+	}
+}
+
+func TestShortID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"3f2b8c1e-9a4d-4c7e-8b21-5d6f7a8b9c0d", "3f2b8c1e"},
+		{"order-00000001", "order-00000001"},
+		{"order-00000002", "order-00000002"},
+		{"msg-1", "msg-1"},
+		{"not-a-uuid-but-exactly-36-characters", "not-a-uuid-but-exactly-36-characters"},
+	}
+	for _, tt := range tests {
+		if got := shortID(tt.id); got != tt.want {
+			t.Errorf("shortID(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// consumerLag counts the messages a topic has handed to one consumer and how
+// many of them the consumer's handler finished without error
+type consumerLag struct {
+	delivered int64
+	handled   int64
+}
+
+// countDelivery records that a message is being handed to consumer and
+// returns done wrapped to record it as handled if processing succeeds
+func (t *Topic) countDelivery(consumer *Consumer, done func(err error)) func(err error) {
+	t.mu.Lock()
+	lag, ok := t.lag[consumer.ID()]
+	if !ok {
+		lag = &consumerLag{}
+		t.lag[consumer.ID()] = lag
+	}
+	t.mu.Unlock()
+
+	atomic.AddInt64(&lag.delivered, 1)
+	return func(err error) {
+		if err == nil {
+			atomic.AddInt64(&lag.handled, 1)
+		}
+		if done != nil {
+			done(err)
+		}
+	}
+}
+
+// consumerLagLocked returns each consumer's delivered minus handled count
+// (must be called with t.mu held)
+func (t *Topic) consumerLagLocked() map[string]int64 {
+	lags := make(map[string]int64, len(t.lag))
+	for id, lag := range t.lag {
+		// Read handled first so a message finishing in between can't make
+		// the lag negative
+		handled := atomic.LoadInt64(&lag.handled)
+		lags[id] = atomic.LoadInt64(&lag.delivered) - handled
+	}
+	return lags
+}
+
+// demoConsumerLag demonstrates spotting a slow consumer by its lag while a
+// fast consumer on the same topic keeps up
+func demoConsumerLag() {
+	fmt.Println("=== Consumer Lag Demo ===")
+
+	mq := NewMessageQueue()
+	defer mq.Close()
+
+	fast := NewConsumer("search-index", MessageHandlerFunc(func(message *Message) error {
+		return nil
+	}))
+	slow := NewConsumer("email", MessageHandlerFunc(func(message *Message) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}))
+	slow.SetWorkerPool(1, 100)
+	mq.Subscribe(fast, "signups")
+	mq.Subscribe(slow, "signups")
+
+	for round := 1; round <= 3; round++ {
+		for i := 0; i < 20; i++ {
+			mq.Publish("signups", fmt.Sprintf("user-%d-%d", round, i), nil)
+		}
+		time.Sleep(100 * time.Millisecond)
+
+		lag := mq.GetTopicStats("signups").ConsumerLag
+		fmt.Printf("After %d messages: search-index lag %d, email lag %d\n",
+			round*20, lag["search-index"], lag["email"])
+	}
+}
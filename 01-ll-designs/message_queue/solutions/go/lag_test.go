@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds, failing the test after five seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConsumerLagSlowAndFast(t *testing.T) {
+	mq := NewMessageQueue()
+	defer mq.Close()
+
+	fast := NewConsumer("search-index", MessageHandlerFunc(func(message *Message) error {
+		return nil
+	}))
+	// The slow consumer's single worker blocks until released
+	release := make(chan struct{})
+	defer close(release)
+	slow := NewConsumer("email", MessageHandlerFunc(func(message *Message) error {
+		<-release
+		return nil
+	}))
+	slow.SetWorkerPool(1, 100)
+	mq.Subscribe(fast, "signups")
+	mq.Subscribe(slow, "signups")
+
+	lag := func() map[string]int64 {
+		return mq.GetTopicStats("signups").ConsumerLag
+	}
+	for round := 1; round <= 3; round++ {
+		for i := 0; i < 20; i++ {
+			mq.Publish("signups", fmt.Sprintf("user-%d-%d", round, i), nil)
+		}
+		want := int64(round * 20)
+		waitFor(t, fmt.Sprintf("email lag %d", want), func() bool { return lag()["email"] == want })
+		waitFor(t, "search-index to catch up", func() bool { return lag()["search-index"] == 0 })
+	}
+
+	// Releasing the slow consumer drains its lag
+	for i := 0; i < 60; i++ {
+		release <- struct{}{}
+	}
+	waitFor(t, "email to catch up", func() bool { return lag()["email"] == 0 })
+}
@@ -16,22 +16,11 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"math/rand"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
-// generateID creates a simple random ID
-func generateID() string {
-	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
-	b := make([]byte, 8)
-	for i := range b {
-		b[i] = charset[rand.Intn(len(charset))]
-	}
-	return string(b)
-}
-
 // Message represents a message in the queue
 type Message struct {
 	ID        string            `json:"id"`
@@ -41,13 +30,18 @@ type Message struct {
 	Headers   map[string]string `json:"headers"`
 }
 
-// NewMessage creates a new message
+// NewMessage creates a new message with a random ID
 func NewMessage(topic, payload string, headers map[string]string) *Message {
+	return newMessage(RandomIDs{}.NewID(), topic, payload, headers)
+}
+
+// newMessage creates a new message with the given ID
+func newMessage(id, topic, payload string, headers map[string]string) *Message {
 	if headers == nil {
 		headers = make(map[string]string)
 	}
 	return &Message{
-		ID:        generateID(),
+		ID:        id,
 		Topic:     topic,
 		Payload:   payload,
 		Timestamp: time.Now(),
@@ -58,7 +52,7 @@ func NewMessage(topic, payload string, headers map[string]string) *Message {
 // String returns a string representation of the message
 func (m *Message) String() string {
 	return fmt.Sprintf("Message{id='%s', topic='%s', payload='%s'}", 
-		m.ID[:8], m.Topic, m.Payload)
+		shortID(m.ID), m.Topic, m.Payload)
 }
 
 // MessageHandler defines the interface for handling messages
@@ -76,47 +70,83 @@ func (f MessageHandlerFunc) HandleMessage(message *Message) error {
 
 // Consumer represents a message consumer
 type Consumer struct {
-	id               string
-	handler          MessageHandler
-	subscribedTopics map[string]bool
-	active           int32 // atomic boolean
-	mu               sync.RWMutex
-}
-
-// NewConsumer creates a new consumer
+	id                string
+	handler           MessageHandler
+	deadLetterHandler MessageHandler
+	retryPolicy       RetryPolicy
+	subscribedTopics  map[string]bool
+	active            int32 // atomic boolean
+	retried           int64
+	deadLettered      int64
+	mu                sync.RWMutex
+	
+	// Worker pool: jobs is created by the first OnMessage and closed by Stop
+	workers     int
+	queueSize   int
+	jobs        chan consumerJob
+	poolStopped bool
+	poolMu      sync.RWMutex
+	inFlight    int64 // handlers running
+	queued      int64 // messages waiting for a worker
+	
+	pause pauseState
+}
+
+// NewConsumer creates a new consumer that retries failed messages with
+// DefaultRetryPolicy and handles them on DefaultConsumerWorkers workers
 func NewConsumer(id string, handler MessageHandler) *Consumer {
 	return &Consumer{
 		id:               id,
 		handler:          handler,
+		retryPolicy:      DefaultRetryPolicy(),
 		subscribedTopics: make(map[string]bool),
 		active:           1,
+		workers:          DefaultConsumerWorkers,
+		queueSize:        DefaultConsumerQueueSize,
+		pause:            pauseState{bufferSize: DefaultPauseBufferSize},
 	}
 }
 
-// OnMessage processes a received message
-func (c *Consumer) OnMessage(message *Message) {
-	if !c.IsActive() {
+// OnMessage queues a received message for the consumer's worker pool, which
+// retries it until it succeeds, the retry policy is exhausted, the consumer
+// stops, or ctx is cancelled. If the queue is full it blocks until a worker
+// frees up or ctx is cancelled. A paused consumer holds or skips the message
+// instead. If done is not nil it is called once processing finishes, with
+// nil on success or the error that ended it.
+func (c *Consumer) OnMessage(ctx context.Context, message *Message, done func(err error)) {
+	job := consumerJob{ctx: ctx, message: message, done: done}
+	c.startWorkers()
+	if c.holdIfPaused(job) {
+		return
+	}
+	c.enqueue(job)
+}
+
+// enqueue sends a job to the worker pool
+func (c *Consumer) enqueue(job consumerJob) {
+	// Hold the read lock while sending so Stop cannot close jobs under us
+	c.poolMu.RLock()
+	defer c.poolMu.RUnlock()
+	if !c.IsActive() || c.poolStopped {
+		job.finish(ErrConsumerStopped)
 		return
 	}
 	
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Printf("Panic in consumer %s processing message %s: %v", 
-					c.id, message.ID, r)
-			}
-		}()
-		
-		if err := c.handler.HandleMessage(message); err != nil {
-			log.Printf("Error in consumer %s processing message %s: %v", 
-				c.id, message.ID, err)
-		}
-	}()
+	atomic.AddInt64(&c.queued, 1)
+	select {
+	case c.jobs <- job:
+	case <-job.ctx.Done():
+		atomic.AddInt64(&c.queued, -1)
+		job.finish(job.ctx.Err())
+	}
 }
 
-// Stop stops the consumer
+// Stop stops the consumer. Its workers finish the messages they are
+// handling and abandon any still queued or held while paused.
 func (c *Consumer) Stop() {
 	atomic.StoreInt32(&c.active, 0)
+	c.stopWorkers()
+	c.dropHeld(ErrConsumerStopped)
 }
 
 // IsActive returns whether the consumer is active
@@ -162,43 +192,143 @@ type TopicStats struct {
 	QueueSize       int    `json:"queueSize"`
 	SubscriberCount int    `json:"subscriberCount"`
 	MaxSize         int    `json:"maxSize"`
-}
-
-// Topic represents a message topic
+	Dropped         int64  `json:"dropped"`
+	RetainedSize    int    `json:"retainedSize"`
+	PullQueueSize   int    `json:"pullQueueSize"`
+	// ConsumerLag is, per consumer ID, how many messages the topic has
+	// handed to the consumer that its handler has not yet finished without
+	// error. A consumer that keeps falling behind, or keeps failing, shows a
+	// growing lag.
+	ConsumerLag map[string]int64 `json:"consumerLag"`
+}
+
+// TopicConfig configures a topic
+type TopicConfig struct {
+	MaxSize int // capacity of the topic's buffered channel
+	// Synchronous makes AddMessage wait until every subscriber has finished
+	// handling the message, or DeliveryTimeout passes, and report failures.
+	// Topics deliver asynchronously by default.
+	Synchronous     bool
+	DeliveryTimeout time.Duration // DefaultDeliveryTimeout if zero
+	// Overflow decides what happens to a message published while the
+	// buffer is full. DropNewest by default.
+	Overflow     OverflowPolicy
+	BlockTimeout time.Duration // how long Block waits, DefaultBlockTimeout if zero
+	// RetainedMessages is how many recently delivered messages the topic
+	// keeps for ReplayFrom. Zero disables the retained log.
+	RetainedMessages int
+}
+
+// Topic represents a message topic. Published messages are buffered in a
+// channel and delivered to subscribers in order by a dispatcher goroutine.
 type Topic struct {
 	name         string
 	maxSize      int
-	messages     chan *Message
+	synchronous  bool
+	timeout      time.Duration
+	overflow     OverflowPolicy
+	blockTimeout time.Duration
+	messages     chan *queuedMessage
+	retained     *retainedLog
+	pull         chan *Message // copy of each message for Poll, created on first use
+	pullClosed   bool
 	subscribers  []*Consumer
+	lag          map[string]*consumerLag // by consumer ID, guarded by mu
 	messageCount int64
+	dropped      int64
 	mu           sync.RWMutex
 	ctx          context.Context
 	cancel       context.CancelFunc
+	
+	// Shutdown state: sendMu guards sending on messages against closing it
+	closed     bool
+	sendMu     sync.RWMutex
+	closing    chan struct{} // closed when the topic stops accepting messages
+	closeOnce  sync.Once
+	dispatched chan struct{} // closed when the dispatcher has emptied messages
+	inFlight   sync.WaitGroup // handler goroutines still running
 }
 
-// NewTopic creates a new topic
+// NewTopic creates a new asynchronous topic
 func NewTopic(name string, maxSize int) *Topic {
+	return NewTopicWithConfig(name, TopicConfig{MaxSize: maxSize})
+}
+
+// NewTopicWithConfig creates a new topic from config
+func NewTopicWithConfig(name string, config TopicConfig) *Topic {
+	if config.DeliveryTimeout <= 0 {
+		config.DeliveryTimeout = DefaultDeliveryTimeout
+	}
+	if config.BlockTimeout <= 0 {
+		config.BlockTimeout = DefaultBlockTimeout
+	}
+	
 	ctx, cancel := context.WithCancel(context.Background())
-	return &Topic{
-		name:        name,
-		maxSize:     maxSize,
-		messages:    make(chan *Message, maxSize),
-		subscribers: make([]*Consumer, 0),
-		ctx:         ctx,
-		cancel:      cancel,
+	topic := &Topic{
+		name:         name,
+		maxSize:      config.MaxSize,
+		synchronous:  config.Synchronous,
+		timeout:      config.DeliveryTimeout,
+		overflow:     config.Overflow,
+		blockTimeout: config.BlockTimeout,
+		messages:     make(chan *queuedMessage, config.MaxSize),
+		retained:     newRetainedLog(config.RetainedMessages),
+		subscribers:  make([]*Consumer, 0),
+		lag:          make(map[string]*consumerLag),
+		ctx:          ctx,
+		cancel:       cancel,
+		closing:      make(chan struct{}),
+		dispatched:   make(chan struct{}),
 	}
+	go topic.dispatch()
+	return topic
 }
 
-// AddMessage adds a message to the topic
-func (t *Topic) AddMessage(message *Message) bool {
-	select {
-	case t.messages <- message:
-		atomic.AddInt64(&t.messageCount, 1)
-		t.deliverMessage(message)
-		return true
-	default:
-		log.Printf("Topic %s is full, dropping message: %s", t.name, message.ID)
-		return false
+// AddMessage queues a message for delivery to subscribers. If the buffer is
+// full the topic's overflow policy applies, and ErrTopicFull or
+// ErrMessageEvicted is returned if this message is the one dropped. On a
+// synchronous topic it also waits for delivery and returns a *DeliveryError
+// if any subscriber failed or timed out.
+func (t *Topic) AddMessage(message *Message) error {
+	return t.addMessage(message, nil)
+}
+
+// addMessage queues a message for delivery to the topic's subscribers plus
+// any extra consumers, such as wildcard subscribers matched by the queue
+func (t *Topic) addMessage(message *Message, extra []*Consumer) error {
+	queued := &queuedMessage{message: message, extra: extra}
+	if t.synchronous {
+		queued.result = make(chan error, 1)
+	}
+	
+	if err := t.enqueue(queued); err != nil {
+		return err
+	}
+	atomic.AddInt64(&t.messageCount, 1)
+	
+	if queued.result == nil {
+		return nil
+	}
+	return <-queued.result
+}
+
+// dispatch delivers queued messages in order until the topic is closed.
+// After Close cancels the topic, remaining messages are dropped instead.
+func (t *Topic) dispatch() {
+	defer close(t.dispatched)
+	defer t.closePull()
+	
+	for queued := range t.messages {
+		if t.ctx.Err() != nil {
+			t.drop(queued, ErrTopicClosed)
+			continue
+		}
+		t.retained.Append(queued.message)
+		t.offerPull(queued.message)
+		err := t.deliverMessage(queued.message, queued.extra)
+		if queued.result != nil {
+			queued.result <- err
+		}
 	}
 }
 
@@ -227,27 +357,48 @@ func (t *Topic) Unsubscribe(consumer *Consumer) {
 		if sub.ID() == consumer.ID() {
 			// Remove from slice
 			t.subscribers = append(t.subscribers[:i], t.subscribers[i+1:]...)
+			delete(t.lag, consumer.ID())
 			consumer.removeSubscription(t.name)
 			break
 		}
 	}
 }
 
-// deliverMessage delivers a message to all active subscribers
-func (t *Topic) deliverMessage(message *Message) {
+// deliverMessage delivers a message to all active subscribers and extra
+// consumers, once per consumer, waiting for them to finish if the topic is
+// synchronous
+func (t *Topic) deliverMessage(message *Message, extra []*Consumer) error {
 	t.mu.RLock()
 	currentSubscribers := make([]*Consumer, len(t.subscribers))
 	copy(currentSubscribers, t.subscribers)
 	t.mu.RUnlock()
 	
+	var ack *deliveryAck
+	if t.synchronous {
+		ack = newDeliveryAck(message)
+	}
+	
+	delivered := make(map[string]bool, len(currentSubscribers)+len(extra))
 	for _, subscriber := range currentSubscribers {
 		if subscriber.IsActive() {
-			subscriber.OnMessage(message)
+			delivered[subscriber.ID()] = true
+			subscriber.OnMessage(t.ctx, message, t.trackInFlight(t.countDelivery(subscriber, ack.track(subscriber))))
 		} else {
 			// Remove inactive subscribers
 			t.Unsubscribe(subscriber)
 		}
 	}
+	for _, consumer := range extra {
+		if consumer.IsActive() && !delivered[consumer.ID()] {
+			delivered[consumer.ID()] = true
+			consumer.OnMessage(t.ctx, message, t.trackInFlight(t.countDelivery(consumer, ack.track(consumer))))
+		}
+	}
+	
+	if ack == nil {
+		return nil
+	}
+	return ack.wait(t.ctx, t.timeout)
 }
 
 // GetStats returns topic statistics
@@ -261,19 +412,26 @@ func (t *Topic) GetStats() TopicStats {
 		QueueSize:       len(t.messages),
 		SubscriberCount: len(t.subscribers),
 		MaxSize:         t.maxSize,
+		Dropped:         atomic.LoadInt64(&t.dropped),
+		RetainedSize:    t.retained.Size(),
+		PullQueueSize:   len(t.pull),
+		ConsumerLag:     t.consumerLagLocked(),
 	}
 }
 
-// Close closes the topic and cleans up resources
+// Close closes the topic immediately. Buffered messages are dropped and
+// handlers still retrying give up; use Drain to deliver them first.
 func (t *Topic) Close() {
 	t.cancel()
-	close(t.messages)
+	t.stopAccepting()
 }
 
 // MessageQueue represents the main message queue broker
 type MessageQueue struct {
 	topics    map[string]*Topic
 	consumers []*Consumer
+	patterns  *subscriptionTrie // wildcard subscriptions
+	ids       IDGenerator
 	mu        sync.RWMutex
 }
 
@@ -282,11 +440,27 @@ func NewMessageQueue() *MessageQueue {
 	return &MessageQueue{
 		topics:    make(map[string]*Topic),
 		consumers: make([]*Consumer, 0),
+		patterns:  newSubscriptionTrie(),
+		ids:       RandomIDs{},
 	}
 }
 
-// CreateTopic creates a new topic
+// SetIDGenerator replaces the generator of the IDs Publish gives messages.
+// The default is RandomIDs.
+func (mq *MessageQueue) SetIDGenerator(generator IDGenerator) {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	mq.ids = generator
+}
+
+// CreateTopic creates a new asynchronous topic
 func (mq *MessageQueue) CreateTopic(name string, maxSize int) *Topic {
+	return mq.CreateTopicWithConfig(name, TopicConfig{MaxSize: maxSize})
+}
+
+// CreateTopicWithConfig creates a new topic from config. If the topic already
+// exists it is returned unchanged.
+func (mq *MessageQueue) CreateTopicWithConfig(name string, config TopicConfig) *Topic {
 	mq.mu.Lock()
 	defer mq.mu.Unlock()
 	
@@ -294,7 +468,7 @@ func (mq *MessageQueue) CreateTopic(name string, maxSize int) *Topic {
 		return topic
 	}
 	
-	topic := NewTopic(name, maxSize)
+	topic := NewTopicWithConfig(name, config)
 	mq.topics[name] = topic
 	return topic
 }
@@ -319,21 +493,43 @@ func (mq *MessageQueue) DeleteTopic(name string) bool {
 	return true
 }
 
-// Publish publishes a message to a topic
-func (mq *MessageQueue) Publish(topicName, payload string, headers map[string]string) string {
+// Publish publishes a message to a topic and returns its ID. The message is
+// delivered to the topic's subscribers and to every consumer whose wildcard
+// pattern matches the topic name. The error is ErrInvalidTopic if the name
+// contains wildcards, ErrTopicFull if the message was dropped, or a
+// *DeliveryError if the topic is synchronous and a subscriber failed to
+// handle it.
+func (mq *MessageQueue) Publish(topicName, payload string, headers map[string]string) (string, error) {
+	if err := validateTopic(topicName, false); err != nil {
+		return "", err
+	}
+	
 	// Create topic if it doesn't exist
 	topic := mq.CreateTopic(topicName, 1000)
 	
-	message := NewMessage(topicName, payload, headers)
-	topic.AddMessage(message)
-	return message.ID
+	mq.mu.RLock()
+	ids := mq.ids
+	mq.mu.RUnlock()
+	
+	message := newMessage(ids.NewID(), topicName, payload, headers)
+	return message.ID, topic.addMessage(message, mq.patterns.Match(topicName))
 }
 
-// Subscribe subscribes a consumer to a topic
-func (mq *MessageQueue) Subscribe(consumer *Consumer, topicName string) {
-	// Create topic if it doesn't exist
-	topic := mq.CreateTopic(topicName, 1000)
-	topic.Subscribe(consumer)
+// Subscribe subscribes a consumer to a topic, or to every topic matching a
+// pattern containing * or # wildcards
+func (mq *MessageQueue) Subscribe(consumer *Consumer, topicName string) error {
+	if err := validateTopic(topicName, true); err != nil {
+		return err
+	}
+	
+	if IsPattern(topicName) {
+		mq.patterns.Insert(topicName, consumer)
+		consumer.addSubscription(topicName)
+	} else {
+		// Create topic if it doesn't exist
+		topic := mq.CreateTopic(topicName, 1000)
+		topic.Subscribe(consumer)
+	}
 	
 	mq.mu.Lock()
 	defer mq.mu.Unlock()
@@ -341,14 +537,21 @@ func (mq *MessageQueue) Subscribe(consumer *Consumer, topicName string) {
 	// Add consumer to our list if not already present
 	for _, c := range mq.consumers {
 		if c.ID() == consumer.ID() {
-			return
+			return nil
 		}
 	}
 	mq.consumers = append(mq.consumers, consumer)
+	return nil
 }
 
-// Unsubscribe unsubscribes a consumer from a topic
+// Unsubscribe unsubscribes a consumer from a topic or pattern
 func (mq *MessageQueue) Unsubscribe(consumer *Consumer, topicName string) {
+	if IsPattern(topicName) {
+		mq.patterns.Remove(topicName, consumer)
+		consumer.removeSubscription(topicName)
+		return
+	}
+	
 	mq.mu.RLock()
 	topic, exists := mq.topics[topicName]
 	mq.mu.RUnlock()
@@ -382,22 +585,32 @@ func (mq *MessageQueue) GetAllStats() map[string]interface{} {
 		topicStats[name] = topic.GetStats()
 	}
 	
+	consumerStats := make(map[string]ConsumerStats)
+	for _, consumer := range mq.consumers {
+		consumerStats[consumer.ID()] = consumer.GetStats()
+	}
+	
 	return map[string]interface{}{
 		"topics":         topicStats,
+		"consumers":      consumerStats,
 		"totalTopics":    len(mq.topics),
 		"totalConsumers": len(mq.consumers),
 	}
 }
 
-// Close closes the message queue and all topics
+// Close drains all topics, waiting up to DefaultDrainTimeout for buffered
+// messages to be delivered, then stops all consumers
 func (mq *MessageQueue) Close() {
-	mq.mu.Lock()
-	defer mq.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultDrainTimeout)
+	defer cancel()
 	
-	for _, topic := range mq.topics {
-		topic.Close()
+	if err := mq.Drain(ctx); err != nil {
+		log.Printf("Message queue closed before draining: %v", err)
 	}
 	
+	mq.mu.RLock()
+	defer mq.mu.RUnlock()
+	
 	for _, consumer := range mq.consumers {
 		consumer.Stop()
 	}
@@ -418,7 +631,7 @@ func NewProducer(id string, messageQueue *MessageQueue) *Producer {
 }
 
 // Publish publishes a message to a topic
-func (p *Producer) Publish(topic, payload string, headers map[string]string) string {
+func (p *Producer) Publish(topic, payload string, headers map[string]string) (string, error) {
 	return p.messageQueue.Publish(topic, payload, headers)
 }
 
@@ -440,13 +653,13 @@ func NewPrintMessageHandler(consumerID string) *PrintMessageHandler {
 // HandleMessage implements MessageHandler
 func (h *PrintMessageHandler) HandleMessage(message *Message) error {
 	fmt.Printf("[%s] Received message %s on topic '%s': %s\n",
-		h.consumerID, message.ID[:8], message.Topic, message.Payload)
+		h.consumerID, shortID(message.ID), message.Topic, message.Payload)
 	return nil
 }
 
 // Demo demonstrates the message queue system
 func demo() {
-	fmt.Println("=== Message Queue Demo ===\n")
+	fmt.Print("=== Message Queue Demo ===\n\n")
 	
 	// Create message queue
 	mq := NewMessageQueue()
@@ -499,6 +712,29 @@ func demo() {
 }
 
 func main() {
-	rand.Seed(time.Now().UnixNano())
 	demo()
+	fmt.Println()
+	demoRetries()
+	fmt.Println()
+	demoSynchronousDelivery()
+	fmt.Println()
+	demoTopicPatterns()
+	fmt.Println()
+	demoOverflowPolicies()
+	fmt.Println()
+	demoReplay()
+	fmt.Println()
+	demoDrain()
+	fmt.Println()
+	demoPoll()
+	fmt.Println()
+	demoWorkerPool()
+	fmt.Println()
+	demoStatsHandler()
+	fmt.Println()
+	demoConsumerLag()
+	fmt.Println()
+	demoIDGenerator()
+	fmt.Println()
+	demoPauseResume()
 }
\ No newline at end of file
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBlockTimeout is how long a Block topic waits for buffer space when
+// TopicConfig.BlockTimeout is not set
+const DefaultBlockTimeout = time.Second
+
+// OverflowPolicy decides which message is lost when a topic's buffer is full
+type OverflowPolicy int
+
+const (
+	// DropNewest rejects the message being published
+	DropNewest OverflowPolicy = iota
+	// DropOldest evicts the oldest buffered message to make room
+	DropOldest
+	// Block waits up to the topic's block timeout for room, then drops the
+	// message being published
+	Block
+)
+
+// String returns the policy name
+func (p OverflowPolicy) String() string {
+	switch p {
+	case DropNewest:
+		return "DropNewest"
+	case DropOldest:
+		return "DropOldest"
+	case Block:
+		return "Block"
+	default:
+		return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+	}
+}
+
+var (
+	// ErrMessageEvicted is returned to a synchronous publisher whose message
+	// was evicted from a DropOldest topic before it was delivered
+	ErrMessageEvicted = errors.New("message evicted by a newer message")
+	// ErrTopicClosed is returned when publishing to a closed topic
+	ErrTopicClosed = errors.New("topic is closed")
+)
+
+// queuedMessage is a message waiting in a topic's buffer
+type queuedMessage struct {
+	message *Message
+	extra   []*Consumer
+	result  chan error // delivery result, synchronous topics only
+}
+
+// enqueue buffers a message, applying the overflow policy if the buffer is full
+func (t *Topic) enqueue(queued *queuedMessage) error {
+	// Holding sendMu keeps Drain and Close from closing messages mid-send
+	t.sendMu.RLock()
+	defer t.sendMu.RUnlock()
+	if t.closed {
+		return ErrTopicClosed
+	}
+
+	select {
+	case t.messages <- queued:
+		return nil
+	default:
+	}
+
+	switch t.overflow {
+	case DropOldest:
+		for {
+			select {
+			case t.messages <- queued:
+				return nil
+			default:
+			}
+			// Another publisher or the dispatcher may take the head first,
+			// in which case the next send has room
+			select {
+			case oldest := <-t.messages:
+				t.drop(oldest, ErrMessageEvicted)
+			default:
+			}
+		}
+	case Block:
+		timer := time.NewTimer(t.blockTimeout)
+		defer timer.Stop()
+		select {
+		case t.messages <- queued:
+			return nil
+		case <-timer.C:
+		case <-t.closing:
+			return ErrTopicClosed
+		}
+	}
+
+	t.drop(queued, ErrTopicFull)
+	return ErrTopicFull
+}
+
+// drop counts and logs a lost message and reports err to its publisher if it
+// is waiting
+func (t *Topic) drop(queued *queuedMessage, err error) {
+	atomic.AddInt64(&t.dropped, 1)
+	log.Printf("Topic %s dropping message %s: %v", t.name, queued.message.ID, err)
+	if queued.result != nil && err != ErrTopicFull {
+		queued.result <- err
+	}
+}
+
+// demoOverflowPolicies floods a small topic behind a slow consumer with each policy
+func demoOverflowPolicies() {
+	fmt.Println("=== Overflow Policy Demo ===")
+
+	for _, policy := range []OverflowPolicy{DropNewest, DropOldest, Block} {
+		mq := NewMessageQueue()
+		topic := mq.CreateTopicWithConfig("telemetry", TopicConfig{
+			MaxSize:      2,
+			Synchronous:  true, // the dispatcher waits for the slow consumer
+			Overflow:     policy,
+			BlockTimeout: 100 * time.Millisecond,
+		})
+
+		var mu sync.Mutex
+		var delivered []string
+		consumer := NewConsumer("dashboard", MessageHandlerFunc(func(message *Message) error {
+			time.Sleep(40 * time.Millisecond)
+			mu.Lock()
+			delivered = append(delivered, message.Payload)
+			mu.Unlock()
+			return nil
+		}))
+		mq.Subscribe(consumer, "telemetry")
+
+		var wg sync.WaitGroup
+		for i := 1; i <= 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				mq.Publish("telemetry", fmt.Sprintf("r%d", i), nil)
+			}(i)
+			time.Sleep(5 * time.Millisecond)
+		}
+		wg.Wait()
+
+		mu.Lock()
+		fmt.Printf("%-10s delivered [%s], dropped %d\n", policy, strings.Join(delivered, " "), topic.GetStats().Dropped)
+		mu.Unlock()
+		mq.Close()
+	}
+}
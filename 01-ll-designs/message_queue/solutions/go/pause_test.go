@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// recordingConsumer returns a consumer with one worker that records the
+// payloads it handles, and a function returning them so far
+func recordingConsumer(id string) (*Consumer, func() []string) {
+	var mu sync.Mutex
+	var payloads []string
+	consumer := NewConsumer(id, MessageHandlerFunc(func(message *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		payloads = append(payloads, message.Payload)
+		return nil
+	}))
+	consumer.SetWorkerPool(1, 10)
+	return consumer, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), payloads...)
+	}
+}
+
+func TestPauseBufferHoldsUntilResume(t *testing.T) {
+	mq := NewMessageQueue()
+	defer mq.Close()
+	reports, handled := recordingConsumer("reports")
+	reports.SetPausePolicy(PauseBuffer, 3)
+	mq.Subscribe(reports, "sales")
+
+	mq.Publish("sales", "sale 1", nil)
+	waitFor(t, "sale 1", func() bool { return len(handled()) == 1 })
+
+	reports.Pause()
+	if !reports.IsPaused() {
+		t.Fatal("IsPaused() = false after Pause")
+	}
+	for i := 2; i <= 6; i++ {
+		mq.Publish("sales", fmt.Sprintf("sale %d", i), nil)
+	}
+	waitFor(t, "five messages to reach the paused consumer", func() bool {
+		stats := reports.GetStats()
+		return stats.Held+stats.Skipped == 5
+	})
+	stats := reports.GetStats()
+	if stats.Held != 3 || stats.Skipped != 2 {
+		t.Errorf("while paused: %d held, %d skipped, want 3 and 2", stats.Held, stats.Skipped)
+	}
+	if got := handled(); len(got) != 1 {
+		t.Errorf("handled %v while paused, want only sale 1", got)
+	}
+
+	reports.Resume()
+	waitFor(t, "held messages to flush", func() bool { return len(handled()) == 4 })
+	if got, want := fmt.Sprint(handled()), "[sale 1 sale 2 sale 3 sale 4]"; got != want {
+		t.Errorf("handled %s, want %s", got, want)
+	}
+	if reports.IsPaused() || reports.GetStats().Held != 0 {
+		t.Errorf("after Resume: paused %t, %d held", reports.IsPaused(), reports.GetStats().Held)
+	}
+
+	// New messages flow again, after the flushed ones
+	mq.Publish("sales", "sale 7", nil)
+	waitFor(t, "sale 7", func() bool { return len(handled()) == 5 })
+	if got := handled()[4]; got != "sale 7" {
+		t.Errorf("last handled %q, want sale 7", got)
+	}
+}
+
+func TestPauseSkipDropsMessages(t *testing.T) {
+	mq := NewMessageQueue()
+	defer mq.Close()
+	audit, handled := recordingConsumer("audit")
+	audit.SetPausePolicy(PauseSkip, 0)
+	mq.Subscribe(audit, "sales")
+
+	audit.Pause()
+	for i := 1; i <= 3; i++ {
+		mq.Publish("sales", fmt.Sprintf("sale %d", i), nil)
+	}
+	waitFor(t, "three skipped messages", func() bool { return audit.GetStats().Skipped == 3 })
+
+	audit.Resume()
+	mq.Publish("sales", "sale 4", nil)
+	waitFor(t, "sale 4", func() bool { return len(handled()) == 1 })
+	if got := handled(); got[0] != "sale 4" {
+		t.Errorf("handled %v, want only sale 4", got)
+	}
+}
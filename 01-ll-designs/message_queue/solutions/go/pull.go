@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// pullQueue returns the topic's pull queue, creating it on the first Poll so
+// pollers, like subscribers, see messages published from then on
+func (t *Topic) pullQueue() chan *Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pull == nil {
+		t.pull = make(chan *Message, t.maxSize)
+		if t.pullClosed {
+			close(t.pull)
+		}
+	}
+	return t.pull
+}
+
+// offerPull copies a delivered message to the pull queue, if anyone has
+// polled, evicting the oldest message if pollers have fallen behind
+func (t *Topic) offerPull(message *Message) {
+	t.mu.RLock()
+	queue := t.pull
+	t.mu.RUnlock()
+	if queue == nil {
+		return
+	}
+
+	for {
+		select {
+		case queue <- message:
+			return
+		default:
+		}
+		select {
+		case <-queue:
+			atomic.AddInt64(&t.dropped, 1)
+		default:
+		}
+	}
+}
+
+// closePull closes the pull queue once the dispatcher has stopped, so
+// pollers drain what is left and then get ErrTopicClosed
+func (t *Topic) closePull() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pullClosed = true
+	if t.pull != nil {
+		close(t.pull)
+	}
+}
+
+// Poll returns up to max messages from the topic's pull queue, waiting up to
+// timeout for the first one. It returns an empty slice if none arrive in
+// time, and ErrTopicClosed once the topic is closed and the queue is empty.
+//
+// All pollers of a topic share one pull queue and compete for its messages,
+// so each message goes to a single poller. The pull queue as a whole gets
+// its own copy of every message, alongside each push subscriber. Pollers
+// are not counted by synchronous delivery.
+func (t *Topic) Poll(max int, timeout time.Duration) ([]*Message, error) {
+	queue := t.pullQueue()
+	if max <= 0 {
+		return nil, nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	messages := make([]*Message, 0, max)
+	select {
+	case message, ok := <-queue:
+		if !ok {
+			return nil, ErrTopicClosed
+		}
+		messages = append(messages, message)
+	case <-timer.C:
+		return messages, nil
+	}
+
+	// Take whatever else is already waiting without blocking again
+	for len(messages) < max {
+		select {
+		case message, ok := <-queue:
+			if !ok {
+				return messages, nil
+			}
+			messages = append(messages, message)
+		default:
+			return messages, nil
+		}
+	}
+	return messages, nil
+}
+
+// Poll returns up to max messages from a topic, creating the topic if it
+// doesn't exist. See Topic.Poll.
+func (mq *MessageQueue) Poll(topicName string, max int, timeout time.Duration) ([]*Message, error) {
+	if err := validateTopic(topicName, false); err != nil {
+		return nil, err
+	}
+	return mq.CreateTopic(topicName, 1000).Poll(max, timeout)
+}
+
+// demoPoll demonstrates a pull consumer working in batches next to a push subscriber
+func demoPoll() {
+	fmt.Println("=== Pull Consumption Demo ===")
+
+	mq := NewMessageQueue()
+	defer mq.Close()
+
+	var audited int64
+	auditor := NewConsumer("auditor", MessageHandlerFunc(func(message *Message) error {
+		atomic.AddInt64(&audited, 1)
+		return nil
+	}))
+	mq.Subscribe(auditor, "jobs")
+
+	// Poll once so the pull queue exists before the jobs are published
+	if messages, _ := mq.Poll("jobs", 3, 10*time.Millisecond); len(messages) == 0 {
+		fmt.Println("Empty poll returned after its timeout")
+	}
+
+	for i := 1; i <= 7; i++ {
+		mq.Publish("jobs", fmt.Sprintf("job-%d", i), nil)
+	}
+
+	for batch := 1; ; batch++ {
+		messages, err := mq.Poll("jobs", 3, 100*time.Millisecond)
+		if err != nil || len(messages) == 0 {
+			break
+		}
+		payloads := make([]string, len(messages))
+		for i, message := range messages {
+			payloads[i] = message.Payload
+		}
+		fmt.Printf("Batch %d: %v\n", batch, payloads)
+	}
+	fmt.Printf("Push subscriber also received %d messages\n", atomic.LoadInt64(&audited))
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// retainedLog is a ring buffer of a topic's most recently delivered messages
+type retainedLog struct {
+	entries []*Message
+	next    int // slot the next message is written to
+	size    int
+	mu      sync.RWMutex
+}
+
+func newRetainedLog(capacity int) *retainedLog {
+	return &retainedLog{entries: make([]*Message, capacity)}
+}
+
+// Append records a message, overwriting the oldest once the log is full
+func (l *retainedLog) Append(message *Message) {
+	if len(l.entries) == 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries[l.next] = message
+	l.next = (l.next + 1) % len(l.entries)
+	if l.size < len(l.entries) {
+		l.size++
+	}
+}
+
+// Last returns up to n of the most recent messages, oldest first
+func (l *retainedLog) Last(n int) []*Message {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if n > l.size {
+		n = l.size
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	messages := make([]*Message, n)
+	start := l.next - n + len(l.entries)
+	for i := range messages {
+		messages[i] = l.entries[(start+i)%len(l.entries)]
+	}
+	return messages
+}
+
+// Size returns the number of retained messages
+func (l *retainedLog) Size() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.size
+}
+
+// ReplayFrom re-delivers the last n retained messages to consumer, oldest
+// first, and returns how many were replayed. It waits for each message to be
+// handled before sending the next so the consumer sees them in order.
+// Messages are replayed to this consumer only, not to other subscribers.
+func (t *Topic) ReplayFrom(consumer *Consumer, n int) int {
+	replayed := 0
+	for _, message := range t.retained.Last(n) {
+		done := make(chan struct{})
+		consumer.OnMessage(t.ctx, message, func(err error) { close(done) })
+		<-done
+		if !consumer.IsActive() {
+			break
+		}
+		replayed++
+	}
+	return replayed
+}
+
+// ReplayFrom re-delivers the last n messages retained by a topic to consumer
+func (mq *MessageQueue) ReplayFrom(topicName string, consumer *Consumer, n int) (int, error) {
+	mq.mu.RLock()
+	topic, exists := mq.topics[topicName]
+	mq.mu.RUnlock()
+
+	if !exists {
+		return 0, fmt.Errorf("topic %q does not exist", topicName)
+	}
+	return topic.ReplayFrom(consumer, n), nil
+}
+
+// demoReplay demonstrates a late subscriber catching up from the retained log
+func demoReplay() {
+	fmt.Println("=== Replay Demo ===")
+
+	mq := NewMessageQueue()
+	defer mq.Close()
+
+	mq.CreateTopicWithConfig("prices", TopicConfig{MaxSize: 100, RetainedMessages: 3})
+	for i, price := range []string{"101.5", "101.7", "101.2", "100.9", "101.4"} {
+		mq.Publish("prices", fmt.Sprintf("tick %d: %s", i+1, price), nil)
+	}
+	time.Sleep(50 * time.Millisecond) // let the dispatcher record the ticks
+
+	stats := mq.GetTopicStats("prices")
+	fmt.Printf("Topic 'prices': %d published, %d retained, %d queued\n",
+		stats.MessageCount, stats.RetainedSize, stats.QueueSize)
+
+	late := NewConsumer("late-chart", MessageHandlerFunc(func(message *Message) error {
+		fmt.Printf("[late-chart] %s\n", message.Payload)
+		return nil
+	}))
+	mq.Subscribe(late, "prices")
+	replayed, _ := mq.ReplayFrom("prices", late, 10)
+	fmt.Printf("Replayed %d retained messages to late-chart\n", replayed)
+}
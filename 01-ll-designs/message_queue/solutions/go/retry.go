@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RetryPolicy controls how a consumer retries messages its handler fails
+type RetryPolicy struct {
+	MaxAttempts    int           // total handler calls per message, including the first
+	InitialBackoff time.Duration // wait before the first retry
+	MaxBackoff     time.Duration // upper bound on the wait between retries
+	Multiplier     float64       // growth of the wait after each retry
+}
+
+// DefaultRetryPolicy returns a policy of 3 attempts with backoff starting at
+// 100ms and doubling up to 5s
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+	}
+}
+
+// NoRetryPolicy returns a policy that gives up after the first failure
+func NoRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 1}
+}
+
+// Backoff returns the wait before the given retry, where retry 1 follows the
+// first failed attempt
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < retry; i++ {
+		backoff *= p.Multiplier
+		if p.MaxBackoff > 0 && backoff >= float64(p.MaxBackoff) {
+			return p.MaxBackoff
+		}
+	}
+	return time.Duration(backoff)
+}
+
+// ConsumerStats represents delivery statistics for a consumer
+type ConsumerStats struct {
+	ID           string `json:"id"`
+	Retried      int64  `json:"retried"`
+	DeadLettered int64  `json:"deadLettered"`
+	InFlight     int64  `json:"inFlight"` // handlers running
+	Queued       int64  `json:"queued"`   // messages waiting for a worker
+	Paused       bool   `json:"paused"`
+	Held         int64  `json:"held"`    // messages held while paused
+	Skipped      int64  `json:"skipped"` // messages skipped while paused
+}
+
+// SetRetryPolicy replaces the consumer's retry policy
+func (c *Consumer) SetRetryPolicy(policy RetryPolicy) {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.retryPolicy = policy
+}
+
+// SetDeadLetterHandler registers a handler for messages that still fail
+// after the last retry. Without one they are logged and dropped.
+func (c *Consumer) SetDeadLetterHandler(handler MessageHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadLetterHandler = handler
+}
+
+// GetStats returns the consumer's delivery statistics
+func (c *Consumer) GetStats() ConsumerStats {
+	return ConsumerStats{
+		ID:           c.id,
+		Retried:      atomic.LoadInt64(&c.retried),
+		DeadLettered: atomic.LoadInt64(&c.deadLettered),
+		InFlight:     atomic.LoadInt64(&c.inFlight),
+		Queued:       atomic.LoadInt64(&c.queued),
+		Paused:       c.IsPaused(),
+		Held:         int64(c.heldCount()),
+		Skipped:      atomic.LoadInt64(&c.pause.skipped),
+	}
+}
+
+// deliver runs the handler, retrying with backoff on failure, and hands the
+// message to the dead-letter handler once the policy is exhausted. It returns
+// nil if the handler succeeded, otherwise the error that ended delivery.
+func (c *Consumer) deliver(ctx context.Context, message *Message) error {
+	c.mu.RLock()
+	policy := c.retryPolicy
+	deadLetterHandler := c.deadLetterHandler
+	c.mu.RUnlock()
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = c.handle(c.handler, message); err == nil {
+			return nil
+		}
+		log.Printf("Error in consumer %s processing message %s (attempt %d/%d): %v",
+			c.id, message.ID, attempt, policy.MaxAttempts, err)
+		if attempt >= policy.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-time.After(policy.Backoff(attempt)):
+		case <-ctx.Done():
+			log.Printf("Consumer %s abandoning message %s: topic closed", c.id, message.ID)
+			return ctx.Err()
+		}
+		if !c.IsActive() {
+			log.Printf("Consumer %s abandoning message %s: consumer stopped", c.id, message.ID)
+			return ErrConsumerStopped
+		}
+		atomic.AddInt64(&c.retried, 1)
+	}
+
+	atomic.AddInt64(&c.deadLettered, 1)
+	if deadLetterHandler == nil {
+		log.Printf("Consumer %s dropping message %s after %d attempts", c.id, message.ID, policy.MaxAttempts)
+		return err
+	}
+	if dlqErr := c.handle(deadLetterHandler, message); dlqErr != nil {
+		log.Printf("Dead-letter handler for consumer %s failed on message %s: %v", c.id, message.ID, dlqErr)
+	}
+	return err
+}
+
+// handle calls handler, turning a panic into an error so it is retried like one
+func (c *Consumer) handle(handler MessageHandler, message *Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler.HandleMessage(message)
+}
+
+// demoRetries demonstrates retries with backoff and dead-lettering
+func demoRetries() {
+	fmt.Println("=== Retry Demo ===")
+
+	mq := NewMessageQueue()
+	defer mq.Close()
+
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	// Fails "flaky" messages twice and "poison" messages every time
+	handler := MessageHandlerFunc(func(message *Message) error {
+		mu.Lock()
+		attempts[message.Payload]++
+		attempt := attempts[message.Payload]
+		mu.Unlock()
+
+		if message.Payload == "poison" || attempt < 3 {
+			return errors.New("downstream unavailable")
+		}
+		fmt.Printf("[payments] Processed '%s' on attempt %d\n", message.Payload, attempt)
+		wg.Done()
+		return nil
+	})
+
+	consumer := NewConsumer("payments", handler)
+	consumer.SetRetryPolicy(RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 20 * time.Millisecond,
+		MaxBackoff:     100 * time.Millisecond,
+		Multiplier:     2,
+	})
+	consumer.SetDeadLetterHandler(MessageHandlerFunc(func(message *Message) error {
+		mu.Lock()
+		attempt := attempts[message.Payload]
+		mu.Unlock()
+
+		fmt.Printf("[payments] Dead-lettered '%s' after %d attempts\n", message.Payload, attempt)
+		wg.Done()
+		return nil
+	}))
+	mq.Subscribe(consumer, "payments")
+
+	mq.Publish("payments", "flaky", nil)
+	mq.Publish("payments", "poison", nil)
+	wg.Wait()
+
+	stats := consumer.GetStats()
+	fmt.Printf("Consumer %s: %d retries, %d dead-lettered\n", stats.ID, stats.Retried, stats.DeadLettered)
+}
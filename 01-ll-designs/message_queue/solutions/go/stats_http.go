@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// StatsHandler returns an http.Handler that serves the queue's statistics
+// as JSON:
+//
+//	GET /stats               everything GetAllStats reports
+//	GET /topics/{name}/stats one topic's TopicStats, or 404 if it doesn't exist
+//
+// Mount it on a ServeMux, under a prefix with http.StripPrefix if needed.
+func (mq *MessageQueue) StatsHandler() http.Handler {
+	return http.HandlerFunc(mq.serveStats)
+}
+
+// serveStats routes a stats request by path
+func (mq *MessageQueue) serveStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if r.URL.Path == "/stats" {
+		writeJSON(w, http.StatusOK, mq.GetAllStats())
+		return
+	}
+
+	// Match the prefix and suffix rather than splitting on slashes, so any
+	// topic name between them is accepted
+	const prefix, suffix = "/topics/", "/stats"
+	path := r.URL.Path
+	if len(path) <= len(prefix)+len(suffix) ||
+		!strings.HasPrefix(path, prefix) || !strings.HasSuffix(path, suffix) {
+		writeJSONError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	name := path[len(prefix) : len(path)-len(suffix)]
+	stats := mq.GetTopicStats(name)
+	if stats == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("topic %q not found", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// writeJSON writes value as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// writeJSONError writes {"error": message} with the given status
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// demoStatsHandler serves the stats endpoints from a test server and
+// queries them like a monitoring tool would
+func demoStatsHandler() {
+	fmt.Println("=== Stats HTTP Endpoint Demo ===")
+
+	mq := NewMessageQueue()
+	defer mq.Close()
+
+	billing := NewConsumer("billing", MessageHandlerFunc(func(message *Message) error {
+		return nil
+	}))
+	mq.Subscribe(billing, "orders")
+	for i := 1; i <= 3; i++ {
+		mq.Publish("orders", fmt.Sprintf("order-%d", i), nil)
+	}
+
+	server := httptest.NewServer(mq.StatsHandler())
+	defer server.Close()
+
+	for _, path := range []string{"/topics/orders/stats", "/topics/refunds/stats", "/stats"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			fmt.Printf("GET %s failed: %v\n", path, err)
+			continue
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		body = []byte(strings.TrimSpace(string(body)))
+		if len(body) > 100 {
+			body = append(body[:100], "..."...)
+		}
+		fmt.Printf("GET %s -> %d %s\n", path, resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// getStats sends a request to the queue's stats handler and returns the
+// response
+func getStats(t *testing.T, mq *MessageQueue, method, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	mq.StatsHandler().ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+	return recorder
+}
+
+// decodeJSON decodes a response body into value
+func decodeJSON(t *testing.T, recorder *httptest.ResponseRecorder, value interface{}) {
+	t.Helper()
+	if got := recorder.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), value); err != nil {
+		t.Fatalf("decoding %q: %v", recorder.Body.String(), err)
+	}
+}
+
+func TestStatsHandlerTopic(t *testing.T) {
+	mq := NewMessageQueue()
+	defer mq.Close()
+	for _, topic := range []string{"orders", "orders/eu"} {
+		for i := 1; i <= 3; i++ {
+			mq.Publish(topic, fmt.Sprintf("order-%d", i), nil)
+		}
+	}
+
+	// A topic name containing a slash is matched whole
+	for _, name := range []string{"orders", "orders/eu"} {
+		recorder := getStats(t, mq, http.MethodGet, "/topics/"+name+"/stats")
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("GET %s stats = %d, want 200", name, recorder.Code)
+		}
+		var stats TopicStats
+		decodeJSON(t, recorder, &stats)
+		if stats.Name != name || stats.MessageCount != 3 {
+			t.Errorf("%s stats = %+v, want name %s with 3 messages", name, stats, name)
+		}
+	}
+}
+
+func TestStatsHandlerAll(t *testing.T) {
+	mq := NewMessageQueue()
+	defer mq.Close()
+	mq.Subscribe(NewConsumer("billing", MessageHandlerFunc(func(message *Message) error {
+		return nil
+	})), "orders")
+	mq.CreateTopic("refunds", 10)
+
+	recorder := getStats(t, mq, http.MethodGet, "/stats")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET /stats = %d, want 200", recorder.Code)
+	}
+	var all struct {
+		Topics      map[string]TopicStats `json:"topics"`
+		TotalTopics int                   `json:"totalTopics"`
+	}
+	decodeJSON(t, recorder, &all)
+	if all.TotalTopics != 2 || len(all.Topics) != 2 {
+		t.Errorf("stats list %d topics (total %d), want 2", len(all.Topics), all.TotalTopics)
+	}
+	if got := all.Topics["orders"].SubscriberCount; got != 1 {
+		t.Errorf("orders subscriberCount = %d, want 1", got)
+	}
+}
+
+func TestStatsHandlerErrors(t *testing.T) {
+	mq := NewMessageQueue()
+	defer mq.Close()
+	mq.CreateTopic("orders", 10)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantError  string
+	}{
+		{"unknown topic", http.MethodGet, "/topics/refunds/stats", http.StatusNotFound, `topic "refunds" not found`},
+		{"empty topic name", http.MethodGet, "/topics//stats", http.StatusNotFound, "not found"},
+		{"missing suffix", http.MethodGet, "/topics/orders", http.StatusNotFound, "not found"},
+		{"unknown path", http.MethodGet, "/consumers", http.StatusNotFound, "not found"},
+		{"POST", http.MethodPost, "/stats", http.StatusMethodNotAllowed, "method not allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := getStats(t, mq, tt.method, tt.path)
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("%s %s = %d, want %d", tt.method, tt.path, recorder.Code, tt.wantStatus)
+			}
+			var body map[string]string
+			decodeJSON(t, recorder, &body)
+			if body["error"] != tt.wantError {
+				t.Errorf("error = %q, want %q", body["error"], tt.wantError)
+			}
+		})
+	}
+
+	recorder := getStats(t, mq, http.MethodPost, "/stats")
+	if got := recorder.Header().Get("Allow"); got != http.MethodGet {
+		t.Errorf("Allow = %q, want GET", got)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDeliveryTimeout is how long a synchronous topic waits for its
+// subscribers when TopicConfig.DeliveryTimeout is not set
+const DefaultDeliveryTimeout = 5 * time.Second
+
+var (
+	// ErrTopicFull is returned when a topic's buffer is full and the message is dropped
+	ErrTopicFull = errors.New("topic is full")
+	// ErrConsumerStopped is reported for a consumer that stopped before handling a message
+	ErrConsumerStopped = errors.New("consumer stopped")
+	// ErrDeliveryTimeout is reported for a subscriber still handling a message
+	// when a synchronous topic's delivery timeout passes
+	ErrDeliveryTimeout = errors.New("delivery timed out")
+)
+
+// DeliveryError reports the subscribers of a synchronous topic that failed
+// to handle a message, keyed by consumer ID
+type DeliveryError struct {
+	MessageID   string
+	Subscribers int
+	Failures    map[string]error
+}
+
+// Error implements error
+func (e *DeliveryError) Error() string {
+	ids := make([]string, 0, len(e.Failures))
+	for id := range e.Failures {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("%s: %v", id, e.Failures[id])
+	}
+	return fmt.Sprintf("message %s: %d of %d subscribers failed (%s)",
+		e.MessageID, len(e.Failures), e.Subscribers, strings.Join(parts, "; "))
+}
+
+// deliveryAck collects the results of delivering one message to the
+// subscribers of a synchronous topic
+type deliveryAck struct {
+	message     *Message
+	subscribers int
+	pending     map[string]bool
+	failures    map[string]error
+	wg          sync.WaitGroup
+	mu          sync.Mutex
+}
+
+func newDeliveryAck(message *Message) *deliveryAck {
+	return &deliveryAck{
+		message:  message,
+		pending:  make(map[string]bool),
+		failures: make(map[string]error),
+	}
+}
+
+// track registers a subscriber and returns the callback it reports its
+// result to. A nil ack (asynchronous topic) returns a nil callback.
+func (a *deliveryAck) track(consumer *Consumer) func(err error) {
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	a.subscribers++
+	a.pending[consumer.ID()] = true
+	a.mu.Unlock()
+	a.wg.Add(1)
+
+	return func(err error) {
+		a.mu.Lock()
+		delete(a.pending, consumer.ID())
+		if err != nil {
+			a.failures[consumer.ID()] = err
+		}
+		a.mu.Unlock()
+		a.wg.Done()
+	}
+}
+
+// wait blocks until every tracked subscriber has reported, timeout passes, or
+// ctx is cancelled, and returns a *DeliveryError if any of them failed or
+// had not finished
+func (a *deliveryAck) wait(ctx context.Context, timeout time.Duration) error {
+	finished := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(finished)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var unfinished error
+	select {
+	case <-finished:
+	case <-timer.C:
+		unfinished = ErrDeliveryTimeout
+	case <-ctx.Done():
+		unfinished = ctx.Err()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	failures := make(map[string]error, len(a.failures)+len(a.pending))
+	for id, err := range a.failures {
+		failures[id] = err
+	}
+	for id := range a.pending {
+		failures[id] = unfinished
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return &DeliveryError{MessageID: a.message.ID, Subscribers: a.subscribers, Failures: failures}
+}
+
+// demoSynchronousDelivery demonstrates publishing that waits for subscribers
+func demoSynchronousDelivery() {
+	fmt.Println("=== Synchronous Delivery Demo ===")
+
+	mq := NewMessageQueue()
+	defer mq.Close()
+
+	mq.CreateTopicWithConfig("ledger", TopicConfig{
+		MaxSize:         100,
+		Synchronous:     true,
+		DeliveryTimeout: 200 * time.Millisecond,
+	})
+
+	ledger := NewConsumer("ledger-writer", MessageHandlerFunc(func(message *Message) error {
+		fmt.Printf("[ledger-writer] Recorded %s\n", message.Payload)
+		return nil
+	}))
+	audit := NewConsumer("audit", MessageHandlerFunc(func(message *Message) error {
+		if strings.Contains(message.Payload, "-") {
+			return errors.New("negative amounts need approval")
+		}
+		return nil
+	}))
+	audit.SetRetryPolicy(NoRetryPolicy())
+	slow := NewConsumer("reporting", MessageHandlerFunc(func(message *Message) error {
+		if strings.Contains(message.Payload, "bulk") {
+			time.Sleep(500 * time.Millisecond)
+		}
+		return nil
+	}))
+	mq.Subscribe(ledger, "ledger")
+	mq.Subscribe(audit, "ledger")
+	mq.Subscribe(slow, "ledger")
+
+	for _, payload := range []string{"credit 100", "debit -40", "bulk import"} {
+		start := time.Now()
+		_, err := mq.Publish("ledger", payload, nil)
+		if err != nil {
+			fmt.Printf("Publish '%s' failed after %v: %v\n", payload, time.Since(start).Round(10*time.Millisecond), err)
+		} else {
+			fmt.Printf("Publish '%s' acknowledged by all subscribers\n", payload)
+		}
+	}
+}
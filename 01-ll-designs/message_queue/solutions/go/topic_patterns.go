@@ -0,0 +1,253 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Topic names are hierarchical, with levels separated by dots
+// ("orders.eu.created"). Subscription patterns may use two wildcards, each
+// standing for whole levels. A * matches exactly one level, so
+// "orders.*.created" matches "orders.eu.created". A # matches zero or more
+// levels, so "orders.#" matches both "orders" and "orders.eu.created".
+const (
+	topicSeparator      = "."
+	singleLevelWildcard = "*"
+	multiLevelWildcard  = "#"
+)
+
+// ErrInvalidTopic is returned for topic names or patterns with empty levels,
+// or wildcards mixed with other characters in a level
+var ErrInvalidTopic = errors.New("invalid topic")
+
+// IsPattern reports whether a subscription contains wildcards
+func IsPattern(pattern string) bool {
+	for _, level := range strings.Split(pattern, topicSeparator) {
+		if level == singleLevelWildcard || level == multiLevelWildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTopic checks a topic name, or a pattern if allowWildcards is set
+func validateTopic(name string, allowWildcards bool) error {
+	for _, level := range strings.Split(name, topicSeparator) {
+		if level == "" {
+			return fmt.Errorf("%w: %q has an empty level", ErrInvalidTopic, name)
+		}
+		isWildcard := level == singleLevelWildcard || level == multiLevelWildcard
+		if isWildcard && !allowWildcards {
+			return fmt.Errorf("%w: %q cannot contain wildcards", ErrInvalidTopic, name)
+		}
+		if !isWildcard && strings.ContainsAny(level, singleLevelWildcard+multiLevelWildcard) {
+			return fmt.Errorf("%w: wildcard in %q must be a whole level", ErrInvalidTopic, name)
+		}
+	}
+	return nil
+}
+
+// TopicMatches reports whether a topic name matches a subscription pattern
+func TopicMatches(pattern, topic string) bool {
+	return matchLevels(strings.Split(pattern, topicSeparator), strings.Split(topic, topicSeparator))
+}
+
+func matchLevels(pattern, topic []string) bool {
+	if len(pattern) == 0 {
+		return len(topic) == 0
+	}
+
+	switch pattern[0] {
+	case multiLevelWildcard:
+		for i := 0; i <= len(topic); i++ {
+			if matchLevels(pattern[1:], topic[i:]) {
+				return true
+			}
+		}
+		return false
+	case singleLevelWildcard:
+		return len(topic) > 0 && matchLevels(pattern[1:], topic[1:])
+	default:
+		return len(topic) > 0 && pattern[0] == topic[0] && matchLevels(pattern[1:], topic[1:])
+	}
+}
+
+// subscriptionNode is one level of a subscription trie
+type subscriptionNode struct {
+	children  map[string]*subscriptionNode
+	consumers map[string]*Consumer
+}
+
+func newSubscriptionNode() *subscriptionNode {
+	return &subscriptionNode{
+		children:  make(map[string]*subscriptionNode),
+		consumers: make(map[string]*Consumer),
+	}
+}
+
+// subscriptionTrie indexes pattern subscriptions by level, so routing a
+// message only walks the branches that can match its topic instead of
+// testing every pattern
+type subscriptionTrie struct {
+	root *subscriptionNode
+	mu   sync.RWMutex
+}
+
+func newSubscriptionTrie() *subscriptionTrie {
+	return &subscriptionTrie{root: newSubscriptionNode()}
+}
+
+// Insert subscribes a consumer to a pattern
+func (st *subscriptionTrie) Insert(pattern string, consumer *Consumer) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	node := st.root
+	for _, level := range strings.Split(pattern, topicSeparator) {
+		child, exists := node.children[level]
+		if !exists {
+			child = newSubscriptionNode()
+			node.children[level] = child
+		}
+		node = child
+	}
+	node.consumers[consumer.ID()] = consumer
+}
+
+// Remove unsubscribes a consumer from a pattern, pruning emptied branches
+func (st *subscriptionTrie) Remove(pattern string, consumer *Consumer) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	levels := strings.Split(pattern, topicSeparator)
+	path := []*subscriptionNode{st.root}
+	for _, level := range levels {
+		child, exists := path[len(path)-1].children[level]
+		if !exists {
+			return
+		}
+		path = append(path, child)
+	}
+	delete(path[len(path)-1].consumers, consumer.ID())
+
+	for i := len(levels) - 1; i >= 0; i-- {
+		node := path[i+1]
+		if len(node.consumers) > 0 || len(node.children) > 0 {
+			break
+		}
+		delete(path[i].children, levels[i])
+	}
+}
+
+// Match returns every consumer with a pattern matching topic, once each
+func (st *subscriptionTrie) Match(topic string) []*Consumer {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	matched := make(map[string]*Consumer)
+	st.match(st.root, strings.Split(topic, topicSeparator), matched)
+
+	consumers := make([]*Consumer, 0, len(matched))
+	for _, consumer := range matched {
+		consumers = append(consumers, consumer)
+	}
+	return consumers
+}
+
+// match collects consumers under node matching the remaining levels (must be
+// called with lock held)
+func (st *subscriptionTrie) match(node *subscriptionNode, levels []string, matched map[string]*Consumer) {
+	if len(levels) == 0 {
+		for id, consumer := range node.consumers {
+			matched[id] = consumer
+		}
+	} else {
+		if child, exists := node.children[levels[0]]; exists {
+			st.match(child, levels[1:], matched)
+		}
+		if child, exists := node.children[singleLevelWildcard]; exists {
+			st.match(child, levels[1:], matched)
+		}
+	}
+
+	// # consumes zero or more of the remaining levels
+	if child, exists := node.children[multiLevelWildcard]; exists {
+		for i := 0; i <= len(levels); i++ {
+			st.match(child, levels[i:], matched)
+		}
+	}
+}
+
+// demoTopicPatterns demonstrates hierarchical topics and wildcard subscriptions
+func demoTopicPatterns() {
+	fmt.Println("=== Wildcard Subscription Demo ===")
+
+	cases := []struct {
+		pattern, topic string
+	}{
+		{"orders.*", "orders.created"},
+		{"orders.*", "orders.eu.created"},
+		{"orders.#", "orders.eu.created"},
+		{"orders.#", "orders"},
+		{"orders.*.created", "orders.eu.created"},
+		{"orders.#.created", "orders.created"},
+		{"#.created", "payments.us.created"},
+		{"*.created", "orders.eu.created"},
+	}
+	for _, c := range cases {
+		fmt.Printf("%-18s matches %-20s %t\n", c.pattern, c.topic, TopicMatches(c.pattern, c.topic))
+	}
+
+	mq := NewMessageQueue()
+	defer mq.Close()
+
+	var mu sync.Mutex
+	received := make(map[string][]string)
+	var wg sync.WaitGroup
+	recorder := func(id string) *Consumer {
+		return NewConsumer(id, MessageHandlerFunc(func(message *Message) error {
+			mu.Lock()
+			received[id] = append(received[id], message.Topic)
+			mu.Unlock()
+			wg.Done()
+			return nil
+		}))
+	}
+
+	allOrders := recorder("all-orders")
+	euOrders := recorder("eu-orders")
+	created := recorder("created-events")
+	mq.Subscribe(allOrders, "orders.#")
+	mq.Subscribe(euOrders, "orders.eu.*")
+	mq.Subscribe(euOrders, "orders.eu.created") // overlaps orders.eu.*, delivered once
+	mq.Subscribe(created, "#.created")
+
+	// Each topic lists how many consumers should receive it
+	published := []struct {
+		topic     string
+		receivers int
+	}{
+		{"orders.eu.created", 3},
+		{"orders.us.shipped", 1},
+		{"payments.created", 1},
+		{"orders", 1},
+	}
+	for _, p := range published {
+		wg.Add(p.receivers)
+		mq.Publish(p.topic, "event", nil)
+	}
+	wg.Wait()
+
+	for _, id := range []string{"all-orders", "eu-orders", "created-events"} {
+		topics := received[id]
+		sort.Strings(topics)
+		fmt.Printf("[%s] received %v\n", id, topics)
+	}
+
+	if err := mq.Subscribe(created, "orders.#x"); err != nil {
+		fmt.Printf("Invalid pattern rejected: %v\n", err)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultConsumerWorkers is how many handlers a consumer runs at once
+	DefaultConsumerWorkers = 4
+	// DefaultConsumerQueueSize is how many messages wait for a free worker
+	// before OnMessage blocks
+	DefaultConsumerQueueSize = 256
+)
+
+// consumerJob is a message waiting for one of the consumer's workers
+type consumerJob struct {
+	ctx     context.Context
+	message *Message
+	done    func(err error)
+}
+
+// SetWorkerPool sets how many handlers the consumer runs at once and how many
+// messages may wait for a free worker. One worker handles messages in the
+// order they arrive. The pool starts with the first message, after which
+// this has no effect.
+func (c *Consumer) SetWorkerPool(workers, queueSize int) {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	c.poolMu.Lock()
+	defer c.poolMu.Unlock()
+	if c.jobs == nil {
+		c.workers = workers
+		c.queueSize = queueSize
+	}
+}
+
+// startWorkers creates the job channel and its workers on first use
+func (c *Consumer) startWorkers() {
+	c.poolMu.RLock()
+	started := c.jobs != nil
+	c.poolMu.RUnlock()
+	if started {
+		return
+	}
+
+	c.poolMu.Lock()
+	defer c.poolMu.Unlock()
+	if c.jobs != nil || c.poolStopped {
+		return
+	}
+	c.jobs = make(chan consumerJob, c.queueSize)
+	for i := 0; i < c.workers; i++ {
+		go c.work(c.jobs)
+	}
+}
+
+// work handles jobs until the consumer is stopped and its queue is empty.
+// Jobs still queued when the consumer stops are abandoned.
+func (c *Consumer) work(jobs <-chan consumerJob) {
+	for job := range jobs {
+		atomic.AddInt64(&c.queued, -1)
+		if !c.IsActive() {
+			job.finish(ErrConsumerStopped)
+			continue
+		}
+
+		atomic.AddInt64(&c.inFlight, 1)
+		err := c.deliver(job.ctx, job.message)
+		atomic.AddInt64(&c.inFlight, -1)
+		job.finish(err)
+	}
+}
+
+// finish reports the outcome of a job to its callback, if any
+func (j consumerJob) finish(err error) {
+	if j.done != nil {
+		j.done(err)
+	}
+}
+
+// stopWorkers closes the job channel so workers exit once it is empty
+func (c *Consumer) stopWorkers() {
+	c.poolMu.Lock()
+	defer c.poolMu.Unlock()
+	if c.jobs != nil && !c.poolStopped {
+		close(c.jobs)
+	}
+	c.poolStopped = true
+}
+
+// demoWorkerPool demonstrates that a slow consumer holds a fixed number of
+// goroutines however fast messages are published
+func demoWorkerPool() {
+	fmt.Println("=== Worker Pool Demo ===")
+
+	mq := NewMessageQueue()
+	defer mq.Close()
+	mq.CreateTopicWithConfig("clicks", TopicConfig{MaxSize: 10, Overflow: Block})
+
+	var handled int64
+	analytics := NewConsumer("analytics", MessageHandlerFunc(func(message *Message) error {
+		time.Sleep(time.Millisecond)
+		atomic.AddInt64(&handled, 1)
+		return nil
+	}))
+	analytics.SetWorkerPool(2, 10)
+	mq.Subscribe(analytics, "clicks")
+
+	peak := runtime.NumGoroutine()
+	for i := 0; i < 500; i++ {
+		mq.Publish("clicks", fmt.Sprintf("click %d", i), nil)
+		if n := runtime.NumGoroutine(); n > peak {
+			peak = n
+		}
+	}
+
+	stats := analytics.GetStats()
+	fmt.Printf("Published 500 messages: %d handled, %d in flight, %d queued\n",
+		atomic.LoadInt64(&handled), stats.InFlight, stats.Queued)
+	fmt.Printf("Goroutines peaked at %d\n", peak)
+}
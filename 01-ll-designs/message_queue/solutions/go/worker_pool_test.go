@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestWorkerPoolBoundsGoroutines publishes far more messages than a slow
+// consumer can keep up with and checks the consumer never runs more
+// goroutines than its pool
+func TestWorkerPoolBoundsGoroutines(t *testing.T) {
+	const (
+		workers   = 4
+		messages  = 100000
+		tolerance = 3 // goroutines the runtime may start or finish on its own
+	)
+
+	mq := NewMessageQueue()
+	defer mq.Close()
+	mq.CreateTopicWithConfig("clicks", TopicConfig{MaxSize: 10, Overflow: Block})
+
+	var handled int64
+	consumer := NewConsumer("analytics", MessageHandlerFunc(func(message *Message) error {
+		atomic.AddInt64(&handled, 1)
+		return nil
+	}))
+	consumer.SetWorkerPool(workers, 10)
+	mq.Subscribe(consumer, "clicks")
+
+	base := runtime.NumGoroutine()
+	peak := base
+	for i := 0; i < messages; i++ {
+		if _, err := mq.Publish("clicks", fmt.Sprintf("click %d", i), nil); err != nil {
+			t.Fatalf("Publish %d: %v", i, err)
+		}
+		if n := runtime.NumGoroutine(); n > peak {
+			peak = n
+		}
+	}
+
+	if limit := base + workers + tolerance; peak > limit {
+		t.Errorf("goroutines peaked at %d, want at most %d (%d before publishing, %d workers)", peak, limit, base, workers)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for atomic.LoadInt64(&handled) < messages && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := atomic.LoadInt64(&handled); got != messages {
+		t.Errorf("handled %d messages, want %d", got, messages)
+	}
+}
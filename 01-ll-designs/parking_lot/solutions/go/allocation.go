@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SpotAllocationStrategy decides which free spot a vehicle gets. spotTypes
+// lists the spot types the vehicle may use, most preferred first. Allocate
+// must take the chosen spot out of its level's free queue and return the level
+// and spot index, or ErrNoAvailableSpots if no such spot is free.
+type SpotAllocationStrategy interface {
+	Allocate(levels []*ParkingLevel, spotTypes []SpotType) (*ParkingLevel, int, error)
+	Name() string
+}
+
+// BestFitStrategy fills levels in the order given and, within a level, uses the smallest
+// compatible spot type first so larger spots stay free for larger vehicles
+type BestFitStrategy struct{}
+
+// Allocate implements SpotAllocationStrategy
+func (BestFitStrategy) Allocate(levels []*ParkingLevel, spotTypes []SpotType) (*ParkingLevel, int, error) {
+	for _, level := range levels {
+		if spotIndex, err := level.FindAvailableSpot(spotTypes); err == nil {
+			return level, spotIndex, nil
+		}
+	}
+	return nil, -1, ErrNoAvailableSpots
+}
+
+// Name implements SpotAllocationStrategy
+func (BestFitStrategy) Name() string { return "BestFit" }
+
+// NearestToEntranceStrategy picks the compatible free spot with the smallest
+// Distance on any level, regardless of spot size. Ties go to the level
+// searched first.
+type NearestToEntranceStrategy struct{}
+
+// Allocate implements SpotAllocationStrategy
+func (NearestToEntranceStrategy) Allocate(levels []*ParkingLevel, spotTypes []SpotType) (*ParkingLevel, int, error) {
+	level, spotIndex := nearestFreeSpot(levels, spotTypes)
+	if level == nil {
+		return nil, -1, ErrNoAvailableSpots
+	}
+	if err := level.ClaimSpot(spotIndex); err != nil {
+		return nil, -1, err
+	}
+	return level, spotIndex, nil
+}
+
+// Name implements SpotAllocationStrategy
+func (NearestToEntranceStrategy) Name() string { return "NearestToEntrance" }
+
+// nearestFreeSpot returns the free spot of the given types with the smallest
+// Distance, preferring earlier levels on ties, or a nil level if none is free
+func nearestFreeSpot(levels []*ParkingLevel, spotTypes []SpotType) (*ParkingLevel, int) {
+	var bestLevel *ParkingLevel
+	bestIndex := -1
+	bestDistance := 0.0
+
+	for _, level := range levels {
+		for _, spotType := range spotTypes {
+			for _, spotIndex := range level.GetFreeSpotIndices(spotType) {
+				spot, err := level.GetSpot(spotIndex)
+				if err != nil {
+					continue
+				}
+				if distance := spot.GetDistance(); bestIndex == -1 || distance < bestDistance {
+					bestLevel, bestIndex, bestDistance = level, spotIndex, distance
+				}
+			}
+		}
+	}
+	return bestLevel, bestIndex
+}
+
+// BalanceAcrossLevelsStrategy parks on the level with the lowest occupancy
+// ratio that has a compatible spot, spreading wear and traffic evenly. Within
+// the level it uses best fit.
+type BalanceAcrossLevelsStrategy struct{}
+
+// Allocate implements SpotAllocationStrategy
+func (BalanceAcrossLevelsStrategy) Allocate(levels []*ParkingLevel, spotTypes []SpotType) (*ParkingLevel, int, error) {
+	var bestLevel *ParkingLevel
+	bestRatio := 0.0
+
+	for _, level := range levels {
+		if !level.HasAvailableSpot(spotTypes) {
+			continue
+		}
+		ratio := float64(level.GetOccupiedSpots()) / float64(level.GetTotalSpots())
+		if bestLevel == nil || ratio < bestRatio {
+			bestLevel, bestRatio = level, ratio
+		}
+	}
+
+	if bestLevel == nil {
+		return nil, -1, ErrNoAvailableSpots
+	}
+	spotIndex, err := bestLevel.FindAvailableSpot(spotTypes)
+	if err != nil {
+		return nil, -1, err
+	}
+	return bestLevel, spotIndex, nil
+}
+
+// Name implements SpotAllocationStrategy
+func (BalanceAcrossLevelsStrategy) Name() string { return "BalanceAcrossLevels" }
+
+// DemoAllocationStrategies parks the same vehicles under each strategy
+func DemoAllocationStrategies() {
+	fmt.Println("=== Allocation Strategy Demo ===")
+
+	strategies := []SpotAllocationStrategy{
+		BestFitStrategy{},
+		NearestToEntranceStrategy{},
+		BalanceAcrossLevelsStrategy{},
+	}
+
+	for _, strategy := range strategies {
+		parkingLot := NewParkingLot("Strategy Demo", []*ParkingLevel{
+			NewParkingLevel(0, 1, 2, 1, 0, 0),
+			NewParkingLevel(1, 1, 2, 1, 0, 0),
+		})
+		parkingLot.SetAllocationStrategy(strategy)
+
+		fmt.Printf("%s:\n", strategy.Name())
+		for i, vehicleType := range []VehicleType{VehicleTypeCar, VehicleTypeCar, VehicleTypeMotorcycle, VehicleTypeCar} {
+			vehicle, _ := NewVehicle(fmt.Sprintf("%s-%d", strategy.Name(), i+1), vehicleType)
+			ticket, err := parkingLot.ParkVehicle(vehicle)
+			if err != nil {
+				fmt.Printf("  ✗ %s: %v\n", vehicleType, err)
+				continue
+			}
+			fmt.Printf("  %-10s -> Level %d, Spot %d (%s)\n", vehicleType, ticket.LevelIndex, ticket.SpotID, ticket.SpotType)
+		}
+	}
+
+	// Query the nearest spot without taking it
+	parkingLot := NewParkingLot("Nearest Demo", []*ParkingLevel{
+		NewParkingLevel(0, 1, 2, 0, 0, 0),
+		NewParkingLevel(1, 0, 1, 0, 0, 0),
+	})
+	for _, vehicleType := range []VehicleType{VehicleTypeCar, VehicleTypeBus} {
+		level, spotIndex, err := parkingLot.FindNearestSpot(vehicleType)
+		var noSpot *NoSpotError
+		if errors.As(err, &noSpot) {
+			fmt.Printf("Nearest spot for %s: none (%v)\n", vehicleType, err)
+			continue
+		}
+		fmt.Printf("Nearest spot for %s: Level %d, spot index %d\n", vehicleType, level, spotIndex)
+	}
+}
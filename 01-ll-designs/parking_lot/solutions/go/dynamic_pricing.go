@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// PricingWindow applies a rate multiplier to hours that start inside it
+type PricingWindow struct {
+	Name       string         `json:"name"`
+	Days       []time.Weekday `json:"days,omitempty"` // empty means every day
+	StartHour  int            `json:"start_hour"`     // inclusive, 0-23
+	EndHour    int            `json:"end_hour"`       // exclusive; less than StartHour wraps past midnight
+	Multiplier float64        `json:"multiplier"`
+}
+
+// Contains reports whether t falls inside the window
+func (pw PricingWindow) Contains(t time.Time) bool {
+	hour := t.Hour()
+	day := t.Weekday()
+
+	// For windows that wrap midnight, the early-morning part belongs to the previous day's window
+	inHours := hour >= pw.StartHour && hour < pw.EndHour
+	if pw.EndHour <= pw.StartHour {
+		inHours = hour >= pw.StartHour || hour < pw.EndHour
+		if hour < pw.EndHour {
+			day = (day + 6) % 7
+		}
+	}
+	if !inHours {
+		return false
+	}
+
+	if len(pw.Days) == 0 {
+		return true
+	}
+	for _, d := range pw.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// DynamicPricingPolicy implements time-of-day pricing on top of the standard
+// rates: each charged hour is billed at the vehicle's hourly rate times the
+// multiplier of the first window containing the start of that hour, or at the
+// plain rate if no window matches
+type DynamicPricingPolicy struct {
+	*StandardPricingPolicy
+	Windows []PricingWindow
+}
+
+// NewDynamicPricingPolicy creates a dynamic pricing policy with weekday peak
+// pricing (9-17, 1.5x) and an overnight discount (22-6, 0.5x)
+func NewDynamicPricingPolicy() *DynamicPricingPolicy {
+	weekdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	return &DynamicPricingPolicy{
+		StandardPricingPolicy: NewStandardPricingPolicy(),
+		Windows: []PricingWindow{
+			{Name: "Peak", Days: weekdays, StartHour: 9, EndHour: 17, Multiplier: 1.5},
+			{Name: "Overnight", StartHour: 22, EndHour: 6, Multiplier: 0.5},
+		},
+	}
+}
+
+// CalculateFee calculates the time-of-day parking fee
+func (dpp *DynamicPricingPolicy) CalculateFee(vehicleType VehicleType, entryTime, exitTime time.Time, energyKWh float64) float64 {
+	return dpp.CalculateFeeDetailed(vehicleType, entryTime, exitTime, energyKWh).Total
+}
+
+// CalculateFeeDetailed walks the stay hour by hour from entryTime, pricing
+// each hour by the window it starts in, so a stay that straddles a peak
+// boundary pays peak rates only for its peak hours. The daily maximum applies
+// to each 24 hours of the stay. With a billing increment shorter than an
+// hour, the last hour is charged only for the fraction of it billed. Billing
+// starts once the grace period is over.
+func (dpp *DynamicPricingPolicy) CalculateFeeDetailed(vehicleType VehicleType, entryTime, exitTime time.Time, energyKWh float64) *FeeBreakdown {
+	breakdown := dpp.StandardPricingPolicy.calculate(vehicleType, entryTime, exitTime, energyKWh)
+	if exitTime.Before(entryTime) {
+		return dpp.finalize(breakdown)
+	}
+
+	hourlyRate := dpp.GetHourlyRate(vehicleType)
+	hours := int(math.Ceil(breakdown.HoursCharged))
+	billedFrom := entryTime
+	if dpp.GracePeriod > 0 {
+		billedFrom = entryTime.Add(dpp.GracePeriod)
+	}
+
+	hourlyCharge, dayCharge := 0.0, 0.0
+	for i := 0; i < hours; i++ {
+		fraction := math.Min(1, breakdown.HoursCharged-float64(i))
+		dayCharge += hourlyRate * fraction * dpp.multiplierAt(billedFrom.Add(time.Duration(i)*time.Hour))
+
+		// Close out each 24-hour block, and the final partial block
+		if (i+1)%24 == 0 || i == hours-1 {
+			if dpp.DailyMaximum > 0 {
+				dayCharge = math.Min(dayCharge, dpp.DailyMaximum)
+			}
+			hourlyCharge += dayCharge
+			dayCharge = 0
+		}
+	}
+
+	breakdown.HourlyCharge = hourlyCharge
+	return dpp.finalize(breakdown)
+}
+
+// multiplierAt returns the rate multiplier for an hour starting at t
+func (dpp *DynamicPricingPolicy) multiplierAt(t time.Time) float64 {
+	for _, window := range dpp.Windows {
+		if window.Contains(t) {
+			return window.Multiplier
+		}
+	}
+	return 1.0
+}
+
+// DemoDynamicPricing demonstrates stays priced across peak and overnight boundaries
+func DemoDynamicPricing() {
+	fmt.Println("=== Dynamic Pricing Demo ===")
+
+	dynamic := NewDynamicPricingPolicy()
+	standard := NewStandardPricingPolicy()
+
+	// Wednesday 10 January 2024, local time
+	day := time.Date(2024, time.January, 10, 0, 0, 0, 0, time.Local)
+	stays := []struct {
+		name        string
+		entry, exit time.Time
+	}{
+		{"Weekday 15:30-18:30 (straddles 17:00)", day.Add(15*time.Hour + 30*time.Minute), day.Add(18*time.Hour + 30*time.Minute)},
+		{"Weekday 10:00-12:00 (all peak)", day.Add(10 * time.Hour), day.Add(12 * time.Hour)},
+		{"Overnight 21:00-01:00", day.Add(21 * time.Hour), day.Add(25 * time.Hour)},
+		{"Saturday 10:00-12:00 (no peak)", day.Add(3*24*time.Hour + 10*time.Hour), day.Add(3*24*time.Hour + 12*time.Hour)},
+	}
+
+	for _, stay := range stays {
+		fmt.Printf("%-40s standard $%.2f, dynamic $%.2f\n", stay.name,
+			standard.CalculateFee(VehicleTypeCar, stay.entry, stay.exit, 0),
+			dynamic.CalculateFee(VehicleTypeCar, stay.entry, stay.exit, 0))
+	}
+
+	// Policies plug into a lot like any other
+	parkingLot := NewParkingLot("Dynamic Demo", []*ParkingLevel{NewParkingLevel(0, 0, 1, 0, 0, 0)})
+	parkingLot.SetPricingPolicy(dynamic)
+	fmt.Printf("Lot %q is using dynamic pricing\n", parkingLot.GetName())
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDynamicPricingPeakBoundaries(t *testing.T) {
+	// Wednesday 10 January 2024; cars pay the $2.00 base fee plus $1.00/h,
+	// 1.5x for hours starting 9-17 on weekdays and 0.5x for 22-6
+	wednesday := time.Date(2024, time.January, 10, 0, 0, 0, 0, time.UTC)
+	at := func(hours, minutes int) time.Time {
+		return wednesday.Add(time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute)
+	}
+
+	tests := []struct {
+		name        string
+		entry, exit time.Time
+		increment   time.Duration
+		grace       time.Duration
+		want        float64
+	}{
+		{"straddles 17:00", at(15, 30), at(18, 30), 0, 0, 2 + 1.5 + 1.5 + 1},
+		{"starts on the 17:00 boundary", at(17, 0), at(19, 0), 0, 0, 2 + 1 + 1},
+		{"last hour starts on the 17:00 boundary", at(16, 0), at(18, 0), 0, 0, 2 + 1.5 + 1},
+		{"enters before 9:00", at(8, 30), at(9, 30), 0, 0, 2 + 1},
+		{"straddles 9:00", at(8, 0), at(10, 0), 0, 0, 2 + 1 + 1.5},
+		{"into the overnight window", at(21, 0), at(25, 0), 0, 0, 2 + 1 + 0.5 + 0.5 + 0.5},
+		{"Saturday has no peak", at(3*24+10, 0), at(3*24+12, 0), 0, 0, 2 + 1 + 1},
+		{"partial hour after the peak", at(16, 0), at(17, 30), 15 * time.Minute, 0, 2 + 1.5 + 0.5},
+		{"grace pushes billing past the peak", at(16, 55), at(18, 5), 0, 10 * time.Minute, 2 + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := NewDynamicPricingPolicy()
+			policy.BillingIncrement = tt.increment
+			policy.GracePeriod = tt.grace
+			if got := policy.CalculateFee(VehicleTypeCar, tt.entry, tt.exit, 0); got != tt.want {
+				t.Errorf("fee = %.2f, want %.2f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPricingWindowContains(t *testing.T) {
+	overnight := PricingWindow{Days: []time.Weekday{time.Friday}, StartHour: 22, EndHour: 6}
+	friday := time.Date(2024, time.January, 12, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		at   time.Time
+		want bool
+	}{
+		{friday.Add(21 * time.Hour), false},
+		{friday.Add(22 * time.Hour), true},
+		// Saturday's early hours belong to Friday's window
+		{friday.Add(29*time.Hour + 59*time.Minute), true},
+		{friday.Add(30 * time.Hour), false},
+		// Friday's early hours belong to Thursday's, which doesn't apply
+		{friday.Add(2 * time.Hour), false},
+	}
+	for _, tt := range tests {
+		if got := overnight.Contains(tt.at); got != tt.want {
+			t.Errorf("Contains(%s) = %t, want %t", tt.at.Format("Mon 15:04"), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// EventListener receives occupancy changes from a ParkingLot. Callbacks run
+// synchronously after the lot's lock is released, in registration order, so
+// they may call back into the lot. A panicking listener is recovered and
+// logged without affecting the lot or the other listeners.
+type EventListener interface {
+	OnPark(ticket *Ticket)
+	OnUnpark(ticket *Ticket, receipt *FeeBreakdown)
+	OnFull(level int)
+	OnHandicapViolation(ticket *Ticket) // a vehicle without a permit took a handicap spot
+}
+
+// EventListenerFuncs adapts a set of optional functions to EventListener.
+// Nil fields are skipped.
+type EventListenerFuncs struct {
+	Park              func(ticket *Ticket)
+	Unpark            func(ticket *Ticket, receipt *FeeBreakdown)
+	Full              func(level int)
+	HandicapViolation func(ticket *Ticket)
+}
+
+// OnPark implements EventListener
+func (f EventListenerFuncs) OnPark(ticket *Ticket) {
+	if f.Park != nil {
+		f.Park(ticket)
+	}
+}
+
+// OnUnpark implements EventListener
+func (f EventListenerFuncs) OnUnpark(ticket *Ticket, receipt *FeeBreakdown) {
+	if f.Unpark != nil {
+		f.Unpark(ticket, receipt)
+	}
+}
+
+// OnFull implements EventListener
+func (f EventListenerFuncs) OnFull(level int) {
+	if f.Full != nil {
+		f.Full(level)
+	}
+}
+
+// OnHandicapViolation implements EventListener
+func (f EventListenerFuncs) OnHandicapViolation(ticket *Ticket) {
+	if f.HandicapViolation != nil {
+		f.HandicapViolation(ticket)
+	}
+}
+
+// eventKind identifies which listener callback an event goes to
+type eventKind int
+
+const (
+	eventPark eventKind = iota
+	eventUnpark
+	eventFull
+	eventHandicapViolation
+)
+
+// lotEvent is an occupancy change recorded under the lock and delivered after it
+type lotEvent struct {
+	kind    eventKind
+	ticket  *Ticket
+	receipt *FeeBreakdown
+	level   int
+}
+
+// AddEventListener registers a listener for occupancy events
+func (pl *ParkingLot) AddEventListener(listener EventListener) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.listeners = append(pl.listeners, listener)
+}
+
+// queueEvent records an event for delivery once the lock is released.
+// Must be called with lock held.
+func (pl *ParkingLot) queueEvent(event lotEvent) {
+	pl.pendingEvents = append(pl.pendingEvents, event)
+}
+
+// flushEvents delivers queued events to every listener. Public methods that
+// change occupancy defer it before taking the lock, so it runs after unlocking.
+func (pl *ParkingLot) flushEvents() {
+	pl.mu.Lock()
+	events := pl.pendingEvents
+	pl.pendingEvents = nil
+	listeners := make([]EventListener, len(pl.listeners))
+	copy(listeners, pl.listeners)
+	pl.mu.Unlock()
+
+	for _, event := range events {
+		for _, listener := range listeners {
+			deliver(listener, event)
+		}
+	}
+}
+
+// deliver invokes one listener callback, recovering from panics
+func deliver(listener EventListener, event lotEvent) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("parking lot event listener panicked: %v", r)
+		}
+	}()
+
+	switch event.kind {
+	case eventPark:
+		listener.OnPark(event.ticket)
+	case eventUnpark:
+		listener.OnUnpark(event.ticket, event.receipt)
+	case eventFull:
+		listener.OnFull(event.level)
+	case eventHandicapViolation:
+		listener.OnHandicapViolation(event.ticket)
+	}
+}
+
+// DemoEventListeners demonstrates occupancy callbacks, including a faulty listener
+func DemoEventListeners() {
+	fmt.Println("=== Event Listener Demo ===")
+
+	parkingLot := NewParkingLot("Events Demo", []*ParkingLevel{NewParkingLevel(0, 0, 2, 0, 0, 0)})
+	parkingLot.AddEventListener(EventListenerFuncs{
+		Park: func(ticket *Ticket) {
+			fmt.Printf("  [dashboard] %s parked at Level %d, Spot %d\n", ticket.LicensePlate, ticket.LevelIndex, ticket.SpotID)
+		},
+		Unpark: func(ticket *Ticket, receipt *FeeBreakdown) {
+			fmt.Printf("  [dashboard] %s left, paid $%.2f\n", ticket.LicensePlate, receipt.Total)
+		},
+		Full: func(level int) {
+			fmt.Printf("  [dashboard] Level %d is FULL\n", level)
+		},
+	})
+	parkingLot.AddEventListener(EventListenerFuncs{
+		Park: func(ticket *Ticket) { panic("listener bug") },
+	})
+
+	var tickets []*Ticket
+	for _, license := range []string{"EVT001", "EVT002"} {
+		vehicle, _ := NewVehicle(license, VehicleTypeCar)
+		if ticket, err := parkingLot.ParkVehicle(vehicle); err == nil {
+			tickets = append(tickets, ticket)
+		}
+	}
+	parkingLot.UnparkVehicle(tickets[0])
+
+	fmt.Println(parkingLot)
+}
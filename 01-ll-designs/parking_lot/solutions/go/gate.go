@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// GateKind says whether a gate lets vehicles in or out
+type GateKind int
+
+const (
+	GateEntry GateKind = iota
+	GateExit
+)
+
+func (gk GateKind) String() string {
+	switch gk {
+	case GateEntry:
+		return "Entry"
+	case GateExit:
+		return "Exit"
+	default:
+		return "Unknown"
+	}
+}
+
+// GateSignal is what a gate's capacity sign shows for a vehicle type
+type GateSignal string
+
+const (
+	SignalOpen GateSignal = "OPEN"
+	SignalFull GateSignal = "FULL"
+)
+
+// GateStats counts what a gate has done since it was created
+type GateStats struct {
+	Admitted int64 `json:"admitted"` // vehicles parked through an entry gate
+	Refused  int64 `json:"refused"`  // vehicles turned away at an entry gate
+	Exits    int64 `json:"exits"`    // vehicles checked out through an exit gate
+}
+
+// Gate is an entry or exit barrier in front of a parking lot. Entry gates
+// park vehicles with ParkVehicle and exit gates check them out with
+// UnparkVehicle. Any number of gates may share a lot and run concurrently:
+// the lot's lock decides races, so when several entry gates compete for the
+// last spot exactly one vehicle gets it and the rest are refused.
+type Gate struct {
+	ID       string
+	Kind     GateKind
+	lot      *ParkingLot
+	admitted atomic.Int64
+	refused  atomic.Int64
+	exits    atomic.Int64
+}
+
+// NewEntryGate creates an entry gate for a parking lot
+func NewEntryGate(id string, lot *ParkingLot) *Gate {
+	return &Gate{ID: id, Kind: GateEntry, lot: lot}
+}
+
+// NewExitGate creates an exit gate for a parking lot
+func NewExitGate(id string, lot *ParkingLot) *Gate {
+	return &Gate{ID: id, Kind: GateExit, lot: lot}
+}
+
+// String returns a string representation of the gate
+func (g *Gate) String() string {
+	return fmt.Sprintf("%s gate %s", g.Kind, g.ID)
+}
+
+// Signal returns what the gate's sign shows for a vehicle type: FULL when
+// no compatible spot is free, OPEN otherwise. It is derived from the lot's
+// availability each time, so it never lags behind parks and exits.
+func (g *Gate) Signal(vehicleType VehicleType) GateSignal {
+	if g.lot.CanAccept(vehicleType) {
+		return SignalOpen
+	}
+	return SignalFull
+}
+
+// Admit lets a vehicle in through an entry gate and parks it. A vehicle is
+// refused without touching the lot when the sign shows FULL for its type,
+// and also when another gate takes the last spot between the check and the
+// park. Refusals match ErrNoAvailableSpots with errors.Is.
+func (g *Gate) Admit(vehicle *Vehicle) (*Ticket, error) {
+	if g.Kind != GateEntry {
+		return nil, &ParkingError{Op: "gate", Msg: fmt.Sprintf("%s cannot admit vehicles", g)}
+	}
+	if vehicle == nil {
+		return nil, &ParkingError{Op: "gate", Msg: "vehicle cannot be nil"}
+	}
+
+	// CanAccept judges a typical vehicle of the type, so permit holders and
+	// vehicles with their own spot count go straight to ParkVehicle
+	typical := !vehicle.HandicapEligible && vehicle.RequiredSpots == 0
+	if typical && !g.lot.CanAccept(vehicle.Type) {
+		g.refused.Add(1)
+		return nil, &ParkingError{
+			Op:  "gate",
+			Msg: fmt.Sprintf("%s shows %s for %s", g, SignalFull, vehicle.Type),
+			Err: ErrNoAvailableSpots,
+		}
+	}
+
+	ticket, err := g.lot.ParkVehicle(vehicle)
+	if err != nil {
+		g.refused.Add(1)
+		return nil, err
+	}
+	g.admitted.Add(1)
+	return ticket, nil
+}
+
+// Exit checks a vehicle out through an exit gate and returns its fee
+func (g *Gate) Exit(ticket *Ticket) (*FeeBreakdown, error) {
+	if g.Kind != GateExit {
+		return nil, &ParkingError{Op: "gate", Msg: fmt.Sprintf("%s cannot check vehicles out", g)}
+	}
+
+	receipt, err := g.lot.UnparkVehicle(ticket)
+	if err != nil {
+		return nil, err
+	}
+	g.exits.Add(1)
+	return receipt, nil
+}
+
+// Stats returns the gate's counters
+func (g *Gate) Stats() GateStats {
+	return GateStats{
+		Admitted: g.admitted.Load(),
+		Refused:  g.refused.Load(),
+		Exits:    g.exits.Load(),
+	}
+}
+
+// CanAccept reports whether a vehicle of the given type, without a handicap
+// permit, would find a spot right now. It only takes the read lock and
+// changes nothing, so gates can consult it cheaply, but another vehicle may
+// take the spot before this one parks; ParkVehicle has the final word.
+func (pl *ParkingLot) CanAccept(vehicleType VehicleType) bool {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+
+	vehicle := &Vehicle{Type: vehicleType}
+	count := pl.requiredSpots(vehicle)
+	for _, spotTypes := range pl.spotTypeTiers(vehicle) {
+		for _, level := range pl.Levels {
+			if count == 1 && level.HasAvailableSpot(spotTypes) {
+				return true
+			}
+			if count > 1 && level.HasContiguousSpots(spotTypes, count) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DemoGates races cars through several entry gates for the last free spot,
+// then shows the FULL sign turning a car away until one leaves
+func DemoGates() {
+	fmt.Println("=== Entry/Exit Gates Demo ===")
+
+	parkingLot := NewParkingLot("Gates Demo", []*ParkingLevel{NewParkingLevel(0, 0, 2, 0, 0, 0)})
+	north, south := NewEntryGate("N", parkingLot), NewEntryGate("S", parkingLot)
+	exit := NewExitGate("X", parkingLot)
+
+	first, _ := NewVehicle("EARLY01", VehicleTypeCar)
+	firstTicket, _ := north.Admit(first)
+	fmt.Printf("%s admitted %s, sign shows %s for cars\n", north, first.LicensePlate, north.Signal(VehicleTypeCar))
+
+	// Eight gates, each with a car, released together for the one spot left
+	gates := make([]*Gate, 8)
+	var winners atomic.Int64
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := range gates {
+		gates[i] = NewEntryGate(fmt.Sprintf("G%d", i+1), parkingLot)
+		vehicle, _ := NewVehicle(fmt.Sprintf("RACE%02d", i+1), VehicleTypeCar)
+		wg.Add(1)
+		go func(gate *Gate) {
+			defer wg.Done()
+			<-start
+			if _, err := gate.Admit(vehicle); err == nil {
+				winners.Add(1)
+				fmt.Printf("✓ %s got the last spot at %s\n", vehicle.LicensePlate, gate)
+			}
+		}(gates[i])
+	}
+	close(start)
+	wg.Wait()
+
+	refused := int64(0)
+	for _, gate := range gates {
+		refused += gate.Stats().Refused
+	}
+	fmt.Printf("%d of %d racing cars parked, %d refused\n", winners.Load(), len(gates), refused)
+
+	late, _ := NewVehicle("LATE01", VehicleTypeCar)
+	if _, err := south.Admit(late); err != nil {
+		fmt.Printf("✗ %s: %v\n", late.LicensePlate, err)
+	}
+
+	receipt, _ := exit.Exit(firstTicket)
+	fmt.Printf("%s let %s out (fee %.2f), sign shows %s for cars\n", exit, first.LicensePlate, receipt.Total, south.Signal(VehicleTypeCar))
+	if ticket, err := south.Admit(late); err == nil {
+		fmt.Printf("✓ %s -> Spot %d through %s\n", late.LicensePlate, ticket.SpotID, south)
+	}
+}
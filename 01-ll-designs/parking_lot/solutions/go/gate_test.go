@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestGatesRaceForLastSpot(t *testing.T) {
+	const gates = 16
+	for trial := 0; trial < 20; trial++ {
+		parkingLot := NewParkingLot("Test", []*ParkingLevel{NewParkingLevel(0, 0, 2, 0, 0, 0)})
+		first, _ := NewVehicle("EARLY01", VehicleTypeCar)
+		if _, err := NewEntryGate("N", parkingLot).Admit(first); err != nil {
+			t.Fatal(err)
+		}
+
+		entries := make([]*Gate, gates)
+		errs := make([]error, gates)
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for i := range entries {
+			entries[i] = NewEntryGate(fmt.Sprintf("G%d", i+1), parkingLot)
+			vehicle, _ := NewVehicle(fmt.Sprintf("RACE%02d", i+1), VehicleTypeCar)
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				_, errs[i] = entries[i].Admit(vehicle)
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+
+		winners := 0
+		var admitted, refused int64
+		for i, err := range errs {
+			if err == nil {
+				winners++
+			} else if !errors.Is(err, ErrNoAvailableSpots) {
+				t.Fatalf("gate %s: err = %v, want ErrNoAvailableSpots", entries[i].ID, err)
+			}
+			stats := entries[i].Stats()
+			admitted += stats.Admitted
+			refused += stats.Refused
+		}
+		if winners != 1 || admitted != 1 || refused != gates-1 {
+			t.Fatalf("trial %d: %d winners, %d admitted, %d refused; want 1, 1, %d",
+				trial, winners, admitted, refused, gates-1)
+		}
+		if got := entries[0].Signal(VehicleTypeCar); got != SignalFull {
+			t.Errorf("trial %d: sign shows %s, want %s", trial, got, SignalFull)
+		}
+	}
+}
+
+func TestGateKindChecked(t *testing.T) {
+	parkingLot := NewParkingLot("Test", []*ParkingLevel{NewParkingLevel(0, 0, 1, 0, 0, 0)})
+	entry, exit := NewEntryGate("N", parkingLot), NewExitGate("X", parkingLot)
+	car, _ := NewVehicle("CAR01", VehicleTypeCar)
+
+	if _, err := exit.Admit(car); err == nil {
+		t.Error("exit gate admitted a vehicle")
+	}
+	ticket, err := entry.Admit(car)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := entry.Exit(ticket); err == nil {
+		t.Error("entry gate checked a vehicle out")
+	}
+	if _, err := exit.Exit(ticket); err != nil {
+		t.Fatal(err)
+	}
+	if got := exit.Stats().Exits; got != 1 {
+		t.Errorf("exit gate Exits = %d, want 1", got)
+	}
+	if got := entry.Signal(VehicleTypeCar); got != SignalOpen {
+		t.Errorf("sign shows %s after the car left, want %s", got, SignalOpen)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import "fmt"
+
+// HandicapAccessible reports whether a vehicle type fits a handicap-accessible
+// spot. Buses are too large; everything else fits.
+func HandicapAccessible(vehicleType VehicleType) bool {
+	return vehicleType != VehicleTypeBus
+}
+
+// SpotTypesFor returns the spot types a vehicle may normally use, in order of
+// preference. Handicap spots are set aside for eligible vehicles, which try
+// them first and otherwise park like any other vehicle of their type.
+func SpotTypesFor(vehicle *Vehicle) []SpotType {
+	spotTypes := CompatibleSpotTypes(vehicle.Type)
+	if vehicle.HandicapEligible && HandicapAccessible(vehicle.Type) {
+		spotTypes = append([]SpotType{SpotTypeHandicap}, spotTypes...)
+	}
+	return spotTypes
+}
+
+// SetHandicapFallback controls whether vehicles without a permit may use a
+// free handicap spot once every other compatible spot in the lot is taken
+func (pl *ParkingLot) SetHandicapFallback(enabled bool) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.HandicapFallback = enabled
+}
+
+// spotTypeTiers returns the spot type lists to try for a vehicle, in order.
+// The second tier, when present, is the handicap fallback: it is only tried
+// once the first has nothing free on any level. Must be called with lock held.
+func (pl *ParkingLot) spotTypeTiers(vehicle *Vehicle) [][]SpotType {
+	tiers := [][]SpotType{SpotTypesFor(vehicle)}
+	if pl.HandicapFallback && !vehicle.HandicapEligible && HandicapAccessible(vehicle.Type) {
+		tiers = append(tiers, []SpotType{SpotTypeHandicap})
+	}
+	return tiers
+}
+
+// allocateSpot asks the allocation strategy for a spot, falling back to
+// handicap spots only when the lot is otherwise full (must be called with lock held)
+func (pl *ParkingLot) allocateSpot(vehicle *Vehicle) (*ParkingLevel, int, error) {
+	var err error
+	levels := pl.searchOrder()
+	for _, spotTypes := range pl.spotTypeTiers(vehicle) {
+		var level *ParkingLevel
+		var spotIndex int
+		level, spotIndex, err = pl.AllocationStrategy.Allocate(levels, spotTypes)
+		if err == nil {
+			return level, spotIndex, nil
+		}
+	}
+	return nil, -1, err
+}
+
+// DemoHandicapSpots demonstrates handicap spot eligibility and the fallback
+// for vehicles without a permit once the lot is otherwise full
+func DemoHandicapSpots() {
+	fmt.Println("=== Handicap Spots Demo ===")
+
+	parkingLot := NewParkingLot("Handicap Demo", []*ParkingLevel{NewParkingLevel(0, 0, 1, 0, 0, 3)})
+	parkingLot.AddEventListener(EventListenerFuncs{
+		HandicapViolation: func(ticket *Ticket) {
+			fmt.Printf("  [attendant] %s has no permit but is in handicap spot %d\n", ticket.LicensePlate, ticket.SpotID)
+		},
+	})
+
+	permit, _ := NewVehicle("ACCESS01", VehicleTypeCar)
+	permit.HandicapEligible = true
+	regular, _ := NewVehicle("REGULAR01", VehicleTypeCar)
+	overflow, _ := NewVehicle("REGULAR02", VehicleTypeCar)
+	late, _ := NewVehicle("REGULAR03", VehicleTypeCar)
+
+	for _, vehicle := range []*Vehicle{permit, regular, overflow} {
+		ticket, err := parkingLot.ParkVehicle(vehicle)
+		if err != nil {
+			fmt.Printf("✗ %s: %v\n", vehicle.LicensePlate, err)
+			continue
+		}
+		fmt.Printf("✓ %s -> Spot %d (%s)\n", vehicle.LicensePlate, ticket.SpotID, ticket.SpotType)
+	}
+
+	// With the fallback off, the last handicap spot stays free for permit holders
+	parkingLot.SetHandicapFallback(false)
+	if _, err := parkingLot.ParkVehicle(late); err != nil {
+		fmt.Printf("✗ %s: %v (handicap fallback disabled)\n", late.LicensePlate, err)
+	}
+
+	fmt.Println(parkingLot.GetAvailabilitySummary())
+}
@@ -11,86 +11,243 @@ type ParkingLevel struct {
 	Index      int                    `json:"index"`
 	Spots      []*ParkingSpot         `json:"spots"`
 	FreeSpots  map[SpotType][]int     `json:"-"` // indices of free spots by type
+	Holds      map[int]int            `json:"-"` // spot index -> pending reservations
 }
 
 // NewParkingLevel creates a new parking level
-func NewParkingLevel(index, motorcycleSpots, compactSpots, largeSpots int) *ParkingLevel {
+func NewParkingLevel(index, motorcycleSpots, compactSpots, largeSpots, electricSpots, handicapSpots int) *ParkingLevel {
 	level := &ParkingLevel{
 		Index:     index,
 		Spots:     make([]*ParkingSpot, 0),
 		FreeSpots: make(map[SpotType][]int),
+		Holds:     make(map[int]int),
 	}
 	
-	level.initializeSpots(motorcycleSpots, compactSpots, largeSpots)
+	level.initializeSpots(motorcycleSpots, compactSpots, largeSpots, electricSpots, handicapSpots)
 	return level
 }
 
 // initializeSpots creates all parking spots and populates free spot queues
-func (pl *ParkingLevel) initializeSpots(motorcycleSpots, compactSpots, largeSpots int) {
+func (pl *ParkingLevel) initializeSpots(motorcycleSpots, compactSpots, largeSpots, electricSpots, handicapSpots int) {
 	spotID := 0
 	
 	// Initialize free spot slices
 	pl.FreeSpots[SpotTypeMotorcycle] = make([]int, 0, motorcycleSpots)
 	pl.FreeSpots[SpotTypeCompact] = make([]int, 0, compactSpots)
 	pl.FreeSpots[SpotTypeLarge] = make([]int, 0, largeSpots)
+	pl.FreeSpots[SpotTypeElectric] = make([]int, 0, electricSpots)
+	pl.FreeSpots[SpotTypeHandicap] = make([]int, 0, handicapSpots)
 	
 	// Create motorcycle spots
 	for i := 0; i < motorcycleSpots; i++ {
-		pl.Spots = append(pl.Spots, NewParkingSpot(spotID, SpotTypeMotorcycle))
+		pl.addSpot(spotID, SpotTypeMotorcycle)
 		pl.FreeSpots[SpotTypeMotorcycle] = append(pl.FreeSpots[SpotTypeMotorcycle], len(pl.Spots)-1)
 		spotID++
 	}
 	
 	// Create compact spots
 	for i := 0; i < compactSpots; i++ {
-		pl.Spots = append(pl.Spots, NewParkingSpot(spotID, SpotTypeCompact))
+		pl.addSpot(spotID, SpotTypeCompact)
 		pl.FreeSpots[SpotTypeCompact] = append(pl.FreeSpots[SpotTypeCompact], len(pl.Spots)-1)
 		spotID++
 	}
 	
 	// Create large spots
 	for i := 0; i < largeSpots; i++ {
-		pl.Spots = append(pl.Spots, NewParkingSpot(spotID, SpotTypeLarge))
+		pl.addSpot(spotID, SpotTypeLarge)
 		pl.FreeSpots[SpotTypeLarge] = append(pl.FreeSpots[SpotTypeLarge], len(pl.Spots)-1)
 		spotID++
 	}
+	
+	// Create electric (charging) spots
+	for i := 0; i < electricSpots; i++ {
+		pl.addSpot(spotID, SpotTypeElectric)
+		pl.FreeSpots[SpotTypeElectric] = append(pl.FreeSpots[SpotTypeElectric], len(pl.Spots)-1)
+		spotID++
+	}
+	
+	// Create handicap-accessible spots
+	for i := 0; i < handicapSpots; i++ {
+		pl.addSpot(spotID, SpotTypeHandicap)
+		pl.FreeSpots[SpotTypeHandicap] = append(pl.FreeSpots[SpotTypeHandicap], len(pl.Spots)-1)
+		spotID++
+	}
+}
+
+// addSpot appends a spot, numbering distance from the entrance in creation order
+func (pl *ParkingLevel) addSpot(spotID int, spotType SpotType) {
+	pl.Spots = append(pl.Spots, NewParkingSpot(spotID, spotType, float64(len(pl.Spots))))
 }
 
-// FindAvailableSpot finds and allocates an available spot for the given vehicle type
-func (pl *ParkingLevel) FindAvailableSpot(vehicleType VehicleType) (int, error) {
+// FindAvailableSpot finds and allocates an available spot, trying spot types
+// in the given order of preference (see CompatibleSpotTypes and SpotTypesFor)
+func (pl *ParkingLevel) FindAvailableSpot(spotTypes []SpotType) (int, error) {
 	pl.mu.Lock()
 	defer pl.mu.Unlock()
 	
-	switch vehicleType {
-	case VehicleTypeMotorcycle:
-		// Motorcycles can use any spot type (prefer smaller first)
-		if spotIndex := pl.popFreeSpot(SpotTypeMotorcycle); spotIndex != -1 {
+	for _, spotType := range spotTypes {
+		if spotIndex := pl.popFreeSpot(spotType); spotIndex != -1 {
 			return spotIndex, nil
 		}
-		if spotIndex := pl.popFreeSpot(SpotTypeCompact); spotIndex != -1 {
-			return spotIndex, nil
+	}
+	
+	return -1, ErrNoAvailableSpots
+}
+
+// FindContiguousSpots finds count adjacent free spots of one type, trying
+// spot types in the given order of preference, and takes all of them out of
+// the free queue in one step. Spots are adjacent when their indices on the
+// level are consecutive.
+func (pl *ParkingLevel) FindContiguousSpots(spotTypes []SpotType, count int) ([]int, error) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	
+	for _, spotType := range spotTypes {
+		start := pl.findFreeRun(spotType, count)
+		if start == -1 {
+			continue
 		}
-		if spotIndex := pl.popFreeSpot(SpotTypeLarge); spotIndex != -1 {
-			return spotIndex, nil
+		
+		// Keep the rest of the queue in order
+		freeSpots := pl.FreeSpots[spotType]
+		remaining := make([]int, 0, len(freeSpots)-count)
+		for _, index := range freeSpots {
+			if index < start || index >= start+count {
+				remaining = append(remaining, index)
+			}
 		}
+		pl.FreeSpots[spotType] = remaining
 		
-	case VehicleTypeCar:
-		// Cars can use compact or large spots
-		if spotIndex := pl.popFreeSpot(SpotTypeCompact); spotIndex != -1 {
-			return spotIndex, nil
+		spotIndices := make([]int, count)
+		for i := range spotIndices {
+			spotIndices[i] = start + i
 		}
-		if spotIndex := pl.popFreeSpot(SpotTypeLarge); spotIndex != -1 {
-			return spotIndex, nil
+		return spotIndices, nil
+	}
+	
+	return nil, ErrNoAvailableSpots
+}
+
+// HasContiguousSpots reports whether count adjacent spots of one of the given
+// types are free
+func (pl *ParkingLevel) HasContiguousSpots(spotTypes []SpotType, count int) bool {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+	
+	for _, spotType := range spotTypes {
+		if pl.findFreeRun(spotType, count) != -1 {
+			return true
 		}
-		
-	case VehicleTypeBus:
-		// Buses can only use large spots
-		if spotIndex := pl.popFreeSpot(SpotTypeLarge); spotIndex != -1 {
-			return spotIndex, nil
+	}
+	return false
+}
+
+// findFreeRun returns the lowest index starting count consecutive free spots
+// of the given type, or -1 if there is none. Must be called with lock held.
+func (pl *ParkingLevel) findFreeRun(spotType SpotType, count int) int {
+	free := make(map[int]bool, len(pl.FreeSpots[spotType]))
+	for _, index := range pl.FreeSpots[spotType] {
+		free[index] = true
+	}
+	
+	run := 0
+	for index := range pl.Spots {
+		if !free[index] {
+			run = 0
+			continue
+		}
+		run++
+		if run == count {
+			return index - count + 1
+		}
+	}
+	return -1
+}
+
+// HasAvailableSpot reports whether any spot of the given types is free
+func (pl *ParkingLevel) HasAvailableSpot(spotTypes []SpotType) bool {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+	
+	for _, spotType := range spotTypes {
+		if len(pl.FreeSpots[spotType]) > 0 {
+			return true
 		}
 	}
+	return false
+}
+
+// GetFreeSpotIndices returns a copy of the free spot indices of the given type
+func (pl *ParkingLevel) GetFreeSpotIndices(spotType SpotType) []int {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
 	
-	return -1, ErrNoAvailableSpots
+	indices := make([]int, len(pl.FreeSpots[spotType]))
+	copy(indices, pl.FreeSpots[spotType])
+	return indices
+}
+
+// ClaimSpot takes a specific free spot out of its free queue
+func (pl *ParkingLevel) ClaimSpot(spotIndex int) error {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	
+	if spotIndex < 0 || spotIndex >= len(pl.Spots) {
+		return &ParkingError{
+			Op:  "claim_spot",
+			Msg: fmt.Sprintf("invalid spot index: %d", spotIndex),
+		}
+	}
+	
+	_, spotType := pl.Spots[spotIndex].GetInfo()
+	freeSpots := pl.FreeSpots[spotType]
+	for i, index := range freeSpots {
+		if index == spotIndex {
+			pl.FreeSpots[spotType] = append(freeSpots[:i:i], freeSpots[i+1:]...)
+			return nil
+		}
+	}
+	
+	return &ParkingError{
+		Op:  "claim_spot",
+		Msg: fmt.Sprintf("spot index %d is not free", spotIndex),
+	}
+}
+
+// unclaimSpot puts a spot taken out of its free queue, but never occupied,
+// back in the queue unless a reservation is holding it
+func (pl *ParkingLevel) unclaimSpot(spotIndex int) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	
+	if spotIndex < 0 || spotIndex >= len(pl.Spots) || pl.Holds[spotIndex] > 0 {
+		return
+	}
+	spot := pl.Spots[spotIndex]
+	if isOccupied, _ := spot.GetStatus(); !isOccupied {
+		_, spotType := spot.GetInfo()
+		pl.FreeSpots[spotType] = append(pl.FreeSpots[spotType], spotIndex)
+	}
+}
+
+// CompatibleSpotTypes returns the spot types a vehicle can use, in order of preference
+func CompatibleSpotTypes(vehicleType VehicleType) []SpotType {
+	switch vehicleType {
+	case VehicleTypeMotorcycle:
+		// Motorcycles can use any spot type (prefer smaller first)
+		return []SpotType{SpotTypeMotorcycle, SpotTypeCompact, SpotTypeLarge}
+	case VehicleTypeCar:
+		// Cars can use compact or large spots
+		return []SpotType{SpotTypeCompact, SpotTypeLarge}
+	case VehicleTypeBus:
+		// Buses can only use large spots
+		return []SpotType{SpotTypeLarge}
+	case VehicleTypeElectric:
+		// EVs prefer charging spots and fall back to large spots
+		return []SpotType{SpotTypeElectric, SpotTypeLarge}
+	default:
+		return nil
+	}
 }
 
 // ReleaseSpot releases a spot and adds it back to the appropriate free queue
@@ -110,10 +267,56 @@ func (pl *ParkingLevel) ReleaseSpot(spotIndex int) error {
 		return err
 	}
 	
-	// Add back to appropriate free queue
-	_, spotType := spot.GetInfo()
-	pl.FreeSpots[spotType] = append(pl.FreeSpots[spotType], spotIndex)
+	// Add back to appropriate free queue unless a reservation is holding it
+	if pl.Holds[spotIndex] == 0 {
+		_, spotType := spot.GetInfo()
+		pl.FreeSpots[spotType] = append(pl.FreeSpots[spotType], spotIndex)
+	}
+	
+	return nil
+}
+
+// HoldSpot records a pending reservation on a spot already taken out of the
+// free queues, so it is not returned to walk-ups when vacated
+func (pl *ParkingLevel) HoldSpot(spotIndex int) error {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	
+	if spotIndex < 0 || spotIndex >= len(pl.Spots) {
+		return &ParkingError{
+			Op:  "hold_spot",
+			Msg: fmt.Sprintf("invalid spot index: %d", spotIndex),
+		}
+	}
+	
+	pl.Holds[spotIndex]++
+	return nil
+}
+
+// ReleaseHold drops one pending reservation from a spot. Once no reservations
+// remain and the spot is empty, it goes back to the free queue.
+func (pl *ParkingLevel) ReleaseHold(spotIndex int) error {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	
+	if pl.Holds[spotIndex] == 0 {
+		return &ParkingError{
+			Op:  "release_hold",
+			Msg: fmt.Sprintf("spot index %d has no reservation", spotIndex),
+		}
+	}
+	
+	pl.Holds[spotIndex]--
+	if pl.Holds[spotIndex] > 0 {
+		return nil
+	}
+	delete(pl.Holds, spotIndex)
 	
+	spot := pl.Spots[spotIndex]
+	if isOccupied, _ := spot.GetStatus(); !isOccupied {
+		_, spotType := spot.GetInfo()
+		pl.FreeSpots[spotType] = append(pl.FreeSpots[spotType], spotIndex)
+	}
 	return nil
 }
 
@@ -147,13 +350,35 @@ func (pl *ParkingLevel) FindSpotIndexByID(spotID int) int {
 }
 
 // GetAvailability returns current availability count for each spot type
-func (pl *ParkingLevel) GetAvailability() (motorcycle, compact, large int) {
+func (pl *ParkingLevel) GetAvailability() (motorcycle, compact, large, electric, handicap int) {
 	pl.mu.RLock()
 	defer pl.mu.RUnlock()
 	
 	return len(pl.FreeSpots[SpotTypeMotorcycle]),
 		   len(pl.FreeSpots[SpotTypeCompact]),
-		   len(pl.FreeSpots[SpotTypeLarge])
+		   len(pl.FreeSpots[SpotTypeLarge]),
+		   len(pl.FreeSpots[SpotTypeElectric]),
+		   len(pl.FreeSpots[SpotTypeHandicap])
+}
+
+// IsFull reports whether no spot of any type is free
+func (pl *ParkingLevel) IsFull() bool {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+	
+	for _, freeSpots := range pl.FreeSpots {
+		if len(freeSpots) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// GetReservedSpots returns number of spots held by pending reservations
+func (pl *ParkingLevel) GetReservedSpots() int {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+	return len(pl.Holds)
 }
 
 // GetTotalSpots returns total number of spots in this level
@@ -191,10 +416,11 @@ func (pl *ParkingLevel) popFreeSpot(spotType SpotType) int {
 }
 
 func (pl *ParkingLevel) String() string {
-	motorcycle, compact, large := pl.GetAvailability()
+	motorcycle, compact, large, electric, handicap := pl.GetAvailability()
 	occupied := pl.GetOccupiedSpots()
+	reserved := pl.GetReservedSpots()
 	total := pl.GetTotalSpots()
 	
-	return fmt.Sprintf("Level %d: %d/%d/%d available (motorcycle/compact/large), %d/%d occupied",
-		pl.Index, motorcycle, compact, large, occupied, total)
+	return fmt.Sprintf("Level %d: %d/%d/%d/%d/%d available (motorcycle/compact/large/electric/handicap), %d/%d occupied, %d reserved",
+		pl.Index, motorcycle, compact, large, electric, handicap, occupied, total, reserved)
 }
\ No newline at end of file
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// LevelSelectionStrategy decides the order in which levels are searched for
+// a free spot. The allocation strategy sees the levels in this order, so
+// BestFitStrategy fills the first level with room, and the other strategies
+// break ties in favour of earlier levels. occupied returns the spots in use
+// on a level by its Index. OrderLevels is called with the lot lock held and
+// must not modify levels.
+type LevelSelectionStrategy interface {
+	OrderLevels(levels []*ParkingLevel, occupied func(levelIndex int) int) []*ParkingLevel
+	Name() string
+}
+
+// FillLowestStrategy searches levels in the order they were given to the
+// lot, so Level 0 fills before any vehicle parks higher up
+type FillLowestStrategy struct{}
+
+// OrderLevels implements LevelSelectionStrategy
+func (FillLowestStrategy) OrderLevels(levels []*ParkingLevel, occupied func(levelIndex int) int) []*ParkingLevel {
+	return levels
+}
+
+// Name implements LevelSelectionStrategy
+func (FillLowestStrategy) Name() string { return "FillLowest" }
+
+// RoundRobinStrategy starts each search one level after the last, so
+// arriving vehicles take turns across levels. A full level is skipped and
+// its turn goes to the next level.
+type RoundRobinStrategy struct {
+	next int
+}
+
+// OrderLevels implements LevelSelectionStrategy
+func (s *RoundRobinStrategy) OrderLevels(levels []*ParkingLevel, occupied func(levelIndex int) int) []*ParkingLevel {
+	if len(levels) == 0 {
+		return levels
+	}
+	start := s.next % len(levels)
+	s.next = start + 1
+
+	ordered := make([]*ParkingLevel, 0, len(levels))
+	ordered = append(ordered, levels[start:]...)
+	return append(ordered, levels[:start]...)
+}
+
+// Name implements LevelSelectionStrategy
+func (*RoundRobinStrategy) Name() string { return "RoundRobin" }
+
+// LeastOccupiedStrategy searches the level with the fewest occupied spots
+// first, lower levels first on ties. It reads the lot's occupancy counters
+// rather than scanning spots, so ordering costs O(levels log levels).
+type LeastOccupiedStrategy struct{}
+
+// OrderLevels implements LevelSelectionStrategy
+func (LeastOccupiedStrategy) OrderLevels(levels []*ParkingLevel, occupied func(levelIndex int) int) []*ParkingLevel {
+	ordered := make([]*ParkingLevel, len(levels))
+	copy(ordered, levels)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return occupied(ordered[i].Index) < occupied(ordered[j].Index)
+	})
+	return ordered
+}
+
+// Name implements LevelSelectionStrategy
+func (LeastOccupiedStrategy) Name() string { return "LeastOccupied" }
+
+// SetLevelSelection sets the order in which levels are searched for spots.
+// A nil strategy restores FillLowestStrategy.
+func (pl *ParkingLot) SetLevelSelection(strategy LevelSelectionStrategy) {
+	if strategy == nil {
+		strategy = FillLowestStrategy{}
+	}
+
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.LevelSelection = strategy
+}
+
+// searchOrder returns the lot's levels in the order the level selection
+// strategy wants them searched (must be called with lock held)
+func (pl *ParkingLot) searchOrder() []*ParkingLevel {
+	return pl.LevelSelection.OrderLevels(pl.Levels, pl.levelOccupied)
+}
+
+// levelOccupied returns the spots in use on a level from the occupancy
+// counters (must be called with lock held)
+func (pl *ParkingLot) levelOccupied(levelIndex int) int {
+	occupied := 0
+	for _, count := range pl.occupancy.occupied[levelIndex] {
+		occupied += count
+	}
+	return occupied
+}
+
+// levelSelectionByName maps a strategy's Name back to a new strategy. An
+// empty name, from a snapshot taken before level selection existed, means
+// FillLowestStrategy.
+func levelSelectionByName(name string) (LevelSelectionStrategy, error) {
+	for _, strategy := range []LevelSelectionStrategy{
+		FillLowestStrategy{},
+		&RoundRobinStrategy{},
+		LeastOccupiedStrategy{},
+	} {
+		if strategy.Name() == name {
+			return strategy, nil
+		}
+	}
+	if name == "" {
+		return FillLowestStrategy{}, nil
+	}
+	return nil, &ParkingError{
+		Op:  "restore",
+		Msg: fmt.Sprintf("unknown level selection strategy %q", name),
+	}
+}
+
+// DemoLevelSelection parks cars under each level selection strategy, lets
+// two leave, and parks three more
+func DemoLevelSelection() {
+	fmt.Println("=== Level Selection Demo ===")
+
+	for _, strategy := range []LevelSelectionStrategy{
+		FillLowestStrategy{},
+		&RoundRobinStrategy{},
+		LeastOccupiedStrategy{},
+	} {
+		parkingLot := NewParkingLot("Levels Demo", []*ParkingLevel{
+			NewParkingLevel(0, 0, 4, 0, 0, 0),
+			NewParkingLevel(1, 0, 4, 0, 0, 0),
+			NewParkingLevel(2, 0, 4, 0, 0, 0),
+		})
+		parkingLot.SetLevelSelection(strategy)
+
+		var tickets []*Ticket
+		park := func(count int) {
+			for i := 0; i < count; i++ {
+				vehicle, _ := NewVehicle(fmt.Sprintf("%s-%d", strategy.Name(), len(tickets)+1), VehicleTypeCar)
+				ticket, err := parkingLot.ParkVehicle(vehicle)
+				if err != nil {
+					fmt.Printf("  ✗ %s: %v\n", vehicle.LicensePlate, err)
+					continue
+				}
+				tickets = append(tickets, ticket)
+			}
+		}
+
+		park(6)
+		parkingLot.UnparkVehicle(tickets[0])
+		parkingLot.UnparkVehicle(tickets[1])
+		park(3)
+
+		perLevel := make([]int, 0, 3)
+		for _, level := range parkingLot.GetLevels() {
+			perLevel = append(perLevel, level.GetOccupiedSpots())
+		}
+		fmt.Printf("%-13s cars per level: %v\n", strategy.Name(), perLevel)
+	}
+}
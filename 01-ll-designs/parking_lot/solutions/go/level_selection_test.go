@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// threeLevelLot returns a lot of three levels with four compact spots each,
+// searched with strategy
+func threeLevelLot(strategy LevelSelectionStrategy) *ParkingLot {
+	parkingLot := NewParkingLot("Test", []*ParkingLevel{
+		NewParkingLevel(0, 0, 4, 0, 0, 0),
+		NewParkingLevel(1, 0, 4, 0, 0, 0),
+		NewParkingLevel(2, 0, 4, 0, 0, 0),
+	})
+	parkingLot.SetLevelSelection(strategy)
+	return parkingLot
+}
+
+// parkCars parks count cars and returns their tickets
+func parkCars(t *testing.T, parkingLot *ParkingLot, prefix string, count int) []*Ticket {
+	t.Helper()
+	tickets := make([]*Ticket, 0, count)
+	for i := 0; i < count; i++ {
+		vehicle, _ := NewVehicle(fmt.Sprintf("%s%d", prefix, i+1), VehicleTypeCar)
+		ticket, err := parkingLot.ParkVehicle(vehicle)
+		if err != nil {
+			t.Fatalf("ParkVehicle(%s): %v", vehicle.LicensePlate, err)
+		}
+		tickets = append(tickets, ticket)
+	}
+	return tickets
+}
+
+// levelsOf returns the level each ticket was issued on
+func levelsOf(tickets []*Ticket) []int {
+	levels := make([]int, len(tickets))
+	for i, ticket := range tickets {
+		levels[i] = ticket.LevelIndex
+	}
+	return levels
+}
+
+// carsPerLevel returns the occupied spots on each level
+func carsPerLevel(parkingLot *ParkingLot) []int {
+	perLevel := make([]int, 0, len(parkingLot.Levels))
+	for _, level := range parkingLot.GetLevels() {
+		perLevel = append(perLevel, level.GetOccupiedSpots())
+	}
+	return perLevel
+}
+
+func TestLevelSelectionSpreadsVehicles(t *testing.T) {
+	tests := []struct {
+		strategy   LevelSelectionStrategy
+		firstSix   string // level of each of the first six cars
+		afterChurn string // cars per level after two leave and three arrive
+	}{
+		{FillLowestStrategy{}, "[0 0 0 0 1 1]", "[4 3 0]"},
+		{&RoundRobinStrategy{}, "[0 1 2 0 1 2]", "[2 2 3]"},
+		{LeastOccupiedStrategy{}, "[0 1 2 0 1 2]", "[3 2 2]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy.Name(), func(t *testing.T) {
+			parkingLot := threeLevelLot(tt.strategy)
+			tickets := parkCars(t, parkingLot, "CAR", 6)
+			if got := fmt.Sprint(levelsOf(tickets)); got != tt.firstSix {
+				t.Errorf("first six cars parked on levels %s, want %s", got, tt.firstSix)
+			}
+
+			for _, ticket := range tickets[:2] {
+				if _, err := parkingLot.UnparkVehicle(ticket); err != nil {
+					t.Fatal(err)
+				}
+			}
+			parkCars(t, parkingLot, "LATE", 3)
+			if got := fmt.Sprint(carsPerLevel(parkingLot)); got != tt.afterChurn {
+				t.Errorf("cars per level %s, want %s", got, tt.afterChurn)
+			}
+		})
+	}
+}
+
+func TestRoundRobinSkipsFullLevel(t *testing.T) {
+	// Level 1 has only motorcycle spots, so it is always full for cars
+	parkingLot := NewParkingLot("Test", []*ParkingLevel{
+		NewParkingLevel(0, 0, 4, 0, 0, 0),
+		NewParkingLevel(1, 4, 0, 0, 0, 0),
+		NewParkingLevel(2, 0, 4, 0, 0, 0),
+	})
+	parkingLot.SetLevelSelection(&RoundRobinStrategy{})
+
+	// Level 1's turn goes to level 2
+	if got := fmt.Sprint(levelsOf(parkCars(t, parkingLot, "CAR", 4))); got != "[0 2 2 0]" {
+		t.Errorf("cars parked on levels %s, want [0 2 2 0]", got)
+	}
+}
+
+func TestLevelSelectionByName(t *testing.T) {
+	for _, name := range []string{"FillLowest", "RoundRobin", "LeastOccupied"} {
+		strategy, err := levelSelectionByName(name)
+		if err != nil || strategy.Name() != name {
+			t.Errorf("levelSelectionByName(%q) = %v, %v", name, strategy, err)
+		}
+	}
+	if strategy, err := levelSelectionByName(""); err != nil || strategy.Name() != "FillLowest" {
+		t.Errorf(`levelSelectionByName("") = %v, %v; want FillLowest`, strategy, err)
+	}
+	if _, err := levelSelectionByName("Random"); err == nil {
+		t.Error(`levelSelectionByName("Random") succeeded`)
+	}
+}
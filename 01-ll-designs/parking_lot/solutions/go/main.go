@@ -1,17 +1,33 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
 	"time"
 )
 
 func main() {
-	fmt.Println("=== Parking Lot System Demo ===\n")
+	addr := flag.String("serve", "", "serve the REST API on this address (e.g. :8080) instead of running the demo")
+	flag.Parse()
+	if *addr != "" {
+		parkingLot := NewParkingLot("CityCenter Mall", []*ParkingLevel{
+			NewParkingLevel(0, 2, 2, 1, 1, 1),
+			NewParkingLevel(1, 1, 2, 1, 0, 0),
+		})
+		if err := NewServer(parkingLot).ListenAndServe(*addr); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	
+	fmt.Print("=== Parking Lot System Demo ===\n\n")
 	
 	// Create a parking lot with 2 levels
 	levels := []*ParkingLevel{
-		NewParkingLevel(0, 2, 2, 1), // Level 0: 2 motorcycle, 2 compact, 1 large
-		NewParkingLevel(1, 1, 2, 1), // Level 1: 1 motorcycle, 2 compact, 1 large
+		NewParkingLevel(0, 2, 2, 1, 1, 1), // Level 0: 2 motorcycle, 2 compact, 1 large, 1 electric, 1 handicap
+		NewParkingLevel(1, 1, 2, 1, 0, 0), // Level 1: 1 motorcycle, 2 compact, 1 large
 	}
 	
 	parkingLot := NewParkingLot("CityCenter Mall", levels)
@@ -34,6 +50,7 @@ func main() {
 		{"BUS777", VehicleTypeBus},
 		{"MH12CD5678", VehicleTypeCar},
 		{"BIKE123", VehicleTypeMotorcycle},
+		{"EV2024", VehicleTypeElectric},
 	}
 	
 	for _, vd := range vehicleData {
@@ -58,6 +75,13 @@ func main() {
 		}
 	}
 	
+	// Charge the EV while it is parked
+	if err := parkingLot.StartCharging("EV2024"); err != nil {
+		fmt.Printf("✗ Failed to start charging: %v\n", err)
+	} else {
+		fmt.Println("⚡ Started charging EV2024")
+	}
+	
 	fmt.Println()
 	fmt.Println("After parking:")
 	fmt.Println(parkingLot)
@@ -83,14 +107,22 @@ func main() {
 	
 	for i := 0; i < unparkCount; i++ {
 		ticket := tickets[i]
-		fee, err := parkingLot.UnparkVehicle(ticket)
+		receipt, err := parkingLot.UnparkVehicle(ticket)
 		if err != nil {
 			fmt.Printf("✗ Failed to unpark %s: %v\n", ticket.LicensePlate, err)
 		} else {
-			fmt.Printf("✓ Unparked %s, Fee: $%.2f\n", ticket.LicensePlate, fee)
+			fmt.Printf("✓ Unparked %s, Fee: $%.2f\n", ticket.LicensePlate, receipt.Total)
 		}
 	}
 	
+	// A driver who lost their ticket is charged from the recorded entry time plus a surcharge
+	receipt, err := parkingLot.UnparkByLicense("MH12CD5678")
+	if err != nil {
+		fmt.Printf("✗ Failed to unpark MH12CD5678 without ticket: %v\n", err)
+	} else {
+		fmt.Printf("✓ Unparked MH12CD5678 without ticket, Receipt:\n%s\n", receipt)
+	}
+	
 	fmt.Println()
 	fmt.Println("Final state:")
 	fmt.Println(parkingLot)
@@ -120,7 +152,7 @@ func main() {
 		SpotID:       0,
 		SpotType:     SpotTypeCompact,
 	}
-	_, err := parkingLot.UnparkVehicle(invalidTicket)
+	_, err = parkingLot.UnparkVehicle(invalidTicket)
 	if err != nil {
 		fmt.Printf("Attempting to unpark with invalid ticket: Correctly rejected - %v\n", err)
 	} else {
@@ -135,6 +167,10 @@ func main() {
 	fmt.Printf("Motorcycle hourly rate: $%.2f\n", pricing.GetHourlyRate(VehicleTypeMotorcycle))
 	fmt.Printf("Car hourly rate: $%.2f\n", pricing.GetHourlyRate(VehicleTypeCar))
 	fmt.Printf("Bus hourly rate: $%.2f\n", pricing.GetHourlyRate(VehicleTypeBus))
+	fmt.Printf("Energy rate: $%.2f/kWh\n", pricing.GetEnergyRate())
+	fmt.Printf("Daily maximum: $%.2f\n", pricing.DailyMaximum)
+	threeDays := pricing.CalculateFee(VehicleTypeCar, time.Now().Add(-74*time.Hour), time.Now(), 0)
+	fmt.Printf("Car parked for 3 days 2 hours: $%.2f\n", threeDays)
 	
 	// Demonstrate premium pricing
 	fmt.Println()
@@ -146,11 +182,58 @@ func main() {
 	entryTime := time.Now().Add(-2 * time.Hour) // 2 hours ago
 	exitTime := time.Now()
 	
-	standardFee := NewStandardPricingPolicy().CalculateFee(VehicleTypeCar, entryTime, exitTime)
-	premiumFee := premiumPricing.CalculateFee(VehicleTypeCar, entryTime, exitTime)
+	standardFee := NewStandardPricingPolicy().CalculateFee(VehicleTypeCar, entryTime, exitTime, 0)
+	premiumFee := premiumPricing.CalculateFee(VehicleTypeCar, entryTime, exitTime, 0)
 	
 	fmt.Printf("Standard 2-hour car parking fee: $%.2f\n", standardFee)
 	fmt.Printf("Premium 2-hour car parking fee: $%.2f\n", premiumFee)
+	fmt.Printf("Premium 2-hour EV receipt with 14.4 kWh charged (energy billed at cost):\n%s\n",
+		premiumPricing.CalculateFeeDetailed(VehicleTypeElectric, entryTime, exitTime, 14.4))
+	
+	fmt.Println()
+	DemoDynamicPricing()
+	
+	fmt.Println()
+	DemoFeeRounding()
+	
+	fmt.Println()
+	DemoReservations()
+	
+	fmt.Println()
+	DemoAllocationStrategies()
+	
+	fmt.Println()
+	DemoLevelSelection()
+	
+	fmt.Println()
+	DemoEventListeners()
+	
+	fmt.Println()
+	DemoSnapshot()
+
+	fmt.Println()
+	DemoWaitlist()
+
+	fmt.Println()
+	DemoOccupancyStats()
+
+	fmt.Println()
+	DemoHandicapSpots()
+
+	fmt.Println()
+	DemoOversizedVehicles()
+
+	fmt.Println()
+	DemoSubscriptions()
+
+	fmt.Println()
+	DemoReconcile()
+
+	fmt.Println()
+	DemoRESTAPI()
+
+	fmt.Println()
+	DemoGates()
 	
 	fmt.Println("\n=== Demo Complete ===")
 }
\ No newline at end of file
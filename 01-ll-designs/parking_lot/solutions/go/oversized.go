@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SetRequiredSpots sets how many adjacent spots vehicles of a type take, for
+// example 2 large spots for a bus. A vehicle's own RequiredSpots overrides it.
+func (pl *ParkingLot) SetRequiredSpots(vehicleType VehicleType, count int) error {
+	if count < 1 {
+		return &ParkingError{Op: "configure", Msg: "required spots must be at least 1"}
+	}
+
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	if count == 1 {
+		delete(pl.RequiredSpots, vehicleType)
+	} else {
+		pl.RequiredSpots[vehicleType] = count
+	}
+	return nil
+}
+
+// requiredSpots returns how many adjacent spots a vehicle takes (must be
+// called with lock held)
+func (pl *ParkingLot) requiredSpots(vehicle *Vehicle) int {
+	if vehicle.RequiredSpots > 0 {
+		return vehicle.RequiredSpots
+	}
+	if count := pl.RequiredSpots[vehicle.Type]; count > 0 {
+		return count
+	}
+	return 1
+}
+
+// allocateSpots takes the spots a vehicle will occupy out of the free queues:
+// one spot chosen by the allocation strategy, or a block of adjacent spots for
+// a vehicle that needs several (must be called with lock held)
+func (pl *ParkingLot) allocateSpots(vehicle *Vehicle) (*ParkingLevel, []int, error) {
+	count := pl.requiredSpots(vehicle)
+	if count == 1 {
+		level, spotIndex, err := pl.allocateSpot(vehicle)
+		if err != nil {
+			return nil, nil, err
+		}
+		return level, []int{spotIndex}, nil
+	}
+	return pl.allocateBlock(vehicle, count)
+}
+
+// allocateBlock finds count adjacent free spots of one type on a single level,
+// searching levels in the level selection order. The allocation strategy only places single spots,
+// since its choices don't extend to blocks. Must be called with lock held.
+func (pl *ParkingLot) allocateBlock(vehicle *Vehicle, count int) (*ParkingLevel, []int, error) {
+	levels := pl.searchOrder()
+	for _, spotTypes := range pl.spotTypeTiers(vehicle) {
+		for _, level := range levels {
+			if spotIndices, err := level.FindContiguousSpots(spotTypes, count); err == nil {
+				return level, spotIndices, nil
+			}
+		}
+	}
+	return nil, nil, &NoContiguousSpotsError{VehicleType: vehicle.Type, Spots: count}
+}
+
+// DemoOversizedVehicles parks buses that take two adjacent large spots,
+// including one turned away because the free large spots aren't adjacent
+func DemoOversizedVehicles() {
+	fmt.Println("=== Oversized Vehicles Demo ===")
+
+	parkingLot := NewParkingLot("Depot", []*ParkingLevel{NewParkingLevel(0, 0, 0, 5, 0, 0)})
+	parkingLot.SetRequiredSpots(VehicleTypeBus, 2)
+
+	park := func(license string, vehicleType VehicleType) *Ticket {
+		vehicle, _ := NewVehicle(license, vehicleType)
+		ticket, err := parkingLot.ParkVehicle(vehicle)
+		var noBlock *NoContiguousSpotsError
+		switch {
+		case errors.As(err, &noBlock):
+			fmt.Printf("✗ %s needs %d adjacent spots: %v\n", license, noBlock.Spots, err)
+		case err != nil:
+			fmt.Printf("✗ %s: %v\n", license, err)
+		default:
+			fmt.Printf("✓ %s\n", ticket)
+		}
+		return ticket
+	}
+	freeLarge := func() []int {
+		return parkingLot.GetLevels()[0].GetFreeSpotIndices(SpotTypeLarge)
+	}
+
+	busTicket := park("BUS001", VehicleTypeBus)
+	carTicket := park("CAR001", VehicleTypeCar)
+	secondCar := park("CAR002", VehicleTypeCar)
+
+	// Two large spots are free, but a car sits between them
+	parkingLot.UnparkVehicle(carTicket)
+	fmt.Printf("Free large spots: %v\n", freeLarge())
+	park("BUS002", VehicleTypeBus)
+
+	parkingLot.UnparkVehicle(secondCar)
+	park("BUS002", VehicleTypeBus)
+
+	// Unparking a bus frees both of its spots
+	parkingLot.UnparkVehicle(busTicket)
+	fmt.Printf("After %s leaves, free large spots: %v\n", busTicket.LicensePlate, freeLarge())
+}
@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+// parkBusOverGhost parks a two-spot bus on a level of three large spots
+// where the spot at ghost is occupied but still listed as free, so
+// occupying the block fails partway
+func parkBusOverGhost(t *testing.T, ghost int) *ParkingLevel {
+	t.Helper()
+
+	level := NewParkingLevel(0, 0, 0, 3, 0, 0)
+	parkingLot := NewParkingLot("Test", []*ParkingLevel{level})
+	if err := parkingLot.SetRequiredSpots(VehicleTypeBus, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := level.Spots[ghost].Occupy("GHOST"); err != nil {
+		t.Fatal(err)
+	}
+
+	bus, _ := NewVehicle("BUS01", VehicleTypeBus)
+	if _, err := parkingLot.ParkVehicle(bus); err == nil {
+		t.Fatal("ParkVehicle succeeded over an occupied spot")
+	}
+	if parkingLot.IsVehicleParked("BUS01") {
+		t.Fatal("bus has a ticket after a failed park")
+	}
+	return level
+}
+
+func TestAssignSpotReleasesOccupiedSpotsOnFailure(t *testing.T) {
+	level := parkBusOverGhost(t, 1)
+
+	if occupied, _ := level.Spots[0].GetStatus(); occupied {
+		t.Error("spot 0 was left occupied by the failed park")
+	}
+	for _, index := range []int{0, 2} {
+		if !containsIndex(level.GetFreeSpotIndices(SpotTypeLarge), index) {
+			t.Errorf("spot %d is missing from the free queue", index)
+		}
+	}
+}
+
+func TestAssignSpotReturnsUnoccupiedSpotsOnFailure(t *testing.T) {
+	level := parkBusOverGhost(t, 0)
+
+	free := level.GetFreeSpotIndices(SpotTypeLarge)
+	for _, index := range []int{1, 2} {
+		if !containsIndex(free, index) {
+			t.Errorf("spot %d is missing from the free queue", index)
+		}
+	}
+	if occupied, _ := level.Spots[1].GetStatus(); occupied {
+		t.Error("spot 1 was left occupied by the failed park")
+	}
+}
+
+func containsIndex(indices []int, index int) bool {
+	for _, i := range indices {
+		if i == index {
+			return true
+		}
+	}
+	return false
+}
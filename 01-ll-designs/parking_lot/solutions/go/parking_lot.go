@@ -7,24 +7,49 @@ import (
 	"time"
 )
 
+// DefaultLostTicketSurcharge is added to the fee when a vehicle leaves without its ticket
+const DefaultLostTicketSurcharge = 25.0
+
 // ParkingLot represents the main parking lot management system
 type ParkingLot struct {
-	mu            sync.RWMutex
-	Name          string                    `json:"name"`
-	Levels        []*ParkingLevel           `json:"levels"`
-	PricingPolicy PricingPolicy             `json:"-"`
-	ActiveTickets map[string]*Ticket        `json:"active_tickets"`
-	SpotToLicense map[string]string         `json:"-"` // "level-spotId" -> licensePlate
+	mu                  sync.RWMutex
+	Name                string                  `json:"name"`
+	Levels              []*ParkingLevel         `json:"levels"`
+	PricingPolicy       PricingPolicy           `json:"-"`
+	AllocationStrategy  SpotAllocationStrategy  `json:"-"`
+	LevelSelection      LevelSelectionStrategy  `json:"-"` // order levels are searched in
+	ActiveTickets       map[string]*Ticket      `json:"active_tickets"`
+	SpotToLicense       map[string]string       `json:"-"` // "level-spotId" -> licensePlate
+	Reservations        map[string]*Reservation `json:"reservations"`
+	GracePeriod         time.Duration           `json:"grace_period"` // how late a reserved vehicle may arrive
+	LostTicketSurcharge float64                 `json:"lost_ticket_surcharge"`
+	HandicapFallback    bool                    `json:"handicap_fallback"` // let other vehicles use handicap spots when the lot is otherwise full
+	RequiredSpots       map[VehicleType]int     `json:"required_spots"`    // adjacent spots each vehicle type needs, when more than one
+	nextReservation     int
+	waitlists           map[VehicleType][]*WaitTicket
+	nextWait            int
+	occupancy           *occupancyCounters
+	listeners           []EventListener
+	pendingEvents       []lotEvent
 }
 
 // NewParkingLot creates a new parking lot
 func NewParkingLot(name string, levels []*ParkingLevel) *ParkingLot {
 	return &ParkingLot{
-		Name:          strings.TrimSpace(name),
-		Levels:        levels,
-		PricingPolicy: NewStandardPricingPolicy(),
-		ActiveTickets: make(map[string]*Ticket),
-		SpotToLicense: make(map[string]string),
+		Name:                strings.TrimSpace(name),
+		Levels:              levels,
+		PricingPolicy:       NewStandardPricingPolicy(),
+		AllocationStrategy:  BestFitStrategy{},
+		LevelSelection:      FillLowestStrategy{},
+		ActiveTickets:       make(map[string]*Ticket),
+		SpotToLicense:       make(map[string]string),
+		Reservations:        make(map[string]*Reservation),
+		GracePeriod:         DefaultReservationGracePeriod,
+		LostTicketSurcharge: DefaultLostTicketSurcharge,
+		HandicapFallback:    true,
+		RequiredSpots:       make(map[VehicleType]int),
+		waitlists:           make(map[VehicleType][]*WaitTicket),
+		occupancy:           newOccupancyCounters(levels),
 	}
 }
 
@@ -35,12 +60,22 @@ func (pl *ParkingLot) SetPricingPolicy(policy PricingPolicy) {
 	pl.PricingPolicy = policy
 }
 
+// SetAllocationStrategy sets the policy used to choose spots for arriving vehicles
+func (pl *ParkingLot) SetAllocationStrategy(strategy SpotAllocationStrategy) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.AllocationStrategy = strategy
+}
+
 // ParkVehicle parks a vehicle and returns a ticket if successful
 func (pl *ParkingLot) ParkVehicle(vehicle *Vehicle) (*Ticket, error) {
 	if vehicle == nil {
 		return nil, &ParkingError{Op: "park", Msg: "vehicle cannot be nil"}
 	}
 	
+	// Listeners run after the lock is released (deferred calls run in reverse order)
+	defer pl.flushEvents()
+	
 	pl.mu.Lock()
 	defer pl.mu.Unlock()
 	
@@ -48,49 +83,85 @@ func (pl *ParkingLot) ParkVehicle(vehicle *Vehicle) (*Ticket, error) {
 	
 	// Check if vehicle is already parked
 	if _, exists := pl.ActiveTickets[licensePlate]; exists {
+		return nil, &AlreadyParkedError{Op: "park", LicensePlate: licensePlate}
+	}
+	if pl.findWaiter(licensePlate) != nil {
 		return nil, &ParkingError{
 			Op:  "park",
-			Msg: fmt.Sprintf("vehicle %s is already parked", licensePlate),
+			Msg: fmt.Sprintf("vehicle %s is on the waitlist", licensePlate),
 		}
 	}
 	
-	// Try to find a spot across all levels
-	for _, level := range pl.Levels {
-		spotIndex, err := level.FindAvailableSpot(vehicle.Type)
-		if err != nil {
-			continue // Try next level
-		}
-		
-		// Get the spot and occupy it
+	return pl.assignSpot(vehicle)
+}
+
+// assignSpot allocates a spot for vehicle, occupies it, and issues a ticket
+// (must be called with lock held)
+func (pl *ParkingLot) assignSpot(vehicle *Vehicle) (*Ticket, error) {
+	licensePlate := vehicle.LicensePlate
+	
+	// Pick a spot, or a block of adjacent spots, across all levels
+	level, spotIndices, err := pl.allocateSpots(vehicle)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Get the spots and occupy them. If one fails partway through a block,
+	// give back every spot taken so far so no capacity leaks.
+	spots := make([]*ParkingSpot, 0, len(spotIndices))
+	for i, spotIndex := range spotIndices {
 		spot, err := level.GetSpot(spotIndex)
+		if err == nil {
+			err = spot.Occupy(licensePlate)
+		}
 		if err != nil {
-			continue // This shouldn't happen, but handle gracefully
+			for _, occupied := range spotIndices[:i] {
+				level.ReleaseSpot(occupied)
+			}
+			for _, claimed := range spotIndices[i+1:] {
+				level.unclaimSpot(claimed)
+			}
+			return nil, err
 		}
-		
-		if err := spot.Occupy(licensePlate); err != nil {
-			continue // Spot was taken by another goroutine, try next
+		spots = append(spots, spot)
+	}
+	
+	// Create ticket
+	spotID, spotType := spots[0].GetInfo()
+	ticket := NewTicket(licensePlate, vehicle.Type, level.Index, spotID, spotType)
+	if len(spots) > 1 {
+		for _, spot := range spots {
+			id, _ := spot.GetInfo()
+			ticket.SpotIDs = append(ticket.SpotIDs, id)
 		}
-		
-		// Create ticket
-		spotID, spotType := spot.GetInfo()
-		ticket := NewTicket(licensePlate, vehicle.Type, level.Index, spotID, spotType)
-		
-		// Update tracking maps
-		pl.ActiveTickets[licensePlate] = ticket
-		pl.SpotToLicense[pl.getSpotKey(level.Index, spotID)] = licensePlate
-		
-		return ticket, nil
 	}
 	
-	return nil, ErrNoAvailableSpots
+	// Update tracking maps
+	pl.ActiveTickets[licensePlate] = ticket
+	for _, id := range ticket.AllSpotIDs() {
+		pl.SpotToLicense[pl.getSpotKey(level.Index, id)] = licensePlate
+	}
+	pl.recordEntry(ticket)
+	
+	pl.queueEvent(lotEvent{kind: eventPark, ticket: ticket})
+	if spotType == SpotTypeHandicap && !vehicle.HandicapEligible {
+		pl.queueEvent(lotEvent{kind: eventHandicapViolation, ticket: ticket})
+	}
+	if level.IsFull() {
+		pl.queueEvent(lotEvent{kind: eventFull, level: level.Index})
+	}
+	
+	return ticket, nil
 }
 
-// UnparkVehicle unparks a vehicle and returns the fee charged
-func (pl *ParkingLot) UnparkVehicle(ticket *Ticket) (float64, error) {
+// UnparkVehicle unparks a vehicle and returns an itemized breakdown of the fee charged
+func (pl *ParkingLot) UnparkVehicle(ticket *Ticket) (*FeeBreakdown, error) {
 	if ticket == nil {
-		return 0, &ParkingError{Op: "unpark", Msg: "ticket cannot be nil"}
+		return nil, &ParkingError{Op: "unpark", Msg: "ticket cannot be nil"}
 	}
 	
+	defer pl.flushEvents()
+	
 	pl.mu.Lock()
 	defer pl.mu.Unlock()
 	
@@ -99,64 +170,201 @@ func (pl *ParkingLot) UnparkVehicle(ticket *Ticket) (float64, error) {
 	// Verify ticket is valid
 	storedTicket, exists := pl.ActiveTickets[licensePlate]
 	if !exists {
-		return 0, &ParkingError{
-			Op:  "unpark",
-			Msg: fmt.Sprintf("ticket for %s not found in active tickets", licensePlate),
+		return nil, &InvalidTicketError{
+			LicensePlate: licensePlate,
+			Msg:          fmt.Sprintf("ticket for %s not found in active tickets", licensePlate),
 		}
 	}
 	
 	if storedTicket.ID != ticket.ID {
-		return 0, &ParkingError{
-			Op:  "unpark",
-			Msg: fmt.Sprintf("ticket mismatch for %s", licensePlate),
+		return nil, &InvalidTicketError{
+			LicensePlate: licensePlate,
+			Msg:          fmt.Sprintf("ticket mismatch for %s", licensePlate),
 		}
 	}
 	
-	// Find the level and spot
-	level := pl.findLevel(ticket.LevelIndex)
-	if level == nil {
-		return 0, &ParkingError{
+	breakdown, err := pl.checkout(storedTicket)
+	if err != nil {
+		return nil, err
+	}
+	
+	pl.queueEvent(lotEvent{kind: eventUnpark, ticket: storedTicket, receipt: breakdown})
+	pl.serveWaitlist()
+	return breakdown, nil
+}
+
+// UnparkByLicense unparks a vehicle whose ticket was lost. The active ticket is
+// found by license plate, cross-checked against the spot it occupies, and the
+// fee is charged from the original entry time plus the lost-ticket surcharge.
+func (pl *ParkingLot) UnparkByLicense(licensePlate string) (*FeeBreakdown, error) {
+	licensePlate = strings.TrimSpace(strings.ToUpper(licensePlate))
+	
+	defer pl.flushEvents()
+	
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	
+	ticket, exists := pl.ActiveTickets[licensePlate]
+	if !exists {
+		return nil, &ParkingError{
 			Op:  "unpark",
-			Msg: fmt.Sprintf("level %d not found", ticket.LevelIndex),
+			Msg: fmt.Sprintf("vehicle %s is not parked", licensePlate),
 		}
 	}
-	
-	spotIndex := level.FindSpotIndexByID(ticket.SpotID)
-	if spotIndex == -1 {
-		return 0, &ParkingError{
+	if pl.SpotToLicense[pl.getSpotKey(ticket.LevelIndex, ticket.SpotID)] != licensePlate {
+		return nil, &ParkingError{
 			Op:  "unpark",
-			Msg: fmt.Sprintf("spot %d not found in level %d", ticket.SpotID, ticket.LevelIndex),
+			Msg: fmt.Sprintf("spot record mismatch for %s", licensePlate),
 		}
 	}
 	
-	spot, err := level.GetSpot(spotIndex)
+	breakdown, err := pl.checkout(ticket)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	if pl.LostTicketSurcharge > 0 {
+		breakdown.AddSurcharge("Lost ticket", pl.LostTicketSurcharge)
 	}
 	
-	// Verify spot occupancy
-	isOccupied, currentLicense := spot.GetStatus()
-	if !isOccupied || currentLicense != licensePlate {
-		return 0, &ParkingError{
+	pl.queueEvent(lotEvent{kind: eventUnpark, ticket: ticket, receipt: breakdown})
+	pl.serveWaitlist()
+	return breakdown, nil
+}
+
+// SetLostTicketSurcharge sets the flat charge added when a vehicle leaves without its ticket
+func (pl *ParkingLot) SetLostTicketSurcharge(surcharge float64) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.LostTicketSurcharge = surcharge
+}
+
+// checkout frees the spots held by a validated ticket and returns the fee
+// (must be called with lock held)
+func (pl *ParkingLot) checkout(ticket *Ticket) (*FeeBreakdown, error) {
+	licensePlate := ticket.LicensePlate
+	
+	// Find the level and spots
+	level := pl.findLevel(ticket.LevelIndex)
+	if level == nil {
+		return nil, &ParkingError{
 			Op:  "unpark",
-			Msg: fmt.Sprintf("spot occupancy mismatch for %s", licensePlate),
+			Msg: fmt.Sprintf("level %d not found", ticket.LevelIndex),
 		}
 	}
 	
-	// Release the spot
-	if err := level.ReleaseSpot(spotIndex); err != nil {
-		return 0, err
+	// Verify every spot before releasing any, so a mismatch leaves the
+	// vehicle fully parked
+	spotIndices := make([]int, 0, len(ticket.AllSpotIDs()))
+	for _, spotID := range ticket.AllSpotIDs() {
+		spotIndex := level.FindSpotIndexByID(spotID)
+		if spotIndex == -1 {
+			return nil, &ParkingError{
+				Op:  "unpark",
+				Msg: fmt.Sprintf("spot %d not found in level %d", spotID, ticket.LevelIndex),
+			}
+		}
+		
+		spot, err := level.GetSpot(spotIndex)
+		if err != nil {
+			return nil, err
+		}
+		
+		// Verify spot occupancy
+		isOccupied, currentLicense := spot.GetStatus()
+		if !isOccupied || currentLicense != licensePlate {
+			return nil, &ParkingError{
+				Op:  "unpark",
+				Msg: fmt.Sprintf("spot occupancy mismatch for %s", licensePlate),
+			}
+		}
+		spotIndices = append(spotIndices, spotIndex)
+	}
+	
+	energyKWh := 0.0
+	for _, spotIndex := range spotIndices {
+		spot, _ := level.GetSpot(spotIndex)
+		
+		// End any charging session before the spot is vacated
+		if spot.IsChargingActive() {
+			if _, err := spot.StopCharging(); err != nil {
+				return nil, err
+			}
+		}
+		energyKWh += spot.GetEnergyDelivered()
+		
+		// Release the spot
+		if err := level.ReleaseSpot(spotIndex); err != nil {
+			return nil, err
+		}
 	}
 	
 	// Calculate fee
 	exitTime := time.Now()
-	fee := pl.PricingPolicy.CalculateFee(ticket.VehicleType, ticket.EntryTime, exitTime)
+	breakdown := pl.calculateFee(ticket, exitTime, energyKWh)
 	
 	// Clean up tracking maps
 	delete(pl.ActiveTickets, licensePlate)
-	delete(pl.SpotToLicense, pl.getSpotKey(ticket.LevelIndex, ticket.SpotID))
+	for _, spotID := range ticket.AllSpotIDs() {
+		delete(pl.SpotToLicense, pl.getSpotKey(ticket.LevelIndex, spotID))
+	}
+	pl.recordExit(ticket, exitTime)
 	
-	return fee, nil
+	return breakdown, nil
+}
+
+// FindNearestSpot returns the level index and spot index of the closest free
+// spot that fits the vehicle type, without allocating it. It returns a
+// *NoSpotError when nothing fits.
+func (pl *ParkingLot) FindNearestSpot(vehicleType VehicleType) (level, spotIndex int, err error) {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+	
+	nearest, spotIndex := nearestFreeSpot(pl.Levels, CompatibleSpotTypes(vehicleType))
+	if nearest == nil {
+		return -1, -1, &NoSpotError{VehicleType: vehicleType}
+	}
+	return nearest.Index, spotIndex, nil
+}
+
+// StartCharging starts a charging session for a vehicle parked in an electric spot
+func (pl *ParkingLot) StartCharging(licensePlate string) error {
+	spot, err := pl.spotForVehicle(licensePlate)
+	if err != nil {
+		return err
+	}
+	return spot.StartCharging()
+}
+
+// StopCharging stops the charging session for a parked vehicle and returns the kWh delivered
+func (pl *ParkingLot) StopCharging(licensePlate string) (float64, error) {
+	spot, err := pl.spotForVehicle(licensePlate)
+	if err != nil {
+		return 0, err
+	}
+	return spot.StopCharging()
+}
+
+// spotForVehicle returns the spot a parked vehicle occupies
+func (pl *ParkingLot) spotForVehicle(licensePlate string) (*ParkingSpot, error) {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+	
+	ticket, exists := pl.ActiveTickets[licensePlate]
+	if !exists {
+		return nil, &ParkingError{
+			Op:  "charge",
+			Msg: fmt.Sprintf("vehicle %s is not parked", licensePlate),
+		}
+	}
+	
+	level := pl.findLevel(ticket.LevelIndex)
+	if level == nil {
+		return nil, &ParkingError{
+			Op:  "charge",
+			Msg: fmt.Sprintf("level %d not found", ticket.LevelIndex),
+		}
+	}
+	return level.GetSpot(level.FindSpotIndexByID(ticket.SpotID))
 }
 
 // GetAvailabilitySummary returns a formatted string with availability information
@@ -167,18 +375,20 @@ func (pl *ParkingLot) GetAvailabilitySummary() string {
 	var summary strings.Builder
 	summary.WriteString(fmt.Sprintf("Parking Lot: %s\n", pl.Name))
 	
-	totalMotorcycle, totalCompact, totalLarge := 0, 0, 0
+	totalMotorcycle, totalCompact, totalLarge, totalElectric, totalHandicap := 0, 0, 0, 0, 0
 	
 	for _, level := range pl.Levels {
-		motorcycle, compact, large := level.GetAvailability()
+		motorcycle, compact, large, electric, handicap := level.GetAvailability()
 		totalMotorcycle += motorcycle
 		totalCompact += compact
 		totalLarge += large
+		totalElectric += electric
+		totalHandicap += handicap
 		summary.WriteString(fmt.Sprintf("%s\n", level.String()))
 	}
 	
-	summary.WriteString(fmt.Sprintf("Total available: %d motorcycle, %d compact, %d large",
-		totalMotorcycle, totalCompact, totalLarge))
+	summary.WriteString(fmt.Sprintf("Total available: %d motorcycle, %d compact, %d large, %d electric, %d handicap",
+		totalMotorcycle, totalCompact, totalLarge, totalElectric, totalHandicap))
 	
 	return summary.String()
 }
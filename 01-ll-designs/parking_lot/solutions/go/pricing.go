@@ -1,21 +1,97 @@
 package main
 
 import (
+	"fmt"
 	"math"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // PricingPolicy interface for different pricing strategies
 type PricingPolicy interface {
-	CalculateFee(vehicleType VehicleType, entryTime, exitTime time.Time) float64
+	CalculateFee(vehicleType VehicleType, entryTime, exitTime time.Time, energyKWh float64) float64
+	CalculateFeeDetailed(vehicleType VehicleType, entryTime, exitTime time.Time, energyKWh float64) *FeeBreakdown
 	GetBaseFee() float64
 	GetHourlyRate(vehicleType VehicleType) float64
+	GetEnergyRate() float64
 }
 
-// StandardPricingPolicy implements the standard pricing strategy
+// Surcharge is a named extra charge on a fee breakdown
+type Surcharge struct {
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+}
+
+// FeeBreakdown itemizes how a parking fee was computed
+type FeeBreakdown struct {
+	VehicleType  VehicleType `json:"vehicle_type"`
+	EntryTime    time.Time   `json:"entry_time"`
+	ExitTime     time.Time   `json:"exit_time"`
+	BaseFee      float64     `json:"base_fee"`
+	HoursCharged float64     `json:"hours_charged"`
+	HourlyCharge float64     `json:"hourly_charge"` // after any daily cap
+	EnergyKWh    float64     `json:"energy_kwh,omitempty"`
+	EnergyCharge float64     `json:"energy_charge,omitempty"`
+	Surcharges   []Surcharge `json:"surcharges,omitempty"`
+	Total        float64     `json:"total"`
+	Currency     string      `json:"currency"` // ISO 4217 code
+}
+
+// AddSurcharge appends a surcharge and updates the total
+func (fb *FeeBreakdown) AddSurcharge(description string, amount float64) {
+	fb.Surcharges = append(fb.Surcharges, Surcharge{Description: description, Amount: amount})
+	fb.updateTotal()
+}
+
+// updateTotal recomputes Total from the line items, to the currency's minor unit
+func (fb *FeeBreakdown) updateTotal() {
+	fb.Total = roundAmount(fb.sum(), lookupCurrency(fb.Currency).MinorUnits, false)
+}
+
+// sum adds up the line items exactly
+func (fb *FeeBreakdown) sum() float64 {
+	total := fb.BaseFee + fb.HourlyCharge + fb.EnergyCharge
+	for _, surcharge := range fb.Surcharges {
+		total += surcharge.Amount
+	}
+	return total
+}
+
+// String formats the breakdown as a printable receipt
+func (fb *FeeBreakdown) String() string {
+	currency := lookupCurrency(fb.Currency)
+	amount := func(value float64) string {
+		return fmt.Sprintf("%s%7.*f", currency.Symbol, currency.MinorUnits, value)
+	}
+
+	var receipt strings.Builder
+	receipt.WriteString(fmt.Sprintf("  %s, %s -> %s\n", fb.VehicleType,
+		fb.EntryTime.Format("Jan 2 15:04:05"), fb.ExitTime.Format("Jan 2 15:04:05")))
+	receipt.WriteString(fmt.Sprintf("  Base fee:                 %s\n", amount(fb.BaseFee)))
+	receipt.WriteString(fmt.Sprintf("  Parking (%3s h charged):  %s\n",
+		strconv.FormatFloat(fb.HoursCharged, 'f', -1, 64), amount(fb.HourlyCharge)))
+	if fb.EnergyKWh > 0 {
+		receipt.WriteString(fmt.Sprintf("  Energy (%5.1f kWh):       %s\n", fb.EnergyKWh, amount(fb.EnergyCharge)))
+	}
+	for _, surcharge := range fb.Surcharges {
+		receipt.WriteString(fmt.Sprintf("  %-25s %s\n", surcharge.Description+":", amount(surcharge.Amount)))
+	}
+	receipt.WriteString(fmt.Sprintf("  Total:                    %s", amount(fb.Total)))
+	return receipt.String()
+}
+
+// StandardPricingPolicy implements the standard pricing strategy. Amounts
+// are in Currency, and the amount due is rounded by Rounding.
 type StandardPricingPolicy struct {
-	BaseFee     float64
-	HourlyRates map[VehicleType]float64
+	BaseFee          float64                 `json:"base_fee"`
+	HourlyRates      map[VehicleType]float64 `json:"hourly_rates"`
+	EnergyRate       float64                 `json:"energy_rate"`       // per kWh delivered at electric spots
+	DailyMaximum     float64                 `json:"daily_maximum"`     // cap on hourly charges per 24 hours, 0 for no cap
+	BillingIncrement time.Duration           `json:"billing_increment"` // parking time is rounded up to this, 0 for an hour
+	GracePeriod      time.Duration           `json:"grace_period"`      // stays this short are free; longer ones are billed for the time after it
+	Rounding         RoundingStrategy        `json:"rounding"`
+	Currency         string                  `json:"currency"` // ISO 4217 code, "" for USD
 }
 
 // NewStandardPricingPolicy creates a new standard pricing policy
@@ -26,22 +102,76 @@ func NewStandardPricingPolicy() *StandardPricingPolicy {
 			VehicleTypeMotorcycle: 0.5,
 			VehicleTypeCar:        1.0,
 			VehicleTypeBus:        3.0,
+			VehicleTypeElectric:   1.0,
 		},
+		EnergyRate:   0.30,
+		DailyMaximum: 20.0,
+		Currency:     DefaultCurrency,
 	}
 }
 
 // CalculateFee calculates the parking fee based on vehicle type and duration
-func (spp *StandardPricingPolicy) CalculateFee(vehicleType VehicleType, entryTime, exitTime time.Time) float64 {
+func (spp *StandardPricingPolicy) CalculateFee(vehicleType VehicleType, entryTime, exitTime time.Time, energyKWh float64) float64 {
+	return spp.CalculateFeeDetailed(vehicleType, entryTime, exitTime, energyKWh).Total
+}
+
+// CalculateFeeDetailed itemizes the parking fee: the base fee, hourly charges
+// capped at DailyMaximum for each 24 hours of the stay, and an energy charge
+// for any kWh delivered at an electric spot. A stay no longer than
+// GracePeriod pays only for energy.
+func (spp *StandardPricingPolicy) CalculateFeeDetailed(vehicleType VehicleType, entryTime, exitTime time.Time, energyKWh float64) *FeeBreakdown {
+	return spp.finalize(spp.calculate(vehicleType, entryTime, exitTime, energyKWh))
+}
+
+// calculate itemizes the fee without rounding, for policies that adjust the
+// standard charges before finalizing them
+func (spp *StandardPricingPolicy) calculate(vehicleType VehicleType, entryTime, exitTime time.Time, energyKWh float64) *FeeBreakdown {
+	breakdown := &FeeBreakdown{
+		VehicleType: vehicleType,
+		EntryTime:   entryTime,
+		ExitTime:    exitTime,
+	}
 	if exitTime.Before(entryTime) {
-		return 0 // Invalid time range
+		return breakdown // Invalid time range
+	}
+	
+	// Energy is billed at cost even during the grace period
+	breakdown.EnergyKWh = energyKWh
+	breakdown.EnergyCharge = spp.EnergyRate * energyKWh
+	
+	stay := exitTime.Sub(entryTime)
+	if spp.GracePeriod > 0 {
+		if stay <= spp.GracePeriod {
+			breakdown.updateTotal()
+			return breakdown // Free, base fee included
+		}
+		stay -= spp.GracePeriod
 	}
 	
-	// Calculate duration in hours (minimum 1 hour)
-	duration := exitTime.Sub(entryTime)
-	durationHours := math.Max(1.0, math.Ceil(duration.Hours()))
+	// Round the billed time up to whole billing increments (minimum one)
+	increment := spp.billingIncrement()
+	increments := math.Max(1.0, math.Ceil(float64(stay)/float64(increment)))
+	durationHours := increments * increment.Hours()
 	
 	hourlyRate := spp.GetHourlyRate(vehicleType)
-	return spp.BaseFee + (hourlyRate * durationHours)
+	breakdown.BaseFee = spp.BaseFee
+	breakdown.HoursCharged = durationHours
+	breakdown.HourlyCharge = spp.capDaily(hourlyRate, durationHours)
+	breakdown.updateTotal()
+	return breakdown
+}
+
+// capDaily returns the hourly charge for a stay, applying the daily maximum to
+// every full day and to the remaining partial day separately
+func (spp *StandardPricingPolicy) capDaily(hourlyRate, hours float64) float64 {
+	if spp.DailyMaximum <= 0 {
+		return hourlyRate * hours
+	}
+	
+	fullDays := math.Floor(hours / 24)
+	remainingHours := hours - fullDays*24
+	return fullDays*math.Min(hourlyRate*24, spp.DailyMaximum) +
+		math.Min(hourlyRate*remainingHours, spp.DailyMaximum)
 }
 
 // GetBaseFee returns the base fee
@@ -57,6 +187,11 @@ func (spp *StandardPricingPolicy) GetHourlyRate(vehicleType VehicleType) float64
 	return 1.0 // Default rate
 }
 
+// GetEnergyRate returns the charge per kWh of electricity
+func (spp *StandardPricingPolicy) GetEnergyRate() float64 {
+	return spp.EnergyRate
+}
+
 // PremiumPricingPolicy implements a premium pricing strategy (example of extensibility)
 type PremiumPricingPolicy struct {
 	*StandardPricingPolicy
@@ -72,7 +207,16 @@ func NewPremiumPricingPolicy(multiplier float64) *PremiumPricingPolicy {
 }
 
 // CalculateFee calculates the premium parking fee
-func (ppp *PremiumPricingPolicy) CalculateFee(vehicleType VehicleType, entryTime, exitTime time.Time) float64 {
-	baseFee := ppp.StandardPricingPolicy.CalculateFee(vehicleType, entryTime, exitTime)
-	return baseFee * ppp.PremiumMultiplier
+func (ppp *PremiumPricingPolicy) CalculateFee(vehicleType VehicleType, entryTime, exitTime time.Time, energyKWh float64) float64 {
+	return ppp.CalculateFeeDetailed(vehicleType, entryTime, exitTime, energyKWh).Total
+}
+
+// CalculateFeeDetailed applies the premium multiplier to the parking charges
+// (base fee and hourly component). Energy is billed at cost. Rounding comes
+// after the multiplier, so the premium is charged on the exact amounts.
+func (ppp *PremiumPricingPolicy) CalculateFeeDetailed(vehicleType VehicleType, entryTime, exitTime time.Time, energyKWh float64) *FeeBreakdown {
+	breakdown := ppp.StandardPricingPolicy.calculate(vehicleType, entryTime, exitTime, energyKWh)
+	breakdown.BaseFee *= ppp.PremiumMultiplier
+	breakdown.HourlyCharge *= ppp.PremiumMultiplier
+	return ppp.finalize(breakdown)
 }
\ No newline at end of file
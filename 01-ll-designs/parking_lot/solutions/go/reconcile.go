@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Reconcile cross-checks every spot against the active tickets and spot
+// records and returns the inconsistencies it finds, without changing
+// anything. An empty result means the lot is consistent.
+func (pl *ParkingLot) Reconcile() []string {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+	return pl.reconcile(false)
+}
+
+// Repair runs the same checks as Reconcile and fixes what it finds: tickets
+// whose spots no longer hold the vehicle are dropped, spots occupied without
+// a ticket are freed, and the spot records, free spot queues, and occupancy
+// counters are rebuilt from the spots. Freed spots go to the waitlist. It
+// returns the inconsistencies found, each noting the repair made. Run it after
+// restoring a snapshot of uncertain provenance.
+func (pl *ParkingLot) Repair() []string {
+	defer pl.flushEvents()
+
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	issues := pl.reconcile(true)
+	if len(issues) > 0 {
+		pl.serveWaitlist()
+	}
+	return issues
+}
+
+// reconcile checks, and with repair set fixes, the lot's bookkeeping against
+// spot occupancy, which is taken as the truth (must be called with lock held)
+func (pl *ParkingLot) reconcile(repair bool) []string {
+	var issues []string
+	report := func(fix, format string, args ...interface{}) {
+		issue := fmt.Sprintf(format, args...)
+		if repair {
+			issue += " (" + fix + ")"
+		}
+		issues = append(issues, issue)
+	}
+
+	plates := make([]string, 0, len(pl.ActiveTickets))
+	for plate := range pl.ActiveTickets {
+		plates = append(plates, plate)
+	}
+	sort.Strings(plates)
+
+	// Tickets must name an existing spot that holds their vehicle
+	ticketed := make(map[string]string) // spot key -> plate, for valid tickets
+	for _, plate := range plates {
+		ticket := pl.ActiveTickets[plate]
+		problem := pl.ticketProblem(plate, ticket)
+		if problem == "" {
+			for _, spotID := range ticket.AllSpotIDs() {
+				ticketed[pl.getSpotKey(ticket.LevelIndex, spotID)] = plate
+			}
+			continue
+		}
+		report("ticket dropped", "ticket %s: %s", plate, problem)
+		if repair {
+			delete(pl.ActiveTickets, plate)
+		}
+	}
+
+	// Occupied spots must be covered by a ticket for their vehicle
+	for _, level := range pl.Levels {
+		for spotIndex, spot := range level.Spots {
+			isOccupied, plate := spot.GetStatus()
+			spotID, _ := spot.GetInfo()
+			if !isOccupied || ticketed[pl.getSpotKey(level.Index, spotID)] == plate {
+				continue
+			}
+			report("spot freed", "level %d spot %d: occupied by %s with no active ticket", level.Index, spotID, plate)
+			if repair {
+				level.ReleaseSpot(spotIndex)
+			}
+		}
+		issues = append(issues, level.reconcileFreeSpots(repair)...)
+	}
+
+	// Spot records must match the tickets
+	records := make(map[string]string)
+	for plate, ticket := range pl.ActiveTickets {
+		for _, spotID := range ticket.AllSpotIDs() {
+			records[pl.getSpotKey(ticket.LevelIndex, spotID)] = plate
+		}
+	}
+	keys := make([]string, 0, len(records)+len(pl.SpotToLicense))
+	for key := range records {
+		keys = append(keys, key)
+	}
+	for key := range pl.SpotToLicense {
+		if _, ok := records[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		want, recorded := records[key], pl.SpotToLicense[key]
+		switch {
+		case want == recorded:
+		case want == "":
+			report("record removed", "spot record %s names %s, which holds no ticket for it", key, recorded)
+		case recorded == "":
+			report("record added", "spot record %s is missing for %s", key, want)
+		default:
+			report("record corrected", "spot record %s names %s instead of %s", key, recorded, want)
+		}
+	}
+	if repair {
+		pl.SpotToLicense = records
+	}
+
+	// Occupancy counters must match the spots
+	counters := pl.occupancy
+	inUse := 0
+	occupied := make(map[int]map[SpotType]int)
+	for _, level := range pl.Levels {
+		occupied[level.Index] = make(map[SpotType]int)
+		for _, spot := range level.Spots {
+			_, spotType := spot.GetInfo()
+			if isOccupied, _ := spot.GetStatus(); isOccupied {
+				occupied[level.Index][spotType]++
+				inUse++
+			}
+		}
+	}
+	if inUse != counters.inUse {
+		report("counters recounted", "occupancy counters show %d spots in use, spots show %d", counters.inUse, inUse)
+	}
+	if repair {
+		counters.occupied, counters.inUse = occupied, inUse
+	}
+
+	return issues
+}
+
+// ticketProblem describes why a ticket is not backed by the spots it names,
+// or returns "" if every spot holds its vehicle (must be called with lock held)
+func (pl *ParkingLot) ticketProblem(plate string, ticket *Ticket) string {
+	if ticket == nil {
+		return "ticket is nil"
+	}
+	if ticket.LicensePlate != plate {
+		return fmt.Sprintf("filed under %s but issued to %s", plate, ticket.LicensePlate)
+	}
+	for _, spotID := range ticket.AllSpotIDs() {
+		level := pl.findLevel(ticket.LevelIndex)
+		if level == nil {
+			return fmt.Sprintf("level %d not found", ticket.LevelIndex)
+		}
+		spotIndex := level.FindSpotIndexByID(spotID)
+		if spotIndex == -1 {
+			return fmt.Sprintf("spot %d not found in level %d", spotID, ticket.LevelIndex)
+		}
+		isOccupied, occupant := level.Spots[spotIndex].GetStatus()
+		switch {
+		case !isOccupied:
+			return fmt.Sprintf("level %d spot %d is empty", ticket.LevelIndex, spotID)
+		case occupant != plate:
+			return fmt.Sprintf("level %d spot %d is occupied by %s", ticket.LevelIndex, spotID, occupant)
+		}
+	}
+	return ""
+}
+
+// reconcileFreeSpots checks that the free queues list exactly the spots that
+// are neither occupied nor held, each once under its own type, and with
+// repair set rebuilds them, keeping the order of the entries that were right
+func (pl *ParkingLevel) reconcileFreeSpots(repair bool) []string {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	var issues []string
+	report := func(format string, args ...interface{}) {
+		issue := fmt.Sprintf(format, args...)
+		if repair {
+			issue += " (free queue rebuilt)"
+		}
+		issues = append(issues, issue)
+	}
+
+	isFree := func(spotIndex int) bool {
+		isOccupied, _ := pl.Spots[spotIndex].GetStatus()
+		return !isOccupied && pl.Holds[spotIndex] == 0
+	}
+
+	queued := make(map[int]bool)
+	rebuilt := make(map[SpotType][]int)
+	for _, spotType := range []SpotType{SpotTypeMotorcycle, SpotTypeCompact, SpotTypeLarge, SpotTypeElectric, SpotTypeHandicap} {
+		rebuilt[spotType] = make([]int, 0, len(pl.FreeSpots[spotType]))
+		for _, spotIndex := range pl.FreeSpots[spotType] {
+			if spotIndex < 0 || spotIndex >= len(pl.Spots) {
+				report("level %d: free %s queue lists invalid spot index %d", pl.Index, spotType, spotIndex)
+				continue
+			}
+			spotID, actualType := pl.Spots[spotIndex].GetInfo()
+			switch {
+			case queued[spotIndex]:
+				report("level %d spot %d: listed as free more than once", pl.Index, spotID)
+			case actualType != spotType:
+				report("level %d spot %d: %s spot listed in the free %s queue", pl.Index, spotID, actualType, spotType)
+			case !isFree(spotIndex):
+				report("level %d spot %d: listed as free but is taken", pl.Index, spotID)
+			default:
+				queued[spotIndex] = true
+				rebuilt[spotType] = append(rebuilt[spotType], spotIndex)
+			}
+		}
+	}
+
+	for spotIndex, spot := range pl.Spots {
+		if queued[spotIndex] || !isFree(spotIndex) {
+			continue
+		}
+		spotID, spotType := spot.GetInfo()
+		report("level %d spot %d: free but missing from the free queue", pl.Index, spotID)
+		rebuilt[spotType] = append(rebuilt[spotType], spotIndex)
+	}
+
+	if repair {
+		pl.FreeSpots = rebuilt
+	}
+	return issues
+}
+
+// DemoReconcile corrupts a lot's bookkeeping in several ways, as a bad
+// snapshot or a bug might, and shows Reconcile finding and Repair fixing it
+func DemoReconcile() {
+	fmt.Println("=== Reconciliation Demo ===")
+
+	parkingLot := NewParkingLot("Reconcile Demo", []*ParkingLevel{
+		NewParkingLevel(0, 1, 3, 1, 0, 0),
+	})
+	for _, plate := range []string{"REC001", "REC002", "REC003", "REC004"} {
+		vehicle, _ := NewVehicle(plate, VehicleTypeCar)
+		parkingLot.ParkVehicle(vehicle)
+	}
+	fmt.Printf("Consistent lot: %d issues\n", len(parkingLot.Reconcile()))
+
+	level := parkingLot.Levels[0]
+	ticket1 := parkingLot.ActiveTickets["REC001"]
+	ticket2 := parkingLot.ActiveTickets["REC002"]
+	ticket3 := parkingLot.ActiveTickets["REC003"]
+
+	// A ticket lost while its car stays parked: an orphaned spot
+	delete(parkingLot.ActiveTickets, "REC001")
+	// A car gone with its ticket still active: a dangling ticket
+	level.Spots[level.FindSpotIndexByID(ticket2.SpotID)].Vacate()
+	// A stale spot record and a missing one
+	parkingLot.SpotToLicense[parkingLot.getSpotKey(0, 0)] = "GHOST1"
+	delete(parkingLot.SpotToLicense, parkingLot.getSpotKey(0, ticket3.SpotID))
+	// A taken spot left in its free queue
+	level.FreeSpots[ticket3.SpotType] = append(level.FreeSpots[ticket3.SpotType], level.FindSpotIndexByID(ticket3.SpotID))
+
+	fmt.Println("After corrupting the lot, Reconcile reports:")
+	for _, issue := range parkingLot.Reconcile() {
+		fmt.Printf("  - %s\n", issue)
+	}
+
+	fmt.Println("Repair:")
+	for _, issue := range parkingLot.Repair() {
+		fmt.Printf("  - %s\n", issue)
+	}
+	fmt.Printf("Reconcile after repair: %d issues\n", len(parkingLot.Reconcile()))
+
+	stats := parkingLot.OccupancyStats()
+	fmt.Printf("Occupied: %d of %d, spot %d free again: %t\n", stats.Occupied, stats.Total,
+		ticket1.SpotID, !level.Spots[level.FindSpotIndexByID(ticket1.SpotID)].IsOccupied)
+
+	vehicle, _ := NewVehicle("REC005", VehicleTypeCar)
+	if ticket, err := parkingLot.ParkVehicle(vehicle); err == nil {
+		fmt.Printf("Parked REC005 in a freed spot: %s\n", ticket)
+	}
+	if _, err := parkingLot.UnparkVehicle(ticket2); err != nil {
+		fmt.Printf("Dropped ticket for REC002 rejected: %v\n", err)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// corruptedLot parks four cars on one level (a motorcycle spot, compact
+// spots 1-3, a large spot 4), then damages its bookkeeping the ways a bad
+// snapshot or a bug might
+func corruptedLot(t *testing.T) *ParkingLot {
+	t.Helper()
+
+	parkingLot := NewParkingLot("Test", []*ParkingLevel{NewParkingLevel(0, 1, 3, 1, 0, 0)})
+	for _, plate := range []string{"REC001", "REC002", "REC003", "REC004"} {
+		vehicle, _ := NewVehicle(plate, VehicleTypeCar)
+		if _, err := parkingLot.ParkVehicle(vehicle); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if issues := parkingLot.Reconcile(); len(issues) != 0 {
+		t.Fatalf("fresh lot has issues: %v", issues)
+	}
+
+	level := parkingLot.Levels[0]
+	ticket2 := parkingLot.ActiveTickets["REC002"]
+	ticket3 := parkingLot.ActiveTickets["REC003"]
+
+	// A ticket lost while its car stays parked: an orphaned spot
+	delete(parkingLot.ActiveTickets, "REC001")
+	// A car gone with its ticket still active: a dangling ticket
+	level.Spots[level.FindSpotIndexByID(ticket2.SpotID)].Vacate()
+	// A stale spot record and a missing one
+	parkingLot.SpotToLicense[parkingLot.getSpotKey(0, 0)] = "GHOST1"
+	delete(parkingLot.SpotToLicense, parkingLot.getSpotKey(0, ticket3.SpotID))
+	// A taken spot left in its free queue
+	level.FreeSpots[ticket3.SpotType] = append(level.FreeSpots[ticket3.SpotType], level.FindSpotIndexByID(ticket3.SpotID))
+	return parkingLot
+}
+
+func TestReconcileDetectsWithoutChanging(t *testing.T) {
+	parkingLot := corruptedLot(t)
+
+	want := []string{
+		"ticket REC002: level 0 spot 2 is empty",
+		"level 0 spot 1: occupied by REC001 with no active ticket",
+		"level 0 spot 3: listed as free but is taken",
+		"level 0 spot 2: free but missing from the free queue",
+		"spot record 0-0 names GHOST1, which holds no ticket for it",
+		"spot record 0-1 names REC001, which holds no ticket for it",
+		"spot record 0-3 is missing for REC003",
+		"occupancy counters show 4 spots in use, spots show 3",
+	}
+	issues := parkingLot.Reconcile()
+	if got := strings.Join(issues, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("Reconcile() =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+
+	// Reconcile only reports, so a second run finds the same problems
+	if again := parkingLot.Reconcile(); strings.Join(again, "\n") != strings.Join(issues, "\n") {
+		t.Errorf("second Reconcile() = %v, want %v", again, issues)
+	}
+}
+
+func TestRepairFixesCorruption(t *testing.T) {
+	parkingLot := corruptedLot(t)
+	level := parkingLot.Levels[0]
+	ticket2 := parkingLot.ActiveTickets["REC002"]
+
+	issues := parkingLot.Repair()
+	if len(issues) == 0 {
+		t.Fatal("Repair() found nothing")
+	}
+	for _, issue := range issues {
+		if !strings.HasSuffix(issue, ")") {
+			t.Errorf("repair issue %q does not note the fix", issue)
+		}
+	}
+	if after := parkingLot.Reconcile(); len(after) != 0 {
+		t.Fatalf("Reconcile() after Repair = %v, want none", after)
+	}
+
+	// REC001's orphaned spot and REC002's empty one are both free again
+	if stats := parkingLot.OccupancyStats(); stats.Occupied != 2 {
+		t.Errorf("occupied %d/%d after repair, want 2", stats.Occupied, stats.Total)
+	}
+	if parkingLot.IsVehicleParked("REC001") || parkingLot.IsVehicleParked("REC002") {
+		t.Error("REC001 or REC002 still has a ticket")
+	}
+	if _, err := parkingLot.UnparkVehicle(ticket2); err == nil {
+		t.Error("dropped ticket for REC002 was accepted")
+	}
+	free := level.GetFreeSpotIndices(SpotTypeCompact)
+	if len(free) != 2 || !containsIndex(free, 1) || !containsIndex(free, 2) {
+		t.Errorf("free compact spots = %v, want indices 1 and 2", free)
+	}
+
+	// Both freed spots can be parked in again, and the lot is then full for cars
+	for _, plate := range []string{"REC005", "REC006"} {
+		vehicle, _ := NewVehicle(plate, VehicleTypeCar)
+		if _, err := parkingLot.ParkVehicle(vehicle); err != nil {
+			t.Fatalf("ParkVehicle(%s) after repair: %v", plate, err)
+		}
+	}
+	vehicle, _ := NewVehicle("REC007", VehicleTypeCar)
+	if _, err := parkingLot.ParkVehicle(vehicle); !errors.Is(err, ErrNoAvailableSpots) {
+		t.Errorf("ParkVehicle in a full lot = %v, want ErrNoAvailableSpots", err)
+	}
+}
+
+func TestRepairRebuildsFreeQueues(t *testing.T) {
+	// Compact spots 0 and 1, large spot 2
+	parkingLot := NewParkingLot("Test", []*ParkingLevel{NewParkingLevel(0, 0, 2, 1, 0, 0)})
+	level := parkingLot.Levels[0]
+	level.FreeSpots[SpotTypeCompact] = []int{0, 0, 2, 99}
+	level.FreeSpots[SpotTypeLarge] = nil
+
+	want := []string{
+		"level 0 spot 0: listed as free more than once (free queue rebuilt)",
+		"level 0 spot 2: Large spot listed in the free Compact queue (free queue rebuilt)",
+		"level 0: free Compact queue lists invalid spot index 99 (free queue rebuilt)",
+		"level 0 spot 1: free but missing from the free queue (free queue rebuilt)",
+		"level 0 spot 2: free but missing from the free queue (free queue rebuilt)",
+	}
+	issues := parkingLot.Repair()
+	if got := strings.Join(issues, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("Repair() =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+	if got := level.GetFreeSpotIndices(SpotTypeCompact); len(got) != 2 || got[0] != 0 || got[1] != 1 {
+		t.Errorf("free compact spots = %v, want [0 1]", got)
+	}
+	if got := level.GetFreeSpotIndices(SpotTypeLarge); len(got) != 1 || got[0] != 2 {
+		t.Errorf("free large spots = %v, want [2]", got)
+	}
+}
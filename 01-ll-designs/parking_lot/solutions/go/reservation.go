@@ -0,0 +1,350 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultReservationGracePeriod is how long a reserved spot is held past the
+// start of its window before the reservation is released as a no-show
+const DefaultReservationGracePeriod = 15 * time.Minute
+
+// TimeRange represents a half-open time window [Start, End)
+type TimeRange struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Overlaps reports whether two time ranges share any instant
+func (tr TimeRange) Overlaps(other TimeRange) bool {
+	return tr.Start.Before(other.End) && other.Start.Before(tr.End)
+}
+
+func (tr TimeRange) String() string {
+	return fmt.Sprintf("%s-%s", tr.Start.Format("15:04:05"), tr.End.Format("15:04:05"))
+}
+
+// Reservation holds a spot of a compatible type for a future time window
+type Reservation struct {
+	ID          string      `json:"id"`
+	VehicleType VehicleType `json:"vehicle_type"`
+	Window      TimeRange   `json:"window"`
+	LevelIndex  int         `json:"level_index"`
+	SpotID      int         `json:"spot_id"`
+	SpotType    SpotType    `json:"spot_type"`
+}
+
+// ExpiresAt returns when the reservation lapses if the vehicle hasn't
+// arrived: the grace period after the window opens, or the window end if sooner
+func (r *Reservation) ExpiresAt(gracePeriod time.Duration) time.Time {
+	expiry := r.Window.Start.Add(gracePeriod)
+	if r.Window.End.Before(expiry) {
+		return r.Window.End
+	}
+	return expiry
+}
+
+func (r *Reservation) String() string {
+	return fmt.Sprintf("Reservation %s: %s at Level %d, Spot %d (%s)",
+		r.ID, r.VehicleType, r.LevelIndex, r.SpotID, r.Window)
+}
+
+// SetReservationGracePeriod sets how late a reserved vehicle may arrive
+func (pl *ParkingLot) SetReservationGracePeriod(gracePeriod time.Duration) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.GracePeriod = gracePeriod
+}
+
+// Reserve holds a spot for vehicleType during window. A spot stays out of the
+// walk-up pool from the moment it is reserved until the reservation is used,
+// cancelled, or expires, so walk-ups can never take it. Several reservations
+// may share one spot as long as their windows don't overlap.
+func (pl *ParkingLot) Reserve(vehicleType VehicleType, window TimeRange) (*Reservation, error) {
+	if !window.End.After(window.Start) {
+		return nil, &ParkingError{Op: "reserve", Msg: "reservation window must end after it starts"}
+	}
+
+	defer pl.flushEvents()
+
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	if pl.RequiredSpots[vehicleType] > 1 {
+		return nil, &ParkingError{
+			Op:  "reserve",
+			Msg: fmt.Sprintf("a %s takes %d spots; reservations hold a single spot", vehicleType, pl.RequiredSpots[vehicleType]),
+		}
+	}
+
+	now := time.Now()
+	if !window.End.After(now) {
+		return nil, &ParkingError{Op: "reserve", Msg: "reservation window is in the past"}
+	}
+	pl.releaseExpiredReservations(now)
+
+	// Prefer stacking onto an already-held spot so free spots stay available
+	conflict := false
+	for _, spotType := range CompatibleSpotTypes(vehicleType) {
+		for _, existing := range pl.Reservations {
+			if existing.SpotType != spotType {
+				continue
+			}
+			if pl.spotHasOverlap(existing.LevelIndex, existing.SpotID, window) {
+				conflict = true
+				continue
+			}
+
+			level := pl.findLevel(existing.LevelIndex)
+			spotIndex := level.FindSpotIndexByID(existing.SpotID)
+			if err := level.HoldSpot(spotIndex); err != nil {
+				return nil, err
+			}
+			return pl.addReservation(vehicleType, window, level.Index, existing.SpotID, spotType), nil
+		}
+	}
+
+	// Otherwise take a free spot out of the walk-up pool
+	level, spotIndex, err := pl.AllocationStrategy.Allocate(pl.searchOrder(), CompatibleSpotTypes(vehicleType))
+	if err != nil {
+		if conflict {
+			return nil, ErrReservationConflict
+		}
+		return nil, err
+	}
+
+	if err := level.HoldSpot(spotIndex); err != nil {
+		return nil, err
+	}
+	spot, _ := level.GetSpot(spotIndex)
+	spotID, spotType := spot.GetInfo()
+	return pl.addReservation(vehicleType, window, level.Index, spotID, spotType), nil
+}
+
+// ParkVehicleWithReservation parks a vehicle in the spot held by a
+// reservation. The vehicle must match the reserved type and arrive between
+// the start of the window and the end of the grace period.
+//
+// The waitlist doesn't apply here: a reserved spot is held out of the pool
+// waiters are served from, so parking in it takes nothing they could get. A
+// vehicle that was itself on the waitlist leaves it, and its Ready channel
+// is closed without a ticket.
+func (pl *ParkingLot) ParkVehicleWithReservation(vehicle *Vehicle, reservationID string) (*Ticket, error) {
+	if vehicle == nil {
+		return nil, &ParkingError{Op: "park", Msg: "vehicle cannot be nil"}
+	}
+
+	defer pl.flushEvents()
+
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	now := time.Now()
+	pl.releaseExpiredReservations(now)
+
+	reservation, exists := pl.Reservations[reservationID]
+	if !exists {
+		return nil, ErrReservationNotFound
+	}
+	if reservation.VehicleType != vehicle.Type {
+		return nil, &ParkingError{
+			Op:  "park",
+			Msg: fmt.Sprintf("reservation %s is for a %s, not a %s", reservationID, reservation.VehicleType, vehicle.Type),
+		}
+	}
+	if pl.requiredSpots(vehicle) > 1 {
+		return nil, &ParkingError{
+			Op:  "park",
+			Msg: fmt.Sprintf("vehicle %s takes %d spots; reservation %s holds one", vehicle.LicensePlate, pl.requiredSpots(vehicle), reservationID),
+		}
+	}
+	if now.Before(reservation.Window.Start) {
+		return nil, &ParkingError{
+			Op:  "park",
+			Msg: fmt.Sprintf("reservation %s starts at %s", reservationID, reservation.Window.Start.Format("15:04:05")),
+		}
+	}
+
+	licensePlate := vehicle.LicensePlate
+	if _, exists := pl.ActiveTickets[licensePlate]; exists {
+		return nil, &AlreadyParkedError{Op: "park", LicensePlate: licensePlate}
+	}
+
+	level := pl.findLevel(reservation.LevelIndex)
+	spotIndex := level.FindSpotIndexByID(reservation.SpotID)
+	spot, err := level.GetSpot(spotIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fails only if the previous holder of the spot overstayed into this window
+	if err := spot.Occupy(licensePlate); err != nil {
+		return nil, err
+	}
+	if err := level.ReleaseHold(spotIndex); err != nil {
+		return nil, err
+	}
+
+	ticket := NewTicket(licensePlate, vehicle.Type, level.Index, reservation.SpotID, reservation.SpotType)
+	ticket.ReservationID = reservationID
+
+	pl.ActiveTickets[licensePlate] = ticket
+	pl.SpotToLicense[pl.getSpotKey(level.Index, reservation.SpotID)] = licensePlate
+	delete(pl.Reservations, reservationID)
+	pl.removeWaiter(licensePlate)
+	pl.recordEntry(ticket)
+
+	// The spot was already out of the free pool, so this can't newly fill the level
+	pl.queueEvent(lotEvent{kind: eventPark, ticket: ticket})
+	return ticket, nil
+}
+
+// CancelReservation releases a reservation's hold on its spot
+func (pl *ParkingLot) CancelReservation(reservationID string) error {
+	defer pl.flushEvents()
+
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	if _, exists := pl.Reservations[reservationID]; !exists {
+		return ErrReservationNotFound
+	}
+	return pl.dropReservation(reservationID)
+}
+
+// ReleaseExpiredReservations releases every reservation whose vehicle did not
+// arrive within the grace period and returns how many were released. Expired
+// reservations are also released lazily by Reserve and ParkVehicleWithReservation.
+func (pl *ParkingLot) ReleaseExpiredReservations() int {
+	defer pl.flushEvents()
+
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	return pl.releaseExpiredReservations(time.Now())
+}
+
+// GetReservations returns a copy of all pending reservations
+func (pl *ParkingLot) GetReservations() []*Reservation {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+
+	reservations := make([]*Reservation, 0, len(pl.Reservations))
+	for _, reservation := range pl.Reservations {
+		reservations = append(reservations, reservation)
+	}
+	return reservations
+}
+
+// releaseExpiredReservations drops no-show reservations (must be called with lock held)
+func (pl *ParkingLot) releaseExpiredReservations(now time.Time) int {
+	released := 0
+	for id, reservation := range pl.Reservations {
+		if now.Before(reservation.ExpiresAt(pl.GracePeriod)) {
+			continue
+		}
+		if pl.dropReservation(id) == nil {
+			released++
+		}
+	}
+	return released
+}
+
+// dropReservation removes a reservation and its hold (must be called with lock held)
+func (pl *ParkingLot) dropReservation(reservationID string) error {
+	reservation := pl.Reservations[reservationID]
+	delete(pl.Reservations, reservationID)
+
+	level := pl.findLevel(reservation.LevelIndex)
+	if level == nil {
+		return &ParkingError{
+			Op:  "reserve",
+			Msg: fmt.Sprintf("level %d not found", reservation.LevelIndex),
+		}
+	}
+	if err := level.ReleaseHold(level.FindSpotIndexByID(reservation.SpotID)); err != nil {
+		return err
+	}
+	pl.serveWaitlist()
+	return nil
+}
+
+// spotHasOverlap reports whether a spot already has a reservation overlapping
+// window (must be called with lock held)
+func (pl *ParkingLot) spotHasOverlap(levelIndex, spotID int, window TimeRange) bool {
+	for _, reservation := range pl.Reservations {
+		if reservation.LevelIndex == levelIndex && reservation.SpotID == spotID &&
+			reservation.Window.Overlaps(window) {
+			return true
+		}
+	}
+	return false
+}
+
+// addReservation records a new reservation (must be called with lock held)
+func (pl *ParkingLot) addReservation(vehicleType VehicleType, window TimeRange, levelIndex, spotID int, spotType SpotType) *Reservation {
+	pl.nextReservation++
+	reservation := &Reservation{
+		ID:          fmt.Sprintf("RSV-%d", pl.nextReservation),
+		VehicleType: vehicleType,
+		Window:      window,
+		LevelIndex:  levelIndex,
+		SpotID:      spotID,
+		SpotType:    spotType,
+	}
+	pl.Reservations[reservation.ID] = reservation
+	return reservation
+}
+
+// DemoReservations demonstrates reserving spots, conflict detection, and
+// releasing no-show reservations after the grace period
+func DemoReservations() {
+	fmt.Println("=== Reservation Demo ===")
+
+	// One compact and one large spot
+	parkingLot := NewParkingLot("Reservations Demo", []*ParkingLevel{NewParkingLevel(0, 0, 1, 1, 0, 0)})
+	now := time.Now()
+
+	morning := TimeRange{Start: now, End: now.Add(2 * time.Hour)}
+	reservation, err := parkingLot.Reserve(VehicleTypeCar, morning)
+	if err != nil {
+		fmt.Printf("✗ Failed to reserve: %v\n", err)
+		return
+	}
+	fmt.Printf("✓ %s\n", reservation)
+
+	// Walk-ups only see the unreserved large spot
+	for _, license := range []string{"WALKUP1", "WALKUP2"} {
+		vehicle, _ := NewVehicle(license, VehicleTypeCar)
+		if ticket, err := parkingLot.ParkVehicle(vehicle); err != nil {
+			fmt.Printf("✗ Walk-up %s rejected: %v\n", license, err)
+		} else {
+			fmt.Printf("✓ Walk-up %s parked at Spot %d\n", license, ticket.SpotID)
+		}
+	}
+
+	// The reserved spot is taken for the morning but free in the evening
+	if _, err := parkingLot.Reserve(VehicleTypeCar, TimeRange{Start: now.Add(time.Hour), End: now.Add(3 * time.Hour)}); err != nil {
+		fmt.Printf("✗ Overlapping reservation rejected: %v\n", err)
+	}
+	evening := TimeRange{Start: now.Add(4 * time.Hour), End: now.Add(6 * time.Hour)}
+	if later, err := parkingLot.Reserve(VehicleTypeCar, evening); err == nil {
+		fmt.Printf("✓ %s\n", later)
+	}
+
+	vehicle, _ := NewVehicle("RESERVED1", VehicleTypeCar)
+	if ticket, err := parkingLot.ParkVehicleWithReservation(vehicle, reservation.ID); err != nil {
+		fmt.Printf("✗ Failed to park with reservation: %v\n", err)
+	} else {
+		fmt.Printf("✓ Parked %s with %s at Spot %d\n", vehicle, ticket.ReservationID, ticket.SpotID)
+	}
+	fmt.Println(parkingLot)
+
+	// A no-show reservation is released once the grace period passes
+	noShowLot := NewParkingLot("No-Show Demo", []*ParkingLevel{NewParkingLevel(0, 0, 1, 0, 0, 0)})
+	noShowLot.SetReservationGracePeriod(100 * time.Millisecond)
+	noShowLot.Reserve(VehicleTypeCar, TimeRange{Start: time.Now(), End: time.Now().Add(time.Hour)})
+	fmt.Printf("\nBefore grace period: %s\n", noShowLot.Levels[0])
+	time.Sleep(150 * time.Millisecond)
+	fmt.Printf("Released %d expired reservation(s)\n", noShowLot.ReleaseExpiredReservations())
+	fmt.Printf("After grace period:  %s\n", noShowLot.Levels[0])
+}
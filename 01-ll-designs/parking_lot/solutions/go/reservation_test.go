@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReservationParksAheadOfWaitlist(t *testing.T) {
+	// One compact spot for walk-ups and one that gets reserved
+	parkingLot := NewParkingLot("Test", []*ParkingLevel{NewParkingLevel(0, 0, 2, 0, 0, 0)})
+	now := time.Now()
+	reservation, err := parkingLot.Reserve(VehicleTypeCar, TimeRange{Start: now, End: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	walkUp, _ := NewVehicle("WALKUP", VehicleTypeCar)
+	if _, err := parkingLot.ParkVehicle(walkUp); err != nil {
+		t.Fatal(err)
+	}
+	waiting := make(map[string]*WaitTicket)
+	for _, plate := range []string{"WAITER", "HOLDER"} {
+		vehicle, _ := NewVehicle(plate, VehicleTypeCar)
+		_, waitTicket, err := parkingLot.ParkOrWait(vehicle)
+		if err != nil || waitTicket == nil {
+			t.Fatalf("ParkOrWait(%s) = %v, %v; want a wait ticket", plate, waitTicket, err)
+		}
+		waiting[plate] = waitTicket
+	}
+
+	// The reservation holder parks in the held spot though a car waited longer
+	holder, _ := NewVehicle("HOLDER", VehicleTypeCar)
+	if _, err := parkingLot.ParkVehicleWithReservation(holder, reservation.ID); err != nil {
+		t.Fatalf("ParkVehicleWithReservation: %v", err)
+	}
+	if n := parkingLot.WaitlistLength(VehicleTypeCar); n != 1 {
+		t.Errorf("waitlist length %d, want 1 after the holder left it", n)
+	}
+	if ticket, open := <-waiting["HOLDER"].Ready; open {
+		t.Errorf("holder's wait ticket delivered %v, want it closed", ticket)
+	}
+
+	// The next freed spot still goes to the car that waited
+	if _, err := parkingLot.UnparkByLicense("WALKUP"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ticket := <-waiting["WAITER"].Ready:
+		if ticket == nil || ticket.LicensePlate != "WAITER" {
+			t.Errorf("waiter got ticket %v", ticket)
+		}
+	default:
+		t.Error("waiter was not parked when the walk-up left")
+	}
+}
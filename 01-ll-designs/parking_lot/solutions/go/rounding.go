@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// RoundingStrategy decides how the amount due is rounded once a fee has been
+// computed. Line items are always rounded to the currency's minor unit; the
+// strategy applies to the total, and any difference it makes is shown on the
+// receipt as a "Rounding" line.
+type RoundingStrategy int
+
+const (
+	RoundNearestMinorUnit RoundingStrategy = iota // nearest cent, halves away from zero
+	RoundUpMinorUnit                              // up to the next cent
+	RoundUpWholeUnit                              // up to the next dollar
+)
+
+// String returns the strategy name
+func (rs RoundingStrategy) String() string {
+	switch rs {
+	case RoundNearestMinorUnit:
+		return "nearest minor unit"
+	case RoundUpMinorUnit:
+		return "up to minor unit"
+	case RoundUpWholeUnit:
+		return "up to whole unit"
+	default:
+		return "unknown"
+	}
+}
+
+// currencyInfo describes how amounts in a currency are written
+type currencyInfo struct {
+	Symbol     string
+	MinorUnits int // digits after the decimal point
+}
+
+// DefaultCurrency is the currency of a pricing policy with none set
+const DefaultCurrency = "USD"
+
+// currencies are the ISO 4217 codes the receipt knows symbols for. Other codes
+// are printed as the code itself with two decimal places.
+var currencies = map[string]currencyInfo{
+	"USD": {Symbol: "$", MinorUnits: 2},
+	"EUR": {Symbol: "€", MinorUnits: 2},
+	"GBP": {Symbol: "£", MinorUnits: 2},
+	"JPY": {Symbol: "¥", MinorUnits: 0},
+}
+
+// lookupCurrency returns how to write amounts in code, defaulting to USD
+func lookupCurrency(code string) currencyInfo {
+	if code == "" {
+		code = DefaultCurrency
+	}
+	if info, ok := currencies[code]; ok {
+		return info
+	}
+	return currencyInfo{Symbol: code + " ", MinorUnits: 2}
+}
+
+// roundAmount rounds amount to a multiple of 10^-digits, to nearest with
+// halves away from zero, or up. Float noise below a millionth of the unit is
+// dropped first, so 4.000000001 stays 4.00 and 2.175 rounds to 2.18.
+func roundAmount(amount float64, digits int, up bool) float64 {
+	scale := math.Pow(10, float64(digits))
+	scaled := math.Round(amount*scale*1e6) / 1e6
+	if up {
+		return math.Ceil(scaled) / scale
+	}
+	return math.Round(scaled) / scale
+}
+
+// apply rounds a raw amount due in a currency with the given minor units
+func (rs RoundingStrategy) apply(amount float64, minorUnits int) float64 {
+	switch rs {
+	case RoundUpMinorUnit:
+		return roundAmount(amount, minorUnits, true)
+	case RoundUpWholeUnit:
+		return roundAmount(amount, 0, true)
+	default:
+		return roundAmount(amount, minorUnits, false)
+	}
+}
+
+// billingIncrement returns the unit parking time is rounded up to, an hour
+// unless BillingIncrement is set
+func (spp *StandardPricingPolicy) billingIncrement() time.Duration {
+	if spp.BillingIncrement <= 0 {
+		return time.Hour
+	}
+	return spp.BillingIncrement
+}
+
+// finalize converts a breakdown computed in exact arithmetic into what the
+// customer is charged. Policies that adjust the standard charges, such as a
+// premium multiplier, call it after adjusting so nothing is rounded twice.
+func (spp *StandardPricingPolicy) finalize(breakdown *FeeBreakdown) *FeeBreakdown {
+	breakdown.Currency = spp.Currency
+	if breakdown.Currency == "" {
+		breakdown.Currency = DefaultCurrency
+	}
+	minorUnits := lookupCurrency(breakdown.Currency).MinorUnits
+
+	due := spp.Rounding.apply(breakdown.sum(), minorUnits)
+
+	breakdown.BaseFee = roundAmount(breakdown.BaseFee, minorUnits, false)
+	breakdown.HourlyCharge = roundAmount(breakdown.HourlyCharge, minorUnits, false)
+	breakdown.EnergyCharge = roundAmount(breakdown.EnergyCharge, minorUnits, false)
+	for i := range breakdown.Surcharges {
+		breakdown.Surcharges[i].Amount = roundAmount(breakdown.Surcharges[i].Amount, minorUnits, false)
+	}
+	breakdown.updateTotal()
+
+	if adjustment := roundAmount(due-breakdown.Total, minorUnits, false); adjustment != 0 {
+		breakdown.AddSurcharge("Rounding", adjustment)
+	}
+	return breakdown
+}
+
+// DemoFeeRounding prices the same stays under each rounding strategy, with
+// 15-minute billing, a free grace period, a premium multiplier, and a
+// zero-decimal currency
+func DemoFeeRounding() {
+	fmt.Println("=== Fee Rounding Demo ===")
+
+	entry := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	durations := []time.Duration{
+		20 * time.Minute,
+		time.Hour + 10*time.Minute,
+		2*time.Hour + 59*time.Minute,
+		26 * time.Hour,
+	}
+
+	strategies := []RoundingStrategy{RoundNearestMinorUnit, RoundUpMinorUnit, RoundUpWholeUnit}
+	fmt.Printf("%-28s", "Motorcycle $0.33/h, 15 min")
+	for _, strategy := range strategies {
+		fmt.Printf("%20s", strategy)
+	}
+	fmt.Println()
+	for _, duration := range durations {
+		fmt.Printf("%-28v", duration)
+		for _, strategy := range strategies {
+			policy := NewStandardPricingPolicy()
+			policy.BillingIncrement = 15 * time.Minute
+			policy.HourlyRates[VehicleTypeMotorcycle] = 0.33
+			policy.Rounding = strategy
+			fmt.Printf("%20.2f", policy.CalculateFee(VehicleTypeMotorcycle, entry, entry.Add(duration), 0))
+		}
+		fmt.Println()
+	}
+
+	// The premium multiplies the exact charges and rounds once at the end
+	premium := NewPremiumPricingPolicy(1.15)
+	premium.HourlyRates[VehicleTypeCar] = 1.35
+	fmt.Printf("Premium 1.15x, 3 hours at $1.35:\n%s\n",
+		premium.CalculateFeeDetailed(VehicleTypeCar, entry, entry.Add(3*time.Hour), 0))
+
+	premium.Rounding = RoundUpWholeUnit
+	fmt.Printf("Same stay rounded up to the dollar:\n%s\n",
+		premium.CalculateFeeDetailed(VehicleTypeCar, entry, entry.Add(3*time.Hour), 0))
+
+	// The first 10 minutes are free; after that the time past them is
+	// billed in 15-minute blocks
+	grace := NewStandardPricingPolicy()
+	grace.BillingIncrement = 15 * time.Minute
+	grace.GracePeriod = 10 * time.Minute
+	fmt.Println("Car $1.00/h, 10 min free, then 15 min blocks:")
+	for _, duration := range []time.Duration{5 * time.Minute, 10 * time.Minute, 20 * time.Minute, 25 * time.Minute, 26 * time.Minute} {
+		breakdown := grace.CalculateFeeDetailed(VehicleTypeCar, entry, entry.Add(duration), 0)
+		fmt.Printf("  %-6v %5.2f h billed, total %.2f\n", duration, breakdown.HoursCharged, breakdown.Total)
+	}
+
+	yen := NewStandardPricingPolicy()
+	yen.Currency = "JPY"
+	yen.BaseFee = 300
+	yen.HourlyRates[VehicleTypeCar] = 333.3
+	yen.DailyMaximum = 3000
+	yen.EnergyRate = 45.5
+	fmt.Printf("Tokyo garage, EV charged 7.3 kWh:\n%s\n",
+		yen.CalculateFeeDetailed(VehicleTypeCar, entry, entry.Add(2*time.Hour), 7.3))
+}
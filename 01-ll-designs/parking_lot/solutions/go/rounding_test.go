@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGracePeriodBilling(t *testing.T) {
+	// Car $1.00/h plus the $2.00 base fee, 10 minutes free, then 15-minute blocks
+	policy := NewStandardPricingPolicy()
+	policy.BillingIncrement = 15 * time.Minute
+	policy.GracePeriod = 10 * time.Minute
+	entry := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		stay      time.Duration
+		wantHours float64
+		wantTotal float64
+	}{
+		{"well inside grace", 5 * time.Minute, 0, 0},
+		{"exactly the grace period", 10 * time.Minute, 0, 0},
+		{"just past grace", 10*time.Minute + time.Second, 0.25, 2.25},
+		{"one block after grace", 20 * time.Minute, 0.25, 2.25},
+		{"exactly one block after grace", 25 * time.Minute, 0.25, 2.25},
+		{"into the second block", 26 * time.Minute, 0.5, 2.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breakdown := policy.CalculateFeeDetailed(VehicleTypeCar, entry, entry.Add(tt.stay), 0)
+			if breakdown.HoursCharged != tt.wantHours || breakdown.Total != tt.wantTotal {
+				t.Errorf("%v stay: %.2f h billed, total %.2f; want %.2f h, %.2f",
+					tt.stay, breakdown.HoursCharged, breakdown.Total, tt.wantHours, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestRoundingStrategies(t *testing.T) {
+	entry := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		stay                    time.Duration
+		nearest, upCent, upUnit float64
+	}{
+		{20 * time.Minute, 2.17, 2.17, 3},
+		{time.Hour + 10*time.Minute, 2.41, 2.42, 3},
+		{26 * time.Hour, 10.58, 10.58, 11},
+	}
+	for _, tt := range tests {
+		for strategy, want := range map[RoundingStrategy]float64{
+			RoundNearestMinorUnit: tt.nearest,
+			RoundUpMinorUnit:      tt.upCent,
+			RoundUpWholeUnit:      tt.upUnit,
+		} {
+			// Motorcycle $0.33/h in 15-minute blocks, so totals fall between cents
+			policy := NewStandardPricingPolicy()
+			policy.BillingIncrement = 15 * time.Minute
+			policy.HourlyRates[VehicleTypeMotorcycle] = 0.33
+			policy.Rounding = strategy
+			if got := policy.CalculateFee(VehicleTypeMotorcycle, entry, entry.Add(tt.stay), 0); got != want {
+				t.Errorf("%v stay rounded %s = %.2f, want %.2f", tt.stay, strategy, got, want)
+			}
+		}
+	}
+}
+
+func TestRoundAmount(t *testing.T) {
+	tests := []struct {
+		amount float64
+		digits int
+		up     bool
+		want   float64
+	}{
+		{2.175, 2, false, 2.18},
+		{4.000000001, 2, true, 4},
+		{4.001, 2, true, 4.01},
+		{-2.175, 2, false, -2.18},
+		{1999.5, 0, false, 2000},
+	}
+	for _, tt := range tests {
+		if got := roundAmount(tt.amount, tt.digits, tt.up); got != tt.want {
+			t.Errorf("roundAmount(%v, %d, %t) = %v, want %v", tt.amount, tt.digits, tt.up, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// Server exposes a ParkingLot as a JSON REST API:
+//
+//	POST /park                    park a vehicle, returns its ticket
+//	POST /unpark                  unpark with a ticket, returns the receipt
+//	GET  /availability            free spots by type, overall and per level
+//	GET  /tickets/{licensePlate}  the active ticket for a vehicle
+type Server struct {
+	lot    *ParkingLot
+	router *http.ServeMux
+}
+
+// NewServer creates a server for lot
+func NewServer(lot *ParkingLot) *Server {
+	s := &Server{lot: lot, router: http.NewServeMux()}
+	s.router.HandleFunc("POST /park", s.parkHandler)
+	s.router.HandleFunc("POST /unpark", s.unparkHandler)
+	s.router.HandleFunc("GET /availability", s.availabilityHandler)
+	s.router.HandleFunc("GET /tickets/{licensePlate}", s.ticketHandler)
+	return s
+}
+
+// ServeHTTP routes a request to its handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves the API on addr until the listener fails
+func (s *Server) ListenAndServe(addr string) error {
+	fmt.Printf("Parking lot %s listening on %s\n", s.lot.GetName(), addr)
+	return http.ListenAndServe(addr, s)
+}
+
+// parkRequest is the body of POST /park
+type parkRequest struct {
+	LicensePlate     string `json:"license_plate"`
+	VehicleType      string `json:"vehicle_type"` // e.g. "car", "motorcycle"
+	HandicapEligible bool   `json:"handicap_eligible,omitempty"`
+	RequiredSpots    int    `json:"required_spots,omitempty"`
+}
+
+// unparkRequest is the body of POST /unpark
+type unparkRequest struct {
+	TicketID     string `json:"ticket_id"`
+	LicensePlate string `json:"license_plate"`
+}
+
+// levelAvailability is the free spot count of one level by spot type
+type levelAvailability struct {
+	Index     int            `json:"index"`
+	Available map[string]int `json:"available"`
+}
+
+// availabilityResponse is the body returned by GET /availability
+type availabilityResponse struct {
+	Lot       string              `json:"lot"`
+	Available map[string]int      `json:"available"`
+	Levels    []levelAvailability `json:"levels"`
+}
+
+func (s *Server) parkHandler(w http.ResponseWriter, r *http.Request) {
+	var req parkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	vehicleType, ok := parseVehicleType(req.VehicleType)
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("unknown vehicle type %q", req.VehicleType))
+		return
+	}
+	vehicle, err := NewVehicle(req.LicensePlate, vehicleType)
+	if err != nil {
+		writeParkingError(w, err)
+		return
+	}
+	vehicle.HandicapEligible = req.HandicapEligible
+	vehicle.RequiredSpots = req.RequiredSpots
+
+	ticket, err := s.lot.ParkVehicle(vehicle)
+	if err != nil {
+		writeParkingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, ticket)
+}
+
+func (s *Server) unparkHandler(w http.ResponseWriter, r *http.Request) {
+	var req unparkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if req.TicketID == "" || req.LicensePlate == "" {
+		writeJSONError(w, http.StatusBadRequest, "ticket_id and license_plate are required")
+		return
+	}
+
+	ticket := &Ticket{
+		ID:           req.TicketID,
+		LicensePlate: normalizeLicensePlate(req.LicensePlate),
+	}
+	receipt, err := s.lot.UnparkVehicle(ticket)
+	if err != nil {
+		writeParkingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, receipt)
+}
+
+func (s *Server) availabilityHandler(w http.ResponseWriter, r *http.Request) {
+	resp := availabilityResponse{
+		Lot:       s.lot.GetName(),
+		Available: make(map[string]int),
+	}
+	for _, level := range s.lot.GetLevels() {
+		counts := make(map[string]int)
+		motorcycle, compact, large, electric, handicap := level.GetAvailability()
+		counts[SpotTypeMotorcycle.String()] = motorcycle
+		counts[SpotTypeCompact.String()] = compact
+		counts[SpotTypeLarge.String()] = large
+		counts[SpotTypeElectric.String()] = electric
+		counts[SpotTypeHandicap.String()] = handicap
+
+		for spotType, count := range counts {
+			resp.Available[spotType] += count
+		}
+		resp.Levels = append(resp.Levels, levelAvailability{Index: level.Index, Available: counts})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) ticketHandler(w http.ResponseWriter, r *http.Request) {
+	licensePlate := normalizeLicensePlate(r.PathValue("licensePlate"))
+
+	ticket := s.lot.GetTicketForVehicle(licensePlate)
+	if ticket == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("vehicle %s is not parked", licensePlate))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ticket)
+}
+
+// parseVehicleType maps a vehicle type name, in any case, to its VehicleType
+func parseVehicleType(name string) (VehicleType, bool) {
+	for _, vt := range []VehicleType{VehicleTypeMotorcycle, VehicleTypeCar, VehicleTypeBus, VehicleTypeElectric} {
+		if strings.EqualFold(strings.TrimSpace(name), vt.String()) {
+			return vt, true
+		}
+	}
+	return 0, false
+}
+
+// normalizeLicensePlate formats a plate the way NewVehicle stores it
+func normalizeLicensePlate(licensePlate string) string {
+	return strings.TrimSpace(strings.ToUpper(licensePlate))
+}
+
+// statusForError maps a parking error to the HTTP status describing it
+func statusForError(err error) int {
+	var parkingErr *ParkingError
+	switch {
+	case errors.Is(err, ErrVehicleAlreadyParked):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidTicket):
+		return http.StatusNotFound
+	case errors.Is(err, ErrNoAvailableSpots):
+		return http.StatusServiceUnavailable
+	case errors.As(err, &parkingErr):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeParkingError(w http.ResponseWriter, err error) {
+	writeJSONError(w, statusForError(err), err.Error())
+}
+
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// DemoRESTAPI runs the API against a test server and walks a vehicle through
+// parking, lookup, and unparking, including the error responses
+func DemoRESTAPI() {
+	fmt.Println("=== REST API Demo ===")
+
+	parkingLot := NewParkingLot("API Garage", []*ParkingLevel{NewParkingLevel(0, 1, 1, 0, 0, 0)})
+	ts := httptest.NewServer(NewServer(parkingLot))
+	defer ts.Close()
+
+	call := func(method, path, body string) (int, string) {
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return 0, err.Error()
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		fmt.Printf("%-6s %-20s -> %d\n", method, path, resp.StatusCode)
+		return resp.StatusCode, strings.TrimSpace(string(data))
+	}
+
+	_, body := call("POST", "/park", `{"license_plate": "api123", "vehicle_type": "car"}`)
+	var ticket Ticket
+	json.Unmarshal([]byte(body), &ticket)
+	fmt.Printf("  ticket %s at level %d, spot %d\n", ticket.ID, ticket.LevelIndex, ticket.SpotID)
+
+	_, body = call("POST", "/park", `{"license_plate": "API123", "vehicle_type": "car"}`)
+	fmt.Printf("  %s\n", body)
+	_, body = call("POST", "/park", `{"license_plate": "API456", "vehicle_type": "car"}`)
+	fmt.Printf("  %s\n", body)
+	_, body = call("POST", "/park", `{"license_plate": "API789", "vehicle_type": "boat"}`)
+	fmt.Printf("  %s\n", body)
+
+	_, body = call("GET", "/availability", "")
+	fmt.Printf("  %s\n", body)
+	call("GET", "/tickets/api123", "")
+
+	_, body = call("POST", "/unpark", `{"ticket_id": "bogus", "license_plate": "API123"}`)
+	fmt.Printf("  %s\n", body)
+	_, body = call("POST", "/unpark", fmt.Sprintf(`{"ticket_id": %q, "license_plate": "API123"}`, ticket.ID))
+	var receipt FeeBreakdown
+	json.Unmarshal([]byte(body), &receipt)
+	fmt.Printf("  charged $%.2f\n", receipt.Total)
+
+	_, body = call("GET", "/tickets/API123", "")
+	fmt.Printf("  %s\n", body)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestServer serves a lot with one motorcycle spot and one compact spot
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	parkingLot := NewParkingLot("API Garage", []*ParkingLevel{NewParkingLevel(0, 1, 1, 0, 0, 0)})
+	ts := httptest.NewServer(NewServer(parkingLot))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// callAPI sends a request to ts, checks its status, and decodes the JSON body
+// into out unless out is nil
+func callAPI(t *testing.T, ts *httptest.Server, method, path, body string, wantStatus int, out interface{}) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%s %s = %d, want %d", method, path, resp.StatusCode, wantStatus)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("%s %s Content-Type = %q, want application/json", method, path, got)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decoding body: %v", method, path, err)
+		}
+	}
+}
+
+func TestServerParkErrors(t *testing.T) {
+	ts := newTestServer(t)
+	callAPI(t, ts, "POST", "/park", `{"license_plate": "api123", "vehicle_type": "car"}`, http.StatusCreated, nil)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"already parked", `{"license_plate": "API123", "vehicle_type": "car"}`, http.StatusConflict},
+		{"lot full", `{"license_plate": "API456", "vehicle_type": "car"}`, http.StatusServiceUnavailable},
+		{"unknown vehicle type", `{"license_plate": "API789", "vehicle_type": "boat"}`, http.StatusBadRequest},
+		{"missing license plate", `{"vehicle_type": "car"}`, http.StatusBadRequest},
+		{"invalid JSON", `{"license_plate":`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]string
+			callAPI(t, ts, "POST", "/park", tt.body, tt.wantStatus, &body)
+			if body["error"] == "" {
+				t.Errorf("body %v has no error message", body)
+			}
+		})
+	}
+}
+
+func TestServerParkLookupUnpark(t *testing.T) {
+	ts := newTestServer(t)
+
+	var ticket Ticket
+	callAPI(t, ts, "POST", "/park", `{"license_plate": "api123", "vehicle_type": "car"}`, http.StatusCreated, &ticket)
+	if ticket.ID == "" || ticket.LicensePlate != "API123" {
+		t.Fatalf("ticket %+v, want an ID and plate API123", ticket)
+	}
+
+	var availability availabilityResponse
+	callAPI(t, ts, "GET", "/availability", "", http.StatusOK, &availability)
+	if availability.Available["Compact"] != 0 || availability.Available["Motorcycle"] != 1 {
+		t.Errorf("availability %v, want the compact spot taken and the motorcycle spot free", availability.Available)
+	}
+	if len(availability.Levels) != 1 || availability.Levels[0].Available["Compact"] != 0 {
+		t.Errorf("levels %+v, want level 0 with no compact spot free", availability.Levels)
+	}
+
+	var found Ticket
+	callAPI(t, ts, "GET", "/tickets/api123", "", http.StatusOK, &found)
+	if found.ID != ticket.ID {
+		t.Errorf("GET /tickets/api123 returned ticket %s, want %s", found.ID, ticket.ID)
+	}
+
+	callAPI(t, ts, "POST", "/unpark", `{"ticket_id": "bogus", "license_plate": "API123"}`, http.StatusNotFound, nil)
+	callAPI(t, ts, "POST", "/unpark", `{"license_plate": "API123"}`, http.StatusBadRequest, nil)
+	callAPI(t, ts, "POST", "/unpark", `not json`, http.StatusBadRequest, nil)
+
+	var receipt FeeBreakdown
+	unpark := fmt.Sprintf(`{"ticket_id": %q, "license_plate": "api123"}`, ticket.ID)
+	callAPI(t, ts, "POST", "/unpark", unpark, http.StatusOK, &receipt)
+	if receipt.Total <= 0 {
+		t.Errorf("receipt total %.2f, want a charge", receipt.Total)
+	}
+
+	callAPI(t, ts, "GET", "/tickets/API123", "", http.StatusNotFound, nil)
+	callAPI(t, ts, "POST", "/unpark", unpark, http.StatusNotFound, nil)
+	callAPI(t, ts, "GET", "/availability", "", http.StatusOK, &availability)
+	if availability.Available["Compact"] != 1 {
+		t.Errorf("compact spots free after unpark = %d, want 1", availability.Available["Compact"])
+	}
+}
+
+func TestServerRejectsWrongMethod(t *testing.T) {
+	ts := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + "/park")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET /park = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// snapshotVersion is bumped whenever the snapshot format changes incompatibly
+const snapshotVersion = 1
+
+// lotSnapshot is the serialized form of a ParkingLot
+type lotSnapshot struct {
+	Version             int                 `json:"version"`
+	Name                string              `json:"name"`
+	Levels              []*ParkingLevel     `json:"levels"`
+	ActiveTickets       []*Ticket           `json:"active_tickets"`
+	Reservations        []*Reservation      `json:"reservations"`
+	Pricing             pricingSnapshot     `json:"pricing"`
+	AllocationStrategy  string              `json:"allocation_strategy"`
+	LevelSelection      string              `json:"level_selection,omitempty"`
+	GracePeriod         time.Duration       `json:"grace_period"`
+	LostTicketSurcharge float64             `json:"lost_ticket_surcharge"`
+	HandicapFallback    bool                `json:"handicap_fallback"`
+	RequiredSpots       map[VehicleType]int `json:"required_spots,omitempty"`
+	NextReservation     int                 `json:"next_reservation"`
+}
+
+// pricingSnapshot records a pricing policy by type tag plus its settings
+type pricingSnapshot struct {
+	Type              string                 `json:"type"`
+	Standard          *StandardPricingPolicy `json:"standard"`
+	PremiumMultiplier float64                `json:"premium_multiplier,omitempty"`
+	Windows           []PricingWindow        `json:"windows,omitempty"`
+	Passes            []*Pass                `json:"passes,omitempty"`
+}
+
+// levelJSON and spotJSON have the same fields as their originals but no
+// methods, so they marshal with the default encoding
+type levelJSON ParkingLevel
+type spotJSON ParkingSpot
+
+// MarshalJSON encodes the level while holding its lock
+func (pl *ParkingLevel) MarshalJSON() ([]byte, error) {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+	return json.Marshal((*levelJSON)(pl))
+}
+
+// MarshalJSON encodes the spot while holding its lock
+func (ps *ParkingSpot) MarshalJSON() ([]byte, error) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return json.Marshal((*spotJSON)(ps))
+}
+
+// Snapshot serializes the lot's levels, spot occupancy, active tickets,
+// reservations, and configuration so RestoreParkingLot can resume in-progress
+// sessions after a restart. Event listeners and the waitlist are not included.
+func (pl *ParkingLot) Snapshot() ([]byte, error) {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+
+	pricing, err := snapshotPricing(pl.PricingPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := lotSnapshot{
+		Version:             snapshotVersion,
+		Name:                pl.Name,
+		Levels:              pl.Levels,
+		ActiveTickets:       make([]*Ticket, 0, len(pl.ActiveTickets)),
+		Reservations:        make([]*Reservation, 0, len(pl.Reservations)),
+		Pricing:             pricing,
+		AllocationStrategy:  pl.AllocationStrategy.Name(),
+		LevelSelection:      pl.LevelSelection.Name(),
+		GracePeriod:         pl.GracePeriod,
+		LostTicketSurcharge: pl.LostTicketSurcharge,
+		HandicapFallback:    pl.HandicapFallback,
+		RequiredSpots:       pl.RequiredSpots,
+		NextReservation:     pl.nextReservation,
+	}
+	for _, ticket := range pl.ActiveTickets {
+		snapshot.ActiveTickets = append(snapshot.ActiveTickets, ticket)
+	}
+	for _, reservation := range pl.Reservations {
+		snapshot.Reservations = append(snapshot.Reservations, reservation)
+	}
+
+	// Sort so identical state always produces identical output
+	sort.Slice(snapshot.ActiveTickets, func(i, j int) bool {
+		return snapshot.ActiveTickets[i].LicensePlate < snapshot.ActiveTickets[j].LicensePlate
+	})
+	sort.Slice(snapshot.Reservations, func(i, j int) bool {
+		return snapshot.Reservations[i].ID < snapshot.Reservations[j].ID
+	})
+
+	return json.MarshalIndent(snapshot, "", "  ")
+}
+
+// RestoreParkingLot rebuilds a parking lot from Snapshot output. Free spot
+// queues and reservation holds are derived from spot occupancy and the
+// pending reservations rather than stored.
+func RestoreParkingLot(data []byte) (*ParkingLot, error) {
+	var snapshot lotSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, &ParkingError{Op: "restore", Msg: "invalid snapshot", Err: err}
+	}
+	if snapshot.Version != snapshotVersion {
+		return nil, &ParkingError{
+			Op:  "restore",
+			Msg: fmt.Sprintf("unsupported snapshot version %d", snapshot.Version),
+		}
+	}
+
+	pricing, err := restorePricing(snapshot.Pricing)
+	if err != nil {
+		return nil, err
+	}
+	strategy, err := allocationStrategyByName(snapshot.AllocationStrategy)
+	if err != nil {
+		return nil, err
+	}
+	levelSelection, err := levelSelectionByName(snapshot.LevelSelection)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, level := range snapshot.Levels {
+		if level == nil {
+			return nil, &ParkingError{Op: "restore", Msg: "snapshot contains a null level"}
+		}
+		level.FreeSpots = make(map[SpotType][]int)
+		level.Holds = make(map[int]int)
+	}
+
+	lot := NewParkingLot(snapshot.Name, snapshot.Levels)
+	lot.PricingPolicy = pricing
+	lot.AllocationStrategy = strategy
+	lot.LevelSelection = levelSelection
+	lot.GracePeriod = snapshot.GracePeriod
+	lot.LostTicketSurcharge = snapshot.LostTicketSurcharge
+	lot.HandicapFallback = snapshot.HandicapFallback
+	for vehicleType, count := range snapshot.RequiredSpots {
+		lot.RequiredSpots[vehicleType] = count
+	}
+	lot.nextReservation = snapshot.NextReservation
+
+	for _, ticket := range snapshot.ActiveTickets {
+		for _, spotID := range ticket.AllSpotIDs() {
+			if _, _, err := lot.locateSpot("restore", ticket.LevelIndex, spotID); err != nil {
+				return nil, err
+			}
+			lot.SpotToLicense[lot.getSpotKey(ticket.LevelIndex, spotID)] = ticket.LicensePlate
+		}
+		lot.ActiveTickets[ticket.LicensePlate] = ticket
+	}
+
+	for _, reservation := range snapshot.Reservations {
+		level, spotIndex, err := lot.locateSpot("restore", reservation.LevelIndex, reservation.SpotID)
+		if err != nil {
+			return nil, err
+		}
+		level.Holds[spotIndex]++
+		lot.Reservations[reservation.ID] = reservation
+	}
+
+	// Every spot that is neither occupied nor held goes back in its free queue
+	for _, level := range lot.Levels {
+		for spotIndex, spot := range level.Spots {
+			if spot.IsOccupied || level.Holds[spotIndex] > 0 {
+				continue
+			}
+			level.FreeSpots[spot.Type] = append(level.FreeSpots[spot.Type], spotIndex)
+		}
+	}
+
+	return lot, nil
+}
+
+// locateSpot finds a spot by level index and spot ID
+func (pl *ParkingLot) locateSpot(op string, levelIndex, spotID int) (*ParkingLevel, int, error) {
+	level := pl.findLevel(levelIndex)
+	if level == nil {
+		return nil, -1, &ParkingError{Op: op, Msg: fmt.Sprintf("level %d not found", levelIndex)}
+	}
+	spotIndex := level.FindSpotIndexByID(spotID)
+	if spotIndex == -1 {
+		return nil, -1, &ParkingError{
+			Op:  op,
+			Msg: fmt.Sprintf("spot %d not found in level %d", spotID, levelIndex),
+		}
+	}
+	return level, spotIndex, nil
+}
+
+// snapshotPricing tags a pricing policy with its type
+func snapshotPricing(policy PricingPolicy) (pricingSnapshot, error) {
+	switch p := policy.(type) {
+	case *StandardPricingPolicy:
+		return pricingSnapshot{Type: "standard", Standard: p}, nil
+	case *PremiumPricingPolicy:
+		return pricingSnapshot{Type: "premium", Standard: p.StandardPricingPolicy, PremiumMultiplier: p.PremiumMultiplier}, nil
+	case *DynamicPricingPolicy:
+		return pricingSnapshot{Type: "dynamic", Standard: p.StandardPricingPolicy, Windows: p.Windows}, nil
+	case *SubscriptionPricingPolicy:
+		return pricingSnapshot{Type: "subscription", Standard: p.StandardPricingPolicy, Passes: p.Registry.Passes()}, nil
+	default:
+		return pricingSnapshot{}, &ParkingError{
+			Op:  "snapshot",
+			Msg: fmt.Sprintf("cannot serialize pricing policy %T", policy),
+		}
+	}
+}
+
+// restorePricing reconstructs a pricing policy from its type tag
+func restorePricing(snapshot pricingSnapshot) (PricingPolicy, error) {
+	standard := snapshot.Standard
+	if standard == nil {
+		standard = NewStandardPricingPolicy()
+	}
+
+	switch snapshot.Type {
+	case "standard":
+		return standard, nil
+	case "premium":
+		return &PremiumPricingPolicy{StandardPricingPolicy: standard, PremiumMultiplier: snapshot.PremiumMultiplier}, nil
+	case "dynamic":
+		return &DynamicPricingPolicy{StandardPricingPolicy: standard, Windows: snapshot.Windows}, nil
+	case "subscription":
+		registry := NewSubscriptionRegistry()
+		registry.restorePasses(snapshot.Passes)
+		return &SubscriptionPricingPolicy{StandardPricingPolicy: standard, Registry: registry}, nil
+	default:
+		return nil, &ParkingError{
+			Op:  "restore",
+			Msg: fmt.Sprintf("unknown pricing policy type %q", snapshot.Type),
+		}
+	}
+}
+
+// allocationStrategyByName maps a strategy's Name back to the strategy
+func allocationStrategyByName(name string) (SpotAllocationStrategy, error) {
+	for _, strategy := range []SpotAllocationStrategy{
+		BestFitStrategy{},
+		NearestToEntranceStrategy{},
+		BalanceAcrossLevelsStrategy{},
+	} {
+		if strategy.Name() == name {
+			return strategy, nil
+		}
+	}
+	return nil, &ParkingError{
+		Op:  "restore",
+		Msg: fmt.Sprintf("unknown allocation strategy %q", name),
+	}
+}
+
+// DemoSnapshot demonstrates saving a busy lot and restoring it
+func DemoSnapshot() {
+	fmt.Println("=== Snapshot / Restore Demo ===")
+
+	parkingLot := NewParkingLot("Snapshot Demo", []*ParkingLevel{NewParkingLevel(0, 1, 2, 1, 1, 0)})
+	parkingLot.SetPricingPolicy(NewPremiumPricingPolicy(1.5))
+
+	var tickets []*Ticket
+	for _, v := range []struct {
+		license string
+		vType   VehicleType
+	}{{"SNAP001", VehicleTypeCar}, {"SNAP002", VehicleTypeMotorcycle}, {"SNAP003", VehicleTypeElectric}} {
+		vehicle, _ := NewVehicle(v.license, v.vType)
+		if ticket, err := parkingLot.ParkVehicle(vehicle); err == nil {
+			tickets = append(tickets, ticket)
+		}
+	}
+	parkingLot.Reserve(VehicleTypeCar, TimeRange{Start: time.Now().Add(time.Hour), End: time.Now().Add(2 * time.Hour)})
+
+	data, err := parkingLot.Snapshot()
+	if err != nil {
+		fmt.Printf("✗ Snapshot failed: %v\n", err)
+		return
+	}
+	fmt.Printf("Snapshot: %d bytes\n", len(data))
+
+	restored, err := RestoreParkingLot(data)
+	if err != nil {
+		fmt.Printf("✗ Restore failed: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Original: %s\n", parkingLot.Levels[0])
+	fmt.Printf("Restored: %s\n", restored.Levels[0])
+	fmt.Printf("Restored %d active tickets and %d reservations\n",
+		len(restored.GetActiveTickets()), len(restored.GetReservations()))
+
+	// A ticket issued before the restart is still honored, at the same pricing
+	receipt, err := restored.UnparkVehicle(tickets[0])
+	if err != nil {
+		fmt.Printf("✗ Failed to unpark after restore: %v\n", err)
+		return
+	}
+	fmt.Printf("✓ Unparked %s after restore, Fee: $%.2f\n", tickets[0].LicensePlate, receipt.Total)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+// occupancy maps "level/spot" to the plate parked there, for every occupied spot
+func occupancy(parkingLot *ParkingLot) map[string]string {
+	occupied := make(map[string]string)
+	for _, level := range parkingLot.Levels {
+		for _, spot := range level.Spots {
+			if isOccupied, license := spot.GetStatus(); isOccupied {
+				occupied[fmt.Sprintf("%d/%d", level.Index, spot.ID)] = license
+			}
+		}
+	}
+	return occupied
+}
+
+// freeSpots lists the free spot indices of each type on each level, sorted
+func freeSpots(parkingLot *ParkingLot) string {
+	var free []string
+	for _, level := range parkingLot.Levels {
+		for _, spotType := range []SpotType{SpotTypeMotorcycle, SpotTypeCompact, SpotTypeLarge, SpotTypeElectric, SpotTypeHandicap} {
+			indices := append([]int(nil), level.GetFreeSpotIndices(spotType)...)
+			sort.Ints(indices)
+			free = append(free, fmt.Sprintf("%d/%s:%v", level.Index, spotType, indices))
+		}
+	}
+	return fmt.Sprint(free)
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	registry := NewSubscriptionRegistry()
+	registry.AddMonthly("SNAP001", time.Now().Add(-time.Hour))
+
+	policies := []struct {
+		tag    string
+		policy PricingPolicy
+	}{
+		{"standard", NewStandardPricingPolicy()},
+		{"premium", NewPremiumPricingPolicy(1.5)},
+		{"dynamic", NewDynamicPricingPolicy()},
+		{"subscription", NewSubscriptionPricingPolicy(registry)},
+	}
+	for _, p := range policies {
+		t.Run(p.tag, func(t *testing.T) {
+			parkingLot := NewParkingLot("Snapshot Test", []*ParkingLevel{
+				NewParkingLevel(0, 1, 2, 1, 1, 0),
+				NewParkingLevel(1, 0, 2, 0, 0, 1),
+			})
+			parkingLot.SetPricingPolicy(p.policy)
+			for _, v := range []struct {
+				license string
+				vType   VehicleType
+			}{{"SNAP001", VehicleTypeCar}, {"SNAP002", VehicleTypeMotorcycle}, {"SNAP003", VehicleTypeElectric}, {"SNAP004", VehicleTypeCar}} {
+				vehicle, _ := NewVehicle(v.license, v.vType)
+				if _, err := parkingLot.ParkVehicle(vehicle); err != nil {
+					t.Fatal(err)
+				}
+			}
+			start := time.Now().Add(time.Hour)
+			if _, err := parkingLot.Reserve(VehicleTypeCar, TimeRange{Start: start, End: start.Add(time.Hour)}); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := parkingLot.Snapshot()
+			if err != nil {
+				t.Fatal(err)
+			}
+			restored, err := RestoreParkingLot(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := fmt.Sprint(occupancy(restored)), fmt.Sprint(occupancy(parkingLot)); got != want {
+				t.Errorf("occupied spots = %s, want %s", got, want)
+			}
+			if got, want := freeSpots(restored), freeSpots(parkingLot); got != want {
+				t.Errorf("free spots = %s, want %s", got, want)
+			}
+			for _, ticket := range parkingLot.GetActiveTickets() {
+				got := restored.GetTicketForVehicle(ticket.LicensePlate)
+				if got == nil || got.ID != ticket.ID || got.LevelIndex != ticket.LevelIndex ||
+					got.SpotID != ticket.SpotID || !got.EntryTime.Equal(ticket.EntryTime) {
+					t.Errorf("restored ticket %+v, want %+v", got, ticket)
+				}
+			}
+			if got := len(restored.GetReservations()); got != 1 {
+				t.Errorf("restored %d reservations, want 1", got)
+			}
+
+			pricing, err := snapshotPricing(restored.PricingPolicy)
+			if err != nil || pricing.Type != p.tag {
+				t.Errorf("restored pricing type %q (%v), want %q", pricing.Type, err, p.tag)
+			}
+			again, err := restored.Snapshot()
+			if err != nil || string(again) != string(data) {
+				t.Errorf("snapshot of the restored lot differs from the original (err %v)", err)
+			}
+
+			// Tickets issued before the restart are honored at the same price
+			ticket := parkingLot.GetTicketForVehicle("SNAP004")
+			exit := time.Now().Add(3 * time.Hour)
+			want := parkingLot.calculateFee(ticket, exit, 0).Total
+			if got := restored.calculateFee(ticket, exit, 0).Total; got != want {
+				t.Errorf("restored fee = %.2f, want %.2f", got, want)
+			}
+			if _, err := restored.UnparkVehicle(ticket); err != nil {
+				t.Errorf("UnparkVehicle after restore: %v", err)
+			}
+		})
+	}
+}
+
+func TestRestoreRejectsBadSnapshots(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not JSON", "not a snapshot"},
+		{"future version", `{"version": 2}`},
+		{"unknown pricing", `{"version": 1, "pricing": {"type": "auction"}}`},
+		{"unknown allocation strategy", `{"version": 1, "pricing": {"type": "standard"}, "allocation_strategy": "random"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := RestoreParkingLot([]byte(tt.data)); err == nil {
+				t.Error("RestoreParkingLot succeeded")
+			}
+		})
+	}
+}
@@ -3,24 +3,38 @@ package main
 import (
 	"fmt"
 	"sync"
+	"time"
 )
 
+// DefaultChargerPowerKW is the output of the charger fitted to electric spots (a Level 2 charger)
+const DefaultChargerPowerKW = 7.2
+
 // ParkingSpot represents a single parking spot
 type ParkingSpot struct {
-	mu                   sync.RWMutex
-	ID                   int      `json:"id"`
-	Type                 SpotType `json:"type"`
-	IsOccupied           bool     `json:"is_occupied"`
-	CurrentVehicleLicense string   `json:"current_vehicle_license,omitempty"`
+	mu                    sync.RWMutex
+	ID                    int       `json:"id"`
+	Type                  SpotType  `json:"type"`
+	IsOccupied            bool      `json:"is_occupied"`
+	CurrentVehicleLicense string    `json:"current_vehicle_license,omitempty"`
+	Distance              float64   `json:"distance"` // from the level entrance, in spots
+	ChargerPowerKW        float64   `json:"charger_power_kw,omitempty"`
+	IsCharging            bool      `json:"is_charging,omitempty"`
+	ChargingStartedAt     time.Time `json:"charging_started_at,omitempty"`
+	EnergyDeliveredKWh    float64   `json:"energy_delivered_kwh,omitempty"` // for the current vehicle
 }
 
-// NewParkingSpot creates a new parking spot
-func NewParkingSpot(id int, spotType SpotType) *ParkingSpot {
-	return &ParkingSpot{
+// NewParkingSpot creates a new parking spot at the given distance from the level entrance
+func NewParkingSpot(id int, spotType SpotType, distance float64) *ParkingSpot {
+	spot := &ParkingSpot{
 		ID:         id,
 		Type:       spotType,
 		IsOccupied: false,
+		Distance:   distance,
+	}
+	if spotType == SpotTypeElectric {
+		spot.ChargerPowerKW = DefaultChargerPowerKW
 	}
+	return spot
 }
 
 // Occupy marks the spot as occupied by a vehicle
@@ -54,9 +68,75 @@ func (ps *ParkingSpot) Vacate() error {
 	
 	ps.IsOccupied = false
 	ps.CurrentVehicleLicense = ""
+	ps.IsCharging = false
+	ps.ChargingStartedAt = time.Time{}
+	ps.EnergyDeliveredKWh = 0
 	return nil
 }
 
+// StartCharging begins a charging session for the vehicle in an electric spot
+func (ps *ParkingSpot) StartCharging() error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	
+	if ps.Type != SpotTypeElectric {
+		return &ParkingError{
+			Op:  "start_charging",
+			Msg: fmt.Sprintf("spot %d has no charger", ps.ID),
+		}
+	}
+	if !ps.IsOccupied {
+		return &ParkingError{
+			Op:  "start_charging",
+			Msg: fmt.Sprintf("spot %d is not occupied", ps.ID),
+		}
+	}
+	if ps.IsCharging {
+		return &ParkingError{
+			Op:  "start_charging",
+			Msg: fmt.Sprintf("spot %d is already charging", ps.ID),
+		}
+	}
+	
+	ps.IsCharging = true
+	ps.ChargingStartedAt = time.Now()
+	return nil
+}
+
+// StopCharging ends the current charging session and returns the kWh it delivered.
+// Energy accumulates across sessions until the vehicle leaves.
+func (ps *ParkingSpot) StopCharging() (float64, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	
+	if !ps.IsCharging {
+		return 0, &ParkingError{
+			Op:  "stop_charging",
+			Msg: fmt.Sprintf("spot %d is not charging", ps.ID),
+		}
+	}
+	
+	delivered := ps.ChargerPowerKW * time.Since(ps.ChargingStartedAt).Hours()
+	ps.EnergyDeliveredKWh += delivered
+	ps.IsCharging = false
+	ps.ChargingStartedAt = time.Time{}
+	return delivered, nil
+}
+
+// IsChargingActive reports whether a charging session is in progress (thread-safe)
+func (ps *ParkingSpot) IsChargingActive() bool {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return ps.IsCharging
+}
+
+// GetEnergyDelivered returns the kWh delivered to the current vehicle (thread-safe)
+func (ps *ParkingSpot) GetEnergyDelivered() float64 {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return ps.EnergyDeliveredKWh
+}
+
 // GetStatus returns the current status of the spot (thread-safe)
 func (ps *ParkingSpot) GetStatus() (bool, string) {
 	ps.mu.RLock()
@@ -64,6 +144,13 @@ func (ps *ParkingSpot) GetStatus() (bool, string) {
 	return ps.IsOccupied, ps.CurrentVehicleLicense
 }
 
+// GetDistance returns the spot's distance from the level entrance (thread-safe)
+func (ps *ParkingSpot) GetDistance() float64 {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return ps.Distance
+}
+
 // GetInfo returns spot information (thread-safe)
 func (ps *ParkingSpot) GetInfo() (int, SpotType) {
 	ps.mu.RLock()
@@ -79,5 +166,8 @@ func (ps *ParkingSpot) String() string {
 	if ps.IsOccupied {
 		status = fmt.Sprintf("Occupied by %s", ps.CurrentVehicleLicense)
 	}
+	if ps.IsCharging {
+		status += " (charging)"
+	}
 	return fmt.Sprintf("Spot %d (%s): %s", ps.ID, ps.Type, status)
 }
\ No newline at end of file
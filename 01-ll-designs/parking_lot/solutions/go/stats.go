@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SpotOccupancy is the occupancy of a group of spots
+type SpotOccupancy struct {
+	Total    int     `json:"total"`
+	Occupied int     `json:"occupied"`
+	Percent  float64 `json:"percent"`
+}
+
+// LevelOccupancy is the occupancy of one level, overall and by spot type
+type LevelOccupancy struct {
+	Index int `json:"index"`
+	SpotOccupancy
+	BySpotType map[string]SpotOccupancy `json:"by_spot_type"`
+}
+
+// OccupancyStats is a point-in-time view of lot occupancy plus counters
+// accumulated since the lot was created (or restored)
+type OccupancyStats struct {
+	Timestamp time.Time `json:"timestamp"`
+	Since     time.Time `json:"since"`
+	SpotOccupancy
+	BySpotType          map[string]SpotOccupancy `json:"by_spot_type"`
+	Levels              []LevelOccupancy         `json:"levels"`
+	PeakOccupied        int                      `json:"peak_occupied"`
+	PeakPercent         float64                  `json:"peak_percent"`
+	PeakAt              time.Time                `json:"peak_at"`
+	Entries             int                      `json:"entries"`
+	Exits               int                      `json:"exits"`
+	AverageDwell        time.Duration            `json:"-"`
+	AverageDwellSeconds float64                  `json:"average_dwell_seconds"`
+}
+
+// occupancyCounters tracks occupancy incrementally so OccupancyStats never
+// has to scan spots. Guarded by the lot lock.
+type occupancyCounters struct {
+	since      time.Time
+	capacity   map[int]map[SpotType]int // level index -> spot type -> spots
+	occupied   map[int]map[SpotType]int
+	total      int
+	inUse      int
+	peak       int
+	peakAt     time.Time
+	entries    int
+	exits      int
+	totalDwell time.Duration
+}
+
+// newOccupancyCounters counts capacity, and any spots already occupied (as
+// after a restore), with one scan of the levels
+func newOccupancyCounters(levels []*ParkingLevel) *occupancyCounters {
+	counters := &occupancyCounters{
+		since:    time.Now(),
+		capacity: make(map[int]map[SpotType]int),
+		occupied: make(map[int]map[SpotType]int),
+	}
+	for _, level := range levels {
+		counters.capacity[level.Index] = make(map[SpotType]int)
+		counters.occupied[level.Index] = make(map[SpotType]int)
+		for _, spot := range level.Spots {
+			_, spotType := spot.GetInfo()
+			counters.capacity[level.Index][spotType]++
+			counters.total++
+			if isOccupied, _ := spot.GetStatus(); isOccupied {
+				counters.occupied[level.Index][spotType]++
+				counters.inUse++
+			}
+		}
+	}
+	counters.peak, counters.peakAt = counters.inUse, counters.since
+	return counters
+}
+
+// recordEntry counts a vehicle taking its spots (must be called with lock held)
+func (pl *ParkingLot) recordEntry(ticket *Ticket) {
+	counters := pl.occupancy
+	spots := len(ticket.AllSpotIDs())
+	counters.occupied[ticket.LevelIndex][ticket.SpotType] += spots
+	counters.inUse += spots
+	counters.entries++
+	if counters.inUse > counters.peak {
+		counters.peak, counters.peakAt = counters.inUse, time.Now()
+	}
+}
+
+// recordExit counts a vehicle leaving its spots (must be called with lock held)
+func (pl *ParkingLot) recordExit(ticket *Ticket, exitTime time.Time) {
+	counters := pl.occupancy
+	spots := len(ticket.AllSpotIDs())
+	counters.occupied[ticket.LevelIndex][ticket.SpotType] -= spots
+	counters.inUse -= spots
+	counters.exits++
+	counters.totalDwell += exitTime.Sub(ticket.EntryTime)
+}
+
+// OccupancyStats returns current occupancy by level and spot type, the peak
+// occupancy since the lot was created, and entry/exit counts with the average
+// dwell time of completed stays. It runs in O(levels) from counters kept up to
+// date on every park and unpark.
+func (pl *ParkingLot) OccupancyStats() *OccupancyStats {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+
+	counters := pl.occupancy
+	stats := &OccupancyStats{
+		Timestamp:     time.Now(),
+		Since:         counters.since,
+		SpotOccupancy: newSpotOccupancy(counters.total, counters.inUse),
+		BySpotType:    make(map[string]SpotOccupancy),
+		Levels:        make([]LevelOccupancy, 0, len(pl.Levels)),
+		PeakOccupied:  counters.peak,
+		PeakPercent:   percent(counters.peak, counters.total),
+		PeakAt:        counters.peakAt,
+		Entries:       counters.entries,
+		Exits:         counters.exits,
+	}
+	if counters.exits > 0 {
+		stats.AverageDwell = counters.totalDwell / time.Duration(counters.exits)
+		stats.AverageDwellSeconds = stats.AverageDwell.Seconds()
+	}
+
+	totalByType := make(map[SpotType]int)
+	occupiedByType := make(map[SpotType]int)
+	for _, level := range pl.Levels {
+		levelStats := LevelOccupancy{Index: level.Index, BySpotType: make(map[string]SpotOccupancy)}
+		levelTotal, levelOccupied := 0, 0
+		for spotType, capacity := range counters.capacity[level.Index] {
+			occupied := counters.occupied[level.Index][spotType]
+			levelStats.BySpotType[spotType.String()] = newSpotOccupancy(capacity, occupied)
+			levelTotal += capacity
+			levelOccupied += occupied
+			totalByType[spotType] += capacity
+			occupiedByType[spotType] += occupied
+		}
+		levelStats.SpotOccupancy = newSpotOccupancy(levelTotal, levelOccupied)
+		stats.Levels = append(stats.Levels, levelStats)
+	}
+	for spotType, capacity := range totalByType {
+		stats.BySpotType[spotType.String()] = newSpotOccupancy(capacity, occupiedByType[spotType])
+	}
+
+	return stats
+}
+
+// JSON returns the stats encoded for a monitoring endpoint
+func (s *OccupancyStats) JSON() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// newSpotOccupancy builds a SpotOccupancy from counts
+func newSpotOccupancy(total, occupied int) SpotOccupancy {
+	return SpotOccupancy{Total: total, Occupied: occupied, Percent: percent(occupied, total)}
+}
+
+// percent returns part as a percentage of whole, or 0 for an empty whole
+func percent(part, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(whole)
+}
+
+// DemoOccupancyStats demonstrates occupancy statistics for a monitoring endpoint
+func DemoOccupancyStats() {
+	fmt.Println("=== Occupancy Statistics Demo ===")
+
+	parkingLot := NewParkingLot("Stats Demo", []*ParkingLevel{
+		NewParkingLevel(0, 1, 2, 1, 0, 0),
+		NewParkingLevel(1, 0, 2, 0, 0, 0),
+	})
+
+	var tickets []*Ticket
+	for i, vehicleType := range []VehicleType{VehicleTypeCar, VehicleTypeCar, VehicleTypeMotorcycle, VehicleTypeCar} {
+		vehicle, _ := NewVehicle(fmt.Sprintf("STAT%03d", i+1), vehicleType)
+		if ticket, err := parkingLot.ParkVehicle(vehicle); err == nil {
+			tickets = append(tickets, ticket)
+		}
+	}
+	parkingLot.UnparkVehicle(tickets[0])
+	parkingLot.UnparkVehicle(tickets[1])
+
+	stats := parkingLot.OccupancyStats()
+	fmt.Printf("Lot: %d/%d occupied (%.1f%%), peak %d (%.1f%%)\n",
+		stats.Occupied, stats.Total, stats.Percent, stats.PeakOccupied, stats.PeakPercent)
+	for _, level := range stats.Levels {
+		fmt.Printf("  Level %d: %.1f%% occupied, compact %.1f%%\n",
+			level.Index, level.Percent, level.BySpotType[SpotTypeCompact.String()].Percent)
+	}
+	fmt.Printf("Entries: %d, Exits: %d, Average dwell: %s\n", stats.Entries, stats.Exits, stats.AverageDwell)
+
+	if data, err := stats.JSON(); err == nil {
+		fmt.Printf("JSON: %d bytes\n", len(data))
+	}
+}
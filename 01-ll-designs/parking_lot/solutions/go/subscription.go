@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VehiclePricingPolicy is implemented by pricing policies whose fee depends on
+// which vehicle parked, not just its type. The lot uses CalculateVehicleFee
+// instead of CalculateFeeDetailed when its policy provides it.
+type VehiclePricingPolicy interface {
+	PricingPolicy
+	CalculateVehicleFee(licensePlate string, vehicleType VehicleType, entryTime, exitTime time.Time, energyKWh float64) *FeeBreakdown
+}
+
+// Pass is a prepaid subscription letting a vehicle park without hourly
+// charges while it is valid
+type Pass struct {
+	ID           string    `json:"id"`
+	LicensePlate string    `json:"license_plate"`
+	Valid        TimeRange `json:"valid"`
+}
+
+// SubscriptionRegistry tracks the passes held by license plate. Each plate has
+// at most one pass; adding another replaces it, which is how a pass is renewed.
+type SubscriptionRegistry struct {
+	mu     sync.RWMutex
+	passes map[string]*Pass
+	nextID int
+}
+
+// NewSubscriptionRegistry creates an empty registry
+func NewSubscriptionRegistry() *SubscriptionRegistry {
+	return &SubscriptionRegistry{passes: make(map[string]*Pass)}
+}
+
+// Add issues a pass for licensePlate valid during window
+func (sr *SubscriptionRegistry) Add(licensePlate string, window TimeRange) (*Pass, error) {
+	licensePlate = strings.TrimSpace(strings.ToUpper(licensePlate))
+	if licensePlate == "" {
+		return nil, &ParkingError{Op: "subscribe", Msg: "license plate cannot be empty"}
+	}
+	if !window.End.After(window.Start) {
+		return nil, &ParkingError{Op: "subscribe", Msg: "pass must end after it starts"}
+	}
+
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	sr.nextID++
+	pass := &Pass{
+		ID:           fmt.Sprintf("PASS-%d", sr.nextID),
+		LicensePlate: licensePlate,
+		Valid:        window,
+	}
+	sr.passes[licensePlate] = pass
+	return pass, nil
+}
+
+// AddMonthly issues a pass for licensePlate valid for one calendar month from start
+func (sr *SubscriptionRegistry) AddMonthly(licensePlate string, start time.Time) (*Pass, error) {
+	return sr.Add(licensePlate, TimeRange{Start: start, End: start.AddDate(0, 1, 0)})
+}
+
+// Remove cancels the pass held by licensePlate
+func (sr *SubscriptionRegistry) Remove(licensePlate string) error {
+	licensePlate = strings.TrimSpace(strings.ToUpper(licensePlate))
+
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	if _, exists := sr.passes[licensePlate]; !exists {
+		return &ParkingError{
+			Op:  "unsubscribe",
+			Msg: fmt.Sprintf("vehicle %s has no pass", licensePlate),
+		}
+	}
+	delete(sr.passes, licensePlate)
+	return nil
+}
+
+// PassFor returns the pass held by licensePlate, valid or not, or nil
+func (sr *SubscriptionRegistry) PassFor(licensePlate string) *Pass {
+	licensePlate = strings.TrimSpace(strings.ToUpper(licensePlate))
+
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+	return sr.passes[licensePlate]
+}
+
+// ActivePass returns the pass held by licensePlate if it is valid at t, or nil
+func (sr *SubscriptionRegistry) ActivePass(licensePlate string, t time.Time) *Pass {
+	pass := sr.PassFor(licensePlate)
+	if pass == nil || t.Before(pass.Valid.Start) || !t.Before(pass.Valid.End) {
+		return nil
+	}
+	return pass
+}
+
+// Passes returns every pass, ordered by ID
+func (sr *SubscriptionRegistry) Passes() []*Pass {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	passes := make([]*Pass, 0, len(sr.passes))
+	for _, pass := range sr.passes {
+		passes = append(passes, pass)
+	}
+	sort.Slice(passes, func(i, j int) bool {
+		return passIDNumber(passes[i].ID) < passIDNumber(passes[j].ID)
+	})
+	return passes
+}
+
+// restorePasses loads passes from a snapshot, continuing the ID sequence
+// after the highest one
+func (sr *SubscriptionRegistry) restorePasses(passes []*Pass) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	for _, pass := range passes {
+		sr.passes[pass.LicensePlate] = pass
+		if n := passIDNumber(pass.ID); n > sr.nextID {
+			sr.nextID = n
+		}
+	}
+}
+
+// passIDNumber returns the sequence number in a "PASS-n" ID
+func passIDNumber(id string) int {
+	var n int
+	fmt.Sscanf(id, "PASS-%d", &n)
+	return n
+}
+
+// SubscriptionPricingPolicy charges nothing for parking while a vehicle holds
+// a valid pass and prices everyone else with the embedded standard policy.
+// Energy is not covered by a pass and is always billed.
+type SubscriptionPricingPolicy struct {
+	*StandardPricingPolicy
+	Registry *SubscriptionRegistry
+}
+
+// NewSubscriptionPricingPolicy creates a subscription policy over registry
+// with standard rates for walk-ups
+func NewSubscriptionPricingPolicy(registry *SubscriptionRegistry) *SubscriptionPricingPolicy {
+	return &SubscriptionPricingPolicy{
+		StandardPricingPolicy: NewStandardPricingPolicy(),
+		Registry:              registry,
+	}
+}
+
+// CalculateVehicleFee prices a stay for a specific vehicle. A stay entirely
+// inside the vehicle's pass costs nothing beyond energy. When the pass starts
+// or expires during the stay, only the hours outside it are charged. The
+// receipt shows the standard fee with the pass as a discount line.
+func (spp *SubscriptionPricingPolicy) CalculateVehicleFee(licensePlate string, vehicleType VehicleType, entryTime, exitTime time.Time, energyKWh float64) *FeeBreakdown {
+	breakdown := spp.StandardPricingPolicy.calculate(vehicleType, entryTime, exitTime, energyKWh)
+
+	pass := spp.Registry.PassFor(licensePlate)
+	if pass == nil || !pass.Valid.Overlaps(TimeRange{Start: entryTime, End: exitTime}) {
+		return spp.finalize(breakdown)
+	}
+
+	// Charge the uncovered time as one stay of that length
+	uncovered := exitTime.Sub(entryTime) - overlap(pass.Valid, TimeRange{Start: entryTime, End: exitTime})
+	due := 0.0
+	if uncovered > 0 {
+		uncoveredFee := spp.StandardPricingPolicy.calculate(vehicleType, entryTime, entryTime.Add(uncovered), 0)
+		due = uncoveredFee.BaseFee + uncoveredFee.HourlyCharge
+	}
+
+	if discount := breakdown.BaseFee + breakdown.HourlyCharge - due; discount > 0 {
+		breakdown.AddSurcharge("Monthly pass "+pass.ID, -discount)
+	}
+	return spp.finalize(breakdown)
+}
+
+// overlap returns how long two time ranges share
+func overlap(a, b TimeRange) time.Duration {
+	start, end := a.Start, a.End
+	if b.Start.After(start) {
+		start = b.Start
+	}
+	if b.End.Before(end) {
+		end = b.End
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// calculateFee prices a ticket with the lot's pricing policy, passing the
+// license plate to policies that use it (must be called with lock held)
+func (pl *ParkingLot) calculateFee(ticket *Ticket, exitTime time.Time, energyKWh float64) *FeeBreakdown {
+	if policy, ok := pl.PricingPolicy.(VehiclePricingPolicy); ok {
+		return policy.CalculateVehicleFee(ticket.LicensePlate, ticket.VehicleType, ticket.EntryTime, exitTime, energyKWh)
+	}
+	return pl.PricingPolicy.CalculateFeeDetailed(ticket.VehicleType, ticket.EntryTime, exitTime, energyKWh)
+}
+
+// DemoSubscriptions parks pass holders and walk-ups in the same lot and
+// compares what they pay
+func DemoSubscriptions() {
+	fmt.Println("=== Monthly Pass Demo ===")
+
+	now := time.Now()
+	registry := NewSubscriptionRegistry()
+	registry.AddMonthly("COMMUTER1", now.AddDate(0, 0, -10))
+	registry.AddMonthly("LAPSED01", now.AddDate(0, -2, 0)) // expired last month
+
+	parkingLot := NewParkingLot("Office Garage", []*ParkingLevel{NewParkingLevel(0, 0, 4, 0, 0, 0)})
+	parkingLot.SetPricingPolicy(NewSubscriptionPricingPolicy(registry))
+
+	var tickets []*Ticket
+	for _, license := range []string{"COMMUTER1", "LAPSED01", "VISITOR1"} {
+		vehicle, _ := NewVehicle(license, VehicleTypeCar)
+		ticket, err := parkingLot.ParkVehicle(vehicle)
+		if err != nil {
+			fmt.Printf("✗ %s: %v\n", license, err)
+			continue
+		}
+		tickets = append(tickets, ticket)
+	}
+
+	// Pass holders still get tickets, so they count toward occupancy
+	stats := parkingLot.OccupancyStats()
+	fmt.Printf("Occupied: %d/%d\n", stats.Occupied, stats.Total)
+
+	for _, ticket := range tickets {
+		receipt, err := parkingLot.UnparkVehicle(ticket)
+		if err != nil {
+			fmt.Printf("✗ %s: %v\n", ticket.LicensePlate, err)
+			continue
+		}
+		pass := "no pass"
+		if active := registry.ActivePass(ticket.LicensePlate, now); active != nil {
+			pass = active.ID
+		} else if registry.PassFor(ticket.LicensePlate) != nil {
+			pass = "expired pass"
+		}
+		fmt.Printf("%-10s (%s): $%.2f\n", ticket.LicensePlate, pass, receipt.Total)
+	}
+
+	// A pass that expires partway through a stay covers only its part
+	policy := NewSubscriptionPricingPolicy(registry)
+	commuter := registry.PassFor("COMMUTER1")
+	entry := commuter.Valid.End.Add(-2 * time.Hour)
+	fmt.Printf("COMMUTER1 parked 5 hours, pass expiring after 2:\n%s\n",
+		policy.CalculateVehicleFee("COMMUTER1", VehicleTypeCar, entry, entry.Add(5*time.Hour), 0))
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPassHoldersAndWalkUpsShareALot(t *testing.T) {
+	now := time.Now()
+	registry := NewSubscriptionRegistry()
+	registry.AddMonthly("COMMUTER1", now.AddDate(0, 0, -10))
+	registry.AddMonthly("LAPSED01", now.AddDate(0, -2, 0)) // expired last month
+
+	parkingLot := NewParkingLot("Office Garage", []*ParkingLevel{NewParkingLevel(0, 0, 4, 0, 0, 0)})
+	parkingLot.SetPricingPolicy(NewSubscriptionPricingPolicy(registry))
+
+	plates := []string{"COMMUTER1", "VISITOR1", "LAPSED01", "VISITOR2"}
+	tickets := make(map[string]*Ticket)
+	for _, plate := range plates {
+		vehicle, _ := NewVehicle(plate, VehicleTypeCar)
+		ticket, err := parkingLot.ParkVehicle(vehicle)
+		if err != nil {
+			t.Fatalf("ParkVehicle(%s): %v", plate, err)
+		}
+		// Back-date entry so the stay bills as three hours
+		ticket.EntryTime = now.Add(-3*time.Hour + time.Minute)
+		tickets[plate] = ticket
+	}
+
+	// Pass holders take spots like anyone else
+	if stats := parkingLot.OccupancyStats(); stats.Occupied != 4 {
+		t.Errorf("occupied %d/%d, want 4", stats.Occupied, stats.Total)
+	}
+	overflow, _ := NewVehicle("VISITOR3", VehicleTypeCar)
+	if _, err := parkingLot.ParkVehicle(overflow); err == nil {
+		t.Error("a fifth car parked in a full lot")
+	}
+
+	// $2.00 base fee plus 3 hours at $1.00 unless a valid pass covers the stay
+	want := map[string]float64{"COMMUTER1": 0, "VISITOR1": 5, "LAPSED01": 5, "VISITOR2": 5}
+	for _, plate := range plates {
+		receipt, err := parkingLot.UnparkVehicle(tickets[plate])
+		if err != nil {
+			t.Fatalf("UnparkVehicle(%s): %v", plate, err)
+		}
+		if receipt.Total != want[plate] {
+			t.Errorf("%s paid %.2f, want %.2f", plate, receipt.Total, want[plate])
+		}
+	}
+}
+
+func TestPassCoversOnlyItsPartOfAStay(t *testing.T) {
+	registry := NewSubscriptionRegistry()
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	pass, err := registry.AddMonthly("COMMUTER1", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := NewSubscriptionPricingPolicy(registry)
+
+	tests := []struct {
+		name        string
+		entry, exit time.Time
+		energyKWh   float64
+		want        float64
+	}{
+		{"inside the pass", start.Add(time.Hour), start.Add(4 * time.Hour), 0, 0},
+		{"energy is always billed", start.Add(time.Hour), start.Add(4 * time.Hour), 10, 3},
+		// Three uncovered hours, priced as one three-hour stay
+		{"pass expires partway", pass.Valid.End.Add(-2 * time.Hour), pass.Valid.End.Add(3 * time.Hour), 0, 2 + 3},
+		{"pass starts partway", start.Add(-time.Hour), start.Add(time.Hour), 0, 2 + 1},
+		{"before the pass", start.Add(-3 * time.Hour), start, 0, 2 + 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.CalculateVehicleFee("COMMUTER1", VehicleTypeCar, tt.entry, tt.exit, tt.energyKWh).Total; got != tt.want {
+				t.Errorf("fee = %.2f, want %.2f", got, tt.want)
+			}
+		})
+	}
+
+	// A walk-up pays the standard fee for the same stay
+	if got := policy.CalculateVehicleFee("VISITOR1", VehicleTypeCar, start.Add(time.Hour), start.Add(4*time.Hour), 0).Total; got != 5 {
+		t.Errorf("walk-up fee = %.2f, want 5.00", got)
+	}
+}
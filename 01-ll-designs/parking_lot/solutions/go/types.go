@@ -9,6 +9,7 @@ const (
 	VehicleTypeMotorcycle VehicleType = iota
 	VehicleTypeCar
 	VehicleTypeBus
+	VehicleTypeElectric
 )
 
 func (vt VehicleType) String() string {
@@ -19,6 +20,8 @@ func (vt VehicleType) String() string {
 		return "Car"
 	case VehicleTypeBus:
 		return "Bus"
+	case VehicleTypeElectric:
+		return "Electric"
 	default:
 		return "Unknown"
 	}
@@ -31,6 +34,8 @@ const (
 	SpotTypeMotorcycle SpotType = iota
 	SpotTypeCompact
 	SpotTypeLarge
+	SpotTypeElectric
+	SpotTypeHandicap
 )
 
 func (st SpotType) String() string {
@@ -41,6 +46,10 @@ func (st SpotType) String() string {
 		return "Compact"
 	case SpotTypeLarge:
 		return "Large"
+	case SpotTypeElectric:
+		return "Electric"
+	case SpotTypeHandicap:
+		return "Handicap"
 	default:
 		return "Unknown"
 	}
@@ -64,10 +73,72 @@ func (e *ParkingError) Unwrap() error {
 	return e.Err
 }
 
+// NoSpotError reports that no free spot compatible with a vehicle type
+// exists. It matches ErrNoAvailableSpots with errors.Is.
+type NoSpotError struct {
+	VehicleType VehicleType
+}
+
+func (e *NoSpotError) Error() string {
+	return fmt.Sprintf("parking find_spot: no free spot fits a %s", e.VehicleType)
+}
+
+func (e *NoSpotError) Unwrap() error {
+	return ErrNoAvailableSpots
+}
+
+// NoContiguousSpotsError reports that no level has enough adjacent free
+// spots for a vehicle that takes several. It matches ErrNoAvailableSpots with
+// errors.Is.
+type NoContiguousSpotsError struct {
+	VehicleType VehicleType
+	Spots       int
+}
+
+func (e *NoContiguousSpotsError) Error() string {
+	return fmt.Sprintf("parking find_spot: no %d adjacent free spots fit a %s", e.Spots, e.VehicleType)
+}
+
+func (e *NoContiguousSpotsError) Unwrap() error {
+	return ErrNoAvailableSpots
+}
+
+// AlreadyParkedError reports that a vehicle arriving at the lot already holds
+// an active ticket. It matches ErrVehicleAlreadyParked with errors.Is.
+type AlreadyParkedError struct {
+	Op           string
+	LicensePlate string
+}
+
+func (e *AlreadyParkedError) Error() string {
+	return fmt.Sprintf("parking %s: vehicle %s is already parked", e.Op, e.LicensePlate)
+}
+
+func (e *AlreadyParkedError) Unwrap() error {
+	return ErrVehicleAlreadyParked
+}
+
+// InvalidTicketError reports that a presented ticket does not match an active
+// one. It matches ErrInvalidTicket with errors.Is.
+type InvalidTicketError struct {
+	LicensePlate string
+	Msg          string
+}
+
+func (e *InvalidTicketError) Error() string {
+	return fmt.Sprintf("parking unpark: %s", e.Msg)
+}
+
+func (e *InvalidTicketError) Unwrap() error {
+	return ErrInvalidTicket
+}
+
 // Common error variables
 var (
 	ErrVehicleAlreadyParked = &ParkingError{Op: "park", Msg: "vehicle already parked"}
 	ErrNoAvailableSpots     = &ParkingError{Op: "park", Msg: "no available spots"}
 	ErrInvalidTicket        = &ParkingError{Op: "unpark", Msg: "invalid ticket"}
 	ErrSpotNotFound         = &ParkingError{Op: "unpark", Msg: "spot not found"}
+	ErrReservationNotFound  = &ParkingError{Op: "reserve", Msg: "reservation not found"}
+	ErrReservationConflict  = &ParkingError{Op: "reserve", Msg: "all compatible spots are reserved for an overlapping window"}
 )
\ No newline at end of file
@@ -8,8 +8,10 @@ import (
 
 // Vehicle represents a vehicle with license plate and type
 type Vehicle struct {
-	LicensePlate string      `json:"license_plate"`
-	Type         VehicleType `json:"type"`
+	LicensePlate     string      `json:"license_plate"`
+	Type             VehicleType `json:"type"`
+	HandicapEligible bool        `json:"handicap_eligible,omitempty"` // displays a disabled parking permit
+	RequiredSpots    int         `json:"required_spots,omitempty"`    // adjacent spots needed; 0 uses the lot's setting for the type
 }
 
 // NewVehicle creates a new vehicle with validation
@@ -31,13 +33,15 @@ func (v *Vehicle) String() string {
 
 // Ticket represents a parking ticket
 type Ticket struct {
-	ID           string      `json:"id"`
-	LicensePlate string      `json:"license_plate"`
-	VehicleType  VehicleType `json:"vehicle_type"`
-	EntryTime    time.Time   `json:"entry_time"`
-	LevelIndex   int         `json:"level_index"`
-	SpotID       int         `json:"spot_id"`
-	SpotType     SpotType    `json:"spot_type"`
+	ID            string      `json:"id"`
+	LicensePlate  string      `json:"license_plate"`
+	VehicleType   VehicleType `json:"vehicle_type"`
+	EntryTime     time.Time   `json:"entry_time"`
+	LevelIndex    int         `json:"level_index"`
+	SpotID        int         `json:"spot_id"`
+	SpotIDs       []int       `json:"spot_ids,omitempty"` // every spot taken, when the vehicle needs more than one
+	SpotType      SpotType    `json:"spot_type"`
+	ReservationID string      `json:"reservation_id,omitempty"`
 }
 
 // NewTicket creates a new parking ticket
@@ -53,9 +57,21 @@ func NewTicket(licensePlate string, vehicleType VehicleType, levelIndex, spotID
 	}
 }
 
+// AllSpotIDs returns the IDs of every spot the ticket covers
+func (t *Ticket) AllSpotIDs() []int {
+	if len(t.SpotIDs) > 0 {
+		return t.SpotIDs
+	}
+	return []int{t.SpotID}
+}
+
 func (t *Ticket) String() string {
-	return fmt.Sprintf("Ticket %s: %s (%s) at Level %d, Spot %d (entered %s)",
-		t.ID, t.LicensePlate, t.VehicleType, t.LevelIndex, t.SpotID,
+	spots := fmt.Sprintf("Spot %d", t.SpotID)
+	if len(t.SpotIDs) > 1 {
+		spots = fmt.Sprintf("Spots %d-%d", t.SpotIDs[0], t.SpotIDs[len(t.SpotIDs)-1])
+	}
+	return fmt.Sprintf("Ticket %s: %s (%s) at Level %d, %s (entered %s)",
+		t.ID, t.LicensePlate, t.VehicleType, t.LevelIndex, spots,
 		t.EntryTime.Format("15:04:05"))
 }
 
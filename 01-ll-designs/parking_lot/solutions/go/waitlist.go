@@ -0,0 +1,227 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrWaitTicketNotFound is returned when a wait ticket is not on the waitlist
+var ErrWaitTicketNotFound = &ParkingError{Op: "wait", Msg: "wait ticket not found"}
+
+// WaitTicket is a vehicle's place in the overflow waitlist. When a compatible
+// spot frees up the vehicle is parked automatically and its parking ticket is
+// sent on Ready. Ready is closed without a value if the wait is cancelled.
+type WaitTicket struct {
+	ID         string
+	Vehicle    *Vehicle
+	EnqueuedAt time.Time
+	Ready      <-chan *Ticket
+	ready      chan *Ticket
+	seq        int
+}
+
+// String returns a string representation of the wait ticket
+func (wt *WaitTicket) String() string {
+	return fmt.Sprintf("WaitTicket[%s]: %s (%s) waiting since %s",
+		wt.ID, wt.Vehicle.LicensePlate, wt.Vehicle.Type, wt.EnqueuedAt.Format("15:04:05"))
+}
+
+// ParkOrWait parks a vehicle if a compatible spot is free, returning its
+// ticket. When the lot is full for the vehicle's type it joins that type's
+// FIFO waitlist instead and a WaitTicket is returned. Vehicles also queue
+// behind earlier waiters of the same type rather than jumping ahead of them.
+func (pl *ParkingLot) ParkOrWait(vehicle *Vehicle) (*Ticket, *WaitTicket, error) {
+	if vehicle == nil {
+		return nil, nil, &ParkingError{Op: "wait", Msg: "vehicle cannot be nil"}
+	}
+
+	defer pl.flushEvents()
+
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	licensePlate := vehicle.LicensePlate
+	if _, exists := pl.ActiveTickets[licensePlate]; exists {
+		return nil, nil, &AlreadyParkedError{Op: "wait", LicensePlate: licensePlate}
+	}
+	if pl.findWaiter(licensePlate) != nil {
+		return nil, nil, &ParkingError{
+			Op:  "wait",
+			Msg: fmt.Sprintf("vehicle %s is already on the waitlist", licensePlate),
+		}
+	}
+
+	if len(pl.waitlists[vehicle.Type]) == 0 {
+		ticket, err := pl.assignSpot(vehicle)
+		if err == nil {
+			return ticket, nil, nil
+		}
+		if !errors.Is(err, ErrNoAvailableSpots) {
+			return nil, nil, err
+		}
+	}
+
+	pl.nextWait++
+	ready := make(chan *Ticket, 1)
+	waitTicket := &WaitTicket{
+		ID:         fmt.Sprintf("WAIT-%d", pl.nextWait),
+		Vehicle:    vehicle,
+		EnqueuedAt: time.Now(),
+		Ready:      ready,
+		ready:      ready,
+		seq:        pl.nextWait,
+	}
+	pl.waitlists[vehicle.Type] = append(pl.waitlists[vehicle.Type], waitTicket)
+	return nil, waitTicket, nil
+}
+
+// CancelWait removes a vehicle from the waitlist and closes its Ready channel
+func (pl *ParkingLot) CancelWait(ticketID string) error {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	for _, queue := range pl.waitlists {
+		for _, waitTicket := range queue {
+			if waitTicket.ID == ticketID {
+				pl.removeWaiter(waitTicket.Vehicle.LicensePlate)
+				return nil
+			}
+		}
+	}
+	return ErrWaitTicketNotFound
+}
+
+// removeWaiter takes a license plate off the waitlist, if it is there, and
+// closes its Ready channel (must be called with lock held)
+func (pl *ParkingLot) removeWaiter(licensePlate string) {
+	for vehicleType, queue := range pl.waitlists {
+		for i, waitTicket := range queue {
+			if waitTicket.Vehicle.LicensePlate != licensePlate {
+				continue
+			}
+			pl.waitlists[vehicleType] = append(queue[:i:i], queue[i+1:]...)
+			close(waitTicket.ready)
+			return
+		}
+	}
+}
+
+// WaitlistLength returns how many vehicles of a type are waiting for a spot
+func (pl *ParkingLot) WaitlistLength(vehicleType VehicleType) int {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+	return len(pl.waitlists[vehicleType])
+}
+
+// findWaiter returns the wait ticket for a license plate, or nil
+// (must be called with lock held)
+func (pl *ParkingLot) findWaiter(licensePlate string) *WaitTicket {
+	for _, queue := range pl.waitlists {
+		for _, waitTicket := range queue {
+			if waitTicket.Vehicle.LicensePlate == licensePlate {
+				return waitTicket
+			}
+		}
+	}
+	return nil
+}
+
+// serveWaitlist parks waiting vehicles into free spots (must be called with
+// lock held). Each round it looks at the head of every vehicle type's queue
+// and serves the longest-waiting head that has a compatible spot free. A head
+// that cannot fit never blocks other types, and because the oldest eligible
+// waiter always goes first, a newly freed spot goes to whoever has waited
+// longest for it, so no type can starve another.
+func (pl *ParkingLot) serveWaitlist() {
+	for {
+		var next *WaitTicket
+		for _, queue := range pl.waitlists {
+			if len(queue) == 0 || !pl.hasAvailableSpot(queue[0].Vehicle) {
+				continue
+			}
+			if next == nil || queue[0].seq < next.seq {
+				next = queue[0]
+			}
+		}
+		if next == nil {
+			return
+		}
+
+		vehicleType := next.Vehicle.Type
+		pl.waitlists[vehicleType] = pl.waitlists[vehicleType][1:]
+
+		ticket, err := pl.assignSpot(next.Vehicle)
+		if err != nil {
+			// Put the waiter back at the front and wait for the next free spot
+			pl.waitlists[vehicleType] = append([]*WaitTicket{next}, pl.waitlists[vehicleType]...)
+			return
+		}
+		next.ready <- ticket
+		close(next.ready)
+	}
+}
+
+// hasAvailableSpot reports whether any level has a spot free that the vehicle
+// may use, or enough adjacent ones if it needs several (must be called with
+// lock held)
+func (pl *ParkingLot) hasAvailableSpot(vehicle *Vehicle) bool {
+	count := pl.requiredSpots(vehicle)
+	for _, spotTypes := range pl.spotTypeTiers(vehicle) {
+		for _, level := range pl.Levels {
+			if count == 1 && level.HasAvailableSpot(spotTypes) {
+				return true
+			}
+			if count > 1 && level.HasContiguousSpots(spotTypes, count) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DemoWaitlist demonstrates vehicles queueing for a full lot and being
+// parked automatically as spots free up
+func DemoWaitlist() {
+	fmt.Println("=== Waitlist Demo ===")
+
+	parkingLot := NewParkingLot("Waitlist Demo", []*ParkingLevel{NewParkingLevel(0, 0, 1, 1, 0, 0)})
+
+	var tickets []*Ticket
+	var waiters []*WaitTicket
+	for _, v := range []struct {
+		license string
+		vType   VehicleType
+	}{{"WAIT001", VehicleTypeCar}, {"WAIT002", VehicleTypeCar}, {"WAIT003", VehicleTypeBus}, {"WAIT004", VehicleTypeCar}} {
+		vehicle, _ := NewVehicle(v.license, v.vType)
+		ticket, waitTicket, err := parkingLot.ParkOrWait(vehicle)
+		switch {
+		case err != nil:
+			fmt.Printf("✗ %s: %v\n", v.license, err)
+		case ticket != nil:
+			tickets = append(tickets, ticket)
+			fmt.Printf("✓ %s parked at Level %d, Spot %d\n", v.license, ticket.LevelIndex, ticket.SpotID)
+		default:
+			waiters = append(waiters, waitTicket)
+			fmt.Printf("… %s\n", waitTicket)
+		}
+	}
+	fmt.Printf("Waiting: %d cars, %d buses\n",
+		parkingLot.WaitlistLength(VehicleTypeCar), parkingLot.WaitlistLength(VehicleTypeBus))
+
+	// The last car gives up; the bus keeps its place in line
+	parkingLot.CancelWait(waiters[len(waiters)-1].ID)
+
+	// The large spot frees first, so the bus (the oldest waiter that fits) gets it
+	parkingLot.UnparkVehicle(tickets[1])
+	for _, waiter := range waiters {
+		if ticket, ok := <-waiter.Ready; ok {
+			fmt.Printf("✓ %s auto-parked at Level %d, Spot %d (%s)\n",
+				waiter.Vehicle.LicensePlate, ticket.LevelIndex, ticket.SpotID, ticket.SpotType)
+		} else {
+			fmt.Printf("  %s left the waitlist\n", waiter.Vehicle.LicensePlate)
+		}
+	}
+	fmt.Printf("Waiting: %d cars, %d buses\n",
+		parkingLot.WaitlistLength(VehicleTypeCar), parkingLot.WaitlistLength(VehicleTypeBus))
+}
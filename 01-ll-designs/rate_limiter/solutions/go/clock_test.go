@@ -0,0 +1,131 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketRefillWithFakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	bucket, err := NewTokenBucket(4, 2.0, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for bucket.AllowSingleRequest() {
+	}
+	if wait := bucket.GetTimeUntilNextAllowedRequest(); wait != 500*time.Millisecond {
+		t.Errorf("wait when drained = %v, want 500ms", wait)
+	}
+
+	// Two tokens a second, capped at the capacity of four
+	steps := []struct {
+		advance time.Duration
+		tokens  float64
+	}{
+		{250 * time.Millisecond, 0.5},
+		{250 * time.Millisecond, 1},
+		{time.Second, 3},
+		{10 * time.Second, 4},
+	}
+	for _, step := range steps {
+		clock.Advance(step.advance)
+		if got := bucket.GetAvailableTokens(); math.Abs(got-step.tokens) > 1e-9 {
+			t.Errorf("after +%v: %.3f tokens, want %.3f", step.advance, got, step.tokens)
+		}
+	}
+
+	for i := 0; i < 4; i++ {
+		if !bucket.AllowSingleRequest() {
+			t.Fatalf("request %d of a full bucket rejected", i)
+		}
+	}
+	if bucket.AllowSingleRequest() {
+		t.Error("fifth request allowed with the bucket empty")
+	}
+}
+
+func TestTokenBucketReserveWithFakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	bucket, err := NewTokenBucket(4, 2.0, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, wait := bucket.Reserve(4); !ok || wait != 0 {
+		t.Fatalf("Reserve(4) on a full bucket = %t, %v; want true, 0", ok, wait)
+	}
+	// A reservation past empty waits for the refill to pay back the deficit
+	if ok, wait := bucket.Reserve(3); !ok || wait != 1500*time.Millisecond {
+		t.Fatalf("Reserve(3) on an empty bucket = %t, %v; want true, 1.5s", ok, wait)
+	}
+	if wait := bucket.GetTimeUntilNextAllowedRequest(); wait != 2*time.Second {
+		t.Errorf("next request after the deficit in %v, want 2s", wait)
+	}
+
+	clock.Advance(time.Second)
+	if bucket.AllowSingleRequest() {
+		t.Error("request allowed halfway through repaying the deficit")
+	}
+	clock.Advance(time.Second)
+	if !bucket.AllowSingleRequest() {
+		t.Error("request rejected once the deficit was repaid")
+	}
+}
+
+func TestSlidingWindowExpiryWithFakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	window, err := NewSlidingWindowRateLimiter(3, time.Minute, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	window.AllowRequest()
+	clock.Advance(20 * time.Second)
+	window.AllowRequest()
+	window.AllowRequest()
+	if window.AllowRequest() {
+		t.Fatal("fourth request in the window allowed")
+	}
+	if wait := window.GetTimeUntilNextAllowedRequest(); wait != 40*time.Second {
+		t.Errorf("wait = %v, want 40s until the first request expires", wait)
+	}
+
+	// A request leaves the window exactly windowSize after it was admitted
+	clock.Advance(40*time.Second - time.Nanosecond)
+	if window.AllowRequest() {
+		t.Error("request allowed 1ns before the first one expired")
+	}
+	clock.Advance(time.Nanosecond)
+	if !window.AllowRequest() {
+		t.Error("request rejected when the first one expired")
+	}
+	if count := window.GetRequestCount(); count != 3 {
+		t.Errorf("window count = %d, want 3", count)
+	}
+
+	// The two admitted at 20s expire together
+	clock.Advance(20 * time.Second)
+	if count := window.GetRequestCount(); count != 1 {
+		t.Errorf("window count at 80s = %d, want 1", count)
+	}
+}
+
+func TestFakeClockSet(t *testing.T) {
+	start := time.Unix(0, 0)
+	clock := NewFakeClock(start)
+	clock.Advance(time.Hour)
+	if got := clock.Now(); !got.Equal(start.Add(time.Hour)) {
+		t.Errorf("Now after Advance = %v, want %v", got, start.Add(time.Hour))
+	}
+
+	window, err := NewSlidingWindowRateLimiter(1, time.Minute, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	window.AllowRequest()
+	clock.Set(start.Add(2 * time.Hour))
+	if !window.AllowRequest() {
+		t.Error("request rejected after Set jumped past the window")
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FixedWindowRateLimiter implements a fixed window counter rate limiter.
+// Time is divided into windows aligned to the wall clock (e.g. every whole
+// second for a 1s window) and a single counter is reset at each boundary.
+// It is the simplest limiter, but a client can send maxRequests at the end of
+// one window and maxRequests again at the start of the next, doubling the
+// effective rate across the boundary.
+//
+// Time Complexity: O(1) per request
+// Space Complexity: O(1)
+type FixedWindowRateLimiter struct {
+	maxRequests int           // Maximum requests allowed per window
+	windowSize  time.Duration // Size of each fixed window
+	windowStart time.Time     // Start of the current window
+	count       int           // Requests seen in the current window
+	mu          sync.Mutex    // Mutex for thread safety
+}
+
+// NewFixedWindowRateLimiter creates a new fixed window rate limiter.
+func NewFixedWindowRateLimiter(maxRequests int, windowSize time.Duration) (*FixedWindowRateLimiter, error) {
+	if maxRequests <= 0 {
+		return nil, errors.New("max requests must be positive")
+	}
+	if windowSize <= 0 {
+		return nil, errors.New("window size must be positive")
+	}
+
+	return &FixedWindowRateLimiter{
+		maxRequests: maxRequests,
+		windowSize:  windowSize,
+		windowStart: time.Now().Truncate(windowSize),
+	}, nil
+}
+
+// AllowRequest checks if a request can be allowed in the current window.
+func (fw *FixedWindowRateLimiter) AllowRequest() bool {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	fw.advanceWindow(time.Now())
+
+	if fw.count < fw.maxRequests {
+		fw.count++
+		return true
+	}
+	return false
+}
+
+// advanceWindow resets the counter if the current time is past the window boundary.
+func (fw *FixedWindowRateLimiter) advanceWindow(currentTime time.Time) {
+	windowStart := currentTime.Truncate(fw.windowSize)
+	if windowStart.After(fw.windowStart) {
+		fw.windowStart = windowStart
+		fw.count = 0
+	}
+}
+
+// GetRequestCount returns the number of requests in the current window.
+func (fw *FixedWindowRateLimiter) GetRequestCount() int {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	fw.advanceWindow(time.Now())
+	return fw.count
+}
+
+// GetMaxRequests returns the maximum number of requests allowed per window.
+func (fw *FixedWindowRateLimiter) GetMaxRequests() int {
+	return fw.maxRequests
+}
+
+// GetWindowSize returns the window size.
+func (fw *FixedWindowRateLimiter) GetWindowSize() time.Duration {
+	return fw.windowSize
+}
+
+// GetTimeUntilNextAllowedRequest calculates the time until the next request can be allowed.
+func (fw *FixedWindowRateLimiter) GetTimeUntilNextAllowedRequest() time.Duration {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	now := time.Now()
+	fw.advanceWindow(now)
+
+	if fw.count < fw.maxRequests {
+		return 0 // Can make request immediately
+	}
+
+	// Need to wait until the next window starts
+	return fw.windowStart.Add(fw.windowSize).Sub(now)
+}
+
+// DemoFixedWindow demonstrates the boundary burst problem of the fixed window
+// counter and how the sliding window avoids it.
+func DemoFixedWindow() {
+	fmt.Println("=== Fixed Window Boundary Burst Demo ===")
+
+	// Allow 5 requests per 1-second window
+	fixedWindow, err := NewFixedWindowRateLimiter(5, time.Second)
+	if err != nil {
+		fmt.Printf("Error creating fixed window limiter: %v\n", err)
+		return
+	}
+	slidingWindow, _ := NewSlidingWindowRateLimiter(5, time.Second)
+
+	// Line up the first burst just before a window boundary
+	now := time.Now()
+	boundary := now.Truncate(time.Second).Add(time.Second)
+	time.Sleep(boundary.Sub(now) - 100*time.Millisecond)
+
+	fixedTotal, windowTotal := 0, 0
+	burst := func(label string) {
+		fixedAllowed, windowAllowed := 0, 0
+		for i := 0; i < 5; i++ {
+			if fixedWindow.AllowRequest() {
+				fixedAllowed++
+			}
+			if slidingWindow.AllowRequest() {
+				windowAllowed++
+			}
+		}
+		fmt.Printf("%s: Fixed=%d/5 allowed, Sliding=%d/5 allowed\n", label, fixedAllowed, windowAllowed)
+		fixedTotal += fixedAllowed
+		windowTotal += windowAllowed
+	}
+
+	burst("Burst before boundary")
+
+	// Cross the boundary and burst again
+	time.Sleep(200 * time.Millisecond)
+	burst("Burst after boundary ")
+
+	fmt.Printf("Within ~200ms the fixed window admitted %d requests and the sliding window %d, against a limit of 5.\n",
+		fixedTotal, windowTotal)
+	if fixedTotal > 5 {
+		fmt.Println("The fixed window let a burst through by resetting at the boundary.")
+	} else {
+		fmt.Println("The bursts didn't straddle a window boundary this run.")
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// JitterStrategy controls how GetTimeUntilNextAllowedRequestJittered spreads
+// out the wait it reports. Clients rejected at the same moment all read the
+// same exact wait and would otherwise retry in lockstep.
+//
+// The jitter is always added on top of the exact wait, never taken off it: a
+// client told to retry sooner would only be rejected again.
+type JitterStrategy int
+
+const (
+	// NoJitter reports the exact wait. It is the default.
+	NoJitter JitterStrategy = iota
+	// FullJitter adds a random delay in [0, wait), spreading retries over
+	// [wait, 2*wait).
+	FullJitter
+	// EqualJitter adds half the wait plus a random delay in [0, wait/2),
+	// spreading retries over [1.5*wait, 2*wait) so none come back early.
+	EqualJitter
+)
+
+// WithJitter selects the strategy the token bucket and sliding window use
+// for GetTimeUntilNextAllowedRequestJittered.
+func WithJitter(strategy JitterStrategy) Option {
+	return func(o *limiterOptions) {
+		o.jitter = strategy
+	}
+}
+
+// jitterInt63n draws the random part of a jittered wait. Tests swap in a
+// seeded source to get repeatable samples.
+var jitterInt63n = rand.Int63n
+
+// applyJitter spreads wait according to strategy. A zero wait stays zero.
+func applyJitter(wait time.Duration, strategy JitterStrategy) time.Duration {
+	if wait <= 0 {
+		return wait
+	}
+
+	switch strategy {
+	case FullJitter:
+		return wait + time.Duration(jitterInt63n(int64(wait)))
+	case EqualJitter:
+		half := wait / 2
+		if half <= 0 {
+			return wait
+		}
+		return wait + half + time.Duration(jitterInt63n(int64(half)))
+	default:
+		return wait
+	}
+}
+
+// GetTimeUntilNextAllowedRequestJittered returns GetTimeUntilNextAllowedRequest
+// spread by the limiter's jitter strategy, for use as a Retry-After value.
+func (sw *SlidingWindowRateLimiter) GetTimeUntilNextAllowedRequestJittered() time.Duration {
+	return applyJitter(sw.GetTimeUntilNextAllowedRequest(), sw.jitter)
+}
+
+// GetTimeUntilNextAllowedRequestJittered returns GetTimeUntilNextAllowedRequest
+// spread by the bucket's jitter strategy, for use as a Retry-After value.
+func (tb *TokenBucket) GetTimeUntilNextAllowedRequestJittered() time.Duration {
+	return applyJitter(tb.GetTimeUntilNextAllowedRequest(), tb.jitter)
+}
+
+// DemoJitter samples the jittered wait of a saturated limiter under each
+// strategy and prints the spread of the samples.
+func DemoJitter() {
+	fmt.Println("=== Retry-After Jitter Demo ===")
+
+	const samples = 10000
+
+	strategies := []struct {
+		name     string
+		strategy JitterStrategy
+	}{
+		{"none", NoJitter},
+		{"full", FullJitter},
+		{"equal", EqualJitter},
+	}
+
+	for _, s := range strategies {
+		limiter, _ := NewSlidingWindowRateLimiter(1, time.Hour, WithJitter(s.strategy))
+		limiter.AllowRequest()
+
+		// The exact wait shrinks as the demo runs, so each sample is
+		// compared with a fresh reading taken just before it
+		var minRatio, maxRatio float64
+		for i := 0; i < samples; i++ {
+			exact := limiter.GetTimeUntilNextAllowedRequest()
+			jittered := limiter.GetTimeUntilNextAllowedRequestJittered()
+			ratio := float64(jittered) / float64(exact)
+			if i == 0 || ratio < minRatio {
+				minRatio = ratio
+			}
+			if ratio > maxRatio {
+				maxRatio = ratio
+			}
+		}
+
+		fmt.Printf("%-6s jitter: wait x %.3f..%.3f over %d samples\n",
+			s.name, minRatio, maxRatio, samples)
+	}
+
+	bucket, _ := NewTokenBucket(1, 0.5, WithJitter(FullJitter))
+	bucket.AllowSingleRequest()
+	fmt.Printf("Token bucket: exact wait %v, jittered %v\n",
+		bucket.GetTimeUntilNextAllowedRequest().Round(time.Millisecond),
+		bucket.GetTimeUntilNextAllowedRequestJittered().Round(time.Millisecond))
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// seedJitter makes jittered waits repeatable for the rest of the test
+func seedJitter(t *testing.T, seed int64) {
+	t.Helper()
+	original := jitterInt63n
+	jitterInt63n = rand.New(rand.NewSource(seed)).Int63n
+	t.Cleanup(func() { jitterInt63n = original })
+}
+
+func TestJitterBounds(t *testing.T) {
+	seedJitter(t, 1)
+	const samples = 10000
+
+	tests := []struct {
+		name     string
+		strategy JitterStrategy
+		low      float64 // lower bound as a multiple of the exact wait
+		high     float64 // upper bound, exclusive unless equal to low
+	}{
+		{"none", NoJitter, 1, 1},
+		{"full", FullJitter, 1, 2},
+		{"equal", EqualJitter, 1.5, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// With a fake clock the exact wait holds still between samples
+			clock := NewFakeClock(time.Unix(0, 0))
+			limiter, err := NewSlidingWindowRateLimiter(1, time.Hour, WithJitter(tt.strategy), WithClock(clock))
+			if err != nil {
+				t.Fatal(err)
+			}
+			limiter.AllowRequest()
+			exact := limiter.GetTimeUntilNextAllowedRequest()
+			if exact != time.Hour {
+				t.Fatalf("exact wait = %v, want 1h", exact)
+			}
+
+			low := time.Duration(tt.low * float64(exact))
+			high := time.Duration(tt.high * float64(exact))
+			minWait, maxWait := high, low
+			for i := 0; i < samples; i++ {
+				jittered := limiter.GetTimeUntilNextAllowedRequestJittered()
+				if jittered < low || jittered > high || (jittered == high && high != low) {
+					t.Fatalf("sample %d: %v outside [%v, %v)", i, jittered, low, high)
+				}
+				if jittered < minWait {
+					minWait = jittered
+				}
+				if jittered > maxWait {
+					maxWait = jittered
+				}
+			}
+
+			// The samples should spread across nearly all of the range
+			if spread := high - low; spread > 0 && maxWait-minWait < spread*99/100 {
+				t.Errorf("samples span %v..%v, want most of [%v, %v)", minWait, maxWait, low, high)
+			}
+		})
+	}
+}
+
+func TestJitterTokenBucket(t *testing.T) {
+	seedJitter(t, 1)
+	clock := NewFakeClock(time.Unix(0, 0))
+	bucket, err := NewTokenBucket(1, 0.5, WithJitter(FullJitter), WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket.AllowSingleRequest()
+
+	exact := bucket.GetTimeUntilNextAllowedRequest()
+	if exact != 2*time.Second {
+		t.Fatalf("exact wait = %v, want 2s", exact)
+	}
+	for i := 0; i < 1000; i++ {
+		if jittered := bucket.GetTimeUntilNextAllowedRequestJittered(); jittered < exact || jittered >= 2*exact {
+			t.Fatalf("sample %d: %v outside [%v, %v)", i, jittered, exact, 2*exact)
+		}
+	}
+}
+
+func TestApplyJitterEdgeCases(t *testing.T) {
+	seedJitter(t, 1)
+	for _, strategy := range []JitterStrategy{NoJitter, FullJitter, EqualJitter} {
+		if got := applyJitter(0, strategy); got != 0 {
+			t.Errorf("strategy %d: applyJitter(0) = %v, want 0", strategy, got)
+		}
+	}
+	// Too short to halve, so nothing is added
+	if got := applyJitter(time.Nanosecond, EqualJitter); got != time.Nanosecond {
+		t.Errorf("EqualJitter of 1ns = %v, want 1ns", got)
+	}
+}
+
+func TestJitterIsRepeatableWithSeed(t *testing.T) {
+	sample := func() []time.Duration {
+		seedJitter(t, 42)
+		waits := make([]time.Duration, 5)
+		for i := range waits {
+			waits[i] = applyJitter(time.Second, FullJitter)
+		}
+		return waits
+	}
+	first, second := sample(), sample()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("sample %d: %v then %v with the same seed", i, first[i], second[i])
+		}
+	}
+}
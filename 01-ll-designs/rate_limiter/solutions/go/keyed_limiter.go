@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// keyedBucket is a per-key token bucket with its last access time.
+type keyedBucket struct {
+	bucket   *TokenBucket
+	lastSeen time.Time
+}
+
+// KeyedRateLimiterStats represents statistics for a keyed rate limiter
+type KeyedRateLimiterStats struct {
+	ActiveKeys  int   `json:"activeKeys"`
+	CreatedKeys int64 `json:"createdKeys"`
+	EvictedKeys int64 `json:"evictedKeys"`
+}
+
+// KeyedRateLimiter rate-limits each key (API key, user ID, client IP)
+// independently with its own TokenBucket. Buckets are created lazily on the
+// first request for a key, and a background sweeper evicts buckets that have
+// been idle longer than idleTTL so memory stays bounded by the number of
+// recently active keys rather than every key ever seen.
+//
+// idleTTL should be at least capacity/refillRate: by then an idle bucket has
+// refilled completely, so evicting it and later recreating it full does not
+// let a client skip any debt.
+type KeyedRateLimiter struct {
+	capacity    int
+	refillRate  float64
+	idleTTL     time.Duration
+	buckets     map[string]*keyedBucket
+	createdKeys int64
+	evictedKeys int64
+	mu          sync.Mutex
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+// NewKeyedRateLimiter creates a keyed rate limiter and starts its sweeper.
+// Call Close to stop the sweeper.
+func NewKeyedRateLimiter(capacity int, refillRate float64, idleTTL time.Duration) (*KeyedRateLimiter, error) {
+	if capacity <= 0 {
+		return nil, errors.New("capacity must be positive")
+	}
+	if refillRate <= 0 {
+		return nil, errors.New("refill rate must be positive")
+	}
+	if idleTTL <= 0 {
+		return nil, errors.New("idle TTL must be positive")
+	}
+
+	krl := &KeyedRateLimiter{
+		capacity:   capacity,
+		refillRate: refillRate,
+		idleTTL:    idleTTL,
+		buckets:    make(map[string]*keyedBucket),
+		stop:       make(chan struct{}),
+	}
+
+	go krl.sweepRoutine()
+	return krl, nil
+}
+
+// Allow attempts to consume one token from the bucket for key.
+func (krl *KeyedRateLimiter) Allow(key string) bool {
+	krl.mu.Lock()
+	entry, exists := krl.buckets[key]
+	if !exists {
+		bucket, _ := NewTokenBucket(krl.capacity, krl.refillRate) // parameters validated in constructor
+		entry = &keyedBucket{bucket: bucket}
+		krl.buckets[key] = entry
+		krl.createdKeys++
+	}
+	entry.lastSeen = time.Now()
+	krl.mu.Unlock()
+
+	// The bucket has its own lock, so keys don't contend with each other here
+	return entry.bucket.AllowSingleRequest()
+}
+
+// GetTimeUntilNextAllowedRequest returns how long until key's bucket has a
+// token again. A key without a bucket is allowed now.
+func (krl *KeyedRateLimiter) GetTimeUntilNextAllowedRequest(key string) time.Duration {
+	krl.mu.Lock()
+	entry, exists := krl.buckets[key]
+	krl.mu.Unlock()
+
+	if !exists {
+		return 0
+	}
+	return entry.bucket.GetTimeUntilNextAllowedRequest()
+}
+
+// sweepRoutine periodically evicts idle buckets until Close is called.
+func (krl *KeyedRateLimiter) sweepRoutine() {
+	ticker := time.NewTicker(krl.idleTTL / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-krl.stop:
+			return
+		case <-ticker.C:
+			krl.Sweep()
+		}
+	}
+}
+
+// Sweep evicts every bucket idle for longer than idleTTL and returns how many
+// were evicted. It runs automatically in the background but can be called
+// directly.
+func (krl *KeyedRateLimiter) Sweep() int {
+	cutoff := time.Now().Add(-krl.idleTTL)
+
+	krl.mu.Lock()
+	defer krl.mu.Unlock()
+
+	evicted := 0
+	for key, entry := range krl.buckets {
+		if entry.lastSeen.Before(cutoff) {
+			delete(krl.buckets, key)
+			evicted++
+		}
+	}
+	krl.evictedKeys += int64(evicted)
+	return evicted
+}
+
+// ActiveKeys returns the number of keys currently holding a bucket.
+func (krl *KeyedRateLimiter) ActiveKeys() int {
+	krl.mu.Lock()
+	defer krl.mu.Unlock()
+	return len(krl.buckets)
+}
+
+// GetStats returns current statistics
+func (krl *KeyedRateLimiter) GetStats() KeyedRateLimiterStats {
+	krl.mu.Lock()
+	defer krl.mu.Unlock()
+
+	return KeyedRateLimiterStats{
+		ActiveKeys:  len(krl.buckets),
+		CreatedKeys: krl.createdKeys,
+		EvictedKeys: krl.evictedKeys,
+	}
+}
+
+// Close stops the background sweeper.
+func (krl *KeyedRateLimiter) Close() {
+	krl.stopOnce.Do(func() {
+		close(krl.stop)
+	})
+}
+
+// DemoKeyedRateLimiter demonstrates per-key limits and idle eviction with
+// 10,000 distinct keys.
+func DemoKeyedRateLimiter() {
+	fmt.Println("=== Keyed Rate Limiter Demo ===")
+
+	limiter, err := NewKeyedRateLimiter(3, 10.0, 300*time.Millisecond)
+	if err != nil {
+		fmt.Printf("Error creating keyed rate limiter: %v\n", err)
+		return
+	}
+	defer limiter.Close()
+
+	// Each key has its own budget
+	for i := 0; i < 4; i++ {
+		fmt.Printf("user-1 request %d: %t, user-2 request %d: %t\n",
+			i+1, limiter.Allow("user-1"), i+1, limiter.Allow("user-2"))
+	}
+
+	var before, loaded, swept runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	for i := 0; i < 10000; i++ {
+		limiter.Allow(fmt.Sprintf("client-%d", i))
+	}
+
+	runtime.GC()
+	runtime.ReadMemStats(&loaded)
+	fmt.Printf("\nBefore: heap %d KB\n", before.HeapAlloc/1024)
+	fmt.Printf("After 10000 distinct keys: %d active, heap %d KB\n",
+		limiter.ActiveKeys(), loaded.HeapAlloc/1024)
+
+	fmt.Println("Waiting for idle keys to be swept...")
+	time.Sleep(500 * time.Millisecond)
+
+	runtime.GC()
+	runtime.ReadMemStats(&swept)
+	stats := limiter.GetStats()
+	fmt.Printf("After sweep: %d active, %d created, %d evicted, heap %d KB\n",
+		stats.ActiveKeys, stats.CreatedKeys, stats.EvictedKeys, swept.HeapAlloc/1024)
+}
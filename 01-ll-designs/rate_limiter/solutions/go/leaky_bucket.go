@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// LeakyBucket implements a thread-safe leaky bucket rate limiter.
+// Requests are modeled as water poured into a bucket that leaks at a constant
+// rate. Each request adds one unit; if the bucket would overflow, the request
+// is rejected. Unlike the token bucket, the output rate is smoothed to the
+// leak rate regardless of how bursty the input is.
+//
+// Time Complexity: O(1) per request
+// Space Complexity: O(1)
+type LeakyBucket struct {
+	capacity int        // Maximum number of queued requests
+	level    float64    // Current amount of water in the bucket
+	leakRate float64    // Requests drained per second
+	lastLeak time.Time  // Last time the bucket was drained
+	mu       sync.Mutex // Mutex for thread safety
+}
+
+// NewLeakyBucket creates a new LeakyBucket rate limiter.
+func NewLeakyBucket(capacity int, leakRate float64) (*LeakyBucket, error) {
+	if capacity <= 0 {
+		return nil, errors.New("capacity must be positive")
+	}
+	if leakRate <= 0 {
+		return nil, errors.New("leak rate must be positive")
+	}
+
+	return &LeakyBucket{
+		capacity: capacity,
+		level:    0, // Start with an empty bucket
+		leakRate: leakRate,
+		lastLeak: time.Now(),
+	}, nil
+}
+
+// AllowRequest adds a request to the bucket if there is room for it.
+func (lb *LeakyBucket) AllowRequest() bool {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	lb.leak()
+
+	if lb.level+1 <= float64(lb.capacity) {
+		lb.level++
+		return true
+	}
+	return false
+}
+
+// leak drains the bucket based on elapsed time since the last leak.
+func (lb *LeakyBucket) leak() {
+	now := time.Now()
+	elapsed := now.Sub(lb.lastLeak).Seconds()
+	lb.lastLeak = now
+
+	lb.level -= elapsed * lb.leakRate
+	if lb.level < 0 {
+		lb.level = 0
+	}
+}
+
+// GetCurrentLevel returns the number of requests currently queued in the bucket.
+func (lb *LeakyBucket) GetCurrentLevel() float64 {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	lb.leak()
+	return lb.level
+}
+
+// GetCapacity returns the bucket capacity.
+func (lb *LeakyBucket) GetCapacity() int {
+	return lb.capacity
+}
+
+// GetLeakRate returns the leak rate in requests per second.
+func (lb *LeakyBucket) GetLeakRate() float64 {
+	return lb.leakRate
+}
+
+// DemoLeakyBucket demonstrates the leaky bucket rate limiter.
+func DemoLeakyBucket() {
+	fmt.Println("=== Leaky Bucket Rate Limiter Demo ===")
+
+	// Create a bucket holding 5 requests that drains 2 requests/second
+	limiter, err := NewLeakyBucket(5, 2.0)
+	if err != nil {
+		fmt.Printf("Error creating leaky bucket: %v\n", err)
+		return
+	}
+
+	// Make several requests quickly
+	for i := 0; i < 8; i++ {
+		allowed := limiter.AllowRequest()
+		level := limiter.GetCurrentLevel()
+		status := "BLOCKED"
+		if allowed {
+			status = "ALLOWED"
+		}
+		fmt.Printf("Request %d: %s (level: %.2f)\n", i+1, status, level)
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	fmt.Println("\nWaiting 1 second for the bucket to drain...")
+	time.Sleep(1 * time.Second)
+
+	fmt.Printf("Level after wait: %.2f\n", limiter.GetCurrentLevel())
+}
@@ -137,6 +137,21 @@ func ErrorHandlingDemo() {
 		fmt.Printf("Expected error for negative window size: %v\n", err)
 	}
 
+	_, err = NewLeakyBucket(0, 1.0)
+	if err != nil {
+		fmt.Printf("Expected error for zero leaky bucket capacity: %v\n", err)
+	}
+
+	_, err = NewLeakyBucket(10, 0)
+	if err != nil {
+		fmt.Printf("Expected error for zero leak rate: %v\n", err)
+	}
+
+	_, err = NewFixedWindowRateLimiter(0, time.Second)
+	if err != nil {
+		fmt.Printf("Expected error for zero fixed window max requests: %v\n", err)
+	}
+
 	// Test edge cases
 	limiter, _ := NewTokenBucket(1, 0.1) // Very slow refill
 	fmt.Printf("Very slow refill - first request: %t\n", limiter.AllowSingleRequest())
@@ -157,9 +172,39 @@ func main() {
 	DemoTokenBucket()
 	fmt.Println()
 
+	DemoReserve()
+	fmt.Println()
+
 	DemoSlidingWindow()
 	fmt.Println()
 
+	DemoWaitForSlot()
+	fmt.Println()
+
+	DemoLeakyBucket()
+	fmt.Println()
+
+	DemoFixedWindow()
+	fmt.Println()
+
+	DemoSharedStore()
+	fmt.Println()
+
+	DemoKeyedRateLimiter()
+	fmt.Println()
+
+	DemoMetrics()
+	fmt.Println()
+
+	DemoHTTPMiddleware()
+	fmt.Println()
+
+	DemoJitter()
+	fmt.Println()
+
+	DemoFakeClock()
+	fmt.Println()
+
 	// Run comparison and analysis demos
 	ComparativeDemo()
 	ConcurrencyDemo()
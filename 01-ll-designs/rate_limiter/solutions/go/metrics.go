@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics receives rate limiter decisions for monitoring. Limiters call it
+// only when configured WithMetrics, so the default path has no overhead.
+// The prommetrics subpackage provides a Prometheus implementation.
+type Metrics interface {
+	// ObserveAllowed records an admitted request.
+	ObserveAllowed()
+	// ObserveBlocked records a rejected request.
+	ObserveBlocked()
+	// SetLevel records the limiter's current level: available tokens for a
+	// token bucket, requests in the window for a sliding window.
+	SetLevel(value float64)
+}
+
+// WithMetrics reports every decision of the limiter to metrics.
+func WithMetrics(metrics Metrics) Option {
+	return func(o *limiterOptions) {
+		o.metrics = metrics
+	}
+}
+
+// CounterMetrics is a dependency-free Metrics implementation backed by
+// atomic counters, handy for demos and tests.
+type CounterMetrics struct {
+	allowed   int64
+	blocked   int64
+	levelBits uint64 // float64 bits of the last level
+}
+
+// NewCounterMetrics creates a zeroed CounterMetrics.
+func NewCounterMetrics() *CounterMetrics {
+	return &CounterMetrics{}
+}
+
+// ObserveAllowed implements Metrics
+func (cm *CounterMetrics) ObserveAllowed() {
+	atomic.AddInt64(&cm.allowed, 1)
+}
+
+// ObserveBlocked implements Metrics
+func (cm *CounterMetrics) ObserveBlocked() {
+	atomic.AddInt64(&cm.blocked, 1)
+}
+
+// SetLevel implements Metrics
+func (cm *CounterMetrics) SetLevel(value float64) {
+	atomic.StoreUint64(&cm.levelBits, math.Float64bits(value))
+}
+
+// Allowed returns the number of admitted requests.
+func (cm *CounterMetrics) Allowed() int64 {
+	return atomic.LoadInt64(&cm.allowed)
+}
+
+// Blocked returns the number of rejected requests.
+func (cm *CounterMetrics) Blocked() int64 {
+	return atomic.LoadInt64(&cm.blocked)
+}
+
+// Level returns the last reported level.
+func (cm *CounterMetrics) Level() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&cm.levelBits))
+}
+
+func (cm *CounterMetrics) String() string {
+	return fmt.Sprintf("allowed=%d, blocked=%d, level=%.2f", cm.Allowed(), cm.Blocked(), cm.Level())
+}
+
+// DemoMetrics demonstrates collecting decisions from both limiters.
+func DemoMetrics() {
+	fmt.Println("=== Rate Limiter Metrics Demo ===")
+
+	bucketMetrics := NewCounterMetrics()
+	windowMetrics := NewCounterMetrics()
+
+	tokenBucket, err := NewTokenBucket(5, 1.0, WithMetrics(bucketMetrics))
+	if err != nil {
+		fmt.Printf("Error creating token bucket: %v\n", err)
+		return
+	}
+	slidingWindow, _ := NewSlidingWindowRateLimiter(3, time.Second, WithMetrics(windowMetrics))
+
+	for i := 0; i < 8; i++ {
+		tokenBucket.AllowSingleRequest()
+		slidingWindow.AllowRequest()
+	}
+
+	fmt.Printf("Token bucket:   %s\n", bucketMetrics)
+	fmt.Printf("Sliding window: %s\n", windowMetrics)
+}
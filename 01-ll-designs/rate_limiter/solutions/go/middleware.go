@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limiter is the minimal interface the HTTP middleware needs.
+// SlidingWindowRateLimiter, LeakyBucket and FixedWindowRateLimiter satisfy it
+// directly; wrap a TokenBucket as LimiterFunc(tb.AllowSingleRequest).
+type Limiter interface {
+	AllowRequest() bool
+}
+
+// LimiterFunc adapts an ordinary function to the Limiter interface.
+type LimiterFunc func() bool
+
+// AllowRequest calls f.
+func (f LimiterFunc) AllowRequest() bool {
+	return f()
+}
+
+// retryAfterer is implemented by limiters that can tell a rejected client
+// how long to wait.
+type retryAfterer interface {
+	GetTimeUntilNextAllowedRequest() time.Duration
+}
+
+// jitteredRetryAfterer is implemented by limiters that can spread the wait
+// they report, configured WithJitter.
+type jitteredRetryAfterer interface {
+	GetTimeUntilNextAllowedRequestJittered() time.Duration
+}
+
+// defaultRetryAfter is sent when the limiter can't compute a wait.
+const defaultRetryAfter = time.Second
+
+// KeyFunc extracts the client identity a request is limited by.
+type KeyFunc func(r *http.Request) string
+
+// ClientIPKey keys requests by the remote IP address, without the port.
+func ClientIPKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// HeaderKey keys requests by the value of the named header, such as an API
+// key. Requests without the header share the empty key.
+func HeaderKey(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return strings.TrimSpace(r.Header.Get(name))
+	}
+}
+
+// MiddlewareOption configures RateLimitMiddleware.
+type MiddlewareOption func(*middlewareOptions)
+
+// middlewareOptions collects the settings applied by MiddlewareOptions.
+type middlewareOptions struct {
+	keyed   *KeyedRateLimiter
+	keyFunc KeyFunc
+}
+
+// WithKeyedLimiter additionally limits each client separately, identified by
+// keyFunc (for example ClientIPKey or HeaderKey("X-API-Key")), using keyed's
+// per-key buckets. A request must pass both the shared limiter, if any, and
+// its client's bucket.
+func WithKeyedLimiter(keyed *KeyedRateLimiter, keyFunc KeyFunc) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.keyed = keyed
+		o.keyFunc = keyFunc
+	}
+}
+
+// RateLimitMiddleware returns middleware that rejects requests the limiter
+// does not allow with 429 Too Many Requests and a Retry-After header. The
+// wait comes from GetTimeUntilNextAllowedRequestJittered or
+// GetTimeUntilNextAllowedRequest when the limiter has one, preferring the
+// jittered wait, and defaults to one second otherwise. A client refused by
+// its own bucket is told when that bucket next has a token. limiter may be
+// nil when only per-client limits are wanted.
+func RateLimitMiddleware(limiter Limiter, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	options := &middlewareOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Check the per-client bucket first so one noisy client doesn't
+			// drain the shared limit with requests it would be refused anyway
+			if options.keyed != nil {
+				key := options.keyFunc(r)
+				if !options.keyed.Allow(key) {
+					rejectRequest(w, options.keyed.GetTimeUntilNextAllowedRequest(key))
+					return
+				}
+			}
+
+			if limiter != nil && !limiter.AllowRequest() {
+				wait := defaultRetryAfter
+				if ra, ok := limiter.(jitteredRetryAfterer); ok {
+					wait = ra.GetTimeUntilNextAllowedRequestJittered()
+				} else if ra, ok := limiter.(retryAfterer); ok {
+					wait = ra.GetTimeUntilNextAllowedRequest()
+				}
+				rejectRequest(w, wait)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rejectRequest writes a 429 response. Retry-After is in whole seconds, so the
+// wait is rounded up and never advertised as zero.
+func rejectRequest(w http.ResponseWriter, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+}
+
+// DemoHTTPMiddleware demonstrates the middleware in front of a publish route
+// shaped like the message broker's, with a shared limit and a per-API-key limit.
+func DemoHTTPMiddleware() {
+	fmt.Println("=== HTTP Middleware Demo ===")
+
+	shared, err := NewSlidingWindowRateLimiter(5, 2*time.Second)
+	if err != nil {
+		fmt.Printf("Error creating sliding window limiter: %v\n", err)
+		return
+	}
+	perKey, err := NewKeyedRateLimiter(2, 1.0, time.Minute)
+	if err != nil {
+		fmt.Printf("Error creating keyed rate limiter: %v\n", err)
+		return
+	}
+	defer perKey.Close()
+
+	publish := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/publish/", RateLimitMiddleware(shared, WithKeyedLimiter(perKey, HeaderKey("X-API-Key")))(publish))
+
+	for i, apiKey := range []string{"alice", "alice", "alice", "bob", "bob", "carol", "dave", "dave"} {
+		req := httptest.NewRequest(http.MethodPost, "/publish/orders", strings.NewReader(`{"payload":"x"}`))
+		req.Header.Set("X-API-Key", apiKey)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		retry := rec.Header().Get("Retry-After")
+		if retry != "" {
+			retry = " (Retry-After: " + retry + "s)"
+		}
+		fmt.Printf("Request %d from %-5s -> %d%s\n", i+1, apiKey, rec.Code, retry)
+	}
+}
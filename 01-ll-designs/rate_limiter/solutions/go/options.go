@@ -0,0 +1,33 @@
+package main
+
+// Option configures optional behavior of a rate limiter at construction time.
+type Option func(*limiterOptions)
+
+// limiterOptions collects the settings applied by Options.
+type limiterOptions struct {
+	store   Store          // shared counter store, nil for in-process state
+	key     string         // key identifying this limiter in the store
+	metrics Metrics        // decision observer, nil to disable
+	jitter  JitterStrategy // spread applied to jittered wait times
+	clock   Clock          // time source, the real clock by default
+}
+
+// newLimiterOptions applies opts over the defaults.
+func newLimiterOptions(opts []Option) *limiterOptions {
+	options := &limiterOptions{clock: realClock{}}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// WithStore makes the sliding window keep its counters in store under key,
+// so every limiter configured with the same store and key shares one limit.
+// NewTokenBucket rejects it: a bucket's refill needs an atomic
+// read-modify-write that Incr, Get and Expire can't provide.
+func WithStore(store Store, key string) Option {
+	return func(o *limiterOptions) {
+		o.store = store
+		o.key = key
+	}
+}
@@ -0,0 +1,61 @@
+// Package prommetrics exports rate limiter decisions as Prometheus metrics.
+// It lives in its own module so the limiters themselves stay dependency-free;
+// a *Metrics can be passed straight to WithMetrics.
+package prommetrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics records decisions for one named limiter.
+type Metrics struct {
+	allowed prometheus.Counter
+	blocked prometheus.Counter
+	level   prometheus.Gauge
+}
+
+// New registers the metrics for the limiter called name with reg and returns
+// a collector for it. Each limiter needs a distinct name.
+func New(reg prometheus.Registerer, name string) (*Metrics, error) {
+	labels := prometheus.Labels{"limiter": name}
+
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:        "rate_limiter_requests_total",
+		Help:        "Requests seen by the rate limiter, by decision.",
+		ConstLabels: labels,
+	}, []string{"decision"})
+	level := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        "rate_limiter_level",
+		Help:        "Available tokens for a token bucket, requests in the window for a sliding window.",
+		ConstLabels: labels,
+	})
+
+	if err := reg.Register(requests); err != nil {
+		return nil, err
+	}
+	if err := reg.Register(level); err != nil {
+		reg.Unregister(requests)
+		return nil, err
+	}
+
+	return &Metrics{
+		allowed: requests.WithLabelValues("allowed"),
+		blocked: requests.WithLabelValues("blocked"),
+		level:   level,
+	}, nil
+}
+
+// ObserveAllowed counts an admitted request.
+func (m *Metrics) ObserveAllowed() {
+	m.allowed.Inc()
+}
+
+// ObserveBlocked counts a rejected request.
+func (m *Metrics) ObserveBlocked() {
+	m.blocked.Inc()
+}
+
+// SetLevel updates the level gauge.
+func (m *Metrics) SetLevel(value float64) {
+	m.level.Set(value)
+}
@@ -1,27 +1,52 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math"
 	"sync"
 	"time"
 )
 
+// windowEntry records an admitted request and its cost.
+type windowEntry struct {
+	timestamp time.Time
+	cost      int
+}
+
 // SlidingWindowRateLimiter implements a sliding window rate limiter.
 // Maintains a sliding window of requests and allows requests only if
-// the count within the window doesn't exceed the limit.
+// the summed cost within the window doesn't exceed the limit.
+//
+// Request entries live in a fixed-size circular buffer sized to
+// maxRequests: expiring old requests advances the head index and admitting a
+// request writes at the tail, so the hot path never allocates.
 //
-// Time Complexity: O(log n) per request where n is window size
-// Space Complexity: O(n) where n is number of requests in window
+// When configured WithStore, per-request timestamps are not shared; instead the
+// limiter keeps one counter per fixed window in the store and estimates the
+// sliding count as previous*(1-elapsed) + current, the usual approach for
+// Redis-backed limiters.
+//
+// Time Complexity: O(1) amortized per request
+// Space Complexity: O(maxRequests)
 type SlidingWindowRateLimiter struct {
-	maxRequests    int           // Maximum requests allowed in window
-	windowSize     time.Duration // Size of the sliding window
-	requests       []time.Time   // Slice of request timestamps
-	mu             sync.Mutex    // Mutex for thread safety
+	maxRequests int            // Maximum requests allowed in window
+	windowSize  time.Duration  // Size of the sliding window
+	requests    []windowEntry  // Circular buffer of request entries
+	head        int            // Index of the oldest request in the buffer
+	count       int            // Number of requests currently in the buffer
+	totalCost   int            // Summed cost of requests in the buffer
+	store       Store          // Optional shared counter store
+	storeKey    string         // Key prefix for counters in the store
+	metrics     Metrics        // Optional decision observer
+	jitter      JitterStrategy // Spread for GetTimeUntilNextAllowedRequestJittered
+	clock       Clock          // Time source for window accounting
+	mu          sync.Mutex     // Mutex for thread safety
 }
 
 // NewSlidingWindowRateLimiter creates a new sliding window rate limiter.
-func NewSlidingWindowRateLimiter(maxRequests int, windowSize time.Duration) (*SlidingWindowRateLimiter, error) {
+func NewSlidingWindowRateLimiter(maxRequests int, windowSize time.Duration, opts ...Option) (*SlidingWindowRateLimiter, error) {
 	if maxRequests <= 0 {
 		return nil, errors.New("max requests must be positive")
 	}
@@ -29,62 +54,215 @@ func NewSlidingWindowRateLimiter(maxRequests int, windowSize time.Duration) (*Sl
 		return nil, errors.New("window size must be positive")
 	}
 
+	options := newLimiterOptions(opts)
+	if options.store != nil {
+		if options.key == "" {
+			return nil, errors.New("store key must not be empty")
+		}
+		return &SlidingWindowRateLimiter{
+			maxRequests: maxRequests,
+			windowSize:  windowSize,
+			store:       options.store,
+			storeKey:    options.key,
+			metrics:     options.metrics,
+			jitter:      options.jitter,
+			clock:       options.clock,
+		}, nil
+	}
+
 	return &SlidingWindowRateLimiter{
 		maxRequests: maxRequests,
 		windowSize:  windowSize,
-		requests:    make([]time.Time, 0),
+		requests:    make([]windowEntry, maxRequests),
+		metrics:     options.metrics,
+		jitter:      options.jitter,
+		clock:       options.clock,
 	}, nil
 }
 
 // AllowRequest checks if a request can be allowed based on the sliding window.
 func (sw *SlidingWindowRateLimiter) AllowRequest() bool {
+	return sw.AllowRequestN(1)
+}
+
+// AllowRequestN checks if a request with the given cost can be allowed. The
+// request is admitted only if the summed cost in the window plus cost stays
+// within maxRequests. A cost that is not positive or exceeds maxRequests on
+// its own is always rejected.
+func (sw *SlidingWindowRateLimiter) AllowRequestN(cost int) bool {
+	if cost <= 0 || cost > sw.maxRequests {
+		sw.observe(false, -1)
+		return false
+	}
+	if sw.store != nil {
+		return sw.allowFromStore(cost)
+	}
+
 	sw.mu.Lock()
 	defer sw.mu.Unlock()
 
-	now := time.Now()
+	allowed, _ := sw.tryRecord(sw.clock.Now(), cost)
+	sw.observe(allowed, float64(sw.totalCost))
+	return allowed
+}
+
+// observe reports a decision and the current window count to the metrics
+// hook, if any. A negative level leaves the gauge untouched.
+func (sw *SlidingWindowRateLimiter) observe(allowed bool, level float64) {
+	if sw.metrics == nil {
+		return
+	}
+	if allowed {
+		sw.metrics.ObserveAllowed()
+	} else {
+		sw.metrics.ObserveBlocked()
+	}
+	if level >= 0 {
+		sw.metrics.SetLevel(level)
+	}
+}
 
+// tryRecord admits a request of the given cost if it fits in the window.
+// Otherwise it returns how long until the oldest request expires.
+// Must be called with lock held.
+func (sw *SlidingWindowRateLimiter) tryRecord(now time.Time, cost int) (bool, time.Duration) {
 	// Remove old requests outside the window
 	sw.removeOldRequests(now)
 
-	// Check if we can allow this request
-	if len(sw.requests) < sw.maxRequests {
-		sw.requests = append(sw.requests, now)
-		return true
+	// Check if we can allow this request. Every entry costs at least 1, so the
+	// buffer never holds more than maxRequests entries.
+	if sw.totalCost+cost <= sw.maxRequests {
+		tail := (sw.head + sw.count) % len(sw.requests)
+		sw.requests[tail] = windowEntry{timestamp: now, cost: cost}
+		sw.count++
+		sw.totalCost += cost
+		return true, 0
 	}
-	return false
+	return false, sw.requests[sw.head].timestamp.Add(sw.windowSize).Sub(now)
 }
 
-// removeOldRequests removes requests that are outside the current sliding window.
-func (sw *SlidingWindowRateLimiter) removeOldRequests(currentTime time.Time) {
-	cutoffTime := currentTime.Add(-sw.windowSize)
-
-	// Find the first request that's still within the window
-	validIndex := 0
-	for i, reqTime := range sw.requests {
-		if reqTime.After(cutoffTime) {
-			validIndex = i
-			break
+// WaitForSlot blocks until a request can be admitted or ctx is done. Rather
+// than polling, it sleeps until the oldest request in the window expires. A
+// woken waiter re-checks under the lock before recording, so two waiters can
+// never both take the same freed slot; the loser simply waits again.
+func (sw *SlidingWindowRateLimiter) WaitForSlot(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
 		}
-		validIndex = i + 1
-	}
 
-	// Remove old requests by slicing
-	if validIndex > 0 {
-		if validIndex >= len(sw.requests) {
-			sw.requests = sw.requests[:0] // Clear all requests
+		var allowed bool
+		var wait time.Duration
+		if sw.store != nil {
+			allowed = sw.allowFromStore(1)
+			wait = sw.GetTimeUntilNextAllowedRequest()
 		} else {
-			sw.requests = sw.requests[validIndex:]
+			sw.mu.Lock()
+			allowed, wait = sw.tryRecord(sw.clock.Now(), 1)
+			if allowed {
+				sw.observe(true, float64(sw.totalCost))
+			}
+			sw.mu.Unlock()
+		}
+		if allowed {
+			return nil
+		}
+		if wait <= 0 {
+			wait = time.Millisecond
 		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// allowFromStore admits a request against the counters in the shared store.
+// The cost is added first and rolled back if it pushes the estimate over the
+// limit, so concurrent instances can over-reject but never over-admit. Store
+// errors fail open: an unreachable store should not take the service down.
+func (sw *SlidingWindowRateLimiter) allowFromStore(cost int) bool {
+	now := sw.clock.Now()
+	currentKey, previousKey, elapsed := sw.storeWindow(now)
+
+	current, err := sw.store.Incr(currentKey, int64(cost))
+	if err != nil {
+		sw.observe(true, -1)
+		return true
+	}
+	if current == int64(cost) {
+		// First request in this window: let the counter outlive the next window
+		sw.store.Expire(currentKey, 2*sw.windowSize)
+	}
+
+	previous, err := sw.store.Get(previousKey)
+	if err != nil {
+		sw.observe(true, -1)
+		return true
+	}
+
+	estimated := float64(previous)*(1-elapsed) + float64(current)
+	if estimated > float64(sw.maxRequests) {
+		sw.store.Incr(currentKey, -int64(cost))
+		sw.observe(false, estimated-float64(cost))
+		return false
 	}
+	sw.observe(true, estimated)
+	return true
 }
 
-// GetRequestCount returns the current number of requests in the sliding window.
+// storeCount returns the estimated summed cost in the window from the store.
+func (sw *SlidingWindowRateLimiter) storeCount(now time.Time) float64 {
+	currentKey, previousKey, elapsed := sw.storeWindow(now)
+	current, err := sw.store.Get(currentKey)
+	if err != nil {
+		return 0
+	}
+	previous, err := sw.store.Get(previousKey)
+	if err != nil {
+		return float64(current)
+	}
+	return float64(previous)*(1-elapsed) + float64(current)
+}
+
+// storeWindow returns the store keys for the current and previous fixed
+// windows and the fraction of the current window that has elapsed.
+func (sw *SlidingWindowRateLimiter) storeWindow(now time.Time) (currentKey, previousKey string, elapsed float64) {
+	index := now.UnixNano() / int64(sw.windowSize)
+	elapsed = float64(now.UnixNano()-index*int64(sw.windowSize)) / float64(sw.windowSize)
+	currentKey = fmt.Sprintf("%s:%d", sw.storeKey, index)
+	previousKey = fmt.Sprintf("%s:%d", sw.storeKey, index-1)
+	return currentKey, previousKey, elapsed
+}
+
+// removeOldRequests removes requests that are outside the current sliding window
+// by advancing the head of the circular buffer.
+func (sw *SlidingWindowRateLimiter) removeOldRequests(currentTime time.Time) {
+	cutoffTime := currentTime.Add(-sw.windowSize)
+
+	for sw.count > 0 && !sw.requests[sw.head].timestamp.After(cutoffTime) {
+		sw.totalCost -= sw.requests[sw.head].cost
+		sw.head = (sw.head + 1) % len(sw.requests)
+		sw.count--
+	}
+}
+
+// GetRequestCount returns the summed cost of requests in the sliding window.
+// When every request has cost 1 this is the number of requests.
 func (sw *SlidingWindowRateLimiter) GetRequestCount() int {
+	if sw.store != nil {
+		return int(math.Ceil(sw.storeCount(sw.clock.Now())))
+	}
+
 	sw.mu.Lock()
 	defer sw.mu.Unlock()
 
-	sw.removeOldRequests(time.Now())
-	return len(sw.requests)
+	sw.removeOldRequests(sw.clock.Now())
+	return sw.totalCost
 }
 
 // GetMaxRequests returns the maximum number of requests allowed in the window.
@@ -99,19 +277,28 @@ func (sw *SlidingWindowRateLimiter) GetWindowSize() time.Duration {
 
 // GetTimeUntilNextAllowedRequest calculates the time until the next request can be allowed.
 func (sw *SlidingWindowRateLimiter) GetTimeUntilNextAllowedRequest() time.Duration {
+	if sw.store != nil {
+		now := sw.clock.Now()
+		if sw.storeCount(now) < float64(sw.maxRequests) {
+			return 0
+		}
+		// The estimate only drops meaningfully once the next window starts
+		return now.Truncate(sw.windowSize).Add(sw.windowSize).Sub(now)
+	}
+
 	sw.mu.Lock()
 	defer sw.mu.Unlock()
 
-	now := time.Now()
+	now := sw.clock.Now()
 	sw.removeOldRequests(now)
 
-	if len(sw.requests) < sw.maxRequests {
+	if sw.totalCost < sw.maxRequests {
 		return 0 // Can make request immediately
 	}
 
 	// Need to wait until the oldest request in window expires
-	if len(sw.requests) > 0 {
-		oldestRequest := sw.requests[0]
+	if sw.count > 0 {
+		oldestRequest := sw.requests[sw.head].timestamp
 		waitTime := oldestRequest.Add(sw.windowSize).Sub(now)
 		if waitTime > 0 {
 			return waitTime
@@ -121,12 +308,60 @@ func (sw *SlidingWindowRateLimiter) GetTimeUntilNextAllowedRequest() time.Durati
 	return 0
 }
 
-// Reset clears all request history.
+// Reset clears all request history. With a shared store this clears the
+// counters for every limiter using the same key.
 func (sw *SlidingWindowRateLimiter) Reset() {
+	if sw.store != nil {
+		currentKey, previousKey, _ := sw.storeWindow(sw.clock.Now())
+		for _, key := range []string{currentKey, previousKey} {
+			if value, err := sw.store.Get(key); err == nil && value != 0 {
+				sw.store.Incr(key, -value)
+			}
+		}
+		return
+	}
+
 	sw.mu.Lock()
 	defer sw.mu.Unlock()
 
-	sw.requests = sw.requests[:0]
+	sw.head = 0
+	sw.count = 0
+	sw.totalCost = 0
+}
+
+// DemoWaitForSlot demonstrates blocking waiters on the sliding window.
+func DemoWaitForSlot() {
+	fmt.Println("=== Sliding Window WaitForSlot Demo ===")
+
+	// Allow 2 requests per 500ms window
+	limiter, err := NewSlidingWindowRateLimiter(2, 500*time.Millisecond)
+	if err != nil {
+		fmt.Printf("Error creating sliding window limiter: %v\n", err)
+		return
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			if err := limiter.WaitForSlot(context.Background()); err != nil {
+				fmt.Printf("Waiter %d: %v\n", id, err)
+				return
+			}
+			fmt.Printf("Waiter %d admitted after %v\n", id, time.Since(start).Round(10*time.Millisecond))
+		}(i + 1)
+	}
+	wg.Wait()
+
+	// Fill the window so the next waiter has to block
+	for limiter.AllowRequest() {
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	fmt.Printf("Waiter with 100ms timeout: %v\n", limiter.WaitForSlot(ctx))
 }
 
 // DemoSlidingWindow demonstrates the sliding window rate limiter.
@@ -198,11 +433,15 @@ func ComparativeDemo() {
 	// Sliding window spreads requests evenly
 	slidingWindow, _ := NewSlidingWindowRateLimiter(5, 5*time.Second)
 
+	// Leaky bucket queues up to capacity and drains at a fixed rate
+	leakyBucket, _ := NewLeakyBucket(5, 1.0)
+
 	fmt.Println("Making 10 rapid requests:")
 
 	for i := 0; i < 10; i++ {
 		tokenAllowed := tokenBucket.AllowSingleRequest()
 		windowAllowed := slidingWindow.AllowRequest()
+		leakyAllowed := leakyBucket.AllowRequest()
 
 		tokenStatus := "BLOCKED"
 		if tokenAllowed {
@@ -212,8 +451,12 @@ func ComparativeDemo() {
 		if windowAllowed {
 			windowStatus = "ALLOWED"
 		}
+		leakyStatus := "BLOCKED"
+		if leakyAllowed {
+			leakyStatus = "ALLOWED"
+		}
 
-		fmt.Printf("Request %d: Token=%s, Window=%s\n", i+1, tokenStatus, windowStatus)
+		fmt.Printf("Request %d: Token=%s, Window=%s, Leaky=%s\n", i+1, tokenStatus, windowStatus, leakyStatus)
 	}
 
 	// Wait and try again
@@ -224,6 +467,7 @@ func ComparativeDemo() {
 	for i := 0; i < 5; i++ {
 		tokenAllowed := tokenBucket.AllowSingleRequest()
 		windowAllowed := slidingWindow.AllowRequest()
+		leakyAllowed := leakyBucket.AllowRequest()
 
 		tokenStatus := "BLOCKED"
 		if tokenAllowed {
@@ -233,7 +477,11 @@ func ComparativeDemo() {
 		if windowAllowed {
 			windowStatus = "ALLOWED"
 		}
+		leakyStatus := "BLOCKED"
+		if leakyAllowed {
+			leakyStatus = "ALLOWED"
+		}
 
-		fmt.Printf("Request %d: Token=%s, Window=%s\n", i+11, tokenStatus, windowStatus)
+		fmt.Printf("Request %d: Token=%s, Window=%s, Leaky=%s\n", i+11, tokenStatus, windowStatus, leakyStatus)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestSlidingWindowAllowRequestN(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	limiter, err := NewSlidingWindowRateLimiter(10, time.Second, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		cost    int
+		allowed bool
+		count   int // window cost afterwards
+	}{
+		{11, false, 0}, // over the limit on its own
+		{0, false, 0},  // not positive
+		{-3, false, 0}, // not positive
+		{7, true, 7},
+		{4, false, 7}, // would make 11
+		{3, true, 10}, // fills the window exactly
+		{1, false, 10},
+	}
+	for i, step := range steps {
+		if got := limiter.AllowRequestN(step.cost); got != step.allowed {
+			t.Errorf("step %d: AllowRequestN(%d) = %t, want %t", i, step.cost, got, step.allowed)
+		}
+		if count := limiter.GetRequestCount(); count != step.count {
+			t.Errorf("step %d: window cost = %d, want %d", i, count, step.count)
+		}
+	}
+
+	// A rejected cost leaves no trace once the window slides
+	clock.Advance(time.Second)
+	if !limiter.AllowRequestN(10) {
+		t.Error("AllowRequestN(10) after the window slid = false, want true")
+	}
+}
+
+func TestSlidingWindowAllowRequestNCountsBlocked(t *testing.T) {
+	metrics := NewCounterMetrics()
+	limiter, err := NewSlidingWindowRateLimiter(5, time.Second,
+		WithClock(NewFakeClock(time.Unix(0, 0))), WithMetrics(metrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cost := range []int{0, -1, 6, 2} {
+		limiter.AllowRequestN(cost)
+	}
+	if metrics.Allowed() != 1 || metrics.Blocked() != 3 {
+		t.Errorf("allowed %d blocked %d, want 1 and 3", metrics.Allowed(), metrics.Blocked())
+	}
+	// Invalid costs leave the level as the last real decision set it
+	if metrics.Level() != 2 {
+		t.Errorf("level = %v, want 2", metrics.Level())
+	}
+}
+
+func TestSlidingWindowAllowRequestNWithStore(t *testing.T) {
+	store := NewMemoryStore()
+	limiter, err := NewSlidingWindowRateLimiter(10, time.Second,
+		WithClock(NewFakeClock(time.Unix(0, 0))), WithStore(store, "api"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cost := range []int{0, -1, 11} {
+		if limiter.AllowRequestN(cost) {
+			t.Errorf("AllowRequestN(%d) with a store = true, want false", cost)
+		}
+	}
+	if count := limiter.GetRequestCount(); count != 0 {
+		t.Errorf("store count after invalid costs = %d, want 0", count)
+	}
+	if !limiter.AllowRequestN(10) || limiter.AllowRequestN(1) {
+		t.Error("a cost of exactly the limit should fill the window")
+	}
+}
+
+func TestNewSlidingWindowRateLimiterErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxRequests int
+		windowSize  time.Duration
+		opts        []Option
+	}{
+		{"zero max requests", 0, time.Second, nil},
+		{"negative max requests", -1, time.Second, nil},
+		{"zero window", 10, 0, nil},
+		{"negative window", 10, -time.Second, nil},
+		{"empty store key", 10, time.Second, []Option{WithStore(NewMemoryStore(), "")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSlidingWindowRateLimiter(tt.maxRequests, tt.windowSize, tt.opts...); err == nil {
+				t.Error("got nil error")
+			}
+		})
+	}
+}
+
+// sliceSlidingWindow is the original slice-backed sliding window, kept only
+// as a baseline for BenchmarkSlidingWindowAllocs. It appends every admitted
+// request and re-slices to drop expired ones, so the backing array keeps
+// reallocating.
+type sliceSlidingWindow struct {
+	maxRequests int
+	windowSize  time.Duration
+	requests    []time.Time
+}
+
+func (s *sliceSlidingWindow) AllowRequest() bool {
+	now := time.Now()
+	cutoffTime := now.Add(-s.windowSize)
+
+	validIndex := 0
+	for validIndex < len(s.requests) && !s.requests[validIndex].After(cutoffTime) {
+		validIndex++
+	}
+	s.requests = s.requests[validIndex:]
+
+	if len(s.requests) < s.maxRequests {
+		s.requests = append(s.requests, now)
+		return true
+	}
+	return false
+}
+
+// benchmarkAllow runs allow b.N times. Besides the usual allocs/op, which
+// rounds down to whole allocations, it reports mallocs/op as a fraction, since
+// a limiter that reallocates once every few thousand requests still shows up
+// as 0 allocs/op.
+func benchmarkAllow(b *testing.B, allow func() bool) {
+	var before, after runtime.MemStats
+	b.ReportAllocs()
+	runtime.ReadMemStats(&before)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		allow()
+	}
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.Mallocs-before.Mallocs)/float64(b.N), "mallocs/op")
+}
+
+// BenchmarkSlidingWindowAllocs compares heap allocations of the ring-buffer
+// sliding window against the slice-backed original under bursty load: a
+// short window keeps requests expiring, so the slice keeps regrowing.
+func BenchmarkSlidingWindowAllocs(b *testing.B) {
+	const maxRequests = 100
+	window := 5 * time.Millisecond
+
+	b.Run("ring", func(b *testing.B) {
+		limiter, err := NewSlidingWindowRateLimiter(maxRequests, window)
+		if err != nil {
+			b.Fatal(err)
+		}
+		benchmarkAllow(b, limiter.AllowRequest)
+	})
+	b.Run("slice", func(b *testing.B) {
+		limiter := &sliceSlidingWindow{maxRequests: maxRequests, windowSize: window}
+		benchmarkAllow(b, limiter.AllowRequest)
+	})
+}
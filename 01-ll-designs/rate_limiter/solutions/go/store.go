@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Store holds rate limiter counters outside the limiter so that several
+// processes can enforce one shared limit. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	// Incr adds delta to the counter stored at key, creating it at zero if
+	// missing, and returns the new value.
+	Incr(key string, delta int64) (int64, error)
+	// Get returns the counter stored at key, or 0 if it does not exist.
+	Get(key string) (int64, error)
+	// Expire sets a time-to-live after which key is deleted.
+	Expire(key string, ttl time.Duration) error
+}
+
+// memoryEntry is a single counter in a MemoryStore.
+type memoryEntry struct {
+	value     int64
+	expiresAt time.Time // zero means no expiry
+}
+
+// MemoryStore is an in-process Store. It is useful for tests and for sharing
+// one limit between several limiter instances inside the same process.
+// Expired keys are removed lazily when they are accessed.
+type MemoryStore struct {
+	entries map[string]*memoryEntry
+	mu      sync.Mutex
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]*memoryEntry),
+	}
+}
+
+// Incr adds delta to the counter at key and returns the new value.
+func (ms *MemoryStore) Incr(key string, delta int64) (int64, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	entry := ms.lookup(key)
+	if entry == nil {
+		entry = &memoryEntry{}
+		ms.entries[key] = entry
+	}
+	entry.value += delta
+	return entry.value, nil
+}
+
+// Get returns the counter at key, or 0 if it does not exist.
+func (ms *MemoryStore) Get(key string) (int64, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if entry := ms.lookup(key); entry != nil {
+		return entry.value, nil
+	}
+	return 0, nil
+}
+
+// Expire sets a time-to-live on key. It is a no-op for missing keys.
+func (ms *MemoryStore) Expire(key string, ttl time.Duration) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if entry := ms.lookup(key); entry != nil {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	return nil
+}
+
+// lookup returns the live entry for key, deleting it if it has expired.
+// Must be called with lock held.
+func (ms *MemoryStore) lookup(key string) *memoryEntry {
+	entry, exists := ms.entries[key]
+	if !exists {
+		return nil
+	}
+	if !entry.expiresAt.IsZero() && !time.Now().Before(entry.expiresAt) {
+		delete(ms.entries, key)
+		return nil
+	}
+	return entry
+}
+
+// DemoSharedStore demonstrates two sliding window limiters, standing in for
+// two service instances, enforcing one limit through a shared store.
+func DemoSharedStore() {
+	fmt.Println("=== Shared Store Sliding Window Demo ===")
+
+	store := NewMemoryStore()
+	instanceA, err := NewSlidingWindowRateLimiter(6, 2*time.Second, WithStore(store, "api:user-42"))
+	if err != nil {
+		fmt.Printf("Error creating sliding window limiter: %v\n", err)
+		return
+	}
+	instanceB, _ := NewSlidingWindowRateLimiter(6, 2*time.Second, WithStore(store, "api:user-42"))
+
+	for i := 0; i < 8; i++ {
+		limiter, name := instanceA, "A"
+		if i%2 == 1 {
+			limiter, name = instanceB, "B"
+		}
+		status := "BLOCKED"
+		if limiter.AllowRequest() {
+			status = "ALLOWED"
+		}
+		fmt.Printf("Request %d via instance %s: %s (shared count: %d)\n",
+			i+1, name, status, limiter.GetRequestCount())
+	}
+}
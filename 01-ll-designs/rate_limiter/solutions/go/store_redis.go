@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RedisStore is a Store backed by Redis, letting limiters in separate
+// processes share counters. It speaks the RESP protocol directly over a single
+// TCP connection so the package keeps zero external dependencies; only the
+// three commands the Store needs (INCRBY, GET, PEXPIRE) are implemented.
+type RedisStore struct {
+	addr    string
+	timeout time.Duration // dial and per-command timeout
+	conn    net.Conn
+	reader  *bufio.Reader
+	mu      sync.Mutex // serializes commands on the shared connection
+}
+
+// NewRedisStore creates a store using the Redis server at addr. The
+// connection is opened lazily on the first command.
+func NewRedisStore(addr string) *RedisStore {
+	return &RedisStore{
+		addr:    addr,
+		timeout: 100 * time.Millisecond,
+	}
+}
+
+// Incr adds delta to the counter at key using INCRBY.
+func (rs *RedisStore) Incr(key string, delta int64) (int64, error) {
+	reply, err := rs.do("INCRBY", key, strconv.FormatInt(delta, 10))
+	if err != nil {
+		return 0, err
+	}
+	value, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected INCRBY reply %v", reply)
+	}
+	return value, nil
+}
+
+// Get returns the counter at key, or 0 if it does not exist.
+func (rs *RedisStore) Get(key string) (int64, error) {
+	reply, err := rs.do("GET", key)
+	if err != nil {
+		return 0, err
+	}
+	if reply == nil {
+		return 0, nil
+	}
+	value, ok := reply.(string)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected GET reply %v", reply)
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// Expire sets a time-to-live on key using PEXPIRE.
+func (rs *RedisStore) Expire(key string, ttl time.Duration) error {
+	_, err := rs.do("PEXPIRE", key, strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+// Close closes the connection to Redis.
+func (rs *RedisStore) Close() error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.conn == nil {
+		return nil
+	}
+	err := rs.conn.Close()
+	rs.conn = nil
+	return err
+}
+
+// do sends a command and reads its reply, reconnecting once if the existing
+// connection turns out to be broken. A retried INCRBY may be applied twice if
+// only the reply was lost, which errs toward rejecting rather than admitting.
+func (rs *RedisStore) do(args ...string) (interface{}, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	reply, err := rs.roundTrip(args)
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		// Network failure: drop the connection and retry on a fresh one
+		rs.closeConn()
+		reply, err = rs.roundTrip(args)
+		if err != nil && !errors.As(err, &redisErr) {
+			rs.closeConn()
+		}
+	}
+	return reply, err
+}
+
+// roundTrip writes one command and reads one reply. Must be called with lock held.
+func (rs *RedisStore) roundTrip(args []string) (interface{}, error) {
+	if rs.conn == nil {
+		conn, err := net.DialTimeout("tcp", rs.addr, rs.timeout)
+		if err != nil {
+			return nil, err
+		}
+		rs.conn = conn
+		rs.reader = bufio.NewReader(conn)
+	}
+
+	rs.conn.SetDeadline(time.Now().Add(rs.timeout))
+
+	// Commands are sent as a RESP array of bulk strings
+	buf := make([]byte, 0, 64)
+	buf = append(buf, fmt.Sprintf("*%d\r\n", len(args))...)
+	for _, arg := range args {
+		buf = append(buf, fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg)...)
+	}
+	if _, err := rs.conn.Write(buf); err != nil {
+		return nil, err
+	}
+
+	return rs.readReply()
+}
+
+// readReply parses a single RESP reply. Bulk strings are returned as string,
+// integers as int64, and a nil bulk string as nil.
+func (rs *RedisStore) readReply() (interface{}, error) {
+	line, err := rs.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	prefix, body := line[0], line[1:len(line)-2]
+
+	switch prefix {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		length, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		data := make([]byte, length+2) // payload plus trailing CRLF
+		if _, err := io.ReadFull(rs.reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:length]), nil
+	default:
+		return nil, fmt.Errorf("redis: unsupported reply type %q", prefix)
+	}
+}
+
+// closeConn drops the current connection. Must be called with lock held.
+func (rs *RedisStore) closeConn() {
+	if rs.conn != nil {
+		rs.conn.Close()
+		rs.conn = nil
+	}
+}
+
+// redisError is an error reply sent by the server, as opposed to a network failure.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
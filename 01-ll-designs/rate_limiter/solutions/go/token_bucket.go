@@ -16,15 +16,19 @@ import (
 // Time Complexity: O(1) per request
 // Space Complexity: O(1)
 type TokenBucket struct {
-	capacity   int           // Maximum number of tokens
-	tokens     float64       // Current number of tokens
-	refillRate float64       // Tokens added per second
-	lastRefill time.Time     // Last time tokens were refilled
-	mu         sync.Mutex    // Mutex for thread safety
+	capacity   int            // Maximum number of tokens
+	tokens     float64        // Current number of tokens
+	refillRate float64        // Tokens added per second
+	lastRefill time.Time      // Last time tokens were refilled
+	metrics    Metrics        // Optional decision observer
+	jitter     JitterStrategy // Spread for GetTimeUntilNextAllowedRequestJittered
+	clock      Clock          // Time source for refills
+	mu         sync.Mutex     // Mutex for thread safety
 }
 
-// NewTokenBucket creates a new TokenBucket rate limiter.
-func NewTokenBucket(capacity int, refillRate float64) (*TokenBucket, error) {
+// NewTokenBucket creates a new TokenBucket rate limiter. Its state is always
+// in-process, so WithStore is an error.
+func NewTokenBucket(capacity int, refillRate float64, opts ...Option) (*TokenBucket, error) {
 	if capacity <= 0 {
 		return nil, errors.New("capacity must be positive")
 	}
@@ -32,11 +36,19 @@ func NewTokenBucket(capacity int, refillRate float64) (*TokenBucket, error) {
 		return nil, errors.New("refill rate must be positive")
 	}
 
+	options := newLimiterOptions(opts)
+	if options.store != nil {
+		return nil, errors.New("token bucket does not support a shared store")
+	}
+
 	return &TokenBucket{
 		capacity:   capacity,
 		tokens:     float64(capacity), // Start with full bucket
 		refillRate: refillRate,
-		lastRefill: time.Now(),
+		lastRefill: options.clock.Now(),
+		metrics:    options.metrics,
+		jitter:     options.jitter,
+		clock:      options.clock,
 	}, nil
 }
 
@@ -49,19 +61,65 @@ func (tb *TokenBucket) AllowRequest(tokensRequested int) bool {
 
 	if tb.tokens >= float64(tokensRequested) {
 		tb.tokens -= float64(tokensRequested)
+		tb.observe(true)
 		return true
 	}
+	tb.observe(false)
 	return false
 }
 
+// observe reports a decision to the metrics hook, if any. Must be called with lock held.
+func (tb *TokenBucket) observe(allowed bool) {
+	if tb.metrics == nil {
+		return
+	}
+	if allowed {
+		tb.metrics.ObserveAllowed()
+	} else {
+		tb.metrics.ObserveBlocked()
+	}
+	tb.metrics.SetLevel(tb.tokens)
+}
+
 // AllowSingleRequest attempts to consume one token for a request.
 func (tb *TokenBucket) AllowSingleRequest() bool {
 	return tb.AllowRequest(1)
 }
 
+// Reserve reserves tokens for a request and reports how long the caller must
+// wait before acting on it. If enough tokens are available they are consumed
+// immediately and wait is zero. Otherwise the tokens are still taken, driving
+// the balance negative, and wait is the time the refill needs to pay back the
+// deficit, so callers can time.Sleep(wait) instead of polling.
+//
+// A request larger than the bucket capacity can never be satisfied; it is
+// rejected with ok=false and nothing is reserved.
+func (tb *TokenBucket) Reserve(tokens int) (ok bool, wait time.Duration) {
+	if tokens <= 0 || tokens > tb.capacity {
+		return false, 0
+	}
+
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.refillTokens()
+
+	requested := float64(tokens)
+	defer tb.observe(true)
+	if tb.tokens >= requested {
+		tb.tokens -= requested
+		return true, 0
+	}
+
+	deficit := requested - tb.tokens
+	tb.tokens -= requested
+	wait = time.Duration(deficit / tb.refillRate * float64(time.Second))
+	return true, wait
+}
+
 // refillTokens adds tokens based on elapsed time since last refill.
 func (tb *TokenBucket) refillTokens() {
-	now := time.Now()
+	now := tb.clock.Now()
 	elapsed := now.Sub(tb.lastRefill).Seconds()
 	tb.lastRefill = now
 
@@ -79,6 +137,18 @@ func (tb *TokenBucket) GetAvailableTokens() float64 {
 	return tb.tokens
 }
 
+// GetTimeUntilNextAllowedRequest calculates the time until a token is available.
+func (tb *TokenBucket) GetTimeUntilNextAllowedRequest() time.Duration {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.refillTokens()
+	if tb.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - tb.tokens) / tb.refillRate * float64(time.Second))
+}
+
 // WaitForToken waits until a token becomes available or context is cancelled.
 func (tb *TokenBucket) WaitForToken(ctx context.Context) error {
 	ticker := time.NewTicker(10 * time.Millisecond)
@@ -164,6 +234,32 @@ func DemoTokenBucket() {
 	}
 }
 
+// DemoReserve demonstrates reserving tokens ahead of time instead of polling.
+func DemoReserve() {
+	fmt.Println("=== Token Bucket Reserve Demo ===")
+
+	// Capacity 3, refill rate 2 tokens/second
+	limiter, err := NewTokenBucket(3, 2.0)
+	if err != nil {
+		fmt.Printf("Error creating token bucket: %v\n", err)
+		return
+	}
+
+	for i := 0; i < 5; i++ {
+		ok, wait := limiter.Reserve(1)
+		fmt.Printf("Reserve %d: ok=%t, wait=%v\n", i+1, ok, wait.Round(time.Millisecond))
+	}
+
+	ok, wait := limiter.Reserve(limiter.GetCapacity() + 1)
+	fmt.Printf("Reserve above capacity: ok=%t, wait=%v\n", ok, wait)
+
+	// Sleep exactly as long as the last successful reservation requires
+	_, wait = limiter.Reserve(1)
+	fmt.Printf("Sleeping %v for the next reserved token...\n", wait.Round(time.Millisecond))
+	time.Sleep(wait)
+	fmt.Println("Request sent")
+}
+
 // BenchmarkTokenBucket performs a simple benchmark of the token bucket.
 func BenchmarkTokenBucket() {
 	fmt.Println("\n=== Token Bucket Benchmark ===")
@@ -183,4 +279,4 @@ func BenchmarkTokenBucket() {
 	fmt.Printf("Processed %d requests in %v\n", iterations, elapsed)
 	fmt.Printf("Allowed: %d, Blocked: %d\n", allowed, iterations-allowed)
 	fmt.Printf("Throughput: %.0f requests/second\n", float64(iterations)/elapsed.Seconds())
-}
\ No newline at end of file
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"sync/atomic"
+)
+
+// BloomFilter is the filter from 01-ll-designs/bloom_filter, cut down to what
+// the broker needs. The designs are standalone programs rather than
+// importable packages, so the broker carries its own copy. It sizes itself
+// for an expected number of elements and false positive rate, and derives
+// its k bit indices from MurmurHash3 and FNV-1a by double hashing.
+type BloomFilter struct {
+	bits              []uint64
+	bitArraySize      uint32
+	numHashFunctions  uint32
+	expectedElements  uint32
+	falsePositiveRate float64
+	numElements       uint32
+}
+
+// maxBloomBits caps a filter's bit array at 128 MB, well below where its
+// uint32 size and word count would wrap
+const maxBloomBits = 1 << 30
+
+// NewBloomFilter creates a Bloom filter with optimal parameters. It fails
+// if they need more than maxBloomBits bits.
+func NewBloomFilter(expectedElements uint32, falsePositiveRate float64) (*BloomFilter, error) {
+	if expectedElements == 0 {
+		return nil, fmt.Errorf("expected elements must be positive")
+	}
+	// Written so NaN fails too
+	if !(falsePositiveRate > 0.0 && falsePositiveRate < 1.0) {
+		return nil, fmt.Errorf("false positive rate must be between 0 and 1")
+	}
+
+	// m = -(n * ln(p)) / (ln(2)^2), k = (m / n) * ln(2). The size is checked
+	// as a float, before it is narrowed to uint32.
+	size := math.Max(1, math.Ceil(-(float64(expectedElements)*math.Log(falsePositiveRate))/(math.Log(2)*math.Log(2))))
+	if size > maxBloomBits {
+		return nil, fmt.Errorf("%d elements at false positive rate %g need %.0f bits, over the %d bit limit",
+			expectedElements, falsePositiveRate, size, maxBloomBits)
+	}
+	bitArraySize := uint32(size)
+	k := (float64(bitArraySize) / float64(expectedElements)) * math.Log(2)
+	numHashFunctions := uint32(math.Max(1, math.Round(k)))
+
+	return &BloomFilter{
+		bits:              make([]uint64, (bitArraySize+63)/64),
+		bitArraySize:      bitArraySize,
+		numHashFunctions:  numHashFunctions,
+		expectedElements:  expectedElements,
+		falsePositiveRate: falsePositiveRate,
+	}, nil
+}
+
+// Add adds an element to the filter
+func (bf *BloomFilter) Add(element string) {
+	h1, h2 := bloomHashes([]byte(element))
+	for i := uint32(0); i < bf.numHashFunctions; i++ {
+		index := bf.index(h1, h2, i)
+		addr := &bf.bits[index/64]
+		for {
+			old := atomic.LoadUint64(addr)
+			updated := old | 1<<(index%64)
+			if updated == old || atomic.CompareAndSwapUint64(addr, old, updated) {
+				break
+			}
+		}
+	}
+	atomic.AddUint32(&bf.numElements, 1)
+}
+
+// Contains reports whether an element might be in the set. False means it
+// was definitely never added.
+func (bf *BloomFilter) Contains(element string) bool {
+	h1, h2 := bloomHashes([]byte(element))
+	for i := uint32(0); i < bf.numHashFunctions; i++ {
+		index := bf.index(h1, h2, i)
+		if atomic.LoadUint64(&bf.bits[index/64])&(1<<(index%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Size returns the number of elements added
+func (bf *BloomFilter) Size() uint32 {
+	return atomic.LoadUint32(&bf.numElements)
+}
+
+// FillRatio returns the fraction of bits set
+func (bf *BloomFilter) FillRatio() float64 {
+	setBits := 0
+	for i := range bf.bits {
+		setBits += bits.OnesCount64(atomic.LoadUint64(&bf.bits[i]))
+	}
+	return float64(setBits) / float64(bf.bitArraySize)
+}
+
+// CurrentFalsePositiveRate estimates the chance that Contains returns true
+// for an element never added, given the bits set so far. It passes the
+// configured rate once more than the expected number of elements are added.
+func (bf *BloomFilter) CurrentFalsePositiveRate() float64 {
+	return math.Pow(bf.FillRatio(), float64(bf.numHashFunctions))
+}
+
+// index returns the i-th bit index of the Kirsch-Mitzenmacher double hashing
+// scheme, (h1 + i*h2) mod size
+func (bf *BloomFilter) index(h1, h2, i uint32) uint32 {
+	return uint32((uint64(h1) + uint64(i)*uint64(h2)) % uint64(bf.bitArraySize))
+}
+
+// bloomHashes computes the two hashes an element's bit indices are derived
+// from. The second is forced odd so the indices never all collapse onto h1.
+func bloomHashes(data []byte) (h1, h2 uint32) {
+	return murmurHash3(data, 0), fnvHash(data, 0) | 1
+}
+
+// murmurHash3 is 32-bit MurmurHash3
+func murmurHash3(data []byte, seed uint32) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+
+	hash := seed
+	length := len(data)
+	roundedEnd := (length >> 2) << 2
+
+	for i := 0; i < roundedEnd; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i : i+4])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		hash ^= k
+		hash = bits.RotateLeft32(hash, 13)*5 + 0xe6546b64
+	}
+
+	k1 := uint32(0)
+	switch length & 3 {
+	case 3:
+		k1 ^= uint32(data[roundedEnd+2]) << 16
+		fallthrough
+	case 2:
+		k1 ^= uint32(data[roundedEnd+1]) << 8
+		fallthrough
+	case 1:
+		k1 ^= uint32(data[roundedEnd])
+		k1 *= c1
+		k1 = bits.RotateLeft32(k1, 15)
+		k1 *= c2
+		hash ^= k1
+	}
+
+	hash ^= uint32(length)
+	hash ^= hash >> 16
+	hash *= 0x85ebca6b
+	hash ^= hash >> 13
+	hash *= 0xc2b2ae35
+	hash ^= hash >> 16
+	return hash
+}
+
+// fnvHash is 32-bit FNV-1a with the seed hashed after the data
+func fnvHash(data []byte, seed uint32) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+
+	hash := uint32(offset32)
+	for _, b := range data {
+		hash ^= uint32(b)
+		hash *= prime32
+	}
+	for shift := uint(0); shift < 32; shift += 8 {
+		hash ^= (seed >> shift) & 0xff
+		hash *= prime32
+	}
+	return hash
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a CircuitBreaker
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // calls pass through and are counted
+	BreakerOpen                         // calls are rejected without running
+	BreakerHalfOpen                     // a few probe calls test whether to close
+)
+
+// String returns the state name
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// errCircuitOpen is returned by CircuitBreaker.Execute when a call is
+// rejected without running
+var errCircuitOpen = fmt.Errorf("circuit breaker is open")
+
+// BreakerConfig sets when a CircuitBreaker trips and how it recovers
+type BreakerConfig struct {
+	FailureRate float64       // fraction of calls in a window that trips the breaker; 0 never trips
+	MinCalls    int           // calls a window needs before its failure rate counts
+	SlowCall    time.Duration // a successful call slower than this counts as a failure; 0 disables
+	Window      time.Duration // how long calls are counted before the counts start over
+	OpenTimeout time.Duration // how long the breaker stays open before probing
+	Probes      int           // successful probes in a row that close it again
+}
+
+// CircuitBreaker stops a component from taking on work while it is failing
+// or overloaded. Closed, it counts calls that fail or run slow; once enough
+// of a window's calls do, it opens and rejects every call at once. After
+// OpenTimeout it lets Probes calls through half-open: if they all succeed it
+// closes, and if any fails it opens again. It depends on nothing in the
+// broker, so it can be copied in front of other services the same way
+// bloom.go was.
+type CircuitBreaker struct {
+	mu     sync.Mutex
+	name   string
+	config BreakerConfig
+	now    func() time.Time
+
+	state       BreakerState
+	changedAt   time.Time
+	generation  uint64 // bumped on every transition, so late results are ignored
+	windowStart time.Time
+	calls       int
+	failures    int
+	probing     int // half-open calls in flight
+	probesOK    int
+	trips       int
+}
+
+// NewCircuitBreaker creates a closed circuit breaker reading the time from now
+func NewCircuitBreaker(name string, config BreakerConfig, now func() time.Time) *CircuitBreaker {
+	if config.Probes < 1 {
+		config.Probes = 1
+	}
+	start := now()
+	return &CircuitBreaker{
+		name:        name,
+		config:      config,
+		now:         now,
+		changedAt:   start,
+		windowStart: start,
+	}
+}
+
+// Execute runs fn if the breaker allows it and records the outcome. An
+// error from fn counts as a failure, so fn should return nil for errors
+// that say nothing about the component's health. A rejected call returns
+// errCircuitOpen without running fn.
+func (cb *CircuitBreaker) Execute(fn func() error) error {
+	generation, err := cb.allow()
+	if err != nil {
+		return err
+	}
+
+	start := cb.now()
+	err = fn()
+	slow := cb.config.SlowCall > 0 && cb.now().Sub(start) > cb.config.SlowCall
+	cb.record(generation, err != nil || slow)
+	return err
+}
+
+// allow admits a call, moving an open breaker to half-open once its
+// timeout has passed, and returns the generation the call belongs to
+func (cb *CircuitBreaker) allow() (uint64, error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	now := cb.now()
+	switch cb.state {
+	case BreakerOpen:
+		if now.Sub(cb.changedAt) < cb.config.OpenTimeout {
+			return 0, errCircuitOpen
+		}
+		cb.setState(BreakerHalfOpen, now)
+		fallthrough
+	case BreakerHalfOpen:
+		if cb.probing+cb.probesOK >= cb.config.Probes {
+			return 0, errCircuitOpen
+		}
+		cb.probing++
+	default:
+		if now.Sub(cb.windowStart) >= cb.config.Window {
+			cb.windowStart, cb.calls, cb.failures = now, 0, 0
+		}
+	}
+	return cb.generation, nil
+}
+
+// record counts a finished call. Calls admitted before the last transition
+// are ignored: a slow call from before the breaker opened says nothing about
+// whether it has recovered.
+func (cb *CircuitBreaker) record(generation uint64, failed bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if generation != cb.generation {
+		return
+	}
+
+	now := cb.now()
+	switch cb.state {
+	case BreakerHalfOpen:
+		cb.probing--
+		if failed {
+			cb.setState(BreakerOpen, now)
+			return
+		}
+		cb.probesOK++
+		if cb.probesOK >= cb.config.Probes {
+			cb.setState(BreakerClosed, now)
+		}
+	case BreakerClosed:
+		cb.calls++
+		if failed {
+			cb.failures++
+		}
+		if cb.config.FailureRate > 0 && cb.calls >= cb.config.MinCalls &&
+			float64(cb.failures) >= cb.config.FailureRate*float64(cb.calls) {
+			cb.setState(BreakerOpen, now)
+		}
+	}
+}
+
+// setState moves the breaker to a new state and starts its counts over.
+// The caller must hold cb.mu.
+func (cb *CircuitBreaker) setState(state BreakerState, now time.Time) {
+	if state == BreakerOpen {
+		cb.trips++
+		log.Printf("Circuit breaker %s opened: %d of %d calls failed", cb.name, cb.failures, cb.calls)
+	} else {
+		log.Printf("Circuit breaker %s: %s -> %s", cb.name, cb.state, state)
+	}
+
+	cb.state = state
+	cb.changedAt = now
+	cb.generation++
+	cb.windowStart, cb.calls, cb.failures = now, 0, 0
+	cb.probing, cb.probesOK = 0, 0
+}
+
+// State returns the breaker's state. An open breaker whose timeout has
+// passed reports half-open, which it becomes on the next call.
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.currentState()
+}
+
+// currentState is State for callers holding cb.mu
+func (cb *CircuitBreaker) currentState() BreakerState {
+	if cb.state == BreakerOpen && cb.now().Sub(cb.changedAt) >= cb.config.OpenTimeout {
+		return BreakerHalfOpen
+	}
+	return cb.state
+}
+
+// RetryAfter returns how long until an open breaker starts probing, or 0
+func (cb *CircuitBreaker) RetryAfter() time.Duration {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state != BreakerOpen {
+		return 0
+	}
+	if remaining := cb.config.OpenTimeout - cb.now().Sub(cb.changedAt); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// Stats describes the breaker for the health endpoint
+func (cb *CircuitBreaker) Stats() map[string]interface{} {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return map[string]interface{}{
+		"state":    cb.currentState().String(),
+		"since":    cb.changedAt,
+		"calls":    cb.calls,
+		"failures": cb.failures,
+		"trips":    cb.trips,
+	}
+}
+
+// breakerConfigFromEnv reads the publish circuit breaker's settings,
+// keeping the default for any that are missing or invalid
+func breakerConfigFromEnv() BreakerConfig {
+	config := BreakerConfig{
+		FailureRate: 0.5,
+		MinCalls:    20,
+		SlowCall:    250 * time.Millisecond,
+		Window:      10 * time.Second,
+		OpenTimeout: 5 * time.Second,
+		Probes:      5,
+	}
+
+	if rate, err := strconv.ParseFloat(getEnv("BREAKER_FAILURE_RATE", "0.5"), 64); err == nil && rate >= 0 && rate <= 1 {
+		config.FailureRate = rate
+	} else {
+		log.Printf("Invalid BREAKER_FAILURE_RATE, using %g", config.FailureRate)
+	}
+	if n, err := strconv.Atoi(getEnv("BREAKER_MIN_CALLS", "20")); err == nil && n > 0 {
+		config.MinCalls = n
+	} else {
+		log.Printf("Invalid BREAKER_MIN_CALLS, using %d", config.MinCalls)
+	}
+	if n, err := strconv.Atoi(getEnv("BREAKER_PROBES", "5")); err == nil && n > 0 {
+		config.Probes = n
+	} else {
+		log.Printf("Invalid BREAKER_PROBES, using %d", config.Probes)
+	}
+
+	durations := []struct {
+		key    string
+		target *time.Duration
+	}{
+		{"BREAKER_SLOW_CALL", &config.SlowCall},
+		{"BREAKER_WINDOW", &config.Window},
+		{"BREAKER_OPEN_TIMEOUT", &config.OpenTimeout},
+	}
+	for _, d := range durations {
+		value, err := time.ParseDuration(getEnv(d.key, d.target.String()))
+		if err != nil || value < 0 {
+			log.Printf("Invalid %s, using %s", d.key, *d.target)
+			continue
+		}
+		*d.target = value
+	}
+
+	return config
+}
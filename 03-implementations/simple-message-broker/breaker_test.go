@@ -0,0 +1,254 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var errTestFailure = errors.New("test failure")
+
+// testBreakerConfig trips on half of four calls and probes twice
+var testBreakerConfig = BreakerConfig{
+	FailureRate: 0.5,
+	MinCalls:    4,
+	SlowCall:    100 * time.Millisecond,
+	Window:      10 * time.Second,
+	OpenTimeout: 5 * time.Second,
+	Probes:      2,
+}
+
+// runCalls executes one call per entry, failing the call when the entry is true
+func runCalls(t *testing.T, cb *CircuitBreaker, failures ...bool) {
+	t.Helper()
+	for i, fail := range failures {
+		err := cb.Execute(func() error {
+			if fail {
+				return errTestFailure
+			}
+			return nil
+		})
+		if err == errCircuitOpen {
+			t.Fatalf("call %d rejected with the breaker %s", i, cb.State())
+		}
+	}
+}
+
+// tripBreaker opens cb with MinCalls failures
+func tripBreaker(t *testing.T, cb *CircuitBreaker) {
+	t.Helper()
+	failures := make([]bool, cb.config.MinCalls)
+	for i := range failures {
+		failures[i] = true
+	}
+	runCalls(t, cb, failures...)
+	if state := cb.State(); state != BreakerOpen {
+		t.Fatalf("state after %d failures = %s, want open", len(failures), state)
+	}
+}
+
+func TestBreakerOpensAtFailureRate(t *testing.T) {
+	clock := newTestClock()
+	cb := NewCircuitBreaker("test", testBreakerConfig, clock.Now)
+
+	// One failure in four stays under the rate
+	runCalls(t, cb, true, false, false, false)
+	if state := cb.State(); state != BreakerClosed {
+		t.Fatalf("state after 1 of 4 failures = %s, want closed", state)
+	}
+
+	// A new window starts the counts over; two failures in four trip it
+	clock.Advance(testBreakerConfig.Window)
+	runCalls(t, cb, true, false, true)
+	if state := cb.State(); state != BreakerClosed {
+		t.Fatalf("state before MinCalls = %s, want closed", state)
+	}
+	runCalls(t, cb, false)
+	if state := cb.State(); state != BreakerOpen {
+		t.Fatalf("state after 2 of 4 failures = %s, want open", state)
+	}
+}
+
+func TestBreakerWindowForgetsOldFailures(t *testing.T) {
+	clock := newTestClock()
+	cb := NewCircuitBreaker("test", testBreakerConfig, clock.Now)
+
+	// Failures spread over windows never reach MinCalls in any one of them
+	for i := 0; i < 3; i++ {
+		runCalls(t, cb, true, true, true)
+		clock.Advance(testBreakerConfig.Window)
+	}
+	if state := cb.State(); state != BreakerClosed {
+		t.Errorf("state = %s, want closed", state)
+	}
+}
+
+func TestBreakerSlowCallCountsAsFailure(t *testing.T) {
+	clock := newTestClock()
+	cb := NewCircuitBreaker("test", testBreakerConfig, clock.Now)
+
+	for i := 0; i < testBreakerConfig.MinCalls; i++ {
+		err := cb.Execute(func() error {
+			clock.Advance(testBreakerConfig.SlowCall + time.Millisecond)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("slow call %d: %v", i, err)
+		}
+	}
+	if state := cb.State(); state != BreakerOpen {
+		t.Errorf("state after %d slow calls = %s, want open", testBreakerConfig.MinCalls, state)
+	}
+}
+
+func TestBreakerOpenRejectsWithoutRunning(t *testing.T) {
+	clock := newTestClock()
+	cb := NewCircuitBreaker("test", testBreakerConfig, clock.Now)
+	tripBreaker(t, cb)
+
+	ran := false
+	err := cb.Execute(func() error {
+		ran = true
+		return nil
+	})
+	if err != errCircuitOpen {
+		t.Errorf("Execute while open = %v, want errCircuitOpen", err)
+	}
+	if ran {
+		t.Error("Execute ran the call while open")
+	}
+
+	if got := cb.RetryAfter(); got != testBreakerConfig.OpenTimeout {
+		t.Errorf("RetryAfter just after opening = %v, want %v", got, testBreakerConfig.OpenTimeout)
+	}
+	clock.Advance(2 * time.Second)
+	if got, want := cb.RetryAfter(), testBreakerConfig.OpenTimeout-2*time.Second; got != want {
+		t.Errorf("RetryAfter 2s after opening = %v, want %v", got, want)
+	}
+}
+
+func TestBreakerHalfOpenClosesAfterProbes(t *testing.T) {
+	clock := newTestClock()
+	cb := NewCircuitBreaker("test", testBreakerConfig, clock.Now)
+	tripBreaker(t, cb)
+
+	clock.Advance(testBreakerConfig.OpenTimeout)
+	if state := cb.State(); state != BreakerHalfOpen {
+		t.Fatalf("state after OpenTimeout = %s, want half-open", state)
+	}
+	if got := cb.RetryAfter(); got != 0 {
+		t.Errorf("RetryAfter after OpenTimeout = %v, want 0", got)
+	}
+
+	runCalls(t, cb, false)
+	if state := cb.State(); state != BreakerHalfOpen {
+		t.Fatalf("state after 1 of %d probes = %s, want half-open", testBreakerConfig.Probes, state)
+	}
+	runCalls(t, cb, false)
+	if state := cb.State(); state != BreakerClosed {
+		t.Fatalf("state after %d probes = %s, want closed", testBreakerConfig.Probes, state)
+	}
+
+	// Closing starts the counts over, so one failure doesn't reopen it
+	runCalls(t, cb, true)
+	if state := cb.State(); state != BreakerClosed {
+		t.Errorf("state after one failure once closed = %s, want closed", state)
+	}
+}
+
+func TestBreakerFailedProbeReopens(t *testing.T) {
+	clock := newTestClock()
+	cb := NewCircuitBreaker("test", testBreakerConfig, clock.Now)
+	tripBreaker(t, cb)
+
+	clock.Advance(testBreakerConfig.OpenTimeout)
+	runCalls(t, cb, false, true)
+	if state := cb.State(); state != BreakerOpen {
+		t.Fatalf("state after a failed probe = %s, want open", state)
+	}
+	if got := cb.RetryAfter(); got != testBreakerConfig.OpenTimeout {
+		t.Errorf("RetryAfter after reopening = %v, want %v", got, testBreakerConfig.OpenTimeout)
+	}
+	if trips := cb.Stats()["trips"]; trips != 2 {
+		t.Errorf("trips = %v, want 2", trips)
+	}
+}
+
+func TestBreakerHalfOpenLimitsProbesInFlight(t *testing.T) {
+	clock := newTestClock()
+	config := testBreakerConfig
+	config.Probes = 1
+	cb := NewCircuitBreaker("test", config, clock.Now)
+	tripBreaker(t, cb)
+	clock.Advance(config.OpenTimeout)
+
+	// While the only probe runs, a second call is turned away
+	var nested error
+	err := cb.Execute(func() error {
+		nested = cb.Execute(func() error { return nil })
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if nested != errCircuitOpen {
+		t.Errorf("call during probe = %v, want errCircuitOpen", nested)
+	}
+	if state := cb.State(); state != BreakerClosed {
+		t.Errorf("state after the probe = %s, want closed", state)
+	}
+}
+
+func TestBreakerIgnoresResultsFromBeforeTransition(t *testing.T) {
+	clock := newTestClock()
+	cb := NewCircuitBreaker("test", testBreakerConfig, clock.Now)
+
+	// A call admitted while closed finishes after the breaker has tripped
+	// and recovered; its failure must not count against the new state
+	runCalls(t, cb, true, true, true)
+	err := cb.Execute(func() error {
+		runCalls(t, cb, true)
+		clock.Advance(testBreakerConfig.OpenTimeout)
+		runCalls(t, cb, false, false)
+		return errTestFailure
+	})
+	if err != errTestFailure {
+		t.Fatalf("Execute = %v, want the call's own error", err)
+	}
+	if state := cb.State(); state != BreakerClosed {
+		t.Errorf("state = %s, want closed", state)
+	}
+	if failures := cb.Stats()["failures"]; failures != 0 {
+		t.Errorf("failures = %v, want 0", failures)
+	}
+}
+
+func TestQueueFullDoesNotTripPublishBreaker(t *testing.T) {
+	clock := newTestClock()
+	mb := newTestBroker(t, clock)
+	config := testBreakerConfig
+	config.SlowCall = 0
+	mb.breaker = NewCircuitBreaker("publish", config, clock.Now)
+
+	if _, err := mb.CreateTopic("full", TopicConfig{MaxQueueSize: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mb.PublishMessage("full", "first", nil); err != nil {
+		t.Fatalf("first publish: %v", err)
+	}
+
+	for i := 0; i < 10*config.MinCalls; i++ {
+		_, err := mb.PublishMessage("full", fmt.Sprintf("overflow-%d", i), nil)
+		if code := errorCode(err); code != CodeQueueFull {
+			t.Fatalf("publish %d to a full topic: code %q, want %q", i, code, CodeQueueFull)
+		}
+	}
+
+	if state := mb.breaker.State(); state != BreakerClosed {
+		t.Errorf("breaker after queue_full publishes = %s, want closed", state)
+	}
+	if _, err := mb.PublishMessage("other", "hello", nil); err != nil {
+		t.Errorf("publish to another topic: %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package brokerclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrPublisherClosed is the result of a message published after Close
+var ErrPublisherClosed = errors.New("batch publisher is closed")
+
+// BatchOptions sets when a BatchPublisher sends a batch
+type BatchOptions struct {
+	MaxBatchSize int           // send once this many messages are waiting; default 100
+	MaxDelay     time.Duration // send once the oldest waiting message is this old; default 50ms
+}
+
+// Future is the pending result of a message given to a BatchPublisher
+type Future struct {
+	done    chan struct{}
+	receipt *Receipt
+	err     error
+}
+
+// Done is closed once the message's batch has been sent, or has failed
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait returns the message's receipt, or the error that failed its batch.
+// If ctx ends first, it returns ctx's error; the message may still be
+// published.
+func (f *Future) Wait(ctx context.Context) (*Receipt, error) {
+	select {
+	case <-f.done:
+		return f.receipt, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// resolve records the result and wakes waiters
+func (f *Future) resolve(receipt *Receipt, err error) {
+	f.receipt, f.err = receipt, err
+	close(f.done)
+}
+
+// batchEntry is a message waiting to be sent
+type batchEntry struct {
+	data   interface{}
+	future *Future
+}
+
+// BatchPublisher collects messages for one topic and publishes them with
+// POST /publish/batch/{topic}, in the order they were given, once
+// MaxBatchSize are waiting or the oldest has waited MaxDelay. Batches are
+// sent one at a time; while one is in flight and another is ready, Publish
+// blocks, so a producer can't outrun the broker without bound. If a batch
+// request fails, every message in it gets the error, although the broker
+// may have published the ones before the message that failed.
+//
+// The context given to NewBatchPublisher bounds every request: once it is
+// cancelled, waiting and new messages fail with its error. Close must be
+// called to flush the last batch and stop the publisher's goroutine.
+type BatchPublisher struct {
+	client  *Client
+	topic   string
+	options BatchOptions
+	ctx     context.Context
+
+	mutex      sync.Mutex
+	batch      []batchEntry
+	timer      *time.Timer
+	generation uint64 // bumped on every flush, so a stale timer does nothing
+	closed     bool
+
+	batches chan []batchEntry // ready batches, in order, for sendBatches
+	stopped chan struct{}     // closed when sendBatches returns
+}
+
+// NewBatchPublisher creates a batch publisher for a topic
+func (c *Client) NewBatchPublisher(ctx context.Context, topic string, options BatchOptions) *BatchPublisher {
+	if options.MaxBatchSize < 1 {
+		options.MaxBatchSize = 100
+	}
+	if options.MaxDelay <= 0 {
+		options.MaxDelay = 50 * time.Millisecond
+	}
+
+	bp := &BatchPublisher{
+		client:  c,
+		topic:   topic,
+		options: options,
+		ctx:     ctx,
+		batches: make(chan []batchEntry, 1),
+		stopped: make(chan struct{}),
+	}
+	go bp.sendBatches()
+	return bp
+}
+
+// Publish adds a message to the current batch and returns its future
+func (bp *BatchPublisher) Publish(data interface{}) *Future {
+	future := &Future{done: make(chan struct{})}
+
+	bp.mutex.Lock()
+	defer bp.mutex.Unlock()
+
+	if bp.closed {
+		future.resolve(nil, ErrPublisherClosed)
+		return future
+	}
+	if err := bp.ctx.Err(); err != nil {
+		future.resolve(nil, err)
+		return future
+	}
+
+	bp.batch = append(bp.batch, batchEntry{data: data, future: future})
+	if len(bp.batch) >= bp.options.MaxBatchSize {
+		bp.flushLocked()
+	} else if len(bp.batch) == 1 {
+		generation := bp.generation
+		bp.timer = time.AfterFunc(bp.options.MaxDelay, func() {
+			bp.mutex.Lock()
+			defer bp.mutex.Unlock()
+			if bp.generation == generation && !bp.closed {
+				bp.flushLocked()
+			}
+		})
+	}
+	return future
+}
+
+// Flush sends the current batch now and waits for it
+func (bp *BatchPublisher) Flush(ctx context.Context) error {
+	bp.mutex.Lock()
+	if len(bp.batch) == 0 || bp.closed {
+		bp.mutex.Unlock()
+		return nil
+	}
+	last := bp.batch[len(bp.batch)-1].future
+	bp.flushLocked()
+	bp.mutex.Unlock()
+
+	_, err := last.Wait(ctx)
+	return err
+}
+
+// Close sends the current batch, waits for every batch to finish, and
+// stops the publisher. It returns the last batch's error, if any. Messages
+// published afterwards fail with ErrPublisherClosed.
+func (bp *BatchPublisher) Close() error {
+	bp.mutex.Lock()
+	if bp.closed {
+		bp.mutex.Unlock()
+		return nil
+	}
+	bp.closed = true
+	var last *Future
+	if len(bp.batch) > 0 {
+		last = bp.batch[len(bp.batch)-1].future
+		bp.flushLocked()
+	}
+	close(bp.batches)
+	bp.mutex.Unlock()
+
+	<-bp.stopped
+	if last == nil {
+		return nil
+	}
+	return last.err
+}
+
+// flushLocked hands the current batch to sendBatches. It blocks while
+// another batch is already waiting. The caller holds bp.mutex.
+func (bp *BatchPublisher) flushLocked() {
+	if bp.timer != nil {
+		bp.timer.Stop()
+		bp.timer = nil
+	}
+	bp.generation++
+
+	batch := bp.batch
+	bp.batch = nil
+	bp.batches <- batch
+}
+
+// sendBatches publishes batches in order and resolves their futures
+func (bp *BatchPublisher) sendBatches() {
+	defer close(bp.stopped)
+
+	for batch := range bp.batches {
+		data := make([]interface{}, len(batch))
+		for i, entry := range batch {
+			data[i] = entry.data
+		}
+
+		receipts, err := bp.client.PublishBatch(bp.ctx, bp.topic, data)
+		for i, entry := range batch {
+			if err != nil {
+				entry.future.resolve(nil, err)
+				continue
+			}
+			receipt := receipts[i]
+			entry.future.resolve(&receipt, nil)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package brokerclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubBroker answers POST /publish/batch/{topic} like the broker, numbering
+// messages in arrival order, and records the size of every batch. With fail
+// set it answers every request with a queue_full error instead.
+type stubBroker struct {
+	*httptest.Server
+	fail bool
+
+	mutex    sync.Mutex
+	batches  []int
+	sequence uint64
+}
+
+func newStubBroker(t *testing.T, fail bool) *stubBroker {
+	stub := &stubBroker{fail: fail}
+	stub.Server = httptest.NewServer(http.HandlerFunc(stub.serve))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+func (s *stubBroker) serve(w http.ResponseWriter, r *http.Request) {
+	var data []interface{}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.batches = append(s.batches, len(data))
+
+	w.Header().Set("Content-Type", "application/json")
+	if s.fail {
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"code": "queue_full", "message": "topic orders queue is full"}`)
+		return
+	}
+	receipts := make([]Receipt, len(data))
+	for i := range receipts {
+		s.sequence++
+		receipts[i] = Receipt{MessageID: fmt.Sprintf("msg-%d", s.sequence), Topic: "orders", Sequence: s.sequence}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"messages": receipts, "count": len(receipts)})
+}
+
+// batchSizes returns the size of every batch received so far
+func (s *stubBroker) batchSizes() []int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]int(nil), s.batches...)
+}
+
+// waitAll waits for every future, failing the test if one takes too long
+func waitAll(t *testing.T, futures []*Future) ([]*Receipt, []error) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	receipts := make([]*Receipt, len(futures))
+	errs := make([]error, len(futures))
+	for i, future := range futures {
+		receipts[i], errs[i] = future.Wait(ctx)
+		if errs[i] == context.DeadlineExceeded {
+			t.Fatalf("message %d was never sent", i)
+		}
+	}
+	return receipts, errs
+}
+
+func TestBatchPublisherFlushesBySize(t *testing.T) {
+	broker := newStubBroker(t, false)
+	publisher := New(broker.URL).NewBatchPublisher(context.Background(), "orders",
+		BatchOptions{MaxBatchSize: 3, MaxDelay: time.Hour})
+	defer publisher.Close()
+
+	futures := make([]*Future, 6)
+	for i := range futures {
+		futures[i] = publisher.Publish(map[string]int{"order": i})
+	}
+	receipts, errs := waitAll(t, futures)
+
+	if got := fmt.Sprint(broker.batchSizes()); got != "[3 3]" {
+		t.Errorf("batch sizes %s, want [3 3]", got)
+	}
+	for i, receipt := range receipts {
+		if errs[i] != nil {
+			t.Fatalf("message %d: %v", i, errs[i])
+		}
+		// Receipts come back in publish order
+		if receipt.Sequence != uint64(i+1) {
+			t.Errorf("message %d has sequence %d, want %d", i, receipt.Sequence, i+1)
+		}
+	}
+}
+
+func TestBatchPublisherFlushesByTime(t *testing.T) {
+	broker := newStubBroker(t, false)
+	const maxDelay = 30 * time.Millisecond
+	publisher := New(broker.URL).NewBatchPublisher(context.Background(), "orders",
+		BatchOptions{MaxBatchSize: 100, MaxDelay: maxDelay})
+	defer publisher.Close()
+
+	start := time.Now()
+	futures := []*Future{publisher.Publish("first"), publisher.Publish("second")}
+	if _, errs := waitAll(t, futures); errs[0] != nil || errs[1] != nil {
+		t.Fatalf("publish failed: %v", errs)
+	}
+
+	if elapsed := time.Since(start); elapsed < maxDelay {
+		t.Errorf("batch sent after %v, before MaxDelay %v", elapsed, maxDelay)
+	}
+	if got := fmt.Sprint(broker.batchSizes()); got != "[2]" {
+		t.Errorf("batch sizes %s, want [2]", got)
+	}
+}
+
+func TestBatchPublisherFailsWholeBatch(t *testing.T) {
+	broker := newStubBroker(t, true)
+	publisher := New(broker.URL).NewBatchPublisher(context.Background(), "orders",
+		BatchOptions{MaxBatchSize: 2, MaxDelay: time.Hour})
+	defer publisher.Close()
+
+	_, errs := waitAll(t, []*Future{publisher.Publish("a"), publisher.Publish("b")})
+	for i, err := range errs {
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) {
+			t.Fatalf("message %d: error %v, want a *StatusError", i, err)
+		}
+		if statusErr.StatusCode != http.StatusServiceUnavailable || statusErr.Code != "queue_full" {
+			t.Errorf("message %d: %v, want 503 queue_full", i, statusErr)
+		}
+		if !statusErr.Retryable() || statusErr.RetryAfter != 2*time.Second {
+			t.Errorf("message %d: retryable %t after %v, want retryable after 2s", i, statusErr.Retryable(), statusErr.RetryAfter)
+		}
+	}
+}
+
+func TestBatchPublisherClose(t *testing.T) {
+	broker := newStubBroker(t, false)
+	publisher := New(broker.URL).NewBatchPublisher(context.Background(), "orders",
+		BatchOptions{MaxBatchSize: 100, MaxDelay: time.Hour})
+
+	pending := publisher.Publish("pending")
+	if err := publisher.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	// Close sends the waiting batch instead of dropping it
+	if _, errs := waitAll(t, []*Future{pending}); errs[0] != nil {
+		t.Errorf("pending message: %v", errs[0])
+	}
+
+	if _, errs := waitAll(t, []*Future{publisher.Publish("late")}); errs[0] != ErrPublisherClosed {
+		t.Errorf("publish after Close: %v, want ErrPublisherClosed", errs[0])
+	}
+	if err := publisher.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
+
+func TestBatchPublisherCancelledContext(t *testing.T) {
+	broker := newStubBroker(t, false)
+	ctx, cancel := context.WithCancel(context.Background())
+	publisher := New(broker.URL).NewBatchPublisher(ctx, "orders", BatchOptions{})
+	defer publisher.Close()
+
+	cancel()
+	if _, errs := waitAll(t, []*Future{publisher.Publish("late")}); errs[0] != context.Canceled {
+		t.Errorf("publish after cancel: %v, want context.Canceled", errs[0])
+	}
+	if sizes := broker.batchSizes(); len(sizes) != 0 {
+		t.Errorf("broker received batches %v after cancel", sizes)
+	}
+}
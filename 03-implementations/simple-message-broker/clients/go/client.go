@@ -0,0 +1,134 @@
+// Package brokerclient is a Go client for the simple message broker's HTTP
+// API. Client publishes one request per call; BatchPublisher collects
+// messages and publishes them in batches for high-volume producers.
+package brokerclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client publishes to a broker over HTTP
+type Client struct {
+	BaseURL    string // e.g. http://localhost:8080
+	HTTPClient *http.Client
+}
+
+// New creates a client for the broker at baseURL
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Receipt is the broker's record of a published message
+type Receipt struct {
+	MessageID string    `json:"messageId"`
+	Topic     string    `json:"topic"`
+	Timestamp time.Time `json:"timestamp"`
+	Sequence  uint64    `json:"sequence,omitempty"`  // zero for a delayed message
+	DeliverAt time.Time `json:"deliverAt,omitempty"` // set for a delayed message
+}
+
+// StatusError is returned when the broker answers with an error status
+type StatusError struct {
+	StatusCode int           `json:"-"`
+	Code       string        `json:"code"` // the broker's error code, such as "queue_full"
+	Message    string        `json:"message"`
+	RetryAfter time.Duration `json:"-"` // from the Retry-After header, if any
+	// Details holds extra fields some codes carry, such as depth and
+	// maxQueueSize for queue_full
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+func (e *StatusError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("broker returned %d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("broker returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Retryable reports whether the same request may succeed later, after
+// RetryAfter if it is set
+func (e *StatusError) Retryable() bool {
+	switch e.Code {
+	case "queue_full", "circuit_open", "unavailable", "rate_limited":
+		return true
+	}
+	return false
+}
+
+// Publish publishes one message to a topic
+func (c *Client) Publish(ctx context.Context, topic string, data interface{}) (*Receipt, error) {
+	var receipt Receipt
+	if err := c.post(ctx, "/publish/"+url.PathEscape(topic), data, &receipt); err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+// PublishBatch publishes several messages to a topic in one request and
+// returns their receipts in order. The broker stops at the first message it
+// can't publish, so after an error some of the batch may have been published.
+func (c *Client) PublishBatch(ctx context.Context, topic string, data []interface{}) ([]Receipt, error) {
+	var response struct {
+		Messages []Receipt `json:"messages"`
+		Count    int       `json:"count"`
+	}
+	if err := c.post(ctx, "/publish/batch/"+url.PathEscape(topic), data, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Messages) != len(data) {
+		return nil, fmt.Errorf("broker returned %d receipts for %d messages", len(response.Messages), len(data))
+	}
+	return response.Messages, nil
+}
+
+// post sends body as JSON and decodes a successful response into result
+func (c *Client) post(ctx context.Context, path string, body, result interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("encoding request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+		statusErr := &StatusError{StatusCode: response.StatusCode}
+		// Errors are a {"code", "message"} envelope; anything else, such as
+		// a proxy's error page, is kept as the message
+		if json.Unmarshal(body, statusErr) != nil || statusErr.Code == "" {
+			statusErr.Code = ""
+			statusErr.Message = strings.TrimSpace(string(body))
+		}
+		if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			statusErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return statusErr
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialRejected dials server's WebSocket expecting the broker to refuse the
+// upgrade, and returns the status, Retry-After and error code it sent
+func dialRejected(t *testing.T, server *httptest.Server) (int, string, string) {
+	t.Helper()
+	conn, resp, err := websocket.DefaultDialer.Dial(wsURL(server), nil)
+	if err == nil {
+		conn.Close()
+		t.Fatal("WebSocket upgrade succeeded, want it refused")
+	}
+	if resp == nil {
+		t.Fatalf("dialing WebSocket: %v", err)
+	}
+	return resp.StatusCode, resp.Header.Get("Retry-After"), decodeError(t, resp).Code
+}
+
+// openConnections returns how many connection slots are taken
+func openConnections(mb *MessageBroker) int {
+	mb.connMutex.Lock()
+	defer mb.connMutex.Unlock()
+	return mb.connections
+}
+
+func TestWebSocketConnectionLimit(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	mb.maxConnections, mb.maxConnsPerIP = 3, 0
+	server := newTestServer(t, mb)
+
+	var conns []*websocket.Conn
+	for i := 0; i < 3; i++ {
+		conns = append(conns, dialWS(t, server))
+	}
+	status, retryAfter, code := dialRejected(t, server)
+	if status != http.StatusServiceUnavailable || retryAfter != "1" || code != CodeUnavailable {
+		t.Errorf("over the limit: %d, Retry-After %q, code %q; want 503, \"1\", %q", status, retryAfter, code, CodeUnavailable)
+	}
+	if active := openConnections(mb); active != 3 {
+		t.Errorf("active connections = %d, want the rejected one not to count", active)
+	}
+
+	// Closing a connection frees its slot
+	conns[0].Close()
+	waitFor(t, "the closed connection's slot", func() bool { return openConnections(mb) == 2 })
+	dialWS(t, server)
+}
+
+func TestWebSocketConnectionLimitPerIP(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	mb.maxConnections, mb.maxConnsPerIP = 0, 2
+	server := newTestServer(t, mb)
+
+	dialWS(t, server)
+	dialWS(t, server)
+	status, retryAfter, code := dialRejected(t, server)
+	if status != http.StatusTooManyRequests || retryAfter != "1" || code != CodeRateLimited {
+		t.Errorf("over the per-IP limit: %d, Retry-After %q, code %q; want 429, \"1\", %q", status, retryAfter, code, CodeRateLimited)
+	}
+}
+
+func TestWebSocketSlowStart(t *testing.T) {
+	clock := newTestClock()
+	mb := newTestBroker(t, clock)
+	mb.maxConnections, mb.maxConnsPerIP = 10, 0
+	mb.slowStart = 10 * time.Second
+	server := newTestServer(t, mb)
+
+	// The limit ramps from 1 to maxConnections over slowStart
+	for _, step := range []struct {
+		elapsed time.Duration
+		limit   int
+	}{
+		{0, 1},
+		{3 * time.Second, 3},
+		{5 * time.Second, 5},
+		{10 * time.Second, 10},
+	} {
+		clock.Advance(step.elapsed - clock.Now().Sub(mb.startedAt))
+		if got := mb.connectionLimit(); got != step.limit {
+			t.Fatalf("limit after %v = %d, want %d", step.elapsed, got, step.limit)
+		}
+		for openConnections(mb) < step.limit {
+			dialWS(t, server)
+		}
+		if status, _, code := dialRejected(t, server); status != http.StatusServiceUnavailable || code != CodeUnavailable {
+			t.Errorf("connection %d after %v: %d %q, want 503 %q", step.limit+1, step.elapsed, status, code, CodeUnavailable)
+		}
+	}
+
+	// Past the ramp the limit stays at the maximum
+	clock.Advance(time.Hour)
+	if got := mb.connectionLimit(); got != 10 {
+		t.Errorf("limit after slow start = %d, want 10", got)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTakeCreditWaitsForGrant(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	subscription := mb.Subscribe("consumer", "orders")
+	subscription.limitCredit(0)
+
+	took := make(chan bool)
+	go func() { took <- subscription.takeCredit() }()
+	select {
+	case <-took:
+		t.Fatal("takeCredit returned with zero credit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := mb.GrantCredit("consumer", "orders", 1); err != nil {
+		t.Fatal(err)
+	}
+	if ok := <-took; !ok {
+		t.Error("takeCredit after a grant = false, want true")
+	}
+
+	// Unsubscribing releases a forwarder still waiting
+	go func() { took <- subscription.takeCredit() }()
+	mb.Unsubscribe("consumer", "orders")
+	if ok := <-took; ok {
+		t.Error("takeCredit after unsubscribe = true, want false")
+	}
+}
+
+func TestGrantCreditErrors(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	limited := mb.Subscribe("limited", "orders")
+	limited.limitCredit(0)
+	mb.Subscribe("unlimited", "orders")
+
+	for _, tt := range []struct {
+		consumer, topic string
+		n               int
+	}{
+		{"limited", "orders", 0},
+		{"limited", "orders", -1},
+		{"unlimited", "orders", 1},
+		{"limited", "payments", 1},
+		{"missing", "orders", 1},
+	} {
+		if err := mb.GrantCredit(tt.consumer, tt.topic, tt.n); err == nil {
+			t.Errorf("GrantCredit(%s, %s, %d) succeeded, want an error", tt.consumer, tt.topic, tt.n)
+		}
+	}
+}
+
+func TestWebSocketCreditHoldsMessages(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	server := newTestServer(t, mb)
+	conn := dialWS(t, server)
+
+	ack := subscribeWS(t, conn, WebSocketMessage{Topic: "orders", Credit: 2})
+	if ack.Credit != 2 {
+		t.Fatalf("ack credit = %d, want 2", ack.Credit)
+	}
+	for i := 1; i <= 5; i++ {
+		if _, err := mb.PublishMessage("orders", i, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for want := uint64(1); want <= 2; want++ {
+		if got := readWS(t, conn); got.Type != "message" || got.Sequence != want {
+			t.Fatalf("frame = %+v, want message %d", got, want)
+		}
+	}
+
+	// Out of credit, nothing more is sent: the next frame is the reply to
+	// a request made well after the messages were published
+	time.Sleep(50 * time.Millisecond)
+	sendWS(t, conn, WebSocketMessage{Type: "credit", Topic: "payments", Count: 1})
+	if got := readWS(t, conn); got.Type != "error" {
+		t.Fatalf("frame with no credit = %+v, want the credit error", got)
+	}
+
+	sendWS(t, conn, WebSocketMessage{Type: "credit", Topic: "orders", Count: 3})
+	for want := uint64(3); want <= 5; want++ {
+		if got := readWS(t, conn); got.Type != "message" || got.Sequence != want {
+			t.Fatalf("frame after grant = %+v, want message %d", got, want)
+		}
+	}
+}
+
+func TestWebSocketDefaultCredit(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	mb.defaultCredit = 1
+	server := newTestServer(t, mb)
+	conn := dialWS(t, server)
+
+	// A subscribe without credit gets WS_DEFAULT_CREDIT
+	if ack := subscribeWS(t, conn, WebSocketMessage{Topic: "orders"}); ack.Credit != 1 {
+		t.Fatalf("ack credit = %d, want the default of 1", ack.Credit)
+	}
+	for i := 1; i <= 2; i++ {
+		if _, err := mb.PublishMessage("orders", i, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := readWS(t, conn); got.Sequence != 1 {
+		t.Fatalf("first frame = %+v, want message 1", got)
+	}
+	time.Sleep(50 * time.Millisecond)
+	sendWS(t, conn, WebSocketMessage{Type: "unsubscribe", Topic: "orders"})
+	if got := readWS(t, conn); got.Type != "unsubscribed" {
+		t.Errorf("frame with no credit = %+v, want unsubscribed", got)
+	}
+}
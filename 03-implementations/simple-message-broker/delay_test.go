@@ -0,0 +1,142 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseDelay(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+		code   string
+	}{
+		{"", 0, ""},
+		{"0", 0, ""},
+		{"1500", 1500 * time.Millisecond, ""},
+		{"-1", 0, CodeInvalidRequest},
+		{"soon", 0, CodeInvalidRequest},
+		{"9223372036854775807", 0, CodeInvalidRequest},        // wraps negative as a Duration
+		{"99999999999999999999", 0, CodeInvalidRequest},       // overflows int64
+		{strconv.FormatInt(1<<53, 10), 0, CodeInvalidRequest}, // wraps past zero
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/publish/orders", nil)
+			if tt.header != "" {
+				r.Header.Set("X-Delay", tt.header)
+			}
+			got, err := parseDelay(r)
+			if code := codeOf(err); code != tt.code {
+				t.Fatalf("parseDelay(%q) error %v, want code %q", tt.header, err, tt.code)
+			}
+			if got != tt.want {
+				t.Errorf("parseDelay(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublishDelayedRejectsOverMaxDelay(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	mb.maxDelay = time.Hour
+
+	if _, err := mb.PublishDelayed("reminders", "late", nil, time.Hour+time.Millisecond); errorCode(err) != CodeInvalidRequest {
+		t.Errorf("delay over MAX_DELAY: error %v, want code %q", err, CodeInvalidRequest)
+	}
+	if _, err := mb.PublishDelayed("reminders", "on time", nil, time.Hour); err != nil {
+		t.Errorf("delay of exactly MAX_DELAY: %v", err)
+	}
+	if _, scheduled := topicDepth(mb, "reminders"); scheduled != 1 {
+		t.Errorf("scheduled = %d, want 1", scheduled)
+	}
+
+	mb.maxDelay = 0
+	if _, err := mb.PublishDelayed("reminders", "much later", nil, 10000*time.Hour); err != nil {
+		t.Errorf("long delay with no limit: %v", err)
+	}
+}
+
+func TestDeliverDue(t *testing.T) {
+	clock := newTestClock()
+	mb := newTestBroker(t, clock)
+	start := clock.Now()
+
+	later, err := mb.PublishDelayed("reminders", "later", nil, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sooner, err := mb.PublishDelayed("reminders", "sooner", nil, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if next := mb.deliverDue(); !next.Equal(start.Add(time.Second)) {
+		t.Errorf("next delivery = %v, want %v", next, start.Add(time.Second))
+	}
+	if _, err := mb.ConsumeMessage("reminders"); errorCode(err) != CodeNoMessages {
+		t.Fatalf("consume before delivery: error %v, want code %q", err, CodeNoMessages)
+	}
+
+	clock.Advance(time.Second)
+	if next := mb.deliverDue(); !next.Equal(start.Add(5 * time.Second)) {
+		t.Errorf("next delivery = %v, want %v", next, start.Add(5*time.Second))
+	}
+	if queued, scheduled := topicDepth(mb, "reminders"); queued != 1 || scheduled != 1 {
+		t.Errorf("after 1s: %d queued and %d scheduled, want 1 and 1", queued, scheduled)
+	}
+
+	clock.Advance(10 * time.Second)
+	if next := mb.deliverDue(); !next.IsZero() {
+		t.Errorf("next delivery with nothing scheduled = %v, want zero", next)
+	}
+
+	// Delivery order, not publish order, decides consume order and sequence
+	for i, want := range []*Message{sooner, later} {
+		got, err := mb.ConsumeMessage("reminders")
+		if err != nil {
+			t.Fatalf("consume %d: %v", i, err)
+		}
+		if got.ID != want.ID {
+			t.Errorf("consume %d = %v, want %v", i, got.Data, want.Data)
+		}
+		if got.Sequence != uint64(i+1) {
+			t.Errorf("consume %d sequence = %d, want %d", i, got.Sequence, i+1)
+		}
+	}
+}
+
+func TestSchedulerRoutineWakesForEarlierMessage(t *testing.T) {
+	clock := newTestClock()
+	mb := newTestBroker(t, clock)
+	go mb.schedulerRoutine()
+
+	// The scheduler sleeps until the hour-long delay is due...
+	if _, err := mb.PublishDelayed("reminders", "in an hour", nil, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// ...and a sooner message has to wake it. Its timer waits 10ms of real
+	// time, by which point the test clock has caught up.
+	if _, err := mb.PublishDelayed("reminders", "soon", nil, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(10 * time.Millisecond)
+
+	waitFor(t, "the sooner message to be delivered", func() bool {
+		queued, _ := topicDepth(mb, "reminders")
+		return queued == 1
+	})
+	message, err := mb.ConsumeMessage("reminders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Data != "soon" {
+		t.Errorf("delivered %v, want soon", message.Data)
+	}
+	if _, scheduled := topicDepth(mb, "reminders"); scheduled != 1 {
+		t.Errorf("scheduled = %d, want the hour-long message still waiting", scheduled)
+	}
+}
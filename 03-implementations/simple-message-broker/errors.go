@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Error codes sent in the code field of error responses. Clients should
+// branch on these rather than on messages, which may change.
+const (
+	CodeInvalidRequest  = "invalid_request"   // malformed body or parameters; fix before retrying
+	CodeNotFound        = "not_found"         // no such topic, consumer, or group
+	CodeConflict        = "conflict"          // the resource already exists
+	CodeLimitReached    = "limit_reached"     // a configured limit on a resource; not retryable until some is freed
+	CodeNoMessages      = "no_messages"       // the topic is empty; poll again later
+	CodeOutOfRange      = "out_of_range"      // a peek offset past the end of the queue
+	CodeQueueFull       = "queue_full"        // retryable after Retry-After
+	CodeCircuitOpen     = "circuit_open"      // retryable after Retry-After
+	CodeMessageTooLarge = "message_too_large" // not retryable
+	CodeUnauthorized    = "unauthorized"
+	CodeForbidden       = "forbidden"
+	CodeUnavailable     = "unavailable"  // retryable after Retry-After
+	CodeRateLimited     = "rate_limited" // retryable after Retry-After
+	CodeTimeout         = "timeout"
+	CodeInternal        = "internal"
+)
+
+// queueFullRetryAfter is how long a publisher to a full topic is told to
+// wait. Consumers drain a queue continuously, so it is short.
+const queueFullRetryAfter = time.Second
+
+// BrokerError is an error a client can act on: a stable code, a message for
+// people, and the HTTP status it is reported with. Broker methods return it
+// for failures caused by the request or the broker's state; errors.As finds
+// it through wrapping.
+type BrokerError struct {
+	Code       string
+	Message    string
+	Status     int
+	RetryAfter time.Duration          // sent as Retry-After, rounded up to seconds; 0 omits it
+	Details    map[string]interface{} // sent as details for clients to act on; nil omits it
+	Err        error                  // underlying error, if any
+}
+
+func (e *BrokerError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *BrokerError) Unwrap() error {
+	return e.Err
+}
+
+// newBrokerError creates a BrokerError with a formatted message
+func newBrokerError(status int, code, format string, args ...interface{}) *BrokerError {
+	return &BrokerError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+		Status:  status,
+	}
+}
+
+// invalidRequest creates a 400 BrokerError
+func invalidRequest(format string, args ...interface{}) *BrokerError {
+	return newBrokerError(http.StatusBadRequest, CodeInvalidRequest, format, args...)
+}
+
+// notFound creates a 404 BrokerError
+func notFound(format string, args ...interface{}) *BrokerError {
+	return newBrokerError(http.StatusNotFound, CodeNotFound, format, args...)
+}
+
+// errorCode returns the code of the BrokerError in err's chain, or
+// CodeInternal if there is none
+func errorCode(err error) string {
+	var brokerErr *BrokerError
+	if errors.As(err, &brokerErr) {
+		return brokerErr.Code
+	}
+	return CodeInternal
+}
+
+// writeError writes err as a JSON envelope, {"code": ..., "message": ...},
+// plus "details" if the BrokerError in its chain has any, with that error's
+// status and Retry-After. Any other error is reported as a 500 with code
+// internal.
+func writeError(w http.ResponseWriter, err error) {
+	var brokerErr *BrokerError
+	if !errors.As(err, &brokerErr) {
+		brokerErr = &BrokerError{
+			Code:    CodeInternal,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if brokerErr.RetryAfter > 0 {
+		seconds := int(math.Ceil(brokerErr.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	body := map[string]interface{}{
+		"code":    brokerErr.Code,
+		"message": brokerErr.Error(),
+	}
+	if brokerErr.Details != nil {
+		body["details"] = brokerErr.Details
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(brokerErr.Status)
+	json.NewEncoder(w).Encode(body)
+}
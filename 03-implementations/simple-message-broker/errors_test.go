@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestErrorEnvelope(t *testing.T) {
+	clock := newTestClock()
+	mb := newTestBroker(t, clock)
+	mb.maxMessageSize = 32
+	mb.breaker = NewCircuitBreaker("publish", testBreakerConfig, clock.Now)
+	server := newTestServer(t, mb)
+
+	if _, err := mb.CreateTopic("full", TopicConfig{MaxQueueSize: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mb.PublishMessage("full", "first", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		header     map[string]string
+		setup      func()
+		status     int
+		code       string
+		message    string // a substring of the message
+		retryAfter string
+		details    map[string]interface{}
+	}{
+		{
+			name:       "queue full",
+			method:     "POST",
+			path:       "/publish/full",
+			body:       `"second"`,
+			status:     http.StatusServiceUnavailable,
+			code:       CodeQueueFull,
+			message:    "topic full queue is full (1 of 1 messages)",
+			retryAfter: "1",
+			details:    map[string]interface{}{"depth": 1.0, "maxQueueSize": 1.0},
+		},
+		{
+			name:    "message too large",
+			method:  "POST",
+			path:    "/publish/orders",
+			body:    `"` + strings.Repeat("x", 64) + `"`,
+			status:  http.StatusRequestEntityTooLarge,
+			code:    CodeMessageTooLarge,
+			message: "over the 32 byte limit",
+		},
+		{
+			name:    "unknown topic",
+			method:  "GET",
+			path:    "/topics/missing/config",
+			status:  http.StatusNotFound,
+			code:    CodeNotFound,
+			message: "topic missing not found",
+		},
+		{
+			name:    "empty topic",
+			method:  "GET",
+			path:    "/consume/empty",
+			status:  http.StatusNotFound,
+			code:    CodeNoMessages,
+			message: "no messages available in topic empty",
+		},
+		{
+			name:    "bad delay",
+			method:  "POST",
+			path:    "/publish/orders",
+			body:    `"later"`,
+			header:  map[string]string{"X-Delay": "soon"},
+			status:  http.StatusBadRequest,
+			code:    CodeInvalidRequest,
+			message: "X-Delay",
+		},
+		{
+			name:   "circuit open",
+			method: "POST",
+			path:   "/publish/orders",
+			body:   `"hello"`,
+			setup: func() {
+				for i := 0; i < testBreakerConfig.MinCalls; i++ {
+					mb.breaker.Execute(func() error { return errTestFailure })
+				}
+				// Retry-After rounds the 2.5s left up to whole seconds
+				clock.Advance(testBreakerConfig.OpenTimeout / 2)
+			},
+			status:     http.StatusServiceUnavailable,
+			code:       CodeCircuitOpen,
+			message:    "publish rejected: circuit breaker is open",
+			retryAfter: "3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup()
+			}
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if got := resp.Header.Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.retryAfter)
+			}
+			envelope := decodeError(t, resp)
+			if envelope.Code != tt.code {
+				t.Errorf("code = %q, want %q", envelope.Code, tt.code)
+			}
+			if !strings.Contains(envelope.Message, tt.message) {
+				t.Errorf("message = %q, want it to contain %q", envelope.Message, tt.message)
+			}
+			for key, want := range tt.details {
+				if got := envelope.Details[key]; got != want {
+					t.Errorf("details[%s] = %v, want %v", key, got, want)
+				}
+			}
+			if tt.details == nil && envelope.Details != nil {
+				t.Errorf("details = %v, want none", envelope.Details)
+			}
+		})
+	}
+}
+
+func TestWriteErrorWithPlainError(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeError(w, errors.New("disk on fire"))
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Retry-After"); got != "" {
+		t.Errorf("Retry-After = %q, want none", got)
+	}
+	envelope := decodeError(t, resp)
+	if envelope.Code != CodeInternal || envelope.Message != "disk on fire" {
+		t.Errorf("envelope = %+v, want internal: disk on fire", envelope)
+	}
+}
+
+func TestWriteErrorRoundsRetryAfterUp(t *testing.T) {
+	for _, tt := range []struct {
+		retryAfter time.Duration
+		want       string
+	}{
+		{0, ""},
+		{time.Millisecond, "1"},
+		{time.Second, "1"},
+		{1500 * time.Millisecond, "2"},
+	} {
+		w := httptest.NewRecorder()
+		writeError(w, &BrokerError{Code: CodeUnavailable, Status: http.StatusServiceUnavailable, RetryAfter: tt.retryAfter})
+		if got := w.Header().Get("Retry-After"); got != tt.want {
+			t.Errorf("RetryAfter %v: header %q, want %q", tt.retryAfter, got, tt.want)
+		}
+	}
+}
@@ -2,13 +2,25 @@ package main
 
 import (
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"math"
+	"math/rand"
+	"net"
 	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 type LoadTestConfig struct {
@@ -17,8 +29,41 @@ type LoadTestConfig struct {
 	Messages    int
 	Topic       string
 	MessageSize int
+
+	RPS      float64       // target requests per second, 0 for as fast as possible
+	Duration time.Duration // with RPS, run each test this long instead of sending Messages
+
+	HistogramCSV    string        // file to write latency histograms to, empty to skip
+	HistogramBucket time.Duration // width of each histogram bucket
+
+	Mode        string        // "http" for publish/consume requests, "ws" for WebSocket delivery
+	Connections int           // WebSocket subscribers in ws mode
+	DrainWait   time.Duration // in ws mode, how long to wait for a delivery before counting the rest as dropped
+
+	// PublishRatio is the fraction of requests that publish in a mixed
+	// run, where each request publishes or consumes at random. 0 runs
+	// separate publish and consume phases instead.
+	PublishRatio float64
+
+	Retries      int           // extra attempts for a 429 or 5xx response
+	RetryBackoff time.Duration // wait before the first retry, doubled for each one after
+	MaxErrorRate float64       // percent of failed requests above which the process exits non-zero
 }
 
+// Result categories, by HTTP status class or by how the request failed
+const (
+	Category2xx        = "2xx"
+	Category4xx        = "4xx"
+	Category429        = "429"
+	Category5xx        = "5xx"
+	CategoryOther      = "other status"
+	CategoryConnection = "connection error"
+	CategoryTimeout    = "timeout"
+)
+
+// httpClient sends every load test request; main sets its timeout
+var httpClient = &http.Client{}
+
 type TestResult struct {
 	TotalRequests    int
 	SuccessfulReqs   int
@@ -27,13 +72,56 @@ type TestResult struct {
 	AvgResponseTime  time.Duration
 	MinResponseTime  time.Duration
 	MaxResponseTime  time.Duration
+	P50ResponseTime  time.Duration
+	P95ResponseTime  time.Duration
+	P99ResponseTime  time.Duration
 	RequestsPerSec   float64
+	TargetRPS        float64 // 0 if the test was not paced
+	AchievedRPS      float64 // rate requests were sent at, if paced
+	Retries          int     // attempts beyond the first, across all requests
+
+	// Categories counts final outcomes by status class or failure kind
+	Categories map[string]int
+
+	// ResponseTimes holds every sample, sorted, for percentiles and histograms
+	ResponseTimes []time.Duration
+}
+
+// WebSocketResult reports how many published messages reached each
+// WebSocket subscriber. Every subscriber should receive every message, so
+// Expected is Published * Connections, and anything short of that was
+// dropped by the broker.
+type WebSocketResult struct {
+	Connections   int
+	Published     int
+	PublishFailed int
+	Expected      int
+	Delivered     int
+	Dropped       int
+	LossRate      float64 // Dropped / Expected
+	TargetRPS     float64 // 0 if publishing was not paced
+	AchievedRPS   float64 // rate messages were published at, if paced
+
+	// Latency measures publish to receive for each delivered message
+	Latency TestResult
+}
+
+// wsPayload is the data each ws mode message carries, so subscribers can
+// tell this run's messages apart and time them
+type wsPayload struct {
+	Run    string `json:"run"`
+	Seq    int    `json:"seq"`
+	SentAt int64  `json:"sentAt"` // UnixNano when the publish was written
+	Data   string `json:"data,omitempty"`
 }
 
 type RequestResult struct {
 	Success      bool
 	ResponseTime time.Duration
 	Error        error
+	Category     string
+	Attempts     int
+	RetryAfter   time.Duration // from a Retry-After header, if any
 }
 
 func main() {
@@ -43,21 +131,73 @@ func main() {
 		messages   = flag.Int("messages", 1000, "Total number of messages to send")
 		topic      = flag.String("topic", "load-test", "Topic name for testing")
 		msgSize    = flag.Int("size", 100, "Message size in bytes")
+		rps        = flag.Float64("rps", 0, "Target requests per second (0 = as fast as possible)")
+		duration   = flag.Duration("duration", 0, "With -rps, run each test for this long instead of sending -messages")
+		histCSV    = flag.String("histogram-csv", "", "Write latency histograms to this CSV file")
+		histBucket = flag.Duration("histogram-bucket", time.Millisecond, "Latency histogram bucket width")
+		mode       = flag.String("mode", "http", "Test mode: http (publish and consume requests) or ws (WebSocket delivery)")
+		conns      = flag.Int("connections", 10, "Number of WebSocket subscribers in ws mode")
+		drainWait  = flag.Duration("drain-wait", 5*time.Second, "In ws mode, how long to wait for another delivery before counting the rest as dropped")
+		rwRatio    = flag.String("readwrite-ratio", "", "Publish:consume ratio for one mixed run, e.g. 70:30 (empty = separate phases)")
+		timeout    = flag.Duration("timeout", 10*time.Second, "Per-request timeout")
+		retries    = flag.Int("retry", 0, "Retry a 429 or 5xx response up to this many times")
+		backoff    = flag.Duration("retry-backoff", 50*time.Millisecond, "Wait before the first retry, doubled for each one after")
+		maxErrRate = flag.Float64("max-error-rate", 100, "Exit with status 1 if any test's error rate exceeds this percent")
 	)
 	flag.Parse()
 
+	httpClient.Timeout = *timeout
+
+	publishRatio := 0.0
+	if *rwRatio != "" {
+		var err error
+		publishRatio, err = parseReadWriteRatio(*rwRatio)
+		if err != nil {
+			log.Fatalf("Invalid -readwrite-ratio: %v", err)
+		}
+	}
+
 	config := LoadTestConfig{
-		BaseURL:     *baseURL,
-		Concurrent:  *concurrent,
-		Messages:    *messages,
-		Topic:       *topic,
-		MessageSize: *msgSize,
+		BaseURL:         *baseURL,
+		Concurrent:      *concurrent,
+		Messages:        *messages,
+		Topic:           *topic,
+		MessageSize:     *msgSize,
+		RPS:             *rps,
+		Duration:        *duration,
+		HistogramCSV:    *histCSV,
+		HistogramBucket: *histBucket,
+		Mode:            *mode,
+		Connections:     *conns,
+		DrainWait:       *drainWait,
+		PublishRatio:    publishRatio,
+		Retries:         *retries,
+		RetryBackoff:    *backoff,
+		MaxErrorRate:    *maxErrRate,
+	}
+
+	if config.Mode != "http" && config.Mode != "ws" {
+		log.Fatalf("Unknown mode %q, want http or ws", config.Mode)
+	}
+	if config.Mode == "ws" && *rwRatio != "" {
+		log.Fatal("-readwrite-ratio applies to http mode only")
 	}
 
 	fmt.Printf("Starting load test with config:\n")
 	fmt.Printf("  URL: %s\n", config.BaseURL)
-	fmt.Printf("  Concurrent: %d\n", config.Concurrent)
-	fmt.Printf("  Messages: %d\n", config.Messages)
+	fmt.Printf("  Mode: %s\n", config.Mode)
+	if config.Mode == "ws" {
+		fmt.Printf("  Connections: %d\n", config.Connections)
+	} else {
+		fmt.Printf("  Concurrent: %d\n", config.Concurrent)
+	}
+	fmt.Printf("  Messages: %d\n", config.totalRequests())
+	if config.RPS > 0 {
+		fmt.Printf("  Target RPS: %.0f\n", config.RPS)
+	}
+	if *rwRatio != "" {
+		fmt.Printf("  Publish:Consume: %s\n", *rwRatio)
+	}
 	fmt.Printf("  Topic: %s\n", config.Topic)
 	fmt.Printf("  Message Size: %d bytes\n", config.MessageSize)
 	fmt.Println()
@@ -67,6 +207,40 @@ func main() {
 		log.Fatal("Health check failed")
 	}
 
+	if config.Mode == "ws" {
+		fmt.Println("Running WebSocket delivery test...")
+		wsResult, err := runWebSocketTest(config)
+		if err != nil {
+			log.Fatalf("WebSocket test failed: %v", err)
+		}
+		printWebSocketResults(wsResult)
+
+		writeHistograms(config, map[string]TestResult{
+			"ws_delivery": wsResult.Latency,
+		})
+		exitOnErrorRate(config, map[string]float64{
+			"ws delivery": wsResult.LossRate * 100,
+		})
+		return
+	}
+
+	if config.PublishRatio > 0 {
+		fmt.Println("Running mixed publish/consume test...")
+		mixed, publishResult, consumeResult := runMixedTest(config)
+		printResults("MIXED TEST", mixed)
+		printResults("MIXED PUBLISH", publishResult)
+		printResults("MIXED CONSUME", consumeResult)
+
+		writeHistograms(config, map[string]TestResult{
+			"mixed_publish": publishResult,
+			"mixed_consume": consumeResult,
+		})
+		exitOnErrorRate(config, map[string]float64{
+			"mixed": mixed.errorRate(),
+		})
+		return
+	}
+
 	// Run publish test
 	fmt.Println("Running publish test...")
 	publishResult := runPublishTest(config)
@@ -79,6 +253,56 @@ func main() {
 	fmt.Println("Running consume test...")
 	consumeResult := runConsumeTest(config)
 	printResults("CONSUME TEST", consumeResult)
+
+	writeHistograms(config, map[string]TestResult{
+		"publish": publishResult,
+		"consume": consumeResult,
+	})
+	exitOnErrorRate(config, map[string]float64{
+		"publish": publishResult.errorRate(),
+		"consume": consumeResult.errorRate(),
+	})
+}
+
+// exitOnErrorRate exits with status 1 if any test's error rate, in percent,
+// exceeds config.MaxErrorRate, so CI can fail a run that degraded
+func exitOnErrorRate(config LoadTestConfig, rates map[string]float64) {
+	names := make([]string, 0, len(rates))
+	for name := range rates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	failed := false
+	for _, name := range names {
+		if rates[name] > config.MaxErrorRate {
+			fmt.Printf("✗ %s error rate %.2f%% exceeds -max-error-rate %.2f%%\n",
+				name, rates[name], config.MaxErrorRate)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// errorRate returns the percent of requests that failed
+func (result TestResult) errorRate() float64 {
+	if result.TotalRequests == 0 {
+		return 0
+	}
+	return float64(result.FailedReqs) / float64(result.TotalRequests) * 100
+}
+
+// writeHistograms writes results to config.HistogramCSV, if set
+func writeHistograms(config LoadTestConfig, results map[string]TestResult) {
+	if config.HistogramCSV == "" {
+		return
+	}
+	if err := writeHistogramCSV(config.HistogramCSV, config.HistogramBucket, results); err != nil {
+		log.Fatalf("Writing latency histogram failed: %v", err)
+	}
+	fmt.Printf("Latency histograms written to %s\n", config.HistogramCSV)
 }
 
 func healthCheck(baseURL string) bool {
@@ -99,66 +323,421 @@ func healthCheck(baseURL string) bool {
 }
 
 func runPublishTest(config LoadTestConfig) TestResult {
-	var wg sync.WaitGroup
-	results := make(chan RequestResult, config.Messages)
-
 	// Generate test message
 	testData := generateTestMessage(config.MessageSize)
 
+	return runTest(config, func() RequestResult {
+		return withRetry(config, func() RequestResult {
+			return publishMessage(config.BaseURL, config.Topic, testData)
+		})
+	})
+}
+
+func runConsumeTest(config LoadTestConfig) TestResult {
+	return runTest(config, func() RequestResult {
+		return withRetry(config, func() RequestResult {
+			return consumeMessage(config.BaseURL, config.Topic)
+		})
+	})
+}
+
+// runMixedTest runs one test in which each request publishes with
+// probability config.PublishRatio and consumes otherwise, so publishers
+// and consumers contend for the topic at the same time. It returns stats
+// for all requests and for each operation.
+func runMixedTest(config LoadTestConfig) (TestResult, TestResult, TestResult) {
+	testData := generateTestMessage(config.MessageSize)
+	total := config.totalRequests()
+	publishResults := make(chan RequestResult, total)
+	consumeResults := make(chan RequestResult, total)
+
+	mixed := runTest(config, func() RequestResult {
+		if rand.Float64() < config.PublishRatio {
+			result := withRetry(config, func() RequestResult {
+				return publishMessage(config.BaseURL, config.Topic, testData)
+			})
+			publishResults <- result
+			return result
+		}
+		result := withRetry(config, func() RequestResult {
+			return consumeMessage(config.BaseURL, config.Topic)
+		})
+		consumeResults <- result
+		return result
+	})
+	close(publishResults)
+	close(consumeResults)
+
+	return mixed,
+		analyzeResults(publishResults, mixed.TotalTime),
+		analyzeResults(consumeResults, mixed.TotalTime)
+}
+
+// parseReadWriteRatio parses a publish:consume ratio such as "70:30" and
+// returns the fraction of requests that publish
+func parseReadWriteRatio(ratio string) (float64, error) {
+	parts := strings.Split(ratio, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("%q is not publish:consume", ratio)
+	}
+
+	publish, err := strconv.Atoi(parts[0])
+	if err != nil || publish < 0 {
+		return 0, fmt.Errorf("%q has an invalid publish weight", ratio)
+	}
+	consume, err := strconv.Atoi(parts[1])
+	if err != nil || consume < 0 {
+		return 0, fmt.Errorf("%q has an invalid consume weight", ratio)
+	}
+	if publish == 0 {
+		return 0, fmt.Errorf("%q never publishes; run without a ratio for a consume phase", ratio)
+	}
+	if publish+consume == 0 {
+		return 0, fmt.Errorf("%q has no weight", ratio)
+	}
+
+	return float64(publish) / float64(publish+consume), nil
+}
+
+// withRetry repeats request while it gets a 429 or 5xx response, up to
+// config.Retries more times. It waits for the response's Retry-After if
+// given, otherwise for config.RetryBackoff doubled after each attempt. The
+// result is the last attempt's, timed from the first attempt, so latency
+// and throughput reflect eventual success.
+func withRetry(config LoadTestConfig, request func() RequestResult) RequestResult {
+	startTime := time.Now()
+	backoff := config.RetryBackoff
+
+	var result RequestResult
+	for attempt := 1; ; attempt++ {
+		result = request()
+		result.Attempts = attempt
+
+		retryable := result.Category == Category429 || result.Category == Category5xx
+		if !retryable || attempt > config.Retries {
+			break
+		}
+
+		wait := backoff
+		if result.RetryAfter > 0 {
+			wait = result.RetryAfter
+		}
+		time.Sleep(wait)
+		backoff *= 2
+	}
+
+	result.ResponseTime = time.Since(startTime)
+	return result
+}
+
+// categorize classifies a response by status class, or a failed request
+// by whether it timed out
+func categorize(statusCode int, err error) string {
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return CategoryTimeout
+		}
+		return CategoryConnection
+	}
+
+	switch {
+	case statusCode >= 200 && statusCode < 300:
+		return Category2xx
+	case statusCode == http.StatusTooManyRequests:
+		return Category429
+	case statusCode >= 400 && statusCode < 500:
+		return Category4xx
+	case statusCode >= 500:
+		return Category5xx
+	default:
+		return CategoryOther
+	}
+}
+
+// retryAfter parses a Retry-After header given in seconds, or returns 0
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// runTest sends requests with at most config.Concurrent in flight, either
+// as fast as possible or paced at config.RPS
+func runTest(config LoadTestConfig, request func() RequestResult) TestResult {
+	var wg sync.WaitGroup
+	total := config.totalRequests()
+	results := make(chan RequestResult, total)
+
 	startTime := time.Now()
 
 	// Create worker pool
 	semaphore := make(chan struct{}, config.Concurrent)
 
-	for i := 0; i < config.Messages; i++ {
+	send := func() {
+		semaphore <- struct{}{} // Acquire
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			semaphore <- struct{}{} // Acquire
 			defer func() { <-semaphore }() // Release
 
-			result := publishMessage(config.BaseURL, config.Topic, testData)
-			results <- result
+			results <- request()
 		}()
 	}
 
+	achievedRPS := 0.0
+	if config.RPS > 0 {
+		achievedRPS = pace(config.RPS, total, send)
+	} else {
+		for i := 0; i < total; i++ {
+			send()
+		}
+	}
+
 	wg.Wait()
 	close(results)
 
 	endTime := time.Now()
 	totalTime := endTime.Sub(startTime)
 
-	return analyzeResults(results, totalTime)
+	result := analyzeResults(results, totalTime)
+	result.TargetRPS = config.RPS
+	result.AchievedRPS = achievedRPS
+	return result
 }
 
-func runConsumeTest(config LoadTestConfig) TestResult {
-	var wg sync.WaitGroup
-	results := make(chan RequestResult, config.Messages)
+// totalRequests returns how many requests each test sends: RPS * Duration
+// for a paced, timed run, otherwise Messages
+func (config LoadTestConfig) totalRequests() int {
+	if config.RPS > 0 && config.Duration > 0 {
+		return int(config.RPS * config.Duration.Seconds())
+	}
+	return config.Messages
+}
 
-	startTime := time.Now()
+// pace calls send total times at rps per second and returns the rate it
+// achieved. A ticker wakes at most every millisecond and sends whatever is
+// due, so high rates don't depend on sub-millisecond timer precision. When
+// send blocks because every concurrent slot is busy, the requests that fall
+// due meanwhile go out as soon as slots free up, so the burst is capped by
+// the concurrency limit and the achieved rate falls below the target.
+func pace(rps float64, total int, send func()) float64 {
+	tick := time.Duration(float64(time.Second) / rps)
+	if tick < time.Millisecond {
+		tick = time.Millisecond
+	}
 
-	// Create worker pool
-	semaphore := make(chan struct{}, config.Concurrent)
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	start := time.Now()
+	sent := 0
+	for {
+		due := int(time.Since(start).Seconds()*rps) + 1
+		for ; sent < due && sent < total; sent++ {
+			send()
+		}
+		if sent == total {
+			break
+		}
+		<-ticker.C
+	}
+
+	// The last request is due at (total-1)/rps, so rate over the gaps
+	if total < 2 {
+		return rps
+	}
+	return float64(total-1) / time.Since(start).Seconds()
+}
+
+// runWebSocketTest subscribes config.Connections WebSocket clients to the
+// topic, publishes over one more connection, and counts what each
+// subscriber receives. The broker drops a message for any subscriber whose
+// channel is full, so the shortfall against Published * Connections is the
+// delivery loss.
+func runWebSocketTest(config LoadTestConfig) (WebSocketResult, error) {
+	wsURL := "ws" + strings.TrimPrefix(config.BaseURL, "http") + "/ws"
+	run := fmt.Sprintf("run-%d", time.Now().UnixNano())
+	total := config.totalRequests()
+
+	// Subscribe every connection before publishing anything
+	subscribers := make([]*websocket.Conn, 0, config.Connections)
+	defer func() {
+		for _, conn := range subscribers {
+			conn.Close()
+		}
+	}()
+	for i := 0; i < config.Connections; i++ {
+		conn, err := subscribeWebSocket(wsURL, config.Topic)
+		if err != nil {
+			return WebSocketResult{}, fmt.Errorf("subscriber %d: %w", i, err)
+		}
+		subscribers = append(subscribers, conn)
+	}
+
+	var (
+		wg           sync.WaitGroup
+		delivered    int64
+		lastDelivery int64 = time.Now().UnixNano()
+	)
+	results := make(chan RequestResult, total*config.Connections)
 
-	for i := 0; i < config.Messages; i++ {
+	for _, conn := range subscribers {
 		wg.Add(1)
-		go func() {
+		go func(conn *websocket.Conn) {
 			defer wg.Done()
-			semaphore <- struct{}{} // Acquire
-			defer func() { <-semaphore }() // Release
 
-			result := consumeMessage(config.BaseURL, config.Topic)
-			results <- result
-		}()
+			seen := make(map[int]bool)
+			for {
+				var msg struct {
+					Type string    `json:"type"`
+					Data wsPayload `json:"data"`
+				}
+				if err := conn.ReadJSON(&msg); err != nil {
+					return
+				}
+				if msg.Type != "message" || msg.Data.Run != run || seen[msg.Data.Seq] {
+					continue
+				}
+				seen[msg.Data.Seq] = true
+
+				now := time.Now().UnixNano()
+				results <- RequestResult{Success: true, ResponseTime: time.Duration(now - msg.Data.SentAt)}
+				atomic.AddInt64(&delivered, 1)
+				atomic.StoreInt64(&lastDelivery, now)
+			}
+		}(conn)
+	}
+
+	publisher, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		return WebSocketResult{}, fmt.Errorf("publisher: %w", err)
+	}
+	defer publisher.Close()
+
+	// Count acks as they come back so publishing never waits on them
+	var published, failed int64
+	acksDone := make(chan struct{})
+	go func() {
+		defer close(acksDone)
+		for atomic.LoadInt64(&published)+atomic.LoadInt64(&failed) < int64(total) {
+			var ack struct {
+				Type string `json:"type"`
+			}
+			if err := publisher.ReadJSON(&ack); err != nil {
+				return
+			}
+			switch ack.Type {
+			case "published":
+				atomic.AddInt64(&published, 1)
+			case "error":
+				atomic.AddInt64(&failed, 1)
+			}
+		}
+	}()
+
+	padding := generateTestMessage(config.MessageSize)["data"].(string)
+	startTime := time.Now()
+	seq := 0
+	send := func() {
+		err := publisher.WriteJSON(map[string]interface{}{
+			"type":  "publish",
+			"topic": config.Topic,
+			"data": wsPayload{
+				Run:    run,
+				Seq:    seq,
+				SentAt: time.Now().UnixNano(),
+				Data:   padding,
+			},
+		})
+		if err != nil {
+			atomic.AddInt64(&failed, 1)
+		}
+		seq++
+	}
+
+	achievedRPS := 0.0
+	if config.RPS > 0 {
+		achievedRPS = pace(config.RPS, total, send)
+	} else {
+		for i := 0; i < total; i++ {
+			send()
+		}
+	}
+
+	select {
+	case <-acksDone:
+	case <-time.After(config.DrainWait):
 	}
 
+	// Wait until every expected delivery arrives, or none has for DrainWait
+	expected := int(atomic.LoadInt64(&published)) * config.Connections
+	for atomic.LoadInt64(&delivered) < int64(expected) {
+		idle := time.Since(time.Unix(0, atomic.LoadInt64(&lastDelivery)))
+		if idle > config.DrainWait {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	totalTime := time.Since(startTime)
+
+	for _, conn := range subscribers {
+		conn.Close()
+	}
 	wg.Wait()
 	close(results)
 
-	endTime := time.Now()
-	totalTime := endTime.Sub(startTime)
+	result := WebSocketResult{
+		Connections:   config.Connections,
+		Published:     int(atomic.LoadInt64(&published)),
+		PublishFailed: total - int(atomic.LoadInt64(&published)),
+		Expected:      expected,
+		Delivered:     int(atomic.LoadInt64(&delivered)),
+		TargetRPS:     config.RPS,
+		AchievedRPS:   achievedRPS,
+		Latency:       analyzeResults(results, totalTime),
+	}
+	result.Dropped = result.Expected - result.Delivered
+	if result.Expected > 0 {
+		result.LossRate = float64(result.Dropped) / float64(result.Expected)
+	}
+	return result, nil
+}
+
+// subscribeWebSocket dials the broker and waits for the subscription to be
+// confirmed, so no message published afterwards can be missed for timing
+func subscribeWebSocket(wsURL, topic string) (*websocket.Conn, error) {
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		return nil, err
+	}
 
-	return analyzeResults(results, totalTime)
+	err = conn.WriteJSON(map[string]interface{}{
+		"type":  "subscribe",
+		"topic": topic,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// Don't hang on a broker that never confirms
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var ack struct {
+		Type string `json:"type"`
+	}
+	if err := conn.ReadJSON(&ack); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Time{})
+	if ack.Type != "subscribed" {
+		conn.Close()
+		return nil, fmt.Errorf("expected subscribed, got %q", ack.Type)
+	}
+	return conn, nil
 }
 
 func generateTestMessage(size int) map[string]interface{} {
@@ -183,7 +762,7 @@ func publishMessage(baseURL, topic string, data interface{}) RequestResult {
 
 	startTime := time.Now()
 
-	resp, err := http.Post(
+	resp, err := httpClient.Post(
 		fmt.Sprintf("%s/publish/%s", baseURL, topic),
 		"application/json",
 		bytes.NewBuffer(jsonData),
@@ -192,28 +771,38 @@ func publishMessage(baseURL, topic string, data interface{}) RequestResult {
 	responseTime := time.Since(startTime)
 
 	if err != nil {
-		return RequestResult{Success: false, ResponseTime: responseTime, Error: err}
+		return RequestResult{Success: false, ResponseTime: responseTime, Error: err, Category: categorize(0, err)}
 	}
 	defer resp.Body.Close()
 
 	success := resp.StatusCode == http.StatusOK
-	return RequestResult{Success: success, ResponseTime: responseTime}
+	return RequestResult{
+		Success:      success,
+		ResponseTime: responseTime,
+		Category:     categorize(resp.StatusCode, nil),
+		RetryAfter:   retryAfter(resp),
+	}
 }
 
 func consumeMessage(baseURL, topic string) RequestResult {
 	startTime := time.Now()
 
-	resp, err := http.Get(fmt.Sprintf("%s/consume/%s", baseURL, topic))
+	resp, err := httpClient.Get(fmt.Sprintf("%s/consume/%s", baseURL, topic))
 	responseTime := time.Since(startTime)
 
 	if err != nil {
-		return RequestResult{Success: false, ResponseTime: responseTime, Error: err}
+		return RequestResult{Success: false, ResponseTime: responseTime, Error: err, Category: categorize(0, err)}
 	}
 	defer resp.Body.Close()
 
 	// Accept both 200 (message found) and 404 (no message) as success
 	success := resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNotFound
-	return RequestResult{Success: success, ResponseTime: responseTime}
+	return RequestResult{
+		Success:      success,
+		ResponseTime: responseTime,
+		Category:     categorize(resp.StatusCode, nil),
+		RetryAfter:   retryAfter(resp),
+	}
 }
 
 func analyzeResults(results chan RequestResult, totalTime time.Duration) TestResult {
@@ -223,6 +812,8 @@ func analyzeResults(results chan RequestResult, totalTime time.Duration) TestRes
 		failedReqs      int
 		responseTimes   []time.Duration
 		totalRespTime   time.Duration
+		retries         int
+		categories      = make(map[string]int)
 	)
 
 	for result := range results {
@@ -232,24 +823,28 @@ func analyzeResults(results chan RequestResult, totalTime time.Duration) TestRes
 		} else {
 			failedReqs++
 		}
+		if result.Category != "" {
+			categories[result.Category]++
+		}
+		if result.Attempts > 1 {
+			retries += result.Attempts - 1
+		}
 		responseTimes = append(responseTimes, result.ResponseTime)
 		totalRespTime += result.ResponseTime
 	}
 
+	// Sort once for min, max, and exact percentiles. This holds every
+	// sample in memory; a streaming estimator would be needed for very
+	// long runs.
+	sort.Slice(responseTimes, func(i, j int) bool {
+		return responseTimes[i] < responseTimes[j]
+	})
+
 	var avgResponseTime, minResponseTime, maxResponseTime time.Duration
 	if len(responseTimes) > 0 {
 		avgResponseTime = totalRespTime / time.Duration(len(responseTimes))
 		minResponseTime = responseTimes[0]
-		maxResponseTime = responseTimes[0]
-
-		for _, rt := range responseTimes {
-			if rt < minResponseTime {
-				minResponseTime = rt
-			}
-			if rt > maxResponseTime {
-				maxResponseTime = rt
-			}
-		}
+		maxResponseTime = responseTimes[len(responseTimes)-1]
 	}
 
 	requestsPerSec := float64(totalRequests) / totalTime.Seconds()
@@ -262,8 +857,82 @@ func analyzeResults(results chan RequestResult, totalTime time.Duration) TestRes
 		AvgResponseTime: avgResponseTime,
 		MinResponseTime: minResponseTime,
 		MaxResponseTime: maxResponseTime,
+		P50ResponseTime: percentile(responseTimes, 50),
+		P95ResponseTime: percentile(responseTimes, 95),
+		P99ResponseTime: percentile(responseTimes, 99),
 		RequestsPerSec:  requestsPerSec,
+		Retries:         retries,
+		Categories:      categories,
+		ResponseTimes:   responseTimes,
+	}
+}
+
+// percentile returns the p-th percentile of sorted samples using the
+// nearest-rank method, or 0 if there are none
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
 	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// writeHistogramCSV writes each test's latencies as fixed-width buckets,
+// one row per non-empty bucket, for plotting offline
+func writeHistogramCSV(path string, bucket time.Duration, results map[string]TestResult) error {
+	if bucket <= 0 {
+		return fmt.Errorf("histogram bucket must be positive, got %v", bucket)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"test", "bucket_start_us", "bucket_end_us", "count"})
+
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		counts := make(map[int64]int)
+		for _, rt := range results[name].ResponseTimes {
+			counts[int64(rt/bucket)]++
+		}
+
+		buckets := make([]int64, 0, len(counts))
+		for index := range counts {
+			buckets = append(buckets, index)
+		}
+		sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+
+		for _, index := range buckets {
+			start := time.Duration(index) * bucket
+			writer.Write([]string{
+				name,
+				strconv.FormatInt(start.Microseconds(), 10),
+				strconv.FormatInt((start + bucket).Microseconds(), 10),
+				strconv.Itoa(counts[index]),
+			})
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
 }
 
 func printResults(testName string, result TestResult) {
@@ -273,10 +942,54 @@ func printResults(testName string, result TestResult) {
 	fmt.Printf("Successful:         %d\n", result.SuccessfulReqs)
 	fmt.Printf("Failed:             %d\n", result.FailedReqs)
 	fmt.Printf("Success Rate:       %.2f%%\n", float64(result.SuccessfulReqs)/float64(result.TotalRequests)*100)
+	for _, category := range []string{
+		Category2xx, Category4xx, Category429, Category5xx,
+		CategoryOther, CategoryConnection, CategoryTimeout,
+	} {
+		if count := result.Categories[category]; count > 0 {
+			fmt.Printf("  %-17s %d\n", category+":", count)
+		}
+	}
+	if result.Retries > 0 {
+		fmt.Printf("Retries:            %d\n", result.Retries)
+	}
 	fmt.Printf("Total Time:         %v\n", result.TotalTime)
 	fmt.Printf("Requests/sec:       %.2f\n", result.RequestsPerSec)
+	if result.TargetRPS > 0 {
+		fmt.Printf("Target RPS:         %.2f\n", result.TargetRPS)
+		fmt.Printf("Achieved RPS:       %.2f (%.1f%% of target)\n",
+			result.AchievedRPS, result.AchievedRPS/result.TargetRPS*100)
+	}
 	fmt.Printf("Avg Response Time:  %v\n", result.AvgResponseTime)
 	fmt.Printf("Min Response Time:  %v\n", result.MinResponseTime)
 	fmt.Printf("Max Response Time:  %v\n", result.MaxResponseTime)
+	fmt.Printf("P50 Response Time:  %v\n", result.P50ResponseTime)
+	fmt.Printf("P95 Response Time:  %v\n", result.P95ResponseTime)
+	fmt.Printf("P99 Response Time:  %v\n", result.P99ResponseTime)
 	fmt.Println()
-}
\ No newline at end of file
+}
+
+func printWebSocketResults(result WebSocketResult) {
+	fmt.Printf("\nWEBSOCKET DELIVERY RESULTS:\n")
+	fmt.Printf("=====================================\n")
+	fmt.Printf("Connections:        %d\n", result.Connections)
+	fmt.Printf("Published:          %d\n", result.Published)
+	fmt.Printf("Publish Failed:     %d\n", result.PublishFailed)
+	if result.TargetRPS > 0 {
+		fmt.Printf("Target RPS:         %.2f\n", result.TargetRPS)
+		fmt.Printf("Achieved RPS:       %.2f (%.1f%% of target)\n",
+			result.AchievedRPS, result.AchievedRPS/result.TargetRPS*100)
+	}
+	fmt.Printf("Expected:           %d\n", result.Expected)
+	fmt.Printf("Delivered:          %d\n", result.Delivered)
+	fmt.Printf("Dropped:            %d\n", result.Dropped)
+	fmt.Printf("Loss Rate:          %.2f%%\n", result.LossRate*100)
+	fmt.Printf("Total Time:         %v\n", result.Latency.TotalTime)
+	fmt.Printf("Avg Latency:        %v\n", result.Latency.AvgResponseTime)
+	fmt.Printf("Min Latency:        %v\n", result.Latency.MinResponseTime)
+	fmt.Printf("Max Latency:        %v\n", result.Latency.MaxResponseTime)
+	fmt.Printf("P50 Latency:        %v\n", result.Latency.P50ResponseTime)
+	fmt.Printf("P95 Latency:        %v\n", result.Latency.P95ResponseTime)
+	fmt.Printf("P99 Latency:        %v\n", result.Latency.P99ResponseTime)
+	fmt.Println()
+}
@@ -1,13 +1,22 @@
 package main
 
 import (
+	"container/heap"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
+	"net"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -25,15 +34,31 @@ type Message struct {
 	Headers   map[string]string      `json:"headers,omitempty"`
 	Timestamp time.Time              `json:"timestamp"`
 	RetryCount int                   `json:"retryCount"`
+	DeliverAt time.Time              `json:"deliverAt,omitempty"` // zero unless published with a delay
+	Sequence  uint64                 `json:"sequence,omitempty"`  // position in the topic, assigned when the message becomes visible
+	
+	size int // data and headers as JSON, in bytes; computed by byteSize
 }
 
 // WebSocketMessage represents a WebSocket message
 type WebSocketMessage struct {
-	Type      string      `json:"type"` // publish, subscribe, unsubscribe
-	Topic     string      `json:"topic"`
-	Data      interface{} `json:"data,omitempty"`
-	MessageID string      `json:"messageId,omitempty"`
-	Timestamp time.Time   `json:"timestamp"`
+	Type      string            `json:"type"` // publish, subscribe, unsubscribe, credit
+	Topic     string            `json:"topic"`
+	Data      interface{}       `json:"data,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Delay     int64             `json:"delay,omitempty"` // milliseconds to hold a published message
+	MessageID string            `json:"messageId,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+	
+	// On subscribe, replay retained messages numbered fromSequence or
+	// later, or delivered at or after fromTimestamp, before live ones
+	FromSequence  uint64    `json:"fromSequence,omitempty"`
+	FromTimestamp time.Time `json:"fromTimestamp,omitempty"`
+	
+	// On subscribe, the messages the client will take before it grants
+	// more; on credit, how many more it grants
+	Credit int `json:"credit,omitempty"`
+	Count  int `json:"count,omitempty"`
 }
 
 // Subscription represents a consumer subscription
@@ -42,49 +67,147 @@ type Subscription struct {
 	Topic    string
 	Channel  chan *Message
 	Consumer *Consumer
+	Cutover  uint64 // topic sequence when subscribed; live messages are numbered above it
+	
+	overflowed atomic.Bool // set when enqueue drops a message because Channel is full
+	
+	// Credit-based flow control. A limited subscription's forwarder sends
+	// a message only while credit remains, and waits for a grant otherwise.
+	creditMutex sync.Mutex
+	limited     bool
+	credit      int
+	granted     chan struct{} // signalled when credit is granted
+	done        chan struct{} // closed on unsubscribe, ending a wait for credit
 }
 
 // Consumer represents a message consumer
 type Consumer struct {
 	ID           string
 	Subscriptions map[string]*Subscription
-	WebSocket    *websocket.Conn
+	WebSocket    *websocket.Conn // nil for consumers without a connection, such as RPC callers
+	ConnectedAt  time.Time
 	mutex        sync.RWMutex
 }
 
 // Topic represents a message topic
 type Topic struct {
 	Name      string
+	Config    TopicConfig // fixed when the topic is created, except MaxQueueSize
 	Messages  []*Message
 	Consumers map[string]*Consumer
+	Scheduled int    // delayed messages not yet delivered
+	Sequence  uint64 // sequence number of the last message enqueued
+	Bytes     int    // total byteSize of Messages, kept up to date as they come and go
 	mutex     sync.RWMutex
 }
 
+// Topic ordering modes
+const (
+	OrderingFIFO     = "fifo"     // messages are consumed in arrival order
+	OrderingPriority = "priority" // highest X-Priority first, then arrival order
+)
+
+// priorityHeader holds a message's priority on priority-ordered topics.
+// Missing or non-integer values count as 0.
+const priorityHeader = "X-Priority"
+
+// TopicConfig holds the per-topic settings. Auto-created topics get the
+// broker defaults; POST /topics can override any of them.
+type TopicConfig struct {
+	MaxQueueSize   int    `json:"maxQueueSize"`
+	RetentionHours int    `json:"retentionHours"`
+	Ordering       string `json:"ordering"`
+}
+
+// errTopicExists is returned by CreateTopic for a name already in use
+var errTopicExists = fmt.Errorf("topic already exists")
+
+// scheduledMessage is a delayed message waiting for its delivery time
+type scheduledMessage struct {
+	topic   *Topic
+	message *Message
+}
+
+// scheduleHeap orders delayed messages by delivery time, earliest first
+type scheduleHeap []scheduledMessage
+
+func (h scheduleHeap) Len() int { return len(h) }
+func (h scheduleHeap) Less(i, j int) bool {
+	return h[i].message.DeliverAt.Before(h[j].message.DeliverAt)
+}
+func (h scheduleHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *scheduleHeap) Push(x interface{}) { *h = append(*h, x.(scheduledMessage)) }
+func (h *scheduleHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
 // MessageBroker is the main broker struct
 type MessageBroker struct {
 	topics    map[string]*Topic
 	consumers map[string]*Consumer
 	mutex     sync.RWMutex
 	
+	// Delayed messages, delivered by schedulerRoutine
+	scheduled     scheduleHeap
+	scheduleMutex sync.Mutex
+	scheduleWake  chan struct{}
+	
+	// now is the broker's clock, replaceable so delays can be tested
+	now func() time.Time
+	
 	// Configuration
 	maxMessageSize int
+	maxBatchSize   int // bytes of a batch publish body
+	maxImportSize  int // bytes of an import upload
 	maxQueueSize   int
+	maxDelay       time.Duration // longest publish delay; 0 means unlimited
 	retentionHours int
+	retention      []RetentionPolicy // applied together; the first to evict a message wins
+	allowedOrigins []string // lower-case patterns, "*" matching any run of characters
+	adminToken     string   // bearer token for /admin; empty disables those endpoints
+	
+	// Rejects publishes with 503 while publishing is failing or slow
+	breaker *CircuitBreaker
+	
+	// Processed-ID Bloom filters per consumer group, created on first ack
+	consumed         map[string]*BloomFilter
+	consumedMutex    sync.Mutex
+	consumedExpected uint32
+	consumedFPRate   float64
+	maxGroups        int // 0 means unlimited
+	
+	// Topics each topic's messages are copied to, by source topic
+	mirrors     map[string][]string
+	mirrorMutex sync.RWMutex
+	
+	// WebSocket connection limits, checked before each upgrade
+	connMutex       sync.Mutex
+	connections     int
+	connectionsByIP map[string]int
+	maxConnections  int           // 0 means unlimited
+	maxConnsPerIP   int           // 0 means unlimited
+	defaultCredit   int           // initial credit of a subscription that sets none; 0 means unlimited
+	slowStart       time.Duration // ramp maxConnections up over this long after start
+	startedAt       time.Time
 	
 	// Metrics
 	messagesPublished prometheus.Counter
 	messagesConsumed  prometheus.Counter
 	activeConnections prometheus.Gauge
+	rejectedConns     *prometheus.CounterVec
+	oversizeRejected  prometheus.Counter
 	queueSizes        *prometheus.GaugeVec
+	queueLimits       *prometheus.GaugeVec
+	mirrorFailures    prometheus.Counter
 	processingTime    prometheus.Histogram
 }
 
-// WebSocket upgrader
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all origins for demo
-	},
-}
+// WebSocket upgrader. main points CheckOrigin at the broker's allowed
+// origins; a rejected origin gets 403.
+var upgrader = websocket.Upgrader{}
 
 // Prometheus metrics
 var (
@@ -103,11 +226,31 @@ var (
 		Help: "Number of active WebSocket connections",
 	})
 	
+	rejectedConns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "message_broker_rejected_connections_total",
+		Help: "WebSocket connections rejected by a connection limit",
+	}, []string{"reason"})
+	
+	oversizeRejected = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "message_broker_oversize_messages_rejected_total",
+		Help: "Messages rejected for exceeding the maximum message size",
+	})
+	
 	queueSizes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "message_broker_queue_size",
 		Help: "Number of messages in queue per topic",
 	}, []string{"topic"})
 	
+	queueLimits = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "message_broker_queue_limit",
+		Help: "Configured maxQueueSize per topic",
+	}, []string{"topic"})
+	
+	mirrorFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "message_broker_mirror_failures_total",
+		Help: "Mirrored copies that could not be published",
+	})
+	
 	processingTime = prometheus.NewHistogram(prometheus.HistogramOpts{
 		Name: "message_broker_processing_duration_seconds",
 		Help: "Time spent processing messages",
@@ -118,31 +261,94 @@ func init() {
 	prometheus.MustRegister(messagesPublished)
 	prometheus.MustRegister(messagesConsumed)
 	prometheus.MustRegister(activeConnections)
+	prometheus.MustRegister(rejectedConns)
+	prometheus.MustRegister(oversizeRejected)
 	prometheus.MustRegister(queueSizes)
+	prometheus.MustRegister(queueLimits)
+	prometheus.MustRegister(mirrorFailures)
 	prometheus.MustRegister(processingTime)
 }
 
-// NewMessageBroker creates a new message broker
+// NewMessageBroker creates a new message broker and starts its cleanup and
+// scheduler routines
 func NewMessageBroker() *MessageBroker {
+	broker := newMessageBroker()
+	
+	// Start cleanup routine
+	go broker.cleanupRoutine()
+	go broker.schedulerRoutine()
+	
+	return broker
+}
+
+// newMessageBroker creates a message broker configured from the environment,
+// without starting its background routines
+func newMessageBroker() *MessageBroker {
 	maxMessageSize, _ := strconv.Atoi(getEnv("MAX_MESSAGE_SIZE", "1048576")) // 1MB
+	maxBatchSize, _ := strconv.Atoi(getEnv("MAX_BATCH_SIZE", "10485760"))    // 10MB
+	maxImportSize, _ := strconv.Atoi(getEnv("MAX_IMPORT_SIZE", "1073741824")) // 1GB
 	maxQueueSize, _ := strconv.Atoi(getEnv("MAX_QUEUE_SIZE", "10000"))
+	maxDelay, err := time.ParseDuration(getEnv("MAX_DELAY", "168h"))
+	if err != nil || maxDelay < 0 {
+		log.Printf("Invalid MAX_DELAY, using 168h")
+		maxDelay = 168 * time.Hour
+	}
 	retentionHours, _ := strconv.Atoi(getEnv("RETENTION_HOURS", "24"))
+	allowedOrigins := parseOrigins(getEnv("ALLOWED_ORIGINS", "*"))
+	adminToken := getEnv("ADMIN_TOKEN", "")
+	maxConnections, _ := strconv.Atoi(getEnv("WS_MAX_CONNECTIONS", "10000"))
+	maxConnectionsPerIP, _ := strconv.Atoi(getEnv("WS_MAX_CONNECTIONS_PER_IP", "100"))
+	defaultCredit, _ := strconv.Atoi(getEnv("WS_DEFAULT_CREDIT", "0"))
+	slowStart, err := time.ParseDuration(getEnv("WS_SLOW_START", "0s"))
+	if err != nil {
+		log.Printf("Invalid WS_SLOW_START: %v, disabling slow start", err)
+		slowStart = 0
+	}
+	maxGroups, _ := strconv.Atoi(getEnv("CONSUMED_MAX_GROUPS", "1000"))
+	consumedExpected, consumedFPRate := consumedFilterConfig(
+		getEnv("CONSUMED_FILTER_EXPECTED", "100000"),
+		getEnv("CONSUMED_FILTER_FP_RATE", "0.01"),
+	)
 	
 	broker := &MessageBroker{
 		topics:            make(map[string]*Topic),
 		consumers:         make(map[string]*Consumer),
 		maxMessageSize:    maxMessageSize,
+		maxBatchSize:      maxBatchSize,
+		maxImportSize:     maxImportSize,
 		maxQueueSize:      maxQueueSize,
+		maxDelay:          maxDelay,
 		retentionHours:    retentionHours,
+		retention:         retentionPoliciesFromEnv(),
+		allowedOrigins:    allowedOrigins,
+		adminToken:        adminToken,
+		consumed:          make(map[string]*BloomFilter),
+		consumedExpected:  consumedExpected,
+		consumedFPRate:    consumedFPRate,
+		maxGroups:         maxGroups,
+		mirrors:           make(map[string][]string),
+		connectionsByIP:   make(map[string]int),
+		maxConnections:    maxConnections,
+		maxConnsPerIP:     maxConnectionsPerIP,
+		defaultCredit:     defaultCredit,
+		slowStart:         slowStart,
+		startedAt:         time.Now(),
 		messagesPublished: messagesPublished,
 		messagesConsumed:  messagesConsumed,
 		activeConnections: activeConnections,
+		rejectedConns:     rejectedConns,
+		oversizeRejected:  oversizeRejected,
 		queueSizes:        queueSizes,
+		queueLimits:       queueLimits,
+		mirrorFailures:    mirrorFailures,
 		processingTime:    processingTime,
+		scheduleWake:      make(chan struct{}, 1),
+		now:               time.Now,
 	}
 	
-	// Start cleanup routine
-	go broker.cleanupRoutine()
+	broker.breaker = NewCircuitBreaker("publish", breakerConfigFromEnv(), func() time.Time {
+		return broker.now()
+	})
 	
 	return broker
 }
@@ -155,6 +361,23 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// consumedFilterConfig parses the size of the per-group processed-ID
+// filters, falling back to 100,000 IDs at a 1% false positive rate if
+// either value is invalid
+func consumedFilterConfig(expected, fpRate string) (uint32, float64) {
+	n, err := strconv.ParseUint(expected, 10, 32)
+	p, perr := strconv.ParseFloat(fpRate, 64)
+	if err != nil || perr != nil {
+		log.Printf("Invalid consumed filter config (%q, %q), using defaults", expected, fpRate)
+		return 100000, 0.01
+	}
+	if _, err := NewBloomFilter(uint32(n), p); err != nil {
+		log.Printf("Invalid consumed filter config: %v, using defaults", err)
+		return 100000, 0.01
+	}
+	return uint32(n), p
+}
+
 // GetOrCreateTopic gets or creates a topic
 func (mb *MessageBroker) GetOrCreateTopic(name string) *Topic {
 	mb.mutex.Lock()
@@ -166,61 +389,422 @@ func (mb *MessageBroker) GetOrCreateTopic(name string) *Topic {
 	
 	topic := &Topic{
 		Name:      name,
+		Config:    mb.defaultTopicConfig(),
 		Messages:  make([]*Message, 0),
 		Consumers: make(map[string]*Consumer),
 	}
 	
 	mb.topics[name] = topic
+	mb.queueLimits.WithLabelValues(name).Set(float64(topic.Config.MaxQueueSize))
 	return topic
 }
 
+// publishTopic returns the topic a publish goes to, creating it like
+// GetOrCreateTopic unless it is an RPC reply topic. Those only exist while
+// their call waits, so a reply to a call that has ended is rejected with
+// not_found instead of recreating a topic nobody will read or delete.
+func (mb *MessageBroker) publishTopic(name string) (*Topic, error) {
+	if strings.HasPrefix(name, replyTopicPrefix) {
+		mb.mutex.RLock()
+		topic, exists := mb.topics[name]
+		mb.mutex.RUnlock()
+		if !exists {
+			return nil, notFound("reply topic %s no longer exists; its call has ended", name)
+		}
+		return topic, nil
+	}
+	return mb.GetOrCreateTopic(name), nil
+}
+
+// CreateTopic creates a topic with its own configuration. Fields left at
+// zero take the broker defaults.
+func (mb *MessageBroker) CreateTopic(name string, config TopicConfig) (*Topic, error) {
+	if name == "" {
+		return nil, fmt.Errorf("topic name is required")
+	}
+	
+	defaults := mb.defaultTopicConfig()
+	if config.MaxQueueSize == 0 {
+		config.MaxQueueSize = defaults.MaxQueueSize
+	}
+	if config.RetentionHours == 0 {
+		config.RetentionHours = defaults.RetentionHours
+	}
+	if config.Ordering == "" {
+		config.Ordering = defaults.Ordering
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	
+	mb.mutex.Lock()
+	defer mb.mutex.Unlock()
+	
+	if _, exists := mb.topics[name]; exists {
+		return nil, errTopicExists
+	}
+	
+	topic := &Topic{
+		Name:      name,
+		Config:    config,
+		Messages:  make([]*Message, 0),
+		Consumers: make(map[string]*Consumer),
+	}
+	mb.topics[name] = topic
+	mb.queueLimits.WithLabelValues(name).Set(float64(config.MaxQueueSize))
+	
+	log.Printf("Created topic %s with config %+v", name, config)
+	return topic, nil
+}
+
+// defaultTopicConfig returns the broker-wide settings auto-created topics use
+func (mb *MessageBroker) defaultTopicConfig() TopicConfig {
+	return TopicConfig{
+		MaxQueueSize:   mb.maxQueueSize,
+		RetentionHours: mb.retentionHours,
+		Ordering:       OrderingFIFO,
+	}
+}
+
+// SetMaxQueueSize changes how many messages, including delayed ones, a
+// topic holds before publishes are rejected. Zero restores the broker
+// default. Lowering it below the current depth keeps the queued messages
+// and rejects publishes until consumers drain the queue below the new limit.
+func (mb *MessageBroker) SetMaxQueueSize(topicName string, maxQueueSize int) (TopicConfig, error) {
+	if maxQueueSize == 0 {
+		maxQueueSize = mb.maxQueueSize
+	}
+	if maxQueueSize < 1 {
+		return TopicConfig{}, invalidRequest("maxQueueSize must be positive")
+	}
+	
+	mb.mutex.RLock()
+	topic, exists := mb.topics[topicName]
+	mb.mutex.RUnlock()
+	if !exists {
+		return TopicConfig{}, notFound("topic %s not found", topicName)
+	}
+	
+	topic.mutex.Lock()
+	topic.Config.MaxQueueSize = maxQueueSize
+	config := topic.Config
+	topic.mutex.Unlock()
+	
+	mb.queueLimits.WithLabelValues(topicName).Set(float64(maxQueueSize))
+	log.Printf("Set topic %s maxQueueSize to %d", topicName, maxQueueSize)
+	return config, nil
+}
+
+// validate checks a fully populated topic config
+func (c TopicConfig) validate() error {
+	if c.MaxQueueSize < 1 {
+		return fmt.Errorf("maxQueueSize must be positive")
+	}
+	if c.RetentionHours < 1 {
+		return fmt.Errorf("retentionHours must be positive")
+	}
+	if c.Ordering != OrderingFIFO && c.Ordering != OrderingPriority {
+		return fmt.Errorf("ordering must be %q or %q", OrderingFIFO, OrderingPriority)
+	}
+	return nil
+}
+
 // PublishMessage publishes a message to a topic
 func (mb *MessageBroker) PublishMessage(topicName string, data interface{}, headers map[string]string) (*Message, error) {
+	return mb.PublishDelayed(topicName, data, headers, 0)
+}
+
+// PublishDelayed publishes a message that consumers only see once delay
+// has passed. Until then it is held in the broker's schedule, where it
+// counts toward the topic's queue size limit but not its retention.
+// Publishes go through the circuit breaker: a slow or internally failing
+// publish counts against it, and while it is open PublishDelayed fails at
+// once. A full queue doesn't count, since the breaker is shared by every
+// topic and one saturated topic must not shut the others out, and neither
+// does a late reply to an RPC call.
+// Failures are *BrokerErrors coded queue_full, circuit_open,
+// message_too_large, invalid_request for a delay over MAX_DELAY, or
+// not_found for a reply to an RPC call that has ended. Once the message is published, copies go to the
+// topic's mirrors; see Mirror.
+func (mb *MessageBroker) PublishDelayed(topicName string, data interface{}, headers map[string]string, delay time.Duration) (*Message, error) {
+	// An oversize message says nothing about the broker's health, so it is
+	// rejected before the breaker counts it
+	if err := mb.checkMessageSize(data); err != nil {
+		return nil, err
+	}
+	if mb.maxDelay > 0 && delay > mb.maxDelay {
+		return nil, invalidRequest("delay of %v is over the %v limit", delay, mb.maxDelay)
+	}
+	
+	var message *Message
+	var publishErr error
+	err := mb.breaker.Execute(func() error {
+		message, publishErr = mb.publish(topicName, data, headers, delay)
+		switch errorCode(publishErr) {
+		case CodeQueueFull, CodeNotFound:
+			return nil // the topic's backlog or the caller's mistake, not the broker's health
+		}
+		return publishErr
+	})
+	if err == errCircuitOpen {
+		return nil, &BrokerError{
+			Code:       CodeCircuitOpen,
+			Message:    "publish rejected",
+			Status:     http.StatusServiceUnavailable,
+			RetryAfter: mb.breaker.RetryAfter(),
+			Err:        err,
+		}
+	}
+	if publishErr != nil {
+		return nil, publishErr
+	}
+	
+	mb.publishMirrors(message, delay)
+	return message, nil
+}
+
+// checkMessageSize rejects data whose JSON encoding is over
+// MAX_MESSAGE_SIZE. This is the check every publish path shares; HTTP
+// bodies and WebSocket frames are also capped as they are read, so an
+// oversize one is never read into memory whole.
+func (mb *MessageBroker) checkMessageSize(data interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return invalidRequest("data is not valid JSON: %v", err)
+	}
+	if mb.maxMessageSize > 0 && len(encoded) > mb.maxMessageSize {
+		return mb.tooLarge("message data is %d bytes, over the %d byte limit", len(encoded), mb.maxMessageSize)
+	}
+	return nil
+}
+
+// tooLarge counts a message rejected for its size and returns its error
+func (mb *MessageBroker) tooLarge(format string, args ...interface{}) error {
+	mb.oversizeRejected.Inc()
+	return newBrokerError(http.StatusRequestEntityTooLarge, CodeMessageTooLarge, format, args...)
+}
+
+// decodeMessageBody decodes a request body holding one message's data. The
+// body is read through http.MaxBytesReader, so one over MAX_MESSAGE_SIZE is
+// cut off at the limit; the limit counts the body as sent, whitespace
+// included.
+func (mb *MessageBroker) decodeMessageBody(w http.ResponseWriter, r *http.Request, data interface{}) error {
+	if err := json.NewDecoder(limitBody(w, r, mb.maxMessageSize)).Decode(data); err != nil {
+		if bodyTooLarge(err) {
+			return mb.tooLarge("request body is over the %d byte limit", mb.maxMessageSize)
+		}
+		return invalidRequest("invalid JSON: %v", err)
+	}
+	return nil
+}
+
+// maxConfigBodySize caps the body of requests that carry a topic config
+const maxConfigBodySize = 64 << 10
+
+// limitBody returns the request body read through http.MaxBytesReader, so
+// the server stops reading after limit bytes; 0 leaves it unlimited
+func limitBody(w http.ResponseWriter, r *http.Request, limit int) io.Reader {
+	if limit <= 0 {
+		return r.Body
+	}
+	return http.MaxBytesReader(w, r.Body, int64(limit))
+}
+
+// bodyTooLarge reports whether err came from reading past limitBody's limit
+func bodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// decodeConfigBody decodes a request body holding a topic config, answering
+// message_too_large past maxConfigBodySize like the publish endpoints do
+func decodeConfigBody(w http.ResponseWriter, r *http.Request, request interface{}) error {
+	if err := json.NewDecoder(limitBody(w, r, maxConfigBodySize)).Decode(request); err != nil {
+		if bodyTooLarge(err) {
+			return newBrokerError(http.StatusRequestEntityTooLarge, CodeMessageTooLarge, "request body is over the %d byte limit", maxConfigBodySize)
+		}
+		return invalidRequest("invalid JSON: %v", err)
+	}
+	return nil
+}
+
+// publish does the work of PublishDelayed
+func (mb *MessageBroker) publish(topicName string, data interface{}, headers map[string]string, delay time.Duration) (*Message, error) {
 	timer := prometheus.NewTimer(mb.processingTime)
 	defer timer.ObserveDuration()
 	
-	topic := mb.GetOrCreateTopic(topicName)
+	topic, err := mb.publishTopic(topicName)
+	if err != nil {
+		return nil, err
+	}
 	
 	message := &Message{
 		ID:        uuid.New().String(),
 		Topic:     topicName,
 		Data:      data,
 		Headers:   headers,
-		Timestamp: time.Now(),
+		Timestamp: mb.now(),
 		RetryCount: 0,
 	}
+	message.byteSize() // once, before the topic lock is taken
 	
 	topic.mutex.Lock()
 	
-	// Check queue size limit
-	if len(topic.Messages) >= mb.maxQueueSize {
+	// Check queue size limit. Scheduled messages count so that they
+	// always fit once they are due.
+	if depth := len(topic.Messages) + topic.Scheduled; depth >= topic.Config.MaxQueueSize {
+		limit := topic.Config.MaxQueueSize
+		mb.queueSizes.WithLabelValues(topicName).Set(float64(len(topic.Messages)))
+		topic.mutex.Unlock()
+		return nil, &BrokerError{
+			Code:       CodeQueueFull,
+			Message:    fmt.Sprintf("topic %s queue is full (%d of %d messages)", topicName, depth, limit),
+			Status:     http.StatusServiceUnavailable,
+			RetryAfter: queueFullRetryAfter,
+			Details: map[string]interface{}{
+				"depth":        depth,
+				"maxQueueSize": limit,
+			},
+		}
+	}
+	
+	mb.messagesPublished.Inc()
+	
+	if delay > 0 {
+		message.DeliverAt = message.Timestamp.Add(delay)
+		topic.Scheduled++
 		topic.mutex.Unlock()
-		return nil, fmt.Errorf("topic queue is full")
+		mb.schedule(topic, message)
+		
+		log.Printf("Scheduled message %s to topic %s for %s", message.ID, topicName, message.DeliverAt.Format(time.RFC3339))
+		return message, nil
 	}
 	
-	// Add message to topic
-	topic.Messages = append(topic.Messages, message)
+	mb.enqueue(topic, message)
+	topic.mutex.Unlock()
+	
+	log.Printf("Published message %s to topic %s", message.ID, topicName)
+	return message, nil
+}
+
+// enqueue adds a message to the topic and offers it to subscribers. The
+// caller must hold topic.mutex.
+func (mb *MessageBroker) enqueue(topic *Topic, message *Message) {
+	// Number messages in the order they become visible. Every enqueue
+	// holds the topic lock, so concurrent publishers never share or skip
+	// a number.
+	topic.Sequence++
+	message.Sequence = topic.Sequence
+	
+	// Add message to topic, behind every message of equal or higher
+	// priority if the topic is priority-ordered
+	if topic.Config.Ordering == OrderingPriority {
+		priority := message.priority()
+		i := len(topic.Messages)
+		for i > 0 && topic.Messages[i-1].priority() < priority {
+			i--
+		}
+		topic.Messages = append(topic.Messages, nil)
+		copy(topic.Messages[i+1:], topic.Messages[i:])
+		topic.Messages[i] = message
+	} else {
+		topic.Messages = append(topic.Messages, message)
+	}
+	topic.Bytes += message.byteSize()
+	
+	// Count- and size-based retention evict as messages arrive
+	for _, policy := range mb.retention {
+		if policy.OnPublish() {
+			policy.Retain(topic, message.availableAt())
+		}
+	}
 	
 	// Update metrics
-	mb.messagesPublished.Inc()
-	mb.queueSizes.WithLabelValues(topicName).Set(float64(len(topic.Messages)))
+	mb.queueSizes.WithLabelValues(topic.Name).Set(float64(len(topic.Messages)))
 	
 	// Notify consumers
 	for _, consumer := range topic.Consumers {
 		select {
-		case consumer.Subscriptions[topicName].Channel <- message:
+		case consumer.Subscriptions[topic.Name].Channel <- message:
 		default:
-			// Consumer channel is full, skip
+			// Consumer channel is full, skip. The delivery goroutine
+			// resends it from the topic once it catches up.
+			consumer.Subscriptions[topic.Name].overflowed.Store(true)
 		}
 	}
+}
+
+// schedule adds a delayed message to the schedule and wakes the scheduler
+// in case it is now the earliest
+func (mb *MessageBroker) schedule(topic *Topic, message *Message) {
+	mb.scheduleMutex.Lock()
+	heap.Push(&mb.scheduled, scheduledMessage{topic: topic, message: message})
+	mb.scheduleMutex.Unlock()
+	
+	select {
+	case mb.scheduleWake <- struct{}{}:
+	default: // a wake-up is already pending
+	}
+}
+
+// deliverDue moves every scheduled message whose time has come into its
+// topic, in delivery time order, and returns when the next one is due, or
+// the zero time if none are scheduled
+func (mb *MessageBroker) deliverDue() time.Time {
+	now := mb.now()
+	
+	mb.scheduleMutex.Lock()
+	var due []scheduledMessage
+	for len(mb.scheduled) > 0 && !mb.scheduled[0].message.DeliverAt.After(now) {
+		due = append(due, heap.Pop(&mb.scheduled).(scheduledMessage))
+	}
+	var next time.Time
+	if len(mb.scheduled) > 0 {
+		next = mb.scheduled[0].message.DeliverAt
+	}
+	mb.scheduleMutex.Unlock()
 	
-	topic.mutex.Unlock()
+	for _, item := range due {
+		item.topic.mutex.Lock()
+		item.topic.Scheduled--
+		mb.enqueue(item.topic, item.message)
+		item.topic.mutex.Unlock()
+		
+		log.Printf("Delivered delayed message %s to topic %s", item.message.ID, item.topic.Name)
+	}
+	return next
+}
+
+// schedulerRoutine delivers delayed messages when they fall due, sleeping
+// until the earliest one unless a new message is scheduled first
+func (mb *MessageBroker) schedulerRoutine() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
 	
-	log.Printf("Published message %s to topic %s", message.ID, topicName)
-	return message, nil
+	for {
+		next := mb.deliverDue()
+		
+		wait := time.Hour
+		if !next.IsZero() {
+			wait = next.Sub(mb.now())
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+		
+		select {
+		case <-timer.C:
+		case <-mb.scheduleWake:
+		}
+	}
 }
 
-// ConsumeMessage consumes a message from a topic
+// ConsumeMessage consumes a message from a topic. An empty topic is a
+// *BrokerError coded no_messages.
 func (mb *MessageBroker) ConsumeMessage(topicName string) (*Message, error) {
 	timer := prometheus.NewTimer(mb.processingTime)
 	defer timer.ObserveDuration()
@@ -231,12 +815,14 @@ func (mb *MessageBroker) ConsumeMessage(topicName string) (*Message, error) {
 	defer topic.mutex.Unlock()
 	
 	if len(topic.Messages) == 0 {
-		return nil, fmt.Errorf("no messages available")
+		return nil, newBrokerError(http.StatusNotFound, CodeNoMessages, "no messages available in topic %s", topicName)
 	}
 	
 	// Get first message (FIFO)
 	message := topic.Messages[0]
+	topic.Messages[0] = nil
 	topic.Messages = topic.Messages[1:]
+	topic.Bytes -= message.byteSize()
 	
 	// Update metrics
 	mb.messagesConsumed.Inc()
@@ -246,89 +832,434 @@ func (mb *MessageBroker) ConsumeMessage(topicName string) (*Message, error) {
 	return message, nil
 }
 
+// maxPeekCount caps how many messages one peek returns, bounding how long
+// it holds the topic's read lock
+const maxPeekCount = 1000
+
+// errOutOfRange is returned by PeekMessages when start is past the last
+// queued message
+var errOutOfRange = &BrokerError{Code: CodeOutOfRange, Message: "offset out of range", Status: http.StatusNotFound}
+
+// PeekMessages returns up to count queued messages starting at position
+// start, where 0 is the next message ConsumeMessage would return, without
+// removing them. Only the read lock is held, and only while the slice of
+// pointers is copied, so publishers wait no longer than for a stats call.
+func (mb *MessageBroker) PeekMessages(topicName string, start, count int) ([]*Message, int, error) {
+	mb.mutex.RLock()
+	topic, exists := mb.topics[topicName]
+	mb.mutex.RUnlock()
+	
+	if !exists {
+		return nil, 0, notFound("topic %s not found", topicName)
+	}
+	
+	topic.mutex.RLock()
+	defer topic.mutex.RUnlock()
+	
+	total := len(topic.Messages)
+	if start >= total {
+		return nil, total, errOutOfRange
+	}
+	end := start + count
+	if end > total {
+		end = total
+	}
+	
+	messages := make([]*Message, end-start)
+	copy(messages, topic.Messages[start:end])
+	return messages, total, nil
+}
+
 // Subscribe creates a subscription for a consumer
 func (mb *MessageBroker) Subscribe(consumerID, topicName string) *Subscription {
+	subscription, _ := mb.SubscribeFrom(consumerID, topicName, 0, time.Time{})
+	return subscription
+}
+
+// SubscribeFrom creates a subscription for a consumer and returns the
+// retained messages it missed: those numbered fromSequence or later, or
+// that reached the topic at or after fromTimestamp, in sequence order. With
+// both zero nothing is replayed. The replay is taken under the same topic
+// lock that attaches the consumer, so it ends exactly at the subscription's
+// Cutover and the first live message is the one numbered after it.
+func (mb *MessageBroker) SubscribeFrom(consumerID, topicName string, fromSequence uint64, fromTimestamp time.Time) (*Subscription, []*Message) {
 	topic := mb.GetOrCreateTopic(topicName)
 	
-	mb.mutex.Lock()
-	consumer, exists := mb.consumers[consumerID]
-	if !exists {
-		consumer = &Consumer{
-			ID:            consumerID,
-			Subscriptions: make(map[string]*Subscription),
-		}
-		mb.consumers[consumerID] = consumer
-	}
-	mb.mutex.Unlock()
+	consumer := mb.connectConsumer(consumerID, nil)
 	
 	subscription := &Subscription{
 		ID:       uuid.New().String(),
 		Topic:    topicName,
 		Channel:  make(chan *Message, 100),
 		Consumer: consumer,
+		granted:  make(chan struct{}, 1),
+		done:     make(chan struct{}),
 	}
 	
 	consumer.mutex.Lock()
 	consumer.Subscriptions[topicName] = subscription
 	consumer.mutex.Unlock()
 	
+	var replay []*Message
 	topic.mutex.Lock()
 	topic.Consumers[consumerID] = consumer
+	subscription.Cutover = topic.Sequence
+	if fromSequence > 0 || !fromTimestamp.IsZero() {
+		replay = retainedMessages(topic, fromSequence, topic.Sequence+1, fromTimestamp)
+	}
 	topic.mutex.Unlock()
 	
 	log.Printf("Consumer %s subscribed to topic %s", consumerID, topicName)
-	return subscription
+	return subscription, replay
 }
 
-// Unsubscribe removes a subscription
-func (mb *MessageBroker) Unsubscribe(consumerID, topicName string) {
-	mb.mutex.RLock()
-	consumer, exists := mb.consumers[consumerID]
-	mb.mutex.RUnlock()
-	
-	if !exists {
-		return
-	}
-	
-	consumer.mutex.Lock()
-	if subscription, exists := consumer.Subscriptions[topicName]; exists {
-		close(subscription.Channel)
-		delete(consumer.Subscriptions, topicName)
-	}
-	consumer.mutex.Unlock()
-	
-	// Remove from topic
-	if topic, exists := mb.topics[topicName]; exists {
-		topic.mutex.Lock()
-		delete(topic.Consumers, consumerID)
-		topic.mutex.Unlock()
+// retainedMessages returns the queued messages of a topic numbered from
+// from up to but not including to, that reached the topic at or after
+// since, in sequence order. The caller must hold topic.mutex.
+func retainedMessages(topic *Topic, from, to uint64, since time.Time) []*Message {
+	var messages []*Message
+	for _, message := range topic.Messages {
+		if message.Sequence >= from && message.Sequence < to && !message.availableAt().Before(since) {
+			messages = append(messages, message)
+		}
 	}
-	
-	log.Printf("Consumer %s unsubscribed from topic %s", consumerID, topicName)
+	// Priority-ordered topics don't queue messages in sequence order
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].Sequence < messages[j].Sequence
+	})
+	return messages
 }
 
-// GetTopicStats returns statistics for a topic
-func (mb *MessageBroker) GetTopicStats(topicName string) map[string]interface{} {
-	mb.mutex.RLock()
-	topic, exists := mb.topics[topicName]
-	mb.mutex.RUnlock()
-	
-	if !exists {
-		return map[string]interface{}{
-			"exists": false,
+// forwardMessages writes a subscription's messages to its WebSocket: the
+// replay first, then live messages as they are published. Messages
+// published while subscribed are numbered consecutively, so when enqueue
+// drops some because the channel is full, the goroutine notices either a
+// live message skipping ahead or, once it has drained the channel, the
+// overflowed flag. It then sends the missed messages still retained on the
+// topic, and skips any that later arrive on the channel as well. On a
+// limited subscription it waits for credit before each message; while it
+// waits the channel fills, and what enqueue drops is caught up the same way.
+func (mb *MessageBroker) forwardMessages(conn *wsWriter, subscription *Subscription, replay []*Message) {
+	send := func(messages ...*Message) bool {
+		for _, message := range messages {
+			if !subscription.takeCredit() {
+				return false // unsubscribed while waiting
+			}
+			err := conn.WriteJSON(map[string]interface{}{
+				"type":      "message",
+				"topic":     message.Topic,
+				"data":      message.Data,
+				"headers":   message.Headers,
+				"messageId": message.ID,
+				"timestamp": message.Timestamp,
+				"sequence":  message.Sequence,
+			})
+			if err != nil {
+				log.Printf("WebSocket write error: %v", err)
+				return false
+			}
 		}
+		return true
 	}
 	
-	topic.mutex.RLock()
-	defer topic.mutex.RUnlock()
+	// catchUp sends the retained messages numbered after last and up to
+	// through, or up to the topic's latest if through is 0
+	last := subscription.Cutover
+	catchUp := func(through uint64) bool {
+		mb.mutex.RLock()
+		topic, exists := mb.topics[subscription.Topic]
+		mb.mutex.RUnlock()
+		if !exists {
+			return true
+		}
+		
+		topic.mutex.RLock()
+		if through == 0 {
+			through = topic.Sequence
+		}
+		missed := retainedMessages(topic, last+1, through+1, time.Time{})
+		topic.mutex.RUnlock()
+		
+		if through > last {
+			last = through
+		}
+		return send(missed...)
+	}
 	
-	return map[string]interface{}{
-		"exists":        true,
-		"messageCount":  len(topic.Messages),
-		"consumerCount": len(topic.Consumers),
+	if !send(replay...) {
+		return
 	}
-}
-
+	
+	for message := range subscription.Channel {
+		if message.Sequence <= last {
+			continue // already sent in the replay or a catch-up
+		}
+		if message.Sequence > last+1 && !catchUp(message.Sequence-1) {
+			return
+		}
+		if !send(message) {
+			return
+		}
+		last = message.Sequence
+		
+		if len(subscription.Channel) == 0 && subscription.overflowed.Swap(false) && !catchUp(0) {
+			return
+		}
+	}
+}
+
+// limitCredit turns on flow control for a subscription, starting it with
+// initial credit. It must be called before the subscription is forwarded.
+func (s *Subscription) limitCredit(initial int) {
+	s.creditMutex.Lock()
+	defer s.creditMutex.Unlock()
+	s.limited = true
+	s.credit = initial
+}
+
+// takeCredit spends one credit, waiting for a grant if none is left. It
+// returns false if the subscription ends while waiting. Unlimited
+// subscriptions never wait.
+func (s *Subscription) takeCredit() bool {
+	for {
+		s.creditMutex.Lock()
+		if !s.limited || s.credit > 0 {
+			if s.limited {
+				s.credit--
+			}
+			s.creditMutex.Unlock()
+			return true
+		}
+		s.creditMutex.Unlock()
+		
+		select {
+		case <-s.granted:
+		case <-s.done:
+			return false
+		}
+	}
+}
+
+// grantCredit adds n credits to a limited subscription and wakes its
+// forwarder
+func (s *Subscription) grantCredit(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("credit count must be positive")
+	}
+	
+	s.creditMutex.Lock()
+	if !s.limited {
+		s.creditMutex.Unlock()
+		return fmt.Errorf("subscription to %s has no flow control; subscribe with credit to enable it", s.Topic)
+	}
+	if s.credit > math.MaxInt32-n {
+		s.credit = math.MaxInt32
+	} else {
+		s.credit += n
+	}
+	s.creditMutex.Unlock()
+	
+	select {
+	case s.granted <- struct{}{}:
+	default: // a wake-up is already pending
+	}
+	return nil
+}
+
+// GrantCredit lets a consumer's subscription to a topic send n more messages
+func (mb *MessageBroker) GrantCredit(consumerID, topicName string, n int) error {
+	mb.mutex.RLock()
+	consumer, exists := mb.consumers[consumerID]
+	mb.mutex.RUnlock()
+	if !exists {
+		return fmt.Errorf("consumer %s not found", consumerID)
+	}
+	
+	consumer.mutex.RLock()
+	subscription, exists := consumer.Subscriptions[topicName]
+	consumer.mutex.RUnlock()
+	if !exists {
+		return fmt.Errorf("not subscribed to %s", topicName)
+	}
+	return subscription.grantCredit(n)
+}
+
+// connectConsumer returns the consumer with the given ID, registering it
+// first if it is new. conn is recorded on new consumers so they can be
+// disconnected later.
+func (mb *MessageBroker) connectConsumer(consumerID string, conn *websocket.Conn) *Consumer {
+	mb.mutex.Lock()
+	defer mb.mutex.Unlock()
+	
+	consumer, exists := mb.consumers[consumerID]
+	if !exists {
+		consumer = &Consumer{
+			ID:            consumerID,
+			Subscriptions: make(map[string]*Subscription),
+			WebSocket:     conn,
+			ConnectedAt:   mb.now(),
+		}
+		mb.consumers[consumerID] = consumer
+	}
+	return consumer
+}
+
+// Unsubscribe removes a subscription
+func (mb *MessageBroker) Unsubscribe(consumerID, topicName string) {
+	mb.mutex.RLock()
+	consumer, exists := mb.consumers[consumerID]
+	mb.mutex.RUnlock()
+	
+	if !exists {
+		return
+	}
+	
+	mb.unsubscribe(consumer, topicName)
+}
+
+// unsubscribe detaches a consumer from a topic and then closes its
+// subscription channel. Detaching first matters: enqueue sends while
+// holding the topic lock, so once the consumer is off the topic nothing
+// can send on the channel being closed.
+func (mb *MessageBroker) unsubscribe(consumer *Consumer, topicName string) {
+	mb.mutex.RLock()
+	topic, exists := mb.topics[topicName]
+	mb.mutex.RUnlock()
+	
+	// Remove from topic
+	if exists {
+		topic.mutex.Lock()
+		delete(topic.Consumers, consumer.ID)
+		topic.mutex.Unlock()
+	}
+	
+	consumer.mutex.Lock()
+	if subscription, exists := consumer.Subscriptions[topicName]; exists {
+		close(subscription.Channel)
+		close(subscription.done)
+		delete(consumer.Subscriptions, topicName)
+	}
+	consumer.mutex.Unlock()
+	
+	log.Printf("Consumer %s unsubscribed from topic %s", consumer.ID, topicName)
+}
+
+// DisconnectConsumer unsubscribes a consumer from every topic, closes its
+// WebSocket if it has one, and forgets it. It reports whether the consumer
+// existed. Closing the subscription channels ends the consumer's delivery
+// goroutines, and closing the connection ends its read loop, which calls
+// back in here and finds nothing left to do.
+func (mb *MessageBroker) DisconnectConsumer(consumerID string) bool {
+	mb.mutex.Lock()
+	consumer, exists := mb.consumers[consumerID]
+	delete(mb.consumers, consumerID)
+	mb.mutex.Unlock()
+	
+	if !exists {
+		return false
+	}
+	
+	consumer.mutex.RLock()
+	topics := make([]string, 0, len(consumer.Subscriptions))
+	for topicName := range consumer.Subscriptions {
+		topics = append(topics, topicName)
+	}
+	consumer.mutex.RUnlock()
+	
+	for _, topicName := range topics {
+		mb.unsubscribe(consumer, topicName)
+	}
+	
+	if consumer.WebSocket != nil {
+		consumer.WebSocket.Close()
+	}
+	
+	log.Printf("Consumer %s disconnected", consumerID)
+	return true
+}
+
+// AckConsumed records that a consumer group has processed a message ID and
+// reports whether the group had probably processed it already. The group's
+// filter is created on its first ack, unless CONSUMED_MAX_GROUPS groups
+// already have one, which fails with limit_reached. A true result may be a
+// false positive; false is always right.
+func (mb *MessageBroker) AckConsumed(group, messageID string) (bool, error) {
+	// Held across the check and the add so two acks of the same ID can't
+	// both report it as new
+	mb.consumedMutex.Lock()
+	defer mb.consumedMutex.Unlock()
+	
+	filter, exists := mb.consumed[group]
+	if !exists {
+		if mb.maxGroups > 0 && len(mb.consumed) >= mb.maxGroups {
+			return false, newBrokerError(http.StatusForbidden, CodeLimitReached,
+				"consumer group limit of %d reached; delete an unused group first", mb.maxGroups)
+		}
+		var err error
+		filter, err = NewBloomFilter(mb.consumedExpected, mb.consumedFPRate)
+		if err != nil {
+			return false, err
+		}
+		mb.consumed[group] = filter
+	}
+	
+	if filter.Contains(messageID) {
+		return true, nil
+	}
+	filter.Add(messageID)
+	return false, nil
+}
+
+// ProbablyConsumed reports whether a consumer group has probably acked a
+// message ID. Groups that have never acked have consumed nothing.
+func (mb *MessageBroker) ProbablyConsumed(group, messageID string) bool {
+	filter := mb.consumedFilter(group)
+	return filter != nil && filter.Contains(messageID)
+}
+
+// ForgetConsumed drops a consumer group's processed-ID filter, freeing its
+// memory and its place under CONSUMED_MAX_GROUPS. It reports whether the
+// group had one.
+func (mb *MessageBroker) ForgetConsumed(group string) bool {
+	mb.consumedMutex.Lock()
+	defer mb.consumedMutex.Unlock()
+	
+	_, exists := mb.consumed[group]
+	delete(mb.consumed, group)
+	return exists
+}
+
+// consumedFilter returns a consumer group's processed-ID filter, or nil
+func (mb *MessageBroker) consumedFilter(group string) *BloomFilter {
+	mb.consumedMutex.Lock()
+	defer mb.consumedMutex.Unlock()
+	return mb.consumed[group]
+}
+
+// GetTopicStats returns statistics for a topic
+func (mb *MessageBroker) GetTopicStats(topicName string) map[string]interface{} {
+	mb.mutex.RLock()
+	topic, exists := mb.topics[topicName]
+	mb.mutex.RUnlock()
+	
+	if !exists {
+		return map[string]interface{}{
+			"exists": false,
+		}
+	}
+	
+	topic.mutex.RLock()
+	defer topic.mutex.RUnlock()
+	
+	return map[string]interface{}{
+		"exists":         true,
+		"messageCount":   len(topic.Messages),
+		"consumerCount":  len(topic.Consumers),
+		"scheduledCount": topic.Scheduled,
+		"lastSequence":   topic.Sequence,
+	}
+}
+
 // cleanupRoutine periodically cleans up old messages
 func (mb *MessageBroker) cleanupRoutine() {
 	ticker := time.NewTicker(time.Hour)
@@ -339,205 +1270,1183 @@ func (mb *MessageBroker) cleanupRoutine() {
 	}
 }
 
-// cleanupOldMessages removes messages older than retention period
-func (mb *MessageBroker) cleanupOldMessages() {
-	cutoff := time.Now().Add(-time.Duration(mb.retentionHours) * time.Hour)
+// cleanupOldMessages applies every retention policy to every topic. A
+// delayed message's age counts from its delivery, and scheduled messages are
+// not in topic.Messages yet, so none expires before consumers can see it.
+func (mb *MessageBroker) cleanupOldMessages() {
+	now := mb.now()
+	
+	mb.mutex.RLock()
+	topics := make([]*Topic, 0, len(mb.topics))
+	for _, topic := range mb.topics {
+		topics = append(topics, topic)
+	}
+	mb.mutex.RUnlock()
+	
+	for _, topic := range topics {
+		topic.mutex.Lock()
+		for _, policy := range mb.retention {
+			if removed := policy.Retain(topic, now); removed > 0 {
+				log.Printf("Cleaned up %d messages from topic %s (%s retention)", removed, topic.Name, policy)
+			}
+		}
+		mb.queueSizes.WithLabelValues(topic.Name).Set(float64(len(topic.Messages)))
+		topic.mutex.Unlock()
+	}
+}
+
+// availableAt returns when the message reached its topic: its delivery
+// time if it was delayed, otherwise its publish time
+func (m *Message) availableAt() time.Time {
+	if !m.DeliverAt.IsZero() {
+		return m.DeliverAt
+	}
+	return m.Timestamp
+}
+
+// priority returns the message's X-Priority header as an integer
+func (m *Message) priority() int {
+	priority, _ := strconv.Atoi(m.Headers[priorityHeader])
+	return priority
+}
+
+// parseDelay reads the X-Delay header, in milliseconds
+func parseDelay(r *http.Request) (time.Duration, error) {
+	delayStr := r.Header.Get("X-Delay")
+	if delayStr == "" {
+		return 0, nil
+	}
+	ms, err := strconv.ParseInt(delayStr, 10, 64)
+	if err != nil || ms < 0 {
+		return 0, invalidRequest("X-Delay must be a non-negative number of milliseconds")
+	}
+	return millisToDelay(ms)
+}
+
+// millisToDelay converts a delay in milliseconds to a duration, rejecting
+// one too long for a time.Duration rather than letting it wrap negative
+func millisToDelay(ms int64) (time.Duration, error) {
+	if ms > int64(math.MaxInt64/time.Millisecond) {
+		return 0, invalidRequest("delay of %dms is too long", ms)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// parseOrigins splits a comma-separated ALLOWED_ORIGINS value into
+// patterns such as "https://app.example.com", "https://*.example.com" or "*"
+func parseOrigins(value string) []string {
+	var origins []string
+	for _, origin := range strings.Split(value, ",") {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		if origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// matchOrigin reports whether origin matches pattern, where the first "*"
+// in pattern matches any run of characters
+func matchOrigin(pattern, origin string) bool {
+	star := strings.Index(pattern, "*")
+	if star < 0 {
+		return pattern == origin
+	}
+	prefix, suffix := pattern[:star], pattern[star+1:]
+	return len(origin) >= len(prefix)+len(suffix) &&
+		strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix)
+}
+
+// originAllowed reports whether a browser on origin may use the broker.
+// Requests without an Origin header don't come from a browser and are
+// always allowed.
+func (mb *MessageBroker) originAllowed(origin string) bool {
+	if origin == "" {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, pattern := range mb.allowedOrigins {
+		if matchOrigin(pattern, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOrigin is the WebSocket upgrader's origin check
+func (mb *MessageBroker) checkOrigin(r *http.Request) bool {
+	return mb.originAllowed(r.Header.Get("Origin"))
+}
+
+// corsMiddleware adds CORS headers for allowed origins and answers
+// preflight requests itself, since the router would reject OPTIONS on
+// routes registered for other methods. Requests from other origins get 403.
+func (mb *MessageBroker) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		
+		w.Header().Add("Vary", "Origin")
+		if !mb.originAllowed(origin) {
+			writeError(w, newBrokerError(http.StatusForbidden, CodeForbidden, "origin %s not allowed", origin))
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+			}
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HTTP Handlers
+
+func (mb *MessageBroker) publishHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	topic := vars["topic"]
+	
+	delay, err := parseDelay(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	
+	var data interface{}
+	if err := mb.decodeMessageBody(w, r, &data); err != nil {
+		writeError(w, err)
+		return
+	}
+	
+	headers := make(map[string]string)
+	for key, values := range r.Header {
+		if len(values) > 0 {
+			headers[key] = values[0]
+		}
+	}
+	
+	message, err := mb.PublishDelayed(topic, data, headers, delay)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(publishReceipt(message))
+}
+
+// publishReceipt describes a published message in a publish response
+func publishReceipt(message *Message) map[string]interface{} {
+	receipt := map[string]interface{}{
+		"messageId": message.ID,
+		"topic":     message.Topic,
+		"timestamp": message.Timestamp,
+	}
+	// A delayed message is numbered by the scheduler when it is delivered,
+	// so it has no sequence yet
+	if !message.DeliverAt.IsZero() {
+		receipt["deliverAt"] = message.DeliverAt
+	} else {
+		receipt["sequence"] = message.Sequence
+	}
+	return receipt
+}
+
+func (mb *MessageBroker) publishBatchHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	topic := vars["topic"]
+	
+	delay, err := parseDelay(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	
+	var dataArray []interface{}
+	if err := json.NewDecoder(limitBody(w, r, mb.maxBatchSize)).Decode(&dataArray); err != nil {
+		if bodyTooLarge(err) {
+			writeError(w, mb.tooLarge("batch body is over the %d byte limit", mb.maxBatchSize))
+			return
+		}
+		writeError(w, invalidRequest("invalid JSON array: %v", err))
+		return
+	}
+	
+	headers := make(map[string]string)
+	for key, values := range r.Header {
+		if len(values) > 0 {
+			headers[key] = values[0]
+		}
+	}
+	
+	var messages []map[string]interface{}
+	for _, data := range dataArray {
+		message, err := mb.PublishDelayed(topic, data, headers, delay)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		
+		messages = append(messages, publishReceipt(message))
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"messages": messages,
+		"count":    len(messages),
+	})
+}
+
+func (mb *MessageBroker) consumeHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	topic := vars["topic"]
+	
+	message, err := mb.ConsumeMessage(topic)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(message)
+}
+
+// consumeFlushInterval is how many messages a batch consume writes between
+// flushes
+const consumeFlushInterval = 100
+
+// consumeBatchHandler streams up to ?limit= messages (default 10) as NDJSON,
+// one message per line, stopping early when the topic runs dry. Each message
+// is consumed only once the previous one is written, so memory stays flat
+// however large the batch, and a client that disconnects stops the batch
+// rather than draining the topic into a closed connection. Messages already
+// written but not yet delivered when the client goes are lost, as with a
+// single consume whose response never arrives.
+func (mb *MessageBroker) consumeBatchHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	topic := vars["topic"]
+	
+	limitStr := r.URL.Query().Get("limit")
+	limit := 10 // default
+	if limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+	
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	
+	count := 0
+	for count < limit && r.Context().Err() == nil {
+		message, err := mb.ConsumeMessage(topic)
+		if err != nil {
+			break // No more messages
+		}
+		if err := encoder.Encode(message); err != nil {
+			log.Printf("Batch consume from %s aborted after %d messages: %v", topic, count, err)
+			return
+		}
+		count++
+		if flusher != nil && count%consumeFlushInterval == 0 {
+			flusher.Flush()
+		}
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// peekHandler returns the message at ?offset=N (default 0, the head)
+// without consuming it
+func (mb *MessageBroker) peekHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	topic := vars["topic"]
+	
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	
+	messages, _, err := mb.PeekMessages(topic, offset, 1)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(messages[0])
+}
+
+// peekRangeHandler returns up to ?count= messages (default 10) from
+// ?start= (default 0) without consuming them
+func (mb *MessageBroker) peekRangeHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	topic := vars["topic"]
+	
+	start, err := queryInt(r, "start", 0)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	count, err := queryInt(r, "count", 10)
+	if err != nil || count < 1 || count > maxPeekCount {
+		writeError(w, invalidRequest("count must be between 1 and %d", maxPeekCount))
+		return
+	}
+	
+	messages, total, err := mb.PeekMessages(topic, start, count)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"messages": messages,
+		"start":    start,
+		"count":    len(messages),
+		"total":    total,
+	})
+}
+
+// queryInt parses a non-negative integer query parameter, returning
+// defaultValue if it is absent
+func queryInt(r *http.Request, name string, defaultValue int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, invalidRequest("%s must be a non-negative integer", name)
+	}
+	return n, nil
+}
+
+// Request-reply headers. Keys are in canonical HTTP form so that a reply
+// published over HTTP with a Correlation-Id header matches.
+const (
+	replyToHeader       = "Reply-To"
+	correlationIDHeader = "Correlation-Id"
+
+	// replyTopicPrefix starts every reply topic's name; see publishTopic
+	replyTopicPrefix = "rpc.reply."
+
+	defaultRPCTimeout = 30 * time.Second
+	maxRPCTimeout     = 5 * time.Minute
+)
+
+// rpcHandler publishes the request body to a topic with a Reply-To topic
+// and Correlation-Id header, then waits for a consumer to publish a reply
+// with the same Correlation-Id to the Reply-To topic. The reply message is
+// returned to the caller. If none arrives within the timeout query
+// parameter, the caller gets 504. The reply topic exists only for the
+// duration of the call.
+func (mb *MessageBroker) rpcHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	topic := vars["topic"]
+
+	timeout := defaultRPCTimeout
+	if timeoutStr := r.URL.Query().Get("timeout"); timeoutStr != "" {
+		t, err := time.ParseDuration(timeoutStr)
+		if err != nil || t <= 0 || t > maxRPCTimeout {
+			writeError(w, invalidRequest("timeout must be a duration up to %v", maxRPCTimeout))
+			return
+		}
+		timeout = t
+	}
+
+	var data interface{}
+	if err := mb.decodeMessageBody(w, r, &data); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	headers := make(map[string]string)
+	for key, values := range r.Header {
+		if len(values) > 0 {
+			headers[key] = values[0]
+		}
+	}
+
+	correlationID := uuid.New().String()
+	replyTo := replyTopicPrefix + correlationID
+	headers[replyToHeader] = replyTo
+	headers[correlationIDHeader] = correlationID
+
+	// Subscribe before publishing so a fast reply can't be missed
+	consumerID := "rpc-" + correlationID
+	subscription := mb.Subscribe(consumerID, replyTo)
+	defer mb.removeReplyTopic(consumerID, replyTo)
+
+	if _, err := mb.PublishMessage(topic, data, headers); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case reply, ok := <-subscription.Channel:
+			if !ok {
+				// The reply consumer was disconnected by an operator
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"code":          CodeUnavailable,
+					"message":       "reply consumer disconnected",
+					"correlationId": correlationID,
+				})
+				return
+			}
+			if reply.Headers[correlationIDHeader] != correlationID {
+				continue // not an answer to this request
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(reply)
+			return
+
+		case <-timer.C:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusGatewayTimeout)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"code":          CodeTimeout,
+				"message":       "no reply before timeout",
+				"correlationId": correlationID,
+				"timeout":       timeout.String(),
+			})
+			return
+
+		case <-r.Context().Done():
+			return // caller went away
+		}
+	}
+}
+
+// removeReplyTopic deletes an RPC call's reply topic and its consumer. The
+// consumer is detached from the topic before anything else, so a publish
+// racing with the cleanup never sends on a removed subscription. A reply
+// that arrives later is rejected; see publishTopic.
+func (mb *MessageBroker) removeReplyTopic(consumerID, topicName string) {
+	mb.mutex.Lock()
+	defer mb.mutex.Unlock()
+
+	if topic, exists := mb.topics[topicName]; exists {
+		topic.mutex.Lock()
+		delete(topic.Consumers, consumerID)
+		topic.mutex.Unlock()
+		delete(mb.topics, topicName)
+	}
+	delete(mb.consumers, consumerID)
+	mb.queueSizes.DeleteLabelValues(topicName)
+	mb.queueLimits.DeleteLabelValues(topicName)
+}
+
+func (mb *MessageBroker) topicsHandler(w http.ResponseWriter, r *http.Request) {
+	mb.mutex.RLock()
+	defer mb.mutex.RUnlock()
+	
+	topics := make([]map[string]interface{}, 0, len(mb.topics))
+	for name, topic := range mb.topics {
+		topic.mutex.RLock()
+		topics = append(topics, map[string]interface{}{
+			"name":          name,
+			"messageCount":  len(topic.Messages),
+			"consumerCount": len(topic.Consumers),
+		})
+		topic.mutex.RUnlock()
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"topics": topics,
+		"count":  len(topics),
+	})
+}
+
+// createTopicHandler creates a topic from a JSON config:
+// {"name": "orders", "maxQueueSize": 500, "retentionHours": 1,
+// "ordering": "priority"}
+func (mb *MessageBroker) createTopicHandler(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Name string `json:"name"`
+		TopicConfig
+	}
+	if err := decodeConfigBody(w, r, &request); err != nil {
+		writeError(w, err)
+		return
+	}
+	
+	topic, err := mb.CreateTopic(request.Name, request.TopicConfig)
+	if err == errTopicExists {
+		writeError(w, newBrokerError(http.StatusConflict, CodeConflict, "topic %s already exists", request.Name))
+		return
+	}
+	if err != nil {
+		writeError(w, invalidRequest("%v", err))
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"name":   topic.Name,
+		"config": topic.Config,
+	})
+}
+
+// topicConfigHandler returns the configuration a topic runs with, whether
+// it was set through POST /topics or taken from the broker defaults
+func (mb *MessageBroker) topicConfigHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["topic"]
+	
+	mb.mutex.RLock()
+	topic, exists := mb.topics[name]
+	mb.mutex.RUnlock()
+	
+	if !exists {
+		writeError(w, notFound("topic %s not found", name))
+		return
+	}
+	
+	topic.mutex.RLock()
+	config := topic.Config
+	topic.mutex.RUnlock()
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"name":   topic.Name,
+		"config": config,
+	})
+}
+
+// updateTopicConfigHandler changes the settings of an existing topic that
+// may change after creation, which today is only the queue limit:
+// {"maxQueueSize": 500}. A null or zero maxQueueSize restores the broker
+// default.
+func (mb *MessageBroker) updateTopicConfigHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["topic"]
+	
+	var request struct {
+		MaxQueueSize   *int    `json:"maxQueueSize"`
+		RetentionHours *int    `json:"retentionHours"`
+		Ordering       *string `json:"ordering"`
+	}
+	if err := decodeConfigBody(w, r, &request); err != nil {
+		writeError(w, err)
+		return
+	}
+	if request.RetentionHours != nil || request.Ordering != nil {
+		writeError(w, invalidRequest("only maxQueueSize can be changed after a topic is created"))
+		return
+	}
+	
+	maxQueueSize := 0
+	if request.MaxQueueSize != nil {
+		maxQueueSize = *request.MaxQueueSize
+	}
+	config, err := mb.SetMaxQueueSize(name, maxQueueSize)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"name":   name,
+		"config": config,
+	})
+}
+
+// mirrorsHandler lists the topics a topic's messages are copied to
+func (mb *MessageBroker) mirrorsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["topic"]
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"topic":   name,
+		"mirrors": mb.Mirrors(name),
+	})
+}
+
+// addMirrorHandler starts copying every message published to a topic into
+// the dest topic
+func (mb *MessageBroker) addMirrorHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["topic"]
+	
+	if err := mb.Mirror(name, vars["dest"]); err != nil {
+		writeError(w, err)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"topic":   name,
+		"mirrors": mb.Mirrors(name),
+	})
+}
+
+// removeMirrorHandler stops copying a topic's messages into the dest topic
+func (mb *MessageBroker) removeMirrorHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["topic"]
+	
+	if err := mb.Unmirror(name, vars["dest"]); err != nil {
+		writeError(w, err)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"topic":   name,
+		"mirrors": mb.Mirrors(name),
+	})
+}
+
+// topicSequenceHandler returns the sequence number of the last message
+// enqueued on a topic. A consumer that has seen every number up to it has
+// missed nothing; any number it hasn't seen was consumed by another client
+// or removed by retention while it was away.
+func (mb *MessageBroker) topicSequenceHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["topic"]
+	
+	mb.mutex.RLock()
+	topic, exists := mb.topics[name]
+	mb.mutex.RUnlock()
+	
+	if !exists {
+		writeError(w, notFound("topic %s not found", name))
+		return
+	}
+	
+	topic.mutex.RLock()
+	sequence := topic.Sequence
+	topic.mutex.RUnlock()
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"topic":    name,
+		"sequence": sequence,
+	})
+}
+
+// consumersHandler lists the active consumers with their topics and how
+// long they have been connected
+func (mb *MessageBroker) consumersHandler(w http.ResponseWriter, r *http.Request) {
+	now := mb.now()
 	
 	mb.mutex.RLock()
-	topics := make([]*Topic, 0, len(mb.topics))
-	for _, topic := range mb.topics {
-		topics = append(topics, topic)
-	}
-	mb.mutex.RUnlock()
+	defer mb.mutex.RUnlock()
 	
-	for _, topic := range topics {
-		topic.mutex.Lock()
-		
-		// Find first message to keep
-		keepIndex := 0
-		for i, message := range topic.Messages {
-			if message.Timestamp.After(cutoff) {
-				keepIndex = i
-				break
-			}
-		}
-		
-		// Remove old messages
-		if keepIndex > 0 {
-			topic.Messages = topic.Messages[keepIndex:]
-			mb.queueSizes.WithLabelValues(topic.Name).Set(float64(len(topic.Messages)))
-			log.Printf("Cleaned up %d old messages from topic %s", keepIndex, topic.Name)
+	consumers := make([]map[string]interface{}, 0, len(mb.consumers))
+	for id, consumer := range mb.consumers {
+		consumer.mutex.RLock()
+		topics := make([]string, 0, len(consumer.Subscriptions))
+		for topicName := range consumer.Subscriptions {
+			topics = append(topics, topicName)
 		}
+		consumer.mutex.RUnlock()
+		sort.Strings(topics)
 		
-		topic.mutex.Unlock()
+		consumers = append(consumers, map[string]interface{}{
+			"id":            id,
+			"topics":        topics,
+			"websocket":     consumer.WebSocket != nil,
+			"connectedAt":   consumer.ConnectedAt,
+			"connectionAge": now.Sub(consumer.ConnectedAt).Round(time.Second).String(),
+		})
 	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"consumers": consumers,
+		"count":     len(consumers),
+	})
 }
 
-// HTTP Handlers
-
-func (mb *MessageBroker) publishHandler(w http.ResponseWriter, r *http.Request) {
+// disconnectConsumerHandler force-disconnects a consumer
+func (mb *MessageBroker) disconnectConsumerHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	topic := vars["topic"]
+	consumerID := vars["consumerId"]
 	
-	var data interface{}
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	if !mb.DisconnectConsumer(consumerID) {
+		writeError(w, notFound("consumer %s not found", consumerID))
 		return
 	}
 	
-	headers := make(map[string]string)
-	for key, values := range r.Header {
-		if len(values) > 0 {
-			headers[key] = values[0]
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"consumerId":   consumerID,
+		"disconnected": true,
+	})
+}
+
+// ackConsumedHandler records a message ID as processed by a consumer group
+// and reports whether it was probably a duplicate
+func (mb *MessageBroker) ackConsumedHandler(w http.ResponseWriter, r *http.Request) {
+	group := mux.Vars(r)["group"]
+	messageID := r.URL.Query().Get("id")
+	if messageID == "" {
+		writeError(w, invalidRequest("id is required"))
+		return
 	}
 	
-	message, err := mb.PublishMessage(topic, data, headers)
+	duplicate, err := mb.AckConsumed(group, messageID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"messageId": message.ID,
-		"topic":     message.Topic,
-		"timestamp": message.Timestamp,
+		"group":     group,
+		"id":        messageID,
+		"duplicate": duplicate,
 	})
 }
 
-func (mb *MessageBroker) publishBatchHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	topic := vars["topic"]
-	
-	var dataArray []interface{}
-	if err := json.NewDecoder(r.Body).Decode(&dataArray); err != nil {
-		http.Error(w, "Invalid JSON array", http.StatusBadRequest)
+// forgetConsumedHandler deletes a consumer group's processed-ID filter
+func (mb *MessageBroker) forgetConsumedHandler(w http.ResponseWriter, r *http.Request) {
+	group := mux.Vars(r)["group"]
+	if !mb.ForgetConsumed(group) {
+		writeError(w, notFound("consumer group %s has no processed IDs", group))
 		return
 	}
 	
-	headers := make(map[string]string)
-	for key, values := range r.Header {
-		if len(values) > 0 {
-			headers[key] = values[0]
-		}
-	}
-	
-	var messages []map[string]interface{}
-	for _, data := range dataArray {
-		message, err := mb.PublishMessage(topic, data, headers)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		
-		messages = append(messages, map[string]interface{}{
-			"messageId": message.ID,
-			"topic":     message.Topic,
-			"timestamp": message.Timestamp,
-		})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"group":   group,
+		"deleted": true,
+	})
+}
+
+// consumedContainsHandler reports whether a consumer group has probably
+// acked a message ID, without recording it
+func (mb *MessageBroker) consumedContainsHandler(w http.ResponseWriter, r *http.Request) {
+	group := mux.Vars(r)["group"]
+	messageID := r.URL.Query().Get("id")
+	if messageID == "" {
+		writeError(w, invalidRequest("id is required"))
+		return
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"messages": messages,
-		"count":    len(messages),
+		"group":    group,
+		"id":       messageID,
+		"contains": mb.ProbablyConsumed(group, messageID),
 	})
 }
 
-func (mb *MessageBroker) consumeHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	topic := vars["topic"]
-	
-	message, err := mb.ConsumeMessage(topic)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+// consumedStatsHandler returns the size and current false positive rate of
+// a consumer group's processed-ID filter
+func (mb *MessageBroker) consumedStatsHandler(w http.ResponseWriter, r *http.Request) {
+	group := mux.Vars(r)["group"]
+	
+	filter := mb.consumedFilter(group)
+	if filter == nil {
+		writeError(w, notFound("consumer group %s has not acked any messages", group))
 		return
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(message)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"group":                    group,
+		"acked":                    filter.Size(),
+		"expectedElements":         filter.expectedElements,
+		"falsePositiveRate":        filter.falsePositiveRate,
+		"currentFalsePositiveRate": filter.CurrentFalsePositiveRate(),
+		"bitArraySize":             filter.bitArraySize,
+		"hashFunctions":            filter.numHashFunctions,
+	})
 }
 
-func (mb *MessageBroker) consumeBatchHandler(w http.ResponseWriter, r *http.Request) {
+func (mb *MessageBroker) topicStatsHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	topic := vars["topic"]
 	
-	limitStr := r.URL.Query().Get("limit")
-	limit := 10 // default
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
+	stats := mb.GetTopicStats(topic)
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// adminAuth guards the /admin endpoints with the ADMIN_TOKEN bearer token.
+// They read and replace every message on the broker and disconnect
+// consumers, so they stay disabled until a token is configured.
+func (mb *MessageBroker) adminAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if mb.adminToken == "" {
+			writeError(w, newBrokerError(http.StatusForbidden, CodeForbidden, "admin endpoints are disabled; set ADMIN_TOKEN to enable them"))
+			return
 		}
+		
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(mb.adminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeError(w, newBrokerError(http.StatusUnauthorized, CodeUnauthorized, "invalid or missing admin token"))
+			return
+		}
+		
+		next.ServeHTTP(w, r)
+	})
+}
+
+// exportRecord is one line of an export: a topic, followed by a record for
+// each of its messages
+type exportRecord struct {
+	Type     string       `json:"type"` // "topic" or "message"
+	Topic    string       `json:"topic"`
+	Config   *TopicConfig `json:"config,omitempty"`
+	Sequence uint64       `json:"sequence,omitempty"`
+	Message  *Message     `json:"message,omitempty"`
+}
+
+// exportHandler streams every topic as NDJSON: a topic record with its
+// config and sequence, its queued messages in queue order, then its delayed
+// messages by delivery time. A delayed message has no sequence yet, which
+// is how import tells it apart.
+//
+// Each topic is copied under its lock, only as a slice of pointers, and
+// encoded after the lock is released. Publishers never wait on a slow
+// download, and memory grows with one topic's queue length rather than with
+// the size of the dump. Topics are copied one after another, so the export
+// is consistent per topic, not across topics.
+func (mb *MessageBroker) exportHandler(w http.ResponseWriter, r *http.Request) {
+	mb.mutex.RLock()
+	topics := make([]*Topic, 0, len(mb.topics))
+	for _, topic := range mb.topics {
+		topics = append(topics, topic)
 	}
+	mb.mutex.RUnlock()
+	sort.Slice(topics, func(i, j int) bool {
+		return topics[i].Name < topics[j].Name
+	})
 	
-	var messages []*Message
-	for i := 0; i < limit; i++ {
-		message, err := mb.ConsumeMessage(topic)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	
+	messageCount := 0
+	for _, topic := range topics {
+		topic.mutex.RLock()
+		config, sequence := topic.Config, topic.Sequence
+		messages := make([]*Message, len(topic.Messages))
+		copy(messages, topic.Messages)
+		
+		// Delayed messages live in the broker-wide schedule
+		mb.scheduleMutex.Lock()
+		var scheduled []*Message
+		for _, item := range mb.scheduled {
+			if item.topic == topic {
+				scheduled = append(scheduled, item.message)
+			}
+		}
+		mb.scheduleMutex.Unlock()
+		topic.mutex.RUnlock()
+		
+		sort.Slice(scheduled, func(i, j int) bool {
+			return scheduled[i].DeliverAt.Before(scheduled[j].DeliverAt)
+		})
+		
+		err := encoder.Encode(exportRecord{Type: "topic", Topic: topic.Name, Config: &config, Sequence: sequence})
+		for _, message := range append(messages, scheduled...) {
+			if err != nil {
+				break
+			}
+			err = encoder.Encode(exportRecord{Type: "message", Topic: topic.Name, Message: message})
+			messageCount++
+		}
 		if err != nil {
-			break // No more messages
+			log.Printf("Export aborted: %v", err)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
 		}
-		messages = append(messages, message)
 	}
 	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"messages": messages,
-		"count":    len(messages),
-	})
+	log.Printf("Exported %d topics and %d messages", len(topics), messageCount)
 }
 
-func (mb *MessageBroker) topicsHandler(w http.ResponseWriter, r *http.Request) {
+// importHandler restores an export into a broker with no topics. Records
+// are decoded and applied one at a time, so the upload is never held in
+// memory. Topics keep their config and sequence, so sequence numbers carry
+// on where the exporting broker left off, and delayed messages are
+// scheduled again. An invalid record stops the import with 400, and an
+// upload over MAX_IMPORT_SIZE with 413, leaving the topics restored before
+// it.
+func (mb *MessageBroker) importHandler(w http.ResponseWriter, r *http.Request) {
 	mb.mutex.RLock()
-	defer mb.mutex.RUnlock()
+	empty := len(mb.topics) == 0
+	mb.mutex.RUnlock()
+	if !empty {
+		writeError(w, newBrokerError(http.StatusConflict, CodeConflict, "broker already has topics; import into an empty broker"))
+		return
+	}
 	
-	topics := make([]map[string]interface{}, 0, len(mb.topics))
-	for name, topic := range mb.topics {
-		topic.mutex.RLock()
-		topics = append(topics, map[string]interface{}{
-			"name":          name,
-			"messageCount":  len(topic.Messages),
-			"consumerCount": len(topic.Consumers),
-		})
-		topic.mutex.RUnlock()
+	decoder := json.NewDecoder(limitBody(w, r, mb.maxImportSize))
+	var topic *Topic
+	topicCount, messageCount, scheduledCount := 0, 0, 0
+	for line := 1; ; line++ {
+		var record exportRecord
+		err := decoder.Decode(&record)
+		if err == io.EOF {
+			break
+		}
+		if bodyTooLarge(err) {
+			writeError(w, newBrokerError(http.StatusRequestEntityTooLarge, CodeMessageTooLarge, "import is over the %d byte limit at record %d", mb.maxImportSize, line))
+			return
+		}
+		if err != nil {
+			writeError(w, invalidRequest("record %d: %v", line, err))
+			return
+		}
+		
+		switch record.Type {
+		case "topic":
+			var config TopicConfig
+			if record.Config != nil {
+				config = *record.Config
+			}
+			topic, err = mb.CreateTopic(record.Topic, config)
+			if err == errTopicExists {
+				writeError(w, newBrokerError(http.StatusConflict, CodeConflict, "record %d: topic %s already exists", line, record.Topic))
+				return
+			}
+			if err != nil {
+				writeError(w, invalidRequest("record %d: %v", line, err))
+				return
+			}
+			topic.mutex.Lock()
+			topic.Sequence = record.Sequence
+			topic.mutex.Unlock()
+			topicCount++
+			
+		case "message":
+			if topic == nil || record.Topic != topic.Name || record.Message == nil {
+				writeError(w, invalidRequest("record %d: message must follow its topic record", line))
+				return
+			}
+			record.Message.Topic = topic.Name
+			if mb.restoreMessage(topic, record.Message) {
+				scheduledCount++
+			} else {
+				messageCount++
+			}
+			
+		default:
+			writeError(w, invalidRequest("record %d: unknown record type %q", line, record.Type))
+			return
+		}
 	}
 	
+	log.Printf("Imported %d topics, %d messages and %d delayed messages", topicCount, messageCount, scheduledCount)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"topics": topics,
-		"count":  len(topics),
+		"topics":    topicCount,
+		"messages":  messageCount,
+		"scheduled": scheduledCount,
 	})
 }
 
-func (mb *MessageBroker) topicStatsHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	topic := vars["topic"]
+// restoreMessage appends an exported message to its topic as it was, or
+// schedules it if it was still waiting for delivery. It reports whether the
+// message was scheduled.
+func (mb *MessageBroker) restoreMessage(topic *Topic, message *Message) bool {
+	topic.mutex.Lock()
 	
-	stats := mb.GetTopicStats(topic)
+	if message.Sequence == 0 && !message.DeliverAt.IsZero() {
+		topic.Scheduled++
+		topic.mutex.Unlock()
+		mb.schedule(topic, message)
+		return true
+	}
 	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(stats)
+	topic.Messages = append(topic.Messages, message)
+	topic.Bytes += message.byteSize()
+	if message.Sequence > topic.Sequence {
+		topic.Sequence = message.Sequence
+	}
+	mb.queueSizes.WithLabelValues(topic.Name).Set(float64(len(topic.Messages)))
+	topic.mutex.Unlock()
+	return false
 }
 
+// healthHandler reports the broker "degraded" while the publish circuit
+// breaker is not closed. It still answers 200: consumers and subscribers
+// are served either way.
 func (mb *MessageBroker) healthHandler(w http.ResponseWriter, r *http.Request) {
+	breaker := mb.breaker.Stats()
+	status := "healthy"
+	if breaker["state"] != BreakerClosed.String() {
+		status = "degraded"
+	}
+	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":    "healthy",
-		"timestamp": time.Now(),
-		"version":   "1.0.0",
+		"status":      status,
+		"breaker":     breaker,
+		"timestamp":   time.Now(),
+		"version":     "1.0.0",
+		"connections": mb.connectionStats(),
 	})
 }
 
+// Errors returned by acquireConnection
+var (
+	errTooManyConnections      = fmt.Errorf("too many WebSocket connections")
+	errTooManyConnectionsForIP = fmt.Errorf("too many WebSocket connections from this address")
+)
+
+// connectionLimit returns how many WebSocket connections are allowed right
+// now. During slow start the limit grows linearly from 1 to maxConnections,
+// so clients reconnecting all at once after a restart are let back in
+// gradually. Zero means unlimited.
+func (mb *MessageBroker) connectionLimit() int {
+	if mb.maxConnections <= 0 || mb.slowStart <= 0 {
+		return mb.maxConnections
+	}
+	
+	elapsed := mb.now().Sub(mb.startedAt)
+	if elapsed >= mb.slowStart {
+		return mb.maxConnections
+	}
+	limit := int(float64(mb.maxConnections) * float64(elapsed) / float64(mb.slowStart))
+	if limit < 1 {
+		limit = 1
+	}
+	return limit
+}
+
+// acquireConnection reserves a WebSocket connection slot for a client
+// address, or returns an error if the broker or the address is at its limit.
+// Every successful call must be paired with releaseConnection.
+func (mb *MessageBroker) acquireConnection(ip string) error {
+	mb.connMutex.Lock()
+	defer mb.connMutex.Unlock()
+	
+	if limit := mb.connectionLimit(); limit > 0 && mb.connections >= limit {
+		mb.rejectedConns.WithLabelValues("total").Inc()
+		return errTooManyConnections
+	}
+	if mb.maxConnsPerIP > 0 && mb.connectionsByIP[ip] >= mb.maxConnsPerIP {
+		mb.rejectedConns.WithLabelValues("per_ip").Inc()
+		return errTooManyConnectionsForIP
+	}
+	
+	mb.connections++
+	mb.connectionsByIP[ip]++
+	return nil
+}
+
+// releaseConnection frees a slot taken by acquireConnection
+func (mb *MessageBroker) releaseConnection(ip string) {
+	mb.connMutex.Lock()
+	defer mb.connMutex.Unlock()
+	
+	mb.connections--
+	if mb.connectionsByIP[ip]--; mb.connectionsByIP[ip] <= 0 {
+		delete(mb.connectionsByIP, ip)
+	}
+}
+
+// connectionStats reports WebSocket connections against their limits
+func (mb *MessageBroker) connectionStats() map[string]interface{} {
+	mb.connMutex.Lock()
+	defer mb.connMutex.Unlock()
+	
+	return map[string]interface{}{
+		"active":          mb.connections,
+		"limit":           mb.connectionLimit(),
+		"max":             mb.maxConnections,
+		"maxPerIP":        mb.maxConnsPerIP,
+		"distinctClients": len(mb.connectionsByIP),
+	}
+}
+
+// clientIP returns the address a request came from. X-Forwarded-For is not
+// trusted, so behind a proxy every client shares the proxy's address.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// wsWriter serializes writes to a WebSocket, which allows one writer at a
+// time. The read loop's replies and every subscription's forwarder share it.
+type wsWriter struct {
+	mutex sync.Mutex
+	conn  *websocket.Conn
+}
+
+// WriteJSON writes v as one JSON message
+func (w *wsWriter) WriteJSON(v interface{}) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.conn.WriteJSON(v)
+}
+
+// wsFrameOverhead is how far a WebSocket frame may exceed MAX_MESSAGE_SIZE,
+// leaving room for its type, topic, headers, and other fields around the
+// data
+const wsFrameOverhead = 64 << 10
+
 // WebSocket handler
 func (mb *MessageBroker) websocketHandler(w http.ResponseWriter, r *http.Request) {
+	// Check the limits before upgrading, so a rejected client gets a plain
+	// HTTP error and never holds a connection
+	ip := clientIP(r)
+	if err := mb.acquireConnection(ip); err != nil {
+		brokerErr := &BrokerError{
+			Code:       CodeUnavailable,
+			Message:    err.Error(),
+			Status:     http.StatusServiceUnavailable,
+			RetryAfter: time.Second,
+		}
+		if err == errTooManyConnectionsForIP {
+			brokerErr.Code, brokerErr.Status = CodeRateLimited, http.StatusTooManyRequests
+		}
+		writeError(w, brokerErr)
+		return
+	}
+	defer mb.releaseConnection(ip)
+	
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade failed: %v", err)
 		return
 	}
 	defer conn.Close()
+	if mb.maxMessageSize > 0 {
+		conn.SetReadLimit(int64(mb.maxMessageSize) + wsFrameOverhead)
+	}
 	
+	writer := &wsWriter{conn: conn}
 	consumerID := uuid.New().String()
+	mb.connectConsumer(consumerID, conn)
 	mb.activeConnections.Inc()
 	defer mb.activeConnections.Dec()
 	
@@ -547,6 +2456,13 @@ func (mb *MessageBroker) websocketHandler(w http.ResponseWriter, r *http.Request
 	for {
 		var wsMsg WebSocketMessage
 		err := conn.ReadJSON(&wsMsg)
+		if err == websocket.ErrReadLimit {
+			// The connection is closed with 1009 (message too big): the
+			// rest of the frame was never read, so it can't carry on
+			mb.oversizeRejected.Inc()
+			log.Printf("WebSocket frame from %s over the size limit, closing", consumerID)
+			break
+		}
 		if err != nil {
 			log.Printf("WebSocket read error: %v", err)
 			break
@@ -554,14 +2470,22 @@ func (mb *MessageBroker) websocketHandler(w http.ResponseWriter, r *http.Request
 		
 		switch wsMsg.Type {
 		case "publish":
-			message, err := mb.PublishMessage(wsMsg.Topic, wsMsg.Data, nil)
+			if wsMsg.Delay < 0 {
+				wsMsg.Delay = 0
+			}
+			var message *Message
+			delay, err := millisToDelay(wsMsg.Delay)
+			if err == nil {
+				message, err = mb.PublishDelayed(wsMsg.Topic, wsMsg.Data, wsMsg.Headers, delay)
+			}
 			if err != nil {
-				conn.WriteJSON(map[string]interface{}{
+				writer.WriteJSON(map[string]interface{}{
 					"type":  "error",
+					"code":  errorCode(err),
 					"error": err.Error(),
 				})
 			} else {
-				conn.WriteJSON(map[string]interface{}{
+				writer.WriteJSON(map[string]interface{}{
 					"type":      "published",
 					"messageId": message.ID,
 					"topic":     message.Topic,
@@ -569,34 +2493,42 @@ func (mb *MessageBroker) websocketHandler(w http.ResponseWriter, r *http.Request
 			}
 			
 		case "subscribe":
-			subscription := mb.Subscribe(consumerID, wsMsg.Topic)
+			subscription, replay := mb.SubscribeFrom(consumerID, wsMsg.Topic, wsMsg.FromSequence, wsMsg.FromTimestamp)
+			
+			ack := map[string]interface{}{
+				"type":     "subscribed",
+				"topic":    wsMsg.Topic,
+				"sequence": subscription.Cutover,
+				"replayed": len(replay),
+			}
+			credit := wsMsg.Credit
+			if credit <= 0 {
+				credit = mb.defaultCredit
+			}
+			if credit > 0 {
+				subscription.limitCredit(credit)
+				ack["credit"] = credit
+			}
+			
+			// Acknowledge before anything is forwarded, so the client
+			// knows where the live stream starts
+			writer.WriteJSON(ack)
 			
 			// Start goroutine to forward messages
-			go func() {
-				for message := range subscription.Channel {
-					err := conn.WriteJSON(map[string]interface{}{
-						"type":    "message",
-						"topic":   message.Topic,
-						"data":    message.Data,
-						"headers": message.Headers,
-						"messageId": message.ID,
-						"timestamp": message.Timestamp,
-					})
-					if err != nil {
-						log.Printf("WebSocket write error: %v", err)
-						return
-					}
-				}
-			}()
+			go mb.forwardMessages(writer, subscription, replay)
 			
-			conn.WriteJSON(map[string]interface{}{
-				"type":  "subscribed",
-				"topic": wsMsg.Topic,
-			})
+		case "credit":
+			if err := mb.GrantCredit(consumerID, wsMsg.Topic, wsMsg.Count); err != nil {
+				writer.WriteJSON(map[string]interface{}{
+					"type":  "error",
+					"topic": wsMsg.Topic,
+					"error": err.Error(),
+				})
+			}
 			
 		case "unsubscribe":
 			mb.Unsubscribe(consumerID, wsMsg.Topic)
-			conn.WriteJSON(map[string]interface{}{
+			writer.WriteJSON(map[string]interface{}{
 				"type":  "unsubscribed",
 				"topic": wsMsg.Topic,
 			})
@@ -604,38 +2536,59 @@ func (mb *MessageBroker) websocketHandler(w http.ResponseWriter, r *http.Request
 	}
 	
 	// Cleanup subscriptions
-	mb.mutex.RLock()
-	if consumer, exists := mb.consumers[consumerID]; exists {
-		consumer.mutex.RLock()
-		for topic := range consumer.Subscriptions {
-			mb.Unsubscribe(consumerID, topic)
-		}
-		consumer.mutex.RUnlock()
-	}
-	mb.mutex.RUnlock()
+	mb.DisconnectConsumer(consumerID)
 	
 	log.Printf("WebSocket connection closed: %s", consumerID)
 }
 
-func main() {
-	broker := NewMessageBroker()
-	
+// routes returns the broker's HTTP API, admin, metrics and WebSocket
+// routes behind its CORS middleware
+func (mb *MessageBroker) routes() http.Handler {
 	r := mux.NewRouter()
 	
 	// HTTP API routes
-	r.HandleFunc("/publish/{topic}", broker.publishHandler).Methods("POST")
-	r.HandleFunc("/publish/batch/{topic}", broker.publishBatchHandler).Methods("POST")
-	r.HandleFunc("/consume/{topic}", broker.consumeHandler).Methods("GET")
-	r.HandleFunc("/consume/{topic}/batch", broker.consumeBatchHandler).Methods("GET")
-	r.HandleFunc("/topics", broker.topicsHandler).Methods("GET")
-	r.HandleFunc("/topics/{topic}/stats", broker.topicStatsHandler).Methods("GET")
-	r.HandleFunc("/health", broker.healthHandler).Methods("GET")
+	r.HandleFunc("/publish/{topic}", mb.publishHandler).Methods("POST")
+	r.HandleFunc("/publish/batch/{topic}", mb.publishBatchHandler).Methods("POST")
+	r.HandleFunc("/consume/{topic}", mb.consumeHandler).Methods("GET")
+	r.HandleFunc("/consume/{topic}/batch", mb.consumeBatchHandler).Methods("GET")
+	r.HandleFunc("/peek/{topic}", mb.peekHandler).Methods("GET")
+	r.HandleFunc("/peek/{topic}/range", mb.peekRangeHandler).Methods("GET")
+	r.HandleFunc("/rpc/{topic}", mb.rpcHandler).Methods("POST")
+	r.HandleFunc("/topics", mb.topicsHandler).Methods("GET")
+	r.HandleFunc("/topics", mb.createTopicHandler).Methods("POST")
+	r.HandleFunc("/topics/{topic}/config", mb.topicConfigHandler).Methods("GET")
+	r.HandleFunc("/topics/{topic}/config", mb.updateTopicConfigHandler).Methods("PATCH")
+	r.HandleFunc("/topics/{topic}/stats", mb.topicStatsHandler).Methods("GET")
+	r.HandleFunc("/topics/{topic}/sequence", mb.topicSequenceHandler).Methods("GET")
+	r.HandleFunc("/topics/{topic}/mirrors", mb.mirrorsHandler).Methods("GET")
+	r.HandleFunc("/topics/{topic}/mirrors/{dest}", mb.addMirrorHandler).Methods("PUT")
+	r.HandleFunc("/topics/{topic}/mirrors/{dest}", mb.removeMirrorHandler).Methods("DELETE")
+	r.HandleFunc("/consumers", mb.consumersHandler).Methods("GET")
+	r.HandleFunc("/consumed/{group}", mb.ackConsumedHandler).Methods("POST")
+	r.HandleFunc("/consumed/{group}", mb.consumedStatsHandler).Methods("GET")
+	r.HandleFunc("/consumed/{group}/contains", mb.consumedContainsHandler).Methods("GET")
+	r.HandleFunc("/health", mb.healthHandler).Methods("GET")
 	r.Handle("/metrics", promhttp.Handler()).Methods("GET")
 	
+	// Admin routes, behind the ADMIN_TOKEN check
+	admin := r.PathPrefix("/admin").Subrouter()
+	admin.Use(mb.adminAuth)
+	admin.HandleFunc("/export", mb.exportHandler).Methods("GET")
+	admin.HandleFunc("/import", mb.importHandler).Methods("POST")
+	admin.HandleFunc("/consumed/{group}", mb.forgetConsumedHandler).Methods("DELETE")
+	admin.HandleFunc("/consumers/{consumerId}", mb.disconnectConsumerHandler).Methods("DELETE")
+	
 	// WebSocket route
-	r.HandleFunc("/ws", broker.websocketHandler)
+	r.HandleFunc("/ws", mb.websocketHandler)
+	
+	return mb.corsMiddleware(r)
+}
+
+func main() {
+	broker := NewMessageBroker()
+	upgrader.CheckOrigin = broker.checkOrigin
 	
 	port := getEnv("PORT", "8080")
 	log.Printf("Starting message broker on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, r))
+	log.Fatal(http.ListenAndServe(":"+port, broker.routes()))
 }
\ No newline at end of file
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// testClock is a clock that only moves when a test advances it
+type testClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+// Now returns the clock's current time
+func (c *testClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d
+func (c *testClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// newTestBroker returns a broker reading the time from clock, with the
+// environment's defaults and no background routines running, so tests
+// decide when cleanup and delivery happen
+func newTestBroker(t *testing.T, clock *testClock) *MessageBroker {
+	t.Helper()
+	mb := newMessageBroker()
+	mb.now = clock.Now
+	mb.startedAt = clock.Now()
+	return mb
+}
+
+// waitFor polls cond until it holds, failing the test after a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// topicDepth returns how many messages a topic holds and how many are scheduled
+func topicDepth(mb *MessageBroker, topicName string) (queued, scheduled int) {
+	topic := mb.GetOrCreateTopic(topicName)
+	topic.mutex.RLock()
+	defer topic.mutex.RUnlock()
+	return len(topic.Messages), topic.Scheduled
+}
+
+// codeOf returns err's error code, or "" for a nil error
+func codeOf(err error) string {
+	if err == nil {
+		return ""
+	}
+	return errorCode(err)
+}
+
+// newTestServer serves mb's routes until the test ends
+func newTestServer(t *testing.T, mb *MessageBroker) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(mb.routes())
+	t.Cleanup(server.Close)
+	return server
+}
+
+// errorEnvelope is the JSON body writeError sends
+type errorEnvelope struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details"`
+}
+
+// decodeError reads an error response's body, closing it
+func decodeError(t *testing.T, resp *http.Response) errorEnvelope {
+	t.Helper()
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("error Content-Type = %q, want application/json", ct)
+	}
+	var envelope errorEnvelope
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		t.Fatalf("decoding error body: %v", err)
+	}
+	return envelope
+}
+
+// wsURL returns the address of server's WebSocket endpoint
+func wsURL(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+}
+
+// dialWS opens a WebSocket to server, closed when the test ends
+func dialWS(t *testing.T, server *httptest.Server) *websocket.Conn {
+	t.Helper()
+	conn, resp, err := websocket.DefaultDialer.Dial(wsURL(server), nil)
+	if err != nil {
+		t.Fatalf("dialing WebSocket: %v (response %v)", err, resp)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// wsReply is any frame the broker sends over a WebSocket
+type wsReply struct {
+	Type      string      `json:"type"`
+	Topic     string      `json:"topic"`
+	Data      interface{} `json:"data"`
+	MessageID string      `json:"messageId"`
+	Sequence  uint64      `json:"sequence"`
+	Replayed  int         `json:"replayed"`
+	Credit    int         `json:"credit"`
+	Code      string      `json:"code"`
+	Error     string      `json:"error"`
+}
+
+// readWS reads the next frame from conn, failing the test if none arrives
+// within a few seconds
+func readWS(t *testing.T, conn *websocket.Conn) wsReply {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var reply wsReply
+	if err := conn.ReadJSON(&reply); err != nil {
+		t.Fatalf("reading WebSocket: %v", err)
+	}
+	return reply
+}
+
+// sendWS writes msg to conn
+func sendWS(t *testing.T, conn *websocket.Conn, msg WebSocketMessage) {
+	t.Helper()
+	if err := conn.WriteJSON(msg); err != nil {
+		t.Fatalf("writing WebSocket: %v", err)
+	}
+}
+
+// subscribeWS subscribes conn with msg and returns the acknowledgement
+func subscribeWS(t *testing.T, conn *websocket.Conn, msg WebSocketMessage) wsReply {
+	t.Helper()
+	msg.Type = "subscribe"
+	sendWS(t, conn, msg)
+	ack := readWS(t, conn)
+	if ack.Type != "subscribed" {
+		t.Fatalf("subscribe reply = %+v, want subscribed", ack)
+	}
+	return ack
+}
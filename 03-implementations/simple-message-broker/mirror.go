@@ -0,0 +1,97 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// mirroredFromHeader names the topic a mirrored copy was published to
+const mirroredFromHeader = "X-Mirrored-From"
+
+// Mirror registers dest as a mirror of source: every message published to
+// source from now on is also published to dest as a copy with a new ID, the
+// same data and headers, and an X-Mirrored-From header naming source.
+// Source's consumers see no difference. A source can have several mirrors,
+// and registering the same pair twice does nothing.
+//
+// Copies are not mirrored again, so chains and cycles such as a topic and
+// its mirror mirroring each other can't loop; a topic can't mirror itself.
+func (mb *MessageBroker) Mirror(source, dest string) error {
+	if source == "" || dest == "" {
+		return invalidRequest("source and destination topics are required")
+	}
+	if source == dest {
+		return invalidRequest("topic %s cannot mirror itself", source)
+	}
+
+	mb.mirrorMutex.Lock()
+	defer mb.mirrorMutex.Unlock()
+
+	for _, existing := range mb.mirrors[source] {
+		if existing == dest {
+			return nil
+		}
+	}
+	mb.mirrors[source] = append(mb.mirrors[source], dest)
+
+	log.Printf("Mirroring topic %s to %s", source, dest)
+	return nil
+}
+
+// Unmirror stops copying messages from source to dest. Copies already
+// published stay in dest.
+func (mb *MessageBroker) Unmirror(source, dest string) error {
+	mb.mirrorMutex.Lock()
+	defer mb.mirrorMutex.Unlock()
+
+	dests := mb.mirrors[source]
+	for i, existing := range dests {
+		if existing != dest {
+			continue
+		}
+		if len(dests) == 1 {
+			delete(mb.mirrors, source)
+		} else {
+			mb.mirrors[source] = append(dests[:i:i], dests[i+1:]...)
+		}
+		log.Printf("Stopped mirroring topic %s to %s", source, dest)
+		return nil
+	}
+	return notFound("topic %s is not mirrored to %s", source, dest)
+}
+
+// Mirrors returns the topics source is mirrored to, in the order they were
+// registered
+func (mb *MessageBroker) Mirrors(source string) []string {
+	mb.mirrorMutex.RLock()
+	defer mb.mirrorMutex.RUnlock()
+
+	return append([]string{}, mb.mirrors[source]...)
+}
+
+// publishMirrors publishes a copy of message to each of its topic's mirrors,
+// with the same delay. Copies skip the circuit breaker and are never
+// mirrored themselves. A copy that can't be published, say because the
+// mirror's queue is full, is logged and counted but doesn't fail the
+// original publish.
+func (mb *MessageBroker) publishMirrors(message *Message, delay time.Duration) {
+	dests := mb.Mirrors(message.Topic)
+	if len(dests) == 0 {
+		return
+	}
+
+	// The original's headers may be shared with other publishes, as in a
+	// batch, so each copy gets its own map
+	for _, dest := range dests {
+		headers := make(map[string]string, len(message.Headers)+1)
+		for key, value := range message.Headers {
+			headers[key] = value
+		}
+		headers[mirroredFromHeader] = message.Topic
+
+		if _, err := mb.publish(dest, message.Data, headers, delay); err != nil {
+			mb.mirrorFailures.Inc()
+			log.Printf("Failed to mirror message %s from topic %s to %s: %v", message.ID, message.Topic, dest, err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMirrorCopiesMessages(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	if err := mb.Mirror("orders", "audit"); err != nil {
+		t.Fatal(err)
+	}
+	subscription := mb.Subscribe("shipping", "orders")
+
+	headers := map[string]string{"X-Order": "1"}
+	original, err := mb.PublishMessage("orders", map[string]interface{}{"orderId": 1.0}, headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, mirrored := headers[mirroredFromHeader]; mirrored {
+		t.Error("mirroring modified the original's headers")
+	}
+
+	// The source's consumers see exactly the original
+	select {
+	case got := <-subscription.Channel:
+		if got.ID != original.ID {
+			t.Errorf("subscriber got %s, want the original %s", got.ID, original.ID)
+		}
+	default:
+		t.Fatal("subscriber got nothing")
+	}
+	if len(subscription.Channel) != 0 {
+		t.Errorf("subscriber has %d more messages, want none", len(subscription.Channel))
+	}
+	consumed, err := mb.ConsumeMessage("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if consumed.ID != original.ID || consumed.Headers[mirroredFromHeader] != "" {
+		t.Errorf("source message = %s with %v, want the original without %s", consumed.ID, consumed.Headers, mirroredFromHeader)
+	}
+
+	copied, err := mb.ConsumeMessage("audit")
+	if err != nil {
+		t.Fatalf("consuming the mirror: %v", err)
+	}
+	if copied.ID == original.ID {
+		t.Error("copy has the original's ID")
+	}
+	if copied.Topic != "audit" || copied.Sequence != 1 {
+		t.Errorf("copy topic %s sequence %d, want audit 1", copied.Topic, copied.Sequence)
+	}
+	if fmt.Sprint(copied.Data) != fmt.Sprint(original.Data) {
+		t.Errorf("copy data = %v, want %v", copied.Data, original.Data)
+	}
+	if copied.Headers[mirroredFromHeader] != "orders" || copied.Headers["X-Order"] != "1" {
+		t.Errorf("copy headers = %v, want X-Order and %s: orders", copied.Headers, mirroredFromHeader)
+	}
+}
+
+func TestMirrorRejectsSelfAndEmpty(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	for _, pair := range [][2]string{{"orders", "orders"}, {"", "audit"}, {"orders", ""}} {
+		if err := mb.Mirror(pair[0], pair[1]); errorCode(err) != CodeInvalidRequest {
+			t.Errorf("Mirror(%q, %q) = %v, want code %q", pair[0], pair[1], err, CodeInvalidRequest)
+		}
+	}
+	if mirrors := mb.Mirrors("orders"); len(mirrors) != 0 {
+		t.Errorf("mirrors = %v, want none", mirrors)
+	}
+
+	// Over HTTP a self-mirror is a 400
+	server := newTestServer(t, mb)
+	req, _ := http.NewRequest("PUT", server.URL+"/topics/orders/mirrors/orders", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if envelope := decodeError(t, resp); resp.StatusCode != http.StatusBadRequest || envelope.Code != CodeInvalidRequest {
+		t.Errorf("PUT self-mirror = %d %s, want 400 %s", resp.StatusCode, envelope.Code, CodeInvalidRequest)
+	}
+}
+
+func TestMirrorCycleDoesNotLoop(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	if err := mb.Mirror("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mb.Mirror("b", "a"); err != nil {
+		t.Fatal(err)
+	}
+	// Registering a pair twice does nothing
+	if err := mb.Mirror("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if mirrors := fmt.Sprint(mb.Mirrors("a")); mirrors != "[b]" {
+		t.Errorf("mirrors of a = %s, want [b]", mirrors)
+	}
+
+	publishAll(t, mb, "a", []string{"from a"})
+	publishAll(t, mb, "b", []string{"from b"})
+
+	for _, topic := range []string{"a", "b"} {
+		if queued, _ := topicDepth(mb, topic); queued != 2 {
+			t.Errorf("topic %s holds %d messages, want its own and one copy", topic, queued)
+		}
+	}
+	messages, _, err := mb.PeekMessages("b", 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from := messages[0].Headers[mirroredFromHeader]; messages[0].Data != "from a" || from != "a" {
+		t.Errorf("b's first message = %v from %q, want the copy from a", messages[0].Data, from)
+	}
+	if from, mirrored := messages[1].Headers[mirroredFromHeader]; messages[1].Data != "from b" || mirrored {
+		t.Errorf("b's second message = %v from %q, want b's own", messages[1].Data, from)
+	}
+}
+
+func TestMirrorFailureKeepsOriginal(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	if _, err := mb.CreateTopic("audit", TopicConfig{MaxQueueSize: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mb.Mirror("orders", "audit"); err != nil {
+		t.Fatal(err)
+	}
+
+	publishAll(t, mb, "orders", []string{"first", "second"})
+	if queued, _ := topicDepth(mb, "orders"); queued != 2 {
+		t.Errorf("source holds %d messages, want 2", queued)
+	}
+	if queued, _ := topicDepth(mb, "audit"); queued != 1 {
+		t.Errorf("full mirror holds %d messages, want 1", queued)
+	}
+}
+
+func TestMirrorKeepsDelay(t *testing.T) {
+	clock := newTestClock()
+	mb := newTestBroker(t, clock)
+	if err := mb.Mirror("orders", "audit"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := mb.PublishDelayed("orders", "later", nil, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	for _, topic := range []string{"orders", "audit"} {
+		if queued, scheduled := topicDepth(mb, topic); queued != 0 || scheduled != 1 {
+			t.Errorf("topic %s: %d queued and %d scheduled, want 0 and 1", topic, queued, scheduled)
+		}
+	}
+	clock.Advance(time.Minute)
+	mb.deliverDue()
+	if queued, _ := topicDepth(mb, "audit"); queued != 1 {
+		t.Errorf("mirror holds %d messages once due, want 1", queued)
+	}
+}
+
+func TestUnmirror(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	if err := mb.Unmirror("orders", "audit"); errorCode(err) != CodeNotFound {
+		t.Errorf("Unmirror of an unknown pair = %v, want code %q", err, CodeNotFound)
+	}
+
+	for _, dest := range []string{"audit", "archive"} {
+		if err := mb.Mirror("orders", dest); err != nil {
+			t.Fatal(err)
+		}
+	}
+	publishAll(t, mb, "orders", []string{"copied"})
+	if err := mb.Unmirror("orders", "audit"); err != nil {
+		t.Fatal(err)
+	}
+	publishAll(t, mb, "orders", []string{"not copied to audit"})
+
+	if mirrors := fmt.Sprint(mb.Mirrors("orders")); mirrors != "[archive]" {
+		t.Errorf("mirrors = %s, want [archive]", mirrors)
+	}
+	if queued, _ := topicDepth(mb, "audit"); queued != 1 {
+		t.Errorf("audit holds %d messages, want the copy made before Unmirror", queued)
+	}
+	if queued, _ := topicDepth(mb, "archive"); queued != 2 {
+		t.Errorf("archive holds %d messages, want 2", queued)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// postStatus sends body to server and returns the status and error code
+// of the response, the code empty on success
+func postStatus(t *testing.T, url, body string, header map[string]string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest("POST", url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode < 300 {
+		resp.Body.Close()
+		return resp.StatusCode, ""
+	}
+	return resp.StatusCode, decodeError(t, resp).Code
+}
+
+// jsonString returns a JSON string literal n bytes long, quotes included
+func jsonString(n int) string {
+	return `"` + strings.Repeat("x", n-2) + `"`
+}
+
+func TestOversizeHTTPBodies(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	mb.maxMessageSize = 64
+	mb.maxBatchSize = 128
+	server := newTestServer(t, mb)
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"publish at the limit", "/publish/orders", jsonString(64), http.StatusOK},
+		{"publish over the limit", "/publish/orders", jsonString(65), http.StatusRequestEntityTooLarge},
+		{"rpc over the limit", "/rpc/orders?timeout=1ms", jsonString(65), http.StatusRequestEntityTooLarge},
+		{"batch at the limit", "/publish/batch/orders", "[" + jsonString(62) + "," + jsonString(63) + "]", http.StatusOK},
+		{"batch over the limit", "/publish/batch/orders", "[" + jsonString(62) + "," + jsonString(64) + "]", http.StatusRequestEntityTooLarge},
+		{"batch message over the limit", "/publish/batch/orders", "[" + jsonString(65) + "]", http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code := postStatus(t, server.URL+tt.path, tt.body, nil)
+			if status != tt.status {
+				t.Fatalf("status = %d (%s), want %d", status, code, tt.status)
+			}
+			if tt.status == http.StatusRequestEntityTooLarge && code != CodeMessageTooLarge {
+				t.Errorf("code = %q, want %q", code, CodeMessageTooLarge)
+			}
+		})
+	}
+}
+
+// importBody returns an import of one topic holding messages messages
+func importBody(messages int) string {
+	var b strings.Builder
+	b.WriteString(`{"type": "topic", "topic": "imported"}` + "\n")
+	for i := 0; i < messages; i++ {
+		fmt.Fprintf(&b, `{"type": "message", "topic": "imported", "message": {"id": "m%d", "data": %d}}`+"\n", i, i)
+	}
+	return b.String()
+}
+
+func TestOversizeImport(t *testing.T) {
+	admin := map[string]string{"Authorization": "Bearer secret"}
+	for _, tt := range []struct {
+		messages int
+		status   int
+	}{
+		{1, http.StatusOK},
+		{10, http.StatusRequestEntityTooLarge},
+	} {
+		// Imports need an empty broker, so each gets its own
+		mb := newTestBroker(t, newTestClock())
+		mb.maxImportSize = 256
+		mb.adminToken = "secret"
+		server := newTestServer(t, mb)
+
+		status, code := postStatus(t, server.URL+"/admin/import", importBody(tt.messages), admin)
+		if status != tt.status {
+			t.Errorf("import of %d messages: %d %s, want %d", tt.messages, status, code, tt.status)
+		}
+		if tt.status == http.StatusRequestEntityTooLarge && code != CodeMessageTooLarge {
+			t.Errorf("import of %d messages: code %q, want %q", tt.messages, code, CodeMessageTooLarge)
+		}
+		if tt.status == http.StatusOK {
+			if queued, _ := topicDepth(mb, "imported"); queued != tt.messages {
+				t.Errorf("imported %d messages, want %d", queued, tt.messages)
+			}
+		}
+	}
+}
+
+func TestOversizeWebSocketFrames(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	mb.maxMessageSize = 64
+	server := newTestServer(t, mb)
+	conn := dialWS(t, server)
+
+	// Data over the limit in a frame that fits is answered with an error
+	// and the connection carries on
+	sendWS(t, conn, WebSocketMessage{Type: "publish", Topic: "orders", Data: strings.Repeat("x", 100)})
+	if reply := readWS(t, conn); reply.Type != "error" || reply.Code != CodeMessageTooLarge {
+		t.Fatalf("oversize data: %+v, want a %s error", reply, CodeMessageTooLarge)
+	}
+	sendWS(t, conn, WebSocketMessage{Type: "publish", Topic: "orders", Data: "ok"})
+	if reply := readWS(t, conn); reply.Type != "published" {
+		t.Fatalf("publish after the error: %+v, want published", reply)
+	}
+
+	// A frame over the read limit closes the connection with 1009
+	sendWS(t, conn, WebSocketMessage{Type: "publish", Topic: "orders", Data: strings.Repeat("x", 64+wsFrameOverhead)})
+	var reply wsReply
+	err := conn.ReadJSON(&reply)
+	if !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+		t.Errorf("oversize frame: read %+v, %v; want close 1009", reply, err)
+	}
+	if queued, _ := topicDepth(mb, "orders"); queued != 1 {
+		t.Errorf("queued = %d, want only the message that fit", queued)
+	}
+}
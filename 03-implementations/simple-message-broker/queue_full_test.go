@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	brokerclient "simple-message-broker/clients/go"
+)
+
+// asStatusError returns the client's *StatusError in err, failing the test
+// if there is none
+func asStatusError(t *testing.T, err error) *brokerclient.StatusError {
+	t.Helper()
+	var statusErr *brokerclient.StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("error %v, want a *StatusError", err)
+	}
+	return statusErr
+}
+
+func TestQueueFullThroughClient(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	server := newTestServer(t, mb)
+	client := brokerclient.New(server.URL)
+	ctx := context.Background()
+
+	if _, err := mb.CreateTopic("orders", TopicConfig{MaxQueueSize: 2}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := client.Publish(ctx, "orders", i); err != nil {
+			t.Fatalf("publish %d: %v", i, err)
+		}
+	}
+
+	_, err := client.Publish(ctx, "orders", "overflow")
+	statusErr := asStatusError(t, err)
+	if statusErr.StatusCode != http.StatusServiceUnavailable || statusErr.Code != CodeQueueFull {
+		t.Errorf("full topic: %d %s, want 503 %s", statusErr.StatusCode, statusErr.Code, CodeQueueFull)
+	}
+	if !statusErr.Retryable() || statusErr.RetryAfter != queueFullRetryAfter {
+		t.Errorf("full topic: retryable %t after %v, want retryable after %v", statusErr.Retryable(), statusErr.RetryAfter, queueFullRetryAfter)
+	}
+	if depth, limit := statusErr.Details["depth"], statusErr.Details["maxQueueSize"]; depth != 2.0 || limit != 2.0 {
+		t.Errorf("details depth %v maxQueueSize %v, want 2 and 2", depth, limit)
+	}
+
+	// A batch stops at the first message that doesn't fit
+	_, err = client.PublishBatch(ctx, "orders", []interface{}{"a", "b"})
+	if statusErr := asStatusError(t, err); statusErr.Code != CodeQueueFull {
+		t.Errorf("batch to a full topic: code %s, want %s", statusErr.Code, CodeQueueFull)
+	}
+
+	// Once a consumer makes room, the retry the error asked for succeeds
+	if _, err := mb.ConsumeMessage("orders"); err != nil {
+		t.Fatal(err)
+	}
+	receipt, err := client.Publish(ctx, "orders", "retried")
+	if err != nil {
+		t.Fatalf("retry after a consume: %v", err)
+	}
+	if receipt.Sequence != 3 {
+		t.Errorf("retried message sequence = %d, want 3", receipt.Sequence)
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy decides which queued messages a topic stops keeping. The
+// broker can run several; each evicts what it no longer keeps, so a message
+// goes as soon as any of them lets it go.
+type RetentionPolicy interface {
+	// Retain removes the messages the policy no longer keeps from
+	// topic.Messages and returns how many it removed. The caller holds
+	// topic.mutex.
+	Retain(topic *Topic, now time.Time) int
+
+	// OnPublish reports whether Retain also runs after every message that
+	// reaches a topic, rather than only on the hourly cleanup
+	OnPublish() bool
+
+	// String describes the policy for logs
+	String() string
+}
+
+// TimeRetention drops messages older than their topic's retentionHours. A
+// delayed message's age counts from its delivery. It runs hourly: checking
+// ages means scanning the whole queue, too slow to do on every publish.
+type TimeRetention struct{}
+
+// Retain removes the messages past their topic's retention period
+func (TimeRetention) Retain(topic *Topic, now time.Time) int {
+	cutoff := now.Add(-time.Duration(topic.Config.RetentionHours) * time.Hour)
+
+	// Priority-ordered topics aren't sorted by age, so every message is
+	// checked
+	return removeMessages(topic, func(message *Message) bool {
+		return message.availableAt().After(cutoff)
+	})
+}
+
+// OnPublish is false: time-based retention waits for the hourly cleanup
+func (TimeRetention) OnPublish() bool { return false }
+
+func (TimeRetention) String() string { return "time" }
+
+// CountRetention keeps the latest MaxMessages messages of each topic,
+// evicting the oldest as new ones arrive
+type CountRetention struct {
+	MaxMessages int
+}
+
+// Retain removes the oldest messages beyond MaxMessages
+func (p CountRetention) Retain(topic *Topic, now time.Time) int {
+	excess := len(topic.Messages) - p.MaxMessages
+	if excess <= 0 {
+		return 0
+	}
+	cutoff := oldestFirst(topic)[excess-1].Sequence
+	return removeMessages(topic, func(message *Message) bool {
+		return message.Sequence > cutoff
+	})
+}
+
+// OnPublish is true: a topic never holds more than MaxMessages
+func (CountRetention) OnPublish() bool { return true }
+
+func (p CountRetention) String() string { return fmt.Sprintf("count(%d)", p.MaxMessages) }
+
+// SizeRetention keeps each topic's queued messages under MaxBytes in total,
+// evicting the oldest as new ones arrive. A message's size is its data and
+// headers as JSON. The newest message is always kept, even on its own
+// over the limit.
+type SizeRetention struct {
+	MaxBytes int
+}
+
+// Retain removes the oldest messages until the rest fit in MaxBytes. It
+// reads the topic's running byte total, so a publish that leaves the topic
+// under the limit costs O(1). Evicting costs O(evicted) on a FIFO topic,
+// whose oldest messages are at the front, and a scan of the queue per
+// evicted message on a priority topic.
+func (p SizeRetention) Retain(topic *Topic, now time.Time) int {
+	total := topic.Bytes
+	if total <= p.MaxBytes {
+		return 0
+	}
+
+	if topic.Config.Ordering != OrderingPriority {
+		evicted := 0
+		for evicted < len(topic.Messages)-1 && total > p.MaxBytes {
+			total -= topic.Messages[evicted].byteSize()
+			evicted++
+		}
+		return removeOldest(topic, evicted)
+	}
+
+	var cutoff uint64
+	for evicted := 0; evicted < len(topic.Messages)-1 && total > p.MaxBytes; evicted++ {
+		next := nextOldest(topic, cutoff)
+		total -= next.byteSize()
+		cutoff = next.Sequence
+	}
+	return removeMessages(topic, func(message *Message) bool {
+		return message.Sequence > cutoff
+	})
+}
+
+// nextOldest returns the queued message with the lowest sequence number
+// above after. The caller holds topic.mutex and knows there is one.
+func nextOldest(topic *Topic, after uint64) *Message {
+	var oldest *Message
+	for _, message := range topic.Messages {
+		if message.Sequence > after && (oldest == nil || message.Sequence < oldest.Sequence) {
+			oldest = message
+		}
+	}
+	return oldest
+}
+
+// removeOldest removes the first count messages of a FIFO topic and returns
+// count. The caller holds topic.mutex.
+func removeOldest(topic *Topic, count int) int {
+	for i := 0; i < count; i++ {
+		topic.Bytes -= topic.Messages[i].byteSize()
+		topic.Messages[i] = nil
+	}
+	topic.Messages = topic.Messages[count:]
+	return count
+}
+
+// OnPublish is true: a topic never holds much more than MaxBytes
+func (SizeRetention) OnPublish() bool { return true }
+
+func (p SizeRetention) String() string { return fmt.Sprintf("size(%d bytes)", p.MaxBytes) }
+
+// removeMessages keeps the messages of a topic that keep accepts, in their
+// order, and returns how many it removed. The caller holds topic.mutex.
+func removeMessages(topic *Topic, keep func(*Message) bool) int {
+	kept := topic.Messages[:0]
+	for _, message := range topic.Messages {
+		if keep(message) {
+			kept = append(kept, message)
+		} else {
+			topic.Bytes -= message.byteSize()
+		}
+	}
+
+	removed := len(topic.Messages) - len(kept)
+	for i := len(kept); i < len(topic.Messages); i++ {
+		topic.Messages[i] = nil
+	}
+	topic.Messages = kept
+	return removed
+}
+
+// oldestFirst returns a topic's queued messages in sequence order. A FIFO
+// queue already is; a priority queue is sorted into a copy. The caller
+// holds topic.mutex and must not modify the result.
+func oldestFirst(topic *Topic) []*Message {
+	if topic.Config.Ordering != OrderingPriority {
+		return topic.Messages
+	}
+	messages := make([]*Message, len(topic.Messages))
+	copy(messages, topic.Messages)
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].Sequence < messages[j].Sequence
+	})
+	return messages
+}
+
+// byteSize returns the size of the message's data and headers as JSON,
+// computed on first use. publish computes it before the message is shared;
+// otherwise the caller holds the lock of the message's topic.
+func (m *Message) byteSize() int {
+	if m.size == 0 {
+		data, _ := json.Marshal(m.Data)
+		m.size = len(data)
+		for key, value := range m.Headers {
+			m.size += len(key) + len(value)
+		}
+	}
+	return m.size
+}
+
+// retentionPoliciesFromEnv reads RETENTION_POLICY, a comma-separated list of
+// time, count, and size, with the limits of the count and size policies.
+// Unknown names and invalid limits are skipped; with nothing left, time is
+// used.
+func retentionPoliciesFromEnv() []RetentionPolicy {
+	var policies []RetentionPolicy
+	for _, name := range strings.Split(getEnv("RETENTION_POLICY", "time"), ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "time":
+			policies = append(policies, TimeRetention{})
+		case "count":
+			n, err := strconv.Atoi(getEnv("RETENTION_MAX_MESSAGES", "1000"))
+			if err != nil || n < 1 {
+				log.Printf("Invalid RETENTION_MAX_MESSAGES, skipping count retention")
+				continue
+			}
+			policies = append(policies, CountRetention{MaxMessages: n})
+		case "size":
+			n, err := strconv.Atoi(getEnv("RETENTION_MAX_BYTES", "67108864"))
+			if err != nil || n < 1 {
+				log.Printf("Invalid RETENTION_MAX_BYTES, skipping size retention")
+				continue
+			}
+			policies = append(policies, SizeRetention{MaxBytes: n})
+		default:
+			log.Printf("Unknown retention policy %q, skipping", name)
+		}
+	}
+
+	if len(policies) == 0 {
+		log.Printf("No valid retention policy configured, using time")
+		policies = append(policies, TimeRetention{})
+	}
+	return policies
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// queuedSequences returns the sequence numbers of a topic's queued messages
+// in queue order
+func queuedSequences(mb *MessageBroker, topicName string) []uint64 {
+	topic := mb.GetOrCreateTopic(topicName)
+	topic.mutex.RLock()
+	defer topic.mutex.RUnlock()
+	sequences := make([]uint64, len(topic.Messages))
+	for i, message := range topic.Messages {
+		sequences[i] = message.Sequence
+	}
+	return sequences
+}
+
+// checkBytes fails the test if a topic's running byte total has drifted
+// from the size of the messages it holds
+func checkBytes(t *testing.T, mb *MessageBroker, topicName string) int {
+	t.Helper()
+	topic := mb.GetOrCreateTopic(topicName)
+	topic.mutex.RLock()
+	defer topic.mutex.RUnlock()
+	want := 0
+	for _, message := range topic.Messages {
+		want += message.byteSize()
+	}
+	if topic.Bytes != want {
+		t.Errorf("topic %s Bytes = %d, want %d", topicName, topic.Bytes, want)
+	}
+	return topic.Bytes
+}
+
+// publishAll publishes each value to a topic, with an X-Priority header
+// when priorities are given
+func publishAll(t *testing.T, mb *MessageBroker, topicName string, values []string, priorities ...int) {
+	t.Helper()
+	for i, value := range values {
+		var headers map[string]string
+		if priorities != nil {
+			headers = map[string]string{priorityHeader: fmt.Sprint(priorities[i])}
+		}
+		if _, err := mb.PublishMessage(topicName, value, headers); err != nil {
+			t.Fatalf("publishing %q: %v", value, err)
+		}
+	}
+}
+
+func TestCountRetention(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	mb.retention = []RetentionPolicy{CountRetention{MaxMessages: 3}}
+
+	publishAll(t, mb, "orders", []string{"a", "b", "c", "d", "e"})
+	if got := fmt.Sprint(queuedSequences(mb, "orders")); got != "[3 4 5]" {
+		t.Errorf("queued = %s, want [3 4 5]", got)
+	}
+	checkBytes(t, mb, "orders")
+}
+
+func TestCountRetentionPriority(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	mb.retention = []RetentionPolicy{CountRetention{MaxMessages: 3}}
+	if _, err := mb.CreateTopic("jobs", TopicConfig{Ordering: OrderingPriority}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The oldest go first whatever their priority
+	publishAll(t, mb, "jobs", []string{"a", "b", "c", "d", "e"}, 9, 1, 5, 1, 9)
+	if got := fmt.Sprint(queuedSequences(mb, "jobs")); got != "[5 3 4]" {
+		t.Errorf("queued = %s, want [5 3 4]", got)
+	}
+	checkBytes(t, mb, "jobs")
+}
+
+func TestSizeRetention(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	// Each message is "xxxx" in quotes, 6 bytes
+	mb.retention = []RetentionPolicy{SizeRetention{MaxBytes: 20}}
+
+	publishAll(t, mb, "orders", []string{"aaaa", "bbbb", "cccc", "dddd", "eeee"})
+	if got := fmt.Sprint(queuedSequences(mb, "orders")); got != "[3 4 5]" {
+		t.Errorf("queued = %s, want [3 4 5]", got)
+	}
+	if bytes := checkBytes(t, mb, "orders"); bytes != 18 {
+		t.Errorf("Bytes = %d, want 18", bytes)
+	}
+
+	// Consuming keeps the total in step
+	if _, err := mb.ConsumeMessage("orders"); err != nil {
+		t.Fatal(err)
+	}
+	if bytes := checkBytes(t, mb, "orders"); bytes != 12 {
+		t.Errorf("Bytes after consume = %d, want 12", bytes)
+	}
+
+	// A message over the limit on its own still replaces everything else
+	publishAll(t, mb, "orders", []string{"this message is over twenty bytes"})
+	if got := fmt.Sprint(queuedSequences(mb, "orders")); got != "[6]" {
+		t.Errorf("queued after an oversize message = %s, want [6]", got)
+	}
+	checkBytes(t, mb, "orders")
+}
+
+func TestSizeRetentionPriority(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	if _, err := mb.CreateTopic("jobs", TopicConfig{Ordering: OrderingPriority}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every message is 6 bytes of data plus an 11-byte X-Priority header
+	publishAll(t, mb, "jobs", []string{"aaaa", "bbbb", "cccc"}, 1, 9, 5)
+	size := checkBytes(t, mb, "jobs") / 3
+	if size != 17 {
+		t.Fatalf("message size = %d, want 17", size)
+	}
+
+	// Evicting the two oldest walks nextOldest past the high-priority
+	// message at the front of the queue
+	mb.retention = []RetentionPolicy{SizeRetention{MaxBytes: 3 * size}}
+	publishAll(t, mb, "jobs", []string{"dddd", "eeee"}, 1, 9)
+	if got := fmt.Sprint(queuedSequences(mb, "jobs")); got != "[5 3 4]" {
+		t.Errorf("queued = %s, want [5 3 4]", got)
+	}
+	if bytes := checkBytes(t, mb, "jobs"); bytes != 3*size {
+		t.Errorf("Bytes = %d, want %d", bytes, 3*size)
+	}
+
+	topic := mb.GetOrCreateTopic("jobs")
+	topic.mutex.Lock()
+	if next := nextOldest(topic, 0); next.Sequence != 3 {
+		t.Errorf("nextOldest(0) = %d, want 3", next.Sequence)
+	}
+	if next := nextOldest(topic, 3); next.Sequence != 4 {
+		t.Errorf("nextOldest(3) = %d, want 4", next.Sequence)
+	}
+	topic.mutex.Unlock()
+}
+
+func TestTimeRetention(t *testing.T) {
+	clock := newTestClock()
+	mb := newTestBroker(t, clock)
+	mb.retention = []RetentionPolicy{TimeRetention{}}
+	if _, err := mb.CreateTopic("orders", TopicConfig{RetentionHours: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	publishAll(t, mb, "orders", []string{"old"})
+	if _, err := mb.PublishDelayed("orders", "delayed", nil, 2*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(30 * time.Minute)
+	publishAll(t, mb, "orders", []string{"newer"})
+
+	// Time retention waits for the cleanup, not the next publish
+	clock.Advance(45 * time.Minute)
+	publishAll(t, mb, "orders", []string{"newest"})
+	if got := len(queuedSequences(mb, "orders")); got != 3 {
+		t.Fatalf("queued before cleanup = %d, want 3", got)
+	}
+
+	mb.cleanupOldMessages()
+	if got := fmt.Sprint(queuedSequences(mb, "orders")); got != "[2 3]" {
+		t.Errorf("queued after cleanup = %s, want [2 3]", got)
+	}
+	checkBytes(t, mb, "orders")
+
+	// A delayed message's age counts from its delivery
+	clock.Advance(45 * time.Minute)
+	mb.deliverDue()
+	mb.cleanupOldMessages()
+	if got := fmt.Sprint(queuedSequences(mb, "orders")); got != "[3 4]" {
+		t.Errorf("queued after delivery = %s, want [3 4]", got)
+	}
+	checkBytes(t, mb, "orders")
+}
+
+func TestRetentionPoliciesFromEnv(t *testing.T) {
+	tests := []struct {
+		policy, maxMessages, maxBytes string
+		want                          string
+	}{
+		{"", "", "", "[time]"},
+		{"count, SIZE", "10", "2048", "[count(10) size(2048 bytes)]"},
+		{"time,count", "0", "", "[time]"},
+		{"count,bogus", "abc", "", "[time]"},
+		{"size", "", "", "[size(67108864 bytes)]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			t.Setenv("RETENTION_POLICY", tt.policy)
+			t.Setenv("RETENTION_MAX_MESSAGES", tt.maxMessages)
+			t.Setenv("RETENTION_MAX_BYTES", tt.maxBytes)
+			if got := fmt.Sprint(retentionPoliciesFromEnv()); got != tt.want {
+				t.Errorf("policies = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// startRPC calls rpcHandler for topic in the background and returns the
+// recorder and a channel closed once the call returns
+func startRPC(t *testing.T, mb *MessageBroker, topic, timeout string) (*httptest.ResponseRecorder, <-chan struct{}) {
+	t.Helper()
+	r := httptest.NewRequest("POST", "/rpc/"+topic+"?timeout="+timeout, strings.NewReader(`{"args": [2, 3]}`))
+	r = mux.SetURLVars(r, map[string]string{"topic": topic})
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		mb.rpcHandler(w, r)
+	}()
+	return w, done
+}
+
+// takeRequest waits for the RPC request published to topic and consumes it
+func takeRequest(t *testing.T, mb *MessageBroker, topic string) *Message {
+	t.Helper()
+	var request *Message
+	waitFor(t, "the RPC request", func() bool {
+		request, _ = mb.ConsumeMessage(topic)
+		return request != nil
+	})
+	return request
+}
+
+// hasTopic reports whether the broker holds a topic, without creating it
+func hasTopic(mb *MessageBroker, name string) bool {
+	mb.mutex.RLock()
+	defer mb.mutex.RUnlock()
+	_, exists := mb.topics[name]
+	return exists
+}
+
+func TestRPCReply(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	w, done := startRPC(t, mb, "math.add", "5s")
+
+	request := takeRequest(t, mb, "math.add")
+	replyTo := request.Headers[replyToHeader]
+	correlationID := request.Headers[correlationIDHeader]
+	if !strings.HasPrefix(replyTo, replyTopicPrefix) {
+		t.Fatalf("Reply-To = %q, want a %s topic", replyTo, replyTopicPrefix)
+	}
+
+	if _, err := mb.PublishMessage(replyTo, "stray", map[string]string{correlationIDHeader: "other"}); err != nil {
+		t.Fatalf("reply with another correlation ID: %v", err)
+	}
+	if _, err := mb.PublishMessage(replyTo, map[string]int{"sum": 5}, map[string]string{correlationIDHeader: correlationID}); err != nil {
+		t.Fatalf("reply: %v", err)
+	}
+	<-done
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var reply Message
+	if err := json.NewDecoder(w.Body).Decode(&reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Headers[correlationIDHeader] != correlationID {
+		t.Errorf("reply Correlation-Id = %q, want %q", reply.Headers[correlationIDHeader], correlationID)
+	}
+	if hasTopic(mb, replyTo) {
+		t.Errorf("reply topic %s still exists after the call", replyTo)
+	}
+}
+
+func TestRPCLateReplyDoesNotRecreateTopic(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	w, done := startRPC(t, mb, "math.add", "20ms")
+
+	request := takeRequest(t, mb, "math.add")
+	<-done
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504: %s", w.Code, w.Body)
+	}
+
+	replyTo := request.Headers[replyToHeader]
+	headers := map[string]string{correlationIDHeader: request.Headers[correlationIDHeader]}
+	for i := 0; i < 3; i++ {
+		if _, err := mb.PublishMessage(replyTo, "too late", headers); errorCode(err) != CodeNotFound {
+			t.Fatalf("late reply %d: error %v, want code %q", i, err, CodeNotFound)
+		}
+	}
+	if hasTopic(mb, replyTo) {
+		t.Errorf("late reply recreated %s", replyTo)
+	}
+	if stats := mb.breaker.Stats(); stats["failures"] != 0 {
+		t.Errorf("breaker failures after late replies = %v, want 0", stats["failures"])
+	}
+}
+
+func TestRPCTimeoutResponse(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	w, done := startRPC(t, mb, "math.add", "10ms")
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("RPC call did not time out")
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body["code"] != CodeTimeout || body["timeout"] != "10ms" || body["correlationId"] == nil {
+		t.Errorf("timeout body = %v", body)
+	}
+}
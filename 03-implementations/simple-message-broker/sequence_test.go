@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentPublishersGetContiguousSequences(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	const publishers, perPublisher = 8, 100
+
+	sequences := make(chan uint64, publishers*perPublisher)
+	var wg sync.WaitGroup
+	for p := 0; p < publishers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perPublisher; i++ {
+				message, err := mb.PublishMessage("orders", fmt.Sprintf("%d-%d", p, i), nil)
+				if err != nil {
+					t.Errorf("publisher %d: %v", p, err)
+					return
+				}
+				sequences <- message.Sequence
+			}
+		}(p)
+	}
+	wg.Wait()
+	close(sequences)
+
+	var got []uint64
+	for sequence := range sequences {
+		got = append(got, sequence)
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+	if len(got) != publishers*perPublisher {
+		t.Fatalf("got %d sequence numbers, want %d", len(got), publishers*perPublisher)
+	}
+	for i, sequence := range got {
+		if sequence != uint64(i+1) {
+			t.Fatalf("sorted sequence %d = %d, want %d: numbers shared or skipped", i, sequence, i+1)
+		}
+	}
+
+	// The queue holds them in the order they were numbered
+	topic := mb.GetOrCreateTopic("orders")
+	topic.mutex.RLock()
+	for i, message := range topic.Messages {
+		if message.Sequence != uint64(i+1) {
+			t.Errorf("queued message %d has sequence %d, want %d", i, message.Sequence, i+1)
+			break
+		}
+	}
+	topic.mutex.RUnlock()
+
+	resp, err := http.Get(newTestServer(t, mb).URL + "/topics/orders/sequence")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body struct{ Sequence uint64 }
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Sequence != publishers*perPublisher {
+		t.Errorf("GET sequence = %d, want %d", body.Sequence, publishers*perPublisher)
+	}
+}
+
+// gaps returns the sequence numbers missing from a run that should start
+// at first and count up by one
+func gaps(first uint64, sequences []uint64) []uint64 {
+	var missing []uint64
+	next := first
+	for _, sequence := range sequences {
+		for ; next < sequence; next++ {
+			missing = append(missing, next)
+		}
+		next = sequence + 1
+	}
+	return missing
+}
+
+func TestResumedSubscriberDetectsGap(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	server := newTestServer(t, mb)
+
+	for i := 1; i <= 5; i++ {
+		if _, err := mb.PublishMessage("orders", i, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Another client consumes the first two over HTTP while this one is away
+	for i := 0; i < 2; i++ {
+		if _, err := mb.ConsumeMessage("orders"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conn := dialWS(t, server)
+	ack := subscribeWS(t, conn, WebSocketMessage{Topic: "orders", FromSequence: 1})
+	if ack.Sequence != 5 || ack.Replayed != 3 {
+		t.Fatalf("ack = %+v, want sequence 5 with 3 replayed", ack)
+	}
+
+	var received []uint64
+	for i := 0; i < ack.Replayed; i++ {
+		received = append(received, readWS(t, conn).Sequence)
+	}
+	if missing := gaps(1, received); fmt.Sprint(missing) != "[1 2]" {
+		t.Errorf("gap in %v = %v, want [1 2]", received, missing)
+	}
+}
+
+func TestSubscriberCatchesUpAfterOverflow(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	server := newTestServer(t, mb)
+	conn := dialWS(t, server)
+
+	// With one credit the forwarder sends one message and stalls, so the
+	// 100-message channel fills and the rest are dropped
+	subscribeWS(t, conn, WebSocketMessage{Topic: "orders", Credit: 1})
+	const published = 300
+	for i := 1; i <= published; i++ {
+		if _, err := mb.PublishMessage("orders", i, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if first := readWS(t, conn); first.Sequence != 1 {
+		t.Fatalf("first message sequence = %d, want 1", first.Sequence)
+	}
+
+	sendWS(t, conn, WebSocketMessage{Type: "credit", Topic: "orders", Count: published})
+	received := []uint64{1}
+	for len(received) < published {
+		received = append(received, readWS(t, conn).Sequence)
+	}
+	if missing := gaps(1, received); len(missing) > 0 {
+		t.Errorf("subscriber missed %d messages after overflow, first %d", len(missing), missing[0])
+	}
+	for i := 1; i < len(received); i++ {
+		if received[i] <= received[i-1] {
+			t.Fatalf("sequence %d after %d: out of order or repeated", received[i], received[i-1])
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCreateTopicHandler(t *testing.T) {
+	mb := newTestBroker(t, newTestClock())
+	mb.maxQueueSize, mb.retentionHours = 100, 24
+	server := newTestServer(t, mb)
+
+	resp, err := http.Post(server.URL+"/topics", "application/json",
+		strings.NewReader(`{"name": "jobs", "maxQueueSize": 5, "ordering": "priority"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("status = %d, want 201", resp.StatusCode)
+	}
+	var body struct {
+		Name   string
+		Config map[string]interface{}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"maxQueueSize": 5.0, "retentionHours": 24.0, "ordering": OrderingPriority}
+	if len(body.Config) != len(want) {
+		t.Errorf("config = %v, want exactly %v", body.Config, want)
+	}
+	for key, value := range want {
+		if body.Config[key] != value {
+			t.Errorf("config %s = %v, want %v", key, body.Config[key], value)
+		}
+	}
+
+	for _, tt := range []struct {
+		body   string
+		status int
+		code   string
+	}{
+		{`{"name": "jobs"}`, http.StatusConflict, CodeConflict},
+		{`{"name": ""}`, http.StatusBadRequest, CodeInvalidRequest},
+		{`{"name": "bad", "maxQueueSize": -1}`, http.StatusBadRequest, CodeInvalidRequest},
+		{`{"name": "bad", "ordering": "lifo"}`, http.StatusBadRequest, CodeInvalidRequest},
+	} {
+		status, code := postStatus(t, server.URL+"/topics", tt.body, nil)
+		if status != tt.status || code != tt.code {
+			t.Errorf("POST %s = %d %s, want %d %s", tt.body, status, code, tt.status, tt.code)
+		}
+	}
+}